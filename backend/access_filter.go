@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/utils"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 面板访问 IP 过滤：设置 access_allowlist（为空表示不限制）与 access_denylist，
+// 逗号分隔的 CIDR 或单个 IP。拒绝列表优先。直接来自回环地址的请求始终允许；带有转发头的回环请求来自同一主机上的
+// 反向代理，未配置 N2N_TRUSTED_PROXIES 时无法得知真实来源，按列表判断而不放行。启动参数 -bypass-ip-filter 可临时关闭过滤。
+
+var (
+	ipFilterBypass bool
+	ipFilterMutex  sync.RWMutex
+	ipAllowlist    []*net.IPNet
+	ipDenylist     []*net.IPNet
+
+	proxyWarnOnce sync.Once
+)
+
+// parseCIDRList 解析逗号分隔的 CIDR / IP 列表
+func parseCIDRList(s string) ([]*net.IPNet, error) {
+	var res []*net.IPNet
+	for _, item := range utils.SplitList(s) {
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", item)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			item = fmt.Sprintf("%s/%d", item, bits)
+		}
+		_, ipnet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", item)
+		}
+		res = append(res, ipnet)
+	}
+	return res, nil
+}
+
+// reloadIPFilter 从设置重新加载访问列表，启动时及保存设置后调用
+// 存储的列表无效时保留上一次有效的列表（启动时为空），不会因此放开限制
+func reloadIPFilter() {
+	ipFilterMutex.Lock()
+	defer ipFilterMutex.Unlock()
+	if allow, err := parseCIDRList(getSettingValue("access_allowlist", "")); err != nil {
+		log.Printf("Invalid access_allowlist, keeping the previous list: %v", err)
+	} else {
+		ipAllowlist = allow
+	}
+	if deny, err := parseCIDRList(getSettingValue("access_denylist", "")); err != nil {
+		log.Printf("Invalid access_denylist, keeping the previous list: %v", err)
+	} else {
+		ipDenylist = deny
+	}
+}
+
+func ipInList(ip net.IP, list []*net.IPNet) bool {
+	for _, n := range list {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedLoopback 请求来自回环地址且带有转发头，即经过同一主机上未被信任的反向代理，ClientIP 不是真实来源
+func forwardedLoopback(c *gin.Context) bool {
+	ip := net.ParseIP(c.ClientIP())
+	if ip == nil || !ip.IsLoopback() {
+		return false
+	}
+	for _, h := range []string{"X-Forwarded-For", "X-Real-IP", "Forwarded"} {
+		if c.GetHeader(h) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ipAllowed 按给定列表判断 IP 是否允许访问，proxied 为 true 时回环地址不再豁免
+func ipAllowed(ip net.IP, proxied bool, allow, deny []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() && !proxied {
+		return true
+	}
+	if ipInList(ip, deny) {
+		return false
+	}
+	return len(allow) == 0 || ipInList(ip, allow)
+}
+
+// ipFilterMiddleware 拒绝不在允许列表内的来源
+func ipFilterMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ipFilterBypass {
+			c.Next()
+			return
+		}
+		proxied := forwardedLoopback(c)
+		ipFilterMutex.RLock()
+		ok := ipAllowed(net.ParseIP(c.ClientIP()), proxied, ipAllowlist, ipDenylist)
+		filtering := len(ipAllowlist) > 0 || len(ipDenylist) > 0
+		ipFilterMutex.RUnlock()
+		if proxied && filtering {
+			proxyWarnOnce.Do(func() {
+				log.Printf("Warning: requests arrive through a local reverse proxy but N2N_TRUSTED_PROXIES is not set, "+
+					"the access lists see every client as %s; set N2N_TRUSTED_PROXIES to the proxy address", c.ClientIP())
+			})
+		}
+		if !ok {
+			respondError(c, 403, ErrAccessDenied, "Access from this address is not allowed")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// validateIPFilterSettings 保存设置前校验访问列表，并防止把当前客户端自己挡在外面
+func validateIPFilterSettings(c *gin.Context, p map[string]string) error {
+	allowStr, allowSet := p["access_allowlist"]
+	denyStr, denySet := p["access_denylist"]
+	if !allowSet && !denySet {
+		return nil
+	}
+	if !allowSet {
+		allowStr = getSettingValue("access_allowlist", "")
+	}
+	if !denySet {
+		denyStr = getSettingValue("access_denylist", "")
+	}
+	allow, err := parseCIDRList(allowStr)
+	if err != nil {
+		return newAPIError(ErrInvalidCIDR, "Invalid access allowlist: "+err.Error())
+	}
+	deny, err := parseCIDRList(denyStr)
+	if err != nil {
+		return newAPIError(ErrInvalidCIDR, "Invalid access denylist: "+err.Error())
+	}
+	if !ipFilterBypass && !ipAllowed(net.ParseIP(c.ClientIP()), forwardedLoopback(c), allow, deny) {
+		return newAPIError(ErrAccessSelfLockout, "These access lists would block your current address: "+c.ClientIP())
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"n2n_ui/backend/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 账户自助：查看个人信息、修改用户名，查看并吊销自己的登录会话和 API 令牌。
+// 每个签发的 JWT 都带有 jti（对应一条 UserSession）、用户 ID、角色和用户的令牌版本。jwtMiddleware 按用户 ID
+// 确定当前用户（修改用户名后已有的令牌继续有效），并检查会话未吊销、角色与令牌版本未变化；
+// 修改密码或角色时令牌版本递增，该用户之前签发的所有令牌立即失效。
+
+const (
+	sessionTouchInterval = time.Minute // 最近活动时间的更新间隔，避免每个请求都写数据库
+	sessionKeepRevoked   = 7 * 24 * time.Hour
+	maxAPITokenDays      = 3650
+
+	sessionKindLogin    = "session"
+	sessionKindAPIToken = "api_token"
+)
+
+// issueToken 创建会话记录并签发对应的 JWT，lifetime 为会话的最长有效期，0 表示不过期
+func issueToken(c *gin.Context, user models.User, kind, name string, lifetime time.Duration) (string, models.UserSession, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", models.UserSession{}, err
+	}
+	now := time.Now()
+	s := models.UserSession{
+		UserID: user.ID, TokenID: hex.EncodeToString(b), Kind: kind, Name: name,
+		IP: c.ClientIP(), UserAgent: c.Request.UserAgent(), LastSeenAt: now,
+	}
+	if lifetime > 0 {
+		exp := now.Add(lifetime)
+		s.ExpiresAt = &exp
+	}
+	if err := db.Create(&s).Error; err != nil {
+		return "", s, err
+	}
+	t, _, err := signSessionToken(user, s)
+	return t, s, err
+}
+
+// signSessionToken 为会话签发 JWT：登录会话的令牌按 session_access_minutes 过期（不超过会话有效期），
+// API 令牌与会话同时过期
+func signSessionToken(user models.User, s models.UserSession) (string, *time.Time, error) {
+	claims := jwt.MapClaims{"username": user.Username, "jti": s.TokenID, "uid": user.ID, "role": userRole(user), "ver": user.TokenVersion}
+	exp := s.ExpiresAt
+	if s.Kind == sessionKindLogin {
+		access := time.Now().Add(sessionLimits().Access)
+		if exp == nil || access.Before(*exp) {
+			exp = &access
+		}
+	}
+	if exp != nil {
+		claims["exp"] = exp.Unix()
+	}
+	t, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	return t, exp, err
+}
+
+// signUserToken 为登录成功的用户签发会话令牌
+func signUserToken(c *gin.Context, user models.User) (string, error) {
+	t, _, err := issueToken(c, user, sessionKindLogin, "", sessionLimits().Refresh)
+	return t, err
+}
+
+// userRole 令牌中记录的角色
+func userRole(user models.User) string {
+	if user.IsAdmin {
+		return "admin"
+	}
+	return "user"
+}
+
+// tokenUser 校验令牌中的用户 ID、角色、令牌版本与会话，返回令牌所属的用户
+func tokenUser(claims jwt.MapClaims) (models.User, bool) {
+	var user models.User
+	uid, _ := claims["uid"].(float64)
+	ver, hasVer := claims["ver"].(float64)
+	role, _ := claims["role"].(string)
+	jti, _ := claims["jti"].(string)
+	if uid <= 0 || !hasVer || jti == "" {
+		return user, false
+	}
+	if err := db.First(&user, uint(uid)).Error; err != nil {
+		return user, false
+	}
+	if int(ver) != user.TokenVersion || role != userRole(user) {
+		return user, false
+	}
+	_, ok := activeSession(jti, user.ID)
+	return user, ok
+}
+
+// activeSession 查找属于该用户且未吊销、未过期、未因空闲超时失效的会话，并按间隔更新最近活动时间
+func activeSession(tokenID string, userID uint) (models.UserSession, bool) {
+	var s models.UserSession
+	if err := db.Where("token_id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).First(&s).Error; err != nil {
+		return s, false
+	}
+	if s.ExpiresAt != nil && time.Now().After(*s.ExpiresAt) {
+		return s, false
+	}
+	if idle := sessionLimits().Idle; idle > 0 && s.Kind == sessionKindLogin && time.Since(s.LastSeenAt) > idle {
+		return s, false
+	}
+	if time.Since(s.LastSeenAt) > sessionTouchInterval {
+		s.LastSeenAt = time.Now()
+		db.Model(&s).UpdateColumn("last_seen_at", s.LastSeenAt)
+	}
+	return s, true
+}
+
+// invalidateTokens 递增用户的令牌版本并吊销其全部会话，用于修改密码或角色之后
+func invalidateTokens(tx *gorm.DB, user *models.User) error {
+	if err := tx.Model(user).UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+		return err
+	}
+	user.TokenVersion++
+	return tx.Model(&models.UserSession{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Update("revoked_at", time.Now()).Error
+}
+
+// pruneSessions 清理已过期及吊销超过一段时间的会话
+func pruneSessions() {
+	now := time.Now()
+	db.Where("expires_at < ? OR revoked_at < ?", now, now.Add(-sessionKeepRevoked)).Delete(&models.UserSession{})
+}
+
+// getProfile 返回当前用户的信息，非管理员附带可管理的社区
+func getProfile(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		respondError(c, 401, ErrInvalidToken, "Invalid token")
+		return
+	}
+	res := gin.H{
+		"id": user.ID, "username": user.Username, "is_admin": user.IsAdmin,
+		"last_login_at": user.LastLoginAt, "last_login_ip": user.LastLoginIP,
+	}
+	if !user.IsAdmin {
+		res["communities"] = userCommunityNames(user.ID)
+	}
+	c.JSON(200, res)
+}
+
+// changeUsername 修改自己的用户名，需提供当前密码；登录记录一并改为新用户名
+func changeUsername(c *gin.Context) {
+	var p struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	user, ok := currentUser(c)
+	if !ok {
+		respondError(c, 401, ErrInvalidToken, "Invalid token")
+		return
+	}
+	p.Username = strings.TrimSpace(p.Username)
+	if p.Username == "" || len(p.Username) > 100 {
+		respondError(c, 400, ErrInvalidRequest, "Username is required")
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(p.Password)); err != nil {
+		respondError(c, 401, ErrOldPasswordIncorrect, "Password incorrect")
+		return
+	}
+	if p.Username == user.Username {
+		c.JSON(200, gin.H{"username": user.Username})
+		return
+	}
+	var count int64
+	db.Model(&models.User{}).Where("username = ?", p.Username).Count(&count)
+	if count > 0 {
+		respondError(c, 409, ErrUserExists, "User already exists")
+		return
+	}
+	old := user.Username
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("username", p.Username).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.LoginHistory{}).Where("username = ?", old).Update("username", p.Username).Error; err != nil {
+			return err
+		}
+		// 节点负责人与提交人按用户名查找用户（公告站内通知、审批结果通知），一并改名，含回收站中的节点
+		if err := tx.Unscoped().Model(&models.Node{}).Where("owner = ?", old).Update("owner", p.Username).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.Node{}).Where("requested_by = ?", old).Update("requested_by", p.Username).Error
+	})
+	if err != nil {
+		// 唯一索引兜底并发创建同名用户的情况
+		respondError(c, 409, ErrUserExists, "User already exists")
+		return
+	}
+	recordAudit(c, "user.rename", p.Username, old+" -> "+p.Username)
+	c.JSON(200, gin.H{"username": p.Username})
+}
+
+// listOwnSessions 列出当前用户有效的会话（kind=session）或 API 令牌（kind=api_token），current 标记本次请求所用的会话
+func listOwnSessions(kind string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			respondError(c, 401, ErrInvalidToken, "Invalid token")
+			return
+		}
+		var list []models.UserSession
+		db.Where("user_id = ? AND kind = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", user.ID, kind, time.Now()).
+			Order("last_seen_at DESC").Find(&list)
+		current := c.GetString("session_id")
+		res := make([]gin.H, 0, len(list))
+		for _, s := range list {
+			res = append(res, gin.H{
+				"id": s.ID, "kind": s.Kind, "name": s.Name, "ip": s.IP, "user_agent": s.UserAgent,
+				"created_at": s.CreatedAt, "last_seen_at": s.LastSeenAt, "expires_at": s.ExpiresAt,
+				"current": s.TokenID == current,
+			})
+		}
+		c.JSON(200, res)
+	}
+}
+
+// createAPIToken 创建长期有效的 API 令牌，令牌只在创建时返回一次；expires_days 为 0 表示不过期
+func createAPIToken(c *gin.Context) {
+	var p struct {
+		Name        string `json:"name" binding:"required"`
+		ExpiresDays int    `json:"expires_days"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	user, ok := currentUser(c)
+	if !ok {
+		respondError(c, 401, ErrInvalidToken, "Invalid token")
+		return
+	}
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" || len(p.Name) > 100 || p.ExpiresDays < 0 || p.ExpiresDays > maxAPITokenDays {
+		respondError(c, 400, ErrInvalidRequest, "Invalid request")
+		return
+	}
+	t, s, err := issueToken(c, user, sessionKindAPIToken, p.Name, time.Duration(p.ExpiresDays)*24*time.Hour)
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	recordAudit(c, "api_token.create", p.Name, "")
+	c.JSON(200, gin.H{"id": s.ID, "name": s.Name, "expires_at": s.ExpiresAt, "token": t})
+}
+
+// revokeOwnSession 吊销自己的会话或 API 令牌，吊销当前会话相当于退出登录
+func revokeOwnSession(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		respondError(c, 401, ErrInvalidToken, "Invalid token")
+		return
+	}
+	var s models.UserSession
+	if err := db.Where("user_id = ? AND revoked_at IS NULL", user.ID).First(&s, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrSessionNotFound, "Session not found")
+		return
+	}
+	db.Model(&s).Update("revoked_at", time.Now())
+	target := s.Name
+	if target == "" {
+		target = s.IP
+	}
+	recordAudit(c, s.Kind+".revoke", target, "")
+	c.JSON(200, gin.H{"message": "revoked"})
+}
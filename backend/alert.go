@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Alert 告警事件
+type Alert struct {
+	Type     string    `json:"type"`  // node_offline, node_online, node_expired, disabled_edge_online, supernode_down, supernode_recovered, supernode_recovery_failed, edge_auth_failed, community_rejected, node_registration, node_registered, node_pending, integrity_issues, update_available, password_rotated, alert_rule, alert_resolved, test
+	Level    string    `json:"level"` // info, warning, critical
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+	Time     time.Time `json:"time"`
+	Channels []string  `json:"-"` // 指定投递的渠道（告警规则），为空时按 notify_routes_* 路由
+	// 站内通知只发给管理员（如待审批的节点），非管理员看不到其他社区的信息
+	AdminOnly bool `json:"-"`
+}
+
+// Notifier 告警通知渠道
+type Notifier interface {
+	Name() string
+	Enabled() bool
+	Send(a Alert) error
+}
+
+const alertCheckInterval = 30 * time.Second
+
+var notifiers = []Notifier{&emailNotifier{}, &telegramNotifier{}, &dingtalkNotifier{}, &wecomNotifier{}}
+
+// dispatchAlert 将告警投递到所有已启用的通知渠道
+func dispatchAlert(a Alert) {
+	if a.Time.IsZero() {
+		a.Time = time.Now()
+	}
+	log.Printf("[告警] %s: %s", a.Title, a.Message)
+	forwardSyslog(alertSyslogCategory(a.Type), alertSyslogSeverity(a.Level), a.Type, a.Title+": "+a.Message,
+		map[string]string{"type": a.Type, "level": a.Level})
+	saveToInbox(a)
+	publishAlertEvent(a)
+	for _, n := range notifiers {
+		routed := alertRouted(n.Name(), a.Type)
+		if len(a.Channels) > 0 {
+			routed = containsString(a.Channels, n.Name())
+		}
+		if !n.Enabled() || !routed {
+			continue
+		}
+		go func(n Notifier) {
+			if err := n.Send(a); err != nil {
+				log.Printf("Alert: %s delivery failed: %v", n.Name(), err)
+			}
+		}(n)
+	}
+}
+
+// emailNotifier 通过 SMTP 发送告警邮件
+type emailNotifier struct{}
+
+func (e *emailNotifier) Name() string { return "email" }
+
+func (e *emailNotifier) Enabled() bool {
+	return getSettingValue("smtp_enabled", "false") == "true"
+}
+
+func (e *emailNotifier) Send(a Alert) error {
+	subject := fmt.Sprintf("[n2n-admin][%s] %s", strings.ToUpper(a.Level), a.Title)
+	body := fmt.Sprintf("%s\n\n时间: %s\n类型: %s\n", a.Message, a.Time.Format("2006-01-02 15:04:05"), a.Type)
+	return utils.SendMail(loadSMTPConfig(), subject, body)
+}
+
+// loadSMTPConfig 从设置中读取 SMTP 配置
+func loadSMTPConfig() utils.SMTPConfig {
+	port, _ := strconv.Atoi(getSettingValue("smtp_port", "25"))
+	return utils.SMTPConfig{
+		Host:     getSettingValue("smtp_host", ""),
+		Port:     port,
+		TLSMode:  getSettingValue("smtp_tls", "none"),
+		Username: getSettingValue("smtp_username", ""),
+		Password: getSettingValue("smtp_password", ""),
+		From:     getSettingValue("smtp_from", ""),
+		To:       utils.SplitList(getSettingValue("smtp_to", "")),
+	}
+}
+
+// startAlertMonitor 定期检查节点在线状态和 supernode 运行状态并产生告警
+func startAlertMonitor() {
+	ticker := time.NewTicker(alertCheckInterval)
+	defer ticker.Stop()
+
+	missCount := make(map[string]int) // MAC -> 连续未见次数
+	offline := make(map[string]bool)  // 已告警离线的节点
+	initialized := false
+	supernodeDown := make(map[uint]bool)  // 实例 ID -> 是否已告警停止
+	blockedSeen := make(map[string]bool)  // 已告警的在线封禁 MAC
+	disabledSeen := make(map[string]bool) // 已告警的在线禁用节点 MAC
+
+	for range ticker.C {
+		edges := make(map[string]utils.EdgeInfo)
+		downNow := make(map[uint]bool)
+		// 管理端口偶尔无响应不告警，连续失败达到阈值才视为停止
+		mgmtThreshold := settingIntValue("alert_mgmt_failure_threshold")
+		for _, rt := range listRuntimes() {
+			instEdges, err := rt.client.GetEdgeInfo()
+			failures := rt.client.Status().Failures
+			down := !isSupernodeActive(rt.instance.Unit) || (err != nil && failures >= mgmtThreshold)
+			downNow[rt.instance.ID] = down || err != nil
+			if down != supernodeDown[rt.instance.ID] {
+				if down {
+					reason := "systemd 服务未处于 active 状态"
+					if err != nil {
+						reason = fmt.Sprintf("管理端口连续 %d 次无响应: %v", failures, err)
+					}
+					dispatchAlert(Alert{Type: "supernode_down", Level: "critical", Title: "Supernode 已停止运行: " + rt.instance.Name, Message: reason})
+				} else {
+					dispatchAlert(Alert{Type: "supernode_recovered", Level: "info", Title: "Supernode 已恢复运行: " + rt.instance.Name, Message: "supernode 服务及管理端口已恢复正常"})
+				}
+				supernodeDown[rt.instance.ID] = down
+			}
+			for mac, info := range instEdges {
+				edges[mac] = info
+			}
+		}
+
+		blocked := blockedMacSet()
+		for mac := range blockedSeen {
+			if _, ok := edges[mac]; !ok {
+				delete(blockedSeen, mac)
+			}
+		}
+		for mac, info := range edges {
+			if blocked[mac] && !blockedSeen[mac] {
+				blockedSeen[mac] = true
+				dispatchAlert(Alert{Type: "blocked_edge_online", Level: "critical", Title: "已封禁的 edge 仍在线: " + mac,
+					Message: fmt.Sprintf("MAC %s 已被封禁，但仍从 %s 连接到 supernode，请重启 supernode 或轮换社区密码", mac, info.External)})
+			}
+		}
+
+		online := make(map[string]bool, len(edges))
+		for mac := range edges {
+			online[mac] = true
+		}
+		enforceDisabledNodes(online, disabledSeen)
+		discoverEdges(edges)
+
+		var comms []models.Community
+		db.Find(&comms)
+		commInstance := make(map[string]uint)
+		for _, cm := range comms {
+			commInstance[cm.Name] = communityInstanceID(cm)
+		}
+
+		var nodes []models.Node
+		db.Where("is_enabled = ?", true).Find(&nodes)
+		// 连续 N 次轮询未见才判定离线，避免抖动误报
+		offlineThreshold := settingIntValue("alert_offline_threshold")
+		for _, n := range nodes {
+			if downNow[commInstance[n.Community]] {
+				// supernode 不可用或管理端口查询失败时其下节点的在线状态未知，不逐个告警
+				continue
+			}
+			m := n.MacAddress
+			if _, ok := edges[m]; ok {
+				if offline[m] {
+					dispatchAlert(Alert{Type: "node_online", Level: "info", Title: "节点已上线: " + n.Name,
+						Message: fmt.Sprintf("节点 %s (%s) 已重新连接到 supernode", n.Name, n.IPAddress)})
+				}
+				delete(offline, m)
+				missCount[m] = 0
+				continue
+			}
+			if !initialized {
+				// 首次检查时不对本来就离线的节点告警
+				offline[m] = true
+				continue
+			}
+			missCount[m]++
+			if missCount[m] >= offlineThreshold && !offline[m] {
+				offline[m] = true
+				dispatchAlert(Alert{Type: "node_offline", Level: "warning", Title: "节点已离线: " + n.Name,
+					Message: fmt.Sprintf("节点 %s (%s) 已连续 %d 次检查未在 supernode 上出现", n.Name, n.IPAddress, missCount[m])})
+			}
+		}
+		initialized = true
+	}
+}
+
+func testEmail(c *gin.Context) {
+	var p struct {
+		To string `json:"to"`
+	}
+	if !bindOptionalJSON(c, &p) {
+		return
+	}
+	cfg := loadSMTPConfig()
+	if p.To != "" {
+		cfg.To = utils.SplitList(p.To)
+	}
+	subject := "[n2n-admin] 测试邮件"
+	body := fmt.Sprintf("这是一封来自 n2n-admin 的测试邮件。\n\n时间: %s\n", time.Now().Format("2006-01-02 15:04:05"))
+	if err := utils.SendMail(cfg, subject, body); err != nil {
+		respondError(c, 500, ErrNotifyFailed, "Send failed: "+err.Error())
+		return
+	}
+	c.JSON(200, gin.H{"message": "sent"})
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 阈值告警规则：后台每 30 秒计算一次各规则的指标，条件持续满足 duration 秒后触发告警并记录历史，
+// 条件不再满足时自动恢复。支持的指标：
+//   - online_count：在线的已启用节点数，target 为社区名时只统计该社区
+//   - relay_ratio：最近一小时在线时经中转（Relay）通信的采样比例（百分比），target 为节点 ID 时只统计该节点
+//   - supernode_restarts：最近 24 小时看门狗自动重启 supernode 的次数，target 为实例 ID 时只统计该实例
+
+const (
+	alertMetricOnline   = "online_count"
+	alertMetricRelay    = "relay_ratio"
+	alertMetricRestarts = "supernode_restarts"
+
+	relayRatioWindow   = time.Hour
+	restartCountWindow = 24 * time.Hour
+	maxAlertDuration   = 7 * 24 * 3600
+
+	alertStatusFiring   = "firing"
+	alertStatusResolved = "resolved"
+)
+
+var (
+	alertMetrics     = []string{alertMetricOnline, alertMetricRelay, alertMetricRestarts}
+	alertComparators = []string{"<", "<=", ">", ">=", "==", "!="}
+	alertSeverities  = []string{"info", "warning", "critical"}
+)
+
+// relaySample 一次轮询时某个在线节点是否经中转通信
+type relaySample struct {
+	At      time.Time
+	Relayed bool
+}
+
+// alertSnapshot 一轮评估共用的数据，避免每条规则重复查询 supernode
+type alertSnapshot struct {
+	edges   map[string]utils.EdgeInfo
+	edgesOK bool
+	nodes   []models.Node
+}
+
+// alertRuleState 一条规则的评估状态
+type alertRuleState struct {
+	pendingSince time.Time          // 条件开始满足的时间，零值表示当前不满足
+	event        *models.AlertEvent // 未恢复的告警
+	updatedAt    time.Time          // 规则被修改后重新开始评估
+}
+
+// alertRuleEngine 规则评估器，状态只在后台协程中访问
+type alertRuleEngine struct {
+	states       map[uint]*alertRuleState
+	relayHistory map[string][]relaySample // MAC -> 最近一小时的采样
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// compareAlertValue 按比较符比较指标值与阈值
+func compareAlertValue(value float64, comparator string, threshold float64) bool {
+	switch comparator {
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	}
+	return false
+}
+
+// sample 记录本轮各在线节点的中转状态并丢弃超出窗口的采样
+func (e *alertRuleEngine) sample(snap *alertSnapshot) {
+	now := time.Now()
+	if snap.edgesOK {
+		relays := relaySources()
+		for mac := range snap.edges {
+			e.relayHistory[mac] = append(e.relayHistory[mac], relaySample{At: now, Relayed: relays[mac]})
+		}
+	}
+	for mac, samples := range e.relayHistory {
+		i := 0
+		for i < len(samples) && now.Sub(samples[i].At) > relayRatioWindow {
+			i++
+		}
+		if i == len(samples) {
+			delete(e.relayHistory, mac)
+		} else if i > 0 {
+			e.relayHistory[mac] = samples[i:]
+		}
+	}
+}
+
+// value 计算规则的当前指标值，数据不可用（如 supernode 查询失败、节点不存在）时返回 false
+func (e *alertRuleEngine) value(r models.AlertRule, snap *alertSnapshot) (float64, bool) {
+	switch r.Metric {
+	case alertMetricOnline:
+		if !snap.edgesOK {
+			return 0, false
+		}
+		count := 0
+		for _, n := range snap.nodes {
+			if r.Target != "" && n.Community != r.Target {
+				continue
+			}
+			if _, ok := snap.edges[n.MacAddress]; ok {
+				count++
+			}
+		}
+		return float64(count), true
+	case alertMetricRelay:
+		macs := make([]string, 0)
+		if r.Target == "" {
+			for mac := range e.relayHistory {
+				macs = append(macs, mac)
+			}
+		} else {
+			for _, n := range snap.nodes {
+				if strconv.FormatUint(uint64(n.ID), 10) == r.Target {
+					macs = append(macs, n.MacAddress)
+				}
+			}
+		}
+		total, relayed := 0, 0
+		for _, mac := range macs {
+			for _, s := range e.relayHistory[mac] {
+				total++
+				if s.Relayed {
+					relayed++
+				}
+			}
+		}
+		if total == 0 {
+			return 0, false
+		}
+		return float64(relayed) * 100 / float64(total), true
+	case alertMetricRestarts:
+		var restarts int64
+		q := db.Model(&models.SupernodeIncident{}).Where("started_at >= ?", time.Now().Add(-restartCountWindow))
+		if r.Target != "" {
+			q = q.Where("instance_id = ?", r.Target)
+		}
+		if err := q.Select("COALESCE(SUM(restarts), 0)").Scan(&restarts).Error; err != nil {
+			return 0, false
+		}
+		return float64(restarts), true
+	}
+	return 0, false
+}
+
+// formatAlertValue 指标值的显示格式
+func formatAlertValue(metric string, v float64) string {
+	if metric == alertMetricRelay {
+		return strconv.FormatFloat(v, 'f', 1, 64) + "%"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// describeAlertRule 规则条件的文字描述，如 "online_count(office) < 5 持续 600 秒"
+func describeAlertRule(r models.AlertRule) string {
+	metric := r.Metric
+	if r.Target != "" {
+		metric += "(" + r.Target + ")"
+	}
+	s := fmt.Sprintf("%s %s %s", metric, r.Comparator, strconv.FormatFloat(r.Threshold, 'f', -1, 64))
+	if r.Duration > 0 {
+		s += fmt.Sprintf(" 持续 %d 秒", r.Duration)
+	}
+	return s
+}
+
+// evaluate 评估一条规则，满足条件足够久时触发告警，条件消失时恢复
+func (e *alertRuleEngine) evaluate(r models.AlertRule, snap *alertSnapshot) {
+	st, ok := e.states[r.ID]
+	if !ok || !st.updatedAt.Equal(r.UpdatedAt) {
+		st = &alertRuleState{updatedAt: r.UpdatedAt}
+		var ev models.AlertEvent
+		if db.Where("rule_id = ? AND status = ?", r.ID, alertStatusFiring).Order("id DESC").Limit(1).Find(&ev); ev.ID != 0 {
+			st.event = &ev
+		}
+		e.states[r.ID] = st
+	}
+	v, ok := e.value(r, snap)
+	if !ok {
+		// 数据不可用时保持原状态，既不触发也不恢复
+		return
+	}
+	channels := utils.SplitList(r.Channels)
+	if !compareAlertValue(v, r.Comparator, r.Threshold) {
+		st.pendingSince = time.Time{}
+		if st.event != nil {
+			now := time.Now()
+			st.event.Status, st.event.ResolvedAt = alertStatusResolved, &now
+			db.Save(st.event)
+			dispatchAlert(Alert{Type: "alert_resolved", Level: "info", Title: "告警已恢复: " + r.Name, Channels: channels,
+				Message: fmt.Sprintf("%s 不再满足，当前值 %s", describeAlertRule(r), formatAlertValue(r.Metric, v))})
+			st.event = nil
+		}
+		return
+	}
+	if st.pendingSince.IsZero() {
+		st.pendingSince = time.Now()
+	}
+	if st.event != nil || time.Since(st.pendingSince) < time.Duration(r.Duration)*time.Second {
+		return
+	}
+	msg := fmt.Sprintf("%s，当前值 %s", describeAlertRule(r), formatAlertValue(r.Metric, v))
+	st.event = &models.AlertEvent{RuleID: r.ID, RuleName: r.Name, Severity: r.Severity, Value: v, Message: msg, Status: alertStatusFiring, FiredAt: time.Now()}
+	if err := db.Create(st.event).Error; err != nil {
+		log.Printf("Alert rules: failed to record event: %v", err)
+	}
+	dispatchAlert(Alert{Type: "alert_rule", Level: r.Severity, Title: "告警规则触发: " + r.Name, Message: msg, Channels: channels})
+}
+
+// run 执行一轮评估，已删除或停用的规则丢弃其状态
+func (e *alertRuleEngine) run() {
+	snap := &alertSnapshot{}
+	edges, err := allEdgeInfo()
+	snap.edges, snap.edgesOK = edges, err == nil
+	db.Where("is_enabled = ?", true).Find(&snap.nodes)
+	e.sample(snap)
+
+	var rules []models.AlertRule
+	db.Where("enabled = ?", true).Find(&rules)
+	active := make(map[uint]bool, len(rules))
+	for _, r := range rules {
+		active[r.ID] = true
+		e.evaluate(r, snap)
+	}
+	for id := range e.states {
+		if !active[id] {
+			delete(e.states, id)
+		}
+	}
+}
+
+// startAlertRuleEngine 定期评估告警规则
+func startAlertRuleEngine() {
+	e := &alertRuleEngine{states: make(map[uint]*alertRuleState), relayHistory: make(map[string][]relaySample)}
+	ticker := time.NewTicker(alertCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		e.run()
+	}
+}
+
+// resolveRuleEvents 将规则未恢复的告警标记为已恢复，用于规则被删除、停用或修改之后
+func resolveRuleEvents(ruleID uint) {
+	db.Model(&models.AlertEvent{}).Where("rule_id = ? AND status = ?", ruleID, alertStatusFiring).
+		Updates(map[string]interface{}{"status": alertStatusResolved, "resolved_at": time.Now()})
+}
+
+// alertRuleInput 创建或修改规则的请求，省略的字段保持原值（创建时使用默认值）
+type alertRuleInput struct {
+	Name       *string  `json:"name"`
+	Metric     *string  `json:"metric"`
+	Target     *string  `json:"target"`
+	Comparator *string  `json:"comparator"`
+	Threshold  *float64 `json:"threshold"`
+	Duration   *int     `json:"duration"`
+	Severity   *string  `json:"severity"`
+	Channels   *string  `json:"channels"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+// apply 将请求合并到规则并校验，返回错误信息
+func (p alertRuleInput) apply(r *models.AlertRule) string {
+	if p.Name != nil {
+		r.Name = strings.TrimSpace(*p.Name)
+	}
+	if p.Metric != nil {
+		r.Metric = *p.Metric
+	}
+	if p.Target != nil {
+		r.Target = strings.TrimSpace(*p.Target)
+	}
+	if p.Comparator != nil {
+		r.Comparator = *p.Comparator
+	}
+	if p.Threshold != nil {
+		r.Threshold = *p.Threshold
+	}
+	if p.Duration != nil {
+		r.Duration = *p.Duration
+	}
+	if p.Severity != nil {
+		r.Severity = *p.Severity
+	}
+	if p.Channels != nil {
+		r.Channels = strings.Join(utils.SplitList(*p.Channels), ",")
+	}
+	if p.Enabled != nil {
+		r.Enabled = *p.Enabled
+	}
+	switch {
+	case r.Name == "" || len(r.Name) > 100:
+		return "Rule name is required"
+	case !containsString(alertMetrics, r.Metric):
+		return "Unknown metric"
+	case !containsString(alertComparators, r.Comparator):
+		return "Unknown comparator"
+	case !containsString(alertSeverities, r.Severity):
+		return "Unknown severity"
+	case r.Duration < 0 || r.Duration > maxAlertDuration:
+		return "Invalid duration"
+	}
+	if r.Target != "" && r.Metric != alertMetricOnline {
+		if _, err := strconv.ParseUint(r.Target, 10, 32); err != nil {
+			return "Target must be an ID"
+		}
+	}
+	for _, ch := range utils.SplitList(r.Channels) {
+		if findNotifier(ch) == nil {
+			return "Unknown channel"
+		}
+	}
+	return ""
+}
+
+func getAlertRules(c *gin.Context) {
+	var rules []models.AlertRule
+	db.Order("id").Find(&rules)
+	c.JSON(200, rules)
+}
+
+func createAlertRule(c *gin.Context) {
+	var p alertRuleInput
+	if !bindJSON(c, &p) {
+		return
+	}
+	r := models.AlertRule{Comparator: ">", Severity: "warning", Enabled: true}
+	if msg := p.apply(&r); msg != "" {
+		respondError(c, 400, ErrInvalidRequest, msg)
+		return
+	}
+	if err := db.Create(&r).Error; err != nil {
+		respondError(c, 500, ErrInternal, "Failed to create rule")
+		return
+	}
+	c.JSON(200, r)
+}
+
+// updateAlertRule 修改规则，未恢复的告警随之恢复，按新条件重新评估
+func updateAlertRule(c *gin.Context) {
+	var r models.AlertRule
+	if err := db.First(&r, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrAlertRuleNotFound, "Alert rule not found")
+		return
+	}
+	var p alertRuleInput
+	if !bindJSON(c, &p) {
+		return
+	}
+	if msg := p.apply(&r); msg != "" {
+		respondError(c, 400, ErrInvalidRequest, msg)
+		return
+	}
+	if err := db.Save(&r).Error; err != nil {
+		respondError(c, 500, ErrInternal, "Failed to save rule")
+		return
+	}
+	resolveRuleEvents(r.ID)
+	c.JSON(200, r)
+}
+
+func deleteAlertRule(c *gin.Context) {
+	var r models.AlertRule
+	if err := db.First(&r, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrAlertRuleNotFound, "Alert rule not found")
+		return
+	}
+	db.Delete(&r)
+	resolveRuleEvents(r.ID)
+	c.JSON(200, gin.H{"message": "deleted"})
+}
+
+// getAlertHistory 返回最近的告警记录，可用 ?rule= / ?status= / ?unacknowledged=true 过滤
+func getAlertHistory(c *gin.Context) {
+	q := db.Order("id DESC").Limit(200)
+	if v := c.Query("rule"); v != "" {
+		q = q.Where("rule_id = ?", v)
+	}
+	if v := c.Query("status"); v != "" {
+		q = q.Where("status = ?", v)
+	}
+	if c.Query("unacknowledged") == "true" {
+		q = q.Where("acknowledged_at IS NULL")
+	}
+	var list []models.AlertEvent
+	q.Find(&list)
+	c.JSON(200, list)
+}
+
+// acknowledgeAlert 确认告警，记录确认人与时间；重复确认保留首次确认的信息
+func acknowledgeAlert(c *gin.Context) {
+	var ev models.AlertEvent
+	if err := db.First(&ev, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrAlertNotFound, "Alert not found")
+		return
+	}
+	if ev.AcknowledgedAt == nil {
+		now := time.Now()
+		ev.AcknowledgedAt, ev.AcknowledgedBy = &now, c.GetString("username")
+		db.Model(&ev).Updates(map[string]interface{}{"acknowledged_at": now, "acknowledged_by": ev.AcknowledgedBy})
+	}
+	c.JSON(200, ev)
+}
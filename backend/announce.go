@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 公告投递渠道：email 发送到节点负责人邮箱，inbox 写入负责人的站内通知，webhook 发送到已启用的聊天机器人
+var announceChannels = map[string]bool{"email": true, "inbox": true, "webhook": true}
+
+// announceTargets 查找社区和分组条件下的节点
+func announceTargets(community, tag string) []models.Node {
+	q := db.Model(&models.Node{})
+	if community != "" {
+		q = q.Where("community = ?", community)
+	}
+	var nodes []models.Node
+	q.Find(&nodes)
+	if tag == "" {
+		return nodes
+	}
+	res := make([]models.Node, 0)
+	for _, n := range nodes {
+		for _, t := range utils.SplitList(n.Tags) {
+			if t == tag {
+				res = append(res, n)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// planDeliveries 按渠道生成去重后的投递记录
+func planDeliveries(a models.Announcement, nodes []models.Node) []models.AnnouncementDelivery {
+	var res []models.AnnouncementDelivery
+	seen := make(map[string]bool)
+	add := func(channel, recipient string) {
+		if recipient == "" || seen[channel+"|"+recipient] {
+			return
+		}
+		seen[channel+"|"+recipient] = true
+		res = append(res, models.AnnouncementDelivery{AnnouncementID: a.ID, Channel: channel, Recipient: recipient, Status: "pending"})
+	}
+	for _, ch := range utils.SplitList(a.Channels) {
+		switch ch {
+		case "email":
+			for _, n := range nodes {
+				add(ch, strings.TrimSpace(n.OwnerEmail))
+			}
+		case "inbox":
+			for _, n := range nodes {
+				add(ch, strings.TrimSpace(n.Owner))
+			}
+		case "webhook":
+			for _, n := range notifiers {
+				if n.Name() != "email" && n.Enabled() {
+					add(ch, n.Name())
+				}
+			}
+		}
+	}
+	return res
+}
+
+// deliverAnnouncement 逐条投递并记录结果
+func deliverAnnouncement(a models.Announcement, deliveries []models.AnnouncementDelivery) {
+	for i := range deliveries {
+		d := &deliveries[i]
+		var err error
+		switch d.Channel {
+		case "email":
+			cfg := loadSMTPConfig()
+			cfg.To = []string{d.Recipient}
+			err = utils.SendMail(cfg, "[n2n-admin] "+a.Title, a.Message)
+		case "inbox":
+			var u models.User
+			if err = db.Where("username = ?", d.Recipient).First(&u).Error; err == nil {
+				err = db.Create(&models.Notification{UserID: u.ID, Type: "announcement", Level: "info", Title: a.Title, Message: a.Message, CreatedAt: a.CreatedAt}).Error
+			} else {
+				err = fmt.Errorf("user not found")
+			}
+		case "webhook":
+			if n := findNotifier(d.Recipient); n != nil {
+				err = n.Send(Alert{Type: "announcement", Level: "info", Title: a.Title, Message: a.Message, Time: a.CreatedAt})
+			} else {
+				err = fmt.Errorf("unknown channel")
+			}
+		}
+		now := time.Now()
+		d.SentAt = &now
+		if err != nil {
+			d.Status, d.Error = "failed", err.Error()
+			log.Printf("Announcement %d: delivery via %s to %s failed: %v", a.ID, d.Channel, d.Recipient, err)
+		} else {
+			d.Status = "sent"
+		}
+		db.Save(d)
+	}
+}
+
+func createAnnouncement(c *gin.Context) {
+	var p struct {
+		Title     string `json:"title"`
+		Message   string `json:"message"`
+		Community string `json:"community"`
+		Tag       string `json:"tag"`
+		Channels  string `json:"channels"` // 默认 email,inbox
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	if strings.TrimSpace(p.Title) == "" {
+		respondError(c, 400, ErrInvalidRequest, "Title is required")
+		return
+	}
+	if p.Community == "" && p.Tag == "" {
+		respondError(c, 400, ErrInvalidRequest, "Community or tag is required")
+		return
+	}
+	if p.Community != "" {
+		if _, err := lookupCommunity(p.Community); err != nil {
+			respondErr(c, 400, err, ErrInvalidRequest)
+			return
+		}
+	}
+	if p.Channels == "" {
+		p.Channels = "email,inbox"
+	}
+	channels := utils.SplitList(p.Channels)
+	for _, ch := range channels {
+		if !announceChannels[ch] {
+			respondError(c, 400, ErrUnknownChannel, "Unknown channel: "+ch)
+			return
+		}
+	}
+
+	nodes := announceTargets(p.Community, p.Tag)
+	a := models.Announcement{
+		Title: p.Title, Message: p.Message, Community: p.Community, Tag: p.Tag,
+		Channels: strings.Join(channels, ","), NodeCount: len(nodes), CreatedBy: c.GetString("username"),
+	}
+	if err := db.Create(&a).Error; err != nil {
+		respondError(c, 500, ErrInternal, "Failed to create announcement")
+		return
+	}
+	deliveries := planDeliveries(a, nodes)
+	if len(deliveries) > 0 {
+		db.Create(&deliveries)
+		go deliverAnnouncement(a, append([]models.AnnouncementDelivery(nil), deliveries...))
+	}
+	c.JSON(200, gin.H{"announcement": a, "deliveries": deliveries})
+}
+
+// announcementSummary 各状态的投递数量
+func announcementSummary(id uint) map[string]int64 {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	db.Model(&models.AnnouncementDelivery{}).Select("status, count(*) as count").Where("announcement_id = ?", id).Group("status").Scan(&rows)
+	res := map[string]int64{"pending": 0, "sent": 0, "failed": 0}
+	for _, r := range rows {
+		res[r.Status] = r.Count
+	}
+	return res
+}
+
+func getAnnouncements(c *gin.Context) {
+	var list []models.Announcement
+	db.Order("id DESC").Limit(100).Find(&list)
+	res := make([]gin.H, 0, len(list))
+	for _, a := range list {
+		res = append(res, gin.H{"announcement": a, "summary": announcementSummary(a.ID)})
+	}
+	c.JSON(200, res)
+}
+
+func getAnnouncement(c *gin.Context) {
+	var a models.Announcement
+	if err := db.First(&a, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNotFound, "Announcement not found")
+		return
+	}
+	var deliveries []models.AnnouncementDelivery
+	db.Where("announcement_id = ?", a.ID).Order("id").Find(&deliveries)
+	c.JSON(200, gin.H{"announcement": a, "summary": announcementSummary(a.ID), "deliveries": deliveries})
+}
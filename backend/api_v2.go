@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// /api/v2 与 v1 共用处理函数，由 envelopeMiddleware 把响应统一包装为：
+//
+//	{"code": "OK", "message": "", "data": <v1 响应体>}
+//	{"code": "<错误码>", "message": "<错误消息>", "data": null}
+//
+// 错误一定使用非 2xx 状态码；v1 中以 200 返回的错误（如 execTool 命令执行失败）在 v2 中改为 502。
+// SSE 等非 JSON 响应原样透传。需设置环境变量 N2N_ENABLE_API_V2=true 启用。
+
+const codeOK = "OK"
+
+// apiEnvelope v2 统一响应结构
+type apiEnvelope struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// envelopeWriter 缓存 JSON 响应体以便包装，其他类型的响应直接写出
+type envelopeWriter struct {
+	gin.ResponseWriter
+	buf         bytes.Buffer
+	status      int
+	passthrough bool
+	decided     bool
+}
+
+func (w *envelopeWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	w.passthrough = !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *envelopeWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *envelopeWriter) WriteHeaderNow() {
+	if w.decide(); w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *envelopeWriter) Write(b []byte) (int, error) {
+	if w.decide(); w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+func (w *envelopeWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *envelopeWriter) Status() int {
+	return w.status
+}
+
+func (w *envelopeWriter) Written() bool {
+	return w.decided
+}
+
+func (w *envelopeWriter) Flush() {
+	if w.decide(); w.passthrough {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// wrapEnvelope 把 v1 的响应体和状态码转换为 v2 信封
+func wrapEnvelope(status int, body []byte) (int, apiEnvelope) {
+	var v1Err struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	if json.Unmarshal(body, &v1Err) == nil && v1Err.Code != "" && v1Err.Error != "" {
+		if status < 400 {
+			status = http.StatusBadGateway
+		}
+		return status, apiEnvelope{Code: v1Err.Code, Message: v1Err.Error, Data: json.RawMessage("null")}
+	}
+	if status >= 400 {
+		return status, apiEnvelope{Code: ErrInternal, Message: http.StatusText(status), Data: json.RawMessage("null")}
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		body = []byte("null")
+	}
+	return status, apiEnvelope{Code: codeOK, Data: body}
+}
+
+// envelopeMiddleware 为 /api/v2 包装响应
+func envelopeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &envelopeWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		if w.passthrough {
+			return
+		}
+		status, env := wrapEnvelope(w.status, w.buf.Bytes())
+		c.JSON(status, env)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordAudit 记录当前用户的一次操作，actor 取登录用户名
+func recordAudit(c *gin.Context, action, target, detail string) {
+	saveAudit(models.AuditLog{Actor: c.GetString("username"), Action: action, Target: target, Detail: detail, IP: c.ClientIP()})
+}
+
+// recordSystemAudit 记录后台任务执行的操作，actor 为 system
+func recordSystemAudit(action, target, detail string) {
+	saveAudit(models.AuditLog{Actor: "system", Action: action, Target: target, Detail: detail})
+}
+
+func saveAudit(entry models.AuditLog) {
+	if err := db.Create(&entry).Error; err != nil {
+		log.Printf("Failed to save audit log: %v", err)
+	}
+	msg := entry.Actor + " " + entry.Action + " " + entry.Target
+	if entry.Detail != "" {
+		msg += ": " + entry.Detail
+	}
+	forwardSyslog(syslogCategoryAudit, utils.SyslogNotice, entry.Action, msg,
+		map[string]string{"actor": entry.Actor, "action": entry.Action, "target": entry.Target, "ip": entry.IP})
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"log"
+	"n2n_ui/backend/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	availabilityPollInterval = 30 * time.Second
+	reliabilityWindow        = 24 * time.Hour // getNodes 中可靠性评分的统计窗口
+)
+
+// edgeSample 上一次轮询时 edge 的状态
+type edgeSample struct {
+	seen     bool
+	lastSeen int
+}
+
+// recordAvailability 记录一次轮询结果到节点当前小时的统计中
+// 只统计已启用的节点，已禁用或到期的节点本来就不应在线
+func recordAvailability(edges map[string]int, prev map[uint]edgeSample) {
+	var nodes []models.Node
+	db.Where("is_enabled = ?", true).Find(&nodes)
+	now := time.Now()
+	hour := now.Truncate(time.Hour)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, n := range nodes {
+			if nodeExpired(n) {
+				continue
+			}
+			lastSeen, seen := edges[n.MacAddress]
+			var b models.NodeAvailability
+			if err := tx.Where(models.NodeAvailability{NodeID: n.ID, Hour: hour}).FirstOrCreate(&b).Error; err != nil {
+				return err
+			}
+			b.Polls++
+			p, known := prev[n.ID]
+			if seen {
+				b.Seen++
+				if known && !p.seen {
+					b.Reconnects++
+				}
+				if known && p.seen && lastSeen > p.lastSeen {
+					b.Registrations++
+				}
+				if age := int(now.Unix()) - lastSeen; lastSeen > 0 && age > b.MaxStaleness {
+					b.MaxStaleness = age
+				}
+			}
+			prev[n.ID] = edgeSample{seen: seen, lastSeen: lastSeen}
+			if err := tx.Save(&b).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Availability: failed to record poll: %v", err)
+	}
+}
+
+// startAvailabilityTracker 定期轮询 supernode，统计每个节点的可见率，并记录在线节点的公网地址等信息供搜索
+// 管理端口查询失败时跳过本次轮询，supernode 故障不计入节点的可靠性
+func startAvailabilityTracker() {
+	prev := make(map[uint]edgeSample)
+	ticker := time.NewTicker(availabilityPollInterval)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+	for range ticker.C {
+		infos, err := allEdgeInfo()
+		if err != nil {
+			continue
+		}
+		edges := make(map[string]int, len(infos))
+		for mac, info := range infos {
+			edges[mac] = info.LastSeen
+		}
+		recordAvailability(edges, prev)
+		recordLiveAttributes(infos)
+		if time.Since(lastPrune) > time.Hour {
+			days := settingIntValue("availability_retention_days")
+			db.Where("hour < ?", time.Now().AddDate(0, 0, -days)).Delete(&models.NodeAvailability{})
+			lastPrune = time.Now()
+		}
+	}
+}
+
+// reliabilityScores 返回各节点自 since 起被 supernode 看到的轮询比例（百分比），没有样本的节点不在结果中
+func reliabilityScores(since time.Time) map[uint]float64 {
+	var rows []struct {
+		NodeID uint
+		Polls  int
+		Seen   int
+	}
+	db.Model(&models.NodeAvailability{}).Select("node_id, SUM(polls) AS polls, SUM(seen) AS seen").
+		Where("hour >= ?", since.Truncate(time.Hour)).Group("node_id").Scan(&rows)
+	res := make(map[uint]float64, len(rows))
+	for _, r := range rows {
+		if r.Polls > 0 {
+			res[r.NodeID] = float64(r.Seen) * 100 / float64(r.Polls)
+		}
+	}
+	return res
+}
+
+// getNodeAvailability 返回节点按小时的在线统计和区间内的可靠性评分，?hours= 指定区间（默认 24，最多 720）
+func getNodeAvailability(c *gin.Context) {
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 || hours > 720 {
+		hours = 24
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour).Truncate(time.Hour)
+	var buckets []models.NodeAvailability
+	db.Where("node_id = ? AND hour >= ?", n.ID, since).Order("hour").Find(&buckets)
+
+	polls, seen, regs, reconnects := 0, 0, 0, 0
+	for _, b := range buckets {
+		polls += b.Polls
+		seen += b.Seen
+		regs += b.Registrations
+		reconnects += b.Reconnects
+	}
+	res := gin.H{"node_id": n.ID, "hours": hours, "polls": polls, "seen": seen,
+		"registrations": regs, "reconnects": reconnects, "history": buckets, "reliability": nil}
+	if polls > 0 {
+		res["reliability"] = float64(seen) * 100 / float64(polls)
+	}
+	c.JSON(200, res)
+}
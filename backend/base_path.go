@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+)
+
+// normalizeBasePath 规范化子路径："n2n/" -> "/n2n"，"/" 或空 -> ""
+func normalizeBasePath(p string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// rewriteAssetPaths 将前端产物中的根路径引用改写到子路径下，并在 index.html 中注入 window.__N2N_BASE_PATH__
+func rewriteAssetPaths(name string, data []byte, base string) []byte {
+	if base == "" {
+		return data
+	}
+	switch {
+	case strings.HasSuffix(name, ".html"):
+		data = bytes.ReplaceAll(data, []byte(`href="/`), []byte(`href="`+base+`/`))
+		data = bytes.ReplaceAll(data, []byte(`src="/`), []byte(`src="`+base+`/`))
+		inject := []byte(`<head><script>window.__N2N_BASE_PATH__="` + base + `"</script>`)
+		data = bytes.Replace(data, []byte("<head>"), inject, 1)
+	case strings.HasSuffix(name, ".js"), strings.HasSuffix(name, ".css"):
+		for _, q := range []string{`"`, `'`, "`", "("} {
+			data = bytes.ReplaceAll(data, []byte(q+"/assets/"), []byte(q+base+"/assets/"))
+		}
+	}
+	return data
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// fieldError 请求体中单个字段的校验错误
+type fieldError struct {
+	Field   string `json:"field"`           // JSON 字段名，整体格式错误时为空
+	Rule    string `json:"rule"`            // 未通过的规则：required、min、oneof、type、json 等
+	Param   string `json:"param,omitempty"` // 规则参数，如 min=6 中的 6
+	Message string `json:"message"`
+}
+
+func init() {
+	// 校验错误中使用 JSON 字段名而不是 Go 结构体字段名
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// ruleMessages 校验规则对应的提示，%s 为规则参数
+var ruleMessages = map[string]string{
+	"required":      "This field is required",
+	"required_if":   "This field is required",
+	"min":           "Must be at least %s",
+	"max":           "Must be at most %s",
+	"len":           "Must have length %s",
+	"oneof":         "Must be one of: %s",
+	"ip":            "Must be a valid IP address",
+	"cidr":          "Must be a valid CIDR",
+	"mac":           "Must be a valid MAC address",
+	"hostname_port": "Must be host:port",
+	"gt":            "Must be greater than %s",
+	"gte":           "Must be at least %s",
+	"lte":           "Must be at most %s",
+}
+
+// bindingFieldErrors 把绑定错误转换为字段级错误，不向客户端暴露 Go 内部的类型和字段名
+func bindingFieldErrors(c *gin.Context, err error) []fieldError {
+	var ves validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	var timeErr *time.ParseError
+	switch {
+	case errors.As(err, &ves):
+		res := make([]fieldError, 0, len(ves))
+		for _, fe := range ves {
+			// Namespace 形如 Struct.field.sub，去掉顶层结构体名
+			field := fe.Namespace()
+			if i := strings.Index(field, "."); i >= 0 {
+				field = field[i+1:]
+			}
+			msg, ok := ruleMessages[fe.Tag()]
+			if !ok {
+				msg = "Invalid value"
+			}
+			if strings.Contains(msg, "%s") {
+				msg = trf(c, msg, strings.ReplaceAll(fe.Param(), " ", ", "))
+			} else {
+				msg = tr(c, msg)
+			}
+			res = append(res, fieldError{Field: field, Rule: fe.Tag(), Param: fe.Param(), Message: msg})
+		}
+		return res
+	case errors.As(err, &typeErr):
+		return []fieldError{{Field: typeErr.Field, Rule: "type", Param: typeErr.Value,
+			Message: trf(c, "Expected %s but got %s", jsonTypeName(typeErr.Type), typeErr.Value)}}
+	case errors.As(err, &timeErr):
+		return []fieldError{{Rule: "format", Message: tr(c, "Invalid time, expected RFC 3339 (e.g. 2026-01-02T15:04:05Z)")}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return []fieldError{{Rule: "json", Message: tr(c, "Malformed JSON body")}}
+	case errors.Is(err, io.EOF):
+		return []fieldError{{Rule: "json", Message: tr(c, "Request body is empty")}}
+	}
+	return []fieldError{{Rule: "invalid", Message: tr(c, "Invalid request")}}
+}
+
+// jsonTypeName 把 Go 类型描述为 JSON 类型名
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
+
+// bindJSON 绑定并校验 JSON 请求体，失败时返回 400 和字段级错误：
+//
+//	{"error": "Invalid request", "code": "INVALID_REQUEST", "fields": [{"field", "rule", "message"}]}
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		respondError(c, 400, ErrInvalidRequest, "Invalid request", gin.H{"fields": bindingFieldErrors(c, err)})
+		return false
+	}
+	return true
+}
+
+// bindOptionalJSON 与 bindJSON 相同，但允许请求体为空（所有参数可选的接口）
+func bindOptionalJSON(c *gin.Context, obj interface{}) bool {
+	if c.Request.Body == nil || c.Request.ContentLength == 0 {
+		return true
+	}
+	err := c.ShouldBindJSON(obj)
+	if err == nil || errors.Is(err, io.EOF) {
+		return true
+	}
+	respondError(c, 400, ErrInvalidRequest, "Invalid request", gin.H{"fields": bindingFieldErrors(c, err)})
+	return false
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// isMacBlocked 判断规范化后的 MAC 是否在封禁列表中
+func isMacBlocked(mac string) bool {
+	var count int64
+	db.Model(&models.BlockedMac{}).Where("mac_address = ?", mac).Count(&count)
+	return count > 0
+}
+
+// blockedMacSet 返回所有封禁的 MAC
+func blockedMacSet() map[string]bool {
+	var items []models.BlockedMac
+	db.Find(&items)
+	res := make(map[string]bool, len(items))
+	for _, b := range items {
+		res[b.MacAddress] = true
+	}
+	return res
+}
+
+// banEdge 封禁 MAC、禁用对应节点并尝试将其从 supernode 注册表中移除
+func banEdge(mac, reason string) gin.H {
+	db.Where("mac_address = ?", mac).Assign(models.BlockedMac{Reason: reason}).FirstOrCreate(&models.BlockedMac{MacAddress: mac})
+
+	var node models.Node
+	instanceID := uint(0)
+	if err := db.Where("mac_address = ?", mac).Limit(1).Find(&node).Error; err == nil && node.ID != 0 {
+		db.Model(&node).Update("is_enabled", false)
+		instanceID = nodeInstanceID(node)
+		if err := syncCommunityList(); err != nil {
+			log.Printf("Failed to sync community list after banning %s: %v", mac, err)
+		}
+	}
+
+	res := gin.H{"mac_address": mac, "blocked": true}
+	dropEdgeRegistration(mac, instanceID, res)
+	return res
+}
+
+// nodeInstanceID 返回节点所属社区的 supernode 实例 ID，社区不存在时为 0（默认实例）
+func nodeInstanceID(n models.Node) uint {
+	var comm models.Community
+	if db.Where("name = ?", n.Community).Limit(1).Find(&comm); comm.ID != 0 {
+		return communityInstanceID(comm)
+	}
+	return 0
+}
+
+// dropEdgeRegistration 尝试将 edge 从 supernode 注册表中移除，结果写入 res 的 dropped / drop_error
+func dropEdgeRegistration(mac string, instanceID uint, res gin.H) {
+	res["dropped"] = false
+	rt := runtimeFor(instanceID)
+	if rt == nil {
+		return
+	}
+	err := rt.client.DropEdge(utils.MAC(mac).Format())
+	switch {
+	case err == nil:
+		res["dropped"] = true
+		rt.client.InvalidateCache()
+	case errors.Is(err, utils.ErrUnsupported):
+		res["drop_error"] = "当前 supernode 不支持通过管理端口移除 edge，请重启 supernode 或轮换社区密码"
+	default:
+		res["drop_error"] = err.Error()
+	}
+}
+
+func banNode(c *gin.Context) {
+	var p struct {
+		Reason string `json:"reason"`
+	}
+	if !bindOptionalJSON(c, &p) {
+		return
+	}
+	var n models.Node
+	if err := db.First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	c.JSON(200, banEdge(n.MacAddress, p.Reason))
+}
+
+// banMac 封禁未纳管的 edge
+func banMac(c *gin.Context) {
+	var p struct {
+		Reason string `json:"reason"`
+	}
+	if !bindOptionalJSON(c, &p) {
+		return
+	}
+	if err := validateMacAddress(c.Param("mac")); err != nil {
+		respondErr(c, 400, err, ErrInvalidMac)
+		return
+	}
+	mac := utils.NormalizeMac(c.Param("mac"))
+	c.JSON(200, banEdge(mac, p.Reason))
+}
+
+func getBlocklist(c *gin.Context) {
+	var items []models.BlockedMac
+	db.Order("id desc").Find(&items)
+	c.JSON(200, items)
+}
+
+func deleteBlocklistEntry(c *gin.Context) {
+	db.Delete(&models.BlockedMac{}, c.Param("id"))
+	c.JSON(200, gin.H{"message": "deleted"})
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 多实例部署：多个面板实例共享同一个数据库时，所有实例都提供 API，但轮询、调度、日志分析等后台任务
+// 只能由一个 leader 运行，否则告警、统计与自动处理会重复执行。N2N_CLUSTER_ROLE 指定本实例的角色：
+//   - standalone（默认）：单实例部署，运行全部后台任务
+//   - auto：通过数据库中的租约（cluster_leases）选举 leader，leader 每 leaderRenewInterval 续约，
+//     超过 leaderLeaseTTL 未续约时其他 auto 实例接管；失去租约的 leader 退出进程，由 systemd 等重启后重新加入
+//   - follower：只提供 API，从不成为 leader
+// 只在 leader 上运行的任务通过 runLeaderWorker 启动，实例在成为 leader 时才启动它们。
+// 各实例还会登记成员租约（member:<名称>），GET /api/admin/cluster 列出在线的实例与当前 leader。
+// 集群中各实例需设置相同的 N2N_ADMIN_SECRET 与加密密钥，登录令牌和加密字段才能互通。
+
+const (
+	clusterRoleStandalone = "standalone"
+	clusterRoleAuto       = "auto"
+	clusterRoleFollower   = "follower"
+
+	leaderLeaseName     = "leader"
+	memberLeasePrefix   = "member:"
+	leaderLeaseTTL      = 30 * time.Second
+	leaderRenewInterval = 10 * time.Second
+)
+
+var (
+	clusterLeader   atomic.Bool // 本实例当前是否运行后台任务，standalone 始终为 true
+	leaderWorkers   []leaderWorker
+	leaderWorkersMu sync.Mutex // 保护 leaderWorkers，并保证任务在成为 leader 时只启动一次
+)
+
+// leaderWorker 等待本实例成为 leader 后再启动的后台任务
+type leaderWorker struct {
+	name string
+	fn   func()
+}
+
+// isLeader 本实例是否运行后台任务
+func isLeader() bool {
+	return clusterLeader.Load()
+}
+
+// runLeaderWorker 在 leader 上运行后台任务；本实例不是 leader 时登记下来，成为 leader 时启动
+func runLeaderWorker(name string, fn func()) {
+	leaderWorkersMu.Lock()
+	defer leaderWorkersMu.Unlock()
+	if isLeader() {
+		runWorker(name, fn)
+		return
+	}
+	leaderWorkers = append(leaderWorkers, leaderWorker{name, fn})
+}
+
+// initCluster 校验集群配置，auto 模式下先尝试获取一次租约，使单个实例启动后立即成为 leader
+func initCluster() {
+	switch appConfig.ClusterRole {
+	case clusterRoleStandalone:
+		clusterLeader.Store(true)
+		return
+	case clusterRoleAuto, clusterRoleFollower:
+	default:
+		log.Fatalf("N2N_CLUSTER_ROLE must be standalone, auto or follower, got %q", appConfig.ClusterRole)
+	}
+	if appConfig.ClusterMember == "" {
+		host, _ := os.Hostname()
+		appConfig.ClusterMember = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if !appConfig.JWTSecretFromEnv {
+		log.Println("[集群] 未设置 N2N_ADMIN_SECRET，各实例签发的登录令牌互不通用，请为所有实例设置相同的密钥")
+	}
+	log.Printf("[集群] 角色 %s，成员名 %s", appConfig.ClusterRole, appConfig.ClusterMember)
+	renewMemberLease()
+	if appConfig.ClusterRole == clusterRoleAuto {
+		if ok, err := acquireLease(leaderLeaseName, leaderLeaseTTL); err != nil {
+			log.Printf("[集群] 获取 leader 租约失败: %v", err)
+		} else if ok {
+			log.Println("[集群] 本实例成为 leader")
+			clusterLeader.Store(true)
+			recordSystemAudit("cluster.leader", appConfig.ClusterMember, "")
+		}
+	}
+	runWorker("cluster_lease", startClusterLease)
+}
+
+// acquireLease 获取或续约租约：租约不存在、已过期或由本实例持有时成功
+func acquireLease(name string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	member := appConfig.ClusterMember
+	res := db.Model(&models.ClusterLease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, member, now).
+		Updates(map[string]interface{}{
+			"acquired_at": gorm.Expr("CASE WHEN holder = ? THEN acquired_at ELSE ? END", member, now),
+			"holder":      member,
+			"expires_at":  now.Add(ttl),
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return true, nil
+	}
+	res = db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.ClusterLease{Name: name, Holder: member, AcquiredAt: now, ExpiresAt: now.Add(ttl)})
+	return res.Error == nil && res.RowsAffected > 0, res.Error
+}
+
+// renewMemberLease 登记本实例在线，只用于列出集群成员
+func renewMemberLease() {
+	if _, err := acquireLease(memberLeasePrefix+appConfig.ClusterMember, leaderLeaseTTL); err != nil {
+		log.Printf("[集群] 更新成员租约失败: %v", err)
+	}
+}
+
+// becomeLeader 启动登记的后台任务和各 supernode 实例的日志分析
+func becomeLeader() {
+	leaderWorkersMu.Lock()
+	defer leaderWorkersMu.Unlock()
+	runtimeMutex.Lock()
+	clusterLeader.Store(true)
+	for _, rt := range runtimes {
+		go startLogAnalyzer(rt.ctx, rt.instance.Name, instanceLogSources(rt.instance))
+	}
+	runtimeMutex.Unlock()
+	for _, w := range leaderWorkers {
+		runWorker(w.name, w.fn)
+	}
+	leaderWorkers = nil
+	log.Println("[集群] 本实例成为 leader，已启动后台任务")
+	recordSystemAudit("cluster.leader", appConfig.ClusterMember, "")
+}
+
+// startClusterLease 定期续约成员租约，auto 模式下竞争或续约 leader 租约
+func startClusterLease() {
+	lastRenewed := time.Now()
+	failing := false
+	ticker := time.NewTicker(leaderRenewInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		renewMemberLease()
+		if appConfig.ClusterRole != clusterRoleAuto {
+			continue
+		}
+		ok, err := acquireLease(leaderLeaseName, leaderLeaseTTL)
+		switch {
+		case ok:
+			lastRenewed, failing = time.Now(), false
+			if !isLeader() {
+				becomeLeader()
+			}
+		case isLeader() && (err == nil || time.Since(lastRenewed) >= leaderLeaseTTL):
+			// 后台任务没有停止机制，失去租约后退出进程，避免与新 leader 同时运行
+			reason := "held by another member"
+			if err != nil {
+				reason = err.Error()
+			}
+			log.Printf("[集群] 失去 leader 租约（%s），退出进程以停止后台任务", reason)
+			os.Exit(1)
+		case err != nil && !failing:
+			failing = true
+			log.Printf("[集群] 续约 leader 租约失败: %v", err)
+		}
+	}
+}
+
+// clusterMembers 未过期的成员租约与当前 leader 租约
+func clusterMembers() ([]gin.H, *models.ClusterLease) {
+	now := time.Now().UTC()
+	var leases []models.ClusterLease
+	db.Where("expires_at >= ?", now).Order("name").Find(&leases)
+	var leader *models.ClusterLease
+	for i := range leases {
+		if leases[i].Name == leaderLeaseName {
+			leader = &leases[i]
+		}
+	}
+	members := make([]gin.H, 0)
+	for _, l := range leases {
+		if name, ok := strings.CutPrefix(l.Name, memberLeasePrefix); ok {
+			members = append(members, gin.H{"name": name, "since": l.AcquiredAt, "leader": leader != nil && leader.Holder == name})
+		}
+	}
+	return members, leader
+}
+
+// clusterHealth 集群模式下 /api/health 的 cluster 检查，没有 leader 时降级
+func clusterHealth() (healthCheck, bool) {
+	if appConfig.ClusterRole == clusterRoleStandalone {
+		return healthCheck{}, false
+	}
+	_, leader := clusterMembers()
+	detail := gin.H{"role": appConfig.ClusterRole, "member": appConfig.ClusterMember, "leader": isLeader()}
+	if leader == nil {
+		return healthCheck{Status: healthDegraded, Message: "no leader holds the lease, background tasks are not running", Detail: detail}, true
+	}
+	detail["leader_member"] = leader.Holder
+	return healthCheck{Status: healthOK, Detail: detail}, true
+}
+
+// getClusterStatus 本实例的角色、在线的成员与当前 leader
+func getClusterStatus(c *gin.Context) {
+	res := gin.H{"role": appConfig.ClusterRole, "member": appConfig.ClusterMember, "leader": isLeader()}
+	if appConfig.ClusterRole != clusterRoleStandalone {
+		members, leader := clusterMembers()
+		res["members"] = members
+		res["leader_lease"] = leader
+	}
+	c.JSON(200, res)
+}
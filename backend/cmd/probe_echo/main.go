@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net"
+)
+
+// 安装在 edge 所在主机上的 UDP 回显程序，供 n2n-admin 测量公网往返延迟。
+// 只回显带有 N2N-PROBE 前缀的报文且原样大小返回，不会被用作流量放大。
+func main() {
+	listen := flag.String("l", ":56460", "监听地址")
+	flag.Parse()
+
+	conn, err := net.ListenPacket("udp", *listen)
+	if err != nil {
+		fmt.Printf("监听失败: %v\n", err)
+		return
+	}
+	defer conn.Close()
+	fmt.Printf("回显服务已启动: %s\n", *listen)
+
+	buf := make([]byte, 128)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			continue
+		}
+		if !bytes.HasPrefix(buf[:n], []byte("N2N-PROBE")) {
+			continue
+		}
+		conn.WriteTo(buf[:n], addr)
+	}
+}
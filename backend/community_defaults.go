@@ -0,0 +1,105 @@
+package main
+
+import (
+	"n2n_ui/backend/models"
+	"net"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// communityDefaults 社区级的 edge 默认选项
+type communityDefaults struct {
+	DefaultEncryption  string `json:"default_encryption"`
+	DefaultCompression bool   `json:"default_compression"`
+	MTU                int    `json:"mtu"`
+	SupernodeOverride  string `json:"supernode_override"`
+	PortStart          int    `json:"port_start"`
+	PortEnd            int    `json:"port_end"`
+}
+
+func defaultsOf(comm models.Community) communityDefaults {
+	return communityDefaults{comm.DefaultEncryption, comm.DefaultCompression, comm.MTU, comm.SupernodeOverride, comm.PortStart, comm.PortEnd}
+}
+
+// validate 校验默认选项，创建社区和修改默认选项时共用
+func (d communityDefaults) validate() error {
+	if err := validateEncryption(d.DefaultEncryption); err != nil {
+		return err
+	}
+	if d.MTU != 0 && (d.MTU < 500 || d.MTU > 9000) {
+		return newAPIError(ErrInvalidRequest, "MTU must be between 500 and 9000")
+	}
+	if d.PortStart != 0 || d.PortEnd != 0 {
+		if d.PortStart < 1 || d.PortEnd > 65535 || d.PortStart > d.PortEnd {
+			return newAPIError(ErrInvalidPort, "Invalid port range")
+		}
+	}
+	if d.SupernodeOverride != "" {
+		host, port, err := net.SplitHostPort(d.SupernodeOverride)
+		if p, perr := strconv.Atoi(port); err != nil || host == "" || perr != nil || p < 1 || p > 65535 {
+			return newAPIError(ErrInvalidRequest, "Supernode address must be host:port")
+		}
+	}
+	return nil
+}
+
+// applyCommunityDefaults 用社区默认值填充新节点未指定的选项，compression 为 nil 表示请求中未指定
+func applyCommunityDefaults(n *models.Node, comm models.Community, compression *bool) {
+	if n.Encryption == "" {
+		n.Encryption = comm.DefaultEncryption
+	}
+	n.Compression = comm.DefaultCompression
+	if compression != nil {
+		n.Compression = *compression
+	}
+}
+
+// refreshCommunityConfigs 社区影响配置的字段修改后，重新计算该社区所有节点的配置差异
+func refreshCommunityConfigs(name string) {
+	var nodes []models.Node
+	db.Where("community = ? AND issued_config <> ''", name).Find(&nodes)
+	for _, n := range nodes {
+		refreshConfigState(&n)
+		db.Model(&n).Updates(map[string]interface{}{"config_diff": n.ConfigDiff, "config_outdated": n.ConfigOutdated})
+	}
+}
+
+// refreshAllConfigs 全局设置影响配置时，重新计算所有已下发节点的配置差异
+func refreshAllConfigs() {
+	var nodes []models.Node
+	db.Where("issued_config <> ''").Find(&nodes)
+	for _, n := range nodes {
+		refreshConfigState(&n)
+		db.Model(&n).Updates(map[string]interface{}{"config_diff": n.ConfigDiff, "config_outdated": n.ConfigOutdated})
+	}
+}
+
+// updateCommunityDefaults 修改社区的 edge 默认选项和本地端口范围
+// 加密和压缩只影响之后新建的节点；MTU 和 supernode 地址会改变已有节点的配置，已下发的节点会被标记为配置过期
+func updateCommunityDefaults(c *gin.Context) {
+	var comm models.Community
+	if err := db.First(&comm, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrCommunityNotFound, "Community not found")
+		return
+	}
+	var p communityDefaults
+	if !bindJSON(c, &p) {
+		return
+	}
+	if err := p.validate(); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return
+	}
+	db.Model(&comm).Updates(map[string]interface{}{
+		"default_encryption":  p.DefaultEncryption,
+		"default_compression": p.DefaultCompression,
+		"mtu":                 p.MTU,
+		"supernode_override":  p.SupernodeOverride,
+		"port_start":          p.PortStart,
+		"port_end":            p.PortEnd,
+	})
+	db.First(&comm, comm.ID)
+	refreshCommunityConfigs(comm.Name)
+	c.JSON(200, comm)
+}
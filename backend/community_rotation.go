@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 社区密码轮换：POST /api/communities/:id/rotate-password 生成新的随机密码，在过渡期（grace_minutes，
+// 默认为设置 community_password_grace_minutes）结束时生效，grace_minutes 为 0 时立即生效。
+//   - 过渡期内 GET /api/agent/config 与 GET /api/nodes/:id/config 额外返回 pending：使用新密码的配置、hash 与生效时间，
+//     edge 代理可提前取得并在生效时切换，没有代理的节点也可以提前手动下发
+//   - 生效后（password_rotator 每分钟检查）新密码写入社区，已下发的节点被标记为配置过期，
+//     仍需手动重新下发的节点见 /api/nodes/outdated-configs，并通知管理员
+//   - 过渡期内可通过 DELETE 同一路径取消
+// 发起、取消与生效都记录在审计日志中（不含密码）。
+
+const (
+	rotatedPasswordLength    = 24
+	passwordRotationInterval = time.Minute
+	passwordRotatedAlertType = "password_rotated"
+)
+
+// pendingConfig 密码轮换过渡期内使用新密码的配置
+type pendingConfig struct {
+	Conf    string    `json:"conf"`
+	Hash    string    `json:"hash"`
+	ApplyAt time.Time `json:"apply_at"`
+}
+
+// pendingNodeConfig 节点所属社区有待生效的密码轮换时返回新配置，否则返回 nil
+func pendingNodeConfig(n models.Node) *pendingConfig {
+	comm, err := lookupCommunity(n.Community)
+	if err != nil || comm.PasswordRotateAt == nil {
+		return nil
+	}
+	comm.Password = comm.PendingPassword
+	conf := renderNodeConfigFor(n, comm)
+	return &pendingConfig{Conf: conf, Hash: configHash(conf), ApplyAt: *comm.PasswordRotateAt}
+}
+
+// communityDeliveryCounts 社区中需要新配置的节点数：agent 由代理自动更新，manual 需要手动下发
+func communityDeliveryCounts(name string) (agent, manual int64) {
+	q := db.Model(&models.Node{}).Where("community = ? AND pending_approval = ?", name, false)
+	q.Session(&gorm.Session{}).Where("agent_token <> ''").Count(&agent)
+	q.Session(&gorm.Session{}).Where("agent_token = '' OR agent_token IS NULL").Count(&manual)
+	return agent, manual
+}
+
+// applyPasswordRotation 使待生效的密码生效，并标记已下发节点的配置过期
+func applyPasswordRotation(comm models.Community) error {
+	err := db.Model(&comm).Updates(map[string]interface{}{
+		"password":           comm.PendingPassword,
+		"pending_password":   "",
+		"password_rotate_at": nil,
+	}).Error
+	if err != nil {
+		return err
+	}
+	refreshCommunityConfigs(comm.Name)
+	var manual int64
+	db.Model(&models.Node{}).Where("community = ? AND config_outdated = ? AND (agent_token = '' OR agent_token IS NULL)", comm.Name, true).Count(&manual)
+	recordSystemAudit("community.password_rotated", comm.Name, fmt.Sprintf("%d node(s) need manual re-provisioning", manual))
+	log.Printf("Community %s: new password is in effect, %d node(s) need manual re-provisioning", comm.Name, manual)
+	if manual > 0 {
+		dispatchAlert(Alert{Type: passwordRotatedAlertType, Level: "warning", AdminOnly: true, Title: "社区 " + comm.Name + " 的密码已轮换",
+			Message: fmt.Sprintf("新密码已生效，%d 个没有 edge 代理的节点需要手动重新下发配置", manual)})
+	}
+	return nil
+}
+
+// rotateCommunityPassword 为社区生成新的随机密码，过渡期结束后生效
+func rotateCommunityPassword(c *gin.Context) {
+	var comm models.Community
+	if err := db.First(&comm, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrCommunityNotFound, "Community not found")
+		return
+	}
+	var p struct {
+		GraceMinutes *int `json:"grace_minutes" binding:"omitempty,min=0,max=10080"`
+	}
+	if !bindOptionalJSON(c, &p) {
+		return
+	}
+	if comm.PasswordRotateAt != nil {
+		respondError(c, 409, ErrRotationPending, "A password rotation is already scheduled")
+		return
+	}
+	grace := settingIntValue("community_password_grace_minutes")
+	if p.GraceMinutes != nil {
+		grace = *p.GraceMinutes
+	}
+	password := generateRandomPassword(rotatedPasswordLength)
+	applyAt := time.Now().Add(time.Duration(grace) * time.Minute)
+	agent, manual := communityDeliveryCounts(comm.Name)
+	comm.PendingPassword = models.EncryptedString(password)
+	if grace == 0 {
+		if err := applyPasswordRotation(comm); err != nil {
+			respondError(c, 500, ErrInternal, "Failed to rotate password")
+			return
+		}
+	} else if err := db.Model(&comm).Updates(map[string]interface{}{"pending_password": comm.PendingPassword, "password_rotate_at": applyAt}).Error; err != nil {
+		respondError(c, 500, ErrInternal, "Failed to rotate password")
+		return
+	}
+	recordAudit(c, "community.rotate_password", comm.Name, fmt.Sprintf("grace %d min, %d node(s) with agent, %d without", grace, agent, manual))
+	c.JSON(200, gin.H{"community": comm.Name, "password": password, "apply_at": applyAt, "applied": grace == 0,
+		"nodes": gin.H{"agent": agent, "manual": manual}})
+}
+
+// cancelPasswordRotation 取消尚未生效的密码轮换
+func cancelPasswordRotation(c *gin.Context) {
+	var comm models.Community
+	if err := db.First(&comm, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrCommunityNotFound, "Community not found")
+		return
+	}
+	if comm.PasswordRotateAt == nil {
+		respondError(c, 409, ErrNoRotationPending, "No password rotation is scheduled")
+		return
+	}
+	db.Model(&comm).Updates(map[string]interface{}{"pending_password": "", "password_rotate_at": nil})
+	recordAudit(c, "community.rotate_password_cancel", comm.Name, "")
+	c.JSON(200, gin.H{"message": tr(c, "Password rotation canceled")})
+}
+
+// startPasswordRotator 定期使到期的密码轮换生效
+func startPasswordRotator() {
+	for {
+		var comms []models.Community
+		db.Where("password_rotate_at IS NOT NULL AND password_rotate_at <= ?", time.Now()).Find(&comms)
+		for _, comm := range comms {
+			if err := applyPasswordRotation(comm); err != nil {
+				log.Printf("Community %s: failed to apply password rotation: %v", comm.Name, err)
+			}
+		}
+		time.Sleep(passwordRotationInterval)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"math/big"
+	"n2n_ui/backend/models"
+	"net"
+
+	"github.com/gin-gonic/gin"
+)
+
+const communitySummaryEvents = 20
+
+// ipUtilization 统计网段内可分配地址数与已分配数
+func ipUtilization(cidr string, nodes []models.Node) gin.H {
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil
+	}
+	ones, bits := ipnet.Mask.Size()
+	total := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	if bits == 32 && bits-ones >= 2 {
+		total.Sub(total, big.NewInt(2)) // 去掉网络地址和广播地址
+	}
+	used := 0
+	for _, n := range nodes {
+		if ip := net.ParseIP(n.IPAddress); ip != nil && ipnet.Contains(ip) {
+			used++
+		}
+	}
+	res := gin.H{"range": cidr, "total": total.String(), "used": used}
+	if total.IsInt64() && total.Int64() > 0 {
+		res["used_pct"] = float64(used) * 100 / float64(total.Int64())
+	}
+	return res
+}
+
+// getCommunitySummary 返回单个社区的概览：节点数、在线数、网段使用率、中转流量和最近事件
+func getCommunitySummary(c *gin.Context) {
+	var comm models.Community
+	if err := scopeCommunities(c).First(&comm, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrCommunityNotFound, "Community not found")
+		return
+	}
+	var nodes []models.Node
+	db.Where("community = ?", comm.Name).Find(&nodes)
+	edges, err := allEdgeInfo()
+
+	macs := make(map[string]bool, len(nodes))
+	online, enabled := 0, 0
+	for _, n := range nodes {
+		m := n.MacAddress
+		macs[m] = true
+		if _, ok := edges[m]; ok {
+			online++
+		}
+		if nodeAccessActive(n) {
+			enabled++
+		}
+	}
+	unregistered := 0
+	for mac, info := range edges {
+		if info.Community == comm.Name && !macs[mac] {
+			unregistered++
+		}
+	}
+
+	// supernode 不提供按社区的流量统计，这里用日志中解析出的中转报文数近似
+	relayPairs, relayPackets := 0, int64(0)
+	for _, ev := range collectActiveRelays() {
+		if macs[ev.SrcMac] || macs[ev.DstMac] {
+			relayPairs++
+			relayPackets += ev.PktCount
+		}
+	}
+
+	events := make([]supernodeEvent, 0)
+	logEventMutex.Lock()
+	for i := len(logEvents) - 1; i >= 0 && len(events) < communitySummaryEvents; i-- {
+		ev := logEvents[i]
+		if ev.Community == comm.Name || (ev.Mac != "" && macs[ev.Mac]) {
+			events = append(events, ev)
+		}
+	}
+	logEventMutex.Unlock()
+
+	c.JSON(200, gin.H{
+		"community":          comm,
+		"node_count":         len(nodes),
+		"enabled_count":      enabled,
+		"online_count":       online,
+		"unregistered_count": unregistered,
+		"ip_utilization":     ipUtilization(comm.Range, nodes),
+		"traffic":            gin.H{"active_relay_pairs": relayPairs, "relay_packets": relayPackets},
+		"recent_events":      events,
+		"mgmt_error":         errString(err),
+	})
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/utils"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// community.list 校对：API 修改社区或节点密钥时立即重写文件，此外 leader 上的 community_reconciler
+//   - 每 community_watch_seconds 秒检查各实例 community.list 的修改时间与大小，文件被手动修改或删除时立即校对
+//   - syncCommunityList 写入失败时立即校对，不再静默留下偏差
+//   - 每 communityReconcileInterval 全量校对一次，同时发现 supernode 上未登记的社区
+// 校对对比社区名称以及启用用户认证的社区下的用户公钥行，community_autoheal=true 时按数据库重写文件。
+// 偏差状态变化时发送 community_drift 告警，并在审计日志中记录 community.drift / community.resync / community.in_sync，
+// 可在 /api/events 中查询。GET /api/communities/reconcile 只检查，POST 立即校对并修复，均可用 ?instance= 指定实例。
+
+const communityReconcileInterval = 5 * time.Minute
+
+// 触发校对的原因，记录在报告与事件中
+const (
+	reconcileTriggerSchedule    = "schedule"
+	reconcileTriggerFileChange  = "file_change"
+	reconcileTriggerWriteFailed = "write_failed"
+	reconcileTriggerManual      = "manual"
+)
+
+// CommunityDriftReport 数据库、community.list 与 supernode 实际状态的对比结果
+type CommunityDriftReport struct {
+	InstanceID    uint      `json:"instance_id"`
+	Instance      string    `json:"instance"`
+	CheckedAt     time.Time `json:"checked_at"`
+	Trigger       string    `json:"trigger,omitempty"`
+	InSync        bool      `json:"in_sync"`
+	MissingInFile []string  `json:"missing_in_file"` // 数据库中有但文件中没有
+	ExtraInFile   []string  `json:"extra_in_file"`   // 文件中有但数据库中没有（手动编辑）
+	AuthDrift     []string  `json:"auth_drift"`      // 用户公钥行与数据库不一致的社区
+	UnknownLive   []string  `json:"unknown_live"`    // supernode 上活跃但数据库中不存在
+	ReadError     string    `json:"read_error,omitempty"`
+	Healed        bool      `json:"healed"`
+	HealError     string    `json:"heal_error,omitempty"`
+}
+
+var (
+	lastDriftReports = make(map[uint]*CommunityDriftReport)
+	driftMutex       sync.Mutex
+
+	// communityReconcileNow 请求立即校对，缓冲一个请求，重复的请求合并
+	communityReconcileNow = make(chan struct{}, 1)
+)
+
+// requestCommunityReconcile 请求 community_reconciler 立即校对，不阻塞
+func requestCommunityReconcile() {
+	select {
+	case communityReconcileNow <- struct{}{}:
+	default:
+	}
+}
+
+// parseCommunityEntries 解析 community.list 的内容，返回社区名称到其用户行的映射。
+// 用户行以 "*" 开头，属于前面最近的社区，按空白规范化后排序，便于比较
+func parseCommunityEntries(lines []string) map[string][]string {
+	entries := make(map[string][]string)
+	current := ""
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "*") {
+			if current != "" {
+				entries[current] = append(entries[current], strings.Join(strings.Fields(line), " "))
+			}
+			continue
+		}
+		// 与 utils.ReadCommunityList 一致，名称后可以跟网段
+		current = strings.Fields(line)[0]
+		if _, ok := entries[current]; !ok {
+			entries[current] = []string{}
+		}
+	}
+	for _, users := range entries {
+		sort.Strings(users)
+	}
+	return entries
+}
+
+// checkCommunityDrift 对比某个实例的三方社区列表
+func checkCommunityDrift(rt *instanceRuntime) *CommunityDriftReport {
+	report := &CommunityDriftReport{
+		InstanceID: rt.instance.ID, Instance: rt.instance.Name, CheckedAt: time.Now(),
+		MissingInFile: []string{}, ExtraInFile: []string{}, AuthDrift: []string{}, UnknownLive: []string{},
+	}
+
+	want := parseCommunityEntries(communityListLines(db, rt.instance.ID))
+	dbSet := make(map[string]bool)
+	for name := range want {
+		dbSet[name] = true
+	}
+
+	fileSet := make(map[string]bool)
+	var have map[string][]string
+	if data, err := os.ReadFile(rt.instance.CommunityListPath); err != nil {
+		report.ReadError = err.Error()
+	} else {
+		have = parseCommunityEntries(strings.Split(string(data), "\n"))
+		for name := range have {
+			fileSet[name] = true
+		}
+	}
+
+	for name := range dbSet {
+		if !fileSet[name] {
+			report.MissingInFile = append(report.MissingInFile, name)
+		}
+	}
+	for name := range fileSet {
+		if !dbSet[name] {
+			report.ExtraInFile = append(report.ExtraInFile, name)
+		}
+	}
+	for name, users := range want {
+		if fileSet[name] && strings.Join(users, "\n") != strings.Join(have[name], "\n") {
+			report.AuthDrift = append(report.AuthDrift, name)
+		}
+	}
+	if edges, err := rt.client.GetEdgeInfo(); err == nil {
+		liveSet := make(map[string]bool)
+		for _, e := range edges {
+			if e.Community != "" && !dbSet[e.Community] {
+				liveSet[e.Community] = true
+			}
+		}
+		for name := range liveSet {
+			report.UnknownLive = append(report.UnknownLive, name)
+		}
+	}
+	sort.Strings(report.MissingInFile)
+	sort.Strings(report.ExtraInFile)
+	sort.Strings(report.AuthDrift)
+	sort.Strings(report.UnknownLive)
+	report.InSync = !fileDrifted(report) && len(report.UnknownLive) == 0
+	return report
+}
+
+// fileDrifted community.list 与数据库不一致，可以通过重写文件修复
+func fileDrifted(r *CommunityDriftReport) bool {
+	return r.ReadError != "" || len(r.MissingInFile) > 0 || len(r.ExtraInFile) > 0 || len(r.AuthDrift) > 0
+}
+
+// reconcileCommunities 检查所有实例的偏差，按需重写 community.list
+func reconcileCommunities(heal bool, trigger string) []*CommunityDriftReport {
+	reports := make([]*CommunityDriftReport, 0)
+	for _, rt := range listRuntimes() {
+		reports = append(reports, reconcileInstance(rt, heal, trigger))
+	}
+	return reports
+}
+
+// reconcileInstance 检查一个实例的偏差，按需重写 community.list，偏差状态变化时告警并记录事件
+func reconcileInstance(rt *instanceRuntime, heal bool, trigger string) *CommunityDriftReport {
+	report := checkCommunityDrift(rt)
+	report.Trigger = trigger
+	if heal && fileDrifted(report) {
+		if err := utils.WriteCommunityList(rt.instance.CommunityListPath, communityListLines(db, rt.instance.ID)); err != nil {
+			report.HealError = err.Error()
+		} else {
+			report.Healed = true
+		}
+	}
+
+	driftMutex.Lock()
+	prev := lastDriftReports[rt.instance.ID]
+	lastDriftReports[rt.instance.ID] = report
+	driftMutex.Unlock()
+
+	name := rt.instance.Name
+	switch {
+	// 仅在状态变化时告警，避免每个周期重复通知
+	case !report.InSync && (prev == nil || driftSignature(prev) != driftSignature(report)):
+		dispatchAlert(Alert{Type: "community_drift", Level: "warning", Title: "社区列表与数据库不一致: " + name, Message: describeDrift(report)})
+		recordSystemAudit("community.drift", name, "触发: "+trigger+"\n"+describeDrift(report))
+		if report.Healed && trigger != reconcileTriggerManual {
+			recordSystemAudit("community.resync", name, "按数据库重写 community.list")
+		}
+	case report.InSync && prev != nil && !prev.InSync:
+		recordSystemAudit("community.in_sync", name, "")
+	}
+	return report
+}
+
+func driftSignature(r *CommunityDriftReport) string {
+	return fmt.Sprintf("%v|%v|%v|%v|%s|%s", r.MissingInFile, r.ExtraInFile, r.AuthDrift, r.UnknownLive, r.ReadError, r.HealError)
+}
+
+func describeDrift(r *CommunityDriftReport) string {
+	var parts []string
+	if r.ReadError != "" {
+		parts = append(parts, "读取 community.list 失败: "+r.ReadError)
+	}
+	if len(r.MissingInFile) > 0 {
+		parts = append(parts, "文件中缺少: "+strings.Join(r.MissingInFile, ", "))
+	}
+	if len(r.ExtraInFile) > 0 {
+		parts = append(parts, "文件中多出: "+strings.Join(r.ExtraInFile, ", "))
+	}
+	if len(r.AuthDrift) > 0 {
+		parts = append(parts, "用户认证公钥不一致: "+strings.Join(r.AuthDrift, ", "))
+	}
+	if len(r.UnknownLive) > 0 {
+		parts = append(parts, "supernode 上存在未登记社区: "+strings.Join(r.UnknownLive, ", "))
+	}
+	if r.Healed {
+		parts = append(parts, "已自动重写 community.list")
+	}
+	if r.HealError != "" {
+		parts = append(parts, "自动修复失败: "+r.HealError)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// fileStamp community.list 的修改时间与大小，文件不存在时 exists 为 false
+type fileStamp struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) fileStamp {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{exists: true, modTime: fi.ModTime(), size: fi.Size()}
+}
+
+// communityWatchInterval 检查 community.list 是否变化的间隔，0 为不检查
+func communityWatchInterval() time.Duration {
+	v, err := strconv.Atoi(getSettingValue("community_watch_seconds", "10"))
+	if err != nil || v < 0 {
+		v = 10
+	}
+	return time.Duration(v) * time.Second
+}
+
+func communityAutoheal() bool {
+	return getSettingValue("community_autoheal", "true") == "true"
+}
+
+func logDrift(reports ...*CommunityDriftReport) {
+	for _, report := range reports {
+		if !report.InSync {
+			log.Printf("Community reconcile [%s, %s]: %s", report.Instance, report.Trigger, strings.ReplaceAll(describeDrift(report), "\n", "; "))
+		}
+	}
+}
+
+// startCommunityReconciler 监视 community.list 的变化并定期校对，community_autoheal=false 时只告警不修复
+func startCommunityReconciler() {
+	stamps := make(map[uint]fileStamp)
+	lastFull := time.Time{}
+	for {
+		if time.Since(lastFull) >= communityReconcileInterval {
+			logDrift(reconcileCommunities(communityAutoheal(), reconcileTriggerSchedule)...)
+			lastFull = time.Now()
+			// 全量校对可能重写了文件，以校对后的状态为基准
+			for _, rt := range listRuntimes() {
+				stamps[rt.instance.ID] = statFile(rt.instance.CommunityListPath)
+			}
+		}
+
+		watch := communityWatchInterval()
+		wait := time.Until(lastFull.Add(communityReconcileInterval))
+		if watch > 0 && watch < wait {
+			wait = watch
+		}
+		select {
+		case <-communityReconcileNow:
+			logDrift(reconcileCommunities(communityAutoheal(), reconcileTriggerWriteFailed)...)
+		case <-time.After(wait):
+		}
+
+		if watch == 0 {
+			continue
+		}
+		for _, rt := range listRuntimes() {
+			stamp := statFile(rt.instance.CommunityListPath)
+			prev, seen := stamps[rt.instance.ID]
+			if seen && stamp != prev {
+				logDrift(reconcileInstance(rt, communityAutoheal(), reconcileTriggerFileChange))
+				stamp = statFile(rt.instance.CommunityListPath)
+			}
+			stamps[rt.instance.ID] = stamp
+		}
+	}
+}
+
+// reconcileTargets ?instance= 指定的实例，未指定时为全部实例；实例不存在时返回错误响应
+func reconcileTargets(c *gin.Context) ([]*instanceRuntime, bool) {
+	v := c.Query("instance")
+	if v == "" {
+		return listRuntimes(), true
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	rt := runtimeFor(uint(id))
+	if err != nil || id == 0 || rt == nil {
+		respondError(c, 404, ErrInstanceNotFound, "Supernode instance not found")
+		return nil, false
+	}
+	return []*instanceRuntime{rt}, true
+}
+
+func getCommunityReconcile(c *gin.Context) {
+	targets, ok := reconcileTargets(c)
+	if !ok {
+		return
+	}
+	reports := make([]*CommunityDriftReport, 0)
+	for _, rt := range targets {
+		reports = append(reports, checkCommunityDrift(rt))
+	}
+	c.JSON(200, reports)
+}
+
+// runCommunityReconcile 立即校对并按数据库重写不一致的 community.list
+func runCommunityReconcile(c *gin.Context) {
+	targets, ok := reconcileTargets(c)
+	if !ok {
+		return
+	}
+	reports := make([]*CommunityDriftReport, 0)
+	for _, rt := range targets {
+		report := reconcileInstance(rt, true, reconcileTriggerManual)
+		if report.Healed {
+			recordAudit(c, "community.resync", rt.instance.Name, "")
+		}
+		reports = append(reports, report)
+	}
+	logDrift(reports...)
+	c.JSON(200, reports)
+}
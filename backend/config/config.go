@@ -11,25 +11,71 @@ import (
 // Config holds all application configuration
 type Config struct {
 	// Database
-	DBPath string
+	DBPath      string
+	AutoMigrate bool // 启动时执行未执行的数据库迁移，为 false 时需先运行 -migrate up
 
 	// Security
 	JWTSecret        string
 	JWTSecretFromEnv bool // 是否从环境变量读取
 	CORSOrigins      string
+	// 加密敏感字段的密钥文件，为空时使用数据库旁的 <DBPath>.key 或由 N2N_ADMIN_SECRET 派生
+	EncryptionKeyFile string
+	// 反向代理地址（逗号分隔的 IP / CIDR），只有来自这些地址的 X-Forwarded-For / X-Real-IP 才会被采信
+	TrustedProxies string
+
+	// Login brute-force protection, can be overridden by settings at runtime
+	LoginMaxAttempts  int           // 失败多少次后锁定，0 表示不锁定
+	LoginLockDuration time.Duration // 锁定时长
+	LoginRecordExpiry time.Duration // 失败记录保留时长
 
 	// n2n Management
-	MgmtAddr string
+	SupernodeUnit   string // 首次启动创建默认实例时使用的 systemd 单元名
+	SupernodeBinary string // 检测 supernode 版本时执行的程序，默认在 PATH 中查找
+	MgmtAddr        string
+	MgmtPassword    string        // n2n 3.x 管理端口写命令密码 (--management-password)
+	MgmtCacheTTL    time.Duration // edge 信息缓存时间，0 表示不缓存
+	MgmtTimeout     time.Duration // 管理端口单次请求等待响应的时间
+	MgmtRetries     int           // 管理端口无响应时的重试次数，0 表示不重试
+
+	// Supernode files
+	// 配置文件目录：首次启动时默认实例的文件位置，以及新建实例未填写路径时的默认位置；为空时为平台默认（/etc/n2n）
+	SupernodeDir      string
+	SupernodeConfPath string // 默认实例的 supernode.conf，为空时为 <SupernodeDir>/supernode.conf
+	CommunityListPath string // 默认实例的 community.list，为空时为 <SupernodeDir>/community.list
+	SystemdUnitDir    string // 一键安装 supernode 时写入 systemd 单元的目录
 
 	// Cache
 	IPCacheTTL  time.Duration
 	IPCacheSize int
 
 	// Server
-	Port string
+	Port      string
+	BasePath  string // 部署在子路径下时的 URL 前缀，如 /n2n
+	AccessLog bool   // 输出 HTTP 访问日志
+
+	// gRPC API，监听地址为空时不启用；必须配置服务端证书与用于校验客户端证书的 CA（mTLS）
+	GRPCListen   string
+	GRPCCert     string
+	GRPCKey      string
+	GRPCClientCA string
 
 	// Features
 	DisableNetTools bool // 禁用网络诊断工具
+	EnableAPIV2     bool // 启用 /api/v2（统一响应信封，预览阶段）
+	MockMode        bool // 用内置的假 supernode 代替管理端口、systemctl 与 journalctl
+
+	// Self-upgrade
+	ServiceUnit     string // 面板自身的服务名，升级后通过 systemctl / Restart-Service 重启；为空时直接重新执行新程序
+	UpdatePublicKey string // 额外信任的发布签名 Ed25519 公钥（base64），与程序内置的公钥一起使用
+	UpdateInsecure  bool   // 跳过签名校验，只校验 SHA-256；必须显式开启
+
+	// Cluster
+	ClusterRole   string // standalone（默认）、auto（通过数据库租约选出 leader）或 follower（只提供 API）
+	ClusterMember string // 本实例在集群中的名称，默认为主机名与进程号
+
+	// First run
+	SetupWizard bool   // 首次启动时不创建随机密码的 admin，而是通过 /api/setup 完成初始化
+	SetupToken  string // 不为空时 /api/setup 需在 X-Setup-Token 头中提供该令牌
 }
 
 var cfg *Config
@@ -61,15 +107,46 @@ func Load() *Config {
 	}
 
 	return &Config{
-		DBPath:           getEnv("N2N_DB_PATH", "n2n_admin.db"),
-		JWTSecret:        jwtSecret,
-		JWTSecretFromEnv: jwtFromEnv,
-		CORSOrigins:      getEnv("N2N_CORS_ORIGINS", ""),
-		MgmtAddr:         getEnv("N2N_MGMT_ADDR", "127.0.0.1:56440"),
-		IPCacheTTL:       getDurationEnv("N2N_IP_CACHE_TTL", 24*time.Hour),
-		IPCacheSize:      getIntEnv("N2N_IP_CACHE_SIZE", 1000),
-		Port:             getEnv("N2N_PORT", "8080"),
-		DisableNetTools:  !getBoolEnv("N2N_ENABLE_NET_TOOLS", false), // 默认禁用，设置 N2N_ENABLE_NET_TOOLS=true 启用
+		DBPath:            getEnv("N2N_DB_PATH", "n2n_admin.db"),
+		AutoMigrate:       getBoolEnv("N2N_AUTO_MIGRATE", true),
+		JWTSecret:         jwtSecret,
+		JWTSecretFromEnv:  jwtFromEnv,
+		EncryptionKeyFile: getEnv("N2N_ENCRYPTION_KEY_FILE", ""),
+		CORSOrigins:       getEnv("N2N_CORS_ORIGINS", ""),
+		TrustedProxies:    getEnv("N2N_TRUSTED_PROXIES", ""),
+		LoginMaxAttempts:  getIntEnv("N2N_LOGIN_MAX_ATTEMPTS", 5),
+		LoginLockDuration: getDurationEnv("N2N_LOGIN_LOCK_DURATION", 15*time.Minute),
+		LoginRecordExpiry: getDurationEnv("N2N_LOGIN_RECORD_EXPIRY", 1*time.Hour),
+		SupernodeUnit:     getEnv("N2N_SUPERNODE_UNIT", "supernode"),
+		SupernodeBinary:   getEnv("N2N_SUPERNODE_BIN", "supernode"),
+		SupernodeDir:      getEnv("N2N_SUPERNODE_DIR", ""),
+		SupernodeConfPath: getEnv("N2N_SUPERNODE_CONF", ""),
+		CommunityListPath: getEnv("N2N_COMMUNITY_LIST", ""),
+		SystemdUnitDir:    getEnv("N2N_SYSTEMD_UNIT_DIR", "/etc/systemd/system"),
+		MgmtAddr:          getEnv("N2N_MGMT_ADDR", "127.0.0.1:56440"),
+		MgmtPassword:      getEnv("N2N_MGMT_PASSWORD", ""),
+		MgmtCacheTTL:      getDurationEnv("N2N_MGMT_CACHE_TTL", 5*time.Second),
+		MgmtTimeout:       getDurationEnv("N2N_MGMT_TIMEOUT", 1*time.Second),
+		MgmtRetries:       getIntEnv("N2N_MGMT_RETRIES", 2),
+		IPCacheTTL:        getDurationEnv("N2N_IP_CACHE_TTL", 24*time.Hour),
+		IPCacheSize:       getIntEnv("N2N_IP_CACHE_SIZE", 1000),
+		Port:              getEnv("N2N_PORT", "8080"),
+		BasePath:          getEnv("N2N_BASE_PATH", ""),
+		AccessLog:         getBoolEnv("N2N_ACCESS_LOG", false),
+		GRPCListen:        getEnv("N2N_GRPC_LISTEN", ""),
+		GRPCCert:          getEnv("N2N_GRPC_CERT", ""),
+		GRPCKey:           getEnv("N2N_GRPC_KEY", ""),
+		GRPCClientCA:      getEnv("N2N_GRPC_CLIENT_CA", ""),
+		DisableNetTools:   !getBoolEnv("N2N_ENABLE_NET_TOOLS", false), // 默认禁用，设置 N2N_ENABLE_NET_TOOLS=true 启用
+		EnableAPIV2:       getBoolEnv("N2N_ENABLE_API_V2", false),
+		MockMode:          getBoolEnv("N2N_MOCK_MODE", false),
+		ServiceUnit:       getEnv("N2N_SERVICE_UNIT", ""),
+		UpdatePublicKey:   getEnv("N2N_UPDATE_PUBLIC_KEY", ""),
+		UpdateInsecure:    getBoolEnv("N2N_UPDATE_INSECURE", false),
+		ClusterRole:       getEnv("N2N_CLUSTER_ROLE", "standalone"),
+		ClusterMember:     getEnv("N2N_CLUSTER_MEMBER", ""),
+		SetupWizard:       getBoolEnv("N2N_SETUP_WIZARD", true),
+		SetupToken:        getEnv("N2N_SETUP_TOKEN", ""),
 	}
 }
 
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// nodeConflict 与新节点 MAC 或 IP 冲突的已有记录
+type nodeConflict struct {
+	Node    models.Node `json:"node"`
+	Fields  []string    `json:"fields"`  // mac / ip
+	Deleted bool        `json:"deleted"` // 已软删除但仍占用唯一索引
+}
+
+// findNodeConflicts 查找与指定 MAC 或 IP 冲突的节点（含已软删除的记录）
+func findNodeConflicts(mac, ip string) []nodeConflict {
+	var nodes []models.Node
+	db.Unscoped().Where("mac_address = ? OR (ip_address = ? AND ip_address <> '')", mac, ip).Find(&nodes)
+	res := make([]nodeConflict, 0, len(nodes))
+	for _, n := range nodes {
+		var fields []string
+		if n.MacAddress == mac {
+			fields = append(fields, "mac")
+		}
+		if ip != "" && n.IPAddress == ip {
+			fields = append(fields, "ip")
+		}
+		res = append(res, nodeConflict{Node: n, Fields: fields, Deleted: n.DeletedAt.Valid})
+	}
+	return res
+}
+
+// ipDuplicate 同一社区内多个在线 edge 使用了相同的虚拟 IP
+type ipDuplicate struct {
+	Community string        `json:"community"`
+	IP        string        `json:"ip"`
+	Macs      []string      `json:"macs"`
+	Nodes     []models.Node `json:"nodes"`
+	FirstSeen time.Time     `json:"first_seen"`
+}
+
+var (
+	ipDuplicates     = make(map[string]*ipDuplicate) // community|ip -> 冲突
+	ipDuplicateMutex sync.Mutex
+)
+
+// detectDuplicateIPs 按社区统计在线 edge 的虚拟 IP，返回被多个 MAC 同时使用的 IP
+func detectDuplicateIPs(edges map[string]utils.EdgeInfo) map[string][]string {
+	seen := make(map[string][]string)
+	for mac, info := range edges {
+		ip := strings.Split(info.Internal, "/")[0]
+		if ip == "" || ip == "0.0.0.0" {
+			continue
+		}
+		key := info.Community + "|" + ip
+		seen[key] = append(seen[key], mac)
+	}
+	for key, macs := range seen {
+		if len(macs) < 2 {
+			delete(seen, key)
+			continue
+		}
+		sort.Strings(macs)
+	}
+	return seen
+}
+
+// startDuplicateIPMonitor 定期检查在线 edge 中的重复 IP，新出现的冲突会发送告警
+func startDuplicateIPMonitor() {
+	ticker := time.NewTicker(alertCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		edges, _ := allEdgeInfo()
+		current := detectDuplicateIPs(edges)
+
+		ipDuplicateMutex.Lock()
+		for key := range ipDuplicates {
+			if _, ok := current[key]; !ok {
+				delete(ipDuplicates, key)
+			}
+		}
+		var fresh []*ipDuplicate
+		for key, macs := range current {
+			parts := strings.SplitN(key, "|", 2)
+			dup, ok := ipDuplicates[key]
+			if !ok {
+				dup = &ipDuplicate{Community: parts[0], IP: parts[1], FirstSeen: time.Now()}
+				ipDuplicates[key] = dup
+				fresh = append(fresh, dup)
+			}
+			dup.Macs = macs
+			dup.Nodes = nil
+			for _, mac := range macs {
+				var n models.Node
+				if err := db.Where("REPLACE(UPPER(mac_address), ':', '') = ?", mac).First(&n).Error; err == nil {
+					dup.Nodes = append(dup.Nodes, n)
+				}
+			}
+		}
+		ipDuplicateMutex.Unlock()
+
+		for _, dup := range fresh {
+			dispatchAlert(Alert{Type: "duplicate_ip", Level: "warning", Title: "检测到重复 IP: " + dup.IP,
+				Message: fmt.Sprintf("社区 %s 中有 %d 个在线 edge 同时使用 IP %s: %s", dup.Community, len(dup.Macs), dup.IP, strings.Join(dup.Macs, ", "))})
+		}
+	}
+}
+
+func getIPConflicts(c *gin.Context) {
+	ipDuplicateMutex.Lock()
+	defer ipDuplicateMutex.Unlock()
+	res := make([]*ipDuplicate, 0, len(ipDuplicates))
+	for _, dup := range ipDuplicates {
+		res = append(res, dup)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Community != res[j].Community {
+			return res[i].Community < res[j].Community
+		}
+		return res[i].IP < res[j].IP
+	})
+	c.JSON(200, res)
+}
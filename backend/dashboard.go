@@ -0,0 +1,64 @@
+package main
+
+import (
+	"n2n_ui/backend/models"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const dashboardCacheTTL = 5 * time.Second
+
+var (
+	dashboardCache   gin.H
+	dashboardCacheAt time.Time
+	dashboardMutex   sync.Mutex
+)
+
+// buildDashboard 一次性汇总仪表盘所需的全部数据，仅查询一次管理端口，结果限定在当前用户可访问的社区内
+func buildDashboard(c *gin.Context) gin.H {
+	var nodes []models.Node
+	scopeNodes(c).Find(&nodes)
+	var comms []models.Community
+	scopeCommunities(c).Find(&comms)
+	edges, err := allEdgeInfo()
+	edges = scopeEdges(c, edges)
+
+	return gin.H{
+		"stats": gin.H{
+			"node_count":      len(nodes),
+			"community_count": len(comms),
+			"online_count":    len(edges),
+		},
+		"nodes":        buildNodeList(nodes, edges),
+		"relays":       scopeRelays(c, collectActiveRelays(), nodes),
+		"communities":  comms,
+		"topology":     buildTopology(nodes, edges),
+		"mgmt_error":   errString(err),
+		"generated_at": time.Now(),
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// getDashboard 返回带短时缓存的仪表盘聚合数据，多个管理员同时刷新时共享同一结果
+// 非管理员用户看到的数据各不相同，不走缓存
+func getDashboard(c *gin.Context) {
+	if _, restricted := communityScope(c); restricted {
+		c.JSON(200, buildDashboard(c))
+		return
+	}
+	dashboardMutex.Lock()
+	defer dashboardMutex.Unlock()
+	if dashboardCache == nil || time.Since(dashboardCacheAt) > dashboardCacheTTL || c.Query("refresh") == "true" {
+		dashboardCache = buildDashboard(c)
+		dashboardCacheAt = time.Now()
+	}
+	c.JSON(200, dashboardCache)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"log"
+	"n2n_ui/backend/fakesupernode"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"os"
+	"strings"
+	"time"
+)
+
+// 演示模式（-demo）：在模拟模式的基础上使用内存数据库和示例数据，无需 root 或安装 n2n 即可体验全部功能
+
+var demoEdges = []fakesupernode.Edge{
+	{Community: "office", Name: "gateway", Mac: "02:00:00:00:01:01", IP: "10.10.0.2/24", External: "203.0.113.10:40001"},
+	{Community: "office", Name: "laptop", Mac: "02:00:00:00:01:02", IP: "10.10.0.3/24", External: "198.51.100.23:51820"},
+	{Community: "office", Name: "nas", Mac: "02:00:00:00:01:03", IP: "10.10.0.4/24", External: "203.0.113.10:40002"},
+	{Community: "lab", Name: "build-01", Mac: "02:00:00:00:02:01", IP: "10.20.0.2/24", External: "192.0.2.50:33000"},
+	{Community: "lab", Name: "build-02", Mac: "02:00:00:00:02:02", IP: "10.20.0.3/24", External: "192.0.2.51:33000"},
+	{Community: "lab", Name: "unknown", Mac: "02:00:00:00:02:99", IP: "10.20.0.99/24", External: "192.0.2.99:41000"},
+}
+
+const demoPeer = "198.51.100.20:7654"
+
+// startDemo 以模拟模式启动示例 edge 表，配置文件写入临时目录
+func startDemo() {
+	dir, err := os.MkdirTemp("", "n2n-demo")
+	if err != nil {
+		log.Fatalf("Demo: failed to create temp dir: %v", err)
+	}
+	startMock(demoEdges, dir)
+	// 联盟中的另一台 supernode，用于演示端口自检
+	mockServer.SetPeers([]fakesupernode.Peer{{Addr: demoPeer, LastSeen: time.Now()}})
+}
+
+// seedDemoData 写入与假 supernode 对应的社区和节点（unknown 故意不登记，另有一个离线节点）
+func seedDemoData() {
+	db.Create(&models.Community{Name: "office", Range: "10.10.0.0/24", Password: "demo-office"})
+	db.Create(&models.Community{Name: "lab", Range: "10.20.0.0/24", Password: "demo-lab"})
+	for _, e := range demoEdges {
+		if e.Name == "unknown" {
+			continue
+		}
+		db.Create(&models.Node{
+			Name: e.Name, Community: e.Community, IPAddress: strings.Split(e.IP, "/")[0],
+			MacAddress: utils.NormalizeMac(e.Mac), IsEnabled: true, Encryption: "AES",
+		})
+	}
+	db.Create(&models.Setting{Key: "selftest_peer", Value: demoPeer})
+	db.Create(&models.Node{Name: "printer", Community: "office", IPAddress: "10.10.0.9", MacAddress: "020000000109", IsEnabled: true, Encryption: "AES"})
+}
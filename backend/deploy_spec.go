@@ -0,0 +1,632 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+	"gorm.io/gorm"
+)
+
+// 部署定义：用一个 YAML 文件声明 supernode 实例、社区、节点和设置，便于放进 Git 管理并应用到新服务器
+// 导入时按名称（实例、社区）和 MAC（节点）匹配已有对象，只修改有差异的字段，重复导入同一文件不会产生变化
+
+const (
+	deploySpecVersion = 1
+	maxDeploySpecSize = 4 << 20
+)
+
+type deploySpec struct {
+	Version     int               `yaml:"version"`
+	Settings    map[string]string `yaml:"settings,omitempty"`
+	Supernodes  []supernodeSpec   `yaml:"supernodes,omitempty"`
+	Communities []communitySpec   `yaml:"communities"`
+	Nodes       []nodeSpec        `yaml:"nodes"`
+}
+
+type supernodeSpec struct {
+	Name              string            `yaml:"name"`
+	Unit              string            `yaml:"unit"`
+	ConfigPath        string            `yaml:"config_path"`
+	CommunityListPath string            `yaml:"community_list_path"`
+	MgmtAddr          string            `yaml:"mgmt_addr"`
+	MgmtPassword      string            `yaml:"mgmt_password,omitempty"` // 为空时保留原值
+	PublicHost        string            `yaml:"public_host,omitempty"`
+	LogSources        string            `yaml:"log_sources,omitempty"`
+	Config            map[string]string `yaml:"config,omitempty"` // supernode.conf 的参数，省略时不修改
+}
+
+type communitySpec struct {
+	Name               string `yaml:"name" json:"name"`
+	Range              string `yaml:"range,omitempty" json:"range,omitempty"`
+	Password           string `yaml:"password,omitempty" json:"password,omitempty"`   // 为空时保留原值，新建时必填
+	Supernode          string `yaml:"supernode,omitempty" json:"supernode,omitempty"` // 所属实例名称，空表示默认实例
+	PortStart          int    `yaml:"port_start,omitempty" json:"port_start,omitempty"`
+	PortEnd            int    `yaml:"port_end,omitempty" json:"port_end,omitempty"`
+	DefaultEncryption  string `yaml:"default_encryption,omitempty" json:"default_encryption,omitempty"`
+	DefaultCompression bool   `yaml:"default_compression,omitempty" json:"default_compression,omitempty"`
+	MTU                int    `yaml:"mtu,omitempty" json:"mtu,omitempty"`
+	SupernodeOverride  string `yaml:"supernode_override,omitempty" json:"supernode_override,omitempty"`
+	UserAuth           bool   `yaml:"user_auth,omitempty" json:"user_auth,omitempty"`
+}
+
+type nodeSpec struct {
+	Name        string     `yaml:"name" json:"name"`
+	MAC         string     `yaml:"mac" json:"mac"`
+	IP          string     `yaml:"ip" json:"ip"`
+	Community   string     `yaml:"community" json:"community"`
+	Description string     `yaml:"description,omitempty" json:"description,omitempty"`
+	Encryption  string     `yaml:"encryption,omitempty" json:"encryption,omitempty"`
+	Compression bool       `yaml:"compression,omitempty" json:"compression,omitempty"`
+	Routing     string     `yaml:"routing,omitempty" json:"routing,omitempty"` // 网段:网关
+	LocalPort   int        `yaml:"local_port,omitempty" json:"local_port,omitempty"`
+	ExtraArgs   string     `yaml:"extra_args,omitempty" json:"extra_args,omitempty"` // 额外的 edge 参数
+	MTU         int        `yaml:"mtu,omitempty" json:"mtu,omitempty"`
+	TapDevice   string     `yaml:"tap_device,omitempty" json:"tap_device,omitempty"`
+	TTL         int        `yaml:"ttl,omitempty" json:"ttl,omitempty"`
+	Disabled    bool       `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+	Tags        string     `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Owner       string     `yaml:"owner,omitempty" json:"owner,omitempty"`
+	OwnerEmail  string     `yaml:"owner_email,omitempty" json:"owner_email,omitempty"`
+	Contact     string     `yaml:"contact,omitempty" json:"contact,omitempty"`
+	Site        string     `yaml:"site,omitempty" json:"site,omitempty"`
+	DeviceType  string     `yaml:"device_type,omitempty" json:"device_type,omitempty"`
+	ExpiresAt   *time.Time `yaml:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+func supernodeSpecOf(inst models.SupernodeInstance) supernodeSpec {
+	return supernodeSpec{
+		Name: inst.Name, Unit: inst.Unit, ConfigPath: inst.ConfigPath, CommunityListPath: inst.CommunityListPath,
+		MgmtAddr: inst.MgmtAddr, MgmtPassword: string(inst.MgmtPassword), PublicHost: inst.PublicHost, LogSources: inst.LogSources,
+	}
+}
+
+func communitySpecOf(comm models.Community, instanceNames map[uint]string) communitySpec {
+	return communitySpec{
+		Name: comm.Name, Range: comm.Range, Password: string(comm.Password), Supernode: instanceNames[comm.SupernodeID],
+		PortStart: comm.PortStart, PortEnd: comm.PortEnd, DefaultEncryption: comm.DefaultEncryption,
+		DefaultCompression: comm.DefaultCompression, MTU: comm.MTU, SupernodeOverride: comm.SupernodeOverride, UserAuth: comm.UserAuth,
+	}
+}
+
+func nodeSpecOf(n models.Node) nodeSpec {
+	return nodeSpec{
+		Name: n.Name, MAC: n.MacAddress, IP: n.IPAddress, Community: n.Community, Description: n.Description,
+		Encryption: n.Encryption, Compression: n.Compression, Routing: n.Routing, LocalPort: n.LocalPort, Disabled: !n.IsEnabled,
+		Tags: n.Tags, Owner: n.Owner, OwnerEmail: n.OwnerEmail, Contact: n.Contact, Site: n.Site, DeviceType: n.DeviceType,
+		ExpiresAt: n.ExpiresAt, ExtraArgs: n.ExtraArgs, MTU: n.MTU, TapDevice: n.TapDevice, TTL: n.TTL,
+	}
+}
+
+func (s supernodeSpec) columns() map[string]interface{} {
+	cols := map[string]interface{}{
+		"name": s.Name, "unit": s.Unit, "config_path": s.ConfigPath, "community_list_path": s.CommunityListPath,
+		"mgmt_addr": s.MgmtAddr, "public_host": s.PublicHost, "log_sources": s.LogSources,
+	}
+	if s.MgmtPassword != "" {
+		cols["mgmt_password"] = models.EncryptedString(s.MgmtPassword)
+	}
+	return cols
+}
+
+func (s communitySpec) columns(supernodeID uint) map[string]interface{} {
+	cols := map[string]interface{}{
+		"name": s.Name, "range": s.Range, "supernode_id": supernodeID, "port_start": s.PortStart, "port_end": s.PortEnd,
+		"default_encryption": s.DefaultEncryption, "default_compression": s.DefaultCompression, "mtu": s.MTU,
+		"supernode_override": s.SupernodeOverride, "user_auth": s.UserAuth,
+	}
+	if s.Password != "" {
+		cols["password"] = models.EncryptedString(s.Password)
+	}
+	return cols
+}
+
+func (s nodeSpec) columns() map[string]interface{} {
+	return map[string]interface{}{
+		"name": s.Name, "mac_address": s.MAC, "ip_address": s.IP, "community": s.Community, "description": s.Description,
+		"encryption": s.Encryption, "compression": s.Compression, "routing": s.Routing, "local_port": s.LocalPort,
+		"extra_args": s.ExtraArgs, "mtu": s.MTU, "tap_device": s.TapDevice, "ttl": s.TTL, "is_enabled": !s.Disabled, "tags": s.Tags, "owner": s.Owner, "owner_email": s.OwnerEmail, "contact": s.Contact,
+		"site": s.Site, "device_type": s.DeviceType, "expires_at": s.ExpiresAt,
+	}
+}
+
+// specFieldChanges 比较两个同类型的 spec，返回取值不同的字段（YAML 名称）
+func specFieldChanges(a, b interface{}) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	changed := make([]string, 0)
+	for i := 0; i < va.NumField(); i++ {
+		fa, fb := va.Field(i).Interface(), vb.Field(i).Interface()
+		equal := reflect.DeepEqual(fa, fb)
+		if ta, ok := fa.(*time.Time); ok {
+			tb := fb.(*time.Time)
+			equal = (ta == nil && tb == nil) || (ta != nil && tb != nil && ta.Equal(*tb))
+		}
+		if !equal {
+			changed = append(changed, strings.Split(va.Type().Field(i).Tag.Get("yaml"), ",")[0])
+		}
+	}
+	return changed
+}
+
+// buildDeploySpec 导出当前部署定义，secrets 为 false 时省略社区密码、管理端口密码和敏感设置
+func buildDeploySpec(secrets bool) deploySpec {
+	spec := deploySpec{Version: deploySpecVersion, Settings: make(map[string]string)}
+
+	var settings []models.Setting
+	db.Order("key").Find(&settings)
+	for _, s := range settings {
+		d, ok := lookupSettingDef(s.Key)
+		if !ok || (d.Secret && !secrets) {
+			continue
+		}
+		if v, err := models.DecryptValue(s.Value); err == nil {
+			spec.Settings[s.Key] = v
+		}
+	}
+
+	var insts []models.SupernodeInstance
+	db.Order("id").Find(&insts)
+	instanceNames := make(map[uint]string)
+	for _, inst := range insts {
+		if !inst.IsDefault {
+			instanceNames[inst.ID] = inst.Name
+		}
+		s := supernodeSpecOf(inst)
+		if !secrets {
+			s.MgmtPassword = ""
+		}
+		s.Config, _ = utils.ReadSupernodeConfig(inst.ConfigPath)
+		spec.Supernodes = append(spec.Supernodes, s)
+	}
+
+	var comms []models.Community
+	db.Order("name").Find(&comms)
+	spec.Communities = make([]communitySpec, 0, len(comms))
+	for _, comm := range comms {
+		s := communitySpecOf(comm, instanceNames)
+		if !secrets {
+			s.Password = ""
+		}
+		spec.Communities = append(spec.Communities, s)
+	}
+
+	var nodes []models.Node
+	db.Order("community, name").Find(&nodes)
+	spec.Nodes = make([]nodeSpec, 0, len(nodes))
+	for _, n := range nodes {
+		spec.Nodes = append(spec.Nodes, nodeSpecOf(n))
+	}
+	return spec
+}
+
+// exportDeploySpec 以 YAML 导出部署定义，?secrets=true 时包含密码等敏感信息
+func exportDeploySpec(c *gin.Context) {
+	data, err := yaml.MarshalWithOptions(buildDeploySpec(c.Query("secrets") == "true"), yaml.IndentSequence(true))
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="n2n-spec.yaml"`)
+	c.Data(200, "application/yaml; charset=utf-8", data)
+}
+
+// specChange 导入计划中的一项变更
+type specChange struct {
+	Kind   string   `json:"kind"` // setting / supernode / community / node
+	Name   string   `json:"name"`
+	Action string   `json:"action"` // create / update / delete
+	Fields []string `json:"fields,omitempty"`
+}
+
+// specProblem 部署定义中无法应用的一项
+type specProblem struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Error string `json:"error"`
+
+	err error
+}
+
+// deployPlan 校验部署定义并与当前状态对比得出的变更
+type deployPlan struct {
+	Changes  []specChange  `json:"changes"`
+	Problems []specProblem `json:"problems"`
+
+	settings   map[string]string
+	supernodes []supernodeSpec
+	comms      []communitySpec
+	nodes      []nodeSpec
+	pruneComms []models.Community
+	pruneNodes []models.Node
+	touched    map[string]bool // 需要刷新配置状态的社区
+}
+
+func (p *deployPlan) change(kind, name, action string, fields []string) {
+	p.Changes = append(p.Changes, specChange{Kind: kind, Name: name, Action: action, Fields: fields})
+}
+
+func (p *deployPlan) problem(kind, name string, err error) {
+	p.Problems = append(p.Problems, specProblem{Kind: kind, Name: name, Error: err.Error(), err: err})
+}
+
+// planDeploySpec 校验部署定义并计算变更，prune 为 true 时删除定义中没有的社区和节点
+func planDeploySpec(spec deploySpec, prune bool) *deployPlan {
+	p := &deployPlan{
+		Changes: make([]specChange, 0), Problems: make([]specProblem, 0),
+		settings: make(map[string]string), touched: make(map[string]bool),
+	}
+	if spec.Version != deploySpecVersion {
+		p.problem("spec", "version", fmt.Errorf("unsupported version %d, expected %d", spec.Version, deploySpecVersion))
+		return p
+	}
+
+	// 设置：脱敏占位符和与当前值相同的项跳过
+	if settings, err := validateSettings(spec.Settings); err != nil {
+		p.problem("setting", "", err)
+	} else {
+		keys := make([]string, 0, len(settings))
+		for k := range settings {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if getSettingValue(k, "") != settings[k] {
+				p.settings[k] = settings[k]
+				p.change("setting", k, "update", nil)
+			}
+		}
+	}
+
+	// supernode 实例
+	instances := make(map[string]bool)
+	for _, s := range spec.Supernodes {
+		in := instanceInput{Name: s.Name, Unit: s.Unit, ConfigPath: s.ConfigPath, CommunityListPath: s.CommunityListPath, MgmtAddr: s.MgmtAddr, LogSources: s.LogSources}
+		if err := in.validate(); err != nil {
+			p.problem("supernode", s.Name, err)
+			continue
+		}
+		if instances[s.Name] {
+			p.problem("supernode", s.Name, errors.New("duplicate supernode name"))
+			continue
+		}
+		instances[s.Name] = true
+		var existing models.SupernodeInstance
+		var fields []string
+		if err := db.Where("name = ?", s.Name).First(&existing).Error; err != nil {
+			p.change("supernode", s.Name, "create", nil)
+		} else {
+			cur := supernodeSpecOf(existing)
+			if s.MgmtPassword == "" {
+				cur.MgmtPassword = ""
+			}
+			cur.Config = s.Config
+			fields = specFieldChanges(cur, s)
+			if s.Config != nil {
+				if conf, _ := utils.ReadSupernodeConfig(s.ConfigPath); !maps.Equal(conf, s.Config) {
+					fields = append(fields, "config")
+				}
+			}
+			if len(fields) == 0 {
+				continue
+			}
+			p.change("supernode", s.Name, "update", fields)
+		}
+		if s.Config != nil {
+			inst := existing
+			inst.Name, inst.ConfigPath, inst.CommunityListPath, inst.MgmtAddr = s.Name, s.ConfigPath, s.CommunityListPath, s.MgmtAddr
+			if s.MgmtPassword != "" {
+				inst.MgmtPassword = models.EncryptedString(s.MgmtPassword)
+			}
+			conf := maps.Clone(s.Config)
+			conf["f"], conf["v"] = "", ""
+			if problems := checkSupernodeConfig(inst, conf); problems.hasErrors() {
+				p.problem("supernode", s.Name, problems.err())
+				continue
+			}
+		}
+		p.supernodes = append(p.supernodes, s)
+	}
+	var insts []models.SupernodeInstance
+	db.Find(&insts)
+	for _, inst := range insts {
+		instances[inst.Name] = true
+	}
+
+	// 社区
+	comms := make(map[string]models.Community)
+	for _, s := range spec.Communities {
+		if _, dup := comms[s.Name]; dup {
+			p.problem("community", s.Name, errors.New("duplicate community name"))
+			continue
+		}
+		comm := models.Community{Name: s.Name, Range: s.Range, Password: models.EncryptedString(s.Password)}
+		var existing models.Community
+		found := db.Unscoped().Where("name = ?", s.Name).First(&existing).Error == nil
+		err := func() error {
+			if strings.TrimSpace(s.Name) == "" {
+				return newAPIError(ErrCommunityNameRequired, "Community name is required")
+			}
+			if found && existing.DeletedAt.Valid {
+				return newAPIError(ErrCommunityInTrash, "Community with this name is in the trash, restore or purge it first")
+			}
+			if s.Range != "" {
+				if err := validateCIDR(s.Range); err != nil {
+					return err
+				}
+			}
+			if s.Supernode != "" && !instances[s.Supernode] {
+				return newAPIError(ErrInstanceNotFound, "Supernode instance not found")
+			}
+			if (s.Password != "" || !found) && len(s.Password) < 4 {
+				return newAPIError(ErrPasswordTooShort, "Password must be at least 4 characters")
+			}
+			return communityDefaults{s.DefaultEncryption, s.DefaultCompression, s.MTU, s.SupernodeOverride, s.PortStart, s.PortEnd}.validate()
+		}()
+		if err != nil {
+			p.problem("community", s.Name, err)
+			continue
+		}
+		comms[s.Name] = comm
+		if !found {
+			p.change("community", s.Name, "create", nil)
+			p.comms = append(p.comms, s)
+		} else {
+			var inst models.SupernodeInstance
+			db.First(&inst, existing.SupernodeID)
+			cur := communitySpecOf(existing, map[uint]string{inst.ID: inst.Name})
+			if inst.IsDefault {
+				cur.Supernode = ""
+			}
+			if s.Password == "" {
+				cur.Password = ""
+			}
+			if fields := specFieldChanges(cur, s); len(fields) > 0 {
+				p.change("community", s.Name, "update", fields)
+				p.comms = append(p.comms, s)
+				p.touched[s.Name] = true
+			}
+		}
+	}
+
+	// 节点按 MAC 匹配
+	var all []models.Node
+	db.Unscoped().Find(&all)
+	byMac := make(map[string]models.Node)
+	for _, n := range all {
+		byMac[n.MacAddress] = n
+	}
+	specIPs := make(map[string]string) // IP -> MAC
+	specMacs := make(map[string]string)
+	for _, s := range spec.Nodes {
+		s.MAC = utils.NormalizeMac(s.MAC)
+		s.Tags = normalizeTags(s.Tags)
+		existing, found := byMac[s.MAC]
+		err := func() error {
+			if err := validateNodeName(s.Name); err != nil {
+				return err
+			}
+			if err := validateMacAddress(s.MAC); err != nil {
+				return err
+			}
+			if _, dup := specMacs[s.MAC]; dup {
+				return errors.New("duplicate MAC address in spec")
+			}
+			if found && existing.DeletedAt.Valid {
+				return newAPIError(ErrNodeConflict, "Node with this MAC address is in the trash, restore or purge it first")
+			}
+			comm, ok := comms[s.Community]
+			if !ok {
+				var err error
+				if comm, err = lookupCommunity(s.Community); err != nil {
+					return err
+				}
+			}
+			if err := validateNodeIP(s.IP, comm); err != nil {
+				return err
+			}
+			if other, dup := specIPs[s.IP]; dup {
+				return fmt.Errorf("IP address %s also used by %s in spec", s.IP, other)
+			}
+			if err := validateEncryption(s.Encryption); err != nil {
+				return err
+			}
+			if s.LocalPort < 0 || s.LocalPort > 65535 {
+				return newAPIError(ErrInvalidPort, "Invalid local port")
+			}
+			if err := validateEdgeOptions(s.MTU, s.TapDevice, s.TTL); err != nil {
+				return err
+			}
+			var err error
+			if s.ExtraArgs, err = normalizeEdgeFlags(s.ExtraArgs); err != nil {
+				return err
+			}
+			if s.Routing != "" {
+				ipnet, gw, ok := parseRouting(s.Routing)
+				if !ok {
+					return newAPIError(ErrInvalidRoute, "Invalid route network format")
+				}
+				if err := validateRoute(ipnet.String(), gw.String(), comm); err != nil {
+					return err
+				}
+			}
+			var previous *time.Time
+			if found {
+				previous = existing.ExpiresAt
+			}
+			return validateExpiry(s.ExpiresAt, previous)
+		}()
+		if err != nil {
+			p.problem("node", s.Name, err)
+			continue
+		}
+		specMacs[s.MAC], specIPs[s.IP] = s.Name, s.Name
+		if s.Encryption == "" {
+			s.Encryption = "AES"
+		}
+		if !found {
+			p.change("node", s.Name, "create", nil)
+			p.nodes = append(p.nodes, s)
+		} else if fields := specFieldChanges(nodeSpecOf(existing), s); len(fields) > 0 {
+			p.change("node", s.Name, "update", fields)
+			p.nodes = append(p.nodes, s)
+			p.touched[s.Community] = true
+		}
+	}
+	// 定义之外的节点（包括回收站中的）仍占用其 IP
+	for _, n := range all {
+		if _, inSpec := specMacs[n.MacAddress]; inSpec {
+			continue
+		}
+		if name, ok := specIPs[n.IPAddress]; ok {
+			p.problem("node", name, fmt.Errorf("IP address %s already used by node %s", n.IPAddress, n.Name))
+		}
+		if prune && !n.DeletedAt.Valid {
+			p.pruneNodes = append(p.pruneNodes, n)
+			p.change("node", n.Name, "delete", nil)
+		}
+	}
+	if prune {
+		var existing []models.Community
+		db.Find(&existing)
+		for _, comm := range existing {
+			if _, ok := comms[comm.Name]; !ok {
+				p.pruneComms = append(p.pruneComms, comm)
+				p.change("community", comm.Name, "delete", nil)
+			}
+		}
+	}
+	return p
+}
+
+// apply 在一个事务中写入计划的变更，提交后写 supernode 配置并重启实例运行时
+func (p *deployPlan) apply() error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for k, v := range p.settings {
+			if err := tx.Where("key = ?", k).Assign(models.Setting{Value: encryptSettingValue(k, v)}).FirstOrCreate(&models.Setting{Key: k}).Error; err != nil {
+				return err
+			}
+		}
+		for _, s := range p.supernodes {
+			var inst models.SupernodeInstance
+			if err := tx.Where("name = ?", s.Name).First(&inst).Error; err != nil {
+				inst = models.SupernodeInstance{Name: s.Name}
+				if err := tx.Create(&inst).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&inst).Updates(s.columns()).Error; err != nil {
+				return err
+			}
+		}
+		for _, s := range p.comms {
+			var supernodeID uint
+			if s.Supernode != "" {
+				var inst models.SupernodeInstance
+				tx.Where("name = ? AND is_default = ?", s.Supernode, false).First(&inst)
+				supernodeID = inst.ID
+			}
+			var comm models.Community
+			if err := tx.Where("name = ?", s.Name).First(&comm).Error; err != nil {
+				comm = models.Community{Name: s.Name, Password: models.EncryptedString(s.Password)}
+				if err := tx.Create(&comm).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&comm).Updates(s.columns(supernodeID)).Error; err != nil {
+				return err
+			}
+		}
+		for _, n := range p.pruneNodes {
+			if err := tx.Delete(&n).Error; err != nil {
+				return err
+			}
+		}
+		for _, comm := range p.pruneComms {
+			if err := tx.Delete(&comm).Error; err != nil {
+				return err
+			}
+		}
+		for _, s := range p.nodes {
+			var n models.Node
+			if err := tx.Where("mac_address = ?", s.MAC).First(&n).Error; err != nil {
+				n = models.Node{Name: s.Name, MacAddress: s.MAC, IPAddress: s.IP, Community: s.Community}
+				if err := tx.Create(&n).Error; err != nil {
+					return fmt.Errorf("node %s: %w", s.Name, err)
+				}
+			}
+			if err := tx.Model(&n).Updates(s.columns()).Error; err != nil {
+				return fmt.Errorf("node %s: %w", s.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, s := range p.supernodes {
+		var inst models.SupernodeInstance
+		db.Where("name = ?", s.Name).First(&inst)
+		if s.Config != nil {
+			cfg := maps.Clone(s.Config)
+			cfg["f"], cfg["v"] = "", ""
+			if err := utils.WriteSupernodeConfig(inst.ConfigPath, cfg); err != nil {
+				log.Printf("Spec import: failed to write supernode config for %s: %v", s.Name, err)
+			}
+		}
+		stopInstance(inst.ID)
+		startInstance(inst)
+	}
+	if len(p.settings) > 0 {
+		reloadIPFilter()
+	}
+	for name := range p.touched {
+		refreshCommunityConfigs(name)
+	}
+	return nil
+}
+
+// importDeploySpec 导入 YAML 部署定义：先校验并返回变更计划，?dry_run=true 时只返回计划；
+// 有任何问题时不做修改；?prune=true 时删除定义中没有的社区和节点（移入回收站）
+func importDeploySpec(c *gin.Context) {
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxDeploySpecSize+1))
+	if err != nil || len(data) > maxDeploySpecSize {
+		respondError(c, 400, ErrInvalidSpec, "Spec is too large or unreadable")
+		return
+	}
+	var spec deploySpec
+	if err := yaml.UnmarshalWithOptions(data, &spec, yaml.Strict()); err != nil {
+		respondError(c, 400, ErrInvalidSpec, "Invalid spec", gin.H{"detail": yaml.FormatError(err, false, true)})
+		return
+	}
+	plan := planDeploySpec(spec, c.Query("prune") == "true")
+	if len(plan.Problems) > 0 {
+		respondError(c, 422, ErrInvalidSpec, "Spec has problems, nothing was applied", gin.H{"problems": plan.Problems, "changes": plan.Changes})
+		return
+	}
+	res := gin.H{"changes": plan.Changes, "applied": false}
+	if c.Query("dry_run") == "true" || len(plan.Changes) == 0 {
+		c.JSON(200, res)
+		return
+	}
+	if err := plan.apply(); err != nil {
+		log.Printf("Spec import failed: %v", err)
+		respondError(c, 409, ErrInvalidSpec, "Failed to apply spec", gin.H{"detail": err.Error()})
+		return
+	}
+	res["applied"] = true
+	if err := syncCommunityList(); err != nil {
+		res["warning"] = tr(c, "Failed to write community.list") + ": " + err.Error()
+	}
+	c.JSON(200, res)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// n2n 3.x 用户名/密码认证：社区启用 user_auth 后，community.list 中该社区下写入
+//
+//	mycommunity
+//	 * <用户名> <公钥>
+//
+// supernode 只接受持有对应密码的 edge。edge 配置中用 -I 传用户名、-J 传密码、-P 传 supernode 公钥。
+// 密钥由 n2n 自带的 n2n-keygen 计算，面板主机上需能找到该程序。
+
+const authUserMaxLen = 15 // n2n 的 edge 描述（-I）最长 15 个字符
+
+var authUserInvalid = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// authPublicKeyPattern n2n-keygen 输出的公钥（base64 字符集），写入 community.list 前校验，防止换行等注入其他行
+var authPublicKeyPattern = regexp.MustCompile(`^[A-Za-z0-9+/]{1,64}={0,2}$`)
+
+// validAuthEntry 用户名与公钥可以安全地写成 community.list 中的用户行
+func validAuthEntry(user, key string) bool {
+	return user != "" && len(user) <= authUserMaxLen && !authUserInvalid.MatchString(user) && authPublicKeyPattern.MatchString(key)
+}
+
+var (
+	federationKeys      = make(map[string]string) // 联盟名称 -> supernode 公钥
+	federationKeysMutex sync.Mutex
+)
+
+// supernodeAuthKey 返回 edge 使用的 supernode 公钥：设置 supernode_public_key，
+// 未设置时按联盟名称 supernode_federation（默认 *Federation）计算
+func supernodeAuthKey() (string, error) {
+	if v := getSettingValue("supernode_public_key", ""); v != "" {
+		return v, nil
+	}
+	federation := getSettingValue("supernode_federation", "*Federation")
+	federationKeysMutex.Lock()
+	defer federationKeysMutex.Unlock()
+	if key, ok := federationKeys[federation]; ok {
+		return key, nil
+	}
+	key, err := utils.FederationPublicKey(federation)
+	if err != nil {
+		return "", err
+	}
+	federationKeys[federation] = key
+	return key, nil
+}
+
+// authUserFor 由节点名称生成社区内唯一的认证用户名，不合法或重名时使用 node<ID>。
+// tx 为创建节点的事务时可以看到同一事务中已写入的节点
+func authUserFor(tx *gorm.DB, n models.Node) string {
+	user := authUserInvalid.ReplaceAllString(n.Name, "")
+	if len(user) > authUserMaxLen {
+		user = user[:authUserMaxLen]
+	}
+	var count int64
+	tx.Model(&models.Node{}).Where("community = ? AND auth_user = ? AND id <> ?", n.Community, user, n.ID).Count(&count)
+	if user == "" || count > 0 {
+		user = fmt.Sprintf("node%d", n.ID)
+	}
+	return user
+}
+
+// authEntryChanged 节点的修改是否影响它在 community.list 中的公钥行
+func authEntryChanged(old, n models.Node) bool {
+	if old.AuthPublicKey == "" && n.AuthPublicKey == "" {
+		return false
+	}
+	expiryChanged := (old.ExpiresAt == nil) != (n.ExpiresAt == nil) ||
+		(old.ExpiresAt != nil && !old.ExpiresAt.Equal(*n.ExpiresAt))
+	return old.Community != n.Community || old.MacAddress != n.MacAddress || old.AuthUser != n.AuthUser ||
+		old.AuthPublicKey != n.AuthPublicKey || old.IsEnabled != n.IsEnabled || expiryChanged
+}
+
+// communityListLines 返回某个实例 community.list 的内容：每个社区一行，
+// 启用用户认证的社区后面跟着其节点的用户公钥；禁用、待审批和已到期的节点不写入，无法再通过认证
+func communityListLines(tx *gorm.DB, instanceID uint) []string {
+	var comms []models.Community
+	tx.Find(&comms)
+	lines := make([]string, 0, len(comms))
+	for _, comm := range comms {
+		if communityInstanceID(comm) != instanceID {
+			continue
+		}
+		lines = append(lines, comm.Name)
+		if !comm.UserAuth {
+			continue
+		}
+		var nodes []models.Node
+		tx.Where("community = ? AND auth_public_key <> '' AND is_enabled = ? AND pending_approval = ?", comm.Name, true, false).
+			Where("expires_at IS NULL OR expires_at > ?", time.Now()).Order("auth_user").Find(&nodes)
+		for _, n := range nodes {
+			if !validAuthEntry(n.AuthUser, n.AuthPublicKey) {
+				log.Printf("Skipping invalid auth key of node %s (%d) in community.list", n.Name, n.ID)
+				continue
+			}
+			lines = append(lines, fmt.Sprintf(" * %s %s", n.AuthUser, n.AuthPublicKey))
+		}
+	}
+	return lines
+}
+
+// afterAuthChange 节点密钥变化后同步 community.list 并刷新配置差异，同步失败时在 res 中附带警告
+func afterAuthChange(c *gin.Context, n *models.Node, res gin.H) {
+	refreshConfigState(n)
+	db.Model(n).Updates(map[string]interface{}{"config_diff": n.ConfigDiff, "config_outdated": n.ConfigOutdated})
+	if err := syncCommunityList(); err != nil {
+		res["warning"] = tr(c, "Failed to write community.list") + ": " + err.Error()
+	}
+}
+
+// assignNodeAuthKey 为节点生成新的认证密码并计算公钥，写入 n 与数据库，返回密码明文
+func assignNodeAuthKey(tx *gorm.DB, n *models.Node) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	secret := hex.EncodeToString(b)
+	user := n.AuthUser
+	if user == "" {
+		user = authUserFor(tx, *n)
+	}
+	pub, err := utils.UserPublicKey(user, secret)
+	if err != nil {
+		return "", err
+	}
+	n.AuthUser, n.AuthPublicKey, n.AuthSecret = user, pub, models.EncryptedString(secret)
+	return secret, tx.Model(n).Updates(map[string]interface{}{"auth_user": user, "auth_public_key": pub, "auth_secret": n.AuthSecret}).Error
+}
+
+// generateNodeAuthKey 为节点生成新的认证密码并计算公钥，旧密码立即失效
+// 密码会写入生成的 edge 配置，这里也返回一次方便手动部署
+func generateNodeAuthKey(c *gin.Context) {
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	secret, err := assignNodeAuthKey(db, &n)
+	if err != nil {
+		log.Printf("Key generation for node %d failed: %v", n.ID, err)
+		respondError(c, 500, ErrKeygenFailed, "Key generation failed, make sure n2n-keygen is installed", gin.H{"detail": err.Error()})
+		return
+	}
+	res := gin.H{"auth_user": n.AuthUser, "auth_public_key": n.AuthPublicKey, "secret": secret}
+	afterAuthChange(c, &n, res)
+	c.JSON(200, res)
+}
+
+// deleteNodeAuthKey 删除节点的认证密钥，所在社区启用用户认证时该节点将无法再连接
+func deleteNodeAuthKey(c *gin.Context) {
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	n.AuthPublicKey, n.AuthSecret = "", ""
+	db.Model(&n).Updates(map[string]interface{}{"auth_public_key": "", "auth_secret": ""})
+	res := gin.H{"message": "deleted"}
+	afterAuthChange(c, &n, res)
+	c.JSON(200, res)
+}
+
+// setCommunityUserAuth 启用或关闭社区的用户认证；启用时返回尚未生成密钥的节点，这些节点将无法连接
+func setCommunityUserAuth(c *gin.Context) {
+	var comm models.Community
+	if err := db.First(&comm, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrCommunityNotFound, "Community not found")
+		return
+	}
+	var p struct {
+		Enabled bool `json:"enabled"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	db.Model(&comm).Update("user_auth", p.Enabled)
+	refreshCommunityConfigs(comm.Name)
+	res := gin.H{"id": comm.ID, "user_auth": p.Enabled}
+	if p.Enabled {
+		var missing []models.Node
+		db.Select("id", "name").Where("community = ? AND auth_public_key = ''", comm.Name).Find(&missing)
+		names := make([]string, 0, len(missing))
+		for _, n := range missing {
+			names = append(names, n.Name)
+		}
+		res["nodes_without_key"] = names
+	}
+	if err := syncCommunityList(); err != nil {
+		res["warning"] = tr(c, "Failed to write community.list") + ": " + err.Error()
+	}
+	c.JSON(200, res)
+}
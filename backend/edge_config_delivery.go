@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"n2n_ui/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// edge 配置下发：社区密码、supernode 地址等修改后，已下发的节点被标记为配置过期（config_outdated）。
+// 有代理令牌的节点由 edge 代理自动更新：
+//   GET  /api/agent/config        X-Agent-Token 认证，返回当前配置与 hash，带 If-None-Match 且未变化时返回 304；
+//                                 社区密码轮换的过渡期内附带 pending（新配置与生效时间），见 community_rotation.go
+//   POST /api/agent/config/ack    {"hash": "..."}，代理应用配置并重启 edge 后确认，该配置成为新的基线
+// 没有代理的节点需要管理员重新下发，GET /api/nodes/outdated-configs 按是否有代理列出配置过期的节点。
+
+// configHash 配置内容的 hash，同时用作 ETag
+func configHash(conf string) string {
+	sum := sha256.Sum256([]byte(conf))
+	return hex.EncodeToString(sum[:8])
+}
+
+// agentConfigNode 代理令牌对应且可以获取配置的节点，待审批、禁用或已到期时返回 403
+func agentConfigNode(c *gin.Context) (models.Node, bool) {
+	n, ok := agentNode(c)
+	if !ok {
+		return n, false
+	}
+	switch {
+	case nodeExpired(n):
+		respondError(c, 403, ErrNodeExpired, "Node access has expired")
+	case n.PendingApproval:
+		respondError(c, 403, ErrNodePending, "Node is pending approval")
+	case !n.IsEnabled:
+		respondError(c, 403, ErrNodeDisabled, "Node is disabled")
+	default:
+		return n, true
+	}
+	return n, false
+}
+
+// pollAgentConfig 代理定期拉取节点的当前配置
+func pollAgentConfig(c *gin.Context) {
+	n, ok := agentConfigNode(c)
+	if !ok {
+		return
+	}
+	conf := renderNodeConfig(n)
+	hash := configHash(conf)
+	// 密码轮换过渡期内附带新配置，ETag 随之变化
+	pending := pendingNodeConfig(n)
+	etag := `"` + hash + `"`
+	if pending != nil {
+		etag = `"` + configHash(conf+pending.Conf) + `"`
+	}
+	c.Header("ETag", etag)
+	if etagListContains(c.GetHeader("If-None-Match"), etag) {
+		c.Status(304)
+		return
+	}
+	c.JSON(200, gin.H{"conf": conf, "hash": hash, "config_outdated": n.ConfigOutdated, "pending": pending})
+}
+
+// ackAgentConfig 代理确认已部署 hash 对应的配置；配置在拉取后又发生变化时返回 409，代理应重新拉取
+func ackAgentConfig(c *gin.Context) {
+	n, ok := agentConfigNode(c)
+	if !ok {
+		return
+	}
+	var p struct {
+		Hash string `json:"hash" binding:"required"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	conf := renderNodeConfig(n)
+	if configHash(conf) != p.Hash {
+		respondError(c, 409, ErrConfigChanged, "Config changed since it was fetched")
+		return
+	}
+	if n.ConfigOutdated || n.IssuedConfig != conf {
+		log.Printf("Edge agent of node %s (%d) deployed config %s", n.Name, n.ID, p.Hash)
+	}
+	db.Model(&n).Updates(map[string]interface{}{"issued_config": conf, "config_diff": "", "config_outdated": false})
+	c.JSON(200, gin.H{"hash": p.Hash, "config_outdated": false})
+}
+
+// getOutdatedConfigs 配置过期的节点：agent 为有代理、会自动更新的节点，manual 为需要手动重新下发的节点
+func getOutdatedConfigs(c *gin.Context) {
+	var nodes []models.Node
+	scopeNodes(c).Where("config_outdated = ?", true).Order("community, id").Find(&nodes)
+	agent, manual := make([]gin.H, 0), make([]gin.H, 0)
+	for _, n := range nodes {
+		item := gin.H{"id": n.ID, "name": n.Name, "community": n.Community, "ip_address": n.IPAddress,
+			"owner": n.Owner, "owner_email": n.OwnerEmail, "last_seen": n.LastSeen, "is_enabled": n.IsEnabled}
+		if n.AgentToken != "" {
+			agent = append(agent, item)
+		} else {
+			manual = append(manual, item)
+		}
+	}
+	c.JSON(200, gin.H{"agent": agent, "manual": manual})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// 节点额外的 edge 参数（Node.ExtraArgs）：只允许不与面板管理的参数冲突、也不影响主机安全的 n2n 选项，
+// 如接收组播（-E）、选择 supernode 的策略（-S1 / -S2）等。面板自己生成的 -a/-c/-k/-l/-m/-I/-J/-P/-A/-p/-M/-z/-n/-d/-L/-f
+// 以及切换用户、执行脚本类的参数都不在允许列表中。
+// 保存时规范化为每行一个参数（-x=10 形式），生成配置时原样追加到 -f 之前。
+
+// edgeFlag 一个允许的参数，value 为 nil 表示不带值
+type edgeFlag struct {
+	value func(string) bool
+}
+
+func intBetween(min, max int) func(string) bool {
+	return func(v string) bool {
+		n, err := strconv.Atoi(v)
+		return err == nil && n >= min && n <= max
+	}
+}
+
+var allowedEdgeFlags = map[string]edgeFlag{
+	"-E":           {},                            // 接收组播 MAC
+	"-S1":          {},                            // 只通过 supernode 转发（UDP）
+	"-S2":          {},                            // 只通过 supernode 转发（TCP）
+	"-D":           {},                            // PMTU 探测
+	"-r":           {},                            // 允许转发非本节点的包（作为路由器）
+	"-v":           {},                            // 更详细的日志
+	"-V":           {},                            // 更少的日志
+	"--select-rtt": {},                            // 按延迟选择 supernode
+	"--select-mac": {},                            // 按 MAC 选择 supernode
+	"-T":           {value: intBetween(0, 255)},   // TOS
+	"-x":           {value: intBetween(0, 65535)}, // TAP 接口路由 metric
+	"-i":           {value: intBetween(1, 3600)},  // NAT 打洞注册间隔（秒）
+	"-t":           {value: intBetween(1, 65535)}, // edge 管理端口
+	"-e": {value: func(v string) bool { // 向 supernode 通告的本地地址
+		return v == "auto" || net.ParseIP(v).To4() != nil
+	}},
+}
+
+// normalizeEdgeFlags 校验额外参数并规范化为每行一个；参数之间可用空格或换行分隔，
+// 带值的参数可写成 -x=10 或 -x 10
+func normalizeEdgeFlags(input string) (string, error) {
+	fields := strings.Fields(input)
+	lines := make([]string, 0, len(fields))
+	for i := 0; i < len(fields); i++ {
+		name, value, hasValue := strings.Cut(fields[i], "=")
+		spec, ok := allowedEdgeFlags[name]
+		if !ok {
+			return "", newAPIErrorf(ErrInvalidEdgeFlag, "Edge flag %s is not allowed", name)
+		}
+		if spec.value == nil {
+			if hasValue {
+				return "", newAPIErrorf(ErrInvalidEdgeFlag, "Edge flag %s does not take a value", name)
+			}
+			lines = append(lines, name)
+			continue
+		}
+		if !hasValue && i+1 < len(fields) && !strings.HasPrefix(fields[i+1], "-") {
+			i++
+			value = fields[i]
+		}
+		if !spec.value(value) {
+			return "", newAPIErrorf(ErrInvalidEdgeFlag, "Invalid value for edge flag %s", name)
+		}
+		lines = append(lines, name+"="+value)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// edgeFlagLines 将已规范化的额外参数拆成行
+func edgeFlagLines(args string) []string {
+	if args == "" {
+		return nil
+	}
+	return strings.Split(args, "\n")
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 错误码目录，与 docs/openapi.yaml 中的 ErrorCode 保持一致，新增时需同步更新文档
+const (
+	ErrInvalidRequest         = "INVALID_REQUEST"
+	ErrInvalidSetting         = "INVALID_SETTING"
+	ErrUnauthorized           = "UNAUTHORIZED"
+	ErrInvalidToken           = "INVALID_TOKEN"
+	ErrForbidden              = "FORBIDDEN"
+	ErrAccessDenied           = "ACCESS_DENIED"
+	ErrAccessSelfLockout      = "ACCESS_SELF_LOCKOUT"
+	ErrLoginFailed            = "LOGIN_FAILED"
+	ErrLoginLocked            = "LOGIN_LOCKED"
+	ErrNotFound               = "NOT_FOUND"
+	ErrInternal               = "INTERNAL_ERROR"
+	ErrUserNotFound           = "USER_NOT_FOUND"
+	ErrUserExists             = "USER_EXISTS"
+	ErrPasswordTooShort       = "PASSWORD_TOO_SHORT"
+	ErrOldPasswordIncorrect   = "OLD_PASSWORD_INCORRECT"
+	ErrNodeNotFound           = "NODE_NOT_FOUND"
+	ErrNodeOffline            = "NODE_OFFLINE"
+	ErrNodeConflict           = "NODE_CONFLICT"
+	ErrNodeExpired            = "NODE_EXPIRED"
+	ErrNodeDisabled           = "NODE_DISABLED"
+	ErrInvalidExpiry          = "INVALID_EXPIRY"
+	ErrNodeNameRequired       = "NODE_NAME_REQUIRED"
+	ErrInvalidMac             = "INVALID_MAC"
+	ErrMacBlocked             = "MAC_BLOCKED"
+	ErrInvalidIP              = "INVALID_IP"
+	ErrNodeIPOutOfRange       = "NODE_IP_OUT_OF_RANGE"
+	ErrInvalidRoute           = "INVALID_ROUTE"
+	ErrSubnetOverlap          = "SUBNET_OVERLAP"
+	ErrInvalidPort            = "INVALID_PORT"
+	ErrPortInUse              = "PORT_IN_USE"
+	ErrPortRangeExhausted     = "PORT_RANGE_EXHAUSTED"
+	ErrCommunityNotFound      = "COMMUNITY_NOT_FOUND"
+	ErrCommunityExists        = "COMMUNITY_EXISTS"
+	ErrCommunityInTrash       = "COMMUNITY_IN_TRASH"
+	ErrCommunityNameRequired  = "COMMUNITY_NAME_REQUIRED"
+	ErrInvalidCIDR            = "INVALID_CIDR"
+	ErrInstanceNotFound       = "INSTANCE_NOT_FOUND"
+	ErrInstanceExists         = "INSTANCE_EXISTS"
+	ErrInstanceInUse          = "INSTANCE_IN_USE"
+	ErrMgmtUnreachable        = "MGMT_UNREACHABLE"
+	ErrMgmtUnsupported        = "MGMT_UNSUPPORTED"
+	ErrToolsDisabled          = "TOOLS_DISABLED"
+	ErrInvalidTarget          = "INVALID_TARGET"
+	ErrTargetNotAllowed       = "TARGET_NOT_ALLOWED"
+	ErrToolFailed             = "TOOL_FAILED"
+	ErrToolJobNotFound        = "TOOL_JOB_NOT_FOUND"
+	ErrTooManyToolJobs        = "TOO_MANY_TOOL_JOBS"
+	ErrAgentRequired          = "AGENT_REQUIRED"
+	ErrSpeedTestBusy          = "SPEEDTEST_IN_PROGRESS"
+	ErrSpeedTestNotFound      = "SPEEDTEST_NOT_FOUND"
+	ErrNotifyFailed           = "NOTIFY_FAILED"
+	ErrUnknownChannel         = "UNKNOWN_CHANNEL"
+	ErrLogsUnavailable        = "LOGS_UNAVAILABLE"
+	ErrKeygenFailed           = "KEYGEN_FAILED"
+	ErrInvalidSpec            = "INVALID_SPEC"
+	ErrPreconditionFailed     = "PRECONDITION_FAILED"
+	ErrInvalidEdgeFlag        = "INVALID_EDGE_FLAG"
+	ErrPublicIPUnavailable    = "PUBLIC_IP_UNAVAILABLE"
+	ErrSetupCompleted         = "SETUP_COMPLETED"
+	ErrSessionNotFound        = "SESSION_NOT_FOUND"
+	ErrCaptchaRequired        = "CAPTCHA_REQUIRED"
+	ErrAlertRuleNotFound      = "ALERT_RULE_NOT_FOUND"
+	ErrAlertNotFound          = "ALERT_NOT_FOUND"
+	ErrInvitationNotFound     = "INVITATION_NOT_FOUND"
+	ErrInvitationExhausted    = "INVITATION_EXHAUSTED"
+	ErrRegistrationNotFound   = "REGISTRATION_NOT_FOUND"
+	ErrRegistrationDecided    = "REGISTRATION_DECIDED"
+	ErrNodePending            = "NODE_PENDING"
+	ErrNodeNotPending         = "NODE_NOT_PENDING"
+	ErrSupernodeSyncFailed    = "SUPERNODE_SYNC_FAILED"
+	ErrUpdateCheckDisabled    = "UPDATE_CHECK_DISABLED"
+	ErrUpgradeFailed          = "UPGRADE_FAILED"
+	ErrUpgradeInProgress      = "UPGRADE_IN_PROGRESS"
+	ErrAlreadyUpToDate        = "ALREADY_UP_TO_DATE"
+	ErrInstallUnsupported     = "INSTALL_UNSUPPORTED"
+	ErrInstallInProgress      = "INSTALL_IN_PROGRESS"
+	ErrConfigChanged          = "CONFIG_CHANGED"
+	ErrRotationPending        = "ROTATION_PENDING"
+	ErrNoRotationPending      = "NO_ROTATION_PENDING"
+	ErrMaintenanceMode        = "MAINTENANCE_MODE"
+	ErrSupernodeConfigInvalid = "SUPERNODE_CONFIG_INVALID"
+)
+
+// apiError 带错误码的业务错误，供校验函数等返回
+type apiError struct {
+	Code    string
+	Message string
+	format  string // newAPIErrorf 的格式串，用于翻译
+	args    []interface{}
+}
+
+func (e *apiError) Error() string { return e.Message }
+
+func newAPIError(code, message string) error {
+	return &apiError{Code: code, Message: message}
+}
+
+func newAPIErrorf(code, format string, args ...interface{}) error {
+	return &apiError{Code: code, Message: fmt.Sprintf(format, args...), format: format, args: args}
+}
+
+// respondError 返回统一的错误响应 {"error": 消息, "code": 错误码}，extra 中的字段会合并进响应
+// 消息按请求语言翻译（见 i18n.go）
+func respondError(c *gin.Context, status int, code, message string, extra ...gin.H) {
+	body := gin.H{"error": tr(c, message), "code": code}
+	for _, e := range extra {
+		for k, v := range e {
+			body[k] = v
+		}
+	}
+	c.JSON(status, body)
+}
+
+// respondErr 从 error 中提取错误码，非 apiError 时使用 fallback
+func respondErr(c *gin.Context, status int, err error, fallback string) {
+	var ae *apiError
+	if errors.As(err, &ae) {
+		respondError(c, status, ae.Code, localizeErr(c, ae))
+		return
+	}
+	respondError(c, status, fallback, err.Error())
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 统一的事件查询：审计日志、登录记录、告警与 supernode 故障按时间倒序合并查询（仅管理员）。
+//   GET /api/events         ?source=audit,login&from=&to=&actor=&type=&q=&limit=&cursor=，返回 events 与 next_cursor
+//   GET /api/events/export  同样的过滤条件，以 CSV 导出全部匹配的事件（最多 maxEventExport 条）
+// from / to 为 RFC3339 时间或 2006-01-02 形式的日期，范围为 [from, to)；cursor 为上一页返回的 next_cursor。
+// 新增事件表时在 eventSources 中登记即可。
+
+const (
+	defaultEventLimit = 100
+	maxEventLimit     = 1000
+	maxEventExport    = 100000
+	eventExportPage   = 500
+)
+
+// eventSource 一张事件表到统一事件字段的映射，各字段为 SQL 表达式
+type eventSource struct {
+	Name   string
+	Table  string
+	Time   string
+	Actor  string
+	Type   string
+	Target string
+	Detail string
+	IP     string
+}
+
+var eventSources = []eventSource{
+	{Name: "audit", Table: "audit_logs", Time: "created_at", Actor: "actor", Type: "action", Target: "target", Detail: "detail", IP: "ip"},
+	{Name: "login", Table: "login_histories", Time: "created_at", Actor: "username",
+		Type: "CASE WHEN success THEN 'login' ELSE 'login_failed' END", Target: "location", Detail: "reason", IP: "ip"},
+	{Name: "alert", Table: "alert_events", Time: "fired_at", Actor: "'system'", Type: "'alert.' || status", Target: "rule_name", Detail: "message", IP: "''"},
+	{Name: "incident", Table: "supernode_incidents", Time: "started_at", Actor: "'system'", Type: "'supernode.' || status", Target: "instance_name", Detail: "reason", IP: "''"},
+}
+
+// eventRecord 统一格式的一条事件
+type eventRecord struct {
+	Source string    `json:"source"`
+	ID     uint      `json:"id"`
+	Time   time.Time `json:"time"`
+	Actor  string    `json:"actor"`
+	Type   string    `json:"type"`
+	Target string    `json:"target,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+	IP     string    `json:"ip,omitempty"`
+	rank   int
+}
+
+// eventCursor 翻页位置：上一页最后一条事件，排序为时间倒序、来源顺序、ID 倒序
+type eventCursor struct {
+	Time time.Time
+	Rank int
+	ID   uint
+}
+
+func (cur eventCursor) String() string {
+	raw := fmt.Sprintf("%d:%d:%d", cur.Time.UnixNano(), cur.Rank, cur.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseEventCursor(s string) (*eventCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	ns, err1 := strconv.ParseInt(parts[0], 10, 64)
+	rank, err2 := strconv.Atoi(parts[1])
+	id, err3 := strconv.ParseUint(parts[2], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil || rank < 0 || rank >= len(eventSources) {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	return &eventCursor{Time: time.Unix(0, ns), Rank: rank, ID: uint(id)}, nil
+}
+
+// eventFilter 查询条件
+type eventFilter struct {
+	Sources []int // eventSources 的下标
+	From    time.Time
+	To      time.Time
+	Actors  []string
+	Types   []string
+	Search  string
+	Cursor  *eventCursor
+}
+
+// parseEventTime 解析 RFC3339 时间或本地时区的日期。数据库中的时间以本地时区的文本保存，比较前统一转换为本地时间
+func parseEventTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Local(), nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+// splitList 逗号分隔的查询参数
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// parseEventFilter 从查询参数读取过滤条件，参数无效时返回错误响应
+func parseEventFilter(c *gin.Context) (eventFilter, bool) {
+	var f eventFilter
+	if names := splitList(c.Query("source")); len(names) > 0 {
+		for _, name := range names {
+			i := eventSourceIndex(name)
+			if i < 0 {
+				respondError(c, 400, ErrInvalidRequest, "Unknown event source", gin.H{"source": name})
+				return f, false
+			}
+			f.Sources = append(f.Sources, i)
+		}
+	} else {
+		for i := range eventSources {
+			f.Sources = append(f.Sources, i)
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &f.From}, {"to", &f.To}} {
+		if v := c.Query(p.name); v != "" {
+			t, err := parseEventTime(v)
+			if err != nil {
+				respondError(c, 400, ErrInvalidRequest, "Invalid time range")
+				return f, false
+			}
+			*p.dst = t
+		}
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		respondError(c, 400, ErrInvalidRequest, "Invalid time range")
+		return f, false
+	}
+	f.Actors = splitList(c.Query("actor"))
+	f.Types = splitList(c.Query("type"))
+	f.Search = strings.TrimSpace(c.Query("q"))
+	if v := c.Query("cursor"); v != "" {
+		cur, err := parseEventCursor(v)
+		if err != nil {
+			respondError(c, 400, ErrInvalidRequest, "Invalid cursor")
+			return f, false
+		}
+		f.Cursor = cur
+	}
+	return f, true
+}
+
+func eventSourceIndex(name string) int {
+	for i, s := range eventSources {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// querySource 按过滤条件查询一个来源，最多 limit 条
+func querySource(rank int, f eventFilter, limit int) ([]eventRecord, error) {
+	s := eventSources[rank]
+	q := db.Table(s.Table).Select(fmt.Sprintf("id, %s AS time, %s AS actor, %s AS type, %s AS target, %s AS detail, %s AS ip",
+		s.Time, s.Actor, s.Type, s.Target, s.Detail, s.IP))
+	if !f.From.IsZero() {
+		q = q.Where(s.Time+" >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q = q.Where(s.Time+" < ?", f.To)
+	}
+	if len(f.Actors) > 0 {
+		q = q.Where(s.Actor+" IN ?", f.Actors)
+	}
+	if len(f.Types) > 0 {
+		q = q.Where(s.Type+" IN ?", f.Types)
+	}
+	if f.Search != "" {
+		like := "%" + f.Search + "%"
+		q = q.Where(fmt.Sprintf("(%s LIKE ? OR %s LIKE ? OR %s LIKE ?)", s.Target, s.Detail, s.Type), like, like, like)
+	}
+	if cur := f.Cursor; cur != nil {
+		// 同一时间的事件按来源顺序、再按 ID 倒序排列
+		switch {
+		case rank > cur.Rank:
+			q = q.Where(s.Time+" <= ?", cur.Time)
+		case rank == cur.Rank:
+			q = q.Where(fmt.Sprintf("(%s < ? OR (%s = ? AND id < ?))", s.Time, s.Time), cur.Time, cur.Time, cur.ID)
+		default:
+			q = q.Where(s.Time+" < ?", cur.Time)
+		}
+	}
+	var list []eventRecord
+	err := q.Order(s.Time + " DESC, id DESC").Limit(limit).Scan(&list).Error
+	for i := range list {
+		list[i].Source, list[i].rank = s.Name, rank
+	}
+	return list, err
+}
+
+// queryEvents 合并各来源的查询结果，返回一页事件，还有更多时返回下一页的 cursor
+func queryEvents(f eventFilter, limit int) ([]eventRecord, *eventCursor, error) {
+	all := make([]eventRecord, 0)
+	for _, rank := range f.Sources {
+		list, err := querySource(rank, f, limit+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		all = append(all, list...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i], all[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.After(b.Time)
+		}
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		return a.ID > b.ID
+	})
+	if len(all) <= limit {
+		return all, nil, nil
+	}
+	all = all[:limit]
+	last := all[limit-1]
+	return all, &eventCursor{Time: last.Time, Rank: last.rank, ID: last.ID}, nil
+}
+
+// getEvents 分页查询事件
+func getEvents(c *gin.Context) {
+	f, ok := parseEventFilter(c)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultEventLimit)))
+	if err != nil || limit < 1 || limit > maxEventLimit {
+		limit = defaultEventLimit
+	}
+	events, next, err := queryEvents(f, limit)
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	res := gin.H{"events": events, "next_cursor": ""}
+	if next != nil {
+		res["next_cursor"] = next.String()
+	}
+	c.JSON(200, res)
+}
+
+// csvCell 防止以公式字符开头的内容在电子表格中被当作公式执行
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// exportEvents 以 CSV 导出匹配的事件，导出操作本身记录在审计日志中
+func exportEvents(c *gin.Context) {
+	f, ok := parseEventFilter(c)
+	if !ok {
+		return
+	}
+	// 先查询第一页，查询失败时仍可返回错误响应
+	events, next, err := queryEvents(f, eventExportPage)
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	recordAudit(c, "events.export", c.Request.URL.RawQuery, "")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="n2n-events-%s.csv"`, time.Now().Format("20060102-150405")))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(200)
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"time", "source", "id", "actor", "type", "target", "detail", "ip"})
+	total := 0
+	for {
+		for _, ev := range events {
+			w.Write([]string{ev.Time.Format(time.RFC3339), ev.Source, strconv.FormatUint(uint64(ev.ID), 10),
+				csvCell(ev.Actor), csvCell(ev.Type), csvCell(ev.Target), csvCell(ev.Detail), ev.IP})
+		}
+		total += len(events)
+		if next == nil || total >= maxEventExport {
+			break
+		}
+		f.Cursor = next
+		if events, next, err = queryEvents(f, eventExportPage); err != nil {
+			break
+		}
+	}
+	w.Flush()
+}
+
+// eventTypes 各来源出现过的事件类型，供筛选使用
+func eventTypes(c *gin.Context) {
+	res := make(map[string][]string, len(eventSources))
+	for _, s := range eventSources {
+		types := make([]string, 0)
+		db.Table(s.Table).Distinct(s.Type+" AS type").Order("type").Pluck("type", &types)
+		res[s.Name] = types
+	}
+	c.JSON(200, res)
+}
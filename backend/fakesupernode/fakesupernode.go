@@ -0,0 +1,398 @@
+// Package fakesupernode is a test double for an n2n supernode. It answers the
+// management port in both the legacy text format ("edges") and the n2n 3.x
+// JSON API ("r <tag> <method>"), and emits synthetic journal lines, so the
+// backend can be exercised without root or a real n2n install.
+package fakesupernode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Edge is one registered edge as reported by the fake supernode
+type Edge struct {
+	Community string
+	Name      string
+	Mac       string // AA:BB:CC:DD:EE:FF
+	IP        string // 10.0.0.2/24
+	External  string // 203.0.113.10:40000
+	LastSeen  time.Time
+}
+
+// Peer is a federated supernode that registers with the fake supernode
+type Peer struct {
+	Addr     string // 198.51.100.20:7654
+	LastSeen time.Time
+}
+
+// Server is a fake supernode listening on a UDP management port
+type Server struct {
+	Password string // required for JSON write requests when set
+
+	mu          sync.Mutex
+	conn        net.PacketConn
+	edges       []Edge
+	peers       []Peer
+	verbosity   int
+	stats       map[string]int64 // packetstats counters, keyed by "<type>.<field>"
+	startTime   time.Time
+	lastFwd     time.Time
+	lastReg     time.Time
+	subscribers map[chan string]bool
+	recent      []string
+	stop        chan struct{}
+}
+
+const recentLines = 500
+
+// Version is the n2n version the fake supernode claims to be
+const Version = "3.0.0"
+
+// New creates a server with the given initial edge table
+func New(edges []Edge) *Server {
+	return &Server{
+		edges:       append([]Edge(nil), edges...),
+		verbosity:   2,
+		stats:       make(map[string]int64),
+		startTime:   time.Now(),
+		subscribers: make(map[chan string]bool),
+		stop:        make(chan struct{}),
+	}
+}
+
+// Listen starts answering management requests on addr (e.g. "127.0.0.1:0")
+func (s *Server) Listen(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+	go s.serve(conn)
+	return nil
+}
+
+// Addr returns the management address the server listens on
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return ""
+	}
+	return s.conn.LocalAddr().String()
+}
+
+// Close stops the server and the simulation
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	for ch := range s.subscribers {
+		close(ch)
+		delete(s.subscribers, ch)
+	}
+}
+
+// SetEdges replaces the edge table
+func (s *Server) SetEdges(edges []Edge) {
+	s.mu.Lock()
+	s.edges = append([]Edge(nil), edges...)
+	s.mu.Unlock()
+}
+
+// SetPeers replaces the federation peer table
+func (s *Server) SetPeers(peers []Peer) {
+	s.mu.Lock()
+	s.peers = append([]Peer(nil), peers...)
+	s.mu.Unlock()
+}
+
+func (s *Server) hasEdge(mac string) bool {
+	for _, e := range s.edges {
+		if e.Mac == mac {
+			return true
+		}
+	}
+	return false
+}
+
+// Edges returns a copy of the edge table
+func (s *Server) Edges() []Edge {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Edge(nil), s.edges...)
+}
+
+// Verbosity returns the current trace level
+func (s *Server) Verbosity() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.verbosity
+}
+
+func (s *Server) serve(conn net.PacketConn) {
+	buf := make([]byte, 2048)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		for _, chunk := range s.handle(strings.TrimSpace(string(buf[:n]))) {
+			conn.WriteTo([]byte(chunk), addr)
+		}
+	}
+}
+
+// handle returns the reply datagrams for one request
+func (s *Server) handle(req string) []string {
+	fields := strings.Fields(req)
+	if len(fields) >= 3 && (fields[0] == "r" || fields[0] == "w") {
+		return s.handleJSON(fields[0], fields[1], fields[2], fields[3:])
+	}
+	if req == "" || req == "edges" || req == "help" {
+		return []string{s.legacyEdges()}
+	}
+	return []string{"Help for supernode management console:\n\tedges\n"}
+}
+
+// legacyEdges renders the edge table like n2n 2.8/3.x text management output
+func (s *Server) legacyEdges() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sb strings.Builder
+	sb.WriteString(" ### | TAP             | MAC               | EDGE                  | DESC        | LAST SEEN\n")
+	byCommunity := make(map[string][]Edge)
+	var order []string
+	for _, e := range s.edges {
+		if _, ok := byCommunity[e.Community]; !ok {
+			order = append(order, e.Community)
+		}
+		byCommunity[e.Community] = append(byCommunity[e.Community], e)
+	}
+	id := 1
+	for _, comm := range order {
+		sb.WriteString("community: " + comm + "\n")
+		for _, e := range byCommunity[comm] {
+			fmt.Fprintf(&sb, " %3d | %-15s | %s | %-21s | %-11s | %d\n", id, e.IP, e.Mac, e.External, e.Name, e.LastSeen.Unix())
+			id++
+		}
+	}
+	sb.WriteString("----------------------------------------------------------------------------------\n")
+	sb.WriteString("SUPERNODES\n")
+	return sb.String()
+}
+
+func (s *Server) handleJSON(kind, tagField, method string, args []string) []string {
+	parts := strings.SplitN(tagField, ":", 3)
+	tag := parts[0]
+	reply := func(objs ...map[string]interface{}) []string {
+		out := make([]string, 0, len(objs))
+		for _, o := range objs {
+			o["_tag"] = tag
+			b, _ := json.Marshal(o)
+			out = append(out, string(b)+"\n")
+		}
+		return out
+	}
+	errReply := func(e string) []string {
+		return reply(map[string]interface{}{"_type": "error", "error": e})
+	}
+	if kind == "w" && s.Password != "" && (len(parts) < 3 || parts[2] != s.Password) {
+		return errReply("badauth")
+	}
+
+	begin := map[string]interface{}{"_type": "begin", "cmd": method}
+	end := map[string]interface{}{"_type": "end", "cmd": method}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch method {
+	case "edges":
+		objs := []map[string]interface{}{begin}
+		for _, e := range s.edges {
+			objs = append(objs, map[string]interface{}{
+				"_type": "row", "mode": "pSp", "community": e.Community, "ip4addr": e.IP, "macaddr": e.Mac,
+				"sockaddr": e.External, "desc": e.Name, "last_seen": e.LastSeen.Unix(),
+			})
+		}
+		return reply(append(objs, end)...)
+	case "communities":
+		objs := []map[string]interface{}{begin}
+		seen := make(map[string]bool)
+		for _, e := range s.edges {
+			if !seen[e.Community] {
+				seen[e.Community] = true
+				objs = append(objs, map[string]interface{}{"_type": "row", "community": e.Community, "purgeable": true, "is_federation": false})
+			}
+		}
+		return reply(append(objs, end)...)
+	case "verbose":
+		if kind == "w" {
+			if len(args) == 0 {
+				return errReply("badargs")
+			}
+			level, err := strconv.Atoi(args[0])
+			if err != nil {
+				return errReply("badargs")
+			}
+			s.verbosity = level
+		}
+		return reply(begin, map[string]interface{}{"_type": "row", "traceLevel": s.verbosity}, end)
+	case "reload_communities":
+		if kind != "w" {
+			return errReply("writeonly")
+		}
+		return reply(begin, end)
+	case "supernodes":
+		objs := []map[string]interface{}{begin}
+		for _, p := range s.peers {
+			objs = append(objs, map[string]interface{}{
+				"_type": "row", "version": "3.0.0", "purgeable": false, "current": 0, "sockaddr": p.Addr,
+				"selection": "", "last_seen": p.LastSeen.Unix(), "uptime": 0,
+			})
+		}
+		return reply(append(objs, end)...)
+	case "packetstats":
+		// same rows as a n2n 3.x supernode
+		return reply(begin,
+			map[string]interface{}{"_type": "row", "type": "forward", "tx_pkt": s.stats["forward.tx_pkt"]},
+			map[string]interface{}{"_type": "row", "type": "broadcast", "tx_pkt": s.stats["broadcast.tx_pkt"]},
+			map[string]interface{}{"_type": "row", "type": "reg_super", "rx_pkt": s.stats["reg_super.rx_pkt"], "nak": s.stats["reg_super.nak"]},
+			map[string]interface{}{"_type": "row", "type": "errors", "tx_pkt": s.stats["errors.tx_pkt"]},
+			end)
+	case "timestamps":
+		return reply(begin, map[string]interface{}{
+			"_type": "row", "start_time": s.startTime.Unix(), "last_fwd": unixOrZero(s.lastFwd), "last_reg_super": unixOrZero(s.lastReg),
+		}, end)
+	}
+	return errReply("unknowncmd")
+}
+
+// Subscribe returns a channel receiving every synthetic journal line and a cancel function
+func (s *Server) Subscribe() (<-chan string, func()) {
+	ch := make(chan string, 256)
+	s.mu.Lock()
+	s.subscribers[ch] = true
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.subscribers[ch] {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Recent returns up to n of the most recently emitted journal lines
+func (s *Server) Recent(n int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n > len(s.recent) {
+		n = len(s.recent)
+	}
+	return append([]string(nil), s.recent[len(s.recent)-n:]...)
+}
+
+// Emit publishes a journal line in the journalctl short format
+func (s *Server) Emit(format string, args ...interface{}) {
+	line := fmt.Sprintf("%s fakehost supernode[4242]: %s", time.Now().Format("Jan 02 15:04:05"), fmt.Sprintf(format, args...))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recent = append(s.recent, line)
+	if len(s.recent) > recentLines {
+		s.recent = s.recent[len(s.recent)-recentLines:]
+	}
+	for ch := range s.subscribers {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// Simulate keeps the fake supernode lively: edges refresh their last-seen time,
+// a few pairs are relayed through the supernode and an edge occasionally
+// disconnects and re-registers. It returns when Close is called.
+func (s *Server) Simulate(interval time.Duration) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var away *Edge
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		now := time.Now()
+		for i := range s.edges {
+			s.edges[i].LastSeen = now
+		}
+		for i := range s.peers {
+			s.peers[i].LastSeen = now
+		}
+		edges := append([]Edge(nil), s.edges...)
+		s.stats["forward.tx_pkt"] += int64(rng.Intn(200))
+		s.stats["broadcast.tx_pkt"] += int64(rng.Intn(20))
+		s.stats["reg_super.rx_pkt"] += int64(len(s.edges))
+		if rng.Intn(10) == 0 {
+			s.stats["reg_super.nak"]++
+		}
+		s.lastFwd, s.lastReg = now, now
+		s.mu.Unlock()
+
+		// Relay a couple of fixed pairs so relay analytics have data
+		for _, i := range []int{0, 3} {
+			if i+1 >= len(edges) || edges[i].Community != edges[i+1].Community {
+				continue
+			}
+			for n := 0; n < 1+rng.Intn(3); n++ {
+				s.Emit("forwarding packet to %s: from %s to %s", edges[i+1].External, edges[i].Mac, edges[i+1].Mac)
+			}
+		}
+
+		switch {
+		case away != nil && rng.Intn(3) == 0:
+			s.mu.Lock()
+			away.LastSeen = now
+			if !s.hasEdge(away.Mac) { // SetEdges may have restored it meanwhile
+				s.edges = append(s.edges, *away)
+			}
+			s.mu.Unlock()
+			s.Emit("register_super: REGISTER_SUPER from %s [%s] community '%s'", away.Mac, away.External, away.Community)
+			away = nil
+		case away == nil && len(edges) > 2 && rng.Intn(10) == 0:
+			s.mu.Lock()
+			idx := len(s.edges) - 1
+			e := s.edges[idx]
+			s.edges = s.edges[:idx]
+			s.mu.Unlock()
+			away = &e
+			s.Emit("purge_expired_nodes: purging %s [%s] from community '%s'", e.Mac, e.External, e.Community)
+		}
+	}
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
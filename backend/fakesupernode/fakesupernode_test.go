@@ -0,0 +1,166 @@
+package fakesupernode_test
+
+import (
+	"errors"
+	"n2n_ui/backend/fakesupernode"
+	"n2n_ui/backend/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testEdges = []fakesupernode.Edge{
+	{Community: "office", Name: "laptop", Mac: "02:00:00:00:01:01", IP: "10.0.0.2/24", External: "203.0.113.10:40000"},
+	{Community: "office", Name: "desktop", Mac: "02:00:00:00:01:02", IP: "10.0.0.3/24", External: "203.0.113.11:40001"},
+	{Community: "lab", Name: "pi", Mac: "02:00:00:00:02:01", IP: "10.1.0.2/24", External: "198.51.100.5:50000"},
+}
+
+// startServer runs a fake supernode on a free loopback port and returns a client for it
+func startServer(t *testing.T, password string) (*fakesupernode.Server, *utils.MgmtClient) {
+	t.Helper()
+	now := time.Now()
+	edges := append([]fakesupernode.Edge(nil), testEdges...)
+	for i := range edges {
+		edges[i].LastSeen = now
+	}
+	s := fakesupernode.New(edges)
+	s.Password = password
+	if err := s.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(s.Close)
+	c := &utils.MgmtClient{Addr: s.Addr(), Password: password, Timeout: 500 * time.Millisecond}
+	t.Cleanup(c.Close)
+	return s, c
+}
+
+func TestFetchEdgeInfo(t *testing.T) {
+	s, c := startServer(t, "")
+	edges, err := c.FetchEdgeInfo()
+	if err != nil {
+		t.Fatalf("FetchEdgeInfo: %v", err)
+	}
+	if len(edges) != len(testEdges) {
+		t.Fatalf("got %d edges, want %d: %v", len(edges), len(testEdges), edges)
+	}
+	e, ok := edges["020000000201"]
+	if !ok {
+		t.Fatalf("edge 02:00:00:00:02:01 missing: %v", edges)
+	}
+	if e.Community != "lab" || e.Internal != "10.1.0.2/24" || e.External != "198.51.100.5:50000" || e.LastSeen == 0 {
+		t.Errorf("unexpected edge %+v", e)
+	}
+
+	// the edge table is read fresh on every call
+	s.SetEdges(testEdges[:1])
+	edges, err = c.FetchEdgeInfo()
+	if err != nil {
+		t.Fatalf("FetchEdgeInfo after SetEdges: %v", err)
+	}
+	if len(edges) != 1 {
+		t.Errorf("got %d edges after SetEdges, want 1: %v", len(edges), edges)
+	}
+}
+
+func TestLegacyEdgeTable(t *testing.T) {
+	_, c := startServer(t, "")
+	resp, err := c.Query("edges")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	for _, want := range []string{"community: office", "community: lab", "02:00:00:00:01:02", "SUPERNODES"} {
+		if !strings.Contains(resp, want) {
+			t.Errorf("legacy edge table has no %q:\n%s", want, resp)
+		}
+	}
+}
+
+func TestVerbosity(t *testing.T) {
+	s, c := startServer(t, "secret")
+	if err := c.SetVerbosity(4); err != nil {
+		t.Fatalf("SetVerbosity: %v", err)
+	}
+	level, err := c.GetVerbosity()
+	if err != nil {
+		t.Fatalf("GetVerbosity: %v", err)
+	}
+	if level != 4 || s.Verbosity() != 4 {
+		t.Errorf("verbosity = %d (server %d), want 4", level, s.Verbosity())
+	}
+}
+
+func TestWriteAuthentication(t *testing.T) {
+	s, _ := startServer(t, "secret")
+	wrong := &utils.MgmtClient{Addr: s.Addr(), Password: "wrong", Timeout: 500 * time.Millisecond}
+	defer wrong.Close()
+	if err := wrong.ReloadCommunities(); err == nil || !strings.Contains(err.Error(), "badauth") {
+		t.Errorf("ReloadCommunities with a wrong password: err = %v, want badauth", err)
+	}
+	// reads need no password
+	if _, err := wrong.GetVerbosity(); err != nil {
+		t.Errorf("GetVerbosity with a wrong password: %v", err)
+	}
+
+	right := &utils.MgmtClient{Addr: s.Addr(), Password: "secret", Timeout: 500 * time.Millisecond}
+	defer right.Close()
+	if err := right.ReloadCommunities(); err != nil {
+		t.Errorf("ReloadCommunities: %v", err)
+	}
+}
+
+func TestUnsupportedCommand(t *testing.T) {
+	_, c := startServer(t, "")
+	if err := c.DropEdge("02:00:00:00:01:01"); !errors.Is(err, utils.ErrUnsupported) {
+		t.Errorf("DropEdge: err = %v, want ErrUnsupported", err)
+	}
+	if c.Status().Failures != 0 {
+		t.Errorf("an error reply counted as a mgmt failure: %+v", c.Status())
+	}
+}
+
+func TestSupernodesAndPacketStats(t *testing.T) {
+	s, c := startServer(t, "")
+	s.SetPeers([]fakesupernode.Peer{{Addr: "198.51.100.20:7654", LastSeen: time.Now()}})
+	rows, err := c.Supernodes()
+	if err != nil {
+		t.Fatalf("Supernodes: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["sockaddr"] != "198.51.100.20:7654" {
+		t.Errorf("Supernodes = %v", rows)
+	}
+
+	rows, err = c.PacketStats()
+	if err != nil {
+		t.Fatalf("PacketStats: %v", err)
+	}
+	types := make(map[string]bool)
+	for _, row := range rows {
+		typ, _ := row["type"].(string)
+		types[typ] = true
+	}
+	for _, want := range []string{"forward", "broadcast", "reg_super", "errors"} {
+		if !types[want] {
+			t.Errorf("PacketStats has no %q row: %v", want, rows)
+		}
+	}
+}
+
+func TestJournalLines(t *testing.T) {
+	s, _ := startServer(t, "")
+	lines, cancel := s.Subscribe()
+	defer cancel()
+	s.Emit("forwarding packet to %s: from %s to %s", testEdges[1].External, testEdges[0].Mac, testEdges[1].Mac)
+
+	select {
+	case line := <-lines:
+		ev, ok := utils.ParseLogEvent(line)
+		if !ok || ev.Type != utils.EventRelay || ev.Mac != "020000000101" || ev.DstMac != "020000000102" {
+			t.Errorf("ParseLogEvent(%q) = %+v, %v", line, ev, ok)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscriber received no line")
+	}
+	if recent := s.Recent(10); len(recent) != 1 {
+		t.Errorf("Recent = %v, want one line", recent)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"n2n_ui/backend/grpcapi"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// gRPC API：供程序化集成使用的只读接口（grpcapi/n2nadmin.proto），与 REST API 分开监听 N2N_GRPC_LISTEN。
+// 只接受 mTLS 连接：客户端证书必须由 N2N_GRPC_CLIENT_CA 签发，证书即身份，不再使用 JWT。
+// WatchEvents 推送 dispatchAlert 投递的告警事件，StreamLogs 与 SSE 日志流共用同一个跟踪进程。
+
+const grpcEventBuf = 64 // 事件订阅者缓冲，处理不过来时丢弃
+
+var (
+	alertSubscribers = make(map[chan Alert]bool)
+	alertSubMutex    sync.Mutex
+)
+
+// publishAlertEvent 将告警推送给所有订阅者，订阅者处理不过来时丢弃
+func publishAlertEvent(a Alert) {
+	alertSubMutex.Lock()
+	defer alertSubMutex.Unlock()
+	for ch := range alertSubscribers {
+		select {
+		case ch <- a:
+		default:
+		}
+	}
+}
+
+func subscribeAlerts() chan Alert {
+	ch := make(chan Alert, grpcEventBuf)
+	alertSubMutex.Lock()
+	alertSubscribers[ch] = true
+	alertSubMutex.Unlock()
+	return ch
+}
+
+func unsubscribeAlerts(ch chan Alert) {
+	alertSubMutex.Lock()
+	delete(alertSubscribers, ch)
+	alertSubMutex.Unlock()
+}
+
+// grpcTLSConfig 加载服务端证书与客户端 CA，要求并校验客户端证书
+func grpcTLSConfig() (*tls.Config, error) {
+	if appConfig.GRPCCert == "" || appConfig.GRPCKey == "" || appConfig.GRPCClientCA == "" {
+		return nil, errors.New("N2N_GRPC_CERT, N2N_GRPC_KEY and N2N_GRPC_CLIENT_CA are required")
+	}
+	cert, err := tls.LoadX509KeyPair(appConfig.GRPCCert, appConfig.GRPCKey)
+	if err != nil {
+		return nil, fmt.Errorf("load server certificate: %w", err)
+	}
+	pem, err := os.ReadFile(appConfig.GRPCClientCA)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", appConfig.GRPCClientCA)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// grpcClientName 返回调用方客户端证书的 CN，用于日志
+func grpcClientName(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "unknown"
+	}
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) > 0 {
+		return info.State.PeerCertificates[0].Subject.CommonName
+	}
+	return p.Addr.String()
+}
+
+// grpcLogStream 记录流式调用的开始与结束，一元调用太频繁不记录
+func grpcLogStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	client := grpcClientName(ss.Context())
+	log.Printf("gRPC: %s opened %s", client, info.FullMethod)
+	err := handler(srv, ss)
+	log.Printf("gRPC: %s closed %s", client, info.FullMethod)
+	return err
+}
+
+// startGRPCServer 按 N2N_GRPC_LISTEN 启动 gRPC 服务，只在配置了监听地址时启动
+func startGRPCServer() {
+	tlsCfg, err := grpcTLSConfig()
+	if err != nil {
+		log.Printf("gRPC API disabled: %v", err)
+		return
+	}
+	lis, err := net.Listen("tcp", appConfig.GRPCListen)
+	if err != nil {
+		log.Printf("gRPC API failed to listen on %s: %v", appConfig.GRPCListen, err)
+		return
+	}
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsCfg)), grpc.StreamInterceptor(grpcLogStream))
+	grpcapi.RegisterN2NAdminServer(srv, &grpcServer{})
+	log.Printf("gRPC API listening on %s (mTLS)", appConfig.GRPCListen)
+	if err := srv.Serve(lis); err != nil {
+		log.Printf("gRPC API stopped: %v", err)
+	}
+}
+
+// grpcServer 实现 grpcapi.N2NAdminServer
+type grpcServer struct {
+	grpcapi.UnimplementedN2NAdminServer
+}
+
+func grpcTime(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// grpcNode 合并数据库节点与 supernode 在线信息
+func grpcNode(n models.Node, edges map[string]utils.EdgeInfo, relays map[string]bool) *grpcapi.Node {
+	m := n.MacAddress
+	info, online := edges[m]
+	res := &grpcapi.Node{
+		Id: uint32(n.ID), Name: n.Name, Community: n.Community, IpAddress: n.IPAddress,
+		MacAddress: utils.MAC(m).Format(), Description: n.Description, Tags: utils.SplitList(n.Tags),
+		Enabled: n.IsEnabled, Online: online, Relay: online && relays[m],
+		LastSeen: grpcTime(n.LastSeen), ExpiresAt: grpcTime(n.ExpiresAt),
+	}
+	if online {
+		res.ExternalIp = strings.Split(info.External, ":")[0]
+	}
+	return res
+}
+
+func (s *grpcServer) ListNodes(ctx context.Context, req *grpcapi.ListNodesRequest) (*grpcapi.ListNodesResponse, error) {
+	var nodes []models.Node
+	q := db.Order("id")
+	if req.Community != "" {
+		q = q.Where("community = ?", req.Community)
+	}
+	if err := q.Find(&nodes).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to load nodes")
+	}
+	edges, _ := allEdgeInfo()
+	relays := relaySources()
+	res := &grpcapi.ListNodesResponse{Nodes: make([]*grpcapi.Node, 0, len(nodes))}
+	for _, n := range nodes {
+		item := grpcNode(n, edges, relays)
+		if req.OnlineOnly && !item.Online {
+			continue
+		}
+		res.Nodes = append(res.Nodes, item)
+	}
+	return res, nil
+}
+
+func (s *grpcServer) GetNode(ctx context.Context, req *grpcapi.GetNodeRequest) (*grpcapi.Node, error) {
+	var (
+		n     models.Node
+		found bool
+	)
+	switch key := req.Key.(type) {
+	case *grpcapi.GetNodeRequest_Id:
+		found = db.First(&n, key.Id).Error == nil
+	case *grpcapi.GetNodeRequest_MacAddress:
+		n, found = findNodeByMac(key.MacAddress)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "id or mac_address is required")
+	}
+	if !found {
+		return nil, status.Error(codes.NotFound, "node not found")
+	}
+	edges, _ := allEdgeInfo()
+	return grpcNode(n, edges, relaySources()), nil
+}
+
+func (s *grpcServer) ListCommunities(ctx context.Context, req *grpcapi.ListCommunitiesRequest) (*grpcapi.ListCommunitiesResponse, error) {
+	var comms []models.Community
+	if err := db.Order("id").Find(&comms).Error; err != nil {
+		return nil, status.Error(codes.Internal, "failed to load communities")
+	}
+	var nodes []models.Node
+	db.Select("community", "mac_address").Find(&nodes)
+	edges, _ := allEdgeInfo()
+	total, online := make(map[string]uint32), make(map[string]uint32)
+	for _, n := range nodes {
+		total[n.Community]++
+		if _, ok := edges[n.MacAddress]; ok {
+			online[n.Community]++
+		}
+	}
+	res := &grpcapi.ListCommunitiesResponse{Communities: make([]*grpcapi.Community, 0, len(comms))}
+	for _, cm := range comms {
+		res.Communities = append(res.Communities, &grpcapi.Community{
+			Id: uint32(cm.ID), Name: cm.Name, Range: cm.Range, SupernodeId: uint32(communityInstanceID(cm)),
+			UserAuth: cm.UserAuth, NodeCount: total[cm.Name], OnlineCount: online[cm.Name],
+		})
+	}
+	return res, nil
+}
+
+func (s *grpcServer) WatchEvents(req *grpcapi.WatchEventsRequest, stream grpc.ServerStreamingServer[grpcapi.Event]) error {
+	ch := subscribeAlerts()
+	defer unsubscribeAlerts(ch)
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case a := <-ch:
+			if len(req.Types) > 0 && !containsString(req.Types, a.Type) {
+				continue
+			}
+			err := stream.Send(&grpcapi.Event{
+				Type: a.Type, Level: a.Level, Title: a.Title, Message: a.Message, Time: timestamppb.New(a.Time),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *grpcServer) StreamLogs(req *grpcapi.StreamLogsRequest, stream grpc.ServerStreamingServer[grpcapi.LogLine]) error {
+	rt := runtimeFor(uint(req.InstanceId))
+	if rt == nil {
+		return status.Error(codes.NotFound, "supernode instance not found")
+	}
+	filter := logFilter{grep: strings.ToLower(req.Grep), level: logLevels["debug"]}
+	if req.MinLevel != "" {
+		level, ok := logLevels[strings.ToLower(req.MinLevel)]
+		if !ok {
+			return status.Error(codes.InvalidArgument, "invalid log level")
+		}
+		filter.level = level
+	}
+	send := func(source, line string) error {
+		if !filter.match(line) {
+			return nil
+		}
+		return stream.Send(&grpcapi.LogLine{Source: source, Line: line})
+	}
+
+	ctx := stream.Context()
+	type sourcedLine struct{ source, line string }
+	lines := make(chan sourcedLine, logSubscriberBuf)
+	for _, src := range instanceLogSources(rt.instance) {
+		ch := newLogChannel()
+		backlog := subscribeLogs(src, ch, req.Replay)
+		defer unsubscribeLogs(src, ch)
+		for _, line := range backlog {
+			if err := send(src, line); err != nil {
+				return err
+			}
+		}
+		go func(src string, ch chan string) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case line := <-ch:
+					select {
+					case lines <- sourcedLine{src, line}:
+					default:
+					}
+				}
+			}
+		}(src, ch)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case l := <-lines:
+			if err := send(l.source, l.line); err != nil {
+				return err
+			}
+		}
+	}
+}
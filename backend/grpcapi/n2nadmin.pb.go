@@ -0,0 +1,910 @@
+// gRPC API of n2n-admin, served next to the REST API on N2N_GRPC_LISTEN.
+// Clients authenticate with a TLS client certificate signed by N2N_GRPC_CLIENT_CA.
+//
+// Regenerate the Go code from this directory with:
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative n2nadmin.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: n2nadmin.proto
+
+package grpcapi
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Node struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Community     string                 `protobuf:"bytes,3,opt,name=community,proto3" json:"community,omitempty"`
+	IpAddress     string                 `protobuf:"bytes,4,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	MacAddress    string                 `protobuf:"bytes,5,opt,name=mac_address,json=macAddress,proto3" json:"mac_address,omitempty"` // aa:bb:cc:dd:ee:ff
+	Description   string                 `protobuf:"bytes,6,opt,name=description,proto3" json:"description,omitempty"`
+	Tags          []string               `protobuf:"bytes,7,rep,name=tags,proto3" json:"tags,omitempty"`
+	Enabled       bool                   `protobuf:"varint,8,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Online        bool                   `protobuf:"varint,9,opt,name=online,proto3" json:"online,omitempty"`
+	Relay         bool                   `protobuf:"varint,10,opt,name=relay,proto3" json:"relay,omitempty"`                            // online and relayed through a supernode
+	ExternalIp    string                 `protobuf:"bytes,11,opt,name=external_ip,json=externalIp,proto3" json:"external_ip,omitempty"` // public address seen by the supernode, empty when offline
+	LastSeen      *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Node) Reset() {
+	*x = Node{}
+	mi := &file_n2nadmin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Node) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Node) ProtoMessage() {}
+
+func (x *Node) ProtoReflect() protoreflect.Message {
+	mi := &file_n2nadmin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Node.ProtoReflect.Descriptor instead.
+func (*Node) Descriptor() ([]byte, []int) {
+	return file_n2nadmin_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Node) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Node) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Node) GetCommunity() string {
+	if x != nil {
+		return x.Community
+	}
+	return ""
+}
+
+func (x *Node) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *Node) GetMacAddress() string {
+	if x != nil {
+		return x.MacAddress
+	}
+	return ""
+}
+
+func (x *Node) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Node) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Node) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *Node) GetOnline() bool {
+	if x != nil {
+		return x.Online
+	}
+	return false
+}
+
+func (x *Node) GetRelay() bool {
+	if x != nil {
+		return x.Relay
+	}
+	return false
+}
+
+func (x *Node) GetExternalIp() string {
+	if x != nil {
+		return x.ExternalIp
+	}
+	return ""
+}
+
+func (x *Node) GetLastSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSeen
+	}
+	return nil
+}
+
+func (x *Node) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+type ListNodesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Community     string                 `protobuf:"bytes,1,opt,name=community,proto3" json:"community,omitempty"` // empty for all communities
+	OnlineOnly    bool                   `protobuf:"varint,2,opt,name=online_only,json=onlineOnly,proto3" json:"online_only,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNodesRequest) Reset() {
+	*x = ListNodesRequest{}
+	mi := &file_n2nadmin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNodesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNodesRequest) ProtoMessage() {}
+
+func (x *ListNodesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_n2nadmin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNodesRequest.ProtoReflect.Descriptor instead.
+func (*ListNodesRequest) Descriptor() ([]byte, []int) {
+	return file_n2nadmin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListNodesRequest) GetCommunity() string {
+	if x != nil {
+		return x.Community
+	}
+	return ""
+}
+
+func (x *ListNodesRequest) GetOnlineOnly() bool {
+	if x != nil {
+		return x.OnlineOnly
+	}
+	return false
+}
+
+type ListNodesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Nodes         []*Node                `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNodesResponse) Reset() {
+	*x = ListNodesResponse{}
+	mi := &file_n2nadmin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNodesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNodesResponse) ProtoMessage() {}
+
+func (x *ListNodesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_n2nadmin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNodesResponse.ProtoReflect.Descriptor instead.
+func (*ListNodesResponse) Descriptor() ([]byte, []int) {
+	return file_n2nadmin_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListNodesResponse) GetNodes() []*Node {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+type GetNodeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Key:
+	//
+	//	*GetNodeRequest_Id
+	//	*GetNodeRequest_MacAddress
+	Key           isGetNodeRequest_Key `protobuf_oneof:"key"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetNodeRequest) Reset() {
+	*x = GetNodeRequest{}
+	mi := &file_n2nadmin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetNodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNodeRequest) ProtoMessage() {}
+
+func (x *GetNodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_n2nadmin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNodeRequest.ProtoReflect.Descriptor instead.
+func (*GetNodeRequest) Descriptor() ([]byte, []int) {
+	return file_n2nadmin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetNodeRequest) GetKey() isGetNodeRequest_Key {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *GetNodeRequest) GetId() uint32 {
+	if x != nil {
+		if x, ok := x.Key.(*GetNodeRequest_Id); ok {
+			return x.Id
+		}
+	}
+	return 0
+}
+
+func (x *GetNodeRequest) GetMacAddress() string {
+	if x != nil {
+		if x, ok := x.Key.(*GetNodeRequest_MacAddress); ok {
+			return x.MacAddress
+		}
+	}
+	return ""
+}
+
+type isGetNodeRequest_Key interface {
+	isGetNodeRequest_Key()
+}
+
+type GetNodeRequest_Id struct {
+	Id uint32 `protobuf:"varint,1,opt,name=id,proto3,oneof"`
+}
+
+type GetNodeRequest_MacAddress struct {
+	MacAddress string `protobuf:"bytes,2,opt,name=mac_address,json=macAddress,proto3,oneof"`
+}
+
+func (*GetNodeRequest_Id) isGetNodeRequest_Key() {}
+
+func (*GetNodeRequest_MacAddress) isGetNodeRequest_Key() {}
+
+type Community struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Range         string                 `protobuf:"bytes,3,opt,name=range,proto3" json:"range,omitempty"`
+	SupernodeId   uint32                 `protobuf:"varint,4,opt,name=supernode_id,json=supernodeId,proto3" json:"supernode_id,omitempty"`
+	UserAuth      bool                   `protobuf:"varint,5,opt,name=user_auth,json=userAuth,proto3" json:"user_auth,omitempty"`
+	NodeCount     uint32                 `protobuf:"varint,6,opt,name=node_count,json=nodeCount,proto3" json:"node_count,omitempty"`
+	OnlineCount   uint32                 `protobuf:"varint,7,opt,name=online_count,json=onlineCount,proto3" json:"online_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Community) Reset() {
+	*x = Community{}
+	mi := &file_n2nadmin_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Community) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Community) ProtoMessage() {}
+
+func (x *Community) ProtoReflect() protoreflect.Message {
+	mi := &file_n2nadmin_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Community.ProtoReflect.Descriptor instead.
+func (*Community) Descriptor() ([]byte, []int) {
+	return file_n2nadmin_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Community) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Community) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Community) GetRange() string {
+	if x != nil {
+		return x.Range
+	}
+	return ""
+}
+
+func (x *Community) GetSupernodeId() uint32 {
+	if x != nil {
+		return x.SupernodeId
+	}
+	return 0
+}
+
+func (x *Community) GetUserAuth() bool {
+	if x != nil {
+		return x.UserAuth
+	}
+	return false
+}
+
+func (x *Community) GetNodeCount() uint32 {
+	if x != nil {
+		return x.NodeCount
+	}
+	return 0
+}
+
+func (x *Community) GetOnlineCount() uint32 {
+	if x != nil {
+		return x.OnlineCount
+	}
+	return 0
+}
+
+type ListCommunitiesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCommunitiesRequest) Reset() {
+	*x = ListCommunitiesRequest{}
+	mi := &file_n2nadmin_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCommunitiesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCommunitiesRequest) ProtoMessage() {}
+
+func (x *ListCommunitiesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_n2nadmin_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCommunitiesRequest.ProtoReflect.Descriptor instead.
+func (*ListCommunitiesRequest) Descriptor() ([]byte, []int) {
+	return file_n2nadmin_proto_rawDescGZIP(), []int{5}
+}
+
+type ListCommunitiesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Communities   []*Community           `protobuf:"bytes,1,rep,name=communities,proto3" json:"communities,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCommunitiesResponse) Reset() {
+	*x = ListCommunitiesResponse{}
+	mi := &file_n2nadmin_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCommunitiesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCommunitiesResponse) ProtoMessage() {}
+
+func (x *ListCommunitiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_n2nadmin_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCommunitiesResponse.ProtoReflect.Descriptor instead.
+func (*ListCommunitiesResponse) Descriptor() ([]byte, []int) {
+	return file_n2nadmin_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListCommunitiesResponse) GetCommunities() []*Community {
+	if x != nil {
+		return x.Communities
+	}
+	return nil
+}
+
+type WatchEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Types         []string               `protobuf:"bytes,1,rep,name=types,proto3" json:"types,omitempty"` // alert types to receive, empty for all
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	mi := &file_n2nadmin_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEventsRequest) ProtoMessage() {}
+
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_n2nadmin_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_n2nadmin_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *WatchEventsRequest) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`   // node_offline, node_online, supernode_down, alert_rule, ...
+	Level         string                 `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"` // info, warning, critical
+	Title         string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=time,proto3" json:"time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_n2nadmin_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_n2nadmin_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_n2nadmin_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *Event) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Event) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Event) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+type StreamLogsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	InstanceId    uint32                 `protobuf:"varint,1,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"` // supernode instance, 0 for the default instance
+	MinLevel      string                 `protobuf:"bytes,2,opt,name=min_level,json=minLevel,proto3" json:"min_level,omitempty"`        // error, warning, normal, info or debug (default)
+	Grep          string                 `protobuf:"bytes,3,opt,name=grep,proto3" json:"grep,omitempty"`                                // case-insensitive substring filter
+	Replay        bool                   `protobuf:"varint,4,opt,name=replay,proto3" json:"replay,omitempty"`                           // send the most recent lines first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamLogsRequest) Reset() {
+	*x = StreamLogsRequest{}
+	mi := &file_n2nadmin_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamLogsRequest) ProtoMessage() {}
+
+func (x *StreamLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_n2nadmin_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamLogsRequest.ProtoReflect.Descriptor instead.
+func (*StreamLogsRequest) Descriptor() ([]byte, []int) {
+	return file_n2nadmin_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *StreamLogsRequest) GetInstanceId() uint32 {
+	if x != nil {
+		return x.InstanceId
+	}
+	return 0
+}
+
+func (x *StreamLogsRequest) GetMinLevel() string {
+	if x != nil {
+		return x.MinLevel
+	}
+	return ""
+}
+
+func (x *StreamLogsRequest) GetGrep() string {
+	if x != nil {
+		return x.Grep
+	}
+	return ""
+}
+
+func (x *StreamLogsRequest) GetReplay() bool {
+	if x != nil {
+		return x.Replay
+	}
+	return false
+}
+
+type LogLine struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Source        string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"` // systemd unit or log file
+	Line          string                 `protobuf:"bytes,2,opt,name=line,proto3" json:"line,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogLine) Reset() {
+	*x = LogLine{}
+	mi := &file_n2nadmin_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogLine) ProtoMessage() {}
+
+func (x *LogLine) ProtoReflect() protoreflect.Message {
+	mi := &file_n2nadmin_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogLine.ProtoReflect.Descriptor instead.
+func (*LogLine) Descriptor() ([]byte, []int) {
+	return file_n2nadmin_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *LogLine) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *LogLine) GetLine() string {
+	if x != nil {
+		return x.Line
+	}
+	return ""
+}
+
+var File_n2nadmin_proto protoreflect.FileDescriptor
+
+const file_n2nadmin_proto_rawDesc = "" +
+	"\n" +
+	"\x0en2nadmin.proto\x12\vn2nadmin.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x9b\x03\n" +
+	"\x04Node\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1c\n" +
+	"\tcommunity\x18\x03 \x01(\tR\tcommunity\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x04 \x01(\tR\tipAddress\x12\x1f\n" +
+	"\vmac_address\x18\x05 \x01(\tR\n" +
+	"macAddress\x12 \n" +
+	"\vdescription\x18\x06 \x01(\tR\vdescription\x12\x12\n" +
+	"\x04tags\x18\a \x03(\tR\x04tags\x12\x18\n" +
+	"\aenabled\x18\b \x01(\bR\aenabled\x12\x16\n" +
+	"\x06online\x18\t \x01(\bR\x06online\x12\x14\n" +
+	"\x05relay\x18\n" +
+	" \x01(\bR\x05relay\x12\x1f\n" +
+	"\vexternal_ip\x18\v \x01(\tR\n" +
+	"externalIp\x127\n" +
+	"\tlast_seen\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\blastSeen\x129\n" +
+	"\n" +
+	"expires_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\"Q\n" +
+	"\x10ListNodesRequest\x12\x1c\n" +
+	"\tcommunity\x18\x01 \x01(\tR\tcommunity\x12\x1f\n" +
+	"\vonline_only\x18\x02 \x01(\bR\n" +
+	"onlineOnly\"<\n" +
+	"\x11ListNodesResponse\x12'\n" +
+	"\x05nodes\x18\x01 \x03(\v2\x11.n2nadmin.v1.NodeR\x05nodes\"L\n" +
+	"\x0eGetNodeRequest\x12\x10\n" +
+	"\x02id\x18\x01 \x01(\rH\x00R\x02id\x12!\n" +
+	"\vmac_address\x18\x02 \x01(\tH\x00R\n" +
+	"macAddressB\x05\n" +
+	"\x03key\"\xc7\x01\n" +
+	"\tCommunity\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05range\x18\x03 \x01(\tR\x05range\x12!\n" +
+	"\fsupernode_id\x18\x04 \x01(\rR\vsupernodeId\x12\x1b\n" +
+	"\tuser_auth\x18\x05 \x01(\bR\buserAuth\x12\x1d\n" +
+	"\n" +
+	"node_count\x18\x06 \x01(\rR\tnodeCount\x12!\n" +
+	"\fonline_count\x18\a \x01(\rR\vonlineCount\"\x18\n" +
+	"\x16ListCommunitiesRequest\"S\n" +
+	"\x17ListCommunitiesResponse\x128\n" +
+	"\vcommunities\x18\x01 \x03(\v2\x16.n2nadmin.v1.CommunityR\vcommunities\"*\n" +
+	"\x12WatchEventsRequest\x12\x14\n" +
+	"\x05types\x18\x01 \x03(\tR\x05types\"\x91\x01\n" +
+	"\x05Event\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x14\n" +
+	"\x05level\x18\x02 \x01(\tR\x05level\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\x12.\n" +
+	"\x04time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\"}\n" +
+	"\x11StreamLogsRequest\x12\x1f\n" +
+	"\vinstance_id\x18\x01 \x01(\rR\n" +
+	"instanceId\x12\x1b\n" +
+	"\tmin_level\x18\x02 \x01(\tR\bminLevel\x12\x12\n" +
+	"\x04grep\x18\x03 \x01(\tR\x04grep\x12\x16\n" +
+	"\x06replay\x18\x04 \x01(\bR\x06replay\"5\n" +
+	"\aLogLine\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12\x12\n" +
+	"\x04line\x18\x02 \x01(\tR\x04line2\xfb\x02\n" +
+	"\bN2NAdmin\x12J\n" +
+	"\tListNodes\x12\x1d.n2nadmin.v1.ListNodesRequest\x1a\x1e.n2nadmin.v1.ListNodesResponse\x129\n" +
+	"\aGetNode\x12\x1b.n2nadmin.v1.GetNodeRequest\x1a\x11.n2nadmin.v1.Node\x12\\\n" +
+	"\x0fListCommunities\x12#.n2nadmin.v1.ListCommunitiesRequest\x1a$.n2nadmin.v1.ListCommunitiesResponse\x12D\n" +
+	"\vWatchEvents\x12\x1f.n2nadmin.v1.WatchEventsRequest\x1a\x12.n2nadmin.v1.Event0\x01\x12D\n" +
+	"\n" +
+	"StreamLogs\x12\x1e.n2nadmin.v1.StreamLogsRequest\x1a\x14.n2nadmin.v1.LogLine0\x01B\x18Z\x16n2n_ui/backend/grpcapib\x06proto3"
+
+var (
+	file_n2nadmin_proto_rawDescOnce sync.Once
+	file_n2nadmin_proto_rawDescData []byte
+)
+
+func file_n2nadmin_proto_rawDescGZIP() []byte {
+	file_n2nadmin_proto_rawDescOnce.Do(func() {
+		file_n2nadmin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_n2nadmin_proto_rawDesc), len(file_n2nadmin_proto_rawDesc)))
+	})
+	return file_n2nadmin_proto_rawDescData
+}
+
+var file_n2nadmin_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_n2nadmin_proto_goTypes = []any{
+	(*Node)(nil),                    // 0: n2nadmin.v1.Node
+	(*ListNodesRequest)(nil),        // 1: n2nadmin.v1.ListNodesRequest
+	(*ListNodesResponse)(nil),       // 2: n2nadmin.v1.ListNodesResponse
+	(*GetNodeRequest)(nil),          // 3: n2nadmin.v1.GetNodeRequest
+	(*Community)(nil),               // 4: n2nadmin.v1.Community
+	(*ListCommunitiesRequest)(nil),  // 5: n2nadmin.v1.ListCommunitiesRequest
+	(*ListCommunitiesResponse)(nil), // 6: n2nadmin.v1.ListCommunitiesResponse
+	(*WatchEventsRequest)(nil),      // 7: n2nadmin.v1.WatchEventsRequest
+	(*Event)(nil),                   // 8: n2nadmin.v1.Event
+	(*StreamLogsRequest)(nil),       // 9: n2nadmin.v1.StreamLogsRequest
+	(*LogLine)(nil),                 // 10: n2nadmin.v1.LogLine
+	(*timestamppb.Timestamp)(nil),   // 11: google.protobuf.Timestamp
+}
+var file_n2nadmin_proto_depIdxs = []int32{
+	11, // 0: n2nadmin.v1.Node.last_seen:type_name -> google.protobuf.Timestamp
+	11, // 1: n2nadmin.v1.Node.expires_at:type_name -> google.protobuf.Timestamp
+	0,  // 2: n2nadmin.v1.ListNodesResponse.nodes:type_name -> n2nadmin.v1.Node
+	4,  // 3: n2nadmin.v1.ListCommunitiesResponse.communities:type_name -> n2nadmin.v1.Community
+	11, // 4: n2nadmin.v1.Event.time:type_name -> google.protobuf.Timestamp
+	1,  // 5: n2nadmin.v1.N2NAdmin.ListNodes:input_type -> n2nadmin.v1.ListNodesRequest
+	3,  // 6: n2nadmin.v1.N2NAdmin.GetNode:input_type -> n2nadmin.v1.GetNodeRequest
+	5,  // 7: n2nadmin.v1.N2NAdmin.ListCommunities:input_type -> n2nadmin.v1.ListCommunitiesRequest
+	7,  // 8: n2nadmin.v1.N2NAdmin.WatchEvents:input_type -> n2nadmin.v1.WatchEventsRequest
+	9,  // 9: n2nadmin.v1.N2NAdmin.StreamLogs:input_type -> n2nadmin.v1.StreamLogsRequest
+	2,  // 10: n2nadmin.v1.N2NAdmin.ListNodes:output_type -> n2nadmin.v1.ListNodesResponse
+	0,  // 11: n2nadmin.v1.N2NAdmin.GetNode:output_type -> n2nadmin.v1.Node
+	6,  // 12: n2nadmin.v1.N2NAdmin.ListCommunities:output_type -> n2nadmin.v1.ListCommunitiesResponse
+	8,  // 13: n2nadmin.v1.N2NAdmin.WatchEvents:output_type -> n2nadmin.v1.Event
+	10, // 14: n2nadmin.v1.N2NAdmin.StreamLogs:output_type -> n2nadmin.v1.LogLine
+	10, // [10:15] is the sub-list for method output_type
+	5,  // [5:10] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_n2nadmin_proto_init() }
+func file_n2nadmin_proto_init() {
+	if File_n2nadmin_proto != nil {
+		return
+	}
+	file_n2nadmin_proto_msgTypes[3].OneofWrappers = []any{
+		(*GetNodeRequest_Id)(nil),
+		(*GetNodeRequest_MacAddress)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_n2nadmin_proto_rawDesc), len(file_n2nadmin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_n2nadmin_proto_goTypes,
+		DependencyIndexes: file_n2nadmin_proto_depIdxs,
+		MessageInfos:      file_n2nadmin_proto_msgTypes,
+	}.Build()
+	File_n2nadmin_proto = out.File
+	file_n2nadmin_proto_goTypes = nil
+	file_n2nadmin_proto_depIdxs = nil
+}
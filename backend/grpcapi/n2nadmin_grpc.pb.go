@@ -0,0 +1,299 @@
+// gRPC API of n2n-admin, served next to the REST API on N2N_GRPC_LISTEN.
+// Clients authenticate with a TLS client certificate signed by N2N_GRPC_CLIENT_CA.
+//
+// Regenerate the Go code from this directory with:
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative n2nadmin.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: n2nadmin.proto
+
+package grpcapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	N2NAdmin_ListNodes_FullMethodName       = "/n2nadmin.v1.N2NAdmin/ListNodes"
+	N2NAdmin_GetNode_FullMethodName         = "/n2nadmin.v1.N2NAdmin/GetNode"
+	N2NAdmin_ListCommunities_FullMethodName = "/n2nadmin.v1.N2NAdmin/ListCommunities"
+	N2NAdmin_WatchEvents_FullMethodName     = "/n2nadmin.v1.N2NAdmin/WatchEvents"
+	N2NAdmin_StreamLogs_FullMethodName      = "/n2nadmin.v1.N2NAdmin/StreamLogs"
+)
+
+// N2NAdminClient is the client API for N2NAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type N2NAdminClient interface {
+	// ListNodes returns the registered nodes merged with their supernode status.
+	ListNodes(ctx context.Context, in *ListNodesRequest, opts ...grpc.CallOption) (*ListNodesResponse, error)
+	// GetNode returns a node by ID or MAC address.
+	GetNode(ctx context.Context, in *GetNodeRequest, opts ...grpc.CallOption) (*Node, error)
+	// ListCommunities returns all communities with node counts.
+	ListCommunities(ctx context.Context, in *ListCommunitiesRequest, opts ...grpc.CallOption) (*ListCommunitiesResponse, error)
+	// WatchEvents streams alert events (node online/offline, supernode down,
+	// rule alerts, ...) as they are dispatched.
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+	// StreamLogs streams the log lines of a supernode instance.
+	StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogLine], error)
+}
+
+type n2NAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewN2NAdminClient(cc grpc.ClientConnInterface) N2NAdminClient {
+	return &n2NAdminClient{cc}
+}
+
+func (c *n2NAdminClient) ListNodes(ctx context.Context, in *ListNodesRequest, opts ...grpc.CallOption) (*ListNodesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListNodesResponse)
+	err := c.cc.Invoke(ctx, N2NAdmin_ListNodes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *n2NAdminClient) GetNode(ctx context.Context, in *GetNodeRequest, opts ...grpc.CallOption) (*Node, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Node)
+	err := c.cc.Invoke(ctx, N2NAdmin_GetNode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *n2NAdminClient) ListCommunities(ctx context.Context, in *ListCommunitiesRequest, opts ...grpc.CallOption) (*ListCommunitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCommunitiesResponse)
+	err := c.cc.Invoke(ctx, N2NAdmin_ListCommunities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *n2NAdminClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &N2NAdmin_ServiceDesc.Streams[0], N2NAdmin_WatchEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type N2NAdmin_WatchEventsClient = grpc.ServerStreamingClient[Event]
+
+func (c *n2NAdminClient) StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogLine], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &N2NAdmin_ServiceDesc.Streams[1], N2NAdmin_StreamLogs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamLogsRequest, LogLine]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type N2NAdmin_StreamLogsClient = grpc.ServerStreamingClient[LogLine]
+
+// N2NAdminServer is the server API for N2NAdmin service.
+// All implementations must embed UnimplementedN2NAdminServer
+// for forward compatibility.
+type N2NAdminServer interface {
+	// ListNodes returns the registered nodes merged with their supernode status.
+	ListNodes(context.Context, *ListNodesRequest) (*ListNodesResponse, error)
+	// GetNode returns a node by ID or MAC address.
+	GetNode(context.Context, *GetNodeRequest) (*Node, error)
+	// ListCommunities returns all communities with node counts.
+	ListCommunities(context.Context, *ListCommunitiesRequest) (*ListCommunitiesResponse, error)
+	// WatchEvents streams alert events (node online/offline, supernode down,
+	// rule alerts, ...) as they are dispatched.
+	WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[Event]) error
+	// StreamLogs streams the log lines of a supernode instance.
+	StreamLogs(*StreamLogsRequest, grpc.ServerStreamingServer[LogLine]) error
+	mustEmbedUnimplementedN2NAdminServer()
+}
+
+// UnimplementedN2NAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedN2NAdminServer struct{}
+
+func (UnimplementedN2NAdminServer) ListNodes(context.Context, *ListNodesRequest) (*ListNodesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNodes not implemented")
+}
+func (UnimplementedN2NAdminServer) GetNode(context.Context, *GetNodeRequest) (*Node, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNode not implemented")
+}
+func (UnimplementedN2NAdminServer) ListCommunities(context.Context, *ListCommunitiesRequest) (*ListCommunitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCommunities not implemented")
+}
+func (UnimplementedN2NAdminServer) WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedN2NAdminServer) StreamLogs(*StreamLogsRequest, grpc.ServerStreamingServer[LogLine]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLogs not implemented")
+}
+func (UnimplementedN2NAdminServer) mustEmbedUnimplementedN2NAdminServer() {}
+func (UnimplementedN2NAdminServer) testEmbeddedByValue()                  {}
+
+// UnsafeN2NAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to N2NAdminServer will
+// result in compilation errors.
+type UnsafeN2NAdminServer interface {
+	mustEmbedUnimplementedN2NAdminServer()
+}
+
+func RegisterN2NAdminServer(s grpc.ServiceRegistrar, srv N2NAdminServer) {
+	// If the following call pancis, it indicates UnimplementedN2NAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&N2NAdmin_ServiceDesc, srv)
+}
+
+func _N2NAdmin_ListNodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNodesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(N2NAdminServer).ListNodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: N2NAdmin_ListNodes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(N2NAdminServer).ListNodes(ctx, req.(*ListNodesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _N2NAdmin_GetNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(N2NAdminServer).GetNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: N2NAdmin_GetNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(N2NAdminServer).GetNode(ctx, req.(*GetNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _N2NAdmin_ListCommunities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCommunitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(N2NAdminServer).ListCommunities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: N2NAdmin_ListCommunities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(N2NAdminServer).ListCommunities(ctx, req.(*ListCommunitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _N2NAdmin_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(N2NAdminServer).WatchEvents(m, &grpc.GenericServerStream[WatchEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type N2NAdmin_WatchEventsServer = grpc.ServerStreamingServer[Event]
+
+func _N2NAdmin_StreamLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(N2NAdminServer).StreamLogs(m, &grpc.GenericServerStream[StreamLogsRequest, LogLine]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type N2NAdmin_StreamLogsServer = grpc.ServerStreamingServer[LogLine]
+
+// N2NAdmin_ServiceDesc is the grpc.ServiceDesc for N2NAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var N2NAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "n2nadmin.v1.N2NAdmin",
+	HandlerType: (*N2NAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListNodes",
+			Handler:    _N2NAdmin_ListNodes_Handler,
+		},
+		{
+			MethodName: "GetNode",
+			Handler:    _N2NAdmin_GetNode_Handler,
+		},
+		{
+			MethodName: "ListCommunities",
+			Handler:    _N2NAdmin_ListCommunities_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _N2NAdmin_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamLogs",
+			Handler:       _N2NAdmin_StreamLogs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "n2nadmin.proto",
+}
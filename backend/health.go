@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 健康检查：/api/health 返回各依赖的详细状态，/livez 与 /readyz 供容器编排的存活 / 就绪探针使用
+//   - livez：进程在运行且后台任务没有退出（任务 panic 后不再让进程崩溃，而是由存活探针触发重启）
+//   - readyz：启动流程已完成且数据库可访问
+// 数据库不可用时整体为 fail（503），其余检查失败只降级为 degraded（200），supernode 故障不影响管理界面本身
+
+const (
+	healthOK       = "ok"
+	healthDegraded = "degraded"
+	healthFail     = "fail"
+	healthUnknown  = "unknown"
+
+	healthCheckTimeout = 3 * time.Second
+)
+
+var (
+	processStartedAt = time.Now()
+	serverReady      atomic.Bool
+)
+
+// workerState 一个后台任务的运行状态
+type workerState struct {
+	Name      string     `json:"name"`
+	Running   bool       `json:"running"`
+	StartedAt time.Time  `json:"started_at"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+var (
+	workers      = make(map[string]*workerState)
+	workersMutex sync.Mutex
+)
+
+// runWorker 在 goroutine 中运行后台任务并登记状态，任务返回或 panic 时记录原因
+func runWorker(name string, fn func()) {
+	st := &workerState{Name: name, Running: true, StartedAt: time.Now()}
+	workersMutex.Lock()
+	workers[name] = st
+	workersMutex.Unlock()
+	go func() {
+		defer func() {
+			r := recover()
+			now := time.Now()
+			workersMutex.Lock()
+			st.Running, st.StoppedAt = false, &now
+			if r != nil {
+				st.Error = fmt.Sprint(r)
+				log.Printf("Background worker %s panicked: %v\n%s", name, r, debug.Stack())
+			} else {
+				st.Error = "exited"
+				log.Printf("Background worker %s exited", name)
+			}
+			workersMutex.Unlock()
+		}()
+		fn()
+	}()
+}
+
+// workerStates 按名称返回所有后台任务的状态副本
+func workerStates() []workerState {
+	workersMutex.Lock()
+	defer workersMutex.Unlock()
+	res := make([]workerState, 0, len(workers))
+	for _, st := range workers {
+		res = append(res, *st)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// healthCheck 单项检查结果
+type healthCheck struct {
+	Status  string      `json:"status"`
+	Message string      `json:"message,omitempty"`
+	Detail  interface{} `json:"detail,omitempty"`
+}
+
+// instanceHealth 一个 supernode 实例的检查结果
+type instanceHealth struct {
+	ID            uint   `json:"id"`
+	Name          string `json:"name"`
+	Unit          string `json:"unit"`
+	ServiceActive bool   `json:"service_active"`
+	MgmtOK        bool   `json:"mgmt_ok"`
+	MgmtError     string `json:"mgmt_error,omitempty"`
+	LatencyMs     int64  `json:"latency_ms"`
+}
+
+type healthReport struct {
+	Status        string                 `json:"status"`
+	Version       string                 `json:"version"`
+	Ready         bool                   `json:"ready"`
+	UptimeSeconds int64                  `json:"uptime_seconds"`
+	Checks        map[string]healthCheck `json:"checks"`
+}
+
+// checkDatabase 用 Ping 和一次简单查询确认数据库可访问
+func checkDatabase(ctx context.Context) healthCheck {
+	sqlDB, err := db.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	if err == nil {
+		var one int
+		err = db.WithContext(ctx).Raw("SELECT 1").Scan(&one).Error
+	}
+	if err != nil {
+		return healthCheck{Status: healthFail, Message: err.Error()}
+	}
+	return healthCheck{Status: healthOK}
+}
+
+// checkDisk 检查数据库所在目录的剩余空间，低于 health_min_free_disk_mb 时降级
+func checkDisk() healthCheck {
+	path := appConfig.DBPath
+	if strings.HasPrefix(path, "file:") || path == ":memory:" {
+		return healthCheck{Status: healthOK, Message: "in-memory database"}
+	}
+	free, total, err := diskUsage(filepath.Dir(path))
+	if err != nil {
+		return healthCheck{Status: healthUnknown, Message: err.Error()}
+	}
+	detail := gin.H{"free_bytes": free, "total_bytes": total}
+	min := settingIntValue("health_min_free_disk_mb")
+	if min > 0 && free < uint64(min)<<20 {
+		return healthCheck{Status: healthDegraded, Message: fmt.Sprintf("less than %d MB free", min), Detail: detail}
+	}
+	return healthCheck{Status: healthOK, Detail: detail}
+}
+
+// checkSupernodes 并发检查各实例的 systemd 单元与管理端口
+func checkSupernodes() healthCheck {
+	rts := listRuntimes()
+	res := make([]instanceHealth, len(rts))
+	var wg sync.WaitGroup
+	for i, rt := range rts {
+		wg.Add(1)
+		go func(i int, rt *instanceRuntime) {
+			defer wg.Done()
+			h := instanceHealth{ID: rt.instance.ID, Name: rt.instance.Name, Unit: rt.instance.Unit}
+			h.ServiceActive = isSupernodeActive(rt.instance.Unit)
+			start := time.Now()
+			_, err := rt.client.FetchEdgeInfo()
+			h.LatencyMs = time.Since(start).Milliseconds()
+			if err != nil {
+				h.MgmtError = err.Error()
+			} else {
+				h.MgmtOK = true
+			}
+			res[i] = h
+		}(i, rt)
+	}
+	wg.Wait()
+	bad := make([]string, 0)
+	for _, h := range res {
+		if !h.ServiceActive || !h.MgmtOK {
+			bad = append(bad, h.Name)
+		}
+	}
+	if len(bad) > 0 {
+		return healthCheck{Status: healthDegraded, Message: "unhealthy: " + strings.Join(bad, ", "), Detail: res}
+	}
+	return healthCheck{Status: healthOK, Detail: res}
+}
+
+// checkWorkers 检查后台任务是否都在运行
+func checkWorkers() healthCheck {
+	states := workerStates()
+	stopped := make([]string, 0)
+	for _, st := range states {
+		if !st.Running {
+			stopped = append(stopped, st.Name)
+		}
+	}
+	if len(stopped) > 0 {
+		return healthCheck{Status: healthDegraded, Message: "stopped: " + strings.Join(stopped, ", "), Detail: states}
+	}
+	return healthCheck{Status: healthOK, Detail: states}
+}
+
+// getHealth 返回详细健康状态，数据库不可用时返回 503
+func getHealth(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+	defer cancel()
+	report := healthReport{
+		Status:        healthOK,
+		Version:       Version,
+		Ready:         serverReady.Load(),
+		UptimeSeconds: int64(time.Since(processStartedAt).Seconds()),
+		Checks: map[string]healthCheck{
+			"database":   checkDatabase(ctx),
+			"disk":       checkDisk(),
+			"supernodes": checkSupernodes(),
+			"workers":    checkWorkers(),
+		},
+	}
+	if ch, ok := publicAddressHealth(); ok {
+		report.Checks["public_address"] = ch
+	}
+	if ch, ok := clusterHealth(); ok {
+		report.Checks["cluster"] = ch
+	}
+	for _, ch := range report.Checks {
+		switch {
+		case ch.Status == healthFail:
+			report.Status = healthFail
+		case ch.Status != healthOK && report.Status == healthOK:
+			report.Status = healthDegraded
+		}
+	}
+	status := 200
+	if report.Status == healthFail {
+		status = 503
+	}
+	c.JSON(status, report)
+}
+
+// livez 存活探针：后台任务有退出时返回 503，由编排系统重启进程
+func livez(c *gin.Context) {
+	if ch := checkWorkers(); ch.Status != healthOK {
+		c.JSON(503, gin.H{"status": healthFail, "message": ch.Message})
+		return
+	}
+	c.JSON(200, gin.H{"status": healthOK})
+}
+
+// readyz 就绪探针：启动完成且数据库可访问时返回 200
+func readyz(c *gin.Context) {
+	if !serverReady.Load() {
+		c.JSON(503, gin.H{"status": healthFail, "message": "starting"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+	defer cancel()
+	if ch := checkDatabase(ctx); ch.Status != healthOK {
+		c.JSON(503, gin.H{"status": healthFail, "message": "database: " + ch.Message})
+		return
+	}
+	c.JSON(200, gin.H{"status": healthOK})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"io"
+	"n2n_ui/backend/utils"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// systemHost 对 supernode 服务与日志的系统操作，Linux 上为 systemd，Windows 上为服务管理器与事件日志，
+// 模拟模式下替换为假实现
+type systemHost interface {
+	ServiceActive(unit string) bool
+	RestartService(unit string) error
+	// FollowLogs 持续读取日志，backlog 为先输出的历史行数；ctx 取消或 Close 时结束
+	FollowLogs(ctx context.Context, unit string, backlog int) (io.ReadCloser, error)
+	RecentLogs(unit string, n int) ([]string, error)
+}
+
+var host systemHost = newSystemHost()
+
+// systemdHost 通过 systemctl 与 journalctl 操作
+type systemdHost struct{}
+
+func (systemdHost) ServiceActive(unit string) bool {
+	out, _ := utils.RunCommand("systemctl", "is-active", unit)
+	return strings.TrimSpace(out) == "active"
+}
+
+func (systemdHost) RestartService(unit string) error {
+	_, err := utils.RunCommand("systemctl", "restart", unit)
+	return err
+}
+
+func (systemdHost) FollowLogs(ctx context.Context, unit string, backlog int) (io.ReadCloser, error) {
+	cmd := exec.CommandContext(ctx, "journalctl", "-u", unit, "-f", "-n", strconv.Itoa(backlog))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &cmdReader{ReadCloser: stdout, cmd: cmd}, nil
+}
+
+func (systemdHost) RecentLogs(unit string, n int) ([]string, error) {
+	out, err := exec.Command("journalctl", "-u", unit, "-n", strconv.Itoa(n), "--no-pager").Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSpace(string(out)), "\n"), nil
+}
+
+// cmdReader 关闭时结束子进程
+type cmdReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (r *cmdReader) Close() error {
+	r.cmd.Process.Kill()
+	r.ReadCloser.Close()
+	return r.cmd.Wait()
+}
+
+// isLogFile 绝对路径（/var/log/...、C:\n2n\supernode.log）视为普通日志文件，否则为服务名
+func isLogFile(source string) bool {
+	return strings.HasPrefix(source, "/") || filepath.IsAbs(source)
+}
+
+// followLogSource 跟踪服务日志或日志文件，模拟模式下都由假 supernode 提供
+func followLogSource(ctx context.Context, source string, backlog int) (io.ReadCloser, error) {
+	if !isLogFile(source) || appConfig.MockMode {
+		return host.FollowLogs(ctx, source, backlog)
+	}
+	return tailFile(ctx, source, backlog)
+}
+
+// recentLogSource 读取服务日志或日志文件最近的 n 行
+func recentLogSource(source string, n int) ([]string, error) {
+	if !isLogFile(source) || appConfig.MockMode {
+		return host.RecentLogs(source, n)
+	}
+	return lastLines(source, n)
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+// defaultSupernodeDir 首次启动创建默认实例时的配置文件目录
+const defaultSupernodeDir = "/etc/n2n"
+
+func newSystemHost() systemHost { return systemdHost{} }
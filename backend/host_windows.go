@@ -0,0 +1,150 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Windows 上 supernode 以服务形式运行（如通过 NSSM / WinSW 注册），通过 PowerShell 查询和重启服务，
+// 日志从应用程序事件日志中按来源（默认与服务同名）读取；supernode 写日志文件时可在实例的 log_sources 中填写文件路径
+
+const (
+	defaultSupernodeDir = `C:\ProgramData\n2n`
+
+	eventLogPollInterval = 2 * time.Second
+	eventLogPollBatch    = 200
+)
+
+func newSystemHost() systemHost { return windowsHost{} }
+
+// windowsHost 通过 Windows 服务管理器与事件日志操作
+type windowsHost struct{}
+
+// psQuote 转义 PowerShell 单引号字符串
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func powershell(ctx context.Context, script string) (string, error) {
+	cmd := exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-Command",
+		"[Console]::OutputEncoding=[Text.Encoding]::UTF8; "+script)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+func (windowsHost) ServiceActive(unit string) bool {
+	out, err := powershell(context.Background(), "(Get-Service -Name "+psQuote(unit)+" -ErrorAction Stop).Status")
+	return err == nil && strings.TrimSpace(out) == "Running"
+}
+
+func (windowsHost) RestartService(unit string) error {
+	_, err := powershell(context.Background(), "Restart-Service -Name "+psQuote(unit)+" -Force -ErrorAction Stop")
+	return err
+}
+
+// eventRecord 事件日志中的一条记录
+type eventRecord struct {
+	id   int64
+	line string
+}
+
+// queryEvents 读取来源为 unit 的最近 n 条应用程序事件，按记录号升序
+func queryEvents(ctx context.Context, unit string, n int) ([]eventRecord, error) {
+	script := fmt.Sprintf("Get-WinEvent -FilterHashtable @{LogName='Application'; ProviderName=%s} -MaxEvents %d -ErrorAction SilentlyContinue | "+
+		"Sort-Object RecordId | ForEach-Object { '{0}`t{1} {2}' -f $_.RecordId, $_.TimeCreated.ToString('s'), ($_.Message -replace '\\r?\\n', ' ') }",
+		psQuote(unit), n)
+	out, err := powershell(ctx, script)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]eventRecord, 0)
+	for _, l := range strings.Split(strings.TrimSpace(out), "\n") {
+		parts := strings.SplitN(strings.TrimRight(l, "\r"), "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		id, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		records = append(records, eventRecord{id: id, line: parts[1]})
+	}
+	return records, nil
+}
+
+func (windowsHost) RecentLogs(unit string, n int) ([]string, error) {
+	records, err := queryEvents(context.Background(), unit, n)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(records))
+	for _, r := range records {
+		lines = append(lines, r.line)
+	}
+	return lines, nil
+}
+
+// FollowLogs 事件日志没有流式接口，定期查询记录号更大的新事件
+func (windowsHost) FollowLogs(ctx context.Context, unit string, backlog int) (io.ReadCloser, error) {
+	initial, err := queryEvents(ctx, unit, backlog+1)
+	if err != nil {
+		return nil, err
+	}
+	var last int64
+	if len(initial) > 0 {
+		last = initial[len(initial)-1].id
+	}
+	if len(initial) > backlog {
+		initial = initial[len(initial)-backlog:]
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	go func() {
+		defer cancel()
+		emit := func(records []eventRecord) bool {
+			for _, r := range records {
+				if r.id <= last {
+					continue
+				}
+				last = r.id
+				if _, err := io.WriteString(pw, r.line+"\n"); err != nil {
+					return false
+				}
+			}
+			return true
+		}
+		for _, r := range initial {
+			if _, err := io.WriteString(pw, r.line+"\n"); err != nil {
+				return
+			}
+		}
+		ticker := time.NewTicker(eventLogPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				pw.CloseWithError(ctx.Err())
+				return
+			case <-ticker.C:
+			}
+			records, err := queryEvents(ctx, unit, eventLogPollBatch)
+			if err != nil {
+				continue
+			}
+			if !emit(records) {
+				return
+			}
+		}
+	}()
+	return &tailReader{PipeReader: pr, cancel: cancel}, nil
+}
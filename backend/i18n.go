@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 接口消息以英文为源语言，其余语言的目录以英文原文为键。
+// 动态消息使用 "前缀: 详情" 的形式，找不到整句时只翻译前缀。
+const sourceLang = "en"
+
+var supportedLangs = []string{"zh-CN", "en"}
+
+var messageCatalogs = map[string]map[string]string{
+	"zh-CN": {
+		// 通用
+		"Invalid request":                         "请求参数无效",
+		"Unauthorized":                            "未登录或登录已过期",
+		"Invalid token":                           "登录凭证无效",
+		"Invalid claims":                          "登录凭证无效",
+		"Not Found":                               "资源不存在",
+		"Internal server error":                   "服务器内部错误",
+		"Admin privileges required":               "需要管理员权限",
+		"Access from this address is not allowed": "不允许从该地址访问",
+		"Invalid access allowlist":                "访问允许列表无效",
+		"Invalid access denylist":                 "访问拒绝列表无效",
+		"These access lists would block your current address": "该访问列表会阻止你当前的地址",
+		"Unknown field":  "未知的字段",
+		"Unknown action": "未知的操作",
+
+		// 请求体校验（见 binding.go）
+		"This field is required":      "此字段为必填项",
+		"Must be at least %s":         "不能小于 %s",
+		"Must be at most %s":          "不能大于 %s",
+		"Must have length %s":         "长度必须为 %s",
+		"Must be one of: %s":          "必须是以下之一: %s",
+		"Must be a valid IP address":  "必须是有效的 IP 地址",
+		"Must be a valid CIDR":        "必须是有效的 CIDR",
+		"Must be a valid MAC address": "必须是有效的 MAC 地址",
+		"Must be host:port":           "格式必须为 主机:端口",
+		"Must be greater than %s":     "必须大于 %s",
+		"Invalid value":               "取值无效",
+		"Expected %s but got %s":      "应为 %s 类型，实际为 %s",
+		"Malformed JSON body":         "请求体不是有效的 JSON",
+		"Request body is empty":       "请求体为空",
+		"Invalid time, expected RFC 3339 (e.g. 2026-01-02T15:04:05Z)": "时间格式无效，应为 RFC 3339（如 2026-01-02T15:04:05Z）",
+
+		// 登录与用户
+		"Invalid username or password":                          "用户名或密码错误",
+		"Too many login attempts, try again in %d minutes":      "登录尝试次数过多，请 %d 分钟后再试",
+		"Account temporarily locked, try again in %d minutes":   "该账户已被临时锁定，请 %d 分钟后再试",
+		"Too many failed logins, account locked for %d minutes": "登录失败次数过多，账户已锁定 %d 分钟",
+		"Login lock not found":                                  "不存在该登录锁定记录",
+		"User not found":                                        "用户不存在",
+		"User already exists":                                   "用户名已存在",
+		"Username is required":                                  "用户名不能为空",
+		"Cannot delete yourself":                                "不能删除当前登录的用户",
+		"Old password incorrect":                                "原密码错误",
+		"New password must be at least 6 characters":            "新密码至少需要 6 个字符",
+
+		// 节点
+		"Node not found":                                            "节点不存在",
+		"Node is offline":                                           "节点当前不在线",
+		"Edge is not online":                                        "edge 当前不在线",
+		"Node name is required":                                     "节点名称不能为空",
+		"Invalid MAC address format":                                "MAC 地址格式无效",
+		"MAC address is blocked":                                    "该 MAC 地址已被封禁",
+		"MAC address already registered":                            "该 MAC 地址已登记",
+		"MAC or IP address already used by another node":            "MAC 或 IP 地址已被其他节点使用",
+		"Invalid IP address format":                                 "IP 地址格式无效",
+		"IP address not in community range":                         "IP 地址不在社区网段内",
+		"Invalid route network format":                              "路由网段格式无效",
+		"Invalid route gateway format":                              "路由网关格式无效",
+		"Route network must be a network address, e.g. %s":          "路由网段需写成网络地址，如 %s",
+		"Route gateway must be a VPN address in the community":      "路由网关必须是社区网段内的 VPN 地址",
+		"Route network overlaps the community range":                "路由网段与社区网段重叠",
+		"Node has no route configured":                              "节点未配置路由",
+		"Target must be inside the route network":                   "目标必须在路由网段内",
+		"Subnet overlaps existing community ranges or node routes":  "网段与已有社区网段或节点路由重叠",
+		"Route network overlaps existing subnets":                   "路由网段与已有社区网段或节点路由重叠",
+		"Invalid local port":                                        "本地端口无效",
+		"Local port not in community port range %d-%d":              "本地端口不在社区端口范围 %d-%d 内",
+		"Local port %d already in use":                              "本地端口 %d 已被占用",
+		"Community port range %d-%d exhausted":                      "社区端口范围 %d-%d 已用完",
+		"Failed to create node":                                     "创建节点失败",
+		"Failed to update node":                                     "更新节点失败",
+		"Failed to save node":                                       "保存节点失败",
+		"Failed to save settings":                                   "保存设置失败",
+		"Invalid setup token":                                       "初始化令牌无效",
+		"Setup has already been completed":                          "初始化设置已完成",
+		"Setup failed":                                              "初始化设置失败",
+		"Invalid timezone":                                          "时区无效",
+		"Unsupported language":                                      "不支持的语言",
+		"Dashboard layout is too large":                             "仪表盘布局数据过大",
+		"Failed to save preferences":                                "保存偏好设置失败",
+		"Password incorrect":                                        "密码错误",
+		"Session not found":                                         "会话不存在",
+		"Session has been revoked or expired":                       "会话已被吊销或已过期",
+		"Please complete the captcha":                               "请完成验证码",
+		"Cannot change your own role":                               "不能修改自己的角色",
+		"API tokens cannot be refreshed":                            "API 令牌不能刷新",
+		"Alert rule not found":                                      "告警规则不存在",
+		"Alert not found":                                           "告警记录不存在",
+		"Rule name is required":                                     "规则名称不能为空",
+		"Unknown metric":                                            "未知的指标",
+		"Unknown comparator":                                        "未知的比较符",
+		"Unknown severity":                                          "未知的告警级别",
+		"Invalid duration":                                          "持续时间无效",
+		"Target must be an ID":                                      "作用对象必须是 ID",
+		"Failed to save rule":                                       "保存规则失败",
+		"Invitation not found or expired":                           "邀请链接不存在或已失效",
+		"This invitation has no registrations left":                 "该邀请链接的注册名额已用完",
+		"Registration not found":                                    "注册申请不存在",
+		"Registration has already been decided":                     "该注册申请已处理",
+		"Invalid email address":                                     "邮箱地址无效",
+		"No free IP address left in the community range":            "社区网段中已没有可用的 IP 地址",
+		"Node is pending approval":                                  "节点等待管理员审批",
+		"Node is not pending approval":                              "节点不在待审批状态",
+		"Unknown integrity check":                                   "未知的检查项",
+		"Update check is disabled":                                  "版本检查已关闭",
+		"An upgrade is already in progress":                         "已有升级正在进行",
+		"Already running the latest version":                        "已是最新版本",
+		"Upgrade failed":                                            "升级失败",
+		"Supernode installation is only supported on Linux":         "只支持在 Linux 上安装 supernode",
+		"Supernode installation is not available in mock mode":      "模拟模式下不能安装 supernode",
+		"n2n-admin must run as root to install the supernode":       "安装 supernode 需要以 root 运行 n2n-admin",
+		"Supernode installation is not supported on this host":      "当前主机不支持自动安装 supernode",
+		"A supernode installation is already running":               "已有 supernode 安装任务在进行",
+		"Invalid source ref":                                        "源码版本（ref）格式不正确",
+		"Config changed since it was fetched":                       "配置在拉取后已发生变化，请重新拉取",
+		"A password rotation is already scheduled":                  "已有待生效的密码轮换",
+		"No password rotation is scheduled":                         "没有待生效的密码轮换",
+		"Password rotation canceled":                                "已取消密码轮换",
+		"Failed to rotate password":                                 "密码轮换失败",
+		"Unknown event source":                                      "未知的事件来源",
+		"Invalid time range":                                        "时间范围无效",
+		"Invalid cursor":                                            "翻页位置（cursor）无效",
+		"Invalid resolution":                                        "分辨率无效",
+		"The panel is in read-only maintenance mode":                "面板处于只读维护模式，暂时无法修改",
+		"Supernode config is invalid":                               "supernode.conf 校验未通过",
+		"Node name must not contain control characters":             "节点名称不能包含换行等控制字符",
+		"Failed to write supernode.conf":                            "supernode.conf 写入失败",
+		"Failed to read supernode.conf":                             "supernode.conf 读取失败",
+		"Failed to detect public address":                           "检测公网地址失败",
+		"Failed to generate MAC address":                            "生成 MAC 地址失败",
+		"Node access has expired":                                   "节点访问已到期",
+		"Node access has expired, extend the expiry time first":     "节点访问已到期，请先延长到期时间",
+		"Invalid node ID list":                                      "节点 ID 列表无效",
+		"Unsupported encryption":                                    "不支持的加密算法",
+		"Edge flag %s is not allowed":                               "不允许的 edge 参数 %s",
+		"Edge flag %s does not take a value":                        "edge 参数 %s 不带值",
+		"Invalid value for edge flag %s":                            "edge 参数 %s 的取值无效",
+		"Invalid TAP device name":                                   "TAP 设备名无效",
+		"TTL must be between 1 and 255":                             "TTL 必须在 1 到 255 之间",
+		"Node is disabled":                                          "节点已禁用",
+		"Expiry time must be in the future":                         "到期时间必须晚于当前时间",
+		"Invalid agent token":                                       "代理令牌无效",
+		"Too many log lines":                                        "单次上报的日志行数过多",
+		"Node has no edge agent token":                              "节点尚未生成 edge 代理令牌",
+		"Set speedtest_server or supernode_host first":              "请先设置 speedtest_server 或 supernode_host",
+		"A speed test is already in progress for this node":         "该节点已有进行中的测速",
+		"Speed test not found or already finished":                  "测速任务不存在或已结束",
+		"Key generation failed, make sure n2n-keygen is installed":  "密钥生成失败，请确认已安装 n2n-keygen",
+		"Node not found in trash":                                   "回收站中不存在该节点",
+		"Community of this node no longer exists, restore it first": "节点所属社区已不存在，请先恢复社区",
+
+		// 社区
+		"Community not found":                    "社区不存在",
+		"Community already exists":               "社区已存在",
+		"Community name is required":             "社区名称不能为空",
+		"Invalid CIDR format":                    "CIDR 格式无效",
+		"Range overlaps existing subnets":        "网段与已有社区网段或节点路由重叠",
+		"Invalid port range":                     "端口范围无效",
+		"Password must be at least 4 characters": "社区密码至少需要 4 个字符",
+		"Failed to create community":             "创建社区失败",
+		"Community not found in trash":           "回收站中不存在该社区",
+		"Community with this name is in the trash, restore or purge it first": "同名社区位于回收站中，请先恢复或彻底删除",
+		"Failed to write community.list":                                      "community.list 写入失败",
+		"MTU must be between 500 and 9000":                                    "MTU 必须在 500 到 9000 之间",
+		"Supernode address must be host:port":                                 "supernode 地址格式应为 host:port",
+
+		// supernode 实例
+		"Supernode instance not found":                          "supernode 实例不存在",
+		"Supernode instance of this community no longer exists": "社区所属的 supernode 实例已不存在",
+		"Instance already exists":                               "实例已存在",
+		"Default instance cannot be deleted":                    "默认实例不能删除",
+		"Instance still has communities":                        "实例下仍有社区",
+		"Instance name is required":                             "实例名称不能为空",
+		"Systemd unit is required":                              "systemd 单元不能为空",
+		"Config and community list paths are required":          "配置文件和社区列表路径不能为空",
+		"Invalid management address":                            "管理端口地址无效",
+		"Invalid instance":                                      "实例参数无效",
+		"Failed to create instance":                             "创建实例失败",
+		"Failed to update instance":                             "更新实例失败",
+		"Spec is too large or unreadable":                       "部署定义过大或无法读取",
+		"Invalid spec":                                          "部署定义格式无效",
+		"Spec has problems, nothing was applied":                "部署定义存在问题，未做任何修改",
+		"Failed to apply spec":                                  "应用部署定义失败",
+		"Resource has been modified":                            "资源已被修改，请重新获取后再提交",
+		"Failed to read logs":                                   "读取日志失败",
+		"Invalid log level":                                     "日志级别无效",
+		"Unknown log source":                                    "未知的日志来源",
+		"Log file path must be absolute":                        "日志文件路径必须为绝对路径",
+
+		// 工具与通知
+		"Network tools are disabled by the administrator": "网络诊断工具已被管理员禁用",
+		"Invalid target address":                          "目标地址无效",
+		"Too many running tool jobs (limit %d)":           "同时运行的诊断任务已达上限（%d 个）",
+		"Tool job not found":                              "诊断任务不存在或已过期",
+		"Cancel requested":                                "已请求终止",
+		"Statistics reset":                                "统计已清零",
+		"Unknown channel":                                 "未知的通知渠道",
+		"Send failed":                                     "发送失败",
+		"Title is required":                               "标题不能为空",
+		"Community or tag is required":                    "需要指定社区或分组",
+		"Failed to create announcement":                   "创建公告失败",
+		"Announcement not found":                          "公告不存在",
+		"Source and destination group are required":       "源分组和目标分组不能为空",
+		"Failed to create rule":                           "创建规则失败",
+
+		"Cannot resolve %s to check it against the allowed ranges":    "无法解析 %s，不能确认其是否在允许的网段内",
+		"Target %s is outside the VPN ranges allowed for diagnostics": "目标 %s 不在允许诊断的 VPN 网段内",
+
+		// 设置
+		"Unknown setting: %s":      "设置项不存在: %s",
+		"Invalid value for %s: %v": "设置项 %s 的取值不合法: %v",
+	},
+}
+
+// requestLang 确定响应语言：用户偏好（由中间件写入 lang）> Accept-Language > 设置 default_language
+func requestLang(c *gin.Context) string {
+	if v, ok := c.Get("lang"); ok {
+		if lang := matchLang(fmt.Sprint(v)); lang != "" {
+			return lang
+		}
+	}
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if lang := matchLang(tag); lang != "" {
+			return lang
+		}
+	}
+	if lang := matchLang(getSettingValue("default_language", sourceLang)); lang != "" {
+		return lang
+	}
+	return sourceLang
+}
+
+// matchLang 将语言标签匹配到支持的语言，zh-TW 等也回退到 zh-CN
+func matchLang(tag string) string {
+	tag = strings.ToLower(tag)
+	for _, lang := range supportedLangs {
+		base := strings.SplitN(strings.ToLower(lang), "-", 2)[0]
+		if tag == strings.ToLower(lang) || tag == base || strings.HasPrefix(tag, base+"-") {
+			return lang
+		}
+	}
+	return ""
+}
+
+// translate 查找消息翻译，整句不存在时按 "前缀: 详情" 只翻译前缀
+func translate(lang, msg string) string {
+	catalog := messageCatalogs[lang]
+	if catalog == nil {
+		return msg
+	}
+	if t, ok := catalog[msg]; ok {
+		return t
+	}
+	if i := strings.Index(msg, ": "); i > 0 {
+		if t, ok := catalog[msg[:i]]; ok {
+			return t + ": " + msg[i+2:]
+		}
+	}
+	return msg
+}
+
+// tr 按请求语言翻译消息
+func tr(c *gin.Context, msg string) string {
+	return translate(requestLang(c), msg)
+}
+
+// trf 翻译格式串后再填充参数
+func trf(c *gin.Context, format string, args ...interface{}) string {
+	return fmt.Sprintf(tr(c, format), args...)
+}
+
+// localizeErr 翻译 error 的消息，apiError 使用其格式串
+func localizeErr(c *gin.Context, err error) string {
+	if ae, ok := err.(*apiError); ok && ae.format != "" {
+		return trf(c, ae.format, ae.args...)
+	}
+	return tr(c, err.Error())
+}
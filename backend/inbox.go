@@ -0,0 +1,92 @@
+package main
+
+import (
+	"log"
+	"n2n_ui/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// saveToInbox 将告警写入所有用户（AdminOnly 时只写入管理员）的站内通知
+func saveToInbox(a Alert) {
+	var users []models.User
+	q := db
+	if a.AdminOnly {
+		q = q.Where("is_admin = ?", true)
+	}
+	q.Find(&users)
+	if len(users) == 0 {
+		return
+	}
+	items := make([]models.Notification, 0, len(users))
+	for _, u := range users {
+		items = append(items, models.Notification{
+			UserID: u.ID, Type: a.Type, Level: a.Level, Title: a.Title, Message: a.Message, CreatedAt: a.Time,
+		})
+	}
+	if err := db.Create(&items).Error; err != nil {
+		log.Printf("Inbox: failed to save notification: %v", err)
+	}
+}
+
+func getNotifications(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		respondError(c, 401, ErrUnauthorized, "Unauthorized")
+		return
+	}
+	q := db.Where("user_id = ?", user.ID)
+	if c.Query("unread") == "true" {
+		q = q.Where("is_read = ?", false)
+	}
+	if t := c.Query("type"); t != "" {
+		q = q.Where("type = ?", t)
+	}
+	if l := c.Query("level"); l != "" {
+		q = q.Where("level = ?", l)
+	}
+	var items []models.Notification
+	q.Order("id desc").Limit(200).Find(&items)
+	c.JSON(200, items)
+}
+
+func getUnreadCount(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		respondError(c, 401, ErrUnauthorized, "Unauthorized")
+		return
+	}
+	var count int64
+	db.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", user.ID, false).Count(&count)
+	c.JSON(200, gin.H{"unread": count})
+}
+
+func markNotificationRead(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		respondError(c, 401, ErrUnauthorized, "Unauthorized")
+		return
+	}
+	db.Model(&models.Notification{}).Where("id = ? AND user_id = ?", c.Param("id"), user.ID).Update("is_read", true)
+	c.JSON(200, gin.H{"message": "success"})
+}
+
+func markAllNotificationsRead(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		respondError(c, 401, ErrUnauthorized, "Unauthorized")
+		return
+	}
+	db.Model(&models.Notification{}).Where("user_id = ? AND is_read = ?", user.ID, false).Update("is_read", true)
+	c.JSON(200, gin.H{"message": "success"})
+}
+
+func deleteNotification(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		respondError(c, 401, ErrUnauthorized, "Unauthorized")
+		return
+	}
+	db.Where("id = ? AND user_id = ?", c.Param("id"), user.ID).Delete(&models.Notification{})
+	c.JSON(200, gin.H{"message": "deleted"})
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 数据完整性检查：定期（integrity_check_hours）检查以下问题，发现新问题时通知管理员，
+// 开启 integrity_auto_fix 时自动修复；管理员也可通过 /api/admin/integrity 查看与修复。
+//   - orphan_nodes：所属社区不存在（已删除或被直接改库）的节点，修复时移入回收站
+//   - duplicate_macs：MAC 只在格式上不同（分隔符、大小写）的节点，修复时保留一个并统一格式，其余移入回收站
+//   - unknown_settings：不在设置 schema 中的设置项（旧版本遗留或手工写入），修复时删除
+//   - expired_trash：超过回收站保留期仍未清除的记录，修复时永久删除
+
+const integrityAlertType = "integrity_issues"
+
+// integrityIssue 一项检查的结果
+type integrityIssue struct {
+	Check string        `json:"check"`
+	Count int           `json:"count"`
+	Fix   string        `json:"fix"` // 修复时的处理方式
+	Items []interface{} `json:"items"`
+	Fixed int           `json:"fixed,omitempty"`
+	Error string        `json:"error,omitempty"` // 修复时遇到的第一个错误
+}
+
+// integrityReport 一次检查的完整结果
+type integrityReport struct {
+	CheckedAt time.Time        `json:"checked_at"`
+	Total     int              `json:"total"`
+	Fixed     int              `json:"fixed"`
+	Issues    []integrityIssue `json:"issues"`
+}
+
+// integrityCheck 检查项：run 返回发现的问题，fix 为 true 时同时修复并返回修复数量
+type integrityCheck struct {
+	name string
+	fix  string
+	run  func(fix bool) ([]interface{}, int, error)
+}
+
+var integrityChecks = []integrityCheck{
+	{name: "orphan_nodes", fix: "移入回收站", run: checkOrphanNodes},
+	{name: "duplicate_macs", fix: "保留一个并统一格式，其余移入回收站", run: checkDuplicateMacs},
+	{name: "unknown_settings", fix: "删除", run: checkUnknownSettings},
+	{name: "expired_trash", fix: "永久删除", run: checkExpiredTrash},
+}
+
+func checkOrphanNodes(fix bool) ([]interface{}, int, error) {
+	var nodes []models.Node
+	db.Where("community NOT IN (?)", db.Model(&models.Community{}).Select("name")).Order("id").Find(&nodes)
+	items := make([]interface{}, 0, len(nodes))
+	fixed := 0
+	var firstErr error
+	for _, n := range nodes {
+		items = append(items, gin.H{"id": n.ID, "name": n.Name, "community": n.Community, "ip_address": n.IPAddress})
+		if !fix {
+			continue
+		}
+		if err := db.Delete(&n).Error; err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		fixed++
+	}
+	return items, fixed, firstErr
+}
+
+// checkDuplicateMacs 按规范化后的 MAC 分组。修复时优先保留已是规范格式的节点，其次最近修改的节点
+func checkDuplicateMacs(fix bool) ([]interface{}, int, error) {
+	var nodes []models.Node
+	db.Order("id").Find(&nodes)
+	groups := make(map[string][]models.Node)
+	for _, n := range nodes {
+		m := utils.NormalizeMac(n.MacAddress)
+		groups[m] = append(groups[m], n)
+	}
+	macs := make([]string, 0)
+	for m, g := range groups {
+		if len(g) > 1 || g[0].MacAddress != m {
+			macs = append(macs, m)
+		}
+	}
+	sort.Strings(macs)
+
+	items := make([]interface{}, 0, len(macs))
+	fixed := 0
+	var firstErr error
+	for _, m := range macs {
+		g := groups[m]
+		list := make([]gin.H, 0, len(g))
+		for _, n := range g {
+			list = append(list, gin.H{"id": n.ID, "name": n.Name, "mac_address": n.MacAddress, "updated_at": n.UpdatedAt})
+		}
+		items = append(items, gin.H{"mac_address": m, "nodes": list})
+		if !fix {
+			continue
+		}
+		sort.SliceStable(g, func(i, j int) bool {
+			if (g[i].MacAddress == m) != (g[j].MacAddress == m) {
+				return g[i].MacAddress == m
+			}
+			return g[i].UpdatedAt.After(g[j].UpdatedAt)
+		})
+		err := func() error {
+			for _, n := range g[1:] {
+				if err := db.Delete(&n).Error; err != nil {
+					return err
+				}
+			}
+			if g[0].MacAddress != m {
+				return db.Model(&g[0]).Update("mac_address", m).Error
+			}
+			return nil
+		}()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", utils.MAC(m).Format(), err)
+			}
+			continue
+		}
+		fixed++
+	}
+	return items, fixed, firstErr
+}
+
+func checkUnknownSettings(fix bool) ([]interface{}, int, error) {
+	var rows []models.Setting
+	db.Order("key").Find(&rows)
+	items := make([]interface{}, 0)
+	fixed := 0
+	for _, r := range rows {
+		if _, ok := lookupSettingDef(r.Key); ok {
+			continue
+		}
+		items = append(items, r.Key)
+		if fix {
+			if err := db.Where("key = ?", r.Key).Delete(&models.Setting{}).Error; err != nil {
+				return items, fixed, err
+			}
+			fixed++
+		}
+	}
+	return items, fixed, nil
+}
+
+func checkExpiredTrash(fix bool) ([]interface{}, int, error) {
+	items := make([]interface{}, 0)
+	retention := trashRetention()
+	if retention == 0 {
+		return items, 0, nil
+	}
+	cutoff := time.Now().Add(-retention)
+	var nodes []models.Node
+	db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Find(&nodes)
+	for _, n := range nodes {
+		items = append(items, gin.H{"type": "node", "id": n.ID, "name": n.Name, "deleted_at": n.DeletedAt.Time})
+	}
+	var comms []models.Community
+	db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Find(&comms)
+	for _, cm := range comms {
+		items = append(items, gin.H{"type": "community", "id": cm.ID, "name": cm.Name, "deleted_at": cm.DeletedAt.Time})
+	}
+	if !fix || len(items) == 0 {
+		return items, 0, nil
+	}
+	purgeExpiredTrash()
+	return items, len(items), nil
+}
+
+// runIntegrityChecks 执行检查，fix 中的检查项同时修复（"*" 表示全部）
+func runIntegrityChecks(fix []string) integrityReport {
+	report := integrityReport{CheckedAt: time.Now(), Issues: make([]integrityIssue, 0, len(integrityChecks))}
+	for _, ch := range integrityChecks {
+		doFix := containsString(fix, "*") || containsString(fix, ch.name)
+		items, fixed, err := ch.run(doFix)
+		issue := integrityIssue{Check: ch.name, Count: len(items), Fix: ch.fix, Items: items, Fixed: fixed}
+		if err != nil {
+			issue.Error = err.Error()
+			log.Printf("Integrity: failed to fix %s: %v", ch.name, err)
+		}
+		report.Total += issue.Count
+		report.Fixed += fixed
+		report.Issues = append(report.Issues, issue)
+	}
+	return report
+}
+
+// summary 告警与日志中使用的简要描述，如 "orphan_nodes 2, unknown_settings 1"
+func (r integrityReport) summary() string {
+	parts := make([]string, 0)
+	for _, is := range r.Issues {
+		if is.Count > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", is.Check, is.Count))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func getIntegrity(c *gin.Context) {
+	c.JSON(200, runIntegrityChecks(nil))
+}
+
+// fixIntegrity 修复指定的检查项，未指定时修复全部
+func fixIntegrity(c *gin.Context) {
+	var p struct {
+		Checks []string `json:"checks"`
+	}
+	if !bindOptionalJSON(c, &p) {
+		return
+	}
+	for _, name := range p.Checks {
+		known := false
+		for _, ch := range integrityChecks {
+			known = known || ch.name == name
+		}
+		if !known {
+			respondError(c, 400, ErrInvalidRequest, "Unknown integrity check")
+			return
+		}
+	}
+	if len(p.Checks) == 0 {
+		p.Checks = []string{"*"}
+	}
+	report := runIntegrityChecks(p.Checks)
+	if report.Fixed > 0 {
+		recordAudit(c, "integrity.fix", strings.Join(p.Checks, ","), report.summary())
+		syncCommunityList() // 移入回收站的节点不再写入 community.list 的用户公钥
+	}
+	c.JSON(200, report)
+}
+
+// startIntegrityChecker 定期检查，问题与上次不同时通知管理员
+func startIntegrityChecker() {
+	time.Sleep(time.Minute) // 等待启动时的迁移与同步完成
+	last := ""
+	for {
+		hours := settingIntValue("integrity_check_hours")
+		if hours <= 0 {
+			time.Sleep(time.Hour)
+			continue
+		}
+		var fix []string
+		if getSettingValue("integrity_auto_fix", "false") == "true" {
+			fix = []string{"*"}
+		}
+		report := runIntegrityChecks(fix)
+		summary := report.summary()
+		if report.Fixed > 0 {
+			log.Printf("Integrity: fixed %d of %d issue(s): %s", report.Fixed, report.Total, summary)
+			syncCommunityList()
+		}
+		if summary != last && report.Total > 0 {
+			msg := "发现以下数据问题: " + summary
+			if report.Fixed > 0 {
+				msg += fmt.Sprintf("，已自动修复 %d 项", report.Fixed)
+			} else {
+				msg += "，可在数据完整性检查中查看详情并修复"
+			}
+			dispatchAlert(Alert{Type: integrityAlertType, Level: "warning", AdminOnly: true, Title: "数据完整性检查发现问题", Message: msg})
+		}
+		last = summary
+		time.Sleep(time.Duration(hours) * time.Hour)
+	}
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 邀请链接：管理员为社区创建邀请，持有链接的人在 /invite/<令牌> 页面提交设备名称即可自助注册节点，
+// 节点按社区默认配置创建（随机 MAC、自动分配 IP 与本地端口），注册成功后页面直接给出 edge 配置。
+// 每个邀请可限制注册名额与有效期；开启 require_approval 时申请进入审批队列，管理员批准后才创建节点，
+// 申请人凭提交时返回的密钥查询结果。
+
+const maxInvitationDays = 365
+
+var errInvitationExhausted = newAPIError(ErrInvitationExhausted, "This invitation has no registrations left")
+
+// newInviteSecret 生成邀请令牌或申请密钥，返回明文与哈希
+func newInviteSecret() (string, string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	s := hex.EncodeToString(b)
+	return s, hashAgentToken(s), nil
+}
+
+// invitationUsable 邀请是否仍可用于注册（未撤销、未过期），名额另行检查
+func invitationUsable(inv models.Invitation) bool {
+	return inv.RevokedAt == nil && (inv.ExpiresAt == nil || inv.ExpiresAt.After(time.Now()))
+}
+
+// invitationParam 按链接中的令牌查找可用的邀请，不可用时返回 404，不区分不存在、过期与撤销
+func invitationParam(c *gin.Context) (models.Invitation, bool) {
+	var inv models.Invitation
+	err := db.Where("token_hash = ?", hashAgentToken(c.Param("token"))).First(&inv).Error
+	if err != nil || !invitationUsable(inv) {
+		respondError(c, 404, ErrInvitationNotFound, "Invitation not found or expired")
+		return inv, false
+	}
+	return inv, true
+}
+
+// invitationLink 邀请页面的路径，前端拼上当前域名即可分享
+func invitationLink(token string) string {
+	return normalizeBasePath(appConfig.BasePath) + "/invite/" + token
+}
+
+func getInvitations(c *gin.Context) {
+	var list []models.Invitation
+	db.Order("id DESC").Find(&list)
+	var counts []struct {
+		InvitationID uint
+		N            int
+	}
+	db.Model(&models.NodeRegistration{}).Select("invitation_id, COUNT(*) AS n").
+		Where("status = ?", "pending").Group("invitation_id").Scan(&counts)
+	pending := make(map[uint]int, len(counts))
+	for _, r := range counts {
+		pending[r.InvitationID] = r.N
+	}
+	res := make([]gin.H, 0, len(list))
+	for _, inv := range list {
+		res = append(res, gin.H{"invitation": inv, "usable": invitationUsable(inv), "pending": pending[inv.ID]})
+	}
+	c.JSON(200, res)
+}
+
+// createInvitation 创建邀请，令牌明文只在响应中返回一次
+func createInvitation(c *gin.Context) {
+	var p struct {
+		Community       string `json:"community" binding:"required"`
+		Note            string `json:"note" binding:"max=255"`
+		MaxUses         int    `json:"max_uses"`
+		ExpiresDays     int    `json:"expires_days"`
+		RequireApproval bool   `json:"require_approval"`
+		NodeTags        string `json:"node_tags"`
+		NodeExpiryDays  int    `json:"node_expiry_days"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	comm, err := scopedCommunity(c, p.Community)
+	if err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return
+	}
+	if p.MaxUses < 0 || p.ExpiresDays < 0 || p.ExpiresDays > maxInvitationDays || p.NodeExpiryDays < 0 {
+		respondError(c, 400, ErrInvalidRequest, "Invalid request")
+		return
+	}
+	token, hash, err := newInviteSecret()
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	inv := models.Invitation{
+		TokenHash: hash, Community: comm.Name, Note: strings.TrimSpace(p.Note), MaxUses: p.MaxUses,
+		RequireApproval: p.RequireApproval, NodeTags: normalizeTags(p.NodeTags), NodeExpiryDays: p.NodeExpiryDays,
+		CreatedBy: c.GetString("username"),
+	}
+	if p.ExpiresDays > 0 {
+		t := time.Now().AddDate(0, 0, p.ExpiresDays)
+		inv.ExpiresAt = &t
+	}
+	if err := db.Create(&inv).Error; err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	recordAudit(c, "invitation.create", comm.Name, inv.Note)
+	c.JSON(200, gin.H{"invitation": inv, "token": token, "link": invitationLink(token)})
+}
+
+// revokeInvitation 撤销邀请，已提交的申请仍可审批
+func revokeInvitation(c *gin.Context) {
+	var inv models.Invitation
+	if err := db.First(&inv, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrInvitationNotFound, "Invitation not found or expired")
+		return
+	}
+	if inv.RevokedAt == nil {
+		now := time.Now()
+		inv.RevokedAt = &now
+		db.Model(&inv).Update("revoked_at", now)
+		recordAudit(c, "invitation.revoke", inv.Community, inv.Note)
+	}
+	c.JSON(200, inv)
+}
+
+// getInvite 邀请页面展示的信息，不包含社区密码等敏感内容
+func getInvite(c *gin.Context) {
+	inv, ok := invitationParam(c)
+	if !ok {
+		return
+	}
+	res := gin.H{"community": inv.Community, "note": inv.Note, "require_approval": inv.RequireApproval,
+		"expires_at": inv.ExpiresAt, "remaining": nil}
+	if inv.MaxUses > 0 {
+		res["remaining"] = inv.MaxUses - inv.Uses
+	}
+	c.JSON(200, res)
+}
+
+// provisionInvitedNode 按社区默认配置为注册申请创建节点：随机 MAC，自动分配 IP 与本地端口
+func provisionInvitedNode(inv models.Invitation, reg models.NodeRegistration) (models.Node, error) {
+	comm, err := lookupCommunity(inv.Community)
+	if err != nil {
+		return models.Node{}, err
+	}
+	n := models.Node{
+		Name: reg.Name, Community: comm.Name, OwnerEmail: reg.Email, Tags: inv.NodeTags, IsEnabled: true,
+		Description: fmt.Sprintf("通过邀请链接 #%d 注册", inv.ID),
+	}
+	applyCommunityDefaults(&n, comm, nil)
+	mac, err := utils.RandomMAC()
+	if err != nil {
+		return n, err
+	}
+	n.MacAddress = mac.String()
+	if n.IPAddress = nextNodeIP(comm); n.IPAddress != "" {
+		if err := validateNodeIP(n.IPAddress, comm); err != nil {
+			return n, newAPIError(ErrInvalidIP, "No free IP address left in the community range")
+		}
+	}
+	if n.LocalPort, err = allocateLocalPort(comm, 0, 0); err != nil {
+		return n, err
+	}
+	if inv.NodeExpiryDays > 0 {
+		t := time.Now().AddDate(0, 0, inv.NodeExpiryDays)
+		n.ExpiresAt = &t
+	}
+	if len(findNodeConflicts(n.MacAddress, n.IPAddress)) > 0 {
+		return n, newAPIError(ErrNodeConflict, "MAC or IP address already used by another node")
+	}
+	_, err = provisionNode(&n, nil)
+	return n, err
+}
+
+// registrationResult 返回给申请人的结果，批准后附带 edge 配置
+func registrationResult(reg models.NodeRegistration) gin.H {
+	res := gin.H{"id": reg.ID, "status": reg.Status, "name": reg.Name, "community": reg.Community}
+	if reg.Status == "rejected" && reg.Reason != "" {
+		res["reason"] = reg.Reason
+	}
+	if reg.Status != "approved" {
+		return res
+	}
+	var n models.Node
+	if err := db.First(&n, reg.NodeID).Error; err != nil || !nodeAccessActive(n) {
+		return res
+	}
+	conf := renderNodeConfig(n)
+	if n.IssuedConfig == "" {
+		db.Model(&n).Update("issued_config", conf)
+	}
+	res["ip_address"], res["mac_address"], res["conf"] = n.IPAddress, utils.MAC(n.MacAddress).Format(), conf
+	return res
+}
+
+// registerWithInvite 通过邀请链接提交注册申请：占用一个名额，无需审批时立即创建节点并返回配置
+func registerWithInvite(c *gin.Context) {
+	inv, ok := invitationParam(c)
+	if !ok {
+		return
+	}
+	var p struct {
+		Name  string `json:"name" binding:"max=100"`
+		Email string `json:"email" binding:"max=255"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	p.Name, p.Email = strings.TrimSpace(p.Name), strings.TrimSpace(p.Email)
+	if err := validateNodeName(p.Name); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return
+	}
+	if p.Email != "" {
+		if _, err := mail.ParseAddress(p.Email); err != nil {
+			respondError(c, 400, ErrInvalidRequest, "Invalid email address")
+			return
+		}
+	}
+	key, keyHash, err := newInviteSecret()
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	reg := models.NodeRegistration{
+		InvitationID: inv.ID, Community: inv.Community, Name: p.Name, Email: p.Email,
+		KeyHash: keyHash, Status: "pending", IP: c.ClientIP(),
+	}
+	// 名额检查与占用在同一条 UPDATE 中完成，并发提交不会超出上限
+	err = db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Invitation{}).Where("id = ? AND (max_uses = 0 OR uses < max_uses)", inv.ID).
+			Update("uses", gorm.Expr("uses + 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errInvitationExhausted
+		}
+		return tx.Create(&reg).Error
+	})
+	if errors.Is(err, errInvitationExhausted) {
+		respondErr(c, 403, err, ErrInvitationExhausted)
+		return
+	} else if err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+
+	if inv.RequireApproval {
+		dispatchAlert(Alert{Type: "node_registration", Level: "info", AdminOnly: true, Title: "新的节点注册申请: " + reg.Name,
+			Message: fmt.Sprintf("%s 通过社区 %s 的邀请链接申请注册节点 %s，请在邀请管理中审批", reg.IP, reg.Community, reg.Name)})
+		res := registrationResult(reg)
+		res["key"] = key
+		c.JSON(202, res)
+		return
+	}
+	n, err := provisionInvitedNode(inv, reg)
+	if err != nil {
+		// 创建失败时释放名额并删除申请，申请人可稍后重试
+		db.Delete(&reg)
+		db.Model(&models.Invitation{}).Where("id = ?", inv.ID).Update("uses", gorm.Expr("uses - 1"))
+		log.Printf("Invitation %d: failed to create node %s: %v", inv.ID, reg.Name, err)
+		respondErr(c, 409, err, ErrInternal)
+		return
+	}
+	now := time.Now()
+	reg.Status, reg.NodeID, reg.DecidedAt = "approved", n.ID, &now
+	db.Save(&reg)
+	dispatchAlert(Alert{Type: "node_registered", Level: "info", Title: "节点已通过邀请链接注册: " + n.Name,
+		Message: fmt.Sprintf("%s 通过社区 %s 的邀请链接注册了节点 %s (%s)", reg.IP, n.Community, n.Name, n.IPAddress)})
+	res := registrationResult(reg)
+	res["key"] = key
+	c.JSON(200, res)
+}
+
+// getRegistrationStatus 申请人凭密钥（?key=）查询申请结果，邀请撤销或过期后仍可查询已提交的申请
+func getRegistrationStatus(c *gin.Context) {
+	var reg models.NodeRegistration
+	err := db.Where("id = ? AND key_hash = ? AND invitation_id IN (?)", c.Param("id"), hashAgentToken(c.Query("key")),
+		db.Model(&models.Invitation{}).Select("id").Where("token_hash = ?", hashAgentToken(c.Param("token")))).
+		First(&reg).Error
+	if err != nil {
+		respondError(c, 404, ErrRegistrationNotFound, "Registration not found")
+		return
+	}
+	c.JSON(200, registrationResult(reg))
+}
+
+// getRegistrations 注册申请列表，?status= 过滤（pending / approved / rejected）
+func getRegistrations(c *gin.Context) {
+	q := db.Order("id DESC").Limit(500)
+	if v := c.Query("status"); v != "" {
+		q = q.Where("status = ?", v)
+	}
+	if v := c.Query("invitation"); v != "" {
+		q = q.Where("invitation_id = ?", v)
+	}
+	var list []models.NodeRegistration
+	q.Find(&list)
+	c.JSON(200, list)
+}
+
+// pendingRegistration 查找待审批的申请
+func pendingRegistration(c *gin.Context) (models.NodeRegistration, bool) {
+	var reg models.NodeRegistration
+	if err := db.First(&reg, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrRegistrationNotFound, "Registration not found")
+		return reg, false
+	}
+	if reg.Status != "pending" {
+		respondError(c, 409, ErrRegistrationDecided, "Registration has already been decided")
+		return reg, false
+	}
+	return reg, true
+}
+
+// approveRegistration 批准申请并创建节点
+func approveRegistration(c *gin.Context) {
+	reg, ok := pendingRegistration(c)
+	if !ok {
+		return
+	}
+	var inv models.Invitation
+	if err := db.First(&inv, reg.InvitationID).Error; err != nil {
+		respondError(c, 404, ErrInvitationNotFound, "Invitation not found or expired")
+		return
+	}
+	n, err := provisionInvitedNode(inv, reg)
+	if err != nil {
+		respondErr(c, 409, err, ErrInternal)
+		return
+	}
+	now := time.Now()
+	reg.Status, reg.NodeID, reg.DecidedAt, reg.DecidedBy = "approved", n.ID, &now, c.GetString("username")
+	db.Save(&reg)
+	recordAudit(c, "registration.approve", n.Name, fmt.Sprintf("community %s, IP %s", n.Community, n.IPAddress))
+	c.JSON(200, gin.H{"registration": reg, "node": n})
+}
+
+// rejectRegistration 拒绝申请并释放名额
+func rejectRegistration(c *gin.Context) {
+	reg, ok := pendingRegistration(c)
+	if !ok {
+		return
+	}
+	var p struct {
+		Reason string `json:"reason" binding:"max=255"`
+	}
+	if !bindOptionalJSON(c, &p) {
+		return
+	}
+	now := time.Now()
+	reg.Status, reg.Reason, reg.DecidedAt, reg.DecidedBy = "rejected", strings.TrimSpace(p.Reason), &now, c.GetString("username")
+	db.Save(&reg)
+	db.Model(&models.Invitation{}).Where("id = ? AND uses > 0", reg.InvitationID).Update("uses", gorm.Expr("uses - 1"))
+	recordAudit(c, "registration.reject", reg.Name, reg.Reason)
+	c.JSON(200, reg)
+}
+
+var invitePageTemplate = template.Must(template.New("invite").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>注册 n2n 节点</title>
+<style>
+body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",Roboto,"PingFang SC","Microsoft YaHei",sans-serif;background:#f5f6f8;color:#1f2329;margin:0}
+main{max-width:640px;margin:40px auto;padding:0 16px}
+h1{font-size:22px}
+.card{background:#fff;border-radius:8px;padding:20px;box-shadow:0 1px 2px rgba(0,0,0,.06)}
+label{display:block;margin:12px 0 4px;color:#646a73}
+input{width:100%;box-sizing:border-box;padding:8px;border:1px solid #d0d3d6;border-radius:4px;font-size:14px}
+button{margin-top:16px;padding:8px 20px;border:0;border-radius:4px;background:#1677ff;color:#fff;font-size:14px;cursor:pointer}
+pre{background:#f5f6f8;padding:12px;border-radius:4px;overflow:auto;font-size:13px}
+.error{color:#d64541}.muted{color:#8f959e;font-size:13px}
+</style>
+</head>
+<body>
+<main>
+<h1>注册 n2n 节点</h1>
+<div class="card" id="app"><p class="muted">加载中…</p></div>
+</main>
+<script>
+const api = {{.API}};
+const app = document.getElementById('app');
+const saved = 'n2n-invite-' + api;
+const esc = s => String(s).replace(/[&<>"']/g, ch => ({'&':'&amp;','<':'&lt;','>':'&gt;','"':'&quot;',"'":'&#39;'}[ch]));
+
+function show(r) {
+  if (r.status === 'approved' && r.conf) {
+    localStorage.removeItem(saved);
+    app.innerHTML = '<p>节点 <b>' + esc(r.name) + '</b> 已注册，虚拟 IP：<b>' + esc(r.ip_address) + '</b></p>' +
+      '<p>将以下内容保存为 edge 配置文件（如 /etc/n2n/edge.conf）后启动 edge：</p><pre id="conf"></pre>' +
+      '<button id="dl">下载配置</button><p class="muted">配置中包含社区密钥，请妥善保管。</p>';
+    document.getElementById('conf').textContent = r.conf;
+    document.getElementById('dl').onclick = () => {
+      const a = document.createElement('a');
+      a.href = URL.createObjectURL(new Blob([r.conf], {type: 'text/plain'}));
+      a.download = 'edge.conf';
+      a.click();
+    };
+  } else if (r.status === 'rejected') {
+    localStorage.removeItem(saved);
+    app.innerHTML = '<p class="error">注册申请已被拒绝' + (r.reason ? '：' + esc(r.reason) : '') + '</p>';
+  } else {
+    app.innerHTML = '<p>申请已提交，等待管理员审批。此页面会自动刷新，也可以稍后重新打开此链接查看结果。</p>';
+    setTimeout(poll, 10000);
+  }
+}
+
+async function poll() {
+  const s = JSON.parse(localStorage.getItem(saved) || 'null');
+  if (!s) return form();
+  const resp = await fetch(api + '/registrations/' + s.id + '?key=' + encodeURIComponent(s.key));
+  if (!resp.ok) { localStorage.removeItem(saved); return form(); }
+  show(await resp.json());
+}
+
+async function form() {
+  const resp = await fetch(api);
+  const info = await resp.json();
+  if (!resp.ok) { app.innerHTML = '<p class="error">' + esc(info.error) + '</p>'; return; }
+  app.innerHTML = '<p>加入社区 <b>' + esc(info.community) + '</b>' + (info.note ? '（' + esc(info.note) + '）' : '') + '</p>' +
+    (info.require_approval ? '<p class="muted">提交后需管理员审批。</p>' : '') +
+    '<form id="f"><label>设备名称</label><input name="name" maxlength="100" required placeholder="如 张三的笔记本">' +
+    '<label>邮箱（可选，用于接收通知）</label><input name="email" type="email" maxlength="255">' +
+    '<button type="submit">注册</button><p class="error" id="err"></p></form>';
+  document.getElementById('f').onsubmit = async e => {
+    e.preventDefault();
+    const fd = new FormData(e.target);
+    const r = await fetch(api, {method: 'POST', headers: {'Content-Type': 'application/json'},
+      body: JSON.stringify({name: fd.get('name'), email: fd.get('email')})});
+    const body = await r.json();
+    if (!r.ok) { document.getElementById('err').textContent = body.error; return; }
+    localStorage.setItem(saved, JSON.stringify({id: body.id, key: body.key}));
+    show(body);
+  };
+}
+
+poll();
+</script>
+</body>
+</html>
+`))
+
+// invitePage 邀请注册页面，数据通过 /api/invite/<令牌> 读取和提交
+func invitePage(c *gin.Context) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer") // 令牌在 URL 中，避免通过 Referer 泄露
+	c.Status(200)
+	api := normalizeBasePath(appConfig.BasePath) + "/api/invite/" + c.Param("token")
+	invitePageTemplate.Execute(c.Writer, gin.H{"API": api})
+}
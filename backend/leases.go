@@ -0,0 +1,173 @@
+package main
+
+import (
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 租约状态
+const (
+	leaseOK         = "ok"          // 在线且使用分配的 IP
+	leaseOffline    = "offline"     // 已分配但不在线
+	leaseMismatch   = "ip_mismatch" // edge 使用的 IP 与分配给其 MAC 的不一致
+	leaseTaken      = "ip_taken"    // 分配的 IP 正被其他 MAC 使用
+	leaseUnassigned = "unassigned"  // 在线 edge 的 MAC 未在面板登记
+)
+
+type ipLease struct {
+	MAC        string       `json:"mac"`
+	Community  string       `json:"community"`
+	AssignedIP string       `json:"assigned_ip"`
+	ReportedIP string       `json:"reported_ip"`
+	Online     bool         `json:"online"`
+	Status     string       `json:"status"`
+	Node       *models.Node `json:"node,omitempty"`
+	UsedBy     string       `json:"used_by,omitempty"` // ip_taken 时实际使用该 IP 的 MAC
+}
+
+func edgeReportedIP(info utils.EdgeInfo) string {
+	return strings.Split(info.Internal, "/")[0]
+}
+
+// buildLeases 将数据库分配的 IP 与 edge 实际上报的 IP 对照
+func buildLeases(nodes []models.Node, edges map[string]utils.EdgeInfo) []ipLease {
+	ipUser := make(map[string]string) // community|ip -> 实际使用的 MAC
+	for mac, info := range edges {
+		ipUser[info.Community+"|"+edgeReportedIP(info)] = mac
+	}
+
+	res := make([]ipLease, 0, len(nodes))
+	known := make(map[string]bool)
+	for i := range nodes {
+		n := &nodes[i]
+		mac := n.MacAddress
+		known[mac] = true
+		l := ipLease{MAC: mac, Community: n.Community, AssignedIP: n.IPAddress, Node: n}
+		if info, ok := edges[mac]; ok {
+			l.Online = true
+			l.ReportedIP = edgeReportedIP(info)
+		}
+		if user, ok := ipUser[n.Community+"|"+n.IPAddress]; ok && user != mac {
+			l.Status, l.UsedBy = leaseTaken, user
+		} else if !l.Online {
+			l.Status = leaseOffline
+		} else if l.ReportedIP != n.IPAddress {
+			l.Status = leaseMismatch
+		} else {
+			l.Status = leaseOK
+		}
+		res = append(res, l)
+	}
+	for mac, info := range edges {
+		if known[mac] {
+			continue
+		}
+		res = append(res, ipLease{MAC: mac, Community: info.Community, ReportedIP: edgeReportedIP(info), Online: true, Status: leaseUnassigned})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Community != res[j].Community {
+			return res[i].Community < res[j].Community
+		}
+		return utils.CompareIP(res[i].sortIP(), res[j].sortIP()) < 0
+	})
+	return res
+}
+
+// sortIP 排序用的 IP，优先使用分配的 IP
+func (l ipLease) sortIP() net.IP {
+	for _, s := range []string{l.AssignedIP, l.ReportedIP} {
+		if ip := net.ParseIP(s); ip != nil {
+			return ip
+		}
+	}
+	return net.IPv4zero
+}
+
+func getLeases(c *gin.Context) {
+	var nodes []models.Node
+	db.Find(&nodes)
+	edges, err := allEdgeInfo()
+	leases := buildLeases(nodes, edges)
+	if status := c.Query("status"); status != "" {
+		filtered := make([]ipLease, 0)
+		for _, l := range leases {
+			if l.Status == status {
+				filtered = append(filtered, l)
+			}
+		}
+		leases = filtered
+	}
+	c.JSON(200, gin.H{"leases": leases, "mgmt_error": errString(err)})
+}
+
+// reconcileLease 一键修复租约：
+// adopt - 将节点的分配 IP 改为 edge 实际使用的 IP
+// register - 为未登记的在线 edge 按其上报的 IP 创建节点
+func reconcileLease(c *gin.Context) {
+	var p struct {
+		Action string `json:"action" binding:"required,oneof=adopt register"`
+		Name   string `json:"name"` // register 时可选的节点名称
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	mac := utils.NormalizeMac(c.Param("mac"))
+	edges, _ := allEdgeInfo()
+	info, online := edges[mac]
+	if !online {
+		respondError(c, 409, ErrNodeOffline, "Edge is not online")
+		return
+	}
+	ip := edgeReportedIP(info)
+	comm, err := lookupCommunity(info.Community)
+	if err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return
+	}
+	if err := validateNodeIP(ip, comm); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return
+	}
+
+	var n models.Node
+	exists := db.Where("mac_address = ?", mac).First(&n).Error == nil
+	switch p.Action {
+	case "adopt":
+		if !exists {
+			respondError(c, 404, ErrNodeNotFound, "Node not found")
+			return
+		}
+		n.IPAddress = ip
+	case "register":
+		if exists {
+			respondError(c, 409, ErrNodeConflict, "MAC address already registered")
+			return
+		}
+		if isMacBlocked(mac) {
+			respondError(c, 403, ErrMacBlocked, "MAC address is blocked")
+			return
+		}
+		n = models.Node{Name: p.Name, MacAddress: mac, IPAddress: ip, Community: comm.Name, IsEnabled: true}
+		if strings.TrimSpace(n.Name) == "" {
+			n.Name = "edge-" + mac
+		}
+	}
+
+	for _, cf := range findNodeConflicts(n.MacAddress, n.IPAddress) {
+		if cf.Node.ID != n.ID {
+			respondError(c, 409, ErrNodeConflict, "MAC or IP address already used by another node", gin.H{"conflicts": []nodeConflict{cf}})
+			return
+		}
+	}
+	refreshConfigState(&n)
+	if err := db.Save(&n).Error; err != nil {
+		respondError(c, 500, ErrInternal, "Failed to save node")
+		return
+	}
+	c.JSON(200, n)
+}
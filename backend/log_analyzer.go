@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	logEventBufferSize    = 500              // 内存中保留的最近事件数
+	logEventAlertCooldown = 10 * time.Minute // 同一类异常对同一对象的告警间隔
+	lastSeenUpdateEvery   = 1 * time.Minute  // 注册事件更新节点 LastSeen 的最小间隔
+)
+
+// supernodeEvent 从 supernode 日志中解析出的事件（不含高频的中转事件）
+type supernodeEvent struct {
+	utils.LogEvent
+	Instance string    `json:"instance"`
+	NodeName string    `json:"node_name,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+var (
+	logEvents       []supernodeEvent
+	logEventAlerted = make(map[string]time.Time) // 类型|对象 -> 最近告警时间
+	logEventMutex   sync.Mutex
+)
+
+// startLogAnalyzer 订阅实例所有日志来源的共享日志流并分发解析出的事件，ctx 取消时退出（实例被删除或修改）
+// 以不丢行的方式订阅，日志突增时也不会漏掉注册、认证失败等事件
+func startLogAnalyzer(ctx context.Context, instance string, sources []string) {
+	lines := newLogChannel()
+	for _, src := range sources {
+		subscribeLogsReliable(ctx, src, lines)
+		defer unsubscribeLogs(src, lines)
+	}
+	lastSeen := make(map[string]time.Time)
+	for {
+		var line string
+		select {
+		case <-ctx.Done():
+			return
+		case line = <-lines:
+		}
+		forwardSyslog(syslogCategorySupernodeLog, logLineSyslogSeverity(line), "supernode_log", line, map[string]string{"instance": instance})
+		ev, ok := utils.ParseLogEvent(line)
+		if !ok {
+			continue
+		}
+		switch ev.Type {
+		case utils.EventRelay:
+			recordRelay(ev.Mac, ev.DstMac)
+			continue
+		case utils.EventRegister:
+			if ev.Mac != "" && time.Since(lastSeen[ev.Mac]) >= lastSeenUpdateEvery {
+				lastSeen[ev.Mac] = time.Now()
+				db.Model(&models.Node{}).Where("mac_address = ?", ev.Mac).Update("last_seen", time.Now())
+			}
+		}
+		handleLogEvent(supernodeEvent{LogEvent: ev, Instance: instance, Time: time.Now()})
+	}
+}
+
+// recordRelay 更新中转表并推送给实时订阅者
+func recordRelay(src, dst string) {
+	key := src + "->" + dst
+	relayMutex.Lock()
+	ev, ok := relayMap[key]
+	isNew := !ok || time.Since(ev.LastActive) >= relayActiveWindow
+	if ok {
+		ev.LastActive = time.Now()
+		ev.PktCount++
+	} else {
+		ev = &RelayEvent{SrcMac: src, DstMac: dst, LastActive: time.Now(), PktCount: 1}
+		relayMap[key] = ev
+	}
+	snapshot := *ev
+	relayMutex.Unlock()
+	publishRelayEvent(snapshot, isNew)
+}
+
+// handleLogEvent 记录事件，认证失败和社区错误按对象限频告警
+func handleLogEvent(ev supernodeEvent) {
+	if ev.Mac != "" {
+		var n models.Node
+		if db.Select("name").Where("mac_address = ?", ev.Mac).Limit(1).Find(&n); n.Name != "" {
+			ev.NodeName = n.Name
+		}
+	}
+	logEventMutex.Lock()
+	logEvents = append(logEvents, ev)
+	if len(logEvents) > logEventBufferSize {
+		logEvents = logEvents[len(logEvents)-logEventBufferSize:]
+	}
+	var alert *Alert
+	switch ev.Type {
+	case utils.EventAuthFailed:
+		who := ev.Mac
+		if ev.NodeName != "" {
+			who = fmt.Sprintf("%s (%s)", ev.NodeName, ev.Mac)
+		}
+		if who == "" {
+			who = ev.Addr
+		}
+		alert = &Alert{Type: "edge_auth_failed", Level: "warning", Title: "edge 认证失败: " + who,
+			Message: fmt.Sprintf("supernode %s 拒绝了 %s 的注册: %s", ev.Instance, who, ev.Line)}
+	case utils.EventCommunityError:
+		alert = &Alert{Type: "community_rejected", Level: "warning", Title: "edge 使用了未允许的社区: " + ev.Community,
+			Message: fmt.Sprintf("supernode %s 拒绝了社区 %s 的注册，请检查 community.list 是否已同步: %s", ev.Instance, ev.Community, ev.Line)}
+	}
+	if alert != nil {
+		key := ev.Type + "|" + ev.Instance + "|" + ev.Mac + ev.Community + ev.Addr
+		if time.Since(logEventAlerted[key]) < logEventAlertCooldown {
+			alert = nil
+		} else {
+			logEventAlerted[key] = time.Now()
+			for k, t := range logEventAlerted {
+				if time.Since(t) >= logEventAlertCooldown {
+					delete(logEventAlerted, k)
+				}
+			}
+		}
+	}
+	logEventMutex.Unlock()
+	if alert != nil {
+		dispatchAlert(*alert)
+	}
+}
+
+// getSupernodeEvents 返回最近的 supernode 日志事件（新的在前），可用 ?type= 和 ?instance=（实例名称）过滤
+func getSupernodeEvents(c *gin.Context) {
+	typ, instance := c.Query("type"), c.Query("instance")
+	logEventMutex.Lock()
+	defer logEventMutex.Unlock()
+	res := make([]supernodeEvent, 0)
+	for i := len(logEvents) - 1; i >= 0; i-- {
+		ev := logEvents[i]
+		if (typ == "" || ev.Type == typ) && (instance == "" || ev.Instance == instance) {
+			res = append(res, ev)
+		}
+	}
+	c.JSON(200, res)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// 日志文件的读取与跟踪，不依赖 tail 命令，Windows 上同样可用
+
+const logFilePollInterval = 500 * time.Millisecond
+
+// lastLines 返回文件最后 n 行，只读取文件末尾足够的部分
+func lastLines(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return []string{}, nil
+	}
+	chunk := int64(n) * 512
+	if chunk < 64*1024 {
+		chunk = 64 * 1024
+	}
+	start := fi.Size() - chunk
+	if start < 0 {
+		start = 0
+	}
+	data := make([]byte, fi.Size()-start)
+	if _, err := f.ReadAt(data, start); err != nil && err != io.EOF {
+		return nil, err
+	}
+	data = bytes.TrimRight(data, "\r\n")
+	if len(data) == 0 {
+		return []string{}, nil
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if start > 0 && len(lines) > 0 {
+		lines = lines[1:] // 第一行可能不完整
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// tailReader 跟踪日志的读取端，Close 时停止后台读取
+type tailReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (t *tailReader) Close() error {
+	t.cancel()
+	return t.PipeReader.Close()
+}
+
+// tailFile 类似 tail -F：先输出最后 backlog 行，之后定期读取新增内容；
+// 文件被替换（轮转）或截断时从头读取新文件，文件暂不存在时等待其出现
+func tailFile(ctx context.Context, path string, backlog int) (io.ReadCloser, error) {
+	initial, err := lastLines(path, backlog)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var offset int64
+	var current os.FileInfo
+	if fi, err := os.Stat(path); err == nil {
+		current, offset = fi, fi.Size()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	go func() {
+		defer cancel()
+		for _, l := range initial {
+			if _, err := io.WriteString(pw, l+"\n"); err != nil {
+				return
+			}
+		}
+		ticker := time.NewTicker(logFilePollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				pw.CloseWithError(ctx.Err())
+				return
+			case <-ticker.C:
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if current == nil || !os.SameFile(fi, current) || fi.Size() < offset {
+				offset = 0
+			}
+			current = fi
+			if fi.Size() == offset {
+				continue
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			f.Seek(offset, io.SeekStart)
+			n, err := io.Copy(pw, f)
+			f.Close()
+			offset += n
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return &tailReader{PipeReader: pr, cancel: cancel}, nil
+}
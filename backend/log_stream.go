@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	logTailBacklog   = 100              // 新订阅者先收到的历史行数
+	logStreamPing    = 15 * time.Second // SSE 心跳间隔
+	logSubscriberBuf = 256              // 订阅者缓冲，处理不过来时丢弃
+)
+
+// logSubscriber 订阅方式：SSE 等客户端跟不上时丢弃新行；reliable 的订阅者（日志分析）不丢行，
+// 缓冲满时发布方等待它处理，直到 done 关闭
+type logSubscriber struct {
+	reliable bool
+	done     <-chan struct{}
+}
+
+// logTailer 每个日志来源（systemd 单元或日志文件）只运行一个跟踪进程，按行广播给所有订阅者
+// 最后一个订阅者离开时停止
+type logTailer struct {
+	source string
+	cancel context.CancelFunc
+	subs   map[chan string]logSubscriber
+	recent []string // 最近 logTailBacklog 行，供新订阅者回放
+}
+
+var (
+	logTailers     = make(map[string]*logTailer)
+	logTailerMutex sync.Mutex
+)
+
+func newLogChannel() chan string {
+	return make(chan string, logSubscriberBuf)
+}
+
+// subscribeLogs 将 ch 订阅到指定来源的日志，同一个 ch 可订阅多个来源；replay 为 true 时返回最近的历史行
+// ch 跟不上时丢弃新行
+func subscribeLogs(source string, ch chan string, replay bool) []string {
+	return addLogSubscriber(source, ch, logSubscriber{}, replay)
+}
+
+// subscribeLogsReliable 与 subscribeLogs 相同但不丢行，ctx 取消后发布方不再等待 ch，之后需调用 unsubscribeLogs
+func subscribeLogsReliable(ctx context.Context, source string, ch chan string) {
+	addLogSubscriber(source, ch, logSubscriber{reliable: true, done: ctx.Done()}, false)
+}
+
+func addLogSubscriber(source string, ch chan string, sub logSubscriber, replay bool) []string {
+	logTailerMutex.Lock()
+	defer logTailerMutex.Unlock()
+	t, ok := logTailers[source]
+	if !ok {
+		// 先读取历史行作为回放缓冲，之后只跟踪新日志；journalctl 可能很慢，读取时不持锁，
+		// 以免阻塞其他来源的发布与订阅
+		logTailerMutex.Unlock()
+		recent, err := recentLogSource(source, logTailBacklog)
+		logTailerMutex.Lock()
+		// 读取期间可能已有其他订阅者启动了跟踪
+		if t, ok = logTailers[source]; !ok {
+			ctx, cancel := context.WithCancel(context.Background())
+			t = &logTailer{source: source, cancel: cancel, subs: make(map[chan string]logSubscriber)}
+			if err == nil {
+				t.recent = recent
+			}
+			logTailers[source] = t
+			go t.run(ctx)
+		}
+	}
+	t.subs[ch] = sub
+	var backlog []string
+	if replay {
+		backlog = append(backlog, t.recent...)
+	}
+	return backlog
+}
+
+// unsubscribeLogs 取消订阅，来源没有订阅者时停止跟踪
+func unsubscribeLogs(source string, ch chan string) {
+	logTailerMutex.Lock()
+	defer logTailerMutex.Unlock()
+	t, ok := logTailers[source]
+	if !ok {
+		return
+	}
+	delete(t.subs, ch)
+	if len(t.subs) == 0 {
+		t.cancel()
+		delete(logTailers, source)
+	}
+}
+
+// run 跟踪日志直到 ctx 取消，跟踪进程异常退出时自动重启
+func (t *logTailer) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		stdout, err := followLogSource(ctx, t.source, 0)
+		if err != nil {
+			log.Printf("Log tailer %s: failed to start: %v, retrying in 5s", t.source, err)
+			sleepCtx(ctx, 5*time.Second)
+			continue
+		}
+		reader := bufio.NewReader(stdout)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Log tailer %s: read error: %v, restarting", t.source, err)
+				}
+				break
+			}
+			t.publish(strings.TrimRight(line, "\r\n"))
+		}
+		stdout.Close()
+		sleepCtx(ctx, 2*time.Second)
+	}
+}
+
+// publish 记录并分发一行；等待 reliable 订阅者时不持锁，每个来源只有 run 一个发布方，行的顺序不变
+func (t *logTailer) publish(line string) {
+	logTailerMutex.Lock()
+	t.recent = append(t.recent, line)
+	if len(t.recent) > logTailBacklog {
+		t.recent = t.recent[len(t.recent)-logTailBacklog:]
+	}
+	subs := make(map[chan string]logSubscriber, len(t.subs))
+	for ch, sub := range t.subs {
+		subs[ch] = sub
+	}
+	logTailerMutex.Unlock()
+
+	for ch, sub := range subs {
+		if sub.reliable {
+			select {
+			case ch <- line:
+			case <-sub.done:
+			}
+			continue
+		}
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// n2n 日志级别，数值越小越严重，与 supernode -v 的 trace level 对应
+var logLevels = map[string]int{"error": 0, "warning": 1, "normal": 2, "info": 3, "debug": 4}
+
+// logLineLevel 从日志行中识别级别，未标注级别的行按 normal 处理
+func logLineLevel(line string) int {
+	switch {
+	case strings.Contains(line, "ERROR"):
+		return logLevels["error"]
+	case strings.Contains(line, "WARNING"):
+		return logLevels["warning"]
+	case strings.Contains(line, "DEBUG"):
+		return logLevels["debug"]
+	case strings.Contains(line, "INFO"):
+		return logLevels["info"]
+	}
+	return logLevels["normal"]
+}
+
+// logFilter 每个客户端独立的过滤条件
+type logFilter struct {
+	grep  string // 不区分大小写的子串匹配
+	level int    // 只显示该级别及更严重的行
+}
+
+func (f logFilter) match(line string) bool {
+	if logLineLevel(line) > f.level {
+		return false
+	}
+	return f.grep == "" || strings.Contains(strings.ToLower(line), f.grep)
+}
+
+// streamLogs 以 SSE 推送 supernode 日志，同一来源的所有客户端共享一个跟踪进程
+// 实例有多个日志来源时每行带 [来源] 前缀，可用 ?source= 只看其中一个
+// 可用 ?grep= 过滤关键字，?level=error|warning|normal|info|debug 过滤级别
+func streamLogs(c *gin.Context) {
+	rt, ok := instanceParam(c)
+	if !ok {
+		return
+	}
+	filter := logFilter{grep: strings.ToLower(c.Query("grep")), level: logLevels["debug"]}
+	if v := c.Query("level"); v != "" {
+		level, ok := logLevels[strings.ToLower(v)]
+		if !ok {
+			respondError(c, 400, ErrInvalidRequest, "Invalid log level")
+			return
+		}
+		filter.level = level
+	}
+	sources, ok := logSourcesParam(c, rt)
+	if !ok {
+		return
+	}
+
+	type sourcedLine struct{ source, line string }
+	lines := make(chan sourcedLine, logSubscriberBuf)
+	var backlog []sourcedLine
+	for _, src := range sources {
+		ch := newLogChannel()
+		for _, line := range subscribeLogs(src, ch, true) {
+			backlog = append(backlog, sourcedLine{src, line})
+		}
+		defer unsubscribeLogs(src, ch)
+		go func(src string, ch chan string) {
+			for {
+				select {
+				case <-c.Request.Context().Done():
+					return
+				case line := <-ch:
+					select {
+					case lines <- sourcedLine{src, line}:
+					default:
+					}
+				}
+			}
+		}(src, ch)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	send := func(l sourcedLine) {
+		if !filter.match(l.line) {
+			return
+		}
+		if len(sources) > 1 {
+			fmt.Fprintf(c.Writer, "data: [%s] %s\n\n", l.source, l.line)
+		} else {
+			fmt.Fprintf(c.Writer, "data: %s\n\n", l.line)
+		}
+	}
+	for _, l := range backlog {
+		send(l)
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(logStreamPing)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case l := <-lines:
+			send(l)
+			c.Writer.Flush()
+		case <-ticker.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// logSourcesParam 返回实例的日志来源，指定 ?source= 时只返回该来源
+func logSourcesParam(c *gin.Context, rt *instanceRuntime) ([]string, bool) {
+	sources := instanceLogSources(rt.instance)
+	v := c.Query("source")
+	if v == "" {
+		return sources, true
+	}
+	for _, src := range sources {
+		if src == v {
+			return []string{src}, true
+		}
+	}
+	respondError(c, 400, ErrInvalidRequest, "Unknown log source")
+	return nil, false
+}
+
+// getRecentLogs 返回实例各日志来源最近的 100 行
+func getRecentLogs(c *gin.Context) {
+	rt, ok := instanceParam(c)
+	if !ok {
+		return
+	}
+	sources, ok := logSourcesParam(c, rt)
+	if !ok {
+		return
+	}
+	res := make([]string, 0)
+	for _, src := range sources {
+		lines, err := recentLogSource(src, logTailBacklog)
+		if err != nil {
+			respondError(c, 500, ErrLogsUnavailable, "Failed to read logs")
+			return
+		}
+		for _, line := range lines {
+			if len(sources) > 1 {
+				line = "[" + src + "] " + line
+			}
+			res = append(res, line)
+		}
+	}
+	c.JSON(200, gin.H{"logs": res})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const loginHistoryRetention = 90 * 24 * time.Hour
+
+// recordLogin 记录一次登录尝试，IP 归属地查询较慢，异步写入
+func recordLogin(c *gin.Context, user models.User, username string, success bool, reason string) {
+	entry := models.LoginHistory{
+		UserID: user.ID, Username: username, IP: c.ClientIP(), UserAgent: c.Request.UserAgent(),
+		Success: success, Reason: reason, CreatedAt: time.Now(),
+	}
+	if success {
+		forwardSyslog(syslogCategoryAudit, utils.SyslogInfo, "login", username+" logged in from "+entry.IP,
+			map[string]string{"user": username, "ip": entry.IP})
+	} else {
+		forwardSyslog(syslogCategoryAudit, utils.SyslogWarning, "login_failed", username+" failed to log in from "+entry.IP+": "+reason,
+			map[string]string{"user": username, "ip": entry.IP, "reason": reason})
+	}
+	go func() {
+		loc := getIPLocation(entry.IP)
+		entry.Location = fmt.Sprintf("%s %s (%s)", loc.Country, loc.City, loc.ISP)
+		if err := db.Create(&entry).Error; err != nil {
+			log.Printf("Failed to save login history: %v", err)
+		}
+	}()
+}
+
+// markLoginSuccess 更新用户的最近登录信息，返回本次登录之前的用户信息与其后的失败次数
+func markLoginSuccess(c *gin.Context, user models.User) gin.H {
+	var failed int64
+	q := db.Model(&models.LoginHistory{}).Where("username = ? AND success = ?", user.Username, false)
+	if user.LastLoginAt != nil {
+		q = q.Where("created_at > ?", *user.LastLoginAt)
+	}
+	q.Count(&failed)
+
+	res := gin.H{
+		"id": user.ID, "username": user.Username, "is_admin": user.IsAdmin,
+		"last_login_at": user.LastLoginAt, "last_login_ip": user.LastLoginIP, "failed_since_last_login": failed,
+	}
+	db.Model(&user).Updates(map[string]interface{}{"last_login_at": time.Now(), "last_login_ip": c.ClientIP()})
+	recordLogin(c, user, user.Username, true, "")
+	return res
+}
+
+// pruneLoginHistory 清理超过保留期的登录记录
+func pruneLoginHistory() {
+	db.Where("created_at < ?", time.Now().Add(-loginHistoryRetention)).Delete(&models.LoginHistory{})
+}
+
+// requireAdmin 仅允许管理员访问
+func requireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok || !user.IsAdmin {
+			respondError(c, 403, ErrForbidden, "Admin privileges required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func getUsers(c *gin.Context) {
+	var users []models.User
+	db.Order("id").Find(&users)
+	c.JSON(200, users)
+}
+
+func getUserLogins(c *gin.Context) {
+	var user models.User
+	if err := db.First(&user, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrUserNotFound, "User not found")
+		return
+	}
+	q := db.Where("username = ?", user.Username)
+	if c.Query("failed") == "true" {
+		q = q.Where("success = ?", false)
+	}
+	var list []models.LoginHistory
+	q.Order("id DESC").Limit(200).Find(&list)
+	c.JSON(200, list)
+}
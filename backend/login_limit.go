@@ -0,0 +1,87 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loginLimitConfig 登录防爆破参数
+type loginLimitConfig struct {
+	MaxAttempts  int
+	LockDuration time.Duration
+	RecordExpiry time.Duration
+}
+
+// loginLimits 读取防爆破参数：设置 login_max_attempts / login_lock_minutes / login_record_expiry_minutes
+// 优先，未设置时使用环境变量配置
+func loginLimits() loginLimitConfig {
+	l := loginLimitConfig{
+		MaxAttempts:  appConfig.LoginMaxAttempts,
+		LockDuration: appConfig.LoginLockDuration,
+		RecordExpiry: appConfig.LoginRecordExpiry,
+	}
+	if v, err := strconv.Atoi(getSettingValue("login_max_attempts", "")); err == nil && v >= 0 {
+		l.MaxAttempts = v
+	}
+	if v, err := strconv.Atoi(getSettingValue("login_lock_minutes", "")); err == nil && v > 0 {
+		l.LockDuration = time.Duration(v) * time.Minute
+	}
+	if v, err := strconv.Atoi(getSettingValue("login_record_expiry_minutes", "")); err == nil && v > 0 {
+		l.RecordExpiry = time.Duration(v) * time.Minute
+	}
+	return l
+}
+
+// getLoginLocks 列出当前的失败记录，locked=true 只返回被锁定的 IP / 用户名
+func getLoginLocks(c *gin.Context) {
+	loginMutex.Lock()
+	now := time.Now()
+	res := make([]gin.H, 0)
+	for key, a := range loginAttempts {
+		locked := now.Before(a.LockUntil)
+		if c.Query("locked") == "true" && !locked {
+			continue
+		}
+		kind, value := "ip", key
+		if strings.HasPrefix(key, "user:") {
+			kind, value = "username", strings.TrimPrefix(key, "user:")
+		}
+		entry := gin.H{"key": key, "type": kind, "value": value, "fail_count": a.FailCount, "last_fail": a.LastFail, "locked": locked}
+		if locked {
+			entry["lock_until"] = a.LockUntil
+			entry["seconds"] = int(a.LockUntil.Sub(now).Seconds())
+		}
+		res = append(res, entry)
+	}
+	loginMutex.Unlock()
+	sort.Slice(res, func(i, j int) bool { return res[i]["key"].(string) < res[j]["key"].(string) })
+
+	limits := loginLimits()
+	c.JSON(200, gin.H{
+		"entries": res, "max_attempts": limits.MaxAttempts,
+		"lock_seconds": int(limits.LockDuration.Seconds()), "expiry_seconds": int(limits.RecordExpiry.Seconds()),
+	})
+}
+
+// unlockLogin 立即解锁，?key= 为 getLoginLocks 返回的 key，未指定时清除全部记录
+func unlockLogin(c *gin.Context) {
+	key := c.Query("key")
+	loginMutex.Lock()
+	defer loginMutex.Unlock()
+	if key == "" {
+		n := len(loginAttempts)
+		loginAttempts = make(map[string]*LoginAttempt)
+		c.JSON(200, gin.H{"unlocked": n})
+		return
+	}
+	if _, ok := loginAttempts[key]; !ok {
+		respondError(c, 404, ErrNotFound, "Login lock not found")
+		return
+	}
+	delete(loginAttempts, key)
+	c.JSON(200, gin.H{"unlocked": 1})
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"crypto/rand"
 	"embed"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -13,8 +14,6 @@ import (
 	"n2n_ui/backend/config"
 	"n2n_ui/backend/models"
 	"n2n_ui/backend/utils"
-	"os/exec"
-	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -28,19 +27,20 @@ import (
 	"gorm.io/gorm"
 )
 
-const Version = "v1.0.0"
+// Version 面板版本，发布构建时可通过 -ldflags "-X main.Version=..." 覆盖
+var Version = "v1.0.0"
 
 //go:embed dist/*
 var content embed.FS
 
 var db *gorm.DB
-var n2nMgmt *utils.MgmtClient
 var jwtSecret []byte
 var appConfig *config.Config
 
 func setupConfig() {
 	appConfig = config.Get()
 	jwtSecret = []byte(appConfig.JWTSecret)
+	utils.SupernodeBinary = appConfig.SupernodeBinary
 }
 
 type IPLocation struct {
@@ -75,12 +75,7 @@ type LoginAttempt struct {
 	LastFail  time.Time
 }
 
-const (
-	maxLoginAttempts  = 5                // 最大失败次数
-	lockDuration      = 15 * time.Minute // 锁定时长
-	maxLoginRecords   = 10000            // 最大记录数，防止内存耗尽
-	loginRecordExpiry = 1 * time.Hour    // 记录过期时间
-)
+const maxLoginRecords = 10000 // 最大记录数，防止内存耗尽
 
 var (
 	loginAttempts = make(map[string]*LoginAttempt) // key: IP 或 username
@@ -89,13 +84,14 @@ var (
 
 // cleanExpiredLoginAttempts 清理过期的登录记录
 func cleanExpiredLoginAttempts() {
+	expiry := loginLimits().RecordExpiry
 	loginMutex.Lock()
 	defer loginMutex.Unlock()
 
 	now := time.Now()
 	for key, attempt := range loginAttempts {
 		// 清理过期记录（未锁定且超过过期时间）
-		if now.After(attempt.LockUntil) && now.Sub(attempt.LastFail) > loginRecordExpiry {
+		if now.After(attempt.LockUntil) && now.Sub(attempt.LastFail) > expiry {
 			delete(loginAttempts, key)
 		}
 	}
@@ -106,11 +102,14 @@ func startLoginCleanupRoutine() {
 	ticker := time.NewTicker(10 * time.Minute)
 	for range ticker.C {
 		cleanExpiredLoginAttempts()
+		pruneLoginHistory()
+		pruneSessions()
 	}
 }
 
 // checkLoginLock 检查是否被锁定，返回剩余锁定时间
 func checkLoginLock(key string) (bool, time.Duration) {
+	max := loginLimits().MaxAttempts
 	loginMutex.Lock()
 	defer loginMutex.Unlock()
 
@@ -119,7 +118,7 @@ func checkLoginLock(key string) (bool, time.Duration) {
 			return true, time.Until(attempt.LockUntil)
 		}
 		// 锁定已过期，重置
-		if attempt.FailCount >= maxLoginAttempts {
+		if max > 0 && attempt.FailCount >= max {
 			attempt.FailCount = 0
 		}
 	}
@@ -128,6 +127,8 @@ func checkLoginLock(key string) (bool, time.Duration) {
 
 // recordLoginFail 记录登录失败
 func recordLoginFail(ip, username string) (locked bool, remaining time.Duration) {
+	limits := loginLimits()
+	captcha := captchaMode() != captchaOff
 	loginMutex.Lock()
 	defer loginMutex.Unlock()
 
@@ -165,10 +166,14 @@ func recordLoginFail(ip, username string) (locked bool, remaining time.Duration)
 		}
 		loginAttempts[key].FailCount++
 		loginAttempts[key].LastFail = time.Now()
-		if loginAttempts[key].FailCount >= maxLoginAttempts {
-			loginAttempts[key].LockUntil = time.Now().Add(lockDuration)
+		// 启用验证码后不再按用户名锁定
+		if captcha && key != ip {
+			continue
+		}
+		if limits.MaxAttempts > 0 && loginAttempts[key].FailCount >= limits.MaxAttempts {
+			loginAttempts[key].LockUntil = time.Now().Add(limits.LockDuration)
 			locked = true
-			remaining = lockDuration
+			remaining = limits.LockDuration
 		}
 	}
 	return
@@ -195,16 +200,35 @@ func generateRandomPassword(length int) string {
 	return string(b)
 }
 
-func initDB() {
+func openDB() {
 	var err error
 	db, err = gorm.Open(sqlite.Open(appConfig.DBPath), &gorm.Config{})
 	if err != nil {
 		log.Fatal("failed to connect database")
 	}
-	db.AutoMigrate(&models.Node{}, &models.Community{}, &models.Setting{}, &models.User{})
+}
+
+func initDB() {
+	openDB()
+	migrateDB()
+	migrateSettings()
+	migrateEncryptedFields()
+	ensureDefaultInstance()
 	var userCount int64
 	db.Model(&models.User{}).Count(&userCount)
-	if userCount == 0 {
+	if userCount == 0 && appConfig.SetupWizard {
+		log.Println("========================================")
+		log.Println("  首次启动，请在浏览器中打开管理面板完成初始化设置")
+		if appConfig.SetupToken != "" {
+			log.Println("  初始化时需提供 N2N_SETUP_TOKEN 中设置的令牌")
+		} else {
+			// 未配置令牌时生成一次性令牌，只有能看到日志的人才能完成初始化，避免公网上的他人抢先成为管理员
+			appConfig.SetupToken = generateRandomPassword(24)
+			log.Printf("  初始化令牌: %s", appConfig.SetupToken)
+			log.Println("  （本次启动有效，重启后重新生成；也可通过 N2N_SETUP_TOKEN 指定）")
+		}
+		log.Println("========================================")
+	} else if userCount == 0 {
 		// 生成随机密码而非固定密码
 		randomPassword := generateRandomPassword(12)
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
@@ -239,7 +263,9 @@ func getIPLocation(ip string) IPLocation {
 
 	url := fmt.Sprintf("http://ip-api.com/json/%s?lang=zh-CN", ip) // 免费版仅支持 HTTP
 	client := &http.Client{Timeout: 5 * time.Second}
+	start := time.Now()
 	resp, err := client.Get(url)
+	recordDependency("geoip", time.Since(start), err)
 	if err != nil {
 		return IPLocation{Country: "未知", City: "查询失败", ISP: "-"}
 	}
@@ -295,44 +321,11 @@ func startIPCacheCleaner() {
 	}
 }
 
-func startLogAnalyzer() {
-	re := regexp.MustCompile(`forwarding packet.*from ([0-9A-Fa-f:]{17}) to ([0-9A-Fa-f:]{17})`)
-	for {
-		cmd := exec.Command("journalctl", "-u", "supernode", "-f", "-n", "0")
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
-			log.Printf("Log analyzer: failed to create pipe: %v, retrying in 5s", err)
-			time.Sleep(5 * time.Second)
-			continue
-		}
-		if err := cmd.Start(); err != nil {
-			log.Printf("Log analyzer: failed to start: %v, retrying in 5s", err)
-			time.Sleep(5 * time.Second)
-			continue
-		}
-		reader := bufio.NewReader(stdout)
-		for {
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				log.Printf("Log analyzer: read error: %v, restarting", err)
-				cmd.Process.Kill()
-				break
-			}
-			matches := re.FindStringSubmatch(line)
-			if len(matches) == 3 {
-				src := strings.ToUpper(strings.ReplaceAll(matches[1], ":", ""))
-				dst := strings.ToUpper(strings.ReplaceAll(matches[2], ":", ""))
-				key := src + "->" + dst
-				relayMutex.Lock()
-				if ev, ok := relayMap[key]; ok {
-					ev.LastActive = time.Now(); ev.PktCount++
-				} else {
-					relayMap[key] = &RelayEvent{SrcMac: src, DstMac: dst, LastActive: time.Now(), PktCount: 1}
-				}
-				relayMutex.Unlock()
-			}
-		}
-		time.Sleep(2 * time.Second)
+// sleepCtx 等待指定时长，ctx 取消时提前返回
+func sleepCtx(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
 	}
 }
 
@@ -344,46 +337,95 @@ func jwtMiddleware() gin.HandlerFunc {
 		}
 		// 安全：不再从 URL query 参数读取 token，防止日志泄露
 		if tokenString == "" {
-			c.JSON(401, gin.H{"error": "Unauthorized"})
+			respondError(c, 401, ErrUnauthorized, "Unauthorized")
 			c.Abort()
 			return
 		}
-		token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-			// 显式校验签名算法，防止算法混淆攻击
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-			}
-			return jwtSecret, nil
-		})
+		token, err := jwt.Parse(tokenString, jwtKey)
 		if err != nil || token == nil || !token.Valid {
-			c.JSON(401, gin.H{"error": "Invalid token"})
+			respondError(c, 401, ErrInvalidToken, "Invalid token")
 			c.Abort()
 			return
 		}
 		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
-			c.JSON(401, gin.H{"error": "Invalid claims"})
+			respondError(c, 401, ErrInvalidToken, "Invalid claims")
 			c.Abort()
 			return
 		}
-		c.Set("username", claims["username"])
+		// 用户名以令牌中用户 ID 对应的用户为准（用户名可能已修改）
+		user, ok := tokenUser(claims)
+		if !ok {
+			respondError(c, 401, ErrInvalidToken, "Session has been revoked or expired")
+			c.Abort()
+			return
+		}
+		c.Set("username", user.Username)
+		c.Set("session_id", claims["jti"])
 		c.Next()
 	}
 }
 
+// currentUser 返回当前登录用户
+func currentUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	u, ok := c.Get("username")
+	if !ok {
+		return user, false
+	}
+	if err := db.Where("username = ?", u).First(&user).Error; err != nil {
+		return user, false
+	}
+	return user, true
+}
+
 func main() {
 	port := flag.String("p", "", "Web UI 监听端口")
 	showVersion := flag.Bool("v", false, "显示版本信息")
 	resetPassword := flag.String("reset-password", "", "重置指定用户的密码 (格式: 用户名:新密码)")
+	demo := flag.Bool("demo", false, "演示模式：使用内置的假 supernode 和内存数据库")
+	flag.BoolVar(&ipFilterBypass, "bypass-ip-filter", false, "紧急情况下忽略访问 IP 允许/拒绝列表")
+	migrate := flag.String("migrate", "", "数据库迁移: status 查看状态, up 执行未执行的迁移, down 回滚最近一次迁移")
+	upgrade := flag.Bool("upgrade", false, "下载并安装最新版本，设置 N2N_SERVICE_UNIT 时随后重启该服务")
+	upgradeForce := flag.Bool("upgrade-force", false, "与 -upgrade 一起使用：即使已是最新版本也重新安装")
 	flag.Parse()
 
 	if *showVersion {
 		fmt.Printf("n2n-admin version: %s\n", Version)
+		if GitCommit != "" || BuildDate != "" {
+			fmt.Printf("commit: %s, built: %s\n", GitCommit, BuildDate)
+		}
 		return
 	}
 
 	setupConfig()
+	if *migrate != "" {
+		initEncryption(false)
+		openDB()
+		runMigrateCommand(*migrate)
+		return
+	}
+	if *upgrade {
+		initEncryption(false)
+		openDB()
+		runUpgradeCommand(*upgradeForce)
+		return
+	}
+	if *demo {
+		appConfig.DBPath = "file:n2n_demo?mode=memory&cache=shared"
+		appConfig.MockMode = true
+		appConfig.SetupWizard = false
+		startDemo()
+	} else if appConfig.MockMode {
+		startMock(nil, mockDefaultDir())
+	}
+	initEncryption(*demo)
 	initDB()
+	if *demo {
+		seedDemoData()
+	} else if appConfig.MockMode {
+		mockServer.SetEdges(mockEdgesFromDB())
+	}
 
 	// 处理密码重置
 	if *resetPassword != "" {
@@ -409,19 +451,56 @@ func main() {
 			return
 		}
 		db.Model(&user).Update("password", string(hash))
-		fmt.Printf("成功: 用户 '%s' 的密码已重置\n", username)
+		invalidateTokens(db, &user)
+		fmt.Printf("成功: 用户 '%s' 的密码已重置，已有的登录会话已失效\n", username)
 		return
 	}
 
-	go startLogAnalyzer()
-	go startIPCacheCleaner()
-	go startLoginCleanupRoutine()
-	n2nMgmt = &utils.MgmtClient{Addr: appConfig.MgmtAddr}
+	reloadIPFilter()
+	if ipFilterBypass {
+		log.Println("[安全提示] 已通过 -bypass-ip-filter 关闭访问 IP 过滤，请在恢复访问后移除该参数")
+	}
+	initCluster()
+	restoreRelaySnapshot()
+	startInstances()
+	runWorker("ip_cache_cleaner", startIPCacheCleaner)
+	runWorker("login_cleanup", startLoginCleanupRoutine)
+	runLeaderWorker("alert_monitor", startAlertMonitor)
+	runLeaderWorker("alert_rules", startAlertRuleEngine)
+	runWorker("snmp_agent", startSNMPAgent)
+	runWorker("syslog_forwarder", startSyslogForwarder)
+	runLeaderWorker("mqtt_publisher", startMQTTPublisher)
+	if appConfig.GRPCListen != "" {
+		// 未配置时不登记，否则健康检查会把直接返回的任务报告为已停止
+		runWorker("grpc_api", startGRPCServer)
+	}
+	runLeaderWorker("community_reconciler", startCommunityReconciler)
+	runLeaderWorker("wan_probe", startWanProbe)
+	runLeaderWorker("duplicate_ip_monitor", startDuplicateIPMonitor)
+	runLeaderWorker("trash_purger", startTrashPurger)
+	runLeaderWorker("integrity_checker", startIntegrityChecker)
+	runLeaderWorker("update_checker", startUpdateChecker)
+	runLeaderWorker("password_rotator", startPasswordRotator)
+	runLeaderWorker("node_log_pruner", startNodeLogPruner)
+	runLeaderWorker("watchdog", startWatchdog)
+	runLeaderWorker("node_expiry_monitor", startNodeExpiryMonitor)
+	runLeaderWorker("availability_tracker", startAvailabilityTracker)
+	runLeaderWorker("stats_sampler", startStatsSampler)
+	runLeaderWorker("relay_snapshot", startRelaySnapshot)
+	if !appConfig.MockMode {
+		runLeaderWorker("public_address_check", startPublicAddressCheck)
+	}
+	if appConfig.MockMode && !*demo {
+		runWorker("mock_edge_sync", startMockEdgeSync)
+	}
 
 	// 安全提示
 	if !appConfig.JWTSecretFromEnv {
 		log.Println("[安全提示] JWT 密钥为自动生成，重启后所有用户需重新登录。建议设置环境变量 N2N_ADMIN_SECRET")
 	}
+	if appConfig.TrustedProxies != "" {
+		log.Printf("[配置] 信任的反向代理: %s", appConfig.TrustedProxies)
+	}
 	if appConfig.CORSOrigins == "" {
 		log.Println("[配置] CORS 未配置，仅允许同源请求。如需跨域访问请设置 N2N_CORS_ORIGINS")
 	}
@@ -439,7 +518,17 @@ func main() {
 
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
+	// 默认不信任任何代理，c.ClientIP() 直接使用连接地址，避免伪造 X-Forwarded-For 绕过登录限制和访问过滤
+	var proxies []string
+	if appConfig.TrustedProxies != "" {
+		proxies = utils.SplitList(appConfig.TrustedProxies)
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		log.Fatalf("Invalid N2N_TRUSTED_PROXIES: %v", err)
+	}
+	r.Use(requestStatsMiddleware())
 	r.Use(gin.Recovery())
+	r.Use(ipFilterMiddleware())
 
 	corsConfig := cors.DefaultConfig()
 	if appConfig.CORSOrigins != "" {
@@ -454,80 +543,88 @@ func main() {
 			return false // 拒绝所有跨域请求
 		}
 	}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "If-Match", "If-None-Match"}
+	corsConfig.ExposeHeaders = []string{"ETag", "X-Supernode-Unreachable"}
 	r.Use(cors.New(corsConfig))
 
-	api := r.Group("/api")
-	{
-		api.GET("/health", func(c *gin.Context) { c.JSON(200, gin.H{"status": "ok", "version": Version}) })
-		api.POST("/login", login)
-		protected := api.Group("/")
-		protected.Use(jwtMiddleware())
-		{
-			protected.GET("/nodes", getNodes)
-			protected.POST("/nodes", createNode)
-			protected.DELETE("/nodes/:id", deleteNode)
-			protected.GET("/nodes/:id/config", getNodeConfig)
-			protected.GET("/stats", getStats)
-			protected.GET("/communities", getCommunities)
-			protected.POST("/communities", createCommunity)
-			protected.DELETE("/communities/:id", deleteCommunity)
-			protected.GET("/settings", getSettings)
-			protected.POST("/settings", saveSettings)
-			protected.GET("/supernode/config", getSupernodeConfig)
-			protected.POST("/supernode/config", saveSupernodeConfig)
-			protected.POST("/supernode/restart", restartSupernode)
-			protected.POST("/tools/exec", execTool)
-			protected.GET("/topology", getTopology)
-			protected.GET("/supernode/logs", streamLogs)
-			protected.GET("/supernode/logs/recent", getRecentLogs)
-			protected.GET("/relays", getActiveRelays)
-			protected.POST("/change-password", changePassword)
-		}
+	base := normalizeBasePath(appConfig.BasePath)
+	r.GET(base+"/livez", livez)
+	r.GET(base+"/readyz", readyz)
+	r.GET(base+"/status", statusPage)
+	r.GET(base+"/invite/:token", invitePage)
+	registerAPI(r.Group(base + "/api"))
+	if appConfig.EnableAPIV2 {
+		v2 := r.Group(base + "/api/v2")
+		v2.Use(envelopeMiddleware())
+		registerAPI(v2)
 	}
 
 	r.NoRoute(func(c *gin.Context) {
 		path := c.Request.URL.Path
+		if base != "" {
+			if path == "/" || path == base {
+				c.Redirect(302, base+"/"); return
+			}
+			if !strings.HasPrefix(path, base+"/") {
+				respondError(c, 404, ErrNotFound, "Not Found"); return
+			}
+			path = strings.TrimPrefix(path, base)
+		}
+		if appConfig.EnableAPIV2 && strings.HasPrefix(path, "/api/v2/") {
+			c.JSON(404, apiEnvelope{Code: ErrNotFound, Message: tr(c, "Not Found"), Data: json.RawMessage("null")}); return
+		}
 		if strings.HasPrefix(path, "/api/") {
-			c.JSON(404, gin.H{"error": "Not Found"}); return
+			respondError(c, 404, ErrNotFound, "Not Found"); return
 		}
 		targetPath := strings.TrimPrefix(path, "/")
 		if targetPath == "" { targetPath = "index.html" }
-		fileBytes, err := content.ReadFile("dist/" + targetPath)
-		if err != nil {
-			index, _ := content.ReadFile("dist/index.html")
-			c.Data(200, "text/html; charset=utf-8", index)
-			return
-		}
-		contentType := "text/plain"
-		if strings.HasSuffix(targetPath, ".html") { contentType = "text/html; charset=utf-8"
-		} else if strings.HasSuffix(targetPath, ".js") { contentType = "application/javascript"
-		} else if strings.HasSuffix(targetPath, ".css") { contentType = "text/css"
-		} else if strings.HasSuffix(targetPath, ".svg") { contentType = "image/svg+xml"
-		} else if strings.HasSuffix(targetPath, ".png") { contentType = "image/png" }
-		c.Data(200, contentType, fileBytes)
+		serveStatic(c, targetPath, base)
 	})
 
-	log.Printf("n2n-admin %s starting on :%s\n", Version, listenPort)
+	log.Printf("n2n-admin %s starting on :%s%s/\n", Version, listenPort, base)
+	serverReady.Store(true)
 	r.Run(":" + listenPort)
 }
 
 func getNodes(c *gin.Context) {
-	var nodes []models.Node; db.Find(&nodes)
-	edges, _ := n2nMgmt.GetEdgeInfo()
-	
+	var nodes []models.Node; scopeNodes(c).Find(&nodes)
+	edges, outages := edgeInfoWithOutages()
+	list := buildNodeList(nodes, scopeEdges(c, edges))
+	markOnlineUnknown(c, list, outages)
+	// ?search= 按空格分隔的多个关键字，需全部匹配，如 "上海 电信"
+	if terms := searchTerms(c.Query("search")); len(terms) > 0 {
+		matched := make([]interface{}, 0)
+		for _, item := range list {
+			if nodeMatchesSearch(item.(gin.H), terms) {
+				matched = append(matched, item)
+			}
+		}
+		list = matched
+	}
+	c.JSON(200, list)
+}
+
+// relaySources 返回当前经 supernode 中转发送数据的 edge MAC
+func relaySources() map[string]bool {
 	relayMutex.Lock()
-	activeRelays := make(map[string]bool)
+	defer relayMutex.Unlock()
+	res := make(map[string]bool)
 	for key := range relayMap {
-		srcMac := strings.Split(key, "->")[0]
-		activeRelays[srcMac] = true
+		res[strings.Split(key, "->")[0]] = true
 	}
-	relayMutex.Unlock()
+	return res
+}
+
+// buildNodeList 合并数据库节点与 supernode 在线信息
+func buildNodeList(nodes []models.Node, edges map[string]utils.EdgeInfo) []interface{} {
+	activeRelays := relaySources()
+	blocked := blockedMacSet()
+	reliability := reliabilityScores(time.Now().Add(-reliabilityWindow))
 
 	res := make([]interface{}, 0)
 	mappedMacs := make(map[string]bool)
 	for _, n := range nodes {
-		m := strings.ToUpper(strings.ReplaceAll(n.MacAddress, ":", ""))
+		m := n.MacAddress
 		info, online := edges[m]
 		var publicIP, locationStr, connType string
 		if online {
@@ -539,11 +636,21 @@ func getNodes(c *gin.Context) {
 				connType = "Relay"
 			}
 		}
-		res = append(res, gin.H{
+		item := gin.H{
 			"id": n.ID, "name": n.Name, "ip_address": n.IPAddress, "mac_address": n.MacAddress, 
-			"community": n.Community, "is_online": online, "is_mapped": true,
+			"community": n.Community, "is_online": online, "is_mapped": true, "tags": n.Tags,
+			"is_enabled": n.IsEnabled, "is_blocked": blocked[m], "config_outdated": n.ConfigOutdated, "has_agent": n.AgentToken != "",
+			"expires_at": n.ExpiresAt, "is_expired": nodeExpired(n), "access": nodeAccessState(n), "pending_approval": n.PendingApproval,
 			"external_ip": publicIP, "location": locationStr, "conn_type": connType,
-		})
+			"last_external_ip": n.LastExternalIP, "last_location": n.LastLocation, "last_conn_type": n.LastConnType,
+			"last_seen": n.LastSeen,
+			"description": n.Description, "owner": n.Owner, "owner_email": n.OwnerEmail,
+			"contact": n.Contact, "site": n.Site, "device_type": n.DeviceType,
+		}
+		if score, ok := reliability[n.ID]; ok {
+			item["reliability"] = score
+		}
+		res = append(res, item)
 		mappedMacs[m] = true
 	}
 	for mac, info := range edges {
@@ -554,7 +661,7 @@ func getNodes(c *gin.Context) {
 			if activeRelays[mac] { connType = "Relay" }
 			res = append(res, gin.H{
 			"id": 0, "name": "新发现节点", "ip_address": info.Internal, "mac_address": mac,
-			"community": "未知", "is_online": true, "is_mapped": false,
+			"community": "未知", "is_online": true, "is_mapped": false, "is_blocked": blocked[mac],
 			"external_ip": publicIP, "location": fmt.Sprintf("%s %s", loc.Country, loc.City), "conn_type": connType,
 		})
 		}
@@ -570,56 +677,63 @@ func getNodes(c *gin.Context) {
 		}
 		return utils.CompareIP(a, b) < 0
 	})
-	c.JSON(200, res)
+	return res
 }
 func login(c *gin.Context) {
 	clientIP := c.ClientIP()
 
 	var p struct {
-		U string `json:"username"`
-		P string `json:"password"`
-	}
-	if err := c.ShouldBindJSON(&p); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid request"})
+		U string `json:"username" binding:"required"`
+		P string `json:"password" binding:"required"`
+		// 验证码：内置算术题提交 captcha_id 与 captcha_answer，Turnstile / hCaptcha 提交 captcha_token
+		CaptchaID     string `json:"captcha_id"`
+		CaptchaAnswer string `json:"captcha_answer"`
+		CaptchaToken  string `json:"captcha_token"`
+	}
+	if !bindJSON(c, &p) {
 		return
 	}
 
 	// 检查 IP 是否被锁定
 	if locked, remaining := checkLoginLock(clientIP); locked {
-		c.JSON(429, gin.H{
-			"error":   fmt.Sprintf("登录尝试次数过多，请 %d 分钟后再试", int(remaining.Minutes())+1),
-			"locked":  true,
-			"seconds": int(remaining.Seconds()),
-		})
+		recordLogin(c, models.User{}, p.U, false, "locked")
+		respondError(c, 429, ErrLoginLocked, trf(c, "Too many login attempts, try again in %d minutes", int(remaining.Minutes())+1),
+			gin.H{"locked": true, "seconds": int(remaining.Seconds())})
 		return
 	}
 
 	// 检查用户名是否被锁定
 	if locked, remaining := checkLoginLock("user:" + p.U); locked {
-		c.JSON(429, gin.H{
-			"error":   fmt.Sprintf("该账户已被临时锁定，请 %d 分钟后再试", int(remaining.Minutes())+1),
-			"locked":  true,
-			"seconds": int(remaining.Seconds()),
-		})
+		recordLogin(c, models.User{}, p.U, false, "locked")
+		respondError(c, 429, ErrLoginLocked, trf(c, "Account temporarily locked, try again in %d minutes", int(remaining.Minutes())+1),
+			gin.H{"locked": true, "seconds": int(remaining.Seconds())})
 		return
 	}
 
+	// 失败次数较多时要求验证码，验证码错误不计入失败次数
+	if captchaRequired(clientIP, p.U) {
+		if !verifyCaptcha(captchaMode(), p.CaptchaID, p.CaptchaAnswer, p.CaptchaToken, clientIP) {
+			recordLogin(c, models.User{}, p.U, false, "captcha")
+			respondError(c, 401, ErrCaptchaRequired, "Please complete the captcha", captchaInfo())
+			return
+		}
+	}
+
 	var user models.User
 	if err := db.Where("username = ?", p.U).First(&user).Error; err != nil {
 		recordLoginFail(clientIP, p.U)
-		c.JSON(401, gin.H{"error": "用户名或密码错误"})
+		recordLogin(c, user, p.U, false, "unknown_user")
+		respondError(c, 401, ErrLoginFailed, "Invalid username or password", loginCaptchaHint(clientIP, p.U))
 		return
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(p.P)); err != nil {
 		locked, remaining := recordLoginFail(clientIP, p.U)
+		recordLogin(c, user, p.U, false, "bad_password")
 		if locked {
-			c.JSON(429, gin.H{
-				"error":   fmt.Sprintf("登录失败次数过多，账户已锁定 %d 分钟", int(remaining.Minutes())),
-				"locked":  true,
-				"seconds": int(remaining.Seconds()),
-			})
+			respondError(c, 429, ErrLoginLocked, trf(c, "Too many failed logins, account locked for %d minutes", int(remaining.Minutes())),
+				gin.H{"locked": true, "seconds": int(remaining.Seconds())})
 		} else {
-			c.JSON(401, gin.H{"error": "用户名或密码错误"})
+			respondError(c, 401, ErrLoginFailed, "Invalid username or password", loginCaptchaHint(clientIP, p.U))
 		}
 		return
 	}
@@ -627,63 +741,47 @@ func login(c *gin.Context) {
 	// 登录成功，清除失败记录
 	clearLoginFail(clientIP, p.U)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": user.Username,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(),
-	})
-	t, err := token.SignedString(jwtSecret)
+	t, err := signUserToken(c, user)
 	if err != nil {
 		log.Printf("Failed to sign JWT token: %v", err)
-		c.JSON(500, gin.H{"error": "Internal server error"})
+		respondError(c, 500, ErrInternal, "Internal server error")
 		return
 	}
-	c.JSON(200, gin.H{"token": t, "user": user})
+	c.JSON(200, gin.H{"token": t, "user": markLoginSuccess(c, user)})
 }
 
 func changePassword(c *gin.Context) {
 	var p struct { Old string `json:"old_password"`; New string `json:"new_password"` }
-	if err := c.ShouldBindJSON(&p); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid request"}); return
+	if !bindJSON(c, &p) {
+		return
 	}
 	if len(p.New) < 6 {
-		c.JSON(400, gin.H{"error": "New password must be at least 6 characters"}); return
+		respondError(c, 400, ErrPasswordTooShort, "New password must be at least 6 characters"); return
 	}
 	u, _ := c.Get("username")
 	var user models.User
 	if err := db.Where("username = ?", u).First(&user).Error; err != nil {
-		c.JSON(404, gin.H{"error": "User not found"}); return
+		respondError(c, 404, ErrUserNotFound, "User not found"); return
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(p.Old)); err != nil {
-		c.JSON(401, gin.H{"error": "Old password incorrect"}); return
+		respondError(c, 401, ErrOldPasswordIncorrect, "Old password incorrect"); return
 	}
 	hash, err := bcrypt.GenerateFromPassword([]byte(p.New), bcrypt.DefaultCost)
 	if err != nil {
 		log.Printf("Failed to hash new password: %v", err)
-		c.JSON(500, gin.H{"error": "Internal server error"}); return
+		respondError(c, 500, ErrInternal, "Internal server error"); return
 	}
+	// 修改密码后之前签发的令牌全部失效（包括当前会话），需重新登录
 	db.Model(&user).Update("password", string(hash))
+	invalidateTokens(db, &user)
 	c.JSON(200, gin.H{"message": "success"})
 }
 
 func getStats(c *gin.Context) {
-	var n, cm int64; db.Model(&models.Node{}).Count(&n); db.Model(&models.Community{}).Count(&cm)
-	macs, _ := n2nMgmt.GetOnlineMacs()
-	c.JSON(200, gin.H{"node_count": n, "community_count": cm, "online_count": len(macs)})
-}
-
-// isValidMac 验证 MAC 地址格式
-func isValidMac(mac string) bool {
-	// 支持 AA:BB:CC:DD:EE:FF 或 AA-BB-CC-DD-EE-FF 或 AABBCCDDEEFF
-	cleaned := strings.ToUpper(strings.ReplaceAll(strings.ReplaceAll(mac, ":", ""), "-", ""))
-	if len(cleaned) != 12 {
-		return false
-	}
-	for _, c := range cleaned {
-		if !((c >= '0' && c <= '9') || (c >= 'A' && c <= 'F')) {
-			return false
-		}
-	}
-	return true
+	var n, cm int64; scopeNodes(c).Model(&models.Node{}).Count(&n); scopeCommunities(c).Model(&models.Community{}).Count(&cm)
+	edges, outages := edgeInfoWithOutages()
+	c.JSON(200, gin.H{"node_count": n, "community_count": cm, "online_count": len(scopeEdges(c, edges)),
+		"supernode_unreachable": len(outages) > 0, "unreachable_instances": outages})
 }
 
 func createNode(c *gin.Context) {
@@ -691,290 +789,550 @@ func createNode(c *gin.Context) {
 		models.Node
 		RouteNet string `json:"route_net"`
 		RouteGw  string `json:"route_gw"`
+		Replace  bool   `json:"replace"` // 为 true 时删除冲突的已有节点
+		// 为 true 时允许路由网段与其他社区网段或节点路由重叠
+		AllowOverlap bool `json:"allow_overlap"`
+		// 未指定时使用社区默认值
+		Compression *bool `json:"compression"`
 	}
-	if err := c.ShouldBindJSON(&p); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !bindJSON(c, &p) {
 		return
 	}
 	n := p.Node
+	n.Tags = normalizeTags(n.Tags)
+	// 认证密钥由面板生成，不接受请求中的值
+	n.AuthUser, n.AuthPublicKey, n.AuthSecret = "", "", ""
 
 	// 验证节点名称
-	if strings.TrimSpace(n.Name) == "" {
-		c.JSON(400, gin.H{"error": "Node name is required"})
+	if err := validateNodeName(n.Name); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return
+	}
+
+	if err := validateExpiry(n.ExpiresAt, nil); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
 		return
 	}
 
 	// 验证社区存在
-	var comm models.Community
-	if err := db.Where("name = ?", n.Community).First(&comm).Error; err != nil {
-		c.JSON(400, gin.H{"error": "Community not found"})
+	comm, err := scopedCommunity(c, n.Community)
+	if err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return
+	}
+	applyCommunityDefaults(&n, comm, p.Compression)
+	if err := validateEncryption(n.Encryption); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return
+	}
+	if err := validateEdgeOptions(n.MTU, n.TapDevice, n.TTL); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return
+	}
+	if n.ExtraArgs, err = normalizeEdgeFlags(n.ExtraArgs); err != nil {
+		respondErr(c, 400, err, ErrInvalidEdgeFlag)
 		return
 	}
 
 	// 验证并处理 MAC 地址
 	if n.MacAddress != "" {
-		if !isValidMac(n.MacAddress) {
-			c.JSON(400, gin.H{"error": "Invalid MAC address format"})
+		if err := validateMacAddress(n.MacAddress); err != nil {
+			respondErr(c, 400, err, ErrInvalidRequest)
+			return
+		}
+		n.MacAddress = utils.NormalizeMac(n.MacAddress)
+		if isMacBlocked(n.MacAddress) {
+			respondError(c, 403, ErrMacBlocked, "MAC address is blocked")
 			return
 		}
-		n.MacAddress = strings.ToUpper(strings.ReplaceAll(strings.ReplaceAll(n.MacAddress, ":", ""), "-", ""))
 	} else {
-		mac, err := utils.GenerateRandomMac()
+		mac, err := utils.RandomMAC()
 		if err != nil {
-			c.JSON(500, gin.H{"error": "Failed to generate MAC address"})
+			respondError(c, 500, ErrInternal, "Failed to generate MAC address")
 			return
 		}
-		n.MacAddress = strings.ToUpper(strings.ReplaceAll(mac, ":", ""))
+		n.MacAddress = mac.String()
 	}
 
 	// 验证并处理 IP 地址
 	if n.IPAddress != "" {
-		if ip := net.ParseIP(n.IPAddress); ip == nil {
-			c.JSON(400, gin.H{"error": "Invalid IP address format"})
+		// 检查 IP 格式及是否在社区范围内
+		if err := validateNodeIP(n.IPAddress, comm); err != nil {
+			respondErr(c, 400, err, ErrInvalidRequest)
 			return
 		}
-		// 检查 IP 是否在社区范围内
-		if comm.Range != "" {
-			if _, ipnet, err := net.ParseCIDR(comm.Range); err == nil {
-				if !ipnet.Contains(net.ParseIP(n.IPAddress)) {
-					c.JSON(400, gin.H{"error": "IP address not in community range"})
-					return
-				}
-			}
-		}
 	} else {
 		// 自动分配 IP
-		if comm.Range != "" {
-			if baseIP, _, err := net.ParseCIDR(comm.Range); err == nil {
-				var nodes []models.Node
-				db.Where("community = ?", n.Community).Find(&nodes)
-				if len(nodes) == 0 {
-					n.IPAddress = utils.NextIP(utils.NextIP(baseIP)).String()
-				} else {
-					// 找出最大的 IP 地址（按数值比较）
-					var maxIP net.IP
-					for _, node := range nodes {
-						ip := net.ParseIP(node.IPAddress)
-						if ip != nil && (maxIP == nil || utils.CompareIP(ip, maxIP) > 0) {
-							maxIP = ip
-						}
-					}
-					if maxIP != nil {
-						n.IPAddress = utils.NextIP(maxIP).String()
-					} else {
-						n.IPAddress = utils.NextIP(utils.NextIP(baseIP)).String()
-					}
-				}
-			}
-		}
+		n.IPAddress = nextNodeIP(comm)
+	}
+
+	// 按社区端口范围校验或分配本地端口
+	if port, err := allocateLocalPort(comm, n.LocalPort, 0); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return
+	} else {
+		n.LocalPort = port
 	}
 
 	// 处理路由配置
 	if p.RouteNet != "" && p.RouteGw != "" {
-		// 验证路由网段格式
-		if _, _, err := net.ParseCIDR(p.RouteNet); err != nil {
-			c.JSON(400, gin.H{"error": "Invalid route network format"})
+		// 验证路由网段与网关
+		if err := validateRoute(p.RouteNet, p.RouteGw, comm); err != nil {
+			respondErr(c, 400, err, ErrInvalidRequest)
 			return
 		}
-		// 验证网关 IP
-		if ip := net.ParseIP(p.RouteGw); ip == nil {
-			c.JSON(400, gin.H{"error": "Invalid route gateway format"})
-			return
+		n.Routing = p.RouteNet + ":" + p.RouteGw
+	}
+	if !checkRouteOverlaps(c, n, p.AllowOverlap) {
+		return
+	}
+
+	// 检查 MAC / IP 冲突，只有明确指定 replace 时才删除已有节点（与创建在同一事务中）
+	conflicts := findNodeConflicts(n.MacAddress, n.IPAddress)
+	if len(conflicts) > 0 && !p.Replace {
+		respondError(c, 409, ErrNodeConflict, "MAC or IP address already used by another node", gin.H{"conflicts": conflicts})
+		return
+	}
+	// 需要审批时以禁用状态创建，并通知管理员
+	n.PendingApproval, n.RequestedBy = nodeApprovalRequired(c), ""
+	n.IsEnabled = !n.PendingApproval
+	if n.PendingApproval {
+		n.RequestedBy = c.GetString("username")
+	}
+	changes, err := provisionNode(&n, conflicts)
+	if err != nil {
+		respondProvisionErr(c, err)
+		return
+	}
+	if n.PendingApproval {
+		notifyPendingNode(n)
+	}
+	c.JSON(200, provisionedResponse(n, changes))
+}
+
+// nextNodeIP 在社区网段内分配下一个 IP（当前最大 IP + 1），社区未设置网段时返回空
+func nextNodeIP(comm models.Community) string {
+	baseIP, _, err := net.ParseCIDR(comm.Range)
+	if comm.Range == "" || err != nil {
+		return ""
+	}
+	var nodes []models.Node
+	db.Where("community = ?", comm.Name).Find(&nodes)
+	// 找出最大的 IP 地址（按数值比较）
+	var maxIP net.IP
+	for _, node := range nodes {
+		ip := net.ParseIP(node.IPAddress)
+		if ip != nil && (maxIP == nil || utils.CompareIP(ip, maxIP) > 0) {
+			maxIP = ip
+		}
+	}
+	if maxIP != nil {
+		return utils.NextIP(maxIP).String()
+	}
+	return utils.NextIP(utils.NextIP(baseIP)).String()
+}
+
+// applyNodeUpdate 将请求中的字段合并到已有节点并校验，未出现的字段保持原值
+func applyNodeUpdate(c *gin.Context) (models.Node, bool) {
+	var existing models.Node
+	if err := scopeNodes(c).First(&existing, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return existing, false
+	}
+	p := struct {
+		models.Node
+		RouteNet     string `json:"route_net"`
+		RouteGw      string `json:"route_gw"`
+		AllowOverlap bool   `json:"allow_overlap"`
+	}{Node: existing}
+	if !bindJSON(c, &p) {
+		return existing, false
+	}
+	n := p.Node
+	// 不允许通过更新接口修改的字段
+	n.ID, n.CreatedAt, n.LastSeen, n.DeletedAt = existing.ID, existing.CreatedAt, existing.LastSeen, existing.DeletedAt
+	n.IssuedConfig, n.ConfigDiff, n.ConfigOutdated = existing.IssuedConfig, existing.ConfigDiff, existing.ConfigOutdated
+	n.PendingApproval, n.RequestedBy = existing.PendingApproval, existing.RequestedBy
+	// 认证用户名与公钥只能通过 /auth-key 生成，写入 community.list
+	n.AuthUser, n.AuthPublicKey = existing.AuthUser, existing.AuthPublicKey
+	// 启用状态只能通过 /enable、/disable 与审批修改，它们会检查到期、封禁与待审批状态
+	n.IsEnabled = existing.IsEnabled
+	n.Tags = normalizeTags(n.Tags)
+
+	if err := validateNodeName(n.Name); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return existing, false
+	}
+	if err := validateExpiry(n.ExpiresAt, existing.ExpiresAt); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return existing, false
+	}
+	comm, err := scopedCommunity(c, n.Community)
+	if err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return existing, false
+	}
+	if err := validateMacAddress(n.MacAddress); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return existing, false
+	}
+	if err := validateEdgeOptions(n.MTU, n.TapDevice, n.TTL); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return existing, false
+	}
+	if n.ExtraArgs, err = normalizeEdgeFlags(n.ExtraArgs); err != nil {
+		respondErr(c, 400, err, ErrInvalidEdgeFlag)
+		return existing, false
+	}
+	n.MacAddress = utils.NormalizeMac(n.MacAddress)
+	if n.MacAddress != existing.MacAddress && isMacBlocked(n.MacAddress) {
+		respondError(c, 403, ErrMacBlocked, "MAC address is blocked")
+		return existing, false
+	}
+	if err := validateNodeIP(n.IPAddress, comm); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return existing, false
+	}
+	port, err := allocateLocalPort(comm, n.LocalPort, n.ID)
+	if err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return existing, false
+	}
+	n.LocalPort = port
+	if p.RouteNet != "" && p.RouteGw != "" {
+		if err := validateRoute(p.RouteNet, p.RouteGw, comm); err != nil {
+			respondErr(c, 400, err, ErrInvalidRequest)
+			return existing, false
 		}
 		n.Routing = p.RouteNet + ":" + p.RouteGw
 	}
+	// 路由未变化时不再检查，以免之前允许的重叠阻止其他字段的修改
+	if n.Routing != existing.Routing && !checkRouteOverlaps(c, n, p.AllowOverlap) {
+		return existing, false
+	}
+	for _, cf := range findNodeConflicts(n.MacAddress, n.IPAddress) {
+		if cf.Node.ID != n.ID {
+			respondError(c, 409, ErrNodeConflict, "MAC or IP address already used by another node", gin.H{"conflicts": []nodeConflict{cf}})
+			return existing, false
+		}
+	}
+	return n, true
+}
 
-	db.Unscoped().Where("mac_address = ? OR ip_address = ?", n.MacAddress, n.IPAddress).Delete(&models.Node{})
-	if err := db.Create(&n).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create node"})
+// updateNode 修改节点，返回新旧配置差异；已下发过配置的节点在确认部署前标记为配置过期
+func updateNode(c *gin.Context) {
+	n, ok := applyNodeUpdate(c)
+	if !ok {
 		return
 	}
-	c.JSON(200, n)
+	var old models.Node
+	db.First(&old, n.ID)
+	refreshConfigState(&n)
+	if err := db.Save(&n).Error; err != nil {
+		respondError(c, 500, ErrInternal, "Failed to update node")
+		return
+	}
+	// 社区、MAC 或到期时间变化后重新生成 community.list，与 node_bulk.go 一样同步失败时附带 warning
+	res := struct {
+		models.Node
+		Warning string `json:"warning,omitempty"`
+	}{Node: n}
+	if authEntryChanged(old, n) {
+		if err := syncCommunityList(); err != nil {
+			res.Warning = tr(c, "Failed to write community.list") + ": " + err.Error()
+		}
+	}
+	c.JSON(200, res)
+}
+
+// allocateLocalPort 在社区端口范围内校验指定端口或分配最小的空闲端口，excludeID 为当前节点（更新时）
+func allocateLocalPort(comm models.Community, requested int, excludeID uint) (int, error) {
+	if comm.PortStart == 0 || comm.PortEnd == 0 {
+		if requested < 0 || requested > 65535 {
+			return 0, newAPIError(ErrInvalidPort, "Invalid local port")
+		}
+		return requested, nil
+	}
+	var nodes []models.Node
+	db.Where("community = ? AND id <> ?", comm.Name, excludeID).Find(&nodes)
+	used := make(map[int]bool)
+	for _, node := range nodes {
+		used[node.LocalPort] = true
+	}
+	if requested != 0 {
+		if requested < comm.PortStart || requested > comm.PortEnd {
+			return 0, newAPIErrorf(ErrInvalidPort, "Local port not in community port range %d-%d", comm.PortStart, comm.PortEnd)
+		}
+		if used[requested] {
+			return 0, newAPIErrorf(ErrPortInUse, "Local port %d already in use", requested)
+		}
+		return requested, nil
+	}
+	for port := comm.PortStart; port <= comm.PortEnd; port++ {
+		if !used[port] {
+			return port, nil
+		}
+	}
+	return 0, newAPIErrorf(ErrPortRangeExhausted, "Community port range %d-%d exhausted", comm.PortStart, comm.PortEnd)
 }
 
 func deleteNode(c *gin.Context) {
-	db.Delete(&models.Node{}, c.Param("id")); c.JSON(200, gin.H{"message": "deleted"})
+	if scopeNodes(c).Delete(&models.Node{}, c.Param("id")).RowsAffected == 0 {
+		respondError(c, 404, ErrNodeNotFound, "Node not found"); return
+	}
+	// 已删除节点的认证公钥不能再留在 community.list 中
+	res := gin.H{"message": "deleted"}
+	syncAfterAccessChange(c, res)
+	c.JSON(200, res)
 }
 
 func getNodeConfig(c *gin.Context) {
-	var n models.Node; db.First(&n, c.Param("id"))
-	var comm models.Community; db.Where("name = ?", n.Community).First(&comm)
-	password := comm.Password; if password == "" { password = "password" }
-	var s models.Setting; db.Where("key = ?", "supernode_host").First(&s)
-	params := utils.ConfigParams{
-		Name: n.Name, IP: n.IPAddress, Community: n.Community, Password: password, Supernode: s.Value, Mac: n.MacAddress,
-		Encryption: n.Encryption, Compression: n.Compression, Routing: n.Routing, LocalPort: n.LocalPort,
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found"); return
+	}
+	if nodeExpired(n) {
+		respondError(c, 403, ErrNodeExpired, "Node access has expired"); return
+	}
+	if n.PendingApproval {
+		respondError(c, 403, ErrNodePending, "Node is pending approval"); return
+	}
+	if !n.IsEnabled {
+		respondError(c, 403, ErrNodeDisabled, "Node is disabled"); return
 	}
-	c.JSON(200, gin.H{"conf": utils.GenerateConfFile(params)})
+	conf := renderNodeConfig(n)
+	if n.IssuedConfig == "" {
+		// 首次下发时记录基线，之后的修改与之对比
+		db.Model(&n).Update("issued_config", conf)
+	}
+	c.JSON(200, gin.H{"conf": conf, "config_outdated": n.ConfigOutdated, "diff": n.ConfigDiff, "pending": pendingNodeConfig(n)})
 }
 
 func getCommunities(c *gin.Context) {
-	var comms []models.Community; db.Find(&comms); c.JSON(200, comms)
+	var comms []models.Community; scopeCommunities(c).Find(&comms); c.JSON(200, comms)
 }
 
-func syncCommunityList() {
+// communityNamesFor 返回属于指定实例的社区名称
+func communityNamesFor(instanceID uint) []string {
 	var comms []models.Community; db.Find(&comms)
 	names := make([]string, 0)
-	for _, c := range comms { names = append(names, c.Name) }
-	utils.WriteCommunityList("/etc/n2n/community.list", names)
+	for _, c := range comms {
+		if communityInstanceID(c) == instanceID { names = append(names, c.Name) }
+	}
+	return names
+}
+
+// syncCommunityList 将数据库中的社区（及启用用户认证社区的用户公钥）写入各 supernode 实例的 community.list
+func syncCommunityList() error {
+	var errs []error
+	for _, rt := range listRuntimes() {
+		if err := utils.WriteCommunityList(rt.instance.CommunityListPath, communityListLines(db, rt.instance.ID)); err != nil {
+			log.Printf("Failed to write community list for %s: %v", rt.instance.Name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", rt.instance.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		// 写入失败时立即校对，偏差会被告警并记录在事件中
+		requestCommunityReconcile()
+	}
+	return errors.Join(errs...)
 }
 
 func createCommunity(c *gin.Context) {
-	var cm models.Community
-	if err := c.ShouldBindJSON(&cm); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid request"})
+	var p struct {
+		models.Community
+		AllowOverlap bool `json:"allow_overlap"` // 为 true 时允许网段与其他社区网段或节点路由重叠
+	}
+	if !bindJSON(c, &p) {
 		return
 	}
+	cm := p.Community
 	// 验证社区名称
 	if strings.TrimSpace(cm.Name) == "" {
-		c.JSON(400, gin.H{"error": "Community name is required"})
+		respondError(c, 400, ErrCommunityNameRequired, "Community name is required")
 		return
 	}
 	// 验证 CIDR 格式
 	if cm.Range != "" {
-		if _, _, err := net.ParseCIDR(cm.Range); err != nil {
-			c.JSON(400, gin.H{"error": "Invalid CIDR format"})
+		if err := validateCIDR(cm.Range); err != nil {
+			respondErr(c, 400, err, ErrInvalidRequest)
+			return
+		}
+		_, ipnet, _ := net.ParseCIDR(cm.Range)
+		if overlaps := findSubnetOverlaps(ipnet, "", "", 0, 0); len(overlaps) > 0 && !p.AllowOverlap {
+			respondError(c, 409, ErrSubnetOverlap, "Range overlaps existing subnets", gin.H{"overlaps": overlaps})
 			return
 		}
 	}
+	// 验证所属 supernode 实例
+	if cm.SupernodeID != 0 && runtimeFor(cm.SupernodeID) == nil {
+		respondError(c, 400, ErrInstanceNotFound, "Supernode instance not found")
+		return
+	}
+	// 验证端口范围及默认 edge 选项
+	if err := defaultsOf(cm).validate(); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return
+	}
 	// 验证密码长度
 	if len(cm.Password) < 4 {
-		c.JSON(400, gin.H{"error": "Password must be at least 4 characters"})
+		respondError(c, 400, ErrPasswordTooShort, "Password must be at least 4 characters")
 		return
 	}
 	// 检查重复
 	var existing models.Community
-	if err := db.Where("name = ?", cm.Name).First(&existing).Error; err == nil {
-		c.JSON(400, gin.H{"error": "Community already exists"})
+	if err := db.Unscoped().Where("name = ?", cm.Name).First(&existing).Error; err == nil {
+		if existing.DeletedAt.Valid {
+			respondError(c, 409, ErrCommunityInTrash, "Community with this name is in the trash, restore or purge it first", gin.H{"id": existing.ID})
+			return
+		}
+		respondError(c, 400, ErrCommunityExists, "Community already exists")
 		return
 	}
 	if err := db.Create(&cm).Error; err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create community"})
+		respondError(c, 500, ErrInternal, "Failed to create community")
+		return
+	}
+	if err := syncCommunityList(); err != nil {
+		// 社区已创建但未写入 community.list，edge 将无法加入，需明确提示
+		c.JSON(200, struct {
+			models.Community
+			Warning string `json:"warning"`
+		}{cm, tr(c, "Failed to write community.list") + ": " + err.Error()})
 		return
 	}
-	syncCommunityList()
 	c.JSON(200, cm)
 }
 
 func deleteCommunity(c *gin.Context) {
-	db.Delete(&models.Community{}, c.Param("id")); syncCommunityList(); c.JSON(200, gin.H{"message": "deleted"})
+	db.Delete(&models.Community{}, c.Param("id"))
+	if err := syncCommunityList(); err != nil {
+		c.JSON(200, gin.H{"message": "deleted", "warning": tr(c, "Failed to write community.list") + ": " + err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "deleted"})
+}
+
+const maskedSecret = "********"
+
+// getSettingValue 读取设置项，不存在或为空时返回默认值
+func getSettingValue(key, defaultValue string) string {
+	var s models.Setting
+	if err := db.Where("key = ?", key).Limit(1).Find(&s).Error; err != nil || s.Value == "" {
+		return defaultValue
+	}
+	v, err := models.DecryptValue(s.Value)
+	if err != nil {
+		log.Printf("Failed to decrypt setting %s: %v", key, err)
+		return defaultValue
+	}
+	return v
 }
 
 func getSettings(c *gin.Context) {
 	var s []models.Setting; db.Find(&s)
 	res := make(map[string]string)
-	for _, x := range s { res[x.Key] = x.Value }
+	for _, x := range s {
+		// 敏感设置项（schema 中 Secret=true）脱敏返回
+		if d, ok := lookupSettingDef(x.Key); ok && d.Secret && x.Value != "" {
+			res[x.Key] = maskedSecret
+			continue
+		}
+		res[x.Key] = x.Value
+	}
 	c.JSON(200, res)
 }
 
 func saveSettings(c *gin.Context) {
-	var p map[string]string; c.ShouldBindJSON(&p)
-	for k, v := range p { db.Where("key = ?", k).Assign(models.Setting{Value: v}).FirstOrCreate(&models.Setting{Key: k}) }
+	var p map[string]string
+	if !bindJSON(c, &p) {
+		return
+	}
+	p, err := validateSettings(p)
+	if err != nil {
+		respondErr(c, 400, err, ErrInvalidSetting); return
+	}
+	if err := validateIPFilterSettings(c, p); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest); return
+	}
+	for k, v := range p {
+		db.Where("key = ?", k).Assign(models.Setting{Value: encryptSettingValue(k, v)}).FirstOrCreate(&models.Setting{Key: k})
+	}
+	reloadIPFilter()
+	// supernode 地址写入每个节点的配置，修改后重新计算已下发配置的差异
+	_, primary := p["supernode_host"]
+	if _, backups := p["supernode_backup_hosts"]; primary || backups {
+		refreshAllConfigs()
+	}
 	c.JSON(200, gin.H{"message": "saved"})
 }
 
 func getSupernodeConfig(c *gin.Context) {
-	cfg, _ := utils.ReadSupernodeConfig("/etc/n2n/supernode.conf"); c.JSON(200, cfg)
+	rt, ok := instanceParam(c); if !ok { return }
+	cfg, _ := utils.ReadSupernodeConfig(rt.instance.ConfigPath); c.JSON(200, cfg)
 }
 
+// saveSupernodeConfig 合并并写入 supernode.conf，校验有错误时拒绝写入，?force=true 跳过（见 supernode_config_check.go）
 func saveSupernodeConfig(c *gin.Context) {
-	var n map[string]string; c.ShouldBindJSON(&n)
-	curr, _ := utils.ReadSupernodeConfig("/etc/n2n/supernode.conf")
-	if curr == nil { curr = make(map[string]string) }
-	for k, v := range n { curr[k] = v }
-	curr["f"] = ""; curr["v"] = ""; utils.WriteSupernodeConfig("/etc/n2n/supernode.conf", curr)
-	c.JSON(200, gin.H{"message": "saved"})
-}
-
-func restartSupernode(c *gin.Context) {
-	utils.RunCommand("systemctl", "restart", "supernode"); c.JSON(200, gin.H{"message": "restarted"})
-}
-
-// isValidTarget 验证目标是否为有效的 IP 地址或域名
-func isValidTarget(target string) bool {
-	if target == "" {
-		return false
-	}
-	// 检查是否为有效 IP
-	if ip := net.ParseIP(target); ip != nil {
-		return true
-	}
-	// 检查是否为有效域名（只允许字母、数字、点和连字符）
-	domainRegex := regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
-	return domainRegex.MatchString(target) && len(target) <= 253
-}
-
-func execTool(c *gin.Context) {
-	// 检查是否禁用网络工具
-	if appConfig.DisableNetTools {
-		c.JSON(403, gin.H{"error": "网络诊断工具已被管理员禁用"})
+	rt, ok := instanceParam(c); if !ok { return }
+	var n map[string]string
+	if !bindJSON(c, &n) { return }
+	curr, _, err := mergeSupernodeConfig(rt.instance, n)
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Failed to read supernode.conf", gin.H{"detail": err.Error()})
 		return
 	}
-	var p struct { Command string `json:"command"`; Target string `json:"target"` }
-	if err := c.ShouldBindJSON(&p); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid request"}); return
-	}
-	// 验证命令类型
-	if p.Command != "ping" && p.Command != "traceroute" {
-		c.JSON(400, gin.H{"error": "Invalid command"}); return
-	}
-	// 验证目标地址
-	if !isValidTarget(p.Target) {
-		c.JSON(400, gin.H{"error": "Invalid target address"}); return
-	}
-	var out string
-	var err error
-	if p.Command == "ping" {
-		out, err = utils.RunCommand("ping", "-c", "4", "-W", "2", p.Target)
-	} else {
-		out, err = utils.RunCommand("traceroute", "-m", "10", "-n", p.Target)
+	problems := checkSupernodeConfig(rt.instance, curr)
+	force := c.Query("force") == "true"
+	if problems.hasErrors() && !force {
+		respondError(c, 400, ErrSupernodeConfigInvalid, "Supernode config is invalid", gin.H{"problems": problems})
+		return
 	}
-	if err != nil {
-		c.JSON(200, gin.H{"output": out, "error": err.Error()})
+	if err := utils.WriteSupernodeConfig(rt.instance.ConfigPath, curr); err != nil {
+		respondError(c, 500, ErrInternal, "Failed to write supernode.conf", gin.H{"detail": err.Error()})
 		return
 	}
-	c.JSON(200, gin.H{"output": out})
+	if problems.hasErrors() {
+		recordAudit(c, "supernode.config_forced", rt.instance.Name, fmt.Sprintf("%d problems", len(problems)))
+	}
+	c.JSON(200, gin.H{"message": "saved", "problems": problems})
 }
 
+func restartSupernode(c *gin.Context) {
+	rt, ok := instanceParam(c); if !ok { return }
+	host.RestartService(rt.instance.Unit); forgetSupernodeVersion(rt.instance.ID); c.JSON(200, gin.H{"message": "restarted"})
+}
+
+
+
 func getTopology(c *gin.Context) {
-	var nodes []models.Node; db.Find(&nodes)
-	macs, _ := n2nMgmt.GetOnlineMacs()
+	var nodes []models.Node; scopeNodes(c).Find(&nodes)
+	edges, _ := allEdgeInfo()
+	c.JSON(200, buildTopology(nodes, scopeEdges(c, edges)))
+}
+
+// buildTopology 生成拓扑图的点和边
+func buildTopology(nodes []models.Node, macs map[string]utils.EdgeInfo) gin.H {
 	vNodes := []interface{}{gin.H{"id": "supernode", "label": "Supernode", "group": "supernode"}}
 	vEdges := []interface{}{}
 	for _, n := range nodes {
-		m := strings.ToUpper(strings.ReplaceAll(n.MacAddress, ":", "")); group := "offline"
+		m := n.MacAddress; group := "offline"
 		if _, online := macs[m]; online { group = "online"; vEdges = append(vEdges, gin.H{"from": "supernode", "to": m}) }
 		vNodes = append(vNodes, gin.H{"id": m, "label": n.Name, "group": group})
 	}
-	c.JSON(200, gin.H{"nodes": vNodes, "edges": vEdges})
+	return gin.H{"nodes": vNodes, "edges": vEdges}
 }
 
-func streamLogs(c *gin.Context) {
-	c.Header("Content-Type", "text/event-stream"); c.Header("Cache-Control", "no-cache"); c.Header("Connection", "keep-alive")
-	cmd := exec.Command("journalctl", "-u", "supernode", "-n", "100", "-f")
-	stdout, _ := cmd.StdoutPipe(); cmd.Start(); defer cmd.Process.Kill()
-	reader := bufio.NewReader(stdout)
-	for {
-		line, err := reader.ReadString('\n'); if err != nil { break }
-		fmt.Fprintf(c.Writer, "data: %s\n\n", strings.TrimSpace(line)); c.Writer.Flush()
-	}
+func getActiveRelays(c *gin.Context) {
+	c.JSON(200, collectActiveRelays())
 }
 
-func getActiveRelays(c *gin.Context) {
+// collectActiveRelays 返回 60 秒内活跃的中转连接副本，并清理过期记录
+func collectActiveRelays() []RelayEvent {
 	relayMutex.Lock(); defer relayMutex.Unlock()
-	active := make([]*RelayEvent, 0); now := time.Now()
+	active := make([]RelayEvent, 0); now := time.Now()
 	for key, ev := range relayMap {
-		if now.Sub(ev.LastActive) < 60*time.Second { active = append(active, ev) } else { delete(relayMap, key) }
+		if now.Sub(ev.LastActive) < relayActiveWindow { active = append(active, *ev) } else { delete(relayMap, key) }
 	}
-	c.JSON(200, active)
+	return active
 }
-
-func getRecentLogs(c *gin.Context) {
-	out, err := exec.Command("journalctl", "-u", "supernode", "-n", "100", "--no-pager").Output()
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to read logs"})
-		return
-	}
-	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
-	c.JSON(200, gin.H{"logs": lines})
-}
\ No newline at end of file
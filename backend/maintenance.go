@@ -0,0 +1,99 @@
+package main
+
+import (
+	"n2n_ui/backend/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 只读维护模式：管理员通过 PUT /api/admin/maintenance 开启后，除 maintenanceAllowed 中的请求外，
+// 所有修改数据的请求（非 GET / HEAD / OPTIONS）返回 503 (MAINTENANCE_MODE) 并附带维护说明，
+// 用于备份、迁移期间，或临时交给他人只读访问。状态保存在设置 maintenance_mode / maintenance_message 中，
+// 多个实例共享数据库时同时生效；后台任务不受影响。GET /api/maintenance 供前端显示横幅。
+
+// maintenanceAllowed 维护模式下仍允许的请求：登录、会话管理、只读的检查与诊断，以及关闭维护模式本身
+var maintenanceAllowed = map[string]bool{
+	"POST /login":                     true,
+	"POST /refresh":                   true,
+	"DELETE /me/sessions/:id":         true,
+	"POST /validate":                  true,
+	"POST /nodes/:id/probe":           true,
+	"POST /nodes/:id/config/preview":  true,
+	"POST /nodes/:id/route-check":     true,
+	"POST /supernode/config/validate": true,
+	"POST /tools/exec":                true,
+	"POST /tools/jobs":                true,
+	"DELETE /tools/jobs/:id":          true,
+	"PUT /admin/maintenance":          true,
+}
+
+// maintenanceState 当前的维护模式状态
+func maintenanceState() (bool, string) {
+	on, _ := strconv.ParseBool(getSettingValue("maintenance_mode", "false"))
+	return on, getSettingValue("maintenance_message", "")
+}
+
+// maintenanceMiddleware 维护模式下拒绝修改数据的请求，prefix 为 API 路由组的路径前缀
+func maintenanceMiddleware(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if maintenanceAllowed[c.Request.Method+" "+strings.TrimPrefix(c.FullPath(), prefix)] {
+			c.Next()
+			return
+		}
+		if on, msg := maintenanceState(); on {
+			respondError(c, 503, ErrMaintenanceMode, "The panel is in read-only maintenance mode", gin.H{"maintenance_message": msg})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// getMaintenance 维护模式状态，所有登录用户可查询以显示横幅
+func getMaintenance(c *gin.Context) {
+	on, msg := maintenanceState()
+	c.JSON(200, gin.H{"enabled": on, "message": msg})
+}
+
+// setMaintenance 开启或关闭维护模式，message 为显示给用户的说明
+func setMaintenance(c *gin.Context) {
+	var p struct {
+		Enabled bool    `json:"enabled"`
+		Message *string `json:"message" binding:"omitempty,max=500"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	values := map[string]string{"maintenance_mode": strconv.FormatBool(p.Enabled)}
+	if p.Message != nil {
+		values["maintenance_message"] = strings.TrimSpace(*p.Message)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for k, v := range values {
+			if err := tx.Where("key = ?", k).Assign(models.Setting{Value: encryptSettingValue(k, v)}).FirstOrCreate(&models.Setting{Key: k}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	on, msg := maintenanceState()
+	action := "maintenance.disable"
+	if on {
+		action = "maintenance.enable"
+	}
+	recordAudit(c, action, "", msg)
+	c.JSON(200, gin.H{"enabled": on, "message": msg})
+}
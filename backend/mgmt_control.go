@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"n2n_ui/backend/utils"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// packetStatsBaseline 统计"重置"基线，supernode 本身不支持清零，面板记录基线后返回差值
+type packetStatsBaseline struct {
+	rows    map[string]utils.MgmtRow // 按 row 中的 type 字段索引
+	resetAt time.Time
+}
+
+var (
+	statsBaselines = make(map[uint]*packetStatsBaseline)
+	baselineMutex  sync.Mutex
+)
+
+// respondMgmtError 区分管理端口不可达与命令不受支持
+func respondMgmtError(c *gin.Context, err error) {
+	if errors.Is(err, utils.ErrUnsupported) {
+		respondError(c, 501, ErrMgmtUnsupported, err.Error())
+		return
+	}
+	respondError(c, 502, ErrMgmtUnreachable, err.Error())
+}
+
+func getMgmtVerbosity(c *gin.Context) {
+	rt, ok := instanceParam(c)
+	if !ok {
+		return
+	}
+	level, err := rt.client.GetVerbosity()
+	if err != nil {
+		respondMgmtError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"level": level})
+}
+
+func setMgmtVerbosity(c *gin.Context) {
+	rt, ok := instanceParam(c)
+	if !ok {
+		return
+	}
+	var p struct {
+		Level *int `json:"level" binding:"required,min=0,max=5"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	if err := rt.client.SetVerbosity(*p.Level); err != nil {
+		respondMgmtError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "success", "level": *p.Level})
+}
+
+func reloadMgmtCommunities(c *gin.Context) {
+	rt, ok := instanceParam(c)
+	if !ok {
+		return
+	}
+	if err := rt.client.ReloadCommunities(); err != nil {
+		respondMgmtError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"message": "reloaded"})
+}
+
+func rowKey(row utils.MgmtRow) string {
+	if t, ok := row["type"].(string); ok {
+		return t
+	}
+	return ""
+}
+
+func getMgmtPacketStats(c *gin.Context) {
+	rt, ok := instanceParam(c)
+	if !ok {
+		return
+	}
+	rows, err := rt.client.PacketStats()
+	if err != nil {
+		respondMgmtError(c, err)
+		return
+	}
+
+	baselineMutex.Lock()
+	base := statsBaselines[rt.instance.ID]
+	baselineMutex.Unlock()
+	if base == nil || c.Query("raw") == "true" {
+		c.JSON(200, gin.H{"stats": rows})
+		return
+	}
+
+	// 计数器减去基线；supernode 重启导致计数回绕时直接返回当前值
+	res := make([]utils.MgmtRow, 0, len(rows))
+	for _, row := range rows {
+		out := make(utils.MgmtRow, len(row))
+		prev := base.rows[rowKey(row)]
+		for k, v := range row {
+			cur, isNum := v.(float64)
+			old, hasOld := prev[k].(float64)
+			if isNum && hasOld && cur >= old {
+				out[k] = cur - old
+			} else {
+				out[k] = v
+			}
+		}
+		res = append(res, out)
+	}
+	c.JSON(200, gin.H{"stats": res, "since": base.resetAt})
+}
+
+func resetMgmtPacketStats(c *gin.Context) {
+	rt, ok := instanceParam(c)
+	if !ok {
+		return
+	}
+	rows, err := rt.client.PacketStats()
+	if err != nil {
+		respondMgmtError(c, err)
+		return
+	}
+	base := &packetStatsBaseline{rows: make(map[string]utils.MgmtRow), resetAt: time.Now()}
+	for _, row := range rows {
+		base.rows[rowKey(row)] = row
+	}
+	baselineMutex.Lock()
+	statsBaselines[rt.instance.ID] = base
+	baselineMutex.Unlock()
+	c.JSON(200, gin.H{"message": "reset", "since": base.resetAt})
+}
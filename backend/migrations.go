@@ -0,0 +1,291 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// 数据库版本化迁移：启动时按 ID 顺序执行尚未执行的迁移，已执行的迁移记录在 schema_migrations 表中。
+// 迁移分两类，合并后按 ID 排序：
+//   - Go 迁移（goMigrations）：新增表或字段时追加一条 tx.AutoMigrate(&models.X{})，需要读写数据的迁移也写在这里
+//   - SQL 迁移（migrations/<ID>.up.sql，可选的 <ID>.down.sql）：重命名列、回填数据、调整索引等 AutoMigrate 无法安全处理的变更
+// ID 以日期和序号开头（如 202610150002_xxx）。已发布的迁移不能修改，只能追加新的迁移。
+// 设置 N2N_AUTO_MIGRATE=false 时启动不执行迁移，存在未执行的迁移时拒绝启动，需先运行 -migrate up。
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const migrationTable = "schema_migrations"
+
+// schemaModels 基线迁移创建的表，即引入版本化迁移前由 AutoMigrate 维护的全部模型
+func schemaModels() []interface{} {
+	return []interface{}{
+		&models.Node{}, &models.Community{}, &models.Setting{}, &models.User{}, &models.SegmentRule{}, &models.Notification{},
+		&models.SupernodeInstance{}, &models.BlockedMac{}, &models.Announcement{}, &models.AnnouncementDelivery{},
+		&models.LoginHistory{}, &models.NodeLog{}, &models.SupernodeIncident{}, &models.UserCommunity{},
+		&models.NodeAvailability{}, &models.SpeedTest{}, &models.UserPreference{}, &models.UserSession{}, &models.AuditLog{},
+		&models.RelayState{}, &models.AlertRule{}, &models.AlertEvent{}, &models.Invitation{}, &models.NodeRegistration{},
+	}
+}
+
+var goMigrations = []*gormigrate.Migration{
+	{
+		// 基线：新数据库创建全部表，已有数据库只补充缺少的列和索引
+		ID:      "202610150001_baseline",
+		Migrate: func(tx *gorm.DB) error { return tx.AutoMigrate(schemaModels()...) },
+	},
+	{
+		// 202610150002 只处理了冒号和连字符，这里按 utils.NormalizeMac/NormalizeIP 统一剩余的格式（点分隔、空白、IP 表示）
+		ID:      "202610150003_canonical_addresses",
+		Migrate: normalizeStoredAddresses,
+	},
+	{
+		ID:      "202610150004_community_password_rotation",
+		Migrate: func(tx *gorm.DB) error { return tx.AutoMigrate(&models.Community{}) },
+	},
+	{
+		ID:      "202610150005_stat_samples",
+		Migrate: func(tx *gorm.DB) error { return tx.AutoMigrate(&models.StatSample{}) },
+	},
+	{
+		ID:      "202610150006_cluster_leases",
+		Migrate: func(tx *gorm.DB) error { return tx.AutoMigrate(&models.ClusterLease{}) },
+	},
+}
+
+// normalizeStoredAddresses 将节点（包括回收站）和封禁列表中的 MAC、IP 改为规范格式。
+// 规范化后与其他记录重复的值保持原样并记录日志，由数据完整性检查提示管理员处理
+func normalizeStoredAddresses(tx *gorm.DB) error {
+	var nodes []models.Node
+	if err := tx.Unscoped().Find(&nodes).Error; err != nil {
+		return err
+	}
+	macs := make(map[string]bool, len(nodes))
+	ips := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		macs[n.MacAddress], ips[n.IPAddress] = true, true
+	}
+	for _, n := range nodes {
+		updates := make(map[string]interface{})
+		if m := utils.NormalizeMac(n.MacAddress); m != n.MacAddress {
+			if macs[m] {
+				log.Printf("Migration: node %d MAC %q conflicts with another node after normalization, left unchanged", n.ID, n.MacAddress)
+			} else {
+				updates["mac_address"], macs[m] = m, true
+			}
+		}
+		if ip := utils.NormalizeIP(n.IPAddress); ip != n.IPAddress {
+			if ips[ip] {
+				log.Printf("Migration: node %d IP %q conflicts with another node after normalization, left unchanged", n.ID, n.IPAddress)
+			} else {
+				updates["ip_address"], ips[ip] = ip, true
+			}
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		if err := tx.Unscoped().Model(&models.Node{}).Where("id = ?", n.ID).UpdateColumns(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	var blocked []models.BlockedMac
+	if err := tx.Find(&blocked).Error; err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(blocked))
+	for _, b := range blocked {
+		seen[b.MacAddress] = true
+	}
+	for _, b := range blocked {
+		m := utils.NormalizeMac(b.MacAddress)
+		if m == b.MacAddress {
+			continue
+		}
+		// 已有相同的封禁记录时删除重复的一条
+		if seen[m] {
+			if err := tx.Delete(&models.BlockedMac{}, b.ID).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		seen[m] = true
+		if err := tx.Model(&models.BlockedMac{}).Where("id = ?", b.ID).UpdateColumn("mac_address", m).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqlMigrations 读取内嵌的 SQL 迁移文件
+func sqlMigrations() ([]*gormigrate.Migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*gormigrate.Migration)
+	var ids []string
+	for _, f := range files {
+		name := path.Base(f)
+		id, dir, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (dir != "up" && dir != "down") {
+			return nil, fmt.Errorf("invalid migration file name %s", name)
+		}
+		b, err := migrationFiles.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		stmt := string(b)
+		m := byID[id]
+		if m == nil {
+			m = &gormigrate.Migration{ID: id}
+			byID[id] = m
+			ids = append(ids, id)
+		}
+		if dir == "up" {
+			m.Migrate = func(tx *gorm.DB) error { return tx.Exec(stmt).Error }
+		} else {
+			m.Rollback = func(tx *gorm.DB) error { return tx.Exec(stmt).Error }
+		}
+	}
+	res := make([]*gormigrate.Migration, 0, len(ids))
+	for _, id := range ids {
+		if byID[id].Migrate == nil {
+			return nil, fmt.Errorf("migration %s has no .up.sql file", id)
+		}
+		res = append(res, byID[id])
+	}
+	return res, nil
+}
+
+// allMigrations 按 ID 排序的全部迁移
+func allMigrations() []*gormigrate.Migration {
+	sqls, err := sqlMigrations()
+	if err != nil {
+		log.Fatalf("Failed to load database migrations: %v", err)
+	}
+	res := append(append([]*gormigrate.Migration{}, goMigrations...), sqls...)
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+func newMigrator(migrations []*gormigrate.Migration) *gormigrate.Gormigrate {
+	return gormigrate.New(db, &gormigrate.Options{
+		TableName:      migrationTable,
+		IDColumnName:   "id",
+		IDColumnSize:   255,
+		UseTransaction: true, // 任一迁移失败时整体回滚，数据库保持在执行前的版本
+	}, migrations)
+}
+
+// appliedMigrations 已执行的迁移 ID
+func appliedMigrations() map[string]bool {
+	res := make(map[string]bool)
+	if !db.Migrator().HasTable(migrationTable) {
+		return res
+	}
+	var ids []string
+	db.Table(migrationTable).Pluck("id", &ids)
+	for _, id := range ids {
+		res[id] = true
+	}
+	return res
+}
+
+// pendingMigrations 尚未执行的迁移 ID
+func pendingMigrations(migrations []*gormigrate.Migration) []string {
+	applied := appliedMigrations()
+	res := make([]string, 0)
+	for _, m := range migrations {
+		if !applied[m.ID] {
+			res = append(res, m.ID)
+		}
+	}
+	return res
+}
+
+// applyMigrations 执行全部未执行的迁移，返回本次执行的迁移
+func applyMigrations() ([]string, error) {
+	migrations := allMigrations()
+	pending := pendingMigrations(migrations)
+	if len(pending) == 0 {
+		return pending, nil
+	}
+	return pending, newMigrator(migrations).Migrate()
+}
+
+// migrateDB 启动时执行迁移；关闭自动迁移时只检查是否有未执行的迁移
+func migrateDB() {
+	if !appConfig.AutoMigrate {
+		if pending := pendingMigrations(allMigrations()); len(pending) > 0 {
+			log.Fatalf("Database has %d pending migration(s) (%s), run with -migrate up first", len(pending), strings.Join(pending, ", "))
+		}
+		return
+	}
+	applied, err := applyMigrations()
+	if err != nil {
+		log.Fatalf("Database migration failed: %v", err)
+	}
+	for _, id := range applied {
+		log.Printf("Database migration %s applied", id)
+	}
+}
+
+// runMigrateCommand 处理 -migrate 参数：status 列出迁移状态，up 执行未执行的迁移，down 回滚最近一次迁移
+func runMigrateCommand(cmd string) {
+	switch cmd {
+	case "status":
+		applied := appliedMigrations()
+		for _, m := range allMigrations() {
+			state := "未执行"
+			if applied[m.ID] {
+				state = "已执行"
+			}
+			fmt.Printf("%-50s %s\n", m.ID, state)
+		}
+	case "up":
+		applied, err := applyMigrations()
+		if err != nil {
+			log.Fatalf("错误: 迁移失败，数据库未修改: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("数据库已是最新版本")
+		}
+		for _, id := range applied {
+			fmt.Printf("已执行迁移 %s\n", id)
+		}
+	case "down":
+		migrations := allMigrations()
+		applied := appliedMigrations()
+		var last *gormigrate.Migration
+		for _, m := range migrations {
+			if applied[m.ID] {
+				last = m
+			}
+		}
+		if last == nil {
+			fmt.Println("没有可回滚的迁移")
+			return
+		}
+		if err := newMigrator(migrations).RollbackMigration(last); err != nil {
+			if errors.Is(err, gormigrate.ErrRollbackImpossible) {
+				log.Fatalf("错误: 迁移 %s 不支持回滚", last.ID)
+			}
+			log.Fatalf("错误: 回滚迁移 %s 失败: %v", last.ID, err)
+		}
+		fmt.Printf("已回滚迁移 %s\n", last.ID)
+	default:
+		log.Fatalf("错误: 未知的 -migrate 命令 %q，可用命令: status, up, down", cmd)
+	}
+}
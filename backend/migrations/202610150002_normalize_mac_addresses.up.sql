@@ -0,0 +1,9 @@
+-- 早期版本保存的 MAC 可能带冒号、连字符或为小写，统一为不带分隔符的大写，与当前写入的格式一致。
+-- 规范化后与已有记录重复的行保持原样（OR IGNORE），由冲突检查提示管理员处理。
+UPDATE OR IGNORE nodes
+SET mac_address = UPPER(REPLACE(REPLACE(mac_address, ':', ''), '-', ''))
+WHERE mac_address <> UPPER(REPLACE(REPLACE(mac_address, ':', ''), '-', ''));
+
+UPDATE OR IGNORE blocked_macs
+SET mac_address = UPPER(REPLACE(REPLACE(mac_address, ':', ''), '-', ''))
+WHERE mac_address <> UPPER(REPLACE(REPLACE(mac_address, ':', ''), '-', ''));
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"n2n_ui/backend/fakesupernode"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 模拟模式（N2N_MOCK_MODE=true）：所有实例的管理端口、systemctl 与 journalctl 都由内置的假 supernode 代替，
+// 密钥由假的 n2n-keygen 生成，数据库照常使用。在线节点按数据库中已启用的节点生成（约五分之一保持离线），
+// 并定期模拟中转和掉线重连，便于在未安装 n2n 的 macOS / Windows / CI 环境中开发前端和运行测试。
+// 演示模式（-demo）在此基础上使用内存数据库和固定的示例节点。
+
+const mockEdgeSyncInterval = 30 * time.Second
+
+var (
+	mockServer *fakesupernode.Server
+	mockDir    string // 模拟模式下默认实例的配置文件目录
+)
+
+// startMock 启动假 supernode 并替换系统操作，dir 为默认实例配置文件所在目录
+func startMock(edges []fakesupernode.Edge, dir string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatalf("Mock: failed to create %s: %v", dir, err)
+	}
+	srv := fakesupernode.New(edges)
+	if err := srv.Listen("127.0.0.1:0"); err != nil {
+		log.Fatalf("Mock: failed to start fake supernode: %v", err)
+	}
+	go srv.Simulate(3 * time.Second)
+	mockServer, mockDir = srv, dir
+	host = &mockHost{srv: srv}
+	utils.Keygen = mockKeygen
+	utils.SupernodeVersion = func() (string, error) { return fakesupernode.Version, nil }
+	log.Printf("[模拟模式] 假 supernode 管理端口: %s，配置文件目录: %s", srv.Addr(), dir)
+}
+
+// instanceMgmtAddr 实例管理端口地址，模拟模式下都指向假 supernode（数据库中的地址保持不变）
+func instanceMgmtAddr(inst models.SupernodeInstance) string {
+	if mockServer != nil {
+		return mockServer.Addr()
+	}
+	return inst.MgmtAddr
+}
+
+// mockEdgesFromDB 按数据库中已启用的节点生成在线 edge，ID 除 5 余 4 的节点保持离线
+func mockEdgesFromDB() []fakesupernode.Edge {
+	var nodes []models.Node
+	db.Where("is_enabled = ?", true).Order("id").Find(&nodes)
+	prefixes := make(map[string]string)
+	var comms []models.Community
+	db.Find(&comms)
+	for _, c := range comms {
+		if _, ipnet, err := net.ParseCIDR(c.Range); err == nil {
+			ones, _ := ipnet.Mask.Size()
+			prefixes[c.Name] = fmt.Sprintf("/%d", ones)
+		}
+	}
+	edges := make([]fakesupernode.Edge, 0, len(nodes))
+	now := time.Now()
+	for _, n := range nodes {
+		if n.ID%5 == 4 || len(n.MacAddress) != 12 {
+			continue
+		}
+		mac := make([]string, 0, 6)
+		for i := 0; i < 12; i += 2 {
+			mac = append(mac, n.MacAddress[i:i+2])
+		}
+		edges = append(edges, fakesupernode.Edge{
+			Community: n.Community,
+			Name:      n.Name,
+			Mac:       strings.Join(mac, ":"),
+			IP:        n.IPAddress + prefixes[n.Community],
+			External:  fmt.Sprintf("203.0.113.%d:%d", 1+n.ID%250, 40000+n.ID%20000),
+			LastSeen:  now,
+		})
+	}
+	return edges
+}
+
+// startMockEdgeSync 定期按数据库刷新假 supernode 的 edge 表，新建的节点随后上线
+func startMockEdgeSync() {
+	ticker := time.NewTicker(mockEdgeSyncInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		mockServer.SetEdges(mockEdgesFromDB())
+	}
+}
+
+// mockKeygen 代替 n2n-keygen，由参数派生固定的假密钥
+func mockKeygen(arg ...string) (string, error) {
+	sum := sha256.Sum256([]byte(strings.Join(arg, "\x00")))
+	return base64.RawStdEncoding.EncodeToString(sum[:]), nil
+}
+
+// mockHost 用假 supernode 代替 systemd 与 journald
+type mockHost struct {
+	srv *fakesupernode.Server
+}
+
+func (h *mockHost) ServiceActive(unit string) bool { return true }
+
+func (h *mockHost) RestartService(unit string) error {
+	h.srv.Emit("supernode restarted by n2n-admin")
+	return nil
+}
+
+func (h *mockHost) RecentLogs(unit string, n int) ([]string, error) {
+	return h.srv.Recent(n), nil
+}
+
+func (h *mockHost) FollowLogs(ctx context.Context, unit string, backlog int) (io.ReadCloser, error) {
+	lines, cancel := h.srv.Subscribe()
+	pr, pw := io.Pipe()
+	go func() {
+		defer cancel()
+		for _, l := range h.srv.Recent(backlog) {
+			if _, err := io.WriteString(pw, l+"\n"); err != nil {
+				return
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				pw.CloseWithError(ctx.Err())
+				return
+			case l, ok := <-lines:
+				if !ok {
+					pw.Close()
+					return
+				}
+				if _, err := io.WriteString(pw, l+"\n"); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return pr, nil
+}
+
+// mockDefaultDir 模拟模式下默认实例配置文件的目录：数据库旁的 n2n-mock
+func mockDefaultDir() string {
+	return filepath.Join(filepath.Dir(appConfig.DBPath), "n2n-mock")
+}
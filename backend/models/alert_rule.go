@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// AlertRule 阈值告警规则：指标按比较符与阈值比较，条件持续满足 Duration 秒后触发
+type AlertRule struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Name       string    `gorm:"size:100" json:"name"`
+	Metric     string    `gorm:"size:50" json:"metric"`    // online_count, relay_ratio, supernode_restarts
+	Target     string    `gorm:"size:100" json:"target"`   // 作用对象：社区名、节点 ID 或实例 ID，空表示全部
+	Comparator string    `gorm:"size:2" json:"comparator"` // <, <=, >, >=, ==, !=
+	Threshold  float64   `json:"threshold"`
+	Duration   int       `json:"duration"`                // 条件需持续满足的秒数
+	Severity   string    `gorm:"size:20" json:"severity"` // info, warning, critical
+	Channels   string    `json:"channels"`                // 逗号分隔的通知渠道，空表示按 notify_routes_* 路由
+	Enabled    bool      `gorm:"index" json:"enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// AlertEvent 告警规则的一次触发，条件不再满足时标记为已恢复
+type AlertEvent struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	RuleID         uint       `gorm:"index" json:"rule_id"`
+	RuleName       string     `gorm:"size:100" json:"rule_name"`
+	Severity       string     `gorm:"size:20" json:"severity"`
+	Value          float64    `json:"value"` // 触发时的指标值
+	Message        string     `json:"message"`
+	Status         string     `gorm:"size:20;index" json:"status"` // firing, resolved
+	FiredAt        time.Time  `gorm:"index" json:"fired_at"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	AcknowledgedBy string     `gorm:"size:100" json:"acknowledged_by,omitempty"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Announcement 发送给某个社区或分组内节点负责人的公告
+type Announcement struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Title     string    `gorm:"size:200;not null" json:"title"`
+	Message   string    `json:"message"`
+	Community string    `gorm:"size:50" json:"community"` // 目标社区，为空表示不限
+	Tag       string    `gorm:"size:50" json:"tag"`       // 目标分组，为空表示不限
+	Channels  string    `json:"channels"`                 // 逗号分隔: email, inbox, webhook
+	NodeCount int       `json:"node_count"`
+	CreatedBy string    `gorm:"size:100" json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AnnouncementDelivery 公告在某个渠道上对某个接收人的投递记录
+type AnnouncementDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	AnnouncementID uint       `gorm:"index" json:"announcement_id"`
+	Channel        string     `gorm:"size:20" json:"channel"`
+	Recipient      string     `json:"recipient"`
+	Status         string     `gorm:"size:20;index" json:"status"` // pending, sent, failed
+	Error          string     `json:"error,omitempty"`
+	SentAt         *time.Time `json:"sent_at"`
+}
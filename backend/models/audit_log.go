@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// AuditLog 用户对账户和系统所做的重要操作
+type AuditLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	Actor     string    `gorm:"size:100;index" json:"actor"` // 操作者用户名
+	Action    string    `gorm:"size:50;index" json:"action"`
+	Target    string    `gorm:"size:200" json:"target,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	IP        string    `gorm:"size:45" json:"ip"`
+}
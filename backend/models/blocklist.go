@@ -0,0 +1,22 @@
+package models
+
+import (
+	"n2n_ui/backend/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BlockedMac 被封禁的 edge MAC，禁止重新纳管
+type BlockedMac struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	MacAddress string    `gorm:"size:17;uniqueIndex" json:"mac_address"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BeforeSave 与节点一致，以不带分隔符的大写格式保存 MAC
+func (b *BlockedMac) BeforeSave(tx *gorm.DB) error {
+	b.MacAddress = utils.NormalizeMac(b.MacAddress)
+	return nil
+}
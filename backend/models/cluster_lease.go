@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// ClusterLease 多个面板实例共享数据库时的租约，持有者在到期前续约，过期后其他实例可以接管
+type ClusterLease struct {
+	Name       string    `gorm:"primaryKey;size:50" json:"name"`
+	Holder     string    `gorm:"size:200" json:"holder"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `gorm:"index" json:"expires_at"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EncryptedPrefix 标记已加密的值，没有前缀的值视为迁移前的明文
+const EncryptedPrefix = "enc:v1:"
+
+var secretCipher cipher.AEAD
+
+// ErrMalformedEncrypted 带加密前缀但不是 EncryptValue 生成的值，通常是恰好以前缀开头的明文
+var ErrMalformedEncrypted = errors.New("malformed encrypted value")
+
+// SetEncryptionKey 设置加密敏感字段使用的 32 字节密钥，需在打开数据库前调用
+func SetEncryptionKey(key []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	secretCipher, err = cipher.NewGCM(block)
+	return err
+}
+
+// IsEncrypted 判断存储的值是否已加密
+func IsEncrypted(v string) bool {
+	return strings.HasPrefix(v, EncryptedPrefix)
+}
+
+// EncryptValue 用 AES-GCM 加密，空串和能用当前密钥解密的值原样返回；
+// 带前缀但无法解密的值（如用户输入的以 enc:v1: 开头的密码）按明文加密，不会以明文存储
+func EncryptValue(plain string) (string, error) {
+	if plain == "" {
+		return plain, nil
+	}
+	if IsEncrypted(plain) {
+		if _, err := DecryptValue(plain); err == nil {
+			return plain, nil
+		}
+	}
+	if secretCipher == nil {
+		return "", errors.New("encryption key not set")
+	}
+	nonce := make([]byte, secretCipher.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := secretCipher.Seal(nonce, nonce, []byte(plain), nil)
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue 解密 EncryptValue 的结果，未加密的值原样返回
+func DecryptValue(v string) (string, error) {
+	if !IsEncrypted(v) {
+		return v, nil
+	}
+	if secretCipher == nil {
+		return "", errors.New("encryption key not set")
+	}
+	data, err := base64.StdEncoding.DecodeString(v[len(EncryptedPrefix):])
+	if err != nil || len(data) < secretCipher.NonceSize() {
+		return "", ErrMalformedEncrypted
+	}
+	n := secretCipher.NonceSize()
+	plain, err := secretCipher.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return "", errors.New("cannot decrypt value, the encryption key may have changed")
+	}
+	return string(plain), nil
+}
+
+// EncryptedString 在数据库中加密存储的字符串字段，读取时自动解密
+// 用 map 更新时需传入 EncryptedString 类型的值，否则会以明文写入
+type EncryptedString string
+
+func (s EncryptedString) Value() (driver.Value, error) {
+	return EncryptValue(string(s))
+}
+
+func (s *EncryptedString) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("unsupported type %T for EncryptedString", value)
+	}
+	plain, err := DecryptValue(raw)
+	if err != nil {
+		return err
+	}
+	*s = EncryptedString(plain)
+	return nil
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SupernodeIncident 看门狗检测到的一次 supernode 故障及自动恢复过程
+type SupernodeIncident struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	InstanceID   uint       `gorm:"index" json:"instance_id"`
+	InstanceName string     `gorm:"size:50" json:"instance_name"`
+	Reason       string     `json:"reason"`
+	Status       string     `gorm:"size:20;index" json:"status"` // recovering, recovered, failed
+	Restarts     int        `json:"restarts"`                    // 自动重启次数
+	LastError    string     `json:"last_error,omitempty"`        // 最近一次重启失败的原因
+	StartedAt    time.Time  `gorm:"index" json:"started_at"`
+	ResolvedAt   *time.Time `json:"resolved_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Invitation 社区邀请链接，持有链接的人可以自助提交设备名称注册节点
+type Invitation struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	TokenHash       string     `gorm:"size:64;uniqueIndex" json:"-"` // 链接中令牌的 SHA-256，明文只在创建时返回
+	Community       string     `gorm:"size:50;index" json:"community"`
+	Note            string     `gorm:"size:255" json:"note"`
+	MaxUses         int        `json:"max_uses"` // 最多注册多少个节点，0 表示不限
+	Uses            int        `json:"uses"`     // 已占用的名额，被拒绝的申请不占用
+	RequireApproval bool       `json:"require_approval"`
+	NodeTags        string     `gorm:"size:255" json:"node_tags"` // 注册的节点自动加入的分组
+	NodeExpiryDays  int        `json:"node_expiry_days"`          // 注册的节点多少天后到期，0 表示永久有效
+	ExpiresAt       *time.Time `json:"expires_at"`                // 链接到期时间，空表示不过期
+	RevokedAt       *time.Time `json:"revoked_at"`
+	CreatedBy       string     `gorm:"size:100" json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// NodeRegistration 通过邀请链接提交的节点注册申请
+type NodeRegistration struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	InvitationID uint       `gorm:"index" json:"invitation_id"`
+	Community    string     `gorm:"size:50" json:"community"`
+	Name         string     `gorm:"size:100" json:"name"`
+	Email        string     `gorm:"size:255" json:"email"`
+	KeyHash      string     `gorm:"size:64" json:"-"`            // 申请人查询结果时使用的密钥
+	Status       string     `gorm:"size:20;index" json:"status"` // pending, approved, rejected
+	NodeID       uint       `json:"node_id"`
+	Reason       string     `json:"reason,omitempty"` // 拒绝原因
+	IP           string     `gorm:"size:45" json:"ip"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DecidedAt    *time.Time `json:"decided_at"`
+	DecidedBy    string     `gorm:"size:100" json:"decided_by"`
+}
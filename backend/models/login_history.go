@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LoginHistory 登录记录，包括成功、失败和被锁定的尝试
+type LoginHistory struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id"` // 用户名不存在时为 0
+	Username  string    `gorm:"size:100;index" json:"username"`
+	IP        string    `gorm:"size:45" json:"ip"`
+	Location  string    `json:"location"`
+	UserAgent string    `json:"user_agent"`
+	Success   bool      `gorm:"index" json:"success"`
+	Reason    string    `gorm:"size:50" json:"reason,omitempty"` // bad_password, unknown_user, locked
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
@@ -1,35 +1,87 @@
 package models
 
 import (
+	"n2n_ui/backend/utils"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type Node struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Name        string         `gorm:"size:100;not null" json:"name"`
-	IPAddress   string         `gorm:"size:45;uniqueIndex" json:"ip_address"`
-	MacAddress  string         `gorm:"size:17;uniqueIndex" json:"mac_address"`
-	Community   string         `gorm:"size:50;index" json:"community"`
-	Description string         `json:"description"`
-	Encryption  string         `gorm:"default:AES" json:"encryption"` // AES, Twofish, ChaCha20
-	Compression bool           `gorm:"default:false" json:"compression"`
-	Routing     string         `json:"routing"`    // e.g., 192.168.1.0/24:10.10.10.5
-	LocalPort   int            `json:"local_port"` // -p parameter
-	IsEnabled   bool           `gorm:"default:true" json:"is_enabled"`
-	LastSeen    *time.Time     `json:"last_seen"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Name        string `gorm:"size:100;not null" json:"name"`
+	IPAddress   string `gorm:"size:45;uniqueIndex" json:"ip_address"`
+	MacAddress  string `gorm:"size:17;uniqueIndex" json:"mac_address"`
+	Community   string `gorm:"size:50;index" json:"community"`
+	Description string `json:"description"`
+	Encryption  string `gorm:"default:AES" json:"encryption"` // AES, Twofish, ChaCha20
+	Compression bool   `gorm:"default:false" json:"compression"`
+	Routing     string `json:"routing"`                    // e.g., 192.168.1.0/24:10.10.10.5
+	LocalPort   int    `json:"local_port"`                 // -p parameter
+	ExtraArgs   string `gorm:"size:500" json:"extra_args"` // 额外的 edge 参数，每行一个，见 edge_flags.go
+	MTU         int    `json:"mtu"`                        // -M，0 表示使用社区的 MTU
+	TapDevice   string `gorm:"size:15" json:"tap_device"`  // -d，空表示 n2n0
+	TTL         int    `json:"ttl"`                        // -L 注册包 TTL，0 表示不设置
+	IsEnabled   bool   `gorm:"default:true" json:"is_enabled"`
+	Tags        string `gorm:"size:255" json:"tags"`        // 逗号分隔的分组标签
+	Owner       string `gorm:"size:100" json:"owner"`       // 负责人的面板用户名，用于站内通知
+	OwnerEmail  string `gorm:"size:255" json:"owner_email"` // 负责人邮箱
+	// 资产信息
+	Contact    string `gorm:"size:255" json:"contact" binding:"max=255"`   // 联系方式（电话、IM 等）
+	Site       string `gorm:"size:255" json:"site" binding:"max=255"`      // 物理位置，如 "上海办公室 3F 机柜 A"
+	DeviceType string `gorm:"size:50" json:"device_type" binding:"max=50"` // 设备类型，如 router、nas、laptop
+	// edge 代理上报数据时使用的令牌（SHA-256），只在生成时返回明文
+	AgentToken string `gorm:"size:64;index" json:"-"`
+	// n2n 3.x 用户名/密码认证：公钥写入 community.list，密码写入生成的 edge 配置
+	AuthUser      string          `gorm:"size:16" json:"auth_user"`
+	AuthPublicKey string          `gorm:"size:64" json:"auth_public_key"`
+	AuthSecret    EncryptedString `json:"-"`
+	// 最近一次下发并确认部署的配置，节点修改后与新配置对比
+	IssuedConfig   string     `json:"-"`
+	ConfigDiff     string     `json:"config_diff,omitempty"`
+	ConfigOutdated bool       `gorm:"default:false" json:"config_outdated"`
+	LastSeen       *time.Time `json:"last_seen"`
+	LastExternalIP string     `gorm:"size:45" json:"last_external_ip"` // 轮询记录的最近一次公网地址、归属地和连接方式
+	LastLocation   string     `gorm:"size:255" json:"last_location"`
+	LastConnType   string     `gorm:"size:10" json:"last_conn_type"`
+	ExpiresAt      *time.Time `gorm:"index" json:"expires_at"` // 到期后失去访问权限，空表示永久有效
+	// 待审批：非管理员创建或自动发现的节点，批准前保持禁用，见 node_approval.go
+	PendingApproval bool           `gorm:"default:false;index" json:"pending_approval"`
+	RequestedBy     string         `gorm:"size:100" json:"requested_by"` // 提交节点的面板用户名，自动发现时为空
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// BeforeSave 以规范格式保存 MAC（不带分隔符的大写）和 IP 地址，查询与比较时无需再次转换。
+// 只对 Create/Save 生效，按列更新（Update/Updates）时需传入规范格式的值
+func (n *Node) BeforeSave(tx *gorm.DB) error {
+	n.MacAddress = utils.NormalizeMac(n.MacAddress)
+	n.IPAddress = utils.NormalizeIP(n.IPAddress)
+	return nil
 }
 
 type Community struct {
-	ID        uint   `gorm:"primaryKey" json:"id"`
-	Name      string `gorm:"size:50;uniqueIndex" json:"name"`
-	Range     string `gorm:"size:50" json:"range"` // e.g., 10.0.0.0/24
-	Password  string `json:"password"`
-	CreatedAt time.Time
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	Name      string          `gorm:"size:50;uniqueIndex" json:"name"`
+	Range     string          `gorm:"size:50" json:"range"` // e.g., 10.0.0.0/24
+	Password  EncryptedString `json:"password"`
+	PortStart int             `json:"port_start"` // 可选的 edge 本地端口分配范围
+	PortEnd   int             `json:"port_end"`
+	// 新节点的默认 edge 选项；MTU 与 supernode 地址在生成配置时直接使用
+	DefaultEncryption  string `gorm:"size:20" json:"default_encryption"`
+	DefaultCompression bool   `gorm:"default:false" json:"default_compression"`
+	MTU                int    `json:"mtu"`                                // 0 表示使用 edge 默认值
+	SupernodeOverride  string `gorm:"size:255" json:"supernode_override"` // 覆盖全局及实例的 supernode 地址
+	// 启用后 community.list 中写入该社区节点的用户公钥，supernode 只接受持有密钥的 edge
+	UserAuth bool `gorm:"default:false" json:"user_auth"`
+	// 所属 supernode 实例，0 表示默认实例
+	SupernodeID uint `gorm:"default:0" json:"supernode_id"`
+	// 密码轮换：PendingPassword 在 PasswordRotateAt 生效，之前的过渡期内 edge 可提前取得新配置
+	PendingPassword  EncryptedString `json:"-"`
+	PasswordRotateAt *time.Time      `json:"password_rotate_at"`
+	CreatedAt        time.Time
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 type Setting struct {
@@ -42,4 +94,18 @@ type User struct {
 	Username string `gorm:"size:100;uniqueIndex" json:"username"`
 	Password string `json:"-"` // 不在 JSON 中返回
 	IsAdmin  bool   `gorm:"default:true" json:"is_admin"`
+	// 最近一次成功登录
+	LastLoginAt *time.Time `json:"last_login_at"`
+	LastLoginIP string     `gorm:"size:45" json:"last_login_ip"`
+	// 修改密码或角色时递增，之前签发的令牌随之失效
+	TokenVersion int `json:"-"`
+}
+
+// SegmentRule 声明一条允许的节点分组间通信（Src -> Dst，* 表示任意分组）
+type SegmentRule struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	SrcGroup    string    `gorm:"size:50;not null" json:"src_group"`
+	DstGroup    string    `gorm:"size:50;not null" json:"dst_group"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// NodeAvailability 节点每小时的在线统计，用于计算连接可靠性
+type NodeAvailability struct {
+	ID            uint      `gorm:"primaryKey" json:"-"`
+	NodeID        uint      `gorm:"uniqueIndex:idx_node_hour" json:"node_id"`
+	Hour          time.Time `gorm:"uniqueIndex:idx_node_hour" json:"hour"`
+	Polls         int       `json:"polls"`         // 轮询次数
+	Seen          int       `json:"seen"`          // 其中在 supernode 上可见的次数
+	Registrations int       `json:"registrations"` // last_seen 前进的次数，即 supernode 收到的注册刷新
+	Reconnects    int       `json:"reconnects"`    // 从不可见恢复为可见的次数
+	MaxStaleness  int       `json:"max_staleness"` // 可见时 last_seen 距轮询时刻的最大秒数
+}
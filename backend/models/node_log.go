@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// NodeLog edge 代理上报的一行 edge 守护进程日志
+type NodeLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	NodeID    uint      `gorm:"index" json:"node_id"`
+	Time      time.Time `gorm:"index" json:"time"` // edge 上的日志时间，未上报时为接收时间
+	Line      string    `json:"line"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Notification 面板内通知，每个用户一份，独立于外部通知渠道
+type Notification struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Type      string    `gorm:"size:50;index" json:"type"`
+	Level     string    `gorm:"size:20" json:"level"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	IsRead    bool      `gorm:"default:false;index" json:"is_read"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// RelayState 中转表的快照，定期保存以便重启后恢复节点的连接类型
+type RelayState struct {
+	SrcMac     string    `gorm:"primaryKey;size:17" json:"src_mac"`
+	DstMac     string    `gorm:"primaryKey;size:17" json:"dst_mac"`
+	LastActive time.Time `json:"last_active"`
+	PktCount   int64     `json:"pkt_count"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SpeedTest 一次 edge 与 supernode 主机之间的 iperf3 吞吐量测试，由 edge 代理执行并上报
+type SpeedTest struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	NodeID      uint       `gorm:"index" json:"node_id"`
+	Status      string     `gorm:"size:16;index" json:"status"` // pending / running / done / failed
+	Server      string     `gorm:"size:100" json:"server"`      // iperf3 服务端 host:port
+	Duration    int        `json:"duration"`                    // 每个方向的测试秒数
+	UploadBps   float64    `json:"upload_bps"`                  // edge -> supernode
+	DownloadBps float64    `json:"download_bps"`                // supernode -> edge
+	Retransmits int        `json:"retransmits"`
+	Error       string     `json:"error,omitempty"`
+	RequestedBy string     `gorm:"size:50" json:"requested_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at"` // 代理领取任务的时间
+	FinishedAt  *time.Time `json:"finished_at"`
+}
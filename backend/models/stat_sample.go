@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// StatSample 时序统计的一个时间桶，按分辨率逐级汇总：1 分钟的原始采样汇总为 5 分钟，再汇总为 1 小时
+type StatSample struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	Metric     string    `gorm:"size:50;uniqueIndex:idx_stat_bucket" json:"metric"`
+	Series     string    `gorm:"size:100;uniqueIndex:idx_stat_bucket" json:"series"` // 社区名、实例名等，空为合计
+	Resolution int       `gorm:"uniqueIndex:idx_stat_bucket" json:"resolution"`      // 桶的长度（秒）
+	Bucket     time.Time `gorm:"uniqueIndex:idx_stat_bucket;index" json:"bucket"`    // 桶的起始时间
+	Count      int       `json:"count"`                                              // 采样次数
+	Sum        float64   `json:"sum"`
+	Min        float64   `json:"min"`
+	Max        float64   `json:"max"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// SupernodeInstance 本机上的一个 supernode 服务实例（不同端口、不同 systemd 单元）
+type SupernodeInstance struct {
+	ID                uint            `gorm:"primaryKey" json:"id"`
+	Name              string          `gorm:"size:50;uniqueIndex" json:"name"`
+	Unit              string          `gorm:"size:100" json:"unit"`        // systemd 单元名
+	ConfigPath        string          `json:"config_path"`                 // supernode.conf 路径
+	CommunityListPath string          `json:"community_list_path"`         // community.list 路径
+	MgmtAddr          string          `gorm:"size:100" json:"mgmt_addr"`   // 管理端口地址
+	MgmtPassword      EncryptedString `json:"-"`                           // 管理端口写命令密码
+	PublicHost        string          `gorm:"size:255" json:"public_host"` // 生成 edge 配置时使用的地址，空则使用 supernode_host 设置
+	LogSources        string          `json:"log_sources"`                 // 逗号分隔的 systemd 单元或日志文件路径（/ 开头），空则跟踪 Unit
+	IsDefault         bool            `gorm:"default:false" json:"is_default"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
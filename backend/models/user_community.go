@@ -0,0 +1,7 @@
+package models
+
+// UserCommunity 非管理员用户可管理的社区
+type UserCommunity struct {
+	UserID      uint `gorm:"primaryKey" json:"user_id"`
+	CommunityID uint `gorm:"primaryKey;index" json:"community_id"`
+}
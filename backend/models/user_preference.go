@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// UserPreference 用户的界面偏好，保存在服务端以便换浏览器后保留
+type UserPreference struct {
+	UserID          uint      `gorm:"primaryKey" json:"-"`
+	Timezone        string    `gorm:"size:64" json:"timezone"` // IANA 时区名，为空时使用浏览器时区
+	Language        string    `gorm:"size:10" json:"language"` // 为空时按 Accept-Language / default_language
+	DashboardLayout string    `gorm:"type:text" json:"-"`      // 前端自定义的 JSON
+	UpdatedAt       time.Time `json:"updated_at"`
+}
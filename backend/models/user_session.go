@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// UserSession 一次登录会话或用户创建的 API 令牌，JWT 的 jti 对应 TokenID，吊销后令牌立即失效
+type UserSession struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index" json:"-"`
+	TokenID    string     `gorm:"size:32;uniqueIndex" json:"-"`
+	Kind       string     `gorm:"size:16;index" json:"kind"` // session / api_token
+	Name       string     `gorm:"size:100" json:"name,omitempty"`
+	IP         string     `gorm:"size:45" json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at"` // 为空表示不过期（API 令牌）
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
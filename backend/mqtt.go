@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"strings"
+	"time"
+)
+
+// MQTT 发布：设置 mqtt_enabled=true 后连接 mqtt_broker，在 mqtt_topic（默认 n2n）下发布：
+//   - <topic>/status：面板是否在线，online / offline（遗嘱消息），保留
+//   - <topic>/node/<mac>/state：节点 online / offline，保留，状态变化后的下一个周期发布
+//   - <topic>/node/<mac>/attributes：节点名称、社区、虚拟 IP、公网地址、连接方式（JSON），保留
+//   - <topic>/stats：节点总数、在线数、中转数等汇总（JSON），每个周期发布
+// <mac> 为不带分隔符的小写 MAC。开启 mqtt_ha_discovery 后为每个节点发布 Home Assistant 的
+// connectivity 类型 binary_sensor，节点删除后清除对应的保留消息。
+
+const (
+	mqttReloadInterval = 10 * time.Second // 未启用时检查设置的间隔
+	mqttReconnectLog   = 10 * time.Minute // 连接持续失败时重复记录错误的间隔
+)
+
+// mqttSettings 当前生效的发布配置
+type mqttSettings struct {
+	cfg        utils.MQTTConfig
+	topic      string
+	qos        byte
+	discovery  string // Home Assistant discovery 前缀，为空表示不发布
+	offlineMin int    // 连续多少个周期未见才发布 offline
+}
+
+// loadMQTTSettings 读取发布设置，未启用或未配置 broker 时返回 nil
+func loadMQTTSettings() *mqttSettings {
+	if getSettingValue("mqtt_enabled", "false") != "true" {
+		return nil
+	}
+	broker := getSettingValue("mqtt_broker", "")
+	if broker == "" {
+		return nil
+	}
+	topic := strings.Trim(getSettingValue("mqtt_topic", "n2n"), "/")
+	interval := time.Duration(settingIntValue("mqtt_interval")) * time.Second
+	s := &mqttSettings{
+		cfg: utils.MQTTConfig{
+			Broker:    broker,
+			TLS:       getSettingValue("mqtt_tls", "false") == "true",
+			ClientID:  getSettingValue("mqtt_client_id", "n2n-admin"),
+			Username:  getSettingValue("mqtt_username", ""),
+			Password:  getSettingValue("mqtt_password", ""),
+			KeepAlive: 2 * interval, // 每个周期都会发布 stats，无需额外心跳
+			Will:      utils.MQTTMessage{Topic: topic + "/status", Payload: "offline", QoS: 1, Retain: true},
+			Birth:     utils.MQTTMessage{Topic: topic + "/status", Payload: "online", QoS: 1, Retain: true},
+		},
+		topic:      topic,
+		offlineMin: settingIntValue("alert_offline_threshold"),
+	}
+	if getSettingValue("mqtt_qos", "0") == "1" {
+		s.qos = 1
+	}
+	if getSettingValue("mqtt_ha_discovery", "false") == "true" {
+		s.discovery = strings.Trim(getSettingValue("mqtt_ha_prefix", "homeassistant"), "/")
+	}
+	return s
+}
+
+func mqttJSON(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// mqttTracker 记录节点在线状态与已发布的保留消息，只在内容变化时重新发布
+type mqttTracker struct {
+	online    map[string]bool // MAC -> 当前发布的状态
+	missCount map[string]int
+	published map[string]string // 主题 -> 已发布的保留消息
+	connects  int
+}
+
+// updateOnline 按本次轮询结果更新节点状态，与告警一致，连续多次未见才判定离线；
+// 所属 supernode 管理端口不可用时保持原状态
+func (t *mqttTracker) updateOnline(mac string, seen, unknown bool, offlineMin int) bool {
+	switch {
+	case unknown:
+	case seen:
+		t.online[mac], t.missCount[mac] = true, 0
+	default:
+		t.missCount[mac]++
+		if _, ok := t.online[mac]; !ok || t.missCount[mac] >= offlineMin {
+			t.online[mac] = false
+		}
+	}
+	return t.online[mac]
+}
+
+// buildRetained 生成所有保留消息（主题 -> 内容）以及汇总数据
+func (t *mqttTracker) buildRetained(s *mqttSettings) (map[string]string, map[string]interface{}) {
+	var nodes []models.Node
+	db.Order("id").Find(&nodes)
+	edges, outages := edgeInfoWithOutages()
+	relays := relaySources()
+	down := make(map[uint]bool)
+	for _, o := range outages {
+		down[o.InstanceID] = true
+	}
+	var comms []models.Community
+	db.Find(&comms)
+	commDown := make(map[string]bool)
+	for _, cm := range comms {
+		commDown[cm.Name] = down[communityInstanceID(cm)]
+	}
+
+	msgs := make(map[string]string)
+	online, relayed := 0, 0
+	known := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		m := n.MacAddress
+		known[m] = true
+		info, seen := edges[m]
+		isOnline := t.updateOnline(m, seen, commDown[n.Community], s.offlineMin)
+		attrs := map[string]interface{}{
+			"id": n.ID, "name": n.Name, "community": n.Community, "ip_address": n.IPAddress,
+			"mac_address": utils.MAC(m).Format(), "enabled": n.IsEnabled,
+		}
+		state := "offline"
+		if isOnline {
+			state = "online"
+			online++
+		}
+		if seen {
+			attrs["external_ip"] = strings.Split(info.External, ":")[0]
+			attrs["conn_type"] = "P2P"
+			if relays[m] {
+				attrs["conn_type"] = "Relay"
+				relayed++
+			}
+		}
+		base := s.topic + "/node/" + strings.ToLower(m)
+		msgs[base+"/state"] = state
+		msgs[base+"/attributes"] = mqttJSON(attrs)
+		if s.discovery != "" {
+			id := "n2n_" + strings.ToLower(m)
+			msgs[s.discovery+"/binary_sensor/"+id+"/config"] = mqttJSON(map[string]interface{}{
+				"name": nil, "unique_id": id, "object_id": id, "device_class": "connectivity",
+				"state_topic": base + "/state", "payload_on": "online", "payload_off": "offline",
+				"json_attributes_topic": base + "/attributes",
+				"availability_topic":    s.topic + "/status",
+				"device":                map[string]interface{}{"identifiers": []string{id}, "name": n.Name, "manufacturer": "n2n", "model": "edge"},
+			})
+		}
+	}
+	for mac := range t.online {
+		if !known[mac] {
+			delete(t.online, mac)
+			delete(t.missCount, mac)
+		}
+	}
+	unknown := 0
+	for mac := range edges {
+		if !known[mac] {
+			unknown++
+		}
+	}
+	stats := map[string]interface{}{
+		"nodes": len(nodes), "online": online, "relay": relayed, "communities": len(comms),
+		"unknown_edges": unknown, "supernodes_unreachable": len(outages), "time": time.Now().Unix(),
+	}
+	return msgs, stats
+}
+
+// publish 发布一个周期的消息：变化的保留消息、已不存在的主题（发布空保留消息以清除）以及汇总
+func (t *mqttTracker) publish(client *utils.MQTTClient, s *mqttSettings) error {
+	if err := client.Connect(); err != nil {
+		return err
+	}
+	msgs, stats := t.buildRetained(s)
+	// 重新连接后全部重发，broker 可能已丢失保留消息
+	resync := client.Connects() != t.connects
+	for topic, payload := range msgs {
+		if !resync && t.published[topic] == payload {
+			continue
+		}
+		if err := client.Publish(utils.MQTTMessage{Topic: topic, Payload: payload, QoS: s.qos, Retain: true}); err != nil {
+			return err
+		}
+		t.published[topic] = payload
+	}
+	for topic := range t.published {
+		if _, ok := msgs[topic]; ok {
+			continue
+		}
+		if err := client.Publish(utils.MQTTMessage{Topic: topic, QoS: s.qos, Retain: true}); err != nil {
+			return err
+		}
+		delete(t.published, topic)
+	}
+	t.connects = client.Connects()
+	return client.Publish(utils.MQTTMessage{Topic: s.topic + "/stats", Payload: mqttJSON(stats), QoS: s.qos})
+}
+
+// startMQTTPublisher 按设置周期发布节点状态；broker、认证或主题变化时重建连接
+func startMQTTPublisher() {
+	var (
+		client   *utils.MQTTClient
+		current  utils.MQTTConfig
+		tracker  *mqttTracker
+		lastErr  string
+		lastLogT time.Time
+	)
+	for {
+		s := loadMQTTSettings()
+		if s == nil || s.cfg != current {
+			if client != nil {
+				client.Close()
+				client = nil
+			}
+			current = utils.MQTTConfig{}
+		}
+		if s == nil {
+			time.Sleep(mqttReloadInterval)
+			continue
+		}
+		if client == nil {
+			client, current = utils.NewMQTTClient(s.cfg), s.cfg
+			// 新连接的主题可能不同，不沿用旧连接的发布记录
+			tracker = &mqttTracker{online: make(map[string]bool), missCount: make(map[string]int), published: make(map[string]string)}
+		}
+		if err := tracker.publish(client, s); err != nil {
+			// 同一错误每 10 分钟最多记录一次
+			if err.Error() != lastErr || time.Since(lastLogT) > mqttReconnectLog {
+				log.Printf("MQTT: failed to publish to %s: %v", s.cfg.Broker, err)
+				lastErr, lastLogT = err.Error(), time.Now()
+			}
+		} else if lastErr != "" {
+			log.Printf("MQTT: publishing to %s resumed", s.cfg.Broker)
+			lastErr = ""
+		}
+		time.Sleep(time.Duration(settingIntValue("mqtt_interval")) * time.Second)
+	}
+}
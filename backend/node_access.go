@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 禁用节点分两个阶段：
+//  1. 标记为禁用，停止提供配置下载，并从之后生成的认证列表中排除
+//  2. 从 supernode 注册表中移除该 edge（drop=true 时立即执行；设置 enforce_node_disable=true 时监控发现仍在线会再次移除）
+
+// nodeAccessActive 节点是否仍具有访问权限：已启用且未到期
+func nodeAccessActive(n models.Node) bool {
+	return n.IsEnabled && !nodeExpired(n)
+}
+
+// nodeAccessState 节点列表中显示的访问状态：enabled / disabled / expired / pending（待审批）
+func nodeAccessState(n models.Node) string {
+	switch {
+	case n.PendingApproval:
+		return "pending"
+	case nodeExpired(n):
+		return "expired"
+	case !n.IsEnabled:
+		return "disabled"
+	}
+	return "enabled"
+}
+
+func disableNode(c *gin.Context) {
+	var p struct {
+		Drop bool `json:"drop"` // 同时从 supernode 移除注册
+	}
+	if !bindOptionalJSON(c, &p) {
+		return
+	}
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	db.Model(&n).Update("is_enabled", false)
+	log.Printf("Node %d (%s) disabled", n.ID, n.Name)
+	res := gin.H{"id": n.ID, "is_enabled": false}
+	syncAfterAccessChange(c, res)
+	if p.Drop {
+		dropEdgeRegistration(n.MacAddress, nodeInstanceID(n), res)
+	}
+	c.JSON(200, res)
+}
+
+func enableNode(c *gin.Context) {
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	if n.PendingApproval {
+		respondError(c, 409, ErrNodePending, "Node is pending approval")
+		return
+	}
+	if nodeExpired(n) {
+		respondError(c, 409, ErrNodeExpired, "Node access has expired, extend the expiry time first")
+		return
+	}
+	if isMacBlocked(n.MacAddress) {
+		respondError(c, 403, ErrMacBlocked, "MAC address is blocked")
+		return
+	}
+	db.Model(&n).Update("is_enabled", true)
+	res := gin.H{"id": n.ID, "is_enabled": true}
+	syncAfterAccessChange(c, res)
+	c.JSON(200, res)
+}
+
+// syncAfterAccessChange 节点启用状态变化后重写 community.list，使其认证公钥随之生效或失效，失败时在 res 中附带警告
+func syncAfterAccessChange(c *gin.Context, res gin.H) {
+	if err := syncCommunityList(); err != nil {
+		res["warning"] = tr(c, "Failed to write community.list") + ": " + err.Error()
+	}
+}
+
+// enforceDisabledNodes 检查已禁用但仍在线的节点：首次发现时告警，enforce_node_disable=true 时移除其注册
+// seen 记录已告警的 MAC，节点离线后清除
+func enforceDisabledNodes(edges map[string]bool, seen map[string]bool) {
+	var nodes []models.Node
+	db.Where("is_enabled = ? AND pending_approval = ?", false, false).Find(&nodes) // 待审批的节点在审批前可能已在线
+	enforce := getSettingValue("enforce_node_disable", "false") == "true"
+	blocked := blockedMacSet() // 已封禁的 MAC 由封禁告警处理
+	stillOnline := make(map[string]bool)
+	for _, n := range nodes {
+		mac := n.MacAddress
+		if !edges[mac] || blocked[mac] {
+			continue
+		}
+		stillOnline[mac] = true
+		res := gin.H{}
+		if enforce {
+			dropEdgeRegistration(mac, nodeInstanceID(n), res)
+		}
+		if seen[mac] {
+			continue
+		}
+		seen[mac] = true
+		msg := fmt.Sprintf("节点 %s (%s) 已禁用，但仍连接在 supernode 上", n.Name, n.IPAddress)
+		if res["dropped"] == true {
+			msg += "，已从 supernode 移除其注册"
+		} else if e, ok := res["drop_error"]; ok {
+			msg += fmt.Sprintf("，移除注册失败: %v", e)
+		}
+		dispatchAlert(Alert{Type: "disabled_edge_online", Level: "warning", Title: "已禁用的节点仍在线: " + n.Name, Message: msg})
+	}
+	for mac := range seen {
+		if !stillOnline[mac] {
+			delete(seen, mac)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 节点审批：开启 node_approval_required 后，非管理员创建的节点进入待审批状态；开启 node_auto_discover 后，
+// 连接到 supernode 但未在面板登记的 edge 由告警轮询自动登记为待审批节点。待审批节点保持禁用、不能下载配置，
+// 也不能由非管理员启用；管理员批准后启用，拒绝时移入回收站（可同时封禁 MAC），回收站中的 MAC 不会被再次登记。
+// 新的待审批节点只通知管理员，审批结果以站内通知告知提交节点的用户。
+
+// nodeApprovalRequired 当前用户创建的节点是否需要审批
+func nodeApprovalRequired(c *gin.Context) bool {
+	if getSettingValue("node_approval_required", "false") != "true" {
+		return false
+	}
+	user, ok := currentUser(c)
+	return !ok || !user.IsAdmin
+}
+
+// createPendingNode 以待审批状态创建节点。is_enabled 的数据库默认值为 true，
+// 创建时零值会被忽略，需在同一事务中改为禁用
+func createPendingNode(n *models.Node) error {
+	n.PendingApproval, n.IsEnabled = true, false
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(n).Error; err != nil {
+			return err
+		}
+		return tx.Model(n).Update("is_enabled", false).Error
+	})
+}
+
+// notifyPendingNode 通知管理员有新的待审批节点
+func notifyPendingNode(n models.Node) {
+	source := "自动发现"
+	if n.RequestedBy != "" {
+		source = "由用户 " + n.RequestedBy + " 创建"
+	}
+	dispatchAlert(Alert{Type: "node_pending", Level: "info", AdminOnly: true, Title: "新的待审批节点: " + n.Name,
+		Message: fmt.Sprintf("社区 %s 中的节点 %s (%s, %s) %s，请在待审批节点中批准或拒绝", n.Community, n.Name, n.IPAddress, utils.MAC(n.MacAddress).Format(), source)})
+}
+
+// notifyRequester 将审批结果写入提交节点的用户的站内通知
+func notifyRequester(n models.Node, typ, level, title, message string) {
+	if n.RequestedBy == "" {
+		return
+	}
+	var user models.User
+	if err := db.Where("username = ?", n.RequestedBy).First(&user).Error; err != nil {
+		return
+	}
+	db.Create(&models.Notification{UserID: user.ID, Type: typ, Level: level, Title: title, Message: message, CreatedAt: time.Now()})
+}
+
+// discoverEdges 将在线但未登记的 edge 登记为待审批节点，使用 edge 上报的 IP；
+// 社区未在面板中配置、IP 不在社区网段或与已有节点（包括回收站）冲突、MAC 已封禁的 edge 跳过
+func discoverEdges(edges map[string]utils.EdgeInfo) {
+	if getSettingValue("node_auto_discover", "false") != "true" {
+		return
+	}
+	var macs []string
+	db.Unscoped().Model(&models.Node{}).Pluck("mac_address", &macs)
+	known := make(map[string]bool, len(macs))
+	for _, m := range macs {
+		known[m] = true
+	}
+	blocked := blockedMacSet()
+	for mac, info := range edges {
+		if known[mac] || blocked[mac] {
+			continue
+		}
+		comm, err := lookupCommunity(info.Community)
+		if err != nil {
+			continue
+		}
+		ip := edgeReportedIP(info)
+		if validateNodeIP(ip, comm) != nil || len(findNodeConflicts(mac, ip)) > 0 {
+			continue
+		}
+		n := models.Node{Name: "edge-" + strings.ToLower(mac), MacAddress: mac, IPAddress: ip, Community: comm.Name,
+			Description: "自动发现于 " + strings.Split(info.External, ":")[0]}
+		applyCommunityDefaults(&n, comm, nil)
+		if err := createPendingNode(&n); err != nil {
+			log.Printf("Discovery: failed to register edge %s: %v", mac, err)
+			continue
+		}
+		log.Printf("Discovery: registered edge %s (%s) in community %s as pending node %d", mac, ip, comm.Name, n.ID)
+		notifyPendingNode(n)
+	}
+}
+
+// getPendingNodes 待审批的节点以及通过邀请链接提交、等待审批的注册申请
+func getPendingNodes(c *gin.Context) {
+	var nodes []models.Node
+	db.Where("pending_approval = ?", true).Order("id").Find(&nodes)
+	var regs []models.NodeRegistration
+	db.Where("status = ?", "pending").Order("id").Find(&regs)
+	c.JSON(200, gin.H{"nodes": nodes, "registrations": regs})
+}
+
+// pendingNode 查找待审批的节点
+func pendingNode(c *gin.Context) (models.Node, bool) {
+	var n models.Node
+	if err := db.First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return n, false
+	}
+	if !n.PendingApproval {
+		respondError(c, 409, ErrNodeNotPending, "Node is not pending approval")
+		return n, false
+	}
+	return n, true
+}
+
+// approveNode 批准节点并启用；已到期或 MAC 已封禁的节点批准后仍保持禁用
+func approveNode(c *gin.Context) {
+	n, ok := pendingNode(c)
+	if !ok {
+		return
+	}
+	n.PendingApproval = false
+	n.IsEnabled = !nodeExpired(n) && !isMacBlocked(n.MacAddress)
+	db.Model(&n).Updates(map[string]interface{}{"pending_approval": false, "is_enabled": n.IsEnabled})
+	if err := syncCommunityList(); err != nil {
+		log.Printf("Failed to sync community list after approving node %d: %v", n.ID, err)
+	}
+	recordAudit(c, "node.approve", n.Name, fmt.Sprintf("community %s, IP %s", n.Community, n.IPAddress))
+	notifyRequester(n, "node_approved", "info", "节点已通过审批: "+n.Name,
+		fmt.Sprintf("你在社区 %s 中创建的节点 %s (%s) 已由管理员批准，现在可以下载配置", n.Community, n.Name, n.IPAddress))
+	c.JSON(200, n)
+}
+
+// rejectNode 拒绝节点并移入回收站，block 为 true 时同时封禁其 MAC
+func rejectNode(c *gin.Context) {
+	n, ok := pendingNode(c)
+	if !ok {
+		return
+	}
+	var p struct {
+		Reason string `json:"reason" binding:"max=255"`
+		Block  bool   `json:"block"`
+	}
+	if !bindOptionalJSON(c, &p) {
+		return
+	}
+	p.Reason = strings.TrimSpace(p.Reason)
+	res := gin.H{"id": n.ID, "rejected": true}
+	if p.Block {
+		for k, v := range banEdge(n.MacAddress, p.Reason) {
+			res[k] = v
+		}
+	}
+	db.Delete(&n)
+	recordAudit(c, "node.reject", n.Name, p.Reason)
+	msg := fmt.Sprintf("你在社区 %s 中创建的节点 %s 未通过管理员审批", n.Community, n.Name)
+	if p.Reason != "" {
+		msg += "，原因：" + p.Reason
+	}
+	notifyRequester(n, "node_rejected", "warning", "节点未通过审批: "+n.Name, msg)
+	c.JSON(200, res)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxBulkNodes = 500
+
+// bulkResult 批量操作中单个节点的结果
+type bulkResult struct {
+	ID    uint        `json:"id"`
+	OK    bool        `json:"ok"`
+	Code  string      `json:"code,omitempty"`
+	Error string      `json:"error,omitempty"`
+	Node  interface{} `json:"node,omitempty"`
+}
+
+// bulkNodes 对多个节点执行同一操作，逐个返回结果，单个失败不影响其他节点
+// action: delete, disable, enable, move（参数 community）, set_encryption（参数 encryption）, regenerate_mac
+func bulkNodes(c *gin.Context) {
+	var p struct {
+		IDs        []uint `json:"ids" binding:"required,min=1"`
+		Action     string `json:"action" binding:"required,oneof=delete disable enable move set_encryption regenerate_mac"`
+		Community  string `json:"community"`
+		Encryption string `json:"encryption"`
+		Drop       bool   `json:"drop"` // disable 时同时从 supernode 移除注册
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	if len(p.IDs) > maxBulkNodes {
+		respondError(c, 400, ErrInvalidRequest, "Invalid node ID list")
+		return
+	}
+	var op func(n *models.Node) error
+	switch p.Action {
+	case "delete":
+		op = func(n *models.Node) error { return db.Delete(n).Error }
+	case "disable":
+		op = func(n *models.Node) error {
+			if err := db.Model(n).Update("is_enabled", false).Error; err != nil {
+				return err
+			}
+			if p.Drop {
+				dropEdgeRegistration(n.MacAddress, nodeInstanceID(*n), gin.H{})
+			}
+			return nil
+		}
+	case "enable":
+		op = func(n *models.Node) error {
+			if n.PendingApproval {
+				return newAPIError(ErrNodePending, "Node is pending approval")
+			}
+			if nodeExpired(*n) {
+				return newAPIError(ErrNodeExpired, "Node access has expired, extend the expiry time first")
+			}
+			if isMacBlocked(n.MacAddress) {
+				return newAPIError(ErrMacBlocked, "MAC address is blocked")
+			}
+			return db.Model(n).Update("is_enabled", true).Error
+		}
+	case "move":
+		comm, err := scopedCommunity(c, p.Community)
+		if err != nil {
+			respondErr(c, 400, err, ErrInvalidRequest)
+			return
+		}
+		op = func(n *models.Node) error { return moveNode(n, comm) }
+	case "set_encryption":
+		if err := validateEncryption(p.Encryption); err != nil || p.Encryption == "" {
+			respondError(c, 400, ErrInvalidRequest, "Unsupported encryption")
+			return
+		}
+		op = func(n *models.Node) error {
+			n.Encryption = p.Encryption
+			return saveBulkNode(n)
+		}
+	case "regenerate_mac":
+		op = func(n *models.Node) error {
+			mac, err := utils.RandomMAC()
+			if err != nil {
+				return err
+			}
+			n.MacAddress = mac.String()
+			return saveBulkNode(n)
+		}
+	default:
+		respondError(c, 400, ErrInvalidRequest, "Unknown action")
+		return
+	}
+
+	results := make([]bulkResult, 0, len(p.IDs))
+	succeeded := 0
+	for _, id := range p.IDs {
+		r := bulkResult{ID: id}
+		var n models.Node
+		if err := scopeNodes(c).First(&n, id).Error; err != nil {
+			r.Code, r.Error = ErrNodeNotFound, tr(c, "Node not found")
+		} else if err := op(&n); err != nil {
+			r.Code, r.Error = ErrInternal, err.Error()
+			var ae *apiError
+			if errors.As(err, &ae) {
+				r.Code, r.Error = ae.Code, localizeErr(c, ae)
+			}
+		} else {
+			r.OK = true
+			if p.Action != "delete" {
+				r.Node = n
+			}
+			succeeded++
+		}
+		results = append(results, r)
+	}
+	res := gin.H{"action": p.Action, "succeeded": succeeded, "failed": len(results) - succeeded, "results": results}
+	switch p.Action {
+	case "delete", "disable", "enable", "move":
+		// 认证公钥随节点的启用状态和所属社区写入 community.list
+		if succeeded > 0 {
+			if err := syncCommunityList(); err != nil {
+				res["warning"] = tr(c, "Failed to write community.list") + ": " + err.Error()
+			}
+		}
+	}
+	c.JSON(200, res)
+}
+
+// moveNode 将节点移到另一个社区，IP 或本地端口不符合新社区的网段/端口范围时重新分配
+func moveNode(n *models.Node, comm models.Community) error {
+	if n.Community == comm.Name {
+		return nil
+	}
+	n.Community = comm.Name
+	if n.IPAddress == "" || validateNodeIP(n.IPAddress, comm) != nil {
+		n.IPAddress = nextNodeIP(comm)
+	}
+	port, err := allocateLocalPort(comm, n.LocalPort, n.ID)
+	if err != nil {
+		if port, err = allocateLocalPort(comm, 0, n.ID); err != nil {
+			return err
+		}
+	}
+	n.LocalPort = port
+	return saveBulkNode(n)
+}
+
+// saveBulkNode 保存修改后的节点并刷新配置差异
+func saveBulkNode(n *models.Node) error {
+	for _, cf := range findNodeConflicts(n.MacAddress, n.IPAddress) {
+		if cf.Node.ID != n.ID {
+			return newAPIError(ErrNodeConflict, "MAC or IP address already used by another node")
+		}
+	}
+	refreshConfigState(n)
+	return db.Save(n).Error
+}
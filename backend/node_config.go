@@ -0,0 +1,116 @@
+package main
+
+import (
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultSupernodePort = "7654"
+
+// renderNodeConfig 根据节点及其社区、所属实例生成 edge 配置文件内容
+func renderNodeConfig(n models.Node) string {
+	var comm models.Community
+	db.Where("name = ?", n.Community).First(&comm)
+	return renderNodeConfigFor(n, comm)
+}
+
+// renderNodeConfigFor 按给定的社区生成配置，密码轮换时用于生成使用新密码的配置
+func renderNodeConfigFor(n models.Node, comm models.Community) string {
+	password := string(comm.Password)
+	if password == "" {
+		password = "password"
+	}
+	// supernode 地址优先级：社区覆盖 > 所属实例的公网地址 > 全局设置
+	supernode := instanceSupernodeAddr(runtimeFor(communityInstanceID(comm)))
+	if comm.SupernodeOverride != "" {
+		supernode = comm.SupernodeOverride
+	}
+	params := utils.ConfigParams{
+		Name: n.Name, IP: n.IPAddress, Community: n.Community, Password: password, Supernode: supernode, Mac: n.MacAddress,
+		Encryption: n.Encryption, Compression: n.Compression, Routing: n.Routing, LocalPort: n.LocalPort, MTU: comm.MTU,
+		ExtraArgs: edgeFlagLines(n.ExtraArgs), TapDevice: n.TapDevice, TTL: n.TTL,
+		Backups: backupSupernodes(supernode),
+	}
+	if n.MTU > 0 {
+		params.MTU = n.MTU
+	}
+	// 社区启用用户认证时带上节点的用户名、密码和 supernode 公钥；公钥无法计算时省略 -P，edge 会给出警告
+	if comm.UserAuth && n.AuthSecret != "" {
+		params.AuthUser, params.AuthPassword = n.AuthUser, string(n.AuthSecret)
+		params.SupernodeKey, _ = supernodeAuthKey()
+	}
+	return utils.GenerateConfFile(params)
+}
+
+// supernodeListenPort 实例 supernode.conf 中的监听端口（-p），未配置时为 n2n 默认的 7654
+func supernodeListenPort(rt *instanceRuntime) string {
+	if conf, err := utils.ReadSupernodeConfig(rt.instance.ConfigPath); err == nil && conf["p"] != "" {
+		// n2n 3.x 的 -p 也可以写成 绑定地址:端口
+		return conf["p"][strings.LastIndex(conf["p"], ":")+1:]
+	}
+	return defaultSupernodePort
+}
+
+// withSupernodePort 地址未带端口时补上实例的监听端口
+func withSupernodePort(addr string, rt *instanceRuntime) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	return net.JoinHostPort(strings.Trim(addr, "[]"), supernodeListenPort(rt))
+}
+
+// instanceSupernodeAddr 写入 edge 配置的实例地址：实例的公网地址，未设置时使用全局的 supernode_host
+func instanceSupernodeAddr(rt *instanceRuntime) string {
+	if rt != nil && rt.instance.PublicHost != "" {
+		return withSupernodePort(rt.instance.PublicHost, rt)
+	}
+	return getSettingValue("supernode_host", "")
+}
+
+// backupSupernodes 备用 supernode 地址，去掉与主地址相同的项
+func backupSupernodes(primary string) []string {
+	var res []string
+	for _, addr := range utils.SplitList(getSettingValue("supernode_backup_hosts", "")) {
+		if addr != primary {
+			res = append(res, addr)
+		}
+	}
+	return res
+}
+
+// refreshConfigState 对比已下发配置与当前配置，更新 n 的差异和过期标记（不写库）
+// 尚未下发过配置的节点没有基线，不视为过期
+func refreshConfigState(n *models.Node) {
+	if n.IssuedConfig == "" {
+		n.ConfigDiff, n.ConfigOutdated = "", false
+		return
+	}
+	n.ConfigDiff = utils.LineDiff(n.IssuedConfig, renderNodeConfig(*n))
+	n.ConfigOutdated = n.ConfigDiff != ""
+}
+
+// previewNodeConfig 预览修改后的配置差异，不保存
+func previewNodeConfig(c *gin.Context) {
+	n, ok := applyNodeUpdate(c)
+	if !ok {
+		return
+	}
+	refreshConfigState(&n)
+	c.JSON(200, gin.H{"conf": renderNodeConfig(n), "diff": n.ConfigDiff, "config_outdated": n.ConfigOutdated})
+}
+
+// confirmNodeConfig 确认当前配置已部署到 edge，将其作为新的基线
+func confirmNodeConfig(c *gin.Context) {
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	n.IssuedConfig, n.ConfigDiff, n.ConfigOutdated = renderNodeConfig(n), "", false
+	db.Model(&n).Updates(map[string]interface{}{"issued_config": n.IssuedConfig, "config_diff": "", "config_outdated": false})
+	c.JSON(200, n)
+}
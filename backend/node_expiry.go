@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"time"
+)
+
+const nodeExpiryCheckInterval = 1 * time.Minute
+
+// nodeExpired 判断节点是否已到期
+func nodeExpired(n models.Node) bool {
+	return n.ExpiresAt != nil && !n.ExpiresAt.After(time.Now())
+}
+
+// validateExpiry 新设置的到期时间必须晚于当前时间，未修改的到期时间不再校验
+func validateExpiry(expiresAt, previous *time.Time) error {
+	if expiresAt == nil || (previous != nil && expiresAt.Equal(*previous)) {
+		return nil
+	}
+	if !expiresAt.After(time.Now()) {
+		return newAPIError(ErrInvalidExpiry, "Expiry time must be in the future")
+	}
+	return nil
+}
+
+// expireNodes 处理到期的节点，设置 node_expiry_action=disable（默认）时自动禁用，flag 时只标记并告警
+func expireNodes(notified map[uint]bool) {
+	var nodes []models.Node
+	db.Where("is_enabled = ? AND expires_at IS NOT NULL AND expires_at <= ?", true, time.Now()).Find(&nodes)
+	disable := getSettingValue("node_expiry_action", "disable") == "disable"
+	changed := false
+	for _, n := range nodes {
+		if disable {
+			db.Model(&n).Update("is_enabled", false)
+			log.Printf("Node %d (%s) expired at %s, disabled", n.ID, n.Name, n.ExpiresAt.Format(time.RFC3339))
+		} else if notified[n.ID] {
+			continue
+		}
+		notified[n.ID] = true
+		changed = true
+		msg := fmt.Sprintf("节点 %s (%s) 的访问期限已于 %s 到期", n.Name, n.IPAddress, n.ExpiresAt.Format("2006-01-02 15:04"))
+		if disable {
+			msg += "，已自动禁用"
+		}
+		dispatchAlert(Alert{Type: "node_expired", Level: "warning", Title: "节点已到期: " + n.Name, Message: msg})
+	}
+	// 到期节点的认证公钥从 community.list 中移除（node_expiry_action=flag 时节点保持启用，同样移除）
+	if changed {
+		if err := syncCommunityList(); err != nil {
+			log.Printf("Failed to sync community list after node expiry: %v", err)
+		}
+	}
+}
+
+func startNodeExpiryMonitor() {
+	notified := make(map[uint]bool)
+	expireNodes(notified)
+	ticker := time.NewTicker(nodeExpiryCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		expireNodes(notified)
+	}
+}
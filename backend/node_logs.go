@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"n2n_ui/backend/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// edge 代理通过 POST /api/agent/logs 上报 edge 守护进程日志，使用节点的代理令牌认证：
+//   X-Agent-Token: <token>
+//   {"lines": [{"time": "2026-01-02T15:04:05Z", "line": "..."}]}
+// time 可省略，此时使用接收时间。
+
+const (
+	nodeLogPruneInterval = 1 * time.Hour
+	nodeLogMaxBatch      = 1000 // 单次上报最多行数
+	nodeLogMaxLineLen    = 2048 // 超长的行会被截断
+)
+
+// nodeLogLimits 返回日志保留天数和每个节点最多保留的行数，设置 node_log_retention_days / node_log_max_lines
+func nodeLogLimits() (time.Duration, int) {
+	days, err := strconv.Atoi(getSettingValue("node_log_retention_days", "7"))
+	if err != nil || days < 1 {
+		days = 7
+	}
+	maxLines, err := strconv.Atoi(getSettingValue("node_log_max_lines", "5000"))
+	if err != nil || maxLines < 1 {
+		maxLines = 5000
+	}
+	return time.Duration(days) * 24 * time.Hour, maxLines
+}
+
+func hashAgentToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createAgentToken 为节点生成新的代理令牌，旧令牌立即失效
+func createAgentToken(c *gin.Context) {
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	token := hex.EncodeToString(b)
+	db.Model(&n).Update("agent_token", hashAgentToken(token))
+	c.JSON(200, gin.H{"token": token})
+}
+
+// agentNode 根据请求头中的代理令牌查找节点
+func agentNode(c *gin.Context) (models.Node, bool) {
+	var n models.Node
+	token := c.GetHeader("X-Agent-Token")
+	if token == "" {
+		respondError(c, 401, ErrUnauthorized, "Invalid agent token")
+		return n, false
+	}
+	if err := db.Where("agent_token = ?", hashAgentToken(token)).First(&n).Error; err != nil {
+		respondError(c, 401, ErrUnauthorized, "Invalid agent token")
+		return n, false
+	}
+	return n, true
+}
+
+// ingestNodeLogs 接收 edge 代理上报的日志
+func ingestNodeLogs(c *gin.Context) {
+	n, ok := agentNode(c)
+	if !ok {
+		return
+	}
+	var p struct {
+		Lines []struct {
+			Time *time.Time `json:"time"`
+			Line string     `json:"line"`
+		} `json:"lines"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	if len(p.Lines) > nodeLogMaxBatch {
+		respondError(c, 400, ErrInvalidRequest, "Too many log lines")
+		return
+	}
+	now := time.Now()
+	entries := make([]models.NodeLog, 0, len(p.Lines))
+	for _, l := range p.Lines {
+		line := strings.TrimRight(l.Line, "\r\n")
+		if line == "" {
+			continue
+		}
+		if len(line) > nodeLogMaxLineLen {
+			line = line[:nodeLogMaxLineLen]
+		}
+		t := now
+		if l.Time != nil && !l.Time.IsZero() {
+			t = *l.Time
+		}
+		entries = append(entries, models.NodeLog{NodeID: n.ID, Time: t, Line: line})
+	}
+	if len(entries) > 0 {
+		if err := db.CreateInBatches(&entries, 200).Error; err != nil {
+			respondError(c, 500, ErrInternal, "Internal server error")
+			return
+		}
+		_, maxLines := nodeLogLimits()
+		trimNodeLogs(n.ID, maxLines)
+	}
+	c.JSON(200, gin.H{"accepted": len(entries)})
+}
+
+// trimNodeLogs 只保留节点最新的 maxLines 行
+func trimNodeLogs(nodeID uint, maxLines int) {
+	var cutoff models.NodeLog
+	db.Where("node_id = ?", nodeID).Order("id DESC").Offset(maxLines).Limit(1).Find(&cutoff)
+	if cutoff.ID != 0 {
+		db.Where("node_id = ? AND id <= ?", nodeID, cutoff.ID).Delete(&models.NodeLog{})
+	}
+}
+
+// getNodeLogs 按时间倒序返回节点日志，可用 ?grep= 过滤、?before= 按 ID 翻页
+func getNodeLogs(c *gin.Context) {
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "200"))
+	if err != nil || limit < 1 || limit > 1000 {
+		limit = 200
+	}
+	q := db.Where("node_id = ?", n.ID)
+	if v := c.Query("grep"); v != "" {
+		q = q.Where("line LIKE ?", "%"+v+"%")
+	}
+	if v := c.Query("before"); v != "" {
+		q = q.Where("id < ?", v)
+	}
+	var logs []models.NodeLog
+	q.Order("id DESC").Limit(limit).Find(&logs)
+	c.JSON(200, gin.H{"logs": logs, "has_agent": n.AgentToken != ""})
+}
+
+// pruneNodeLogs 清理超过保留期以及所属节点已被彻底删除的日志
+func pruneNodeLogs() {
+	retention, _ := nodeLogLimits()
+	old := db.Where("created_at < ?", time.Now().Add(-retention)).Delete(&models.NodeLog{})
+	orphan := db.Where("node_id NOT IN (?)", db.Unscoped().Model(&models.Node{}).Select("id")).Delete(&models.NodeLog{})
+	if old.RowsAffected > 0 || orphan.RowsAffected > 0 {
+		log.Printf("Pruned %d expired and %d orphaned edge log lines", old.RowsAffected, orphan.RowsAffected)
+	}
+}
+
+func startNodeLogPruner() {
+	pruneNodeLogs()
+	pruneSpeedTests()
+	ticker := time.NewTicker(nodeLogPruneInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		pruneNodeLogs()
+		pruneSpeedTests()
+	}
+}
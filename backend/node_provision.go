@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 节点创建在一个事务中完成：删除被替换的冲突节点、写入节点、社区启用用户认证时生成认证密钥，
+// 最后重写内容发生变化的 community.list。任一步失败时数据库回滚，已重写的 community.list 恢复原内容，
+// 脚本调用一次即可拿到可直接部署的 edge 配置，不会留下只完成一半的节点。
+
+// provisionedNode 创建节点的响应：节点字段之外附带 edge 配置和 supernode 侧的变更
+type provisionedNode struct {
+	models.Node
+	Conf             string   `json:"conf,omitempty"` // 待审批、禁用或已到期的节点不返回
+	SupernodeChanges []string `json:"supernode_changes"`
+}
+
+// communityListBackup 重写前的 community.list，事务提交失败时用于恢复
+type communityListBackup struct {
+	path    string
+	content []byte
+	existed bool
+}
+
+func (b communityListBackup) restore() {
+	var err error
+	if b.existed {
+		err = os.WriteFile(b.path, b.content, 0644)
+	} else {
+		err = os.Remove(b.path)
+	}
+	if err != nil {
+		log.Printf("Failed to restore %s after rollback: %v", b.path, err)
+	}
+}
+
+// writeChangedCommunityLists 按事务中的数据重写内容有变化的 community.list，返回变更说明与备份
+func writeChangedCommunityLists(tx *gorm.DB) ([]string, []communityListBackup, error) {
+	changes := make([]string, 0)
+	backups := make([]communityListBackup, 0)
+	for _, rt := range listRuntimes() {
+		lines := communityListLines(tx, rt.instance.ID)
+		path := rt.instance.CommunityListPath
+		old, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return changes, backups, fmt.Errorf("%s: %w", rt.instance.Name, err)
+		}
+		content := strings.Join(lines, "\n")
+		if content != "" {
+			content += "\n"
+		}
+		if err == nil && string(old) == content {
+			continue
+		}
+		backups = append(backups, communityListBackup{path: path, content: old, existed: err == nil})
+		if err := utils.WriteCommunityList(path, lines); err != nil {
+			return changes, backups, fmt.Errorf("%s: %w", rt.instance.Name, err)
+		}
+		changes = append(changes, fmt.Sprintf("%s: 已更新 %s", rt.instance.Name, path))
+	}
+	return changes, backups, nil
+}
+
+// provisionNode 在事务中创建节点，replace 为需要先删除的冲突节点，返回 supernode 侧的变更。
+// 待审批节点以禁用状态创建且不生成认证密钥，批准后由管理员生成
+func provisionNode(n *models.Node, replace []nodeConflict) ([]string, error) {
+	comm, err := lookupCommunity(n.Community)
+	if err != nil {
+		return nil, err
+	}
+	var changes []string
+	var backups []communityListBackup
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, cf := range replace {
+			log.Printf("Replacing node %d (%s, %s, %s) on create", cf.Node.ID, cf.Node.Name, cf.Node.MacAddress, cf.Node.IPAddress)
+			if err := tx.Unscoped().Delete(&models.Node{}, cf.Node.ID).Error; err != nil {
+				return err
+			}
+		}
+		// is_enabled 的数据库默认值为 true，创建时零值会被忽略并回填为 true，需在同一事务中改回
+		enabled := n.IsEnabled
+		if err := tx.Create(n).Error; err != nil {
+			return err
+		}
+		if !enabled {
+			n.IsEnabled = false
+			if err := tx.Model(n).Update("is_enabled", false).Error; err != nil {
+				return err
+			}
+		}
+		if comm.UserAuth && !n.PendingApproval {
+			if _, err := assignNodeAuthKey(tx, n); err != nil {
+				log.Printf("Key generation for node %s failed: %v", n.Name, err)
+				return newAPIError(ErrKeygenFailed, "Key generation failed, make sure n2n-keygen is installed")
+			}
+		}
+		var err error
+		changes, backups, err = writeChangedCommunityLists(tx)
+		if err != nil {
+			log.Printf("Failed to write community list for new node %s: %v", n.Name, err)
+			return newAPIError(ErrSupernodeSyncFailed, "Failed to write community.list")
+		}
+		return nil
+	})
+	if err != nil {
+		for _, b := range backups {
+			b.restore()
+		}
+		return nil, err
+	}
+	return changes, nil
+}
+
+// provisionedResponse 附带配置的创建结果，返回配置时记录为已下发的基线
+func provisionedResponse(n models.Node, changes []string) provisionedNode {
+	res := provisionedNode{Node: n, SupernodeChanges: changes}
+	if res.SupernodeChanges == nil {
+		res.SupernodeChanges = []string{}
+	}
+	if !n.PendingApproval && nodeAccessActive(n) {
+		res.Conf = renderNodeConfig(n)
+		db.Model(&n).Update("issued_config", res.Conf)
+	}
+	return res
+}
+
+// respondProvisionErr 创建失败时的响应：密钥生成、community.list 写入失败按错误码返回，数据库错误不返回细节
+func respondProvisionErr(c *gin.Context, err error) {
+	var ae *apiError
+	if errors.As(err, &ae) {
+		respondErr(c, 500, err, ErrInternal)
+		return
+	}
+	log.Printf("Failed to create node: %v", err)
+	respondError(c, 500, ErrInternal, "Failed to create node")
+}
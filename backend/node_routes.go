@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const routeCheckTimeout = 15 * time.Second
+
+// cidrsOverlap 判断两个网段是否有交集（网段要么包含要么不相交，检查双方的网络地址即可）
+func cidrsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// parseRouting 解析节点的 Routing 字段（网段:网关，如 192.168.1.0/24:10.10.10.5）
+func parseRouting(routing string) (*net.IPNet, net.IP, bool) {
+	slash := strings.Index(routing, "/")
+	if slash < 0 {
+		return nil, nil, false
+	}
+	colon := strings.Index(routing[slash:], ":")
+	if colon < 0 {
+		return nil, nil, false
+	}
+	_, ipnet, err := net.ParseCIDR(routing[:slash+colon])
+	gw := net.ParseIP(routing[slash+colon+1:])
+	if err != nil || gw == nil {
+		return nil, nil, false
+	}
+	return ipnet, gw, true
+}
+
+// routeEntry 路由总览中的一条路由：同一社区内网段和网关都相同的节点合并为一条
+type routeEntry struct {
+	Community     string   `json:"community"`
+	Network       string   `json:"network"`
+	Gateway       string   `json:"gateway"`
+	GatewayNodeID uint     `json:"gateway_node_id,omitempty"` // 持有网关 VPN 地址的节点
+	GatewayNode   string   `json:"gateway_node,omitempty"`
+	GatewayOnline bool     `json:"gateway_online"`
+	NodeIDs       []uint   `json:"node_ids"`       // 配置了该路由的节点
+	Overlaps      []string `json:"overlaps"`       // 与之重叠的其他路由（社区/网段 via 网关）
+	RangeOverlaps []string `json:"range_overlaps"` // 与之重叠的社区网段
+	Problems      []string `json:"problems"`
+
+	ipnet *net.IPNet
+}
+
+func (r *routeEntry) key() string {
+	return fmt.Sprintf("%s/%s via %s", r.Community, r.Network, r.Gateway)
+}
+
+// collectRoutes 汇总 nodes 中配置的路由，标记网关节点、重叠的路由和社区网段
+func collectRoutes(nodes []models.Node, comms []models.Community) []*routeEntry {
+	edges, _ := allEdgeInfo()
+	byIP := make(map[string]models.Node) // 社区/IP -> 节点
+	for _, n := range nodes {
+		byIP[n.Community+"/"+n.IPAddress] = n
+	}
+	index := make(map[string]*routeEntry)
+	routes := make([]*routeEntry, 0)
+	for _, n := range nodes {
+		ipnet, gw, ok := parseRouting(n.Routing)
+		if !ok {
+			continue
+		}
+		r := &routeEntry{Community: n.Community, Network: ipnet.String(), Gateway: gw.String(), ipnet: ipnet}
+		if existing, ok := index[r.key()]; ok {
+			existing.NodeIDs = append(existing.NodeIDs, n.ID)
+			continue
+		}
+		r.NodeIDs = []uint{n.ID}
+		r.Overlaps, r.RangeOverlaps, r.Problems = make([]string, 0), make([]string, 0), make([]string, 0)
+		if gwNode, ok := byIP[n.Community+"/"+r.Gateway]; ok {
+			r.GatewayNodeID, r.GatewayNode = gwNode.ID, gwNode.Name
+			_, r.GatewayOnline = edges[gwNode.MacAddress]
+			if !r.GatewayOnline {
+				r.Problems = append(r.Problems, "gateway node is offline")
+			}
+		} else {
+			r.Problems = append(r.Problems, "no node has the gateway address")
+		}
+		index[r.key()] = r
+		routes = append(routes, r)
+	}
+	for i, a := range routes {
+		for j, b := range routes {
+			if i != j && cidrsOverlap(a.ipnet, b.ipnet) {
+				a.Overlaps = append(a.Overlaps, b.key())
+			}
+		}
+		for _, comm := range comms {
+			if _, commNet, err := net.ParseCIDR(comm.Range); err == nil && cidrsOverlap(a.ipnet, commNet) {
+				a.RangeOverlaps = append(a.RangeOverlaps, comm.Name)
+			}
+		}
+		if len(a.Overlaps) > 0 {
+			a.Problems = append(a.Problems, "overlaps another route")
+		}
+		if len(a.RangeOverlaps) > 0 {
+			a.Problems = append(a.Problems, "overlaps a community range")
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].key() < routes[j].key() })
+	return routes
+}
+
+// getRoutes 列出全网配置的路由网段，标出网关节点的在线状态以及相互重叠的网段
+func getRoutes(c *gin.Context) {
+	var nodes []models.Node
+	scopeNodes(c).Find(&nodes)
+	var comms []models.Community
+	db.Find(&comms)
+	routes := collectRoutes(nodes, comms)
+	conflicts := 0
+	for _, r := range routes {
+		if len(r.Overlaps) > 0 || len(r.RangeOverlaps) > 0 {
+			conflicts++
+		}
+	}
+	c.JSON(200, gin.H{"routes": routes, "conflicts": conflicts})
+}
+
+// checkNodeRoute 实时检查节点路由：网关节点是否在线、网关 VPN 地址以及网段内的目标能否 ping 通
+// 目标默认为网段内第一个地址，可用 target 指定；ping 从面板主机发出，面板主机需已加入该 VPN 并配置了相同路由
+func checkNodeRoute(c *gin.Context) {
+	if appConfig.DisableNetTools {
+		respondError(c, 403, ErrToolsDisabled, "Network tools are disabled by the administrator")
+		return
+	}
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	ipnet, gw, ok := parseRouting(n.Routing)
+	if !ok {
+		respondError(c, 400, ErrInvalidRoute, "Node has no route configured")
+		return
+	}
+	var p struct {
+		Target string `json:"target" binding:"omitempty,ip"`
+	}
+	if !bindOptionalJSON(c, &p) {
+		return
+	}
+	target := p.Target
+	if target == "" {
+		first := make(net.IP, len(ipnet.IP))
+		copy(first, ipnet.IP)
+		first[len(first)-1]++
+		target = first.String()
+	}
+	if !ipnet.Contains(net.ParseIP(target)) {
+		respondError(c, 400, ErrInvalidTarget, "Target must be inside the route network")
+		return
+	}
+
+	var nodes []models.Node
+	db.Where("community = ?", n.Community).Find(&nodes)
+	routes := collectRoutes(nodes, nil)
+	var route *routeEntry
+	for _, r := range routes {
+		if r.Network == ipnet.String() && r.Gateway == gw.String() {
+			route = r
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), routeCheckTimeout)
+	defer cancel()
+	ping := func(addr string) gin.H {
+		res, err := runTool(ctx, toolRequest{Command: "ping", Target: addr, Count: 3}, func(string) {})
+		r := gin.H{"target": addr, "result": res}
+		if err != nil {
+			r["error"] = err.Error()
+		}
+		if pr, ok := res.(utils.PingResult); ok {
+			r["reachable"] = pr.Received > 0
+		}
+		return r
+	}
+	c.JSON(200, gin.H{
+		"network":         route.Network,
+		"gateway":         route.Gateway,
+		"gateway_node":    route.GatewayNode,
+		"gateway_node_id": route.GatewayNodeID,
+		"gateway_online":  route.GatewayOnline,
+		"gateway_ping":    ping(route.Gateway),
+		"target_ping":     ping(target),
+	})
+}
+
+// subnetOverlap 与待保存网段重叠的社区网段或节点路由
+type subnetOverlap struct {
+	Kind      string `json:"kind"` // community / route
+	Community string `json:"community"`
+	Network   string `json:"network"`
+	Gateway   string `json:"gateway,omitempty"`
+	NodeID    uint   `json:"node_id,omitempty"`
+	NodeName  string `json:"node_name,omitempty"`
+}
+
+// findSubnetOverlaps 查找与 ipnet 重叠的其他社区网段和节点路由
+// skipCommID / skipNodeID 为正在保存的社区或节点本身；检查路由时 community、gw 为其所属社区和网关，
+// 同一社区内网段和网关都相同的路由是同一条路由的多个使用者，不算重叠
+func findSubnetOverlaps(ipnet *net.IPNet, community, gw string, skipCommID, skipNodeID uint) []subnetOverlap {
+	res := make([]subnetOverlap, 0)
+	var comms []models.Community
+	db.Find(&comms)
+	for _, comm := range comms {
+		if comm.ID == skipCommID || comm.Name == community {
+			continue
+		}
+		if _, commNet, err := net.ParseCIDR(comm.Range); err == nil && cidrsOverlap(ipnet, commNet) {
+			res = append(res, subnetOverlap{Kind: "community", Community: comm.Name, Network: commNet.String()})
+		}
+	}
+	var nodes []models.Node
+	db.Where("routing <> ''").Find(&nodes)
+	for _, n := range nodes {
+		routeNet, routeGw, ok := parseRouting(n.Routing)
+		if !ok || n.ID == skipNodeID || !cidrsOverlap(ipnet, routeNet) {
+			continue
+		}
+		if gw != "" && n.Community == community && routeNet.String() == ipnet.String() && routeGw.String() == gw {
+			continue
+		}
+		res = append(res, subnetOverlap{Kind: "route", Community: n.Community, Network: routeNet.String(),
+			Gateway: routeGw.String(), NodeID: n.ID, NodeName: n.Name})
+	}
+	return res
+}
+
+// checkRouteOverlaps 保存节点路由前检查重叠，allow 为 true 时跳过；有重叠时返回 409 和重叠列表
+func checkRouteOverlaps(c *gin.Context, n models.Node, allow bool) bool {
+	ipnet, gw, ok := parseRouting(n.Routing)
+	if !ok || allow {
+		return true
+	}
+	if overlaps := findSubnetOverlaps(ipnet, n.Community, gw.String(), 0, n.ID); len(overlaps) > 0 {
+		respondError(c, 409, ErrSubnetOverlap, "Route network overlaps existing subnets", gin.H{"overlaps": overlaps})
+		return false
+	}
+	return true
+}
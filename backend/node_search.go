@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordLiveAttributes 把在线节点的公网地址、归属地和连接方式写入数据库，只在变化时更新
+func recordLiveAttributes(edges map[string]utils.EdgeInfo) {
+	if len(edges) == 0 {
+		return
+	}
+	relays := relaySources()
+	var nodes []models.Node
+	db.Find(&nodes)
+	for _, n := range nodes {
+		m := n.MacAddress
+		info, online := edges[m]
+		if !online {
+			continue
+		}
+		publicIP := strings.Split(info.External, ":")[0]
+		connType := "P2P"
+		if relays[m] {
+			connType = "Relay"
+		}
+		// 归属地查询失败时保留原记录（公网地址未变时）
+		location := n.LastLocation
+		if publicIP != n.LastExternalIP {
+			location = ""
+		}
+		if loc := getIPLocation(publicIP); loc.Country != "未知" {
+			location = fmt.Sprintf("%s %s (%s)", loc.Country, loc.City, loc.ISP)
+		}
+		if publicIP == n.LastExternalIP && location == n.LastLocation && connType == n.LastConnType {
+			continue
+		}
+		db.Model(&n).UpdateColumns(map[string]interface{}{
+			"last_external_ip": publicIP, "last_location": location, "last_conn_type": connType,
+		})
+	}
+}
+
+// searchTerms 把搜索词按空白拆分并转为小写，所有词都需匹配
+func searchTerms(q string) []string {
+	return strings.Fields(strings.ToLower(q))
+}
+
+// nodeMatchesSearch 判断节点列表项是否匹配全部搜索词，每个词可匹配任意文本字段
+// （名称、IP、MAC、社区、标签、公网地址、归属地、连接方式及最近一次记录的值等）
+func nodeMatchesSearch(item gin.H, terms []string) bool {
+	var text strings.Builder
+	for _, v := range item {
+		if s, ok := v.(string); ok {
+			text.WriteString(strings.ToLower(s))
+			text.WriteByte('\n')
+		}
+	}
+	haystack := text.String()
+	for _, t := range terms {
+		if !strings.Contains(haystack, t) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"n2n_ui/backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// formatAlertText 聊天机器人使用的纯文本告警格式
+func formatAlertText(a Alert) string {
+	return fmt.Sprintf("[n2n-admin][%s] %s\n%s\n时间: %s", strings.ToUpper(a.Level), a.Title, a.Message, a.Time.Format("2006-01-02 15:04:05"))
+}
+
+// alertRouted 判断某类事件是否需要发送到指定渠道
+// 路由规则读取设置 notify_routes_<渠道>，逗号分隔的事件类型列表，* 表示全部（默认）
+func alertRouted(channel, eventType string) bool {
+	if eventType == "test" {
+		return true
+	}
+	for _, r := range utils.SplitList(getSettingValue("notify_routes_"+channel, "*")) {
+		if r == "*" || r == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type telegramNotifier struct{}
+
+func (t *telegramNotifier) Name() string { return "telegram" }
+
+func (t *telegramNotifier) Enabled() bool {
+	return getSettingValue("telegram_enabled", "false") == "true"
+}
+
+func (t *telegramNotifier) Send(a Alert) error {
+	return utils.SendTelegram(getSettingValue("telegram_bot_token", ""), getSettingValue("telegram_chat_id", ""), formatAlertText(a))
+}
+
+type dingtalkNotifier struct{}
+
+func (d *dingtalkNotifier) Name() string { return "dingtalk" }
+
+func (d *dingtalkNotifier) Enabled() bool {
+	return getSettingValue("dingtalk_enabled", "false") == "true"
+}
+
+func (d *dingtalkNotifier) Send(a Alert) error {
+	return utils.SendDingTalk(getSettingValue("dingtalk_webhook", ""), getSettingValue("dingtalk_secret", ""), formatAlertText(a))
+}
+
+type wecomNotifier struct{}
+
+func (w *wecomNotifier) Name() string { return "wecom" }
+
+func (w *wecomNotifier) Enabled() bool {
+	return getSettingValue("wecom_enabled", "false") == "true"
+}
+
+func (w *wecomNotifier) Send(a Alert) error {
+	return utils.SendWeCom(getSettingValue("wecom_webhook", ""), formatAlertText(a))
+}
+
+// findNotifier 按名称查找通知渠道
+func findNotifier(name string) Notifier {
+	for _, n := range notifiers {
+		if n.Name() == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// testNotifier 向指定渠道发送测试消息（无论是否启用）
+func testNotifier(c *gin.Context) {
+	n := findNotifier(c.Param("channel"))
+	if n == nil {
+		respondError(c, 404, ErrUnknownChannel, "Unknown channel")
+		return
+	}
+	a := Alert{Type: "test", Level: "info", Title: "测试通知", Message: "这是一条来自 n2n-admin 的测试通知。", Time: time.Now()}
+	if err := n.Send(a); err != nil {
+		respondError(c, 500, ErrNotifyFailed, "Send failed: "+err.Error())
+		return
+	}
+	c.JSON(200, gin.H{"message": "sent"})
+}
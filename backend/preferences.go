@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"n2n_ui/backend/models"
+	"time"
+	_ "time/tzdata" // Windows 等没有系统时区数据库时也能校验时区名
+
+	"github.com/gin-gonic/gin"
+)
+
+// 用户偏好（时区、界面语言、仪表盘布局）保存在服务端，换浏览器或清理 localStorage 后仍然有效。
+// 设置了语言的用户，接口消息也按该语言返回（见 requestLang）。
+
+const maxDashboardLayoutSize = 16 * 1024
+
+// preferencesResponse 返回给前端的偏好，dashboard_layout 原样返回保存的 JSON
+type preferencesResponse struct {
+	models.UserPreference
+	DashboardLayout json.RawMessage `json:"dashboard_layout"`
+}
+
+func preferencesOf(p models.UserPreference) preferencesResponse {
+	res := preferencesResponse{UserPreference: p, DashboardLayout: json.RawMessage("null")}
+	if p.DashboardLayout != "" {
+		res.DashboardLayout = json.RawMessage(p.DashboardLayout)
+	}
+	return res
+}
+
+// userPreference 读取用户偏好，尚未保存过时返回空值
+func userPreference(userID uint) models.UserPreference {
+	p := models.UserPreference{UserID: userID}
+	db.Where("user_id = ?", userID).First(&p)
+	return p
+}
+
+func getPreferences(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		respondError(c, 401, ErrInvalidToken, "Invalid token")
+		return
+	}
+	c.JSON(200, preferencesOf(userPreference(user.ID)))
+}
+
+// updatePreferences 只修改请求中出现的字段，字段设为空字符串 / null 表示恢复默认
+func updatePreferences(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		respondError(c, 401, ErrInvalidToken, "Invalid token")
+		return
+	}
+	var p struct {
+		Timezone        *string         `json:"timezone"`
+		Language        *string         `json:"language"`
+		DashboardLayout json.RawMessage `json:"dashboard_layout"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	pref := userPreference(user.ID)
+	if p.Timezone != nil {
+		if *p.Timezone != "" {
+			if _, err := time.LoadLocation(*p.Timezone); err != nil {
+				respondError(c, 400, ErrInvalidRequest, "Invalid timezone")
+				return
+			}
+		}
+		pref.Timezone = *p.Timezone
+	}
+	if p.Language != nil {
+		pref.Language = matchLang(*p.Language)
+		if *p.Language != "" && pref.Language == "" {
+			respondError(c, 400, ErrInvalidRequest, "Unsupported language")
+			return
+		}
+	}
+	if p.DashboardLayout != nil {
+		if len(p.DashboardLayout) > maxDashboardLayoutSize {
+			respondError(c, 400, ErrInvalidRequest, "Dashboard layout is too large")
+			return
+		}
+		pref.DashboardLayout = string(p.DashboardLayout)
+		if pref.DashboardLayout == "null" {
+			pref.DashboardLayout = ""
+		}
+	}
+	if err := db.Save(&pref).Error; err != nil {
+		respondError(c, 500, ErrInternal, "Failed to save preferences")
+		return
+	}
+	c.JSON(200, preferencesOf(pref))
+}
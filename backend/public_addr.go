@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 公网地址检测：通过 STUN（失败时用 HTTP 查询服务）得到面板主机的公网 IP，与 supernode_host 解析出的地址对比。
+// supernode_host 填错（内网地址、旧 IP、解析到别处的域名）是新用户最常见的配置问题，edge 会一直连不上。
+// 启动时及之后每 6 小时检测一次：未设置 supernode_host 时按 supernode_host_autofill 自动填写，不一致时记录警告，
+// 并在 /api/health 中报告 degraded。面板与 supernode 不在同一台主机时请关闭 public_ip_detection。
+
+const (
+	publicAddressInterval = 6 * time.Hour
+	publicAddressTimeout  = 3 * time.Second
+)
+
+// publicAddressReport 一次检测的结果
+type publicAddressReport struct {
+	DetectedIP    string    `json:"detected_ip"`
+	Source        string    `json:"source"`                   // 得到地址的 STUN 服务器或 HTTP 地址
+	Suggested     string    `json:"suggested"`                // 建议的 supernode_host
+	Configured    string    `json:"configured"`               // 当前的 supernode_host
+	ConfiguredIPs []string  `json:"configured_ips,omitempty"` // supernode_host 解析出的地址
+	Matches       bool      `json:"matches"`
+	Warning       string    `json:"warning,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	CheckedAt     time.Time `json:"checked_at"`
+}
+
+var (
+	lastPublicAddress  *publicAddressReport
+	publicAddressMutex sync.Mutex
+)
+
+// detectPublicIP 依次尝试各 STUN 服务器，都失败时使用 HTTP 查询服务
+func detectPublicIP() (net.IP, string, error) {
+	var lastErr error
+	for _, server := range utils.SplitList(getSettingValue("public_ip_stun_servers", "stun.l.google.com:19302,stun.cloudflare.com:3478")) {
+		start := time.Now()
+		ip, err := utils.StunPublicIP(server, publicAddressTimeout)
+		recordDependency("stun", time.Since(start), err)
+		if err == nil {
+			return ip, "stun:" + server, nil
+		}
+		lastErr = err
+	}
+	if url := getSettingValue("public_ip_check_url", "https://api.ipify.org"); url != "" {
+		start := time.Now()
+		ip, err := utils.HTTPPublicIP(url, publicAddressTimeout)
+		recordDependency("public_ip", time.Since(start), err)
+		if err == nil {
+			return ip, url, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no STUN server or check URL configured")
+	}
+	return nil, "", lastErr
+}
+
+// suggestedSupernodeHost 检测到的 IP 加上端口：沿用 supernode_host 中的端口，未设置时取默认实例的监听端口
+func suggestedSupernodeHost(ip net.IP, configured string) string {
+	if _, port, err := net.SplitHostPort(configured); err == nil {
+		return net.JoinHostPort(ip.String(), port)
+	}
+	if rt := runtimeFor(0); rt != nil {
+		return withSupernodePort(ip.String(), rt)
+	}
+	return net.JoinHostPort(ip.String(), defaultSupernodePort)
+}
+
+// checkPublicAddress 检测公网地址并与 supernode_host 对比，结果同时缓存供健康检查使用
+func checkPublicAddress(ctx context.Context) publicAddressReport {
+	r := publicAddressReport{Configured: getSettingValue("supernode_host", ""), CheckedAt: time.Now()}
+	ip, source, err := detectPublicIP()
+	if err != nil {
+		r.Error = err.Error()
+	} else {
+		r.DetectedIP, r.Source = ip.String(), source
+		r.Suggested = suggestedSupernodeHost(ip, r.Configured)
+		if r.Configured == "" {
+			r.Warning = "supernode_host is not set"
+		} else if host, _, err := net.SplitHostPort(r.Configured); err == nil {
+			ctx, cancel := context.WithTimeout(ctx, publicAddressTimeout)
+			addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+			cancel()
+			for _, a := range addrs {
+				r.ConfiguredIPs = append(r.ConfiguredIPs, a.IP.String())
+				r.Matches = r.Matches || a.IP.Equal(ip)
+			}
+			switch {
+			case err != nil:
+				r.Warning = "supernode_host does not resolve: " + err.Error()
+			case !r.Matches:
+				r.Warning = "supernode_host does not point to the detected public IP " + r.DetectedIP
+			}
+		}
+	}
+	publicAddressMutex.Lock()
+	lastPublicAddress = &r
+	publicAddressMutex.Unlock()
+	return r
+}
+
+// applySupernodeHost 把检测结果中建议的地址写入 supernode_host 并更新缓存的结果，已下发的配置标记为过期
+func applySupernodeHost(r *publicAddressReport) error {
+	if err := db.Where("key = ?", "supernode_host").Assign(models.Setting{Value: encryptSettingValue("supernode_host", r.Suggested)}).FirstOrCreate(&models.Setting{Key: "supernode_host"}).Error; err != nil {
+		return err
+	}
+	refreshAllConfigs()
+	r.Configured, r.ConfiguredIPs, r.Matches, r.Warning = r.Suggested, []string{r.DetectedIP}, true, ""
+	publicAddressMutex.Lock()
+	lastPublicAddress = r
+	publicAddressMutex.Unlock()
+	return nil
+}
+
+// startPublicAddressCheck 启动时及之后定期检测公网地址，需 public_ip_detection=true
+func startPublicAddressCheck() {
+	ticker := time.NewTicker(publicAddressInterval)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		if getSettingValue("public_ip_detection", "true") != "true" {
+			continue
+		}
+		r := checkPublicAddress(context.Background())
+		switch {
+		case r.Error != "":
+			log.Printf("Public address detection failed: %s", r.Error)
+		case r.Configured == "" && getSettingValue("supernode_host_autofill", "true") == "true":
+			if err := applySupernodeHost(&r); err != nil {
+				log.Printf("Failed to save detected supernode_host: %v", err)
+			} else {
+				log.Printf("supernode_host was not set, filled in detected public address %s", r.Configured)
+			}
+		case r.Warning != "":
+			log.Printf("Warning: %s (suggested: %s)", r.Warning, r.Suggested)
+		}
+	}
+}
+
+// publicAddressHealth 最近一次检测发现 supernode_host 与公网地址不一致时降级，尚未检测时不报告
+func publicAddressHealth() (healthCheck, bool) {
+	publicAddressMutex.Lock()
+	defer publicAddressMutex.Unlock()
+	r := lastPublicAddress
+	switch {
+	case r == nil:
+		return healthCheck{}, false
+	case r.Error != "":
+		return healthCheck{Status: healthUnknown, Message: r.Error, Detail: r}, true
+	case r.Warning != "":
+		return healthCheck{Status: healthDegraded, Message: r.Warning, Detail: r}, true
+	}
+	return healthCheck{Status: healthOK, Detail: r}, true
+}
+
+// getPublicAddress 返回公网地址检测结果，refresh=true 或尚未检测时重新检测
+func getPublicAddress(c *gin.Context) {
+	publicAddressMutex.Lock()
+	r := lastPublicAddress
+	publicAddressMutex.Unlock()
+	if r == nil || c.Query("refresh") == "true" {
+		fresh := checkPublicAddress(c.Request.Context())
+		r = &fresh
+	}
+	c.JSON(200, r)
+}
+
+// applyPublicAddress 重新检测并将建议的地址写入 supernode_host
+func applyPublicAddress(c *gin.Context) {
+	r := checkPublicAddress(c.Request.Context())
+	if r.Error != "" {
+		respondError(c, 502, ErrPublicIPUnavailable, "Failed to detect public address", gin.H{"detail": r.Error})
+		return
+	}
+	if err := applySupernodeHost(&r); err != nil {
+		respondError(c, 500, ErrInternal, "Failed to save settings")
+		return
+	}
+	c.JSON(200, r)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+	"n2n_ui/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 中转表只保存在内存中，重启后要等到 supernode 日志里再次出现转发记录，所有节点才会重新显示为 Relay。
+// 这里定期把中转表写入数据库，启动时（停机不超过 30 分钟）恢复停机前不久仍活跃的记录，
+// 它们在一个活跃窗口内被视为中转，之后由新的日志确认或自然过期。
+
+const (
+	relaySnapshotInterval = 30 * time.Second
+	relayRestoreMaxAge    = 5 * time.Minute  // 只恢复快照前这段时间内活跃过的记录
+	relayRestoreMaxGap    = 30 * time.Minute // 停机超过这段时间时快照已过时，不再恢复
+)
+
+// saveRelaySnapshot 用当前中转表替换数据库中的快照
+func saveRelaySnapshot() error {
+	relayMutex.Lock()
+	rows := make([]models.RelayState, 0, len(relayMap))
+	for _, ev := range relayMap {
+		rows = append(rows, models.RelayState{SrcMac: ev.SrcMac, DstMac: ev.DstMac, LastActive: ev.LastActive, PktCount: ev.PktCount})
+	}
+	relayMutex.Unlock()
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.RelayState{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rows, 100).Error
+	})
+}
+
+// restoreRelaySnapshot 启动时恢复中转表，恢复的记录以当前时间作为最近活跃时间
+func restoreRelaySnapshot() {
+	var rows []models.RelayState
+	db.Find(&rows)
+	var newest time.Time
+	for _, r := range rows {
+		if r.LastActive.After(newest) {
+			newest = r.LastActive
+		}
+	}
+	now := time.Now()
+	if now.Sub(newest) > relayRestoreMaxGap {
+		return
+	}
+	restored := 0
+	relayMutex.Lock()
+	for _, r := range rows {
+		if newest.Sub(r.LastActive) > relayRestoreMaxAge {
+			continue
+		}
+		key := r.SrcMac + "->" + r.DstMac
+		if _, ok := relayMap[key]; !ok {
+			relayMap[key] = &RelayEvent{SrcMac: r.SrcMac, DstMac: r.DstMac, LastActive: now, PktCount: r.PktCount}
+			restored++
+		}
+	}
+	relayMutex.Unlock()
+	if restored > 0 {
+		log.Printf("Restored %d relay connections from the last snapshot", restored)
+	}
+}
+
+// startRelaySnapshot 定期保存中转表
+func startRelaySnapshot() {
+	ticker := time.NewTicker(relaySnapshotInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := saveRelaySnapshot(); err != nil {
+			log.Printf("Failed to save relay snapshot: %v", err)
+		}
+	}
+}
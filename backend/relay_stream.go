@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	relayStreamThrottle  = 1 * time.Second  // 同一对节点的事件最少间隔
+	relayStreamHeartbeat = 5 * time.Second  // 心跳及过期检查间隔
+	relayStreamNameTTL   = 30 * time.Second // 节点名称缓存时长
+	relayActiveWindow    = 60 * time.Second // 与 collectActiveRelays 一致
+)
+
+// relayUpdate 日志分析器解析出的一条中转事件
+type relayUpdate struct {
+	RelayEvent
+	New bool `json:"new"` // 该节点对在活跃窗口内首次出现
+}
+
+var (
+	relaySubscribers = make(map[chan relayUpdate]bool)
+	relaySubMutex    sync.Mutex
+)
+
+// publishRelayEvent 将事件推送给所有订阅者，订阅者处理不过来时丢弃
+func publishRelayEvent(ev RelayEvent, isNew bool) {
+	relaySubMutex.Lock()
+	defer relaySubMutex.Unlock()
+	for ch := range relaySubscribers {
+		select {
+		case ch <- relayUpdate{RelayEvent: ev, New: isNew}:
+		default:
+		}
+	}
+}
+
+func subscribeRelays() chan relayUpdate {
+	ch := make(chan relayUpdate, 64)
+	relaySubMutex.Lock()
+	relaySubscribers[ch] = true
+	relaySubMutex.Unlock()
+	return ch
+}
+
+func unsubscribeRelays(ch chan relayUpdate) {
+	relaySubMutex.Lock()
+	delete(relaySubscribers, ch)
+	relaySubMutex.Unlock()
+}
+
+// nodeNamesByMac 返回 MAC -> 节点名称
+func nodeNamesByMac() map[string]string {
+	var nodes []models.Node
+	db.Select("name", "mac_address").Find(&nodes)
+	names := make(map[string]string, len(nodes))
+	for _, n := range nodes {
+		names[n.MacAddress] = n.Name
+	}
+	return names
+}
+
+type relayStreamEvent struct {
+	RelayEvent
+	SrcName string `json:"src_name"`
+	DstName string `json:"dst_name"`
+	New     bool   `json:"new,omitempty"`
+}
+
+// streamRelays 以 SSE 推送实时中转事件：
+// snapshot - 连接时的当前活跃列表；relay - 新的中转报文（同一节点对最多每秒一次）；expired - 节点对超过 60 秒无中转
+// 可用 ?mac= 只关注与某个节点相关的事件
+func streamRelays(c *gin.Context) {
+	filter := utils.NormalizeMac(c.Query("mac"))
+	match := func(ev RelayEvent) bool {
+		return filter == "" || ev.SrcMac == filter || ev.DstMac == filter
+	}
+
+	names, namesAt := nodeNamesByMac(), time.Now()
+	named := func(ev RelayEvent, isNew bool) relayStreamEvent {
+		if time.Since(namesAt) > relayStreamNameTTL {
+			names, namesAt = nodeNamesByMac(), time.Now()
+		}
+		return relayStreamEvent{RelayEvent: ev, SrcName: names[ev.SrcMac], DstName: names[ev.DstMac], New: isNew}
+	}
+	send := func(event string, v interface{}) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, data)
+		c.Writer.Flush()
+	}
+
+	ch := subscribeRelays()
+	defer unsubscribeRelays(ch)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	active := make(map[string]time.Time) // 节点对 -> 最近一次活跃
+	sentAt := make(map[string]time.Time) // 节点对 -> 最近一次推送
+	snapshot := make([]relayStreamEvent, 0)
+	for _, ev := range collectActiveRelays() {
+		if match(ev) {
+			active[ev.SrcMac+"->"+ev.DstMac] = ev.LastActive
+			snapshot = append(snapshot, named(ev, false))
+		}
+	}
+	send("snapshot", snapshot)
+
+	ticker := time.NewTicker(relayStreamHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case u := <-ch:
+			if !match(u.RelayEvent) {
+				continue
+			}
+			key := u.SrcMac + "->" + u.DstMac
+			_, known := active[key]
+			active[key] = u.LastActive
+			if known && !u.New && time.Since(sentAt[key]) < relayStreamThrottle {
+				continue
+			}
+			sentAt[key] = time.Now()
+			send("relay", named(u.RelayEvent, u.New || !known))
+		case <-ticker.C:
+			for key, last := range active {
+				if time.Since(last) >= relayActiveWindow {
+					parts := strings.SplitN(key, "->", 2)
+					send("expired", named(RelayEvent{SrcMac: parts[0], DstMac: parts[1], LastActive: last}, false))
+					delete(active, key)
+					delete(sentAt, key)
+				}
+			}
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"log"
+	"n2n_ui/backend/utils"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 请求统计：按路由模板（如 GET /api/nodes/:id）累计请求数、4xx/5xx 数和耗时，
+// 同时记录管理端口查询和 IP 归属地查询等外部依赖的耗时，用于判断界面变慢的原因。
+// 统计只保存在内存中，重启或调用 reset 后清零；p95 基于每项最近 statsSampleSize 个样本。
+// 设置 N2N_ACCESS_LOG=true 时每个请求额外输出一行访问日志（不含查询参数，避免记录 token）。
+
+const statsSampleSize = 1024
+
+// latencyStats 一项统计的累计值和最近的耗时样本
+type latencyStats struct {
+	count   int64
+	errors  int64 // 依赖查询失败数；路由统计中为 5xx 数
+	client  int64 // 路由统计中的 4xx 数
+	total   time.Duration
+	max     time.Duration
+	samples []time.Duration
+	next    int
+}
+
+func (s *latencyStats) add(d time.Duration) {
+	s.count++
+	s.total += d
+	if d > s.max {
+		s.max = d
+	}
+	if len(s.samples) < statsSampleSize {
+		s.samples = append(s.samples, d)
+		return
+	}
+	s.samples[s.next] = d
+	s.next = (s.next + 1) % statsSampleSize
+}
+
+func (s *latencyStats) p95() time.Duration {
+	if len(s.samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), s.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)*95+99)/100-1]
+}
+
+type statsSummary struct {
+	Name      string  `json:"name"`
+	Count     int64   `json:"count"`
+	Errors4xx int64   `json:"errors_4xx,omitempty"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	AvgMs     float64 `json:"avg_ms"`
+	P95Ms     float64 `json:"p95_ms"`
+	MaxMs     float64 `json:"max_ms"`
+	TotalMs   float64 `json:"total_ms"`
+}
+
+func (s *latencyStats) summary(name string) statsSummary {
+	ms := func(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
+	sum := statsSummary{Name: name, Count: s.count, Errors4xx: s.client, Errors: s.errors, MaxMs: ms(s.max), TotalMs: ms(s.total), P95Ms: ms(s.p95())}
+	if s.count > 0 {
+		sum.ErrorRate = float64(s.errors) / float64(s.count)
+		sum.AvgMs = ms(s.total / time.Duration(s.count))
+	}
+	return sum
+}
+
+var (
+	routeStats      = make(map[string]*latencyStats)
+	dependencyStats = make(map[string]*latencyStats)
+	statsSince      = time.Now()
+	statsMutex      sync.Mutex
+)
+
+func init() {
+	utils.QueryObserver = func(op string, d time.Duration, err error) {
+		recordDependency("mgmt:"+op, d, err)
+	}
+}
+
+// recordDependency 记录一次外部依赖调用
+func recordDependency(name string, d time.Duration, err error) {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	s, ok := dependencyStats[name]
+	if !ok {
+		s = &latencyStats{}
+		dependencyStats[name] = s
+	}
+	s.add(d)
+	if err != nil {
+		s.errors++
+	}
+}
+
+// statsRouteName 未匹配路由的请求按前端文件和未知接口归为两类，避免每个路径单独成项
+func statsRouteName(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return c.Request.Method + " " + route
+	}
+	if strings.Contains(c.Request.URL.Path, "/api/") {
+		return c.Request.Method + " (unmatched api)"
+	}
+	return c.Request.Method + " (frontend)"
+}
+
+// requestStatsMiddleware 统计每个请求的耗时，需在 Recovery 之前注册以便统计到 panic 产生的 500
+// SSE 等长连接不计入耗时统计
+func requestStatsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		d := time.Since(start)
+		status := c.Writer.Status()
+		if appConfig.AccessLog {
+			user, _ := c.Get("username")
+			if user == nil {
+				user = "-"
+			}
+			log.Printf("[access] %s %s %d %s %s %v", c.Request.Method, c.Request.URL.Path, status, d.Round(time.Microsecond), c.ClientIP(), user)
+		}
+		if strings.HasPrefix(c.Writer.Header().Get("Content-Type"), "text/event-stream") {
+			return
+		}
+		name := statsRouteName(c)
+		statsMutex.Lock()
+		defer statsMutex.Unlock()
+		s, ok := routeStats[name]
+		if !ok {
+			s = &latencyStats{}
+			routeStats[name] = s
+		}
+		s.add(d)
+		switch {
+		case status >= 500:
+			s.errors++
+		case status >= 400:
+			s.client++
+		}
+	}
+}
+
+// sortedSummaries 按累计耗时从高到低排列，最拖慢界面的项排在前面
+func sortedSummaries(m map[string]*latencyStats) []statsSummary {
+	res := make([]statsSummary, 0, len(m))
+	for name, s := range m {
+		res = append(res, s.summary(name))
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].TotalMs != res[j].TotalMs {
+			return res[i].TotalMs > res[j].TotalMs
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// getRequestStats 返回各路由和外部依赖的请求数、错误率和耗时
+func getRequestStats(c *gin.Context) {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	c.JSON(200, gin.H{
+		"since":        statsSince,
+		"routes":       sortedSummaries(routeStats),
+		"dependencies": sortedSummaries(dependencyStats),
+	})
+}
+
+// resetRequestStats 清空统计
+func resetRequestStats(c *gin.Context) {
+	statsMutex.Lock()
+	routeStats = make(map[string]*latencyStats)
+	dependencyStats = make(map[string]*latencyStats)
+	statsSince = time.Now()
+	statsMutex.Unlock()
+	c.JSON(200, gin.H{"message": tr(c, "Statistics reset")})
+}
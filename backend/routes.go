@@ -0,0 +1,189 @@
+package main
+
+import "github.com/gin-gonic/gin"
+
+// registerAPI 注册全部 API 路由，/api 与 /api/v2 共用同一套处理函数
+func registerAPI(api *gin.RouterGroup) {
+	api.Use(maintenanceMiddleware(api.BasePath()))
+	api.GET("/health", getHealth)
+	api.GET("/version", getVersion)
+	api.GET("/status", getPublicStatus)
+	api.GET("/invite/:token", getInvite)
+	api.POST("/invite/:token", registerWithInvite)
+	api.GET("/invite/:token/registrations/:id", getRegistrationStatus)
+	api.POST("/login", login)
+	api.GET("/login/captcha", getLoginCaptcha)
+	api.POST("/refresh", refreshToken)
+	api.GET("/setup", getSetupStatus)
+	api.POST("/setup", runSetup)
+	api.POST("/agent/logs", ingestNodeLogs)
+	api.GET("/agent/speedtest", pollSpeedTest)
+	api.POST("/agent/speedtest/:id", reportSpeedTest)
+	api.GET("/agent/config", pollAgentConfig)
+	api.POST("/agent/config/ack", ackAgentConfig)
+	protected := api.Group("/")
+	protected.Use(jwtMiddleware(), scopeMiddleware())
+	{
+		protected.GET("/nodes", getNodes)
+		protected.POST("/nodes", createNode)
+		protected.POST("/nodes/bulk", bulkNodes)
+		protected.PUT("/nodes/:id", updateNode)
+		protected.DELETE("/nodes/:id", deleteNode)
+		protected.POST("/nodes/:id/disable", disableNode)
+		protected.POST("/nodes/:id/enable", enableNode)
+		protected.POST("/nodes/:id/probe", probeNode)
+		protected.GET("/nodes/:id/config", getNodeConfig)
+		protected.GET("/nodes/:id/logs", getNodeLogs)
+		protected.GET("/nodes/:id/availability", getNodeAvailability)
+		protected.GET("/nodes/:id/speedtest", getSpeedTests)
+		protected.POST("/nodes/:id/speedtest", startSpeedTest)
+		protected.POST("/nodes/:id/agent-token", createAgentToken)
+		protected.GET("/nodes/outdated-configs", getOutdatedConfigs)
+		protected.POST("/nodes/:id/auth-key", generateNodeAuthKey)
+		protected.DELETE("/nodes/:id/auth-key", deleteNodeAuthKey)
+		protected.POST("/nodes/:id/config/preview", previewNodeConfig)
+		protected.POST("/nodes/:id/config/confirm", confirmNodeConfig)
+		protected.GET("/stats", getStats)
+		protected.GET("/dashboard", getDashboard)
+		protected.POST("/validate", validateField)
+		protected.GET("/communities", getCommunities)
+		protected.GET("/communities/:id/summary", getCommunitySummary)
+		protected.GET("/topology", getTopology)
+		protected.GET("/routes", getRoutes)
+		protected.POST("/nodes/:id/route-check", checkNodeRoute)
+		protected.POST("/change-password", changePassword)
+		protected.GET("/me/preferences", getPreferences)
+		protected.PUT("/me/preferences", updatePreferences)
+		protected.GET("/me", getProfile)
+		protected.GET("/maintenance", getMaintenance)
+		protected.PUT("/me/username", changeUsername)
+		protected.GET("/me/sessions", listOwnSessions(sessionKindLogin))
+		protected.GET("/notifications", getNotifications)
+		protected.GET("/notifications/unread-count", getUnreadCount)
+		protected.POST("/notifications/read-all", markAllNotificationsRead)
+		protected.POST("/notifications/:id/read", markNotificationRead)
+		protected.DELETE("/notifications/:id", deleteNotification)
+		protected.DELETE("/me/sessions/:id", revokeOwnSession)
+		protected.GET("/me/tokens", listOwnSessions(sessionKindAPIToken))
+		protected.POST("/me/tokens", createAPIToken)
+		protected.DELETE("/me/tokens/:id", revokeOwnSession)
+		protected.PUT("/nodes/:id/tags", updateNodeTags)
+		protected.GET("/nodes/:id/firewall", getNodeFirewall)
+	}
+	// 全局功能（supernode、设置、工具、用户等）只对管理员开放
+	admin := protected.Group("/")
+	admin.Use(requireAdmin())
+	{
+		admin.POST("/nodes/:id/ban", banNode)
+		admin.GET("/probes", getWanProbes)
+		admin.GET("/conflicts/ip", getIPConflicts)
+		admin.GET("/leases", getLeases)
+		admin.POST("/leases/:mac/reconcile", reconcileLease)
+		admin.GET("/announcements", getAnnouncements)
+		admin.POST("/announcements", createAnnouncement)
+		admin.GET("/announcements/:id", getAnnouncement)
+		admin.POST("/edges/:mac/ban", banMac)
+		admin.GET("/blocklist", getBlocklist)
+		admin.DELETE("/blocklist/:id", deleteBlocklistEntry)
+		admin.POST("/communities", createCommunity)
+		admin.DELETE("/communities/:id", deleteCommunity)
+		admin.PUT("/communities/:id/defaults", updateCommunityDefaults)
+		admin.PUT("/communities/:id/user-auth", setCommunityUserAuth)
+		admin.POST("/communities/:id/rotate-password", rotateCommunityPassword)
+		admin.DELETE("/communities/:id/rotate-password", cancelPasswordRotation)
+		admin.GET("/communities/reconcile", getCommunityReconcile)
+		admin.GET("/trash", getTrash)
+		admin.DELETE("/trash", emptyTrash)
+		admin.POST("/trash/nodes/:id/restore", restoreNode)
+		admin.DELETE("/trash/nodes/:id", purgeNode)
+		admin.POST("/trash/communities/:id/restore", restoreCommunity)
+		admin.DELETE("/trash/communities/:id", purgeCommunity)
+		admin.POST("/communities/reconcile", runCommunityReconcile)
+		admin.GET("/settings", getSettings)
+		admin.POST("/settings", saveSettings)
+		admin.GET("/settings/schema", getSettingsSchema)
+		admin.GET("/admin/export-spec", exportDeploySpec)
+		admin.POST("/admin/import-spec", importDeploySpec)
+		admin.GET("/admin/stats", getRequestStats)
+		admin.GET("/admin/integrity", getIntegrity)
+		admin.POST("/admin/integrity/fix", fixIntegrity)
+		admin.POST("/admin/stats/reset", resetRequestStats)
+		admin.POST("/version/check", checkUpdateNow)
+		admin.POST("/admin/upgrade", upgradePanel)
+		admin.GET("/admin/public-address", getPublicAddress)
+		admin.POST("/admin/public-address/apply", applyPublicAddress)
+		admin.GET("/nodes/by-mac/:mac", getNodeByMac)
+		admin.GET("/nodes/pending", getPendingNodes)
+		admin.POST("/nodes/:id/approve", approveNode)
+		admin.POST("/nodes/:id/reject", rejectNode)
+		admin.PUT("/nodes/by-mac/:mac", upsertNodeByMac)
+		admin.GET("/communities/by-name/:name", getCommunityByName)
+		admin.PUT("/communities/by-name/:name", upsertCommunityByName)
+		admin.GET("/supernode/config", getSupernodeConfig)
+		admin.POST("/supernode/config", saveSupernodeConfig)
+		admin.POST("/supernode/config/validate", validateSupernodeConfig)
+		admin.POST("/supernode/restart", restartSupernode)
+		admin.GET("/supernode/status", getSupernodeStatus)
+		admin.POST("/supernode/install", installSupernode)
+		admin.GET("/supernode/selftest", supernodeSelfTest)
+		admin.GET("/supernode/stats", getSupernodeStats)
+		admin.GET("/metrics", getMetrics)
+		admin.GET("/supernode/mgmt/verbosity", getMgmtVerbosity)
+		admin.POST("/supernode/mgmt/verbosity", setMgmtVerbosity)
+		admin.POST("/supernode/mgmt/reload-communities", reloadMgmtCommunities)
+		admin.GET("/supernode/mgmt/packetstats", getMgmtPacketStats)
+		admin.POST("/supernode/mgmt/packetstats/reset", resetMgmtPacketStats)
+		admin.GET("/supernodes", getSupernodes)
+		admin.POST("/supernodes", createSupernode)
+		admin.PUT("/supernodes/:id", updateSupernode)
+		admin.DELETE("/supernodes/:id", deleteSupernode)
+		admin.GET("/supernodes/incidents", getIncidents)
+		admin.POST("/tools/exec", execTool)
+		admin.GET("/tools/jobs", getToolJobs)
+		admin.POST("/tools/jobs", startToolJob)
+		admin.GET("/tools/jobs/:id", getToolJob)
+		admin.DELETE("/tools/jobs/:id", cancelToolJob)
+		admin.GET("/tools/jobs/:id/stream", streamToolJob)
+		admin.GET("/supernode/logs", streamLogs)
+		admin.GET("/supernode/logs/recent", getRecentLogs)
+		admin.GET("/relays", getActiveRelays)
+		admin.GET("/relays/stream", streamRelays)
+		admin.GET("/supernode/events", getSupernodeEvents)
+		admin.GET("/users", getUsers)
+		admin.POST("/users", createUser)
+		admin.PUT("/users/:id", updateUser)
+		admin.DELETE("/users/:id", deleteUser)
+		admin.GET("/users/:id/communities", getUserCommunities)
+		admin.PUT("/users/:id/communities", setUserCommunities)
+		admin.GET("/login-locks", getLoginLocks)
+		admin.DELETE("/login-locks", unlockLogin)
+		admin.GET("/users/:id/logins", getUserLogins)
+		admin.GET("/events", getEvents)
+		admin.GET("/events/export", exportEvents)
+		admin.GET("/events/types", eventTypes)
+		admin.GET("/stats/history", getStatsHistory)
+		admin.GET("/admin/stats-retention", getStatsRetention)
+		admin.POST("/admin/stats-retention/run", runStatsRetentionNow)
+		admin.GET("/admin/cluster", getClusterStatus)
+		admin.PUT("/admin/maintenance", setMaintenance)
+		admin.POST("/alerts/test-email", testEmail)
+		admin.POST("/alerts/test/:channel", testNotifier)
+		admin.GET("/alerts/rules", getAlertRules)
+		admin.POST("/alerts/rules", createAlertRule)
+		admin.PUT("/alerts/rules/:id", updateAlertRule)
+		admin.DELETE("/alerts/rules/:id", deleteAlertRule)
+		admin.GET("/invitations", getInvitations)
+		admin.POST("/invitations", createInvitation)
+		admin.DELETE("/invitations/:id", revokeInvitation)
+		admin.GET("/invitations/registrations", getRegistrations)
+		admin.POST("/invitations/registrations/:id/approve", approveRegistration)
+		admin.POST("/invitations/registrations/:id/reject", rejectRegistration)
+		admin.GET("/alerts/history", getAlertHistory)
+		admin.POST("/alerts/history/:id/ack", acknowledgeAlert)
+		admin.GET("/segmentation/rules", getSegmentRules)
+		admin.POST("/segmentation/rules", createSegmentRule)
+		admin.DELETE("/segmentation/rules/:id", deleteSegmentRule)
+		admin.GET("/segmentation/matrix", getSegmentMatrix)
+		admin.GET("/segmentation/violations", getSegmentViolations)
+	}
+}
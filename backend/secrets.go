@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"os"
+	"strings"
+)
+
+// 敏感字段（社区密码、节点认证密码、实例管理端口密码以及 schema 中 Secret=true 的设置）
+// 以 AES-GCM 加密后存入数据库。密钥来源按优先级：
+//  1. N2N_ENCRYPTION_KEY_FILE 指定的密钥文件，不存在时生成
+//  2. 数据库旁已存在的 <数据库>.key
+//  3. 由 N2N_ADMIN_SECRET 派生
+//  4. 生成 <数据库>.key
+// 已有密钥文件优先于 N2N_ADMIN_SECRET，避免之后设置环境变量导致已加密数据无法解密。
+// 代理令牌只保存 SHA-256 摘要，无需加密。
+
+// readOrCreateKeyFile 读取密钥文件（64 位十六进制），不存在且 create 为 true 时生成
+func readOrCreateKeyFile(path string, create bool) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("%s: key must be 64 hex characters", path)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) || !create {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	log.Printf("[安全提示] 已生成数据加密密钥 %s，请妥善备份，丢失后已加密的密码无法恢复", path)
+	return key, nil
+}
+
+// initEncryption 确定密钥并设置给 models，ephemeral 为 true 时（演示模式）使用随机密钥
+func initEncryption(ephemeral bool) {
+	var key []byte
+	var err error
+	defaultFile := appConfig.DBPath + ".key"
+	switch {
+	case ephemeral:
+		key = make([]byte, 32)
+		_, err = rand.Read(key)
+	case appConfig.EncryptionKeyFile != "":
+		key, err = readOrCreateKeyFile(appConfig.EncryptionKeyFile, true)
+	default:
+		if key, err = readOrCreateKeyFile(defaultFile, false); errors.Is(err, os.ErrNotExist) {
+			if appConfig.JWTSecretFromEnv {
+				sum := sha256.Sum256([]byte("n2n-admin encryption:" + appConfig.JWTSecret))
+				key, err = sum[:], nil
+			} else {
+				key, err = readOrCreateKeyFile(defaultFile, true)
+			}
+		}
+	}
+	if err == nil {
+		err = models.SetEncryptionKey(key)
+	}
+	if err != nil {
+		log.Fatalf("Failed to set up data encryption key: %v", err)
+	}
+}
+
+// secretSettingKeys 返回 schema 中需要加密存储的设置项
+func secretSettingKeys() []string {
+	keys := make([]string, 0)
+	for _, d := range settingSchema {
+		if d.Secret {
+			keys = append(keys, d.Key)
+		}
+	}
+	return keys
+}
+
+// encryptSettingValue 保存设置前加密敏感项
+func encryptSettingValue(key, value string) string {
+	if d, ok := lookupSettingDef(key); !ok || !d.Secret {
+		return value
+	}
+	enc, err := models.EncryptValue(value)
+	if err != nil {
+		log.Printf("Failed to encrypt setting %s: %v", key, err)
+		return value
+	}
+	return enc
+}
+
+// secretColumn 加密存储的列，迁移时按行直接读取原始值，某一行无法解密不影响其他行
+type secretColumn struct {
+	table, column string
+}
+
+var secretColumns = []secretColumn{
+	{"communities", "password"},
+	{"nodes", "auth_secret"},
+	{"supernode_instances", "mgmt_password"},
+}
+
+// migrateEncryptedFields 启动时检查密钥能否解密已有数据，并加密迁移前遗留的明文
+// 无法解密的行记录日志后跳过；所有已加密的值都无法解密时说明密钥不匹配，直接退出，以免之后用空值覆盖这些数据
+func migrateEncryptedFields() {
+	type storedSecret struct {
+		ID    string
+		Value string
+	}
+	migrated, decrypted, failed := 0, 0, 0
+	check := func(where string, s storedSecret, update func(enc string)) {
+		if models.IsEncrypted(s.Value) {
+			_, err := models.DecryptValue(s.Value)
+			switch {
+			case err == nil:
+				decrypted++
+				return
+			case !errors.Is(err, models.ErrMalformedEncrypted):
+				failed++
+				log.Printf("Skipping %s %s: %v", where, s.ID, err)
+				return
+			}
+			// 旧版本把以前缀开头的明文原样保存，按明文加密
+		}
+		enc, err := models.EncryptValue(s.Value)
+		if err != nil {
+			log.Printf("Failed to encrypt %s %s: %v", where, s.ID, err)
+			return
+		}
+		update(enc)
+		migrated++
+	}
+	for _, col := range secretColumns {
+		var rows []storedSecret
+		db.Table(col.table).Select("id, " + col.column + " AS value").Where(col.column + " <> ''").Scan(&rows)
+		for _, r := range rows {
+			check(col.table+"."+col.column+" id", r, func(enc string) {
+				db.Table(col.table).Where("id = ?", r.ID).UpdateColumn(col.column, enc)
+			})
+		}
+	}
+	var settings []storedSecret
+	db.Model(&models.Setting{}).Select("key AS id, value").Where("key IN ? AND value <> ''", secretSettingKeys()).Scan(&settings)
+	for _, s := range settings {
+		check("setting", s, func(enc string) {
+			db.Model(&models.Setting{}).Where("key = ?", s.ID).Update("value", enc)
+		})
+	}
+
+	if failed > 0 && decrypted == 0 {
+		log.Fatalf("Failed to decrypt stored secrets, check N2N_ENCRYPTION_KEY_FILE / N2N_ADMIN_SECRET")
+	}
+	if failed > 0 {
+		log.Printf("Warning: %d stored secrets could not be decrypted and were left unchanged", failed)
+	}
+	if migrated > 0 {
+		log.Printf("Encrypted %d stored secrets", migrated)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// normalizeTags 去除空白与重复标签，统一为逗号分隔格式
+func normalizeTags(tags string) string {
+	seen := make(map[string]bool)
+	res := make([]string, 0)
+	for _, t := range utils.SplitList(tags) {
+		if t == "*" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		res = append(res, t)
+	}
+	return strings.Join(res, ",")
+}
+
+// segmentPolicy 已加载的通信矩阵
+type segmentPolicy struct {
+	rules []models.SegmentRule
+}
+
+func loadSegmentPolicy() *segmentPolicy {
+	var rules []models.SegmentRule
+	db.Find(&rules)
+	return &segmentPolicy{rules: rules}
+}
+
+// declared 是否声明了任何规则；未声明时视为全部放行
+func (p *segmentPolicy) declared() bool {
+	return len(p.rules) > 0
+}
+
+// allowed 判断 src 节点是否被允许访问 dst 节点
+func (p *segmentPolicy) allowed(src, dst models.Node) bool {
+	if !p.declared() {
+		return true
+	}
+	srcTags := utils.SplitList(src.Tags)
+	dstTags := utils.SplitList(dst.Tags)
+	for _, r := range p.rules {
+		if matchGroup(r.SrcGroup, srcTags) && matchGroup(r.DstGroup, dstTags) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGroup(group string, tags []string) bool {
+	if group == "*" {
+		return true
+	}
+	for _, t := range tags {
+		if t == group {
+			return true
+		}
+	}
+	return false
+}
+
+func getSegmentRules(c *gin.Context) {
+	var rules []models.SegmentRule
+	db.Order("id").Find(&rules)
+	c.JSON(200, rules)
+}
+
+func createSegmentRule(c *gin.Context) {
+	var r models.SegmentRule
+	if !bindJSON(c, &r) {
+		return
+	}
+	r.SrcGroup = strings.TrimSpace(r.SrcGroup)
+	r.DstGroup = strings.TrimSpace(r.DstGroup)
+	if r.SrcGroup == "" || r.DstGroup == "" {
+		respondError(c, 400, ErrInvalidRequest, "Source and destination group are required")
+		return
+	}
+	r.ID = 0
+	if err := db.Create(&r).Error; err != nil {
+		respondError(c, 500, ErrInternal, "Failed to create rule")
+		return
+	}
+	c.JSON(200, r)
+}
+
+func deleteSegmentRule(c *gin.Context) {
+	db.Delete(&models.SegmentRule{}, c.Param("id"))
+	c.JSON(200, gin.H{"message": "deleted"})
+}
+
+func updateNodeTags(c *gin.Context) {
+	var p struct {
+		Tags string `json:"tags"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	db.Model(&n).Update("tags", normalizeTags(p.Tags))
+	c.JSON(200, n)
+}
+
+// getSegmentMatrix 返回分组 x 分组的通信矩阵
+func getSegmentMatrix(c *gin.Context) {
+	var nodes []models.Node
+	db.Find(&nodes)
+	policy := loadSegmentPolicy()
+
+	groupSet := make(map[string]bool)
+	for _, n := range nodes {
+		for _, t := range utils.SplitList(n.Tags) {
+			groupSet[t] = true
+		}
+	}
+	for _, r := range policy.rules {
+		for _, g := range []string{r.SrcGroup, r.DstGroup} {
+			if g != "*" {
+				groupSet[g] = true
+			}
+		}
+	}
+	groups := make([]string, 0, len(groupSet))
+	for g := range groupSet {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	matrix := make(map[string]map[string]bool)
+	for _, src := range groups {
+		matrix[src] = make(map[string]bool)
+		for _, dst := range groups {
+			matrix[src][dst] = policy.allowed(models.Node{Tags: src}, models.Node{Tags: dst})
+		}
+	}
+	c.JSON(200, gin.H{"declared": policy.declared(), "groups": groups, "matrix": matrix})
+}
+
+// getNodeFirewall 生成节点的入站防火墙脚本 (iptables 或 nft)
+func getNodeFirewall(c *gin.Context) {
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	var peers []models.Node
+	db.Where("community = ? AND id <> ?", n.Community, n.ID).Find(&peers)
+	policy := loadSegmentPolicy()
+
+	params := utils.FirewallParams{NodeName: n.Name, NodeIP: n.IPAddress, Device: n.TapDevice}
+	for _, peer := range peers {
+		if peer.IPAddress != "" && policy.allowed(peer, n) {
+			params.Allowed = append(params.Allowed, utils.FirewallPeer{Name: peer.Name, IP: peer.IPAddress})
+		}
+	}
+	if c.Query("format") == "nft" {
+		c.JSON(200, gin.H{"format": "nft", "script": utils.GenerateNftScript(params)})
+		return
+	}
+	c.JSON(200, gin.H{"format": "iptables", "script": utils.GenerateIptablesScript(params)})
+}
+
+// getSegmentViolations 检查最近观测到的中转流量是否违反通信矩阵
+func getSegmentViolations(c *gin.Context) {
+	policy := loadSegmentPolicy()
+	violations := make([]gin.H, 0)
+	if !policy.declared() {
+		c.JSON(200, violations)
+		return
+	}
+
+	var nodes []models.Node
+	db.Find(&nodes)
+	byMac := make(map[string]models.Node)
+	for _, n := range nodes {
+		byMac[n.MacAddress] = n
+	}
+
+	for _, ev := range collectActiveRelays() {
+		src, okSrc := byMac[ev.SrcMac]
+		dst, okDst := byMac[ev.DstMac]
+		if !okSrc || !okDst || policy.allowed(src, dst) {
+			continue
+		}
+		violations = append(violations, gin.H{
+			"src_mac": ev.SrcMac, "src_name": src.Name, "src_tags": src.Tags,
+			"dst_mac": ev.DstMac, "dst_name": dst.Name, "dst_tags": dst.Tags,
+			"pkt_count": ev.PktCount, "last_active": ev.LastActive,
+		})
+	}
+	c.JSON(200, violations)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 登录会话的有效期：
+//   - session_access_minutes：访问令牌（JWT）的有效期，到期后前端用 POST /api/refresh 换取新令牌
+//   - session_refresh_hours：会话的最长有效期，到期后必须重新登录
+//   - session_idle_minutes：超过该时间没有任何请求时会话失效，0 表示不限制
+//   - session_sliding：每次刷新令牌时把会话有效期顺延 session_refresh_hours，活跃的会话不会过期
+// 例如要求 30 分钟无操作即退出：session_idle_minutes=30、session_access_minutes=15。API 令牌不受这些设置影响。
+
+// sessionLimitConfig 登录会话的有效期参数
+type sessionLimitConfig struct {
+	Access  time.Duration
+	Refresh time.Duration
+	Idle    time.Duration
+	Sliding bool
+}
+
+// sessionLimits 读取会话有效期设置，访问令牌的有效期不超过会话的最长有效期
+func sessionLimits() sessionLimitConfig {
+	l := sessionLimitConfig{
+		Access:  time.Duration(settingIntValue("session_access_minutes")) * time.Minute,
+		Refresh: time.Duration(settingIntValue("session_refresh_hours")) * time.Hour,
+		Idle:    time.Duration(settingIntValue("session_idle_minutes")) * time.Minute,
+		Sliding: getSettingValue("session_sliding", "false") == "true",
+	}
+	if l.Access <= 0 || l.Access > l.Refresh {
+		l.Access = l.Refresh
+	}
+	return l
+}
+
+// jwtKey 校验签名算法并返回签名密钥，防止算法混淆攻击
+func jwtKey(t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+	return jwtSecret, nil
+}
+
+// refreshToken 为仍然有效的登录会话签发新的访问令牌。访问令牌本身可以已经过期（只校验签名），
+// 但会话不能已吊销、过期或空闲超时，令牌版本也必须与用户一致
+func refreshToken(c *gin.Context) {
+	tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	token, err := jwt.Parse(tokenString, jwtKey, jwt.WithoutClaimsValidation())
+	if err != nil || token == nil || !token.Valid {
+		respondError(c, 401, ErrInvalidToken, "Invalid token")
+		return
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		respondError(c, 401, ErrInvalidToken, "Invalid claims")
+		return
+	}
+	user, ok := tokenUser(claims)
+	if !ok {
+		respondError(c, 401, ErrInvalidToken, "Session has been revoked or expired")
+		return
+	}
+	jti, _ := claims["jti"].(string)
+	s, _ := activeSession(jti, user.ID)
+	if s.Kind != sessionKindLogin {
+		respondError(c, 400, ErrInvalidRequest, "API tokens cannot be refreshed")
+		return
+	}
+	if limits := sessionLimits(); limits.Sliding && s.ExpiresAt != nil {
+		exp := time.Now().Add(limits.Refresh)
+		s.ExpiresAt = &exp
+		db.Model(&s).UpdateColumn("expires_at", exp)
+	}
+	t, exp, err := signSessionToken(user, s)
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	res := gin.H{"token": t, "expires_at": exp, "session_expires_at": s.ExpiresAt}
+	if idle := sessionLimits().Idle; idle > 0 {
+		res["idle_timeout"] = int(idle.Seconds())
+	}
+	c.JSON(200, res)
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 设置项类型
+const (
+	settingString    = "string"
+	settingInt       = "int"
+	settingBool      = "bool"
+	settingEnum      = "enum"
+	settingHostPort  = "host_port"  // host:port
+	settingHostList  = "host_list"  // 逗号分隔的 host:port
+	settingCIDRList  = "cidr_list"  // 逗号分隔的 CIDR 或 IP
+	settingList      = "list"       // 逗号分隔的字符串
+	settingOID       = "oid"        // SNMP OID，如 1.3.6.1.4.1
+	settingMQTTTopic = "mqtt_topic" // MQTT 主题，不能包含通配符
+)
+
+// settingDef 描述一个设置项：类型、默认值、取值范围以及前端分组展示所需的信息
+// 值为空表示使用默认值
+type settingDef struct {
+	Key         string   `json:"key"`
+	Group       string   `json:"group"`
+	Label       string   `json:"label"`
+	Type        string   `json:"type"`
+	Default     string   `json:"default"`
+	Options     []string `json:"options,omitempty"` // enum 的可选值，list 的可选项
+	Min         *int     `json:"min,omitempty"`
+	Max         *int     `json:"max,omitempty"`
+	Secret      bool     `json:"secret,omitempty"`
+	Prefix      bool     `json:"prefix,omitempty"` // Key 为前缀，匹配一组动态键（如 notify_routes_<渠道>）
+	Description string   `json:"description,omitempty"`
+}
+
+// settingGroup 前端设置页的分组，按顺序展示
+type settingGroup struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+}
+
+var settingGroups = []settingGroup{
+	{"edge", "节点默认配置"},
+	{"general", "通用"},
+	{"security", "访问与登录安全"},
+	{"nodes", "节点管理"},
+	{"supernode", "Supernode 监控"},
+	{"alerts", "告警"},
+	{"snmp", "SNMP"},
+	{"syslog", "Syslog 转发"},
+	{"mqtt", "MQTT"},
+	{"email", "邮件通知"},
+	{"telegram", "Telegram 通知"},
+	{"dingtalk", "钉钉通知"},
+	{"wecom", "企业微信通知"},
+}
+
+func intPtr(v int) *int { return &v }
+
+var settingSchema = []settingDef{
+	{Key: "supernode_host", Group: "edge", Label: "Supernode 服务地址", Type: settingHostPort, Description: "写入生成的 edge.conf，客户端需能通过该地址访问 supernode"},
+	{Key: "public_ip_detection", Group: "edge", Label: "检测公网地址", Type: settingBool, Default: "true", Description: "定期检测本机公网 IP 并与 Supernode 服务地址对比，面板与 supernode 不在同一台主机时请关闭"},
+	{Key: "supernode_host_autofill", Group: "edge", Label: "自动填写 Supernode 服务地址", Type: settingBool, Default: "true", Description: "未设置时使用检测到的公网 IP 和 supernode 监听端口"},
+	{Key: "public_ip_stun_servers", Group: "edge", Label: "STUN 服务器", Type: settingHostList, Default: "stun.l.google.com:19302,stun.cloudflare.com:3478"},
+	{Key: "public_ip_check_url", Group: "edge", Label: "公网 IP 查询地址", Type: settingString, Default: "https://api.ipify.org", Description: "STUN 都失败时使用，需返回纯文本的 IP 地址"},
+	{Key: "community_password_grace_minutes", Group: "edge", Label: "社区密码轮换过渡期（分钟）", Type: settingInt, Default: "60", Min: intPtr(0), Max: intPtr(10080), Description: "轮换密码时新密码在过渡期结束后生效，期间 edge 代理可提前取得新配置"},
+	{Key: "supernode_backup_hosts", Group: "edge", Label: "备用 Supernode 地址", Type: settingHostList, Description: "逗号分隔的 host:port，在 edge.conf 中作为额外的 -l 写在主地址之后，主 supernode 不可用时 edge 自动切换"},
+
+	{Key: "default_language", Group: "general", Label: "默认语言", Type: settingEnum, Default: sourceLang, Options: supportedLangs},
+	{Key: "trash_retention_days", Group: "general", Label: "回收站保留天数", Type: settingInt, Default: "30", Min: intPtr(0), Description: "0 表示永久保留"},
+	{Key: "maintenance_mode", Group: "general", Label: "只读维护模式", Type: settingBool, Default: "false", Description: "开启后除登录和诊断外的修改请求均被拒绝，需通过维护模式开关（PUT /api/admin/maintenance）关闭"},
+	{Key: "maintenance_message", Group: "general", Label: "维护说明", Type: settingString, Description: "维护模式下显示给用户的横幅内容"},
+	{Key: "stats_raw_retention_hours", Group: "general", Label: "统计原始数据保留时长（小时）", Type: settingInt, Default: "48", Min: intPtr(1), Max: intPtr(720), Description: "1 分钟分辨率的统计数据，超过后汇总为 5 分钟"},
+	{Key: "stats_5m_retention_days", Group: "general", Label: "5 分钟统计保留天数", Type: settingInt, Default: "30", Min: intPtr(1), Max: intPtr(365), Description: "超过后汇总为 1 小时"},
+	{Key: "stats_hourly_retention_days", Group: "general", Label: "小时统计保留天数", Type: settingInt, Default: "365", Min: intPtr(1), Max: intPtr(3650)},
+	{Key: "stats_vacuum_free_percent", Group: "general", Label: "数据库空闲空间回收阈值（%）", Type: settingInt, Default: "20", Min: intPtr(0), Max: intPtr(90), Description: "清理后空闲页超过该比例时执行 VACUUM 缩小数据库文件，0 表示不自动执行"},
+	{Key: "integrity_check_hours", Group: "general", Label: "数据完整性检查间隔（小时）", Type: settingInt, Default: "24", Min: intPtr(0), Max: intPtr(720), Description: "检查孤立节点、格式不同的重复 MAC、未知设置项和过期的回收站记录，0 表示不检查"},
+	{Key: "update_check_enabled", Group: "general", Label: "检查新版本", Type: settingBool, Default: "true", Description: "每天查询一次最新发布版本，有新版本时通知管理员；无法访问外网的环境请关闭"},
+	{Key: "update_check_url", Group: "general", Label: "版本查询地址", Type: settingString, Default: defaultUpdateCheckURL, Description: "返回 GitHub Releases 格式（tag_name、html_url）的 JSON，可指向内网镜像"},
+	{Key: "integrity_auto_fix", Group: "general", Label: "自动修复完整性问题", Type: settingBool, Default: "false", Description: "孤立节点和重复节点移入回收站，未知设置项删除"},
+	{Key: "tool_job_timeout_seconds", Group: "general", Label: "诊断任务超时（秒）", Type: settingInt, Default: "120", Min: intPtr(5), Max: intPtr(3600)},
+	{Key: "tool_max_jobs", Group: "general", Label: "同时运行的诊断任务上限", Type: settingInt, Default: "4", Min: intPtr(1), Max: intPtr(64)},
+	{Key: "health_min_free_disk_mb", Group: "general", Label: "数据库所在磁盘最低剩余空间（MB）", Type: settingInt, Default: "100", Min: intPtr(0), Description: "低于该值时健康检查报告 degraded，0 表示不检查"},
+	{Key: "status_page_enabled", Group: "general", Label: "公开状态页", Type: settingBool, Default: "false", Description: "无需登录即可访问 /status，只显示 supernode 状态、在线节点数与可用率，不含节点名称和地址"},
+	{Key: "status_page_title", Group: "general", Label: "状态页标题", Type: settingString, Default: "n2n 网络状态"},
+
+	{Key: "access_allowlist", Group: "security", Label: "访问允许列表", Type: settingCIDRList},
+	{Key: "access_denylist", Group: "security", Label: "访问拒绝列表", Type: settingCIDRList},
+	{Key: "tool_target_policy", Group: "security", Label: "诊断工具目标范围", Type: settingEnum, Default: "any", Options: []string{"any", "vpn"}, Description: "vpn 表示只允许探测社区网段及下方允许列表中的地址"},
+	{Key: "tool_target_allowlist", Group: "security", Label: "诊断工具额外允许的网段", Type: settingCIDRList},
+	{Key: "login_max_attempts", Group: "security", Label: "登录失败次数上限", Type: settingInt, Min: intPtr(0), Description: "为空时使用环境变量配置"},
+	{Key: "login_lock_minutes", Group: "security", Label: "登录锁定分钟数", Type: settingInt, Min: intPtr(1), Description: "为空时使用环境变量配置"},
+	{Key: "login_record_expiry_minutes", Group: "security", Label: "失败记录过期分钟数", Type: settingInt, Min: intPtr(1), Description: "为空时使用环境变量配置"},
+	{Key: "session_access_minutes", Group: "security", Label: "访问令牌有效期（分钟）", Type: settingInt, Default: "1440", Min: intPtr(1), Description: "到期后前端通过 /api/refresh 换取新令牌"},
+	{Key: "session_refresh_hours", Group: "security", Label: "登录会话最长有效期（小时）", Type: settingInt, Default: "24", Min: intPtr(1), Description: "到期后必须重新登录"},
+	{Key: "session_idle_minutes", Group: "security", Label: "空闲超时（分钟）", Type: settingInt, Default: "0", Min: intPtr(0), Description: "超过该时间没有请求时会话失效，0 表示不限制"},
+	{Key: "session_sliding", Group: "security", Label: "刷新令牌时顺延会话有效期", Type: settingBool, Default: "false"},
+	{Key: "login_captcha", Group: "security", Label: "登录验证码", Type: settingEnum, Default: "off", Options: []string{"off", "builtin", "turnstile", "hcaptcha"}, Description: "连续失败后要求验证码，启用后不再按用户名锁定；builtin 为内置算术题"},
+	{Key: "login_captcha_after", Group: "security", Label: "失败多少次后要求验证码", Type: settingInt, Default: "2", Min: intPtr(0), Description: "0 表示每次登录都需要"},
+	{Key: "login_captcha_site_key", Group: "security", Label: "验证码 Site Key", Type: settingString, Description: "Turnstile / hCaptcha 前端组件使用"},
+	{Key: "login_captcha_secret", Group: "security", Label: "验证码 Secret", Type: settingString, Secret: true, Description: "Turnstile / hCaptcha 服务端校验使用"},
+
+	{Key: "node_expiry_action", Group: "nodes", Label: "节点到期处理", Type: settingEnum, Default: "disable", Options: []string{"disable", "flag"}},
+	{Key: "enforce_node_disable", Group: "nodes", Label: "自动移除已禁用的在线节点", Type: settingBool, Default: "false"},
+	{Key: "node_approval_required", Group: "nodes", Label: "非管理员创建的节点需要审批", Type: settingBool, Default: "false", Description: "审批通过前节点保持禁用，不能下载配置"},
+	{Key: "node_auto_discover", Group: "nodes", Label: "自动登记未知 edge", Type: settingBool, Default: "false", Description: "连接到 supernode 但未在面板登记的 edge 自动登记为待审批节点"},
+	{Key: "node_log_retention_days", Group: "nodes", Label: "edge 日志保留天数", Type: settingInt, Default: "7", Min: intPtr(1)},
+	{Key: "node_log_max_lines", Group: "nodes", Label: "每个节点最多保留的日志行数", Type: settingInt, Default: "5000", Min: intPtr(1)},
+	{Key: "availability_retention_days", Group: "nodes", Label: "在线统计保留天数", Type: settingInt, Default: "30", Min: intPtr(1)},
+	{Key: "speedtest_server", Group: "nodes", Label: "iperf3 测速服务端", Type: settingHostPort, Description: "为空时使用 Supernode 服务地址的主机和 5201 端口"},
+	{Key: "speedtest_duration", Group: "nodes", Label: "测速时长（秒，每个方向）", Type: settingInt, Default: "10", Min: intPtr(1), Max: intPtr(60)},
+	{Key: "wan_probe_enabled", Group: "nodes", Label: "公网可达性探测", Type: settingBool, Default: "false"},
+	{Key: "wan_probe_port", Group: "nodes", Label: "探测端口", Type: settingInt, Default: "56460", Min: intPtr(1), Max: intPtr(65535)},
+
+	{Key: "community_autoheal", Group: "supernode", Label: "自动修复 community.list", Type: settingBool, Default: "true"},
+	{Key: "community_watch_seconds", Group: "supernode", Label: "community.list 变化检查间隔（秒）", Type: settingInt, Default: "10", Min: intPtr(0), Description: "文件被手动修改或删除时立即校对，0 为只定期校对"},
+	{Key: "watchdog_max_restarts", Group: "supernode", Label: "看门狗最多重启次数", Type: settingInt, Default: "3", Min: intPtr(0)},
+	{Key: "watchdog_backoff_seconds", Group: "supernode", Label: "看门狗重启间隔（秒，指数退避）", Type: settingInt, Default: "10", Min: intPtr(1)},
+	{Key: "supernode_federation", Group: "supernode", Label: "Supernode 联盟名称", Type: settingString, Default: "*Federation", Description: "用户认证时据此计算写入 edge 配置的 supernode 公钥，需与 supernode 的 -F 一致"},
+	{Key: "selftest_service_url", Group: "supernode", Label: "端口自检服务", Type: settingString, Description: "从公网探测 supernode UDP 端口的外部服务地址，为空时跳过"},
+	{Key: "selftest_peer", Group: "supernode", Label: "端口自检联盟节点", Type: settingHostPort, Description: "在 -l 中加入本机的另一台 supernode，用其最近的注册时间判断入站 UDP 是否可达"},
+	{Key: "supernode_public_key", Group: "supernode", Label: "Supernode 公钥", Type: settingString, Description: "为空时由联盟名称计算"},
+
+	{Key: "alert_offline_threshold", Group: "alerts", Label: "离线判定次数", Type: settingInt, Default: "2", Min: intPtr(1), Max: intPtr(100), Description: "连续 N 次轮询未见才判定节点离线"},
+	{Key: "alert_mgmt_failure_threshold", Group: "alerts", Label: "管理端口故障判定次数", Type: settingInt, Default: "3", Min: intPtr(1), Max: intPtr(100), Description: "管理端口连续 N 次请求无响应才发送 supernode_down 告警"},
+	{Key: "notify_routes_", Group: "alerts", Label: "通知路由", Type: settingList, Default: "*", Prefix: true, Description: "notify_routes_<渠道>，逗号分隔的事件类型，* 表示全部"},
+
+	{Key: "snmp_enabled", Group: "snmp", Label: "启用 SNMP 代理", Type: settingBool, Default: "false", Description: "只读的 SNMP v1/v2c 代理，导出节点与 supernode 状态（私有 MIB）"},
+	{Key: "snmp_listen", Group: "snmp", Label: "监听地址", Type: settingHostPort, Default: defaultSNMPListen, Description: "UDP 地址，使用 161 端口需要 root 权限"},
+	{Key: "snmp_community", Group: "snmp", Label: "团体名", Type: settingString, Secret: true, Description: "为空时不应答任何请求"},
+	{Key: "snmp_base_oid", Group: "snmp", Label: "MIB 根 OID", Type: settingOID, Default: defaultSNMPBaseOID, Description: "默认值为文档示例用的企业号，请改为自己申请的企业号"},
+
+	{Key: "syslog_enabled", Group: "syslog", Label: "启用", Type: settingBool, Default: "false"},
+	{Key: "syslog_address", Group: "syslog", Label: "Syslog 服务器", Type: settingHostPort},
+	{Key: "syslog_protocol", Group: "syslog", Label: "协议", Type: settingEnum, Default: "udp", Options: []string{"udp", "tcp", "tls"}, Description: "消息格式为 RFC 5424，TCP / TLS 按 RFC 6587 加长度前缀"},
+	{Key: "syslog_facility", Group: "syslog", Label: "Facility", Type: settingEnum, Default: "local0", Options: []string{"user", "daemon", "auth", "authpriv", "local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7"}},
+	{Key: "syslog_categories", Group: "syslog", Label: "转发的事件类别", Type: settingList, Default: "audit,node,supernode,alert", Options: syslogCategories, Description: "audit（操作审计与登录）、node（节点状态）、supernode（supernode 事件）、alert（告警规则）、supernode_log（supernode 原始日志）"},
+	{Key: "syslog_hostname", Group: "syslog", Label: "主机名", Type: settingString, Description: "消息中的 HOSTNAME 字段，为空时使用本机主机名"},
+
+	{Key: "mqtt_enabled", Group: "mqtt", Label: "启用 MQTT 发布", Type: settingBool, Default: "false", Description: "向 MQTT broker 发布节点上下线状态与汇总数据，可用于 Home Assistant 等自动化"},
+	{Key: "mqtt_broker", Group: "mqtt", Label: "Broker 地址", Type: settingHostPort, Description: "如 192.168.1.10:1883"},
+	{Key: "mqtt_tls", Group: "mqtt", Label: "使用 TLS", Type: settingBool, Default: "false"},
+	{Key: "mqtt_username", Group: "mqtt", Label: "用户名", Type: settingString},
+	{Key: "mqtt_password", Group: "mqtt", Label: "密码", Type: settingString, Secret: true},
+	{Key: "mqtt_client_id", Group: "mqtt", Label: "Client ID", Type: settingString, Default: "n2n-admin"},
+	{Key: "mqtt_topic", Group: "mqtt", Label: "主题前缀", Type: settingMQTTTopic, Default: "n2n", Description: "节点状态发布到 <前缀>/node/<MAC>/state，汇总发布到 <前缀>/stats"},
+	{Key: "mqtt_qos", Group: "mqtt", Label: "QoS", Type: settingEnum, Default: "0", Options: []string{"0", "1"}},
+	{Key: "mqtt_interval", Group: "mqtt", Label: "发布周期（秒）", Type: settingInt, Default: "30", Min: intPtr(5), Max: intPtr(3600), Description: "离线判定沿用告警的离线判定次数"},
+	{Key: "mqtt_ha_discovery", Group: "mqtt", Label: "Home Assistant 自动发现", Type: settingBool, Default: "false", Description: "为每个节点发布 connectivity 类型的 binary_sensor"},
+	{Key: "mqtt_ha_prefix", Group: "mqtt", Label: "自动发现前缀", Type: settingMQTTTopic, Default: "homeassistant"},
+
+	{Key: "smtp_enabled", Group: "email", Label: "启用", Type: settingBool, Default: "false"},
+	{Key: "smtp_host", Group: "email", Label: "SMTP 服务器", Type: settingString},
+	{Key: "smtp_port", Group: "email", Label: "端口", Type: settingInt, Default: "25", Min: intPtr(1), Max: intPtr(65535)},
+	{Key: "smtp_tls", Group: "email", Label: "加密方式", Type: settingEnum, Default: "none", Options: []string{"none", "starttls", "tls"}},
+	{Key: "smtp_username", Group: "email", Label: "用户名", Type: settingString},
+	{Key: "smtp_password", Group: "email", Label: "密码", Type: settingString, Secret: true},
+	{Key: "smtp_from", Group: "email", Label: "发件人", Type: settingString},
+	{Key: "smtp_to", Group: "email", Label: "收件人", Type: settingList},
+
+	{Key: "telegram_enabled", Group: "telegram", Label: "启用", Type: settingBool, Default: "false"},
+	{Key: "telegram_bot_token", Group: "telegram", Label: "Bot Token", Type: settingString, Secret: true},
+	{Key: "telegram_chat_id", Group: "telegram", Label: "Chat ID", Type: settingString},
+
+	{Key: "dingtalk_enabled", Group: "dingtalk", Label: "启用", Type: settingBool, Default: "false"},
+	{Key: "dingtalk_webhook", Group: "dingtalk", Label: "Webhook", Type: settingString, Secret: true},
+	{Key: "dingtalk_secret", Group: "dingtalk", Label: "加签密钥", Type: settingString, Secret: true},
+
+	{Key: "wecom_enabled", Group: "wecom", Label: "启用", Type: settingBool, Default: "false"},
+	{Key: "wecom_webhook", Group: "wecom", Label: "Webhook", Type: settingString, Secret: true},
+}
+
+// lookupSettingDef 按键查找设置项定义，前缀定义匹配以其开头的键
+func lookupSettingDef(key string) (settingDef, bool) {
+	for _, d := range settingSchema {
+		if d.Key == key || (d.Prefix && strings.HasPrefix(key, d.Key) && len(key) > len(d.Key)) {
+			return d, true
+		}
+	}
+	return settingDef{}, false
+}
+
+// normalize 规整设置值（去除空白、统一布尔写法），不合法时返回错误；空值表示恢复默认
+func (d settingDef) normalize(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "", nil
+	}
+	switch d.Type {
+	case settingInt:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return v, fmt.Errorf("must be an integer")
+		}
+		if d.Min != nil && n < *d.Min {
+			return v, fmt.Errorf("must be at least %d", *d.Min)
+		}
+		if d.Max != nil && n > *d.Max {
+			return v, fmt.Errorf("must be at most %d", *d.Max)
+		}
+		return strconv.Itoa(n), nil
+	case settingBool:
+		switch strings.ToLower(v) {
+		case "true", "1", "yes", "on":
+			return "true", nil
+		case "false", "0", "no", "off":
+			return "false", nil
+		}
+		return v, fmt.Errorf("must be true or false")
+	case settingEnum:
+		for _, o := range d.Options {
+			if strings.EqualFold(v, o) {
+				return o, nil
+			}
+		}
+		return v, fmt.Errorf("must be one of %s", strings.Join(d.Options, ", "))
+	case settingHostPort:
+		return v, checkHostPort(v)
+	case settingHostList:
+		items := utils.SplitList(v)
+		for _, item := range items {
+			if err := checkHostPort(item); err != nil {
+				return v, fmt.Errorf("%s: %v", item, err)
+			}
+		}
+		return strings.Join(items, ","), nil
+	case settingCIDRList:
+		if _, err := parseCIDRList(v); err != nil {
+			return v, err
+		}
+		return strings.Join(utils.SplitList(v), ","), nil
+	case settingList:
+		items := utils.SplitList(v)
+		for _, item := range items {
+			if len(d.Options) > 0 && !containsString(d.Options, item) {
+				return v, fmt.Errorf("%s: must be one of %s", item, strings.Join(d.Options, ", "))
+			}
+		}
+		return strings.Join(items, ","), nil
+	case settingOID:
+		oid, err := utils.ParseOID(v)
+		if err != nil {
+			return v, err
+		}
+		return oid.String(), nil
+	case settingMQTTTopic:
+		v = strings.Trim(v, "/")
+		return v, utils.ValidateMQTTTopic(v)
+	}
+	return v, nil
+}
+
+func checkHostPort(v string) error {
+	host, port, err := net.SplitHostPort(v)
+	if err != nil || host == "" {
+		return fmt.Errorf("must be host:port")
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("invalid port")
+	}
+	return nil
+}
+
+// validateSettings 校验并规整待保存的设置，返回规整后的值
+func validateSettings(p map[string]string) (map[string]string, error) {
+	res := make(map[string]string, len(p))
+	for k, v := range p {
+		d, ok := lookupSettingDef(k)
+		if !ok {
+			return nil, newAPIErrorf(ErrInvalidSetting, "Unknown setting: %s", k)
+		}
+		// 前端回传脱敏占位符时保留原值
+		if d.Secret && v == maskedSecret {
+			continue
+		}
+		nv, err := d.normalize(v)
+		if err != nil {
+			return nil, newAPIErrorf(ErrInvalidSetting, "Invalid value for %s: %v", k, err)
+		}
+		res[k] = nv
+	}
+	return res, nil
+}
+
+// settingIntValue 读取整数设置，不存在或不合法时使用 schema 中的默认值
+func settingIntValue(key string) int {
+	d, _ := lookupSettingDef(key)
+	if v, err := d.normalize(getSettingValue(key, d.Default)); err == nil && v != "" {
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	n, _ := strconv.Atoi(d.Default)
+	return n
+}
+
+// getSettingsSchema 返回设置项定义、分组以及当前值（敏感项脱敏）
+func getSettingsSchema(c *gin.Context) {
+	stored := make(map[string]string)
+	var rows []models.Setting
+	db.Find(&rows)
+	for _, r := range rows {
+		stored[r.Key] = r.Value
+	}
+	items := make([]gin.H, 0, len(settingSchema))
+	for _, d := range settingSchema {
+		item := gin.H{"def": d}
+		if !d.Prefix {
+			v := stored[d.Key]
+			if d.Secret && v != "" {
+				v = maskedSecret
+			}
+			item["value"] = v
+			item["effective"] = v
+			if v == "" {
+				item["effective"] = d.Default
+			}
+		}
+		items = append(items, item)
+	}
+	c.JSON(200, gin.H{"groups": settingGroups, "settings": items})
+}
+
+// migrateSettings 启动时按 schema 规整已有的设置行（去除空白、统一布尔写法等）
+// 不合法的值和未知键只记录日志，保留原值以免丢失数据，读取处会按默认值处理
+func migrateSettings() {
+	var rows []models.Setting
+	db.Find(&rows)
+	for _, r := range rows {
+		d, ok := lookupSettingDef(r.Key)
+		if !ok {
+			log.Printf("Settings: unknown key %q", r.Key)
+			continue
+		}
+		nv, err := d.normalize(r.Value)
+		if err != nil {
+			log.Printf("Settings: invalid value %q for %s: %v", r.Value, r.Key, err)
+			continue
+		}
+		if nv != r.Value {
+			db.Model(&models.Setting{}).Where("key = ?", r.Key).Update("value", nv)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"n2n_ui/backend/models"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 首次启动的初始化向导：数据库中还没有用户时，不再创建随机密码的 admin（需要到 journald 里找密码），
+// 而是由 /api/setup 一次性提交管理员账户、supernode_host 与第一个社区，在同一事务中写入。
+// 创建出第一个用户后接口即锁定。初始化必须在 X-Setup-Token 头中提供令牌，防止他人抢先完成初始化：
+// 令牌可由 N2N_SETUP_TOKEN 指定，未指定时启动时随机生成并输出到日志。
+
+var setupMutex sync.Mutex
+
+// setupRequest 初始化提交的内容，supernode_host 与社区均可留空稍后在面板中设置
+type setupRequest struct {
+	Username      string `json:"username" binding:"required"`
+	Password      string `json:"password" binding:"required"`
+	SupernodeHost string `json:"supernode_host"`
+	Community     *struct {
+		Name     string `json:"name"`
+		Range    string `json:"range"`
+		Password string `json:"password"`
+	} `json:"community"`
+}
+
+// setupCompleted 是否已有用户
+func setupCompleted() bool {
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	return count > 0
+}
+
+// checkSetupToken 校验 X-Setup-Token 头，没有令牌（initDB 未生成）时一律拒绝
+func checkSetupToken(c *gin.Context) bool {
+	if appConfig.SetupToken != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Setup-Token")), []byte(appConfig.SetupToken)) == 1 {
+		return true
+	}
+	respondError(c, 403, ErrForbidden, "Invalid setup token")
+	return false
+}
+
+// getSetupStatus 返回是否已完成初始化，未完成时附带检测到的公网地址作为 supernode_host 的建议值
+func getSetupStatus(c *gin.Context) {
+	if setupCompleted() {
+		c.JSON(200, gin.H{"initialized": true})
+		return
+	}
+	res := gin.H{"initialized": false, "token_required": true}
+	publicAddressMutex.Lock()
+	if r := lastPublicAddress; r != nil && r.Suggested != "" {
+		res["suggested_supernode_host"] = r.Suggested
+	}
+	publicAddressMutex.Unlock()
+	c.JSON(200, res)
+}
+
+// runSetup 创建管理员、保存 supernode_host 并创建第一个社区，成功后直接返回登录令牌
+func runSetup(c *gin.Context) {
+	// 已初始化的面板不再有令牌，先返回 409 而不是令牌错误
+	if setupCompleted() {
+		respondError(c, 409, ErrSetupCompleted, "Setup has already been completed")
+		return
+	}
+	if !checkSetupToken(c) {
+		return
+	}
+	var p setupRequest
+	if !bindJSON(c, &p) {
+		return
+	}
+	p.Username = strings.TrimSpace(p.Username)
+	if p.Username == "" {
+		respondError(c, 400, ErrInvalidRequest, "Username is required")
+		return
+	}
+	if len(p.Password) < 6 {
+		respondError(c, 400, ErrPasswordTooShort, "New password must be at least 6 characters")
+		return
+	}
+	settings := map[string]string{}
+	if p.SupernodeHost = strings.TrimSpace(p.SupernodeHost); p.SupernodeHost != "" {
+		var err error
+		if settings, err = validateSettings(map[string]string{"supernode_host": p.SupernodeHost}); err != nil {
+			respondErr(c, 400, err, ErrInvalidSetting)
+			return
+		}
+	}
+	var cm *models.Community
+	if p.Community != nil && strings.TrimSpace(p.Community.Name) != "" {
+		cm = &models.Community{Name: strings.TrimSpace(p.Community.Name), Range: strings.TrimSpace(p.Community.Range), Password: models.EncryptedString(p.Community.Password)}
+		if cm.Range != "" {
+			if err := validateCIDR(cm.Range); err != nil {
+				respondErr(c, 400, err, ErrInvalidRequest)
+				return
+			}
+		}
+		if len(cm.Password) < 4 {
+			respondError(c, 400, ErrPasswordTooShort, "Password must be at least 4 characters")
+			return
+		}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(p.Password), bcrypt.DefaultCost)
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+
+	setupMutex.Lock()
+	defer setupMutex.Unlock()
+	errCompleted := errors.New("setup completed")
+	user := models.User{Username: p.Username, Password: string(hash), IsAdmin: true}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.User{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errCompleted
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		for k, v := range settings {
+			if err := tx.Where("key = ?", k).Assign(models.Setting{Value: encryptSettingValue(k, v)}).FirstOrCreate(&models.Setting{Key: k}).Error; err != nil {
+				return err
+			}
+		}
+		if cm != nil {
+			return tx.Create(cm).Error
+		}
+		return nil
+	})
+	if errors.Is(err, errCompleted) {
+		respondError(c, 409, ErrSetupCompleted, "Setup has already been completed")
+		return
+	}
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Setup failed")
+		return
+	}
+
+	res := gin.H{"community": cm}
+	if cm != nil {
+		if err := syncCommunityList(); err != nil {
+			res["warning"] = tr(c, "Failed to write community.list") + ": " + err.Error()
+		}
+	}
+	if len(settings) > 0 {
+		refreshAllConfigs()
+	}
+	t, err := signUserToken(c, user)
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	res["token"], res["user"] = t, markLoginSuccess(c, user)
+	c.JSON(200, res)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SNMP 代理：供只支持 SNMP 的网管系统采集，设置 snmp_enabled=true 后在 snmp_listen 上应答 v1/v2c 只读请求。
+// 私有 MIB（docs/N2N-ADMIN-MIB.txt）挂在 snmp_base_oid 下：
+//   - .1 汇总：节点总数、在线数、经中转的在线节点数、社区数、未登记的在线 edge 数
+//   - .2.1 节点表，索引为节点 ID：名称、社区、虚拟 IP、MAC、启用、在线、中转、中转包数
+//   - .3.1 supernode 实例表，索引为实例 ID：名称、管理端口是否可用、在线 edge 数以及 packetstats 计数（Counter64，仅 v2c）
+
+const (
+	snmpReloadInterval = 10 * time.Second
+	snmpCacheTTL       = 5 * time.Second // 一次 walk 会发出大量请求，共用同一份数据
+	defaultSNMPListen  = "0.0.0.0:1161"
+	defaultSNMPBaseOID = "1.3.6.1.4.1.32473.1" // RFC 5612 保留给文档示例的企业号，正式部署请换成自己的
+)
+
+var (
+	snmpCache     []utils.SNMPVar
+	snmpCacheTime time.Time
+	snmpCacheMu   sync.Mutex
+)
+
+// snmpBaseOID 读取 MIB 的根 OID，设置不合法时使用默认值
+func snmpBaseOID() utils.OID {
+	oid, err := utils.ParseOID(getSettingValue("snmp_base_oid", defaultSNMPBaseOID))
+	if err != nil {
+		oid, _ = utils.ParseOID(defaultSNMPBaseOID)
+	}
+	return oid
+}
+
+// snmpTruth SNMPv2-TC 的 TruthValue：true(1) / false(2)
+func snmpTruth(b bool) utils.SNMPVar {
+	if b {
+		return utils.SNMPVar{Type: utils.SNMPInteger, Value: int64(1)}
+	}
+	return utils.SNMPVar{Type: utils.SNMPInteger, Value: int64(2)}
+}
+
+func snmpGauge(n int64) utils.SNMPVar {
+	if n < 0 {
+		n = 0
+	}
+	return utils.SNMPVar{Type: utils.SNMPGauge32, Value: uint64(n)}
+}
+
+func snmpString(s string) utils.SNMPVar {
+	return utils.SNMPVar{Type: utils.SNMPOctetString, Value: s}
+}
+
+func snmpCounter64(n uint64) utils.SNMPVar {
+	return utils.SNMPVar{Type: utils.SNMPCounter64, Value: n}
+}
+
+// buildSNMPVars 按私有 MIB 生成当前的全部变量
+func buildSNMPVars() []utils.SNMPVar {
+	base := snmpBaseOID()
+	vars := make([]utils.SNMPVar, 0)
+	put := func(v utils.SNMPVar, sub ...uint32) {
+		v.OID = base.Append(sub...)
+		vars = append(vars, v)
+	}
+
+	var nodes []models.Node
+	db.Order("id").Find(&nodes)
+	edges, _ := allEdgeInfo()
+	relays := relaySources()
+	relayPackets := make(map[string]int64)
+	relayMutex.Lock()
+	for _, ev := range relayMap {
+		relayPackets[ev.SrcMac] += ev.PktCount
+	}
+	relayMutex.Unlock()
+	var communities int64
+	db.Model(&models.Community{}).Count(&communities)
+
+	online, relayed := 0, 0
+	known := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		m := n.MacAddress
+		known[m] = true
+		_, isOnline := edges[m]
+		isRelay := isOnline && relays[m]
+		if isOnline {
+			online++
+		}
+		if isRelay {
+			relayed++
+		}
+		id := uint32(n.ID)
+		put(utils.SNMPVar{Type: utils.SNMPInteger, Value: int64(n.ID)}, 2, 1, 1, id)
+		put(snmpString(n.Name), 2, 1, 2, id)
+		put(snmpString(n.Community), 2, 1, 3, id)
+		put(snmpString(n.IPAddress), 2, 1, 4, id)
+		put(snmpString(utils.MAC(m).Format()), 2, 1, 5, id)
+		put(snmpTruth(n.IsEnabled), 2, 1, 6, id)
+		put(snmpTruth(isOnline), 2, 1, 7, id)
+		put(snmpTruth(isRelay), 2, 1, 8, id)
+		put(snmpGauge(relayPackets[m]), 2, 1, 9, id)
+	}
+	unknown := 0
+	for mac := range edges {
+		if !known[mac] {
+			unknown++
+		}
+	}
+	put(snmpGauge(int64(len(nodes))), 1, 1, 0)
+	put(snmpGauge(int64(online)), 1, 2, 0)
+	put(snmpGauge(int64(relayed)), 1, 3, 0)
+	put(snmpGauge(communities), 1, 4, 0)
+	put(snmpGauge(int64(unknown)), 1, 5, 0)
+
+	rts := listRuntimes()
+	sort.Slice(rts, func(i, j int) bool { return rts[i].instance.ID < rts[j].instance.ID })
+	for _, rt := range rts {
+		id := uint32(rt.instance.ID)
+		put(utils.SNMPVar{Type: utils.SNMPInteger, Value: int64(rt.instance.ID)}, 3, 1, 1, id)
+		put(snmpString(rt.instance.Name), 3, 1, 2, id)
+		stats, err := rt.client.Stats()
+		put(snmpTruth(err == nil), 3, 1, 3, id)
+		instEdges, _ := rt.client.GetEdgeInfo()
+		put(snmpGauge(int64(len(instEdges))), 3, 1, 4, id)
+		if err != nil {
+			continue
+		}
+		fwd, _ := stats.Counter("forward")
+		bcst, _ := stats.Counter("broadcast")
+		reg, _ := stats.Counter("reg_super")
+		errs, _ := stats.Counter("errors")
+		put(snmpCounter64(fwd.TxPkt), 3, 1, 5, id)
+		put(snmpCounter64(bcst.TxPkt), 3, 1, 6, id)
+		put(snmpCounter64(reg.RxPkt), 3, 1, 7, id)
+		put(snmpCounter64(reg.Nak), 3, 1, 8, id)
+		put(snmpCounter64(errs.TxPkt), 3, 1, 9, id)
+	}
+	return vars
+}
+
+// cachedSNMPVars 返回缓存的变量，过期后重新生成
+func cachedSNMPVars() []utils.SNMPVar {
+	snmpCacheMu.Lock()
+	defer snmpCacheMu.Unlock()
+	if snmpCache == nil || time.Since(snmpCacheTime) > snmpCacheTTL {
+		snmpCache, snmpCacheTime = buildSNMPVars(), time.Now()
+	}
+	// 代理会对结果排序，返回副本避免并发请求互相影响
+	return append([]utils.SNMPVar(nil), snmpCache...)
+}
+
+// startSNMPAgent 按设置启动或停止 SNMP 代理，修改监听地址后自动重新监听；团体名每个请求实时读取
+func startSNMPAgent() {
+	agent := &utils.SNMPAgent{
+		Community: func() string { return getSettingValue("snmp_community", "") },
+		Vars:      cachedSNMPVars,
+	}
+	var (
+		conn           net.PacketConn
+		current, fails string
+	)
+	reload := func() {
+		listen := ""
+		if getSettingValue("snmp_enabled", "false") == "true" {
+			listen = getSettingValue("snmp_listen", defaultSNMPListen)
+		}
+		if listen == current {
+			return
+		}
+		if conn != nil {
+			conn.Close()
+			conn = nil
+			log.Printf("SNMP agent stopped listening on %s", current)
+		}
+		current = ""
+		if listen == "" {
+			return
+		}
+		c, err := net.ListenPacket("udp", listen)
+		if err != nil {
+			// 下次检查时重试，同一地址只记录一次错误
+			if fails != listen {
+				log.Printf("SNMP agent failed to listen on %s: %v", listen, err)
+				fails = listen
+			}
+			return
+		}
+		conn, current, fails = c, listen, ""
+		if getSettingValue("snmp_community", "") == "" {
+			log.Printf("SNMP agent listening on %s, but snmp_community is empty so no request will be answered", listen)
+		} else {
+			log.Printf("SNMP agent listening on %s", listen)
+		}
+		go agent.Serve(c)
+	}
+	reload()
+	ticker := time.NewTicker(snmpReloadInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		reload()
+	}
+}
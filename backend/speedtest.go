@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 吞吐量测试由 edge 代理执行（supernode 主机上需运行 iperf3 -s）：
+//  1. 管理员 POST /api/nodes/:id/speedtest 创建待执行的测试
+//  2. 代理定期 GET /api/agent/speedtest（X-Agent-Token 认证）领取任务，无任务时返回 204
+//  3. 代理依次运行 iperf3 -c <host> -p <port> -t <duration> -J 以及加 -R 的反向测试，
+//     POST /api/agent/speedtest/:id 上报 {"upload": <iperf3 JSON>, "download": <iperf3 JSON>}，失败时上报 {"error": "..."}
+
+const (
+	speedTestDefaultPort = 5201
+	speedTestPickupLimit = 10 * time.Minute // 代理超过该时间未领取视为失败
+	speedTestHistory     = 50               // 每个节点保留的测试记录数
+)
+
+// 测试状态
+const (
+	speedTestPending = "pending"
+	speedTestRunning = "running"
+	speedTestDone    = "done"
+	speedTestFailed  = "failed"
+)
+
+// speedTestServer 返回 iperf3 服务端地址：设置 speedtest_server，未设置时使用 supernode_host 的主机和默认端口
+func speedTestServer() string {
+	if v := getSettingValue("speedtest_server", ""); v != "" {
+		return v
+	}
+	host, _, err := net.SplitHostPort(getSettingValue("supernode_host", ""))
+	if err != nil || host == "" {
+		return ""
+	}
+	return net.JoinHostPort(host, strconv.Itoa(speedTestDefaultPort))
+}
+
+// expireSpeedTests 把长时间未领取或未上报结果的测试标记为失败
+func expireSpeedTests() {
+	now := time.Now()
+	db.Model(&models.SpeedTest{}).
+		Where("status = ? AND created_at < ?", speedTestPending, now.Add(-speedTestPickupLimit)).
+		Updates(map[string]interface{}{"status": speedTestFailed, "error": "agent did not pick up the test", "finished_at": now})
+	// 两个方向各 duration 秒，再留出与领取相同的余量
+	var running []models.SpeedTest
+	db.Where("status = ?", speedTestRunning).Find(&running)
+	for _, t := range running {
+		if t.StartedAt != nil && now.Sub(*t.StartedAt) > time.Duration(2*t.Duration)*time.Second+speedTestPickupLimit {
+			db.Model(&t).Updates(map[string]interface{}{"status": speedTestFailed, "error": "agent did not report a result", "finished_at": now})
+		}
+	}
+}
+
+// trimSpeedTests 只保留节点最新的 speedTestHistory 条记录
+func trimSpeedTests(nodeID uint) {
+	var cutoff models.SpeedTest
+	db.Where("node_id = ?", nodeID).Order("id DESC").Offset(speedTestHistory).Limit(1).Find(&cutoff)
+	if cutoff.ID != 0 {
+		db.Where("node_id = ? AND id <= ?", nodeID, cutoff.ID).Delete(&models.SpeedTest{})
+	}
+}
+
+// startSpeedTest 为节点创建一次吞吐量测试，等待 edge 代理领取执行
+func startSpeedTest(c *gin.Context) {
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	if n.AgentToken == "" {
+		respondError(c, 409, ErrAgentRequired, "Node has no edge agent token")
+		return
+	}
+	server := speedTestServer()
+	if server == "" {
+		respondError(c, 400, ErrInvalidSetting, "Set speedtest_server or supernode_host first")
+		return
+	}
+	expireSpeedTests()
+	var active int64
+	db.Model(&models.SpeedTest{}).Where("node_id = ? AND status IN ?", n.ID, []string{speedTestPending, speedTestRunning}).Count(&active)
+	if active > 0 {
+		respondError(c, 409, ErrSpeedTestBusy, "A speed test is already in progress for this node")
+		return
+	}
+	t := models.SpeedTest{
+		NodeID:      n.ID,
+		Status:      speedTestPending,
+		Server:      server,
+		Duration:    settingIntValue("speedtest_duration"),
+		RequestedBy: c.GetString("username"),
+	}
+	if err := db.Create(&t).Error; err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	trimSpeedTests(n.ID)
+	c.JSON(202, t)
+}
+
+// getSpeedTests 返回节点的测试历史（最新在前）
+func getSpeedTests(c *gin.Context) {
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	expireSpeedTests()
+	var tests []models.SpeedTest
+	db.Where("node_id = ?", n.ID).Order("id DESC").Find(&tests)
+	var latest *models.SpeedTest
+	for i := range tests {
+		if tests[i].Status == speedTestDone {
+			latest = &tests[i]
+			break
+		}
+	}
+	c.JSON(200, gin.H{"tests": tests, "latest": latest, "has_agent": n.AgentToken != ""})
+}
+
+// pollSpeedTest 代理领取待执行的测试，返回 iperf3 参数；没有任务时返回 204
+func pollSpeedTest(c *gin.Context) {
+	n, ok := agentNode(c)
+	if !ok {
+		return
+	}
+	expireSpeedTests()
+	var t models.SpeedTest
+	if err := db.Where("node_id = ? AND status = ?", n.ID, speedTestPending).Order("id").First(&t).Error; err != nil {
+		c.Status(204)
+		return
+	}
+	now := time.Now()
+	db.Model(&t).Updates(map[string]interface{}{"status": speedTestRunning, "started_at": now})
+	host, port, _ := net.SplitHostPort(t.Server)
+	p, _ := strconv.Atoi(port)
+	c.JSON(200, gin.H{"id": t.ID, "host": host, "port": p, "duration": t.Duration})
+}
+
+// reportSpeedTest 接收代理上报的 iperf3 结果
+func reportSpeedTest(c *gin.Context) {
+	n, ok := agentNode(c)
+	if !ok {
+		return
+	}
+	var t models.SpeedTest
+	if err := db.Where("node_id = ? AND status = ?", n.ID, speedTestRunning).First(&t, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrSpeedTestNotFound, "Speed test not found or already finished")
+		return
+	}
+	var p struct {
+		Upload   json.RawMessage `json:"upload"`
+		Download json.RawMessage `json:"download"`
+		Error    string          `json:"error" binding:"max=500"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	updates := map[string]interface{}{"status": speedTestDone, "finished_at": time.Now()}
+	errMsg := p.Error
+	if errMsg == "" && len(p.Upload) == 0 && len(p.Download) == 0 {
+		errMsg = "no result reported"
+	}
+	if len(p.Upload) > 0 {
+		if r, err := utils.ParseIperf3(p.Upload); err != nil {
+			errMsg = "upload: " + err.Error()
+		} else {
+			updates["upload_bps"], updates["retransmits"] = r.ReceivedBps, r.Retransmits
+		}
+	}
+	if len(p.Download) > 0 {
+		if r, err := utils.ParseIperf3(p.Download); err != nil && errMsg == "" {
+			errMsg = "download: " + err.Error()
+		} else if err == nil {
+			updates["download_bps"] = r.ReceivedBps
+		}
+	}
+	if errMsg != "" {
+		updates["status"], updates["error"] = speedTestFailed, errMsg
+	}
+	db.Model(&t).Updates(updates)
+	db.First(&t, t.ID)
+	c.JSON(200, t)
+}
+
+// pruneSpeedTests 清理所属节点已被彻底删除的测试记录
+func pruneSpeedTests() {
+	db.Where("node_id NOT IN (?)", db.Unscoped().Model(&models.Node{}).Select("id")).Delete(&models.SpeedTest{})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 前端静态文件：首次请求时从内嵌的 dist 读取并改写子路径、计算 ETag 和压缩版本，之后直接使用缓存
+// 构建时生成的 .br / .gz 预压缩文件在未设置子路径时直接使用（设置子路径后内容被改写，只能重新 gzip）
+
+const staticGzipMinSize = 1024
+
+// fingerprinted 匹配 Vite 等工具生成的带内容哈希的文件名，如 assets/index-BqVx3k9a.js
+var fingerprinted = regexp.MustCompile(`^assets/.+[-.][A-Za-z0-9_-]{8,}\.[a-z0-9]+$`)
+
+type staticAsset struct {
+	body        []byte
+	gzip        []byte
+	brotli      []byte
+	contentType string
+	etag        string
+	immutable   bool
+}
+
+var (
+	staticAssets      = make(map[string]*staticAsset)
+	staticAssetsMutex sync.Mutex
+)
+
+// staticContentType 按扩展名确定 MIME 类型，未知扩展名时按内容嗅探
+func staticContentType(name string, data []byte) string {
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		return t
+	}
+	return http.DetectContentType(data)
+}
+
+func compressible(contentType string) bool {
+	for _, p := range []string{"text/", "application/javascript", "application/json", "image/svg+xml", "application/wasm", "application/manifest+json"} {
+		if strings.HasPrefix(contentType, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadStaticAsset 读取并缓存 dist 中的文件，不存在时返回 nil
+func loadStaticAsset(name, base string) *staticAsset {
+	staticAssetsMutex.Lock()
+	defer staticAssetsMutex.Unlock()
+	if a, ok := staticAssets[name]; ok {
+		return a
+	}
+	data, err := fs.ReadFile(content, "dist/"+name)
+	if err != nil || strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".br") {
+		return nil
+	}
+	a := &staticAsset{body: rewriteAssetPaths(name, data, base), immutable: fingerprinted.MatchString(name)}
+	a.contentType = staticContentType(name, a.body)
+	sum := sha256.Sum256(a.body)
+	a.etag = `"` + hex.EncodeToString(sum[:8]) + `"`
+	if compressible(a.contentType) && len(a.body) >= staticGzipMinSize {
+		if base == "" {
+			a.gzip, _ = fs.ReadFile(content, "dist/"+name+".gz")
+			a.brotli, _ = fs.ReadFile(content, "dist/"+name+".br")
+		}
+		if a.gzip == nil {
+			var buf bytes.Buffer
+			w, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+			w.Write(a.body)
+			w.Close()
+			a.gzip = buf.Bytes()
+		}
+	}
+	staticAssets[name] = a
+	return a
+}
+
+// acceptsEncoding 判断 Accept-Encoding 是否接受指定编码（q=0 视为不接受）
+func acceptsEncoding(header, enc string) bool {
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		if strings.TrimSpace(fields[0]) != enc {
+			continue
+		}
+		for _, f := range fields[1:] {
+			if q := strings.TrimSpace(f); strings.HasPrefix(q, "q=") {
+				v, err := strconv.ParseFloat(q[2:], 64)
+				return err == nil && v > 0
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// serveStatic 返回前端文件，assets/ 以外不存在的路径回退到 index.html（前端路由）
+// 带哈希的文件名长期缓存，其余文件（含 index.html）每次用 ETag 验证
+func serveStatic(c *gin.Context, name, base string) {
+	a := loadStaticAsset(name, base)
+	if a == nil && !strings.HasPrefix(name, "assets/") {
+		a = loadStaticAsset("index.html", base)
+	}
+	if a == nil {
+		respondError(c, 404, ErrNotFound, "Not Found")
+		return
+	}
+	h := c.Writer.Header()
+	h.Set("ETag", a.etag)
+	h.Set("Vary", "Accept-Encoding")
+	if a.immutable {
+		h.Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		h.Set("Cache-Control", "no-cache")
+	}
+	if etagListContains(c.GetHeader("If-None-Match"), a.etag) {
+		c.Status(304)
+		return
+	}
+	body := a.body
+	accept := c.GetHeader("Accept-Encoding")
+	switch {
+	case a.brotli != nil && acceptsEncoding(accept, "br"):
+		body = a.brotli
+		h.Set("Content-Encoding", "br")
+	case a.gzip != nil && acceptsEncoding(accept, "gzip"):
+		body = a.gzip
+		h.Set("Content-Encoding", "gzip")
+	}
+	c.Data(200, a.contentType, body)
+}
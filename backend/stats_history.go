@@ -0,0 +1,329 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 时序统计的保留与降采样：stats_sampler 每分钟把指标写入 1 分钟分辨率的桶（StatSample），
+// 超过各级保留期的桶汇总到下一级分辨率后删除，最后一级超过保留期直接删除：
+//   1 分钟  保留 stats_raw_retention_hours（默认 48 小时）后汇总为 5 分钟
+//   5 分钟  保留 stats_5m_retention_days（默认 30 天）后汇总为 1 小时
+//   1 小时  保留 stats_hourly_retention_days（默认 365 天）
+// 汇总保留次数、总和、最小与最大值，平均值由总和 / 次数得出。删除后数据库空闲页超过
+// stats_vacuum_free_percent 时执行 VACUUM 回收空间，避免 SQLite 文件无限增长。
+// 新的时序指标在 sampleStats 中用 statSample 生成采样即可，不需要单独建表。
+
+const (
+	statsSampleInterval = time.Minute
+	statsRollupInterval = 10 * time.Minute
+	statsRollupBatch    = 5000
+
+	statsMetricEdges       = "edges"        // 在线 edge 数，series 为社区名，空为合计
+	statsMetricSupernodeUp = "supernode_up" // 管理端口是否可用，series 为实例名
+)
+
+var statsMetrics = []string{statsMetricEdges, statsMetricSupernodeUp}
+
+// statsTier 一级分辨率及其保留期设置
+type statsTier struct {
+	Resolution time.Duration
+	Setting    string
+	Unit       time.Duration
+}
+
+var statsTiers = []statsTier{
+	{time.Minute, "stats_raw_retention_hours", time.Hour},
+	{5 * time.Minute, "stats_5m_retention_days", 24 * time.Hour},
+	{time.Hour, "stats_hourly_retention_days", 24 * time.Hour},
+}
+
+func (t statsTier) seconds() int { return int(t.Resolution / time.Second) }
+
+func (t statsTier) retention() time.Duration {
+	return time.Duration(settingIntValue(t.Setting)) * t.Unit
+}
+
+// statsRetentionRun 一次汇总与清理的结果
+type statsRetentionRun struct {
+	At          time.Time `json:"at"`
+	RolledUp    int64     `json:"rolled_up"` // 汇总到下一级的桶数
+	Expired     int64     `json:"expired"`   // 最后一级超过保留期被删除的桶数
+	Vacuumed    bool      `json:"vacuumed"`
+	FreeBefore  int64     `json:"free_bytes_before,omitempty"`
+	DurationMs  int64     `json:"duration_ms"`
+	VacuumError string    `json:"vacuum_error,omitempty"`
+}
+
+var (
+	lastStatsRetention *statsRetentionRun
+	statsRetentionMu   sync.Mutex // 串行执行汇总，并保护 lastStatsRetention
+)
+
+// mergeStatSamples 把桶合并到已有的同一桶中
+func mergeStatSamples(tx *gorm.DB, rows []models.StatSample) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "metric"}, {Name: "series"}, {Name: "resolution"}, {Name: "bucket"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count": gorm.Expr(`"count" + excluded."count"`),
+			"sum":   gorm.Expr(`"sum" + excluded."sum"`),
+			"min":   gorm.Expr(`MIN("min", excluded."min")`),
+			"max":   gorm.Expr(`MAX("max", excluded."max")`),
+		}),
+	}).CreateInBatches(&rows, 200).Error
+}
+
+// statSample 指标的一个采样值，写入 1 分钟分辨率的桶
+func statSample(metric, series string, value float64, at time.Time) models.StatSample {
+	return models.StatSample{Metric: metric, Series: series, Resolution: statsTiers[0].seconds(),
+		Bucket: at.Truncate(statsTiers[0].Resolution), Count: 1, Sum: value, Min: value, Max: value}
+}
+
+// sampleStats 采样各实例管理端口的可用性和在线 edge 数（合计与各社区），有实例查询失败时不记录 edge 数
+func sampleStats(now time.Time) {
+	edges, outages := edgeInfoWithOutages()
+	down := make(map[string]bool, len(outages))
+	for _, o := range outages {
+		down[o.Name] = true
+	}
+	rows := make([]models.StatSample, 0)
+	for _, rt := range listRuntimes() {
+		up := 1.0
+		if down[rt.instance.Name] {
+			up = 0
+		}
+		rows = append(rows, statSample(statsMetricSupernodeUp, rt.instance.Name, up, now))
+	}
+	if len(outages) == 0 {
+		perCommunity := make(map[string]int)
+		var comms []models.Community
+		db.Find(&comms)
+		for _, cm := range comms {
+			perCommunity[cm.Name] = 0
+		}
+		for _, e := range edges {
+			perCommunity[e.Community]++
+		}
+		rows = append(rows, statSample(statsMetricEdges, "", float64(len(edges)), now))
+		for name, n := range perCommunity {
+			rows = append(rows, statSample(statsMetricEdges, name, float64(n), now))
+		}
+	}
+	if err := mergeStatSamples(db, rows); err != nil {
+		log.Printf("Stats: failed to record samples: %v", err)
+	}
+}
+
+// rollupTier 把一级中早于 cutoff 的桶汇总到下一级后删除
+func rollupTier(from, to statsTier, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		var rows []models.StatSample
+		if err := db.Where("resolution = ? AND bucket < ?", from.seconds(), cutoff).Order("id").Limit(statsRollupBatch).Find(&rows).Error; err != nil {
+			return total, err
+		}
+		if len(rows) == 0 {
+			return total, nil
+		}
+		type key struct {
+			metric, series string
+			bucket         int64
+		}
+		merged := make(map[key]*models.StatSample)
+		order := make([]key, 0)
+		ids := make([]uint, 0, len(rows))
+		for _, r := range rows {
+			ids = append(ids, r.ID)
+			b := r.Bucket.Truncate(to.Resolution)
+			k := key{r.Metric, r.Series, b.Unix()}
+			m, ok := merged[k]
+			if !ok {
+				m = &models.StatSample{Metric: r.Metric, Series: r.Series, Resolution: to.seconds(), Bucket: b, Min: r.Min, Max: r.Max}
+				merged[k] = m
+				order = append(order, k)
+			}
+			m.Count += r.Count
+			m.Sum += r.Sum
+			if r.Min < m.Min {
+				m.Min = r.Min
+			}
+			if r.Max > m.Max {
+				m.Max = r.Max
+			}
+		}
+		out := make([]models.StatSample, 0, len(order))
+		for _, k := range order {
+			out = append(out, *merged[k])
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := mergeStatSamples(tx, out); err != nil {
+				return err
+			}
+			return tx.Where("id IN ?", ids).Delete(&models.StatSample{}).Error
+		})
+		if err != nil {
+			return total, err
+		}
+		total += int64(len(rows))
+		if len(rows) < statsRollupBatch {
+			return total, nil
+		}
+	}
+}
+
+// sqliteFreeBytes 数据库文件中空闲页占用的字节数与空闲比例
+func sqliteFreeBytes() (int64, float64) {
+	var pageSize, pages, free int64
+	db.Raw("PRAGMA page_size").Scan(&pageSize)
+	db.Raw("PRAGMA page_count").Scan(&pages)
+	db.Raw("PRAGMA freelist_count").Scan(&free)
+	if pages == 0 {
+		return 0, 0
+	}
+	return free * pageSize, float64(free) * 100 / float64(pages)
+}
+
+// runStatsRetention 按保留策略汇总、清理统计数据，空闲空间超过阈值（或 forceVacuum）时执行 VACUUM
+func runStatsRetention(forceVacuum bool) statsRetentionRun {
+	statsRetentionMu.Lock()
+	defer statsRetentionMu.Unlock()
+	start := time.Now()
+	res := statsRetentionRun{At: start}
+	for i, t := range statsTiers {
+		cutoff := start.Add(-t.retention())
+		if i+1 < len(statsTiers) {
+			next := statsTiers[i+1]
+			n, err := rollupTier(t, next, cutoff.Truncate(next.Resolution))
+			res.RolledUp += n
+			if err != nil {
+				log.Printf("Stats: failed to roll up %s samples: %v", t.Resolution, err)
+				break
+			}
+			continue
+		}
+		r := db.Where("resolution = ? AND bucket < ?", t.seconds(), cutoff).Delete(&models.StatSample{})
+		res.Expired = r.RowsAffected
+	}
+	freeBytes, freePct := sqliteFreeBytes()
+	threshold := settingIntValue("stats_vacuum_free_percent")
+	if forceVacuum || (threshold > 0 && freePct >= float64(threshold)) {
+		res.FreeBefore = freeBytes
+		if err := db.Exec("VACUUM").Error; err != nil {
+			res.VacuumError = err.Error()
+			log.Printf("Stats: VACUUM failed: %v", err)
+		} else {
+			res.Vacuumed = true
+			log.Printf("Database vacuumed, %d bytes of free pages released", freeBytes)
+		}
+	}
+	res.DurationMs = time.Since(start).Milliseconds()
+	if res.RolledUp > 0 || res.Expired > 0 {
+		log.Printf("Stats: rolled up %d and expired %d buckets in %d ms", res.RolledUp, res.Expired, res.DurationMs)
+	}
+	lastStatsRetention = &res
+	return res
+}
+
+// startStatsSampler 每分钟采样，每 statsRollupInterval 执行一次汇总与清理
+func startStatsSampler() {
+	ticker := time.NewTicker(statsSampleInterval)
+	defer ticker.Stop()
+	lastRollup := time.Time{}
+	for now := range ticker.C {
+		sampleStats(now)
+		if time.Since(lastRollup) >= statsRollupInterval {
+			runStatsRetention(false)
+			lastRollup = time.Now()
+		}
+	}
+}
+
+// statsTierFor 覆盖 since 的最高分辨率，都不覆盖时使用最后一级
+func statsTierFor(since time.Time) statsTier {
+	for _, t := range statsTiers {
+		if !since.Before(time.Now().Add(-t.retention())) {
+			return t
+		}
+	}
+	return statsTiers[len(statsTiers)-1]
+}
+
+// getStatsHistory 指标的历史曲线，?metric=&series=&hours=（默认 24，最多 8760），
+// ?resolution= 指定分辨率（秒），未指定时使用覆盖整个区间的最高分辨率
+func getStatsHistory(c *gin.Context) {
+	metric := c.DefaultQuery("metric", statsMetricEdges)
+	if !containsString(statsMetrics, metric) {
+		respondError(c, 400, ErrInvalidRequest, "Unknown metric")
+		return
+	}
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 || hours > 8760 {
+		hours = 24
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	tier := statsTierFor(since)
+	if v := c.Query("resolution"); v != "" {
+		found := false
+		for _, t := range statsTiers {
+			if strconv.Itoa(t.seconds()) == v {
+				tier, found = t, true
+			}
+		}
+		if !found {
+			respondError(c, 400, ErrInvalidRequest, "Invalid resolution")
+			return
+		}
+	}
+	var rows []models.StatSample
+	db.Where("metric = ? AND series = ? AND resolution = ? AND bucket >= ?", metric, c.Query("series"), tier.seconds(), since.Truncate(tier.Resolution)).
+		Order("bucket").Find(&rows)
+	points := make([]gin.H, 0, len(rows))
+	for _, r := range rows {
+		points = append(points, gin.H{"time": r.Bucket, "avg": r.Sum / float64(r.Count), "min": r.Min, "max": r.Max})
+	}
+	c.JSON(200, gin.H{"metric": metric, "series": c.Query("series"), "resolution": tier.seconds(), "points": points})
+}
+
+// statsRetentionStatus 各级的保留期与桶数、数据库空闲空间以及最近一次汇总的结果
+func statsRetentionStatus() gin.H {
+	tiers := make([]gin.H, 0, len(statsTiers))
+	for _, t := range statsTiers {
+		var n int64
+		var oldest models.StatSample
+		db.Model(&models.StatSample{}).Where("resolution = ?", t.seconds()).Count(&n)
+		db.Where("resolution = ?", t.seconds()).Order("bucket").Limit(1).Find(&oldest)
+		item := gin.H{"resolution": t.seconds(), "retention_hours": int(t.retention() / time.Hour), "setting": t.Setting, "buckets": n}
+		if oldest.ID != 0 {
+			item["oldest"] = oldest.Bucket
+		}
+		tiers = append(tiers, item)
+	}
+	freeBytes, freePct := sqliteFreeBytes()
+	statsRetentionMu.Lock()
+	last := lastStatsRetention
+	statsRetentionMu.Unlock()
+	return gin.H{"tiers": tiers, "free_bytes": freeBytes, "free_percent": fmt.Sprintf("%.1f", freePct),
+		"vacuum_free_percent": settingIntValue("stats_vacuum_free_percent"), "last_run": last}
+}
+
+func getStatsRetention(c *gin.Context) {
+	c.JSON(200, statsRetentionStatus())
+}
+
+// runStatsRetentionNow 立即执行汇总与清理，?vacuum=true 时无论空闲空间多少都执行 VACUUM
+func runStatsRetentionNow(c *gin.Context) {
+	res := runStatsRetention(c.Query("vacuum") == "true")
+	recordAudit(c, "stats.retention_run", "", fmt.Sprintf("rolled up %d, expired %d, vacuumed %v", res.RolledUp, res.Expired, res.Vacuumed))
+	c.JSON(200, res)
+}
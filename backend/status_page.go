@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"n2n_ui/backend/models"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 公开状态页：设置 status_page_enabled=true 后无需登录即可访问 /status（HTML）与 /api/status（JSON），
+// 供终端用户查看网络整体状态。只包含汇总数据：supernode 是否运行及可用率、在线节点数、节点平均在线率，
+// 不含节点名称、IP、MAC 与社区名称，supernode 按序号匿名显示。未启用时两个地址均返回 404。
+
+const statusPageCacheTTL = 30 * time.Second // 公开接口不需要鉴权，缓存结果避免被频繁请求拖慢
+
+// publicSupernodeStatus 一个 supernode 实例的公开状态
+type publicSupernodeStatus struct {
+	Name      string   `json:"name"` // 匿名名称，如 Supernode 1
+	Up        bool     `json:"up"`
+	Uptime24h *float64 `json:"uptime_24h"` // 可用率（百分比），根据看门狗记录的故障时长计算
+	Uptime7d  *float64 `json:"uptime_7d"`
+	Uptime30d *float64 `json:"uptime_30d"`
+}
+
+// publicStatus 公开状态页的数据
+type publicStatus struct {
+	Title        string                  `json:"title"`
+	Status       string                  `json:"status"` // operational, degraded, down
+	Supernodes   []publicSupernodeStatus `json:"supernodes"`
+	NodesTotal   int                     `json:"nodes_total"` // 已启用且未到期的节点
+	NodesOnline  int                     `json:"nodes_online"`
+	Availability *float64                `json:"availability_24h"` // 节点过去 24 小时平均在线率（百分比）
+	UpdatedAt    time.Time               `json:"updated_at"`
+}
+
+var (
+	statusPageCache     *publicStatus
+	statusPageCacheTime time.Time
+	statusPageMu        sync.Mutex
+)
+
+// supernodeUptime 根据故障记录计算实例自 since 起的可用率，实例创建晚于 since 时从创建时起算
+func supernodeUptime(inst models.SupernodeInstance, incidents []models.SupernodeIncident, since time.Time) *float64 {
+	now := time.Now()
+	if inst.CreatedAt.After(since) {
+		since = inst.CreatedAt
+	}
+	window := now.Sub(since)
+	if window <= 0 {
+		return nil
+	}
+	var down time.Duration
+	for _, inc := range incidents {
+		if inc.InstanceID != inst.ID {
+			continue
+		}
+		start, end := inc.StartedAt, now
+		if inc.ResolvedAt != nil {
+			end = *inc.ResolvedAt
+		}
+		if start.Before(since) {
+			start = since
+		}
+		if end.After(start) {
+			down += end.Sub(start)
+		}
+	}
+	v := 100 * (1 - float64(down)/float64(window))
+	if v < 0 {
+		v = 0
+	}
+	return &v
+}
+
+// buildPublicStatus 汇总公开状态
+func buildPublicStatus() *publicStatus {
+	now := time.Now()
+	res := &publicStatus{
+		Title:      getSettingValue("status_page_title", "n2n 网络状态"),
+		Supernodes: make([]publicSupernodeStatus, 0),
+		UpdatedAt:  now,
+	}
+
+	var incidents []models.SupernodeIncident
+	db.Where("resolved_at IS NULL OR resolved_at >= ?", now.AddDate(0, 0, -30)).Find(&incidents)
+	rts := listRuntimes()
+	sort.Slice(rts, func(i, j int) bool { return rts[i].instance.ID < rts[j].instance.ID })
+	edges, outages := edgeInfoWithOutages()
+	unreachable := make(map[uint]bool, len(outages))
+	for _, o := range outages {
+		unreachable[o.InstanceID] = true
+	}
+	up := 0
+	for i, rt := range rts {
+		s := publicSupernodeStatus{
+			Name:      fmt.Sprintf("Supernode %d", i+1),
+			Up:        !unreachable[rt.instance.ID] && isSupernodeActive(rt.instance.Unit),
+			Uptime24h: supernodeUptime(rt.instance, incidents, now.Add(-24*time.Hour)),
+			Uptime7d:  supernodeUptime(rt.instance, incidents, now.AddDate(0, 0, -7)),
+			Uptime30d: supernodeUptime(rt.instance, incidents, now.AddDate(0, 0, -30)),
+		}
+		if s.Up {
+			up++
+		}
+		res.Supernodes = append(res.Supernodes, s)
+	}
+	switch {
+	case len(rts) > 0 && up == 0:
+		res.Status = "down"
+	case up < len(rts):
+		res.Status = "degraded"
+	default:
+		res.Status = "operational"
+	}
+
+	var nodes []models.Node
+	db.Select("id", "mac_address", "expires_at").Where("is_enabled = ?", true).Find(&nodes)
+	ids := make([]uint, 0, len(nodes))
+	for _, n := range nodes {
+		if nodeExpired(n) {
+			continue
+		}
+		res.NodesTotal++
+		ids = append(ids, n.ID)
+		if _, ok := edges[n.MacAddress]; ok {
+			res.NodesOnline++
+		}
+	}
+	if len(ids) > 0 {
+		var row struct {
+			Polls int
+			Seen  int
+		}
+		db.Model(&models.NodeAvailability{}).Select("SUM(polls) AS polls, SUM(seen) AS seen").
+			Where("node_id IN ? AND hour >= ?", ids, now.Add(-24*time.Hour).Truncate(time.Hour)).Scan(&row)
+		if row.Polls > 0 {
+			v := float64(row.Seen) * 100 / float64(row.Polls)
+			res.Availability = &v
+		}
+	}
+	return res
+}
+
+// cachedPublicStatus 返回缓存的公开状态，过期后重新生成
+func cachedPublicStatus() *publicStatus {
+	statusPageMu.Lock()
+	defer statusPageMu.Unlock()
+	if statusPageCache == nil || time.Since(statusPageCacheTime) > statusPageCacheTTL {
+		statusPageCache, statusPageCacheTime = buildPublicStatus(), time.Now()
+	}
+	return statusPageCache
+}
+
+// statusPageEnabled 未启用时返回 404，不暴露状态页的存在
+func statusPageEnabled(c *gin.Context) bool {
+	if getSettingValue("status_page_enabled", "false") != "true" {
+		respondError(c, 404, ErrNotFound, "Not Found")
+		return false
+	}
+	return true
+}
+
+// getPublicStatus 以 JSON 返回公开状态
+func getPublicStatus(c *gin.Context) {
+	if !statusPageEnabled(c) {
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=30")
+	c.JSON(200, cachedPublicStatus())
+}
+
+var statusPageTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"pct": func(v *float64) string {
+		if v == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.2f%%", *v)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="60">
+<title>{{.Title}}</title>
+<style>
+body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",Roboto,"PingFang SC","Microsoft YaHei",sans-serif;background:#f5f6f8;color:#1f2329;margin:0}
+main{max-width:720px;margin:40px auto;padding:0 16px}
+h1{font-size:24px;margin:0 0 16px}
+.banner{padding:16px 20px;border-radius:8px;color:#fff;font-size:18px;margin-bottom:24px}
+.operational{background:#2e9e5b}.degraded{background:#e0a100}.down{background:#d64541}
+.card{background:#fff;border-radius:8px;padding:16px 20px;margin-bottom:16px;box-shadow:0 1px 2px rgba(0,0,0,.06)}
+table{width:100%;border-collapse:collapse}th,td{text-align:left;padding:8px 4px;border-bottom:1px solid #eee}th{color:#646a73;font-weight:normal}
+.up{color:#2e9e5b}.off{color:#d64541}
+.big{font-size:28px;font-weight:600}
+footer{color:#8f959e;font-size:12px;text-align:center;margin-top:24px}
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Title}}</h1>
+<div class="banner {{.Status}}">{{if eq .Status "operational"}}所有服务运行正常{{else if eq .Status "degraded"}}部分服务异常{{else}}服务中断{{end}}</div>
+<div class="card">
+<div class="big">{{.NodesOnline}} / {{.NodesTotal}}</div>
+<div>节点在线 · 过去 24 小时平均在线率 {{pct .Availability}}</div>
+</div>
+{{if .Supernodes}}<div class="card">
+<table>
+<tr><th>Supernode</th><th>状态</th><th>24 小时</th><th>7 天</th><th>30 天</th></tr>
+{{range .Supernodes}}<tr><td>{{.Name}}</td><td>{{if .Up}}<span class="up">运行中</span>{{else}}<span class="off">不可用</span>{{end}}</td><td>{{pct .Uptime24h}}</td><td>{{pct .Uptime7d}}</td><td>{{pct .Uptime30d}}</td></tr>
+{{end}}</table>
+</div>{{end}}
+<footer>更新于 {{.UpdatedAt.Format "2006-01-02 15:04:05"}} · 页面每分钟自动刷新</footer>
+</main>
+</body>
+</html>
+`))
+
+// statusPage 渲染公开状态页
+func statusPage(c *gin.Context) {
+	if !statusPageEnabled(c) {
+		return
+	}
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "public, max-age=30")
+	c.Status(200)
+	statusPageTemplate.Execute(c.Writer, cachedPublicStatus())
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// instanceRuntime 一个 supernode 实例的运行时状态
+type instanceRuntime struct {
+	instance models.SupernodeInstance
+	client   *utils.MgmtClient
+	ctx      context.Context // 实例被删除或修改时取消
+	cancel   context.CancelFunc
+}
+
+var (
+	runtimes     = make(map[uint]*instanceRuntime)
+	runtimeMutex sync.RWMutex
+)
+
+// supernodeDir 默认实例与新建实例的配置文件目录，模拟模式下为数据库旁的 n2n-mock/
+func supernodeDir() string {
+	if mockServer != nil {
+		return mockDir
+	}
+	if appConfig.SupernodeDir != "" {
+		return appConfig.SupernodeDir
+	}
+	return defaultSupernodeDir
+}
+
+// defaultInstancePaths 默认实例的 supernode.conf 与 community.list 路径，可分别由 N2N_SUPERNODE_CONF / N2N_COMMUNITY_LIST 指定
+func defaultInstancePaths() (string, string) {
+	dir := supernodeDir()
+	conf, list := filepath.Join(dir, "supernode.conf"), filepath.Join(dir, "community.list")
+	if mockServer == nil && appConfig.SupernodeConfPath != "" {
+		conf = appConfig.SupernodeConfPath
+	}
+	if mockServer == nil && appConfig.CommunityListPath != "" {
+		list = appConfig.CommunityListPath
+	}
+	return conf, list
+}
+
+// ensureDefaultInstance 首次启动时按配置的路径创建默认实例；之后路径保存在数据库中，
+// 与配置不一致时只提示，避免环境变量覆盖在实例设置中所做的修改
+func ensureDefaultInstance() {
+	conf, list := defaultInstancePaths()
+	var inst models.SupernodeInstance
+	if err := db.Where("is_default = ?", true).First(&inst).Error; err == nil {
+		if (appConfig.SupernodeDir != "" || appConfig.SupernodeConfPath != "" || appConfig.CommunityListPath != "") &&
+			(inst.ConfigPath != conf || inst.CommunityListPath != list) {
+			log.Printf("Default supernode instance uses %s and %s, the configured paths (%s, %s) only apply on first start; "+
+				"change them with PUT /api/supernodes/%d", inst.ConfigPath, inst.CommunityListPath, conf, list, inst.ID)
+		}
+		return
+	}
+	var count int64
+	db.Model(&models.SupernodeInstance{}).Count(&count)
+	if count > 0 {
+		return
+	}
+	db.Create(&models.SupernodeInstance{
+		Name:              "default",
+		Unit:              appConfig.SupernodeUnit,
+		ConfigPath:        conf,
+		CommunityListPath: list,
+		MgmtAddr:          appConfig.MgmtAddr,
+		IsDefault:         true,
+	})
+}
+
+// startInstances 为所有实例启动管理端口客户端与日志分析
+func startInstances() {
+	var instances []models.SupernodeInstance
+	db.Find(&instances)
+	for _, inst := range instances {
+		startInstance(inst)
+	}
+}
+
+func startInstance(inst models.SupernodeInstance) {
+	password := string(inst.MgmtPassword)
+	if password == "" && inst.IsDefault {
+		password = appConfig.MgmtPassword
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	rt := &instanceRuntime{
+		instance: inst,
+		client:   newMgmtClient(instanceMgmtAddr(inst), password, appConfig.MgmtCacheTTL),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	go rt.client.StartCacheRefresher()
+
+	// 日志分析只在 leader 上运行，本实例之后成为 leader 时由 becomeLeader 启动
+	runtimeMutex.Lock()
+	if isLeader() {
+		go startLogAnalyzer(ctx, inst.Name, instanceLogSources(inst))
+	}
+	runtimes[inst.ID] = rt
+	runtimeMutex.Unlock()
+}
+
+func stopInstance(id uint) {
+	runtimeMutex.Lock()
+	rt, ok := runtimes[id]
+	delete(runtimes, id)
+	runtimeMutex.Unlock()
+	if ok {
+		rt.cancel()
+		rt.client.Close()
+	}
+}
+
+// listRuntimes 按 ID 顺序返回所有实例
+func listRuntimes() []*instanceRuntime {
+	runtimeMutex.RLock()
+	defer runtimeMutex.RUnlock()
+	res := make([]*instanceRuntime, 0, len(runtimes))
+	for _, rt := range runtimes {
+		res = append(res, rt)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].instance.ID < res[j].instance.ID })
+	return res
+}
+
+// runtimeFor 查找实例运行时，id 为 0 时返回默认实例
+func runtimeFor(id uint) *instanceRuntime {
+	runtimeMutex.RLock()
+	defer runtimeMutex.RUnlock()
+	if id != 0 {
+		if rt, ok := runtimes[id]; ok {
+			return rt
+		}
+		return nil
+	}
+	for _, rt := range runtimes {
+		if rt.instance.IsDefault {
+			return rt
+		}
+	}
+	return nil
+}
+
+// newMgmtClient 按配置的超时与重试次数创建管理端口客户端
+func newMgmtClient(addr, password string, cacheTTL time.Duration) *utils.MgmtClient {
+	retries := appConfig.MgmtRetries
+	if retries <= 0 {
+		retries = -1 // MgmtClient 中 0 表示默认值
+	}
+	return &utils.MgmtClient{Addr: addr, Password: password, CacheTTL: cacheTTL, Timeout: appConfig.MgmtTimeout, Retries: retries}
+}
+
+var (
+	fallbackMgmt     *utils.MgmtClient
+	fallbackMgmtOnce sync.Once
+)
+
+// defaultMgmt 返回默认实例的管理端口客户端，没有默认实例时使用按环境变量创建的共享客户端
+func defaultMgmt() *utils.MgmtClient {
+	if rt := runtimeFor(0); rt != nil {
+		return rt.client
+	}
+	fallbackMgmtOnce.Do(func() {
+		fallbackMgmt = newMgmtClient(instanceMgmtAddr(models.SupernodeInstance{MgmtAddr: appConfig.MgmtAddr}), appConfig.MgmtPassword, 0)
+	})
+	return fallbackMgmt
+}
+
+// communityInstanceID 返回社区实际所属的实例 ID
+func communityInstanceID(comm models.Community) uint {
+	if comm.SupernodeID != 0 {
+		return comm.SupernodeID
+	}
+	if rt := runtimeFor(0); rt != nil {
+		return rt.instance.ID
+	}
+	return 0
+}
+
+// allEdgeInfo 合并所有实例的在线 edge，任一实例查询失败时返回最后一个错误
+func allEdgeInfo() (map[string]utils.EdgeInfo, error) {
+	merged, outages := edgeInfoWithOutages()
+	if len(outages) > 0 {
+		o := outages[len(outages)-1]
+		return merged, fmt.Errorf("%s: %s", o.Name, o.Error)
+	}
+	return merged, nil
+}
+
+// mgmtOutage 管理端口查询失败的实例，其下节点的在线状态未知
+type mgmtOutage struct {
+	InstanceID uint       `json:"instance_id"`
+	Name       string     `json:"name"`
+	Error      string     `json:"error"`
+	Failures   int        `json:"failures"` // 连续失败次数
+	Since      *time.Time `json:"since"`    // 最后一次成功的时间，启动后从未成功时为 null
+}
+
+// edgeInfoWithOutages 合并所有实例的在线 edge，并返回查询失败的实例
+func edgeInfoWithOutages() (map[string]utils.EdgeInfo, []mgmtOutage) {
+	merged := make(map[string]utils.EdgeInfo)
+	outages := make([]mgmtOutage, 0)
+	for _, rt := range listRuntimes() {
+		edges, err := rt.client.GetEdgeInfo()
+		if err != nil {
+			st := rt.client.Status()
+			o := mgmtOutage{InstanceID: rt.instance.ID, Name: rt.instance.Name, Error: err.Error(), Failures: st.Failures}
+			if !st.LastSuccess.IsZero() {
+				o.Since = &st.LastSuccess
+			}
+			outages = append(outages, o)
+			continue
+		}
+		for mac, info := range edges {
+			merged[mac] = info
+		}
+	}
+	return merged, outages
+}
+
+// markOnlineUnknown 管理端口查询失败时，为相应实例下的节点加上 online_unknown: true（is_online 为 false 并不代表离线），
+// 并设置响应头 X-Supernode-Unreachable: true
+func markOnlineUnknown(c *gin.Context, list []interface{}, outages []mgmtOutage) {
+	if len(outages) == 0 {
+		return
+	}
+	down := make(map[uint]bool)
+	for _, o := range outages {
+		down[o.InstanceID] = true
+	}
+	var comms []models.Community
+	db.Find(&comms)
+	commDown := make(map[string]bool)
+	for _, cm := range comms {
+		if down[communityInstanceID(cm)] {
+			commDown[cm.Name] = true
+		}
+	}
+	for _, item := range list {
+		if h, ok := item.(gin.H); ok {
+			if name, _ := h["community"].(string); commDown[name] {
+				h["online_unknown"] = true
+			}
+		}
+	}
+	c.Header("X-Supernode-Unreachable", "true")
+}
+
+// instanceParam 解析请求中的 ?instance= 参数，未指定时使用默认实例
+func instanceParam(c *gin.Context) (*instanceRuntime, bool) {
+	var id uint
+	if v := c.Query("instance"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			respondError(c, 400, ErrInvalidRequest, "Invalid instance")
+			return nil, false
+		}
+		id = uint(parsed)
+	}
+	rt := runtimeFor(id)
+	if rt == nil {
+		respondError(c, 404, ErrInstanceNotFound, "Supernode instance not found")
+		return nil, false
+	}
+	return rt, true
+}
+
+// instanceLogSources 返回实例的日志来源，未配置时跟踪实例的 systemd 单元
+func instanceLogSources(inst models.SupernodeInstance) []string {
+	if sources := utils.SplitList(inst.LogSources); len(sources) > 0 {
+		return sources
+	}
+	return []string{inst.Unit}
+}
+
+// isSupernodeActive 通过 systemd 判断指定单元是否在运行
+func isSupernodeActive(unit string) bool {
+	return host.ServiceActive(unit)
+}
+
+type instanceInput struct {
+	Name              string `json:"name"`
+	Unit              string `json:"unit"`
+	ConfigPath        string `json:"config_path"`
+	CommunityListPath string `json:"community_list_path"`
+	MgmtAddr          string `json:"mgmt_addr"`
+	MgmtPassword      string `json:"mgmt_password"`
+	PublicHost        string `json:"public_host"`
+	LogSources        string `json:"log_sources"`
+}
+
+// instanceFileNameInvalid 不能直接用作文件名的实例名
+var instanceFileNameInvalid = regexp.MustCompile(`[^A-Za-z0-9_.-]|^\.`)
+
+// fillDefaultPaths 新建实例未填写路径时使用配置目录下以实例名区分的文件
+func (in *instanceInput) fillDefaultPaths() {
+	name := strings.TrimSpace(in.Name)
+	if name == "" || instanceFileNameInvalid.MatchString(name) {
+		return
+	}
+	if in.ConfigPath == "" {
+		in.ConfigPath = filepath.Join(supernodeDir(), "supernode-"+name+".conf")
+	}
+	if in.CommunityListPath == "" {
+		in.CommunityListPath = filepath.Join(supernodeDir(), "community-"+name+".list")
+	}
+}
+
+func (in instanceInput) validate() error {
+	if strings.TrimSpace(in.Name) == "" {
+		return newAPIError(ErrInvalidRequest, "Instance name is required")
+	}
+	if strings.TrimSpace(in.Unit) == "" {
+		return newAPIError(ErrInvalidRequest, "Systemd unit is required")
+	}
+	if in.ConfigPath == "" || in.CommunityListPath == "" {
+		return newAPIError(ErrInvalidRequest, "Config and community list paths are required")
+	}
+	if _, _, err := net.SplitHostPort(in.MgmtAddr); err != nil {
+		return newAPIError(ErrInvalidRequest, "Invalid management address")
+	}
+	for _, src := range utils.SplitList(in.LogSources) {
+		if !isLogFile(src) && (strings.HasPrefix(src, ".") || strings.ContainsAny(src, `/\`)) {
+			return newAPIError(ErrInvalidRequest, "Log file path must be absolute")
+		}
+	}
+	return nil
+}
+
+func getSupernodes(c *gin.Context) {
+	var instances []models.SupernodeInstance
+	db.Order("id").Find(&instances)
+	res := make([]gin.H, 0, len(instances))
+	for _, inst := range instances {
+		var commCount int64
+		q := db.Model(&models.Community{}).Where("supernode_id = ?", inst.ID)
+		if inst.IsDefault {
+			q = q.Or("supernode_id = 0")
+		}
+		q.Count(&commCount)
+		res = append(res, gin.H{"instance": inst, "active": isSupernodeActive(inst.Unit), "community_count": commCount})
+	}
+	c.JSON(200, res)
+}
+
+func createSupernode(c *gin.Context) {
+	var in instanceInput
+	if !bindJSON(c, &in) {
+		return
+	}
+	in.fillDefaultPaths()
+	if err := in.validate(); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return
+	}
+	var existing models.SupernodeInstance
+	if err := db.Where("name = ?", in.Name).First(&existing).Error; err == nil {
+		respondError(c, 400, ErrInstanceExists, "Instance already exists")
+		return
+	}
+	inst := models.SupernodeInstance{
+		Name: in.Name, Unit: in.Unit, ConfigPath: in.ConfigPath, CommunityListPath: in.CommunityListPath,
+		MgmtAddr: in.MgmtAddr, MgmtPassword: models.EncryptedString(in.MgmtPassword), PublicHost: in.PublicHost, LogSources: in.LogSources,
+	}
+	if err := db.Create(&inst).Error; err != nil {
+		respondError(c, 500, ErrInternal, "Failed to create instance")
+		return
+	}
+	startInstance(inst)
+	c.JSON(200, inst)
+}
+
+func updateSupernode(c *gin.Context) {
+	var inst models.SupernodeInstance
+	if err := db.First(&inst, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrInstanceNotFound, "Supernode instance not found")
+		return
+	}
+	var in instanceInput
+	if !bindJSON(c, &in) {
+		return
+	}
+	if err := in.validate(); err != nil {
+		respondErr(c, 400, err, ErrInvalidRequest)
+		return
+	}
+	inst.Name, inst.Unit, inst.ConfigPath, inst.CommunityListPath = in.Name, in.Unit, in.ConfigPath, in.CommunityListPath
+	inst.MgmtAddr, inst.PublicHost, inst.LogSources = in.MgmtAddr, in.PublicHost, in.LogSources
+	if in.MgmtPassword != "" {
+		inst.MgmtPassword = models.EncryptedString(in.MgmtPassword)
+	}
+	if err := db.Save(&inst).Error; err != nil {
+		respondError(c, 500, ErrInternal, "Failed to update instance")
+		return
+	}
+	stopInstance(inst.ID)
+	startInstance(inst)
+	if err := syncCommunityList(); err != nil {
+		log.Printf("Failed to sync community list after instance update: %v", err)
+	}
+	c.JSON(200, inst)
+}
+
+func deleteSupernode(c *gin.Context) {
+	var inst models.SupernodeInstance
+	if err := db.First(&inst, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrInstanceNotFound, "Supernode instance not found")
+		return
+	}
+	if inst.IsDefault {
+		respondError(c, 400, ErrInstanceInUse, "Default instance cannot be deleted")
+		return
+	}
+	var commCount int64
+	db.Model(&models.Community{}).Where("supernode_id = ?", inst.ID).Count(&commCount)
+	if commCount > 0 {
+		respondError(c, 400, ErrInstanceInUse, "Instance still has communities")
+		return
+	}
+	db.Delete(&inst)
+	stopInstance(inst.ID)
+	c.JSON(200, gin.H{"message": "deleted"})
+}
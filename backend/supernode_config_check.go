@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// supernode.conf 校验：supernode 没有只检查配置而不启动的 --dry-run，写入前由面板按 n2n 3.x 的选项校验，
+// 避免保存了错误的配置、重启失败而面板仍显示正常：
+//   POST /api/supernode/config/validate  请求体与保存相同（要修改的选项），返回合并后的配置、问题列表与文件差异，不写入
+//   POST /api/supernode/config           有 error 级别的问题时返回 400 (SUPERNODE_CONFIG_INVALID)，?force=true 时仍然写入
+// 除格式外还检查与面板的配合：-t 与实例的管理端口地址一致、-c 指向实例的 community.list、端口不与其他实例冲突。
+// warning 级别的问题不阻止保存，随响应返回。部署定义导入时同样校验其中的 supernode 配置。
+
+const (
+	configProblemError   = "error"
+	configProblemWarning = "warning"
+
+	defaultSupernodeMgmtPort = 5645 // supernode 未配置 -t 时的管理端口
+)
+
+// supernodeOption supernode 配置文件中的一个选项
+type supernodeOption struct {
+	flag  bool // 不带值
+	since int  // 从哪个 n2n 主版本开始支持
+}
+
+// supernodeOptions n2n 3.x supernode 支持的选项，键与 utils.ReadSupernodeConfig 一致（去掉一个前导 "-"）
+var supernodeOptions = map[string]supernodeOption{
+	"p":                    {since: 2},
+	"c":                    {since: 2},
+	"l":                    {since: 2},
+	"t":                    {since: 2},
+	"u":                    {since: 2},
+	"g":                    {since: 2},
+	"f":                    {flag: true, since: 2},
+	"v":                    {flag: true, since: 2},
+	"F":                    {since: 3},
+	"m":                    {since: 3},
+	"M":                    {flag: true, since: 3},
+	"V":                    {since: 3},
+	"a":                    {since: 3},
+	"-management-password": {since: 3},
+}
+
+// autoIPRangePattern -a 的格式，如 10.128.255.0-10.255.255.0/24
+var autoIPRangePattern = regexp.MustCompile(`^(\d+\.\d+\.\d+\.\d+)-(\d+\.\d+\.\d+\.\d+)/(\d+)$`)
+
+// configProblem 校验发现的一个问题
+type configProblem struct {
+	Level   string `json:"level"` // error 或 warning
+	Option  string `json:"option,omitempty"`
+	Message string `json:"message"`
+}
+
+type configProblems []configProblem
+
+func (ps *configProblems) add(level, option, format string, args ...interface{}) {
+	*ps = append(*ps, configProblem{Level: level, Option: option, Message: fmt.Sprintf(format, args...)})
+}
+
+// hasErrors 是否有阻止保存的问题
+func (ps configProblems) hasErrors() bool {
+	for _, p := range ps {
+		if p.Level == configProblemError {
+			return true
+		}
+	}
+	return false
+}
+
+// err 把 error 级别的问题合并为一个错误，用于部署定义导入
+func (ps configProblems) err() error {
+	var msgs []string
+	for _, p := range ps {
+		if p.Level == configProblemError {
+			msgs = append(msgs, p.Message)
+		}
+	}
+	return newAPIError(ErrSupernodeConfigInvalid, "supernode.conf: "+strings.Join(msgs, "; "))
+}
+
+// parsePortOption 解析端口，-p 还可以带绑定地址（[ip:]port）
+func parsePortOption(v string, withAddr bool) (int, error) {
+	if withAddr {
+		if i := strings.LastIndex(v, ":"); i >= 0 {
+			if net.ParseIP(strings.Trim(v[:i], "[]")) == nil {
+				return 0, fmt.Errorf("invalid bind address %q", v[:i])
+			}
+			v = v[i+1:]
+		}
+	}
+	port, err := strconv.Atoi(v)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", v)
+	}
+	return port, nil
+}
+
+// supernodePorts 配置中的监听端口与管理端口，未配置或无效时为 supernode 的默认值
+func supernodePorts(conf map[string]string) (int, int) {
+	port, _ := strconv.Atoi(defaultSupernodePort)
+	mgmt := defaultSupernodeMgmtPort
+	if p, err := parsePortOption(conf["p"], true); err == nil {
+		port = p
+	}
+	if p, err := parsePortOption(conf["t"], false); err == nil {
+		mgmt = p
+	}
+	return port, mgmt
+}
+
+// checkSupernodeConfig 校验实例 inst 将要写入的配置 conf
+func checkSupernodeConfig(inst models.SupernodeInstance, conf map[string]string) configProblems {
+	problems := make(configProblems, 0)
+	keys := make([]string, 0, len(conf))
+	for k := range conf {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	major := 0
+	if rt := runtimeFor(inst.ID); rt != nil && inst.ID != 0 {
+		if v := supernodeVersionFor(rt).Installed; v != "" {
+			major, _ = strconv.Atoi(strings.SplitN(v, ".", 2)[0])
+		}
+	}
+	for _, k := range keys {
+		v := conf[k]
+		opt, known := supernodeOptions[k]
+		switch {
+		case strings.ContainsAny(k+v, "\r\n"):
+			problems.add(configProblemError, k, "option must be on a single line")
+			continue
+		case !known:
+			problems.add(configProblemWarning, k, "unknown option -%s, supernode may refuse to start", k)
+			continue
+		case opt.flag && v != "":
+			problems.add(configProblemError, k, "-%s does not take a value", k)
+			continue
+		case !opt.flag && v == "":
+			problems.add(configProblemError, k, "-%s requires a value", k)
+			continue
+		}
+		if major > 0 && major < opt.since {
+			problems.add(configProblemWarning, k, "-%s needs n2n %d.x, the installed supernode is %d.x", k, opt.since, major)
+		}
+		switch k {
+		case "p", "t":
+			if _, err := parsePortOption(v, k == "p"); err != nil {
+				problems.add(configProblemError, k, "%v", err)
+			}
+		case "l":
+			if _, port, err := net.SplitHostPort(v); err != nil {
+				problems.add(configProblemError, k, "federation peer must be host:port")
+			} else if _, err := parsePortOption(port, false); err != nil {
+				problems.add(configProblemError, k, "%v", err)
+			}
+		case "a":
+			m := autoIPRangePattern.FindStringSubmatch(v)
+			if m == nil || net.ParseIP(m[1]).To4() == nil || net.ParseIP(m[2]).To4() == nil {
+				problems.add(configProblemError, k, "auto IP range must look like 10.128.255.0-10.255.255.0/24")
+			} else if bits, _ := strconv.Atoi(m[3]); bits < 8 || bits > 30 {
+				problems.add(configProblemError, k, "auto IP range prefix must be between 8 and 30")
+			}
+		case "m":
+			if err := validateMacAddress(v); err != nil {
+				problems.add(configProblemError, k, "invalid MAC address %q", v)
+			}
+		case "u", "g":
+			if _, err := strconv.ParseUint(v, 10, 32); err != nil {
+				problems.add(configProblemError, k, "-%s must be a numeric id", k)
+			}
+		}
+	}
+
+	// 与面板的配合
+	port, mgmt := supernodePorts(conf)
+	if port == mgmt {
+		problems.add(configProblemError, "t", "management port %d is the same as the listening port", mgmt)
+	}
+	if _, p, err := net.SplitHostPort(inst.MgmtAddr); err == nil && p != strconv.Itoa(mgmt) {
+		problems.add(configProblemError, "t", "management port %d does not match the instance management address %s, the panel could not reach the supernode", mgmt, inst.MgmtAddr)
+	}
+	switch c, ok := conf["c"]; {
+	case !ok:
+		problems.add(configProblemWarning, "c", "no community list, the supernode accepts any community")
+	case c != "" && c != inst.CommunityListPath:
+		problems.add(configProblemError, "c", "community list %s is not the instance's %s, communities managed by the panel would not apply", c, inst.CommunityListPath)
+	}
+	password := string(inst.MgmtPassword)
+	if password == "" && inst.IsDefault {
+		password = appConfig.MgmtPassword
+	}
+	if v, ok := conf["-management-password"]; ok && v != password {
+		problems.add(configProblemWarning, "-management-password", "management password differs from the instance setting, write commands from the panel will fail")
+	}
+	if f, ok := conf["F"]; ok && f != getSettingValue("supernode_federation", "*Federation") && len(userAuthCommunities(inst.ID)) > 0 {
+		problems.add(configProblemWarning, "F", "federation name differs from setting supernode_federation, edges of user-auth communities would get a wrong supernode public key")
+	}
+
+	// 同一主机上的其他实例
+	var others []models.SupernodeInstance
+	db.Where("id <> ?", inst.ID).Find(&others)
+	for _, o := range others {
+		oc, err := utils.ReadSupernodeConfig(o.ConfigPath)
+		if err != nil || o.ConfigPath == inst.ConfigPath {
+			continue
+		}
+		oport, omgmt := supernodePorts(oc)
+		if port == oport || port == omgmt {
+			problems.add(configProblemError, "p", "port %d is already used by instance %s", port, o.Name)
+		}
+		if mgmt == oport || mgmt == omgmt {
+			problems.add(configProblemError, "t", "management port %d is already used by instance %s", mgmt, o.Name)
+		}
+	}
+	return problems
+}
+
+// mergeSupernodeConfig 读取实例当前的配置并合并要修改的选项，始终前台运行并输出详细日志
+// 文件不存在时按空配置处理；其他读取错误（如权限不足）返回错误，以免覆盖掉无法读取的现有配置
+func mergeSupernodeConfig(inst models.SupernodeInstance, changes map[string]string) (map[string]string, string, error) {
+	conf, err := utils.ReadSupernodeConfig(inst.ConfigPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, "", err
+	}
+	var current string
+	if data, err := os.ReadFile(inst.ConfigPath); err == nil {
+		current = string(data)
+	}
+	if conf == nil {
+		conf = make(map[string]string)
+	}
+	for k, v := range changes {
+		conf[k] = v
+	}
+	conf["f"], conf["v"] = "", ""
+	return conf, current, nil
+}
+
+// validateSupernodeConfig 校验要修改的选项而不写入，返回合并后的配置、问题与文件差异
+func validateSupernodeConfig(c *gin.Context) {
+	rt, ok := instanceParam(c)
+	if !ok {
+		return
+	}
+	var changes map[string]string
+	if !bindJSON(c, &changes) {
+		return
+	}
+	conf, current, err := mergeSupernodeConfig(rt.instance, changes)
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Failed to read supernode.conf", gin.H{"detail": err.Error()})
+		return
+	}
+	problems := checkSupernodeConfig(rt.instance, conf)
+	content := utils.FormatSupernodeConfig(conf)
+	c.JSON(200, gin.H{"valid": !problems.hasErrors(), "problems": problems, "config": conf,
+		"content": content, "diff": utils.LineDiff(current, content)})
+}
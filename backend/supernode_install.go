@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// supernode 安装向导：先装好面板、还没有 supernode 的主机上，由管理员通过 POST /api/supernode/install 完成安装：
+//   1. 从 /etc/os-release 识别发行版与包管理器
+//   2. 安装 n2n：method=source（默认）安装编译依赖后从 GitHub 拉取 ref（默认 3.0）编译安装，得到 3.x 的全部功能；
+//      method=package 使用发行版自带的 n2n 软件包（Debian / Ubuntu / Alpine，通常是 2.8）
+//   3. 写入实例的 supernode.conf（监听端口、community.list、管理端口）与 community.list
+//   4. 写入 systemd 单元，启用并启动
+// 安装在后台任务中执行，通过 /api/tools/jobs/:id/stream 获取实时输出；dry_run 只返回将执行的步骤。
+// 已安装的 supernode、已存在的 supernode.conf 与 systemd 单元默认保留，分别由 reinstall、overwrite 控制是否覆盖。
+
+const (
+	supernodeInstallTimeout = 30 * time.Minute
+	defaultN2NSourceRef     = "3.0"
+)
+
+var (
+	supernodeInstalling atomic.Bool
+	gitRefPattern       = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+)
+
+type supernodeInstallRequest struct {
+	Method    string `json:"method" binding:"omitempty,oneof=source package"`
+	Ref       string `json:"ref"`                                      // method=source 时编译的分支或标签
+	Port      int    `json:"port" binding:"omitempty,min=1,max=65535"` // supernode 监听的 UDP 端口
+	Reinstall bool   `json:"reinstall"`                                // 已安装 supernode 时仍重新安装
+	Overwrite bool   `json:"overwrite"`                                // 覆盖已存在的 supernode.conf 与 systemd 单元
+	DryRun    bool   `json:"dry_run"`
+}
+
+// installStep 安装的一步，command 为展示给用户的命令或说明
+type installStep struct {
+	Title   string `json:"title"`
+	Command string `json:"command,omitempty"`
+	run     func(ctx context.Context, onLine func(string)) error
+}
+
+// supernodeInstallResult 安装完成后的结果
+type supernodeInstallResult struct {
+	Distro     utils.Distro `json:"distro"`
+	Version    string       `json:"version,omitempty"`
+	Binary     string       `json:"binary"`
+	ConfigPath string       `json:"config_path"`
+	Unit       string       `json:"unit"`
+	Active     bool         `json:"active"`
+}
+
+func commandStep(title, name string, args ...string) installStep {
+	return installStep{
+		Title:   title,
+		Command: strings.TrimSpace(name + " " + strings.Join(args, " ")),
+		run: func(ctx context.Context, onLine func(string)) error {
+			return utils.StreamCommand(ctx, onLine, name, args...)
+		},
+	}
+}
+
+func noteStep(title string) installStep {
+	return installStep{Title: title, run: func(context.Context, func(string)) error { return nil }}
+}
+
+// installedSupernodeBinary 安装后的 supernode 程序位置，n2n 的 make install 与发行版软件包都安装在 /usr/sbin
+func installedSupernodeBinary() string {
+	if filepath.IsAbs(utils.SupernodeBinary) {
+		return utils.SupernodeBinary
+	}
+	if p, err := exec.LookPath(utils.SupernodeBinary); err == nil {
+		return p
+	}
+	return "/usr/sbin/supernode"
+}
+
+// n2nPackageSteps 安装 n2n 的步骤
+func n2nPackageSteps(p supernodeInstallRequest, d utils.Distro) ([]installStep, error) {
+	pm := d.PackageManager()
+	if pm == "" {
+		return nil, fmt.Errorf("unsupported distribution %s", d.Name)
+	}
+	steps := make([]installStep, 0)
+	if pm == "apt" {
+		steps = append(steps, commandStep("Update package lists", "apt-get", "update"))
+	}
+	if p.Method == "package" {
+		if pm != "apt" && pm != "apk" {
+			return nil, fmt.Errorf("%s has no n2n package, use method source", d.Name)
+		}
+		name, args := utils.InstallCommand(pm, "n2n")
+		return append(steps, commandStep("Install the n2n package", name, args...)), nil
+	}
+
+	dir := filepath.Join(os.TempDir(), "n2n-admin-build")
+	name, args := utils.InstallCommand(pm, utils.BuildDependencies(pm)...)
+	clone := commandStep("Download n2n "+p.Ref, "git", "clone", "--depth", "1", "--branch", p.Ref, utils.N2NSourceRepo, dir)
+	download := clone.run
+	clone.run = func(ctx context.Context, onLine func(string)) error {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		return download(ctx, onLine)
+	}
+	build := commandStep("Build and install n2n", "sh", "-c", "cd "+dir+" && ./autogen.sh && ./configure && make && make install")
+	cleanup := installStep{Title: "Remove the build directory", Command: "rm -rf " + dir, run: func(context.Context, func(string)) error {
+		return os.RemoveAll(dir)
+	}}
+	return append(steps, commandStep("Install build dependencies", name, args...), clone, build, cleanup), nil
+}
+
+// supernodeConfigStep 写入 supernode.conf：监听端口、community.list、管理端口，前台运行并输出详细日志
+func supernodeConfigStep(p supernodeInstallRequest, inst models.SupernodeInstance) installStep {
+	path := inst.ConfigPath
+	if _, err := os.Stat(path); err == nil && !p.Overwrite {
+		return noteStep(path + " already exists, keeping it")
+	}
+	conf := map[string]string{"p": strconv.Itoa(p.Port), "c": inst.CommunityListPath, "f": "", "v": ""}
+	if _, port, err := net.SplitHostPort(inst.MgmtAddr); err == nil {
+		conf["t"] = port
+	}
+	password := string(inst.MgmtPassword)
+	if password == "" && inst.IsDefault {
+		password = appConfig.MgmtPassword
+	}
+	if password != "" {
+		conf["-management-password"] = password
+	}
+	return installStep{Title: "Write " + path, run: func(ctx context.Context, onLine func(string)) error {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := utils.WriteSupernodeConfig(path, conf); err != nil {
+			return err
+		}
+		// 按数据库生成 community.list，没有社区时写入空文件
+		if err := syncCommunityList(); err != nil {
+			return err
+		}
+		if _, err := os.Stat(inst.CommunityListPath); os.IsNotExist(err) {
+			return utils.WriteCommunityList(inst.CommunityListPath, nil)
+		}
+		return nil
+	}}
+}
+
+// supernodeUnitSteps 写入 systemd 单元，启用并（重新）启动
+func supernodeUnitSteps(p supernodeInstallRequest, inst models.SupernodeInstance) ([]installStep, error) {
+	unit := strings.TrimSuffix(inst.Unit, ".service")
+	if strings.Contains(unit, "@") {
+		return nil, fmt.Errorf("unit %s is an instance of a template unit, create the template manually", inst.Unit)
+	}
+	path := filepath.Join(appConfig.SystemdUnitDir, unit+".service")
+	steps := make([]installStep, 0, 4)
+	if _, err := os.Stat(path); err == nil && !p.Overwrite {
+		steps = append(steps, noteStep(path+" already exists, keeping it"))
+	} else {
+		steps = append(steps, installStep{Title: "Write " + path, run: func(ctx context.Context, onLine func(string)) error {
+			return os.WriteFile(path, []byte(utils.SupernodeUnitFile(installedSupernodeBinary(), inst.ConfigPath)), 0644)
+		}})
+	}
+	return append(steps,
+		commandStep("Reload systemd", "systemctl", "daemon-reload"),
+		commandStep("Enable "+unit, "systemctl", "enable", unit),
+		commandStep("Start "+unit, "systemctl", "restart", unit),
+	), nil
+}
+
+// planSupernodeInstall 生成安装步骤
+func planSupernodeInstall(p supernodeInstallRequest, d utils.Distro, inst models.SupernodeInstance) ([]installStep, error) {
+	steps := make([]installStep, 0)
+	if v, err := utils.SupernodeVersion(); err == nil && !p.Reinstall {
+		steps = append(steps, noteStep(fmt.Sprintf("supernode %s is already installed, skipping installation", v)))
+	} else {
+		pkg, err := n2nPackageSteps(p, d)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, pkg...)
+	}
+	steps = append(steps, supernodeConfigStep(p, inst))
+	unit, err := supernodeUnitSteps(p, inst)
+	if err != nil {
+		return nil, err
+	}
+	return append(steps, unit...), nil
+}
+
+// runSupernodeInstall 依次执行安装步骤，最后检查版本与服务状态
+func runSupernodeInstall(ctx context.Context, onLine func(string), steps []installStep, d utils.Distro, inst models.SupernodeInstance) (interface{}, error) {
+	defer supernodeInstalling.Store(false)
+	res := supernodeInstallResult{Distro: d, ConfigPath: inst.ConfigPath, Unit: inst.Unit}
+	for i, s := range steps {
+		onLine(fmt.Sprintf("==> [%d/%d] %s", i+1, len(steps), s.Title))
+		if s.Command != "" {
+			onLine("$ " + s.Command)
+		}
+		if err := s.run(ctx, onLine); err != nil {
+			return res, fmt.Errorf("%s: %w", s.Title, err)
+		}
+	}
+	forgetSupernodeVersion(inst.ID)
+	res.Binary = installedSupernodeBinary()
+	if v, err := utils.SupernodeVersion(); err == nil {
+		res.Version = v
+		onLine("supernode " + v + " installed at " + res.Binary)
+	}
+	time.Sleep(2 * time.Second) // 等待 supernode 启动或启动失败
+	if res.Active = isSupernodeActive(inst.Unit); !res.Active {
+		return res, fmt.Errorf("%s is not running, check journalctl -u %s", inst.Unit, inst.Unit)
+	}
+	onLine(inst.Unit + " is running")
+	return res, nil
+}
+
+// installSupernode 安装 supernode 并配置为实例的 systemd 服务，?instance= 指定实例（默认实例）
+func installSupernode(c *gin.Context) {
+	rt, ok := instanceParam(c)
+	if !ok {
+		return
+	}
+	var p supernodeInstallRequest
+	if !bindOptionalJSON(c, &p) {
+		return
+	}
+	if p.Method == "" {
+		p.Method = "source"
+	}
+	if p.Ref == "" {
+		p.Ref = defaultN2NSourceRef
+	}
+	if p.Port == 0 {
+		p.Port, _ = strconv.Atoi(defaultSupernodePort)
+	}
+	if !gitRefPattern.MatchString(p.Ref) {
+		respondError(c, 400, ErrInvalidRequest, "Invalid source ref")
+		return
+	}
+	switch {
+	case runtime.GOOS != "linux":
+		respondError(c, 409, ErrInstallUnsupported, "Supernode installation is only supported on Linux")
+		return
+	case mockServer != nil && !p.DryRun:
+		respondError(c, 409, ErrInstallUnsupported, "Supernode installation is not available in mock mode")
+		return
+	case os.Geteuid() != 0 && !p.DryRun:
+		respondError(c, 409, ErrInstallUnsupported, "n2n-admin must run as root to install the supernode")
+		return
+	}
+	distro, err := utils.DetectDistro()
+	var steps []installStep
+	if err == nil {
+		steps, err = planSupernodeInstall(p, distro, rt.instance)
+	}
+	if err != nil {
+		respondError(c, 409, ErrInstallUnsupported, "Supernode installation is not supported on this host", gin.H{"detail": err.Error()})
+		return
+	}
+	if p.DryRun {
+		c.JSON(200, gin.H{"distro": distro, "steps": steps})
+		return
+	}
+	startSupernodeInstall(c, p, distro, steps, rt.instance)
+}
+
+// startSupernodeInstall 以后台任务执行安装，同一时间只允许一个安装任务
+func startSupernodeInstall(c *gin.Context, p supernodeInstallRequest, d utils.Distro, steps []installStep, inst models.SupernodeInstance) {
+	if !supernodeInstalling.CompareAndSwap(false, true) {
+		respondError(c, 409, ErrInstallInProgress, "A supernode installation is already running")
+		return
+	}
+	recordAudit(c, "supernode.install", inst.Name, fmt.Sprintf("method %s, ref %s, port %d", p.Method, p.Ref, p.Port))
+	toolJobsMutex.Lock()
+	pruneToolJobs()
+	j := &toolJob{Command: "supernode_install", Target: inst.Name, User: c.GetString("username")}
+	view := launchToolJobLocked(j, supernodeInstallTimeout, func(ctx context.Context, onLine func(string)) (interface{}, error) {
+		return runSupernodeInstall(ctx, onLine, steps, d, inst)
+	})
+	toolJobsMutex.Unlock()
+	c.JSON(202, view)
+}
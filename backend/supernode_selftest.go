@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// supernode UDP 端口自检：edge 连不上时，逐项检查是 supernode 没在监听、edge 配置中的地址不对，还是防火墙 / NAT 挡住了入站 UDP。
+//   - listening：本机 UDP 端口是否已被占用（能绑定说明 supernode 没有在该端口运行）
+//   - address：写入 edge 配置的地址与端口，是否与检测到的公网 IP、supernode 监听端口一致
+//   - external：由外部检测服务（设置 selftest_service_url）从公网向该地址发起探测
+//   - federation_peer：联盟中的另一台 supernode（设置 selftest_peer，需在其 -l 中加入本机）会定期主动注册到本机，
+//     管理端口的 supernodes 列表中最近见过它，说明来自公网的 UDP 能到达 supernode
+// 外部检测服务接口：POST JSON {"host": "...", "port": 7654}，返回 {"reachable": true|false, "rtt_ms": 12.3, "error": "..."}
+
+const (
+	selfTestOK      = "ok"
+	selfTestWarn    = "warn"
+	selfTestFail    = "fail"
+	selfTestSkipped = "skipped"
+
+	selfTestServiceTimeout = 10 * time.Second
+	selfTestPeerMaxAge     = 2 * time.Minute
+)
+
+// selfTestStep 自检的一项结果
+type selfTestStep struct {
+	Name    string      `json:"name"`
+	Status  string      `json:"status"`
+	Message string      `json:"message,omitempty"`
+	Detail  interface{} `json:"detail,omitempty"`
+}
+
+// selfTestListening 尝试绑定 supernode 的端口，绑定失败说明端口已被占用（通常就是 supernode 本身）
+func selfTestListening(port string) selfTestStep {
+	step := selfTestStep{Name: "listening", Detail: gin.H{"port": port}}
+	if mockServer != nil {
+		step.Status, step.Message = selfTestSkipped, "mock mode"
+		return step
+	}
+	conn, err := net.ListenPacket("udp", ":"+port)
+	if err != nil {
+		step.Status = selfTestOK
+		return step
+	}
+	conn.Close()
+	step.Status, step.Message = selfTestFail, fmt.Sprintf("nothing is listening on UDP port %s, is the supernode running with -p %s?", port, port)
+	return step
+}
+
+// selfTestAddress 检查 edge 配置中的地址：端口与监听端口不同（可能是端口映射）或与检测到的公网 IP 不一致时给出警告
+func selfTestAddress(addr, port string) selfTestStep {
+	step := selfTestStep{Name: "address", Detail: gin.H{"supernode": addr}}
+	_, addrPort, err := net.SplitHostPort(addr)
+	if err != nil {
+		step.Status, step.Message = selfTestFail, "supernode_host is not set"
+		return step
+	}
+	step.Status = selfTestOK
+	if addrPort != port {
+		step.Status, step.Message = selfTestWarn, fmt.Sprintf("edges connect to port %s but the supernode listens on %s, make sure the port is forwarded", addrPort, port)
+	}
+	publicAddressMutex.Lock()
+	r := lastPublicAddress
+	publicAddressMutex.Unlock()
+	if r != nil && r.Error == "" && r.Configured == getSettingValue("supernode_host", "") && r.Warning != "" {
+		step.Status, step.Message = selfTestWarn, r.Warning
+	}
+	return step
+}
+
+// selfTestExternal 请外部检测服务从公网探测 supernode 端口
+func selfTestExternal(ctx context.Context, addr string) selfTestStep {
+	step := selfTestStep{Name: "external"}
+	url := getSettingValue("selftest_service_url", "")
+	if url == "" {
+		step.Status, step.Message = selfTestSkipped, "selftest_service_url is not set"
+		return step
+	}
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		step.Status, step.Message = selfTestSkipped, "supernode_host is not set"
+		return step
+	}
+	port, _ := strconv.Atoi(portStr)
+	body, _ := json.Marshal(gin.H{"host": host, "port": port})
+	ctx, cancel := context.WithTimeout(ctx, selfTestServiceTimeout)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	recordDependency("selftest", time.Since(start), err)
+	if err != nil {
+		step.Status, step.Message = selfTestFail, "self-test service unavailable: "+err.Error()
+		return step
+	}
+	defer resp.Body.Close()
+	var res struct {
+		Reachable bool    `json:"reachable"`
+		RttMs     float64 `json:"rtt_ms"`
+		Error     string  `json:"error"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&res) != nil {
+		step.Status, step.Message = selfTestFail, "self-test service returned "+resp.Status
+		return step
+	}
+	step.Detail = res
+	if res.Reachable {
+		step.Status = selfTestOK
+	} else {
+		step.Status, step.Message = selfTestFail, "UDP port is not reachable from the internet, check the firewall and NAT port forwarding"
+		if res.Error != "" {
+			step.Message += ": " + res.Error
+		}
+	}
+	return step
+}
+
+// selfTestFederationPeer 在管理端口的 supernodes 列表中查找 selftest_peer 最近一次注册的时间
+func selfTestFederationPeer(ctx context.Context, rt *instanceRuntime) selfTestStep {
+	step := selfTestStep{Name: "federation_peer"}
+	peer := getSettingValue("selftest_peer", "")
+	if peer == "" {
+		step.Status, step.Message = selfTestSkipped, "selftest_peer is not set"
+		return step
+	}
+	host, _, err := net.SplitHostPort(peer)
+	if err != nil {
+		host = peer
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		step.Status, step.Message = selfTestFail, "cannot resolve selftest_peer: "+err.Error()
+		return step
+	}
+	rows, err := rt.client.Supernodes()
+	if err != nil {
+		step.Status, step.Message = selfTestFail, "management port: "+err.Error()
+		return step
+	}
+	var lastSeen time.Time
+	for _, row := range rows {
+		sockaddr, _ := row["sockaddr"].(string)
+		rowHost, _, err := net.SplitHostPort(sockaddr)
+		if err != nil {
+			continue
+		}
+		for _, ip := range ips {
+			if ip.IP.Equal(net.ParseIP(rowHost)) {
+				if ts, ok := row["last_seen"].(float64); ok && time.Unix(int64(ts), 0).After(lastSeen) {
+					lastSeen = time.Unix(int64(ts), 0)
+				}
+			}
+		}
+	}
+	switch {
+	case lastSeen.IsZero():
+		step.Status, step.Message = selfTestFail, "peer "+peer+" has never registered, add this supernode to its -l and check that inbound UDP is allowed"
+	case time.Since(lastSeen) > selfTestPeerMaxAge:
+		step.Status, step.Message = selfTestFail, fmt.Sprintf("peer %s last registered %s ago, inbound UDP may be blocked", peer, time.Since(lastSeen).Round(time.Second))
+		step.Detail = gin.H{"last_seen": lastSeen}
+	default:
+		step.Status, step.Detail = selfTestOK, gin.H{"last_seen": lastSeen}
+	}
+	return step
+}
+
+// supernodeSelfTest 检查实例的 UDP 端口是否可从外部访问，?instance= 指定实例
+func supernodeSelfTest(c *gin.Context) {
+	rt, ok := instanceParam(c)
+	if !ok {
+		return
+	}
+	port := supernodeListenPort(rt)
+	addr := instanceSupernodeAddr(rt)
+	steps := []selfTestStep{
+		selfTestListening(port),
+		selfTestAddress(addr, port),
+		selfTestExternal(c.Request.Context(), addr),
+		selfTestFederationPeer(c.Request.Context(), rt),
+	}
+	status := selfTestOK
+	for _, s := range steps {
+		switch {
+		case s.Status == selfTestFail:
+			status = selfTestFail
+		case s.Status == selfTestWarn && status == selfTestOK:
+			status = selfTestWarn
+		}
+	}
+	c.JSON(200, gin.H{"instance": rt.instance.Name, "supernode": addr, "status": status, "steps": steps})
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// supernode 的转发 / 广播 / 注册计数（packetstats）与时间戳（timestamps），
+// 以 JSON（/api/supernode/stats）和 Prometheus 文本格式（/api/metrics，所有实例）提供。
+// Prometheus 抓取时可使用 API 令牌（POST /api/me/tokens）作为 bearer_token。
+
+// getSupernodeStats 返回实例的 packetstats 与 timestamps，?instance= 指定实例
+func getSupernodeStats(c *gin.Context) {
+	rt, ok := instanceParam(c)
+	if !ok {
+		return
+	}
+	stats, err := rt.client.Stats()
+	if err != nil {
+		respondMgmtError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"instance": rt.instance.Name, "counters": stats.Counters, "timestamps": stats.Timestamps})
+}
+
+// metricFamily 一个指标的全部样本，Prometheus 要求同名样本连续输出
+type metricFamily struct {
+	typ, help string
+	samples   []string
+}
+
+// metricsWriter 收集各实例的样本，按指标分组后以 Prometheus 文本格式输出
+type metricsWriter struct {
+	order    []string
+	families map[string]*metricFamily
+}
+
+// promLabel 转义标签值中的反斜杠、双引号和换行
+var promLabel = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (w *metricsWriter) write(name, typ, help string, labels map[string]string, value float64) {
+	f, ok := w.families[name]
+	if !ok {
+		f = &metricFamily{typ: typ, help: help}
+		w.families[name] = f
+		w.order = append(w.order, name)
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, k, promLabel.Replace(labels[k])))
+	}
+	f.samples = append(f.samples, fmt.Sprintf("%s{%s} %s", name, strings.Join(pairs, ","), strconv.FormatFloat(value, 'f', -1, 64)))
+}
+
+func (w *metricsWriter) String() string {
+	var b strings.Builder
+	for _, name := range w.order {
+		f := w.families[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, f.help, name, f.typ)
+		for _, s := range f.samples {
+			b.WriteString(s + "\n")
+		}
+	}
+	return b.String()
+}
+
+func (w *metricsWriter) writePackets(instance, typ, direction string, n uint64) {
+	w.write("n2n_supernode_packets_total", "counter", "Packets counted by the supernode packetstats command.",
+		map[string]string{"instance": instance, "type": typ, "direction": direction}, float64(n))
+}
+
+// writeTime 输出时间戳指标，零值（从未发生）不输出
+func (w *metricsWriter) writeTime(name, help string, labels map[string]string, t time.Time) {
+	if !t.IsZero() {
+		w.write(name, "gauge", help, labels, float64(t.Unix()))
+	}
+}
+
+// writeInstanceMetrics 输出一个实例的指标，管理端口不可用时只输出 n2n_supernode_up 0
+func (w *metricsWriter) writeInstanceMetrics(rt *instanceRuntime) {
+	instance := map[string]string{"instance": rt.instance.Name}
+	stats, err := rt.client.Stats()
+	if err != nil {
+		w.write("n2n_supernode_up", "gauge", "Whether the supernode management port answers.", instance, 0)
+		return
+	}
+	w.write("n2n_supernode_up", "gauge", "Whether the supernode management port answers.", instance, 1)
+	for _, ctr := range stats.Counters {
+		// reg_super 只有接收计数，其余类型只有发送计数
+		if ctr.Type != "reg_super" || ctr.TxPkt > 0 {
+			w.writePackets(rt.instance.Name, ctr.Type, "tx", ctr.TxPkt)
+		}
+		if ctr.Type == "reg_super" || ctr.RxPkt > 0 {
+			w.writePackets(rt.instance.Name, ctr.Type, "rx", ctr.RxPkt)
+		}
+		if ctr.Type == "reg_super" {
+			w.write("n2n_supernode_reg_super_nak_total", "counter", "Registrations rejected by the supernode.", instance, float64(ctr.Nak))
+		}
+	}
+	if ts := stats.Timestamps; ts != nil {
+		w.writeTime("n2n_supernode_start_time_seconds", "Supernode start time in unix seconds.", instance, ts.StartTime)
+		w.writeTime("n2n_supernode_last_forward_timestamp_seconds", "Time of the last forwarded packet in unix seconds.", instance, ts.LastForward)
+		w.writeTime("n2n_supernode_last_reg_super_timestamp_seconds", "Time of the last edge registration in unix seconds.", instance, ts.LastRegSuper)
+	}
+	if edges, err := rt.client.GetEdgeInfo(); err == nil {
+		perCommunity := make(map[string]int)
+		communities := make([]string, 0)
+		for _, e := range edges {
+			if perCommunity[e.Community] == 0 {
+				communities = append(communities, e.Community)
+			}
+			perCommunity[e.Community]++
+		}
+		sort.Strings(communities)
+		for _, community := range communities {
+			w.write("n2n_supernode_edges", "gauge", "Edges registered with the supernode.",
+				map[string]string{"instance": rt.instance.Name, "community": community}, float64(perCommunity[community]))
+		}
+	}
+}
+
+// getMetrics 以 Prometheus 文本格式输出所有实例的指标
+func getMetrics(c *gin.Context) {
+	w := &metricsWriter{families: make(map[string]*metricFamily)}
+	for _, rt := range listRuntimes() {
+		w.writeInstanceMetrics(rt)
+	}
+	c.Data(200, "text/plain; version=0.0.4; charset=utf-8", []byte(w.String()))
+}
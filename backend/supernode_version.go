@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// supernode 版本与兼容性：从 supernode -h 的输出读取已安装的版本（程序位置见 N2N_SUPERNODE_BIN），
+// 并向管理端口发送 verbose / timestamps 读请求，判断运行中的 supernode 支持哪些管理接口。
+// 结果在 /api/supernode/status 中返回，面板的功能不被该版本支持时附带警告：
+//   - 版本不在支持范围（n2nMinVersion ~ n2nMaxVersion）内
+//   - 管理端口不支持 JSON 接口（n2n 2.x）：日志级别、packetstats、重新加载社区、联盟状态不可用
+//   - 实例下有启用用户认证的社区，但 supernode 低于 3.0
+// 检测结果缓存 supernodeVersionTTL，通过面板重启 supernode 后重新检测。
+
+const supernodeVersionTTL = 10 * time.Minute
+
+// supernodeVersion 一个实例的版本与兼容性
+type supernodeVersion struct {
+	Installed string                  `json:"installed,omitempty"` // supernode -h 报告的版本
+	Error     string                  `json:"error,omitempty"`     // 无法检测已安装版本的原因
+	Mgmt      *utils.MgmtCapabilities `json:"mgmt,omitempty"`      // 管理端口无响应时为空
+	Supported string                  `json:"supported"`
+	Warnings  []string                `json:"warnings"`
+	CheckedAt time.Time               `json:"checked_at"`
+}
+
+var (
+	supernodeVersions     = make(map[uint]supernodeVersion)
+	supernodeVersionMutex sync.Mutex
+)
+
+// n2nVersionWarning 版本不在支持范围内时的警告，n2nMaxVersion 形如 3.x，只比较主版本
+func n2nVersionWarning(v string) string {
+	if compareVersions(v, n2nMinVersion) < 0 {
+		return fmt.Sprintf("supernode %s is older than %s, the oldest version n2n-admin supports", v, n2nMinVersion)
+	}
+	major := strings.SplitN(v, ".", 2)[0]
+	if compareVersions(major, strings.TrimSuffix(n2nMaxVersion, ".x")) > 0 {
+		return fmt.Sprintf("supernode %s is newer than %s, some features may not work as expected", v, n2nMaxVersion)
+	}
+	return ""
+}
+
+// userAuthCommunities 实例下启用了用户认证的社区
+func userAuthCommunities(instanceID uint) []string {
+	var comms []models.Community
+	db.Where("user_auth = ?", true).Find(&comms)
+	names := make([]string, 0)
+	for _, cm := range comms {
+		if communityInstanceID(cm) == instanceID {
+			names = append(names, cm.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// detectSupernodeVersion 检测已安装的版本与管理端口的能力，并生成兼容性警告
+func detectSupernodeVersion(rt *instanceRuntime) supernodeVersion {
+	res := supernodeVersion{Supported: n2nMinVersion + " - " + n2nMaxVersion, Warnings: make([]string, 0), CheckedAt: time.Now()}
+	v, err := utils.SupernodeVersion()
+	if err != nil {
+		res.Error = err.Error()
+	} else {
+		res.Installed = v
+		if w := n2nVersionWarning(v); w != "" {
+			res.Warnings = append(res.Warnings, w)
+		}
+	}
+	if caps, err := rt.client.ProbeCapabilities(); err == nil {
+		res.Mgmt = &caps
+	}
+
+	legacy := res.Installed != "" && compareVersions(res.Installed, "3.0") < 0
+	if res.Mgmt != nil && !res.Mgmt.JSON {
+		if res.Installed != "" && !legacy {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("supernode %s is installed but the running supernode is older, restart it to load the new version", res.Installed))
+		}
+		res.Warnings = append(res.Warnings, "the management port does not speak the JSON API (n2n 2.x): log level, packet statistics, community reload and federation status are unavailable")
+		legacy = true
+	} else if res.Mgmt != nil && !res.Mgmt.Timestamps {
+		res.Warnings = append(res.Warnings, "the supernode does not report timestamps, its start time and last activity are unavailable")
+	}
+	if comms := userAuthCommunities(rt.instance.ID); legacy && len(comms) > 0 {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("communities %s use user/password authentication, which requires n2n 3.0 or later", strings.Join(comms, ", ")))
+	}
+	return res
+}
+
+// supernodeVersionFor 返回缓存的检测结果，过期时重新检测；警告发生变化时记录日志
+func supernodeVersionFor(rt *instanceRuntime) supernodeVersion {
+	supernodeVersionMutex.Lock()
+	cached, ok := supernodeVersions[rt.instance.ID]
+	supernodeVersionMutex.Unlock()
+	if ok && time.Since(cached.CheckedAt) < supernodeVersionTTL {
+		return cached
+	}
+	res := detectSupernodeVersion(rt)
+	if w := strings.Join(res.Warnings, "; "); w != "" && w != strings.Join(cached.Warnings, "; ") {
+		log.Printf("Supernode %s (%s): %s", rt.instance.Name, res.Installed, w)
+	}
+	supernodeVersionMutex.Lock()
+	supernodeVersions[rt.instance.ID] = res
+	supernodeVersionMutex.Unlock()
+	return res
+}
+
+// forgetSupernodeVersion 丢弃缓存的检测结果，下次查询时重新检测
+func forgetSupernodeVersion(id uint) {
+	supernodeVersionMutex.Lock()
+	delete(supernodeVersions, id)
+	supernodeVersionMutex.Unlock()
+}
+
+// getSupernodeStatus 实例的运行状态、管理端口可达性与版本兼容性，?instance= 指定实例
+func getSupernodeStatus(c *gin.Context) {
+	rt, ok := instanceParam(c)
+	if !ok {
+		return
+	}
+	c.JSON(200, gin.H{
+		"instance": rt.instance.Name,
+		"active":   isSupernodeActive(rt.instance.Unit),
+		"mgmt":     rt.client.Status(),
+		"version":  supernodeVersionFor(rt),
+	})
+}
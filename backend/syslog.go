@@ -0,0 +1,191 @@
+package main
+
+import (
+	"log"
+	"n2n_ui/backend/utils"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Syslog 转发：按 syslog_categories 把以下类别的事件以 RFC 5424 格式发送到远程 syslog / SIEM（UDP、TCP 或 TLS）：
+//   - audit：管理操作审计与登录记录
+//   - node：节点上线、离线、到期等状态变化
+//   - supernode：supernode 停止、恢复、认证失败等事件
+//   - alert：告警规则的触发与恢复
+//   - supernode_log：supernode 的原始日志行（量大，默认不转发）
+// 事件先进入内存队列，由后台任务发送，远程服务器不可用时不会阻塞面板，队列满时丢弃。
+
+const (
+	syslogCategoryAudit        = "audit"
+	syslogCategoryNode         = "node"
+	syslogCategorySupernode    = "supernode"
+	syslogCategoryAlert        = "alert"
+	syslogCategorySupernodeLog = "supernode_log"
+
+	syslogQueueSize      = 1000
+	syslogReloadInterval = 10 * time.Second
+	syslogSDID           = "n2n@32473" // 结构化数据 ID，32473 为 RFC 5612 中的示例企业号
+)
+
+var syslogCategories = []string{syslogCategoryAudit, syslogCategoryNode, syslogCategorySupernode, syslogCategoryAlert, syslogCategorySupernodeLog}
+
+// syslogEntry 等待发送的一条事件
+type syslogEntry struct {
+	category string
+	msg      utils.SyslogMessage
+}
+
+// syslogSettings 当前生效的转发配置，由后台任务定期从设置中刷新，forwardSyslog 只读取内存副本
+type syslogSettings struct {
+	cfg        utils.SyslogConfig
+	categories map[string]bool
+}
+
+var (
+	syslogQueue   = make(chan syslogEntry, syslogQueueSize)
+	syslogActive  *syslogSettings // nil 表示未启用
+	syslogMu      sync.RWMutex
+	syslogDropped int64
+)
+
+// loadSyslogSettings 读取转发设置，未启用或未配置地址时返回 nil
+func loadSyslogSettings() *syslogSettings {
+	if getSettingValue("syslog_enabled", "false") != "true" {
+		return nil
+	}
+	addr := getSettingValue("syslog_address", "")
+	if addr == "" {
+		return nil
+	}
+	host := getSettingValue("syslog_hostname", "")
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+	facility, ok := utils.SyslogFacilities[getSettingValue("syslog_facility", "local0")]
+	if !ok {
+		facility = utils.SyslogFacilities["local0"]
+	}
+	s := &syslogSettings{
+		cfg: utils.SyslogConfig{
+			Network: getSettingValue("syslog_protocol", "udp"), Address: addr, Facility: facility, Hostname: host, AppName: "n2n-admin",
+		},
+		categories: make(map[string]bool),
+	}
+	for _, c := range utils.SplitList(getSettingValue("syslog_categories", "audit,node,supernode,alert")) {
+		s.categories[c] = true
+	}
+	return s
+}
+
+// forwardSyslog 将事件放入发送队列；未启用、类别未选中时直接返回，队列满时丢弃
+func forwardSyslog(category string, severity int, msgID, message string, data map[string]string) {
+	syslogMu.RLock()
+	s := syslogActive
+	syslogMu.RUnlock()
+	if s == nil || !s.categories[category] {
+		return
+	}
+	if data == nil {
+		data = make(map[string]string)
+	}
+	data["category"] = category
+	entry := syslogEntry{category: category, msg: utils.SyslogMessage{
+		Severity: severity, Time: time.Now(), MsgID: msgID, SDID: syslogSDID, Data: data, Message: message,
+	}}
+	select {
+	case syslogQueue <- entry:
+	default:
+		atomic.AddInt64(&syslogDropped, 1)
+	}
+}
+
+// alertSyslogCategory 告警事件所属的转发类别
+func alertSyslogCategory(alertType string) string {
+	switch {
+	case strings.HasPrefix(alertType, "alert_"):
+		return syslogCategoryAlert
+	case strings.HasPrefix(alertType, "node_"), strings.HasSuffix(alertType, "_edge_online"):
+		return syslogCategoryNode
+	}
+	return syslogCategorySupernode
+}
+
+// alertSyslogSeverity 告警级别对应的 syslog 严重程度
+func alertSyslogSeverity(level string) int {
+	switch level {
+	case "critical":
+		return utils.SyslogCritical
+	case "warning":
+		return utils.SyslogWarning
+	}
+	return utils.SyslogNotice
+}
+
+// logLineSyslogSeverity supernode 日志级别对应的 syslog 严重程度
+func logLineSyslogSeverity(line string) int {
+	switch logLineLevel(line) {
+	case logLevels["error"]:
+		return utils.SyslogError
+	case logLevels["warning"]:
+		return utils.SyslogWarning
+	case logLevels["info"]:
+		return utils.SyslogInfo
+	case logLevels["debug"]:
+		return utils.SyslogDebug
+	}
+	return utils.SyslogNotice
+}
+
+// startSyslogForwarder 发送队列中的事件，并定期刷新转发配置；目标地址或协议变化时重建连接
+func startSyslogForwarder() {
+	var (
+		client  *utils.SyslogClient
+		current utils.SyslogConfig
+		failing bool
+	)
+	reload := func() {
+		s := loadSyslogSettings()
+		syslogMu.Lock()
+		syslogActive = s
+		syslogMu.Unlock()
+		if s == nil || s.cfg != current {
+			if client != nil {
+				client.Close()
+				client = nil
+			}
+			current = utils.SyslogConfig{}
+		}
+		if s != nil && client == nil {
+			client, current = utils.NewSyslogClient(s.cfg, os.Getpid()), s.cfg
+		}
+		if n := atomic.SwapInt64(&syslogDropped, 0); n > 0 {
+			log.Printf("Syslog: queue full, dropped %d events", n)
+		}
+	}
+	reload()
+	ticker := time.NewTicker(syslogReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			reload()
+		case e := <-syslogQueue:
+			if client == nil {
+				continue
+			}
+			// 同一次故障只记录一次错误，恢复后再记录
+			if err := client.Send(e.msg); err != nil {
+				if !failing {
+					log.Printf("Syslog: failed to send to %s: %v", current.Address, err)
+					failing = true
+				}
+			} else if failing {
+				log.Printf("Syslog: delivery to %s resumed", current.Address)
+				failing = false
+			}
+		}
+	}
+}
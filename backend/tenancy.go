@@ -0,0 +1,290 @@
+package main
+
+import (
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 多租户：管理员可访问全部数据；非管理员只能看到和管理分配给自己的社区及其中的节点。
+// supernode、设置、工具等全局功能只对管理员开放（路由上使用 requireAdmin）。
+
+// scopeMiddleware 在 jwtMiddleware 之后加载当前用户可访问的社区，写入 scope（管理员为 nil）
+func scopeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			respondError(c, 401, ErrInvalidToken, "Invalid token")
+			c.Abort()
+			return
+		}
+		if !user.IsAdmin {
+			c.Set("scope", userCommunityNames(user.ID))
+		}
+		if lang := userPreference(user.ID).Language; lang != "" {
+			c.Set("lang", lang)
+		}
+		c.Next()
+	}
+}
+
+// userCommunityNames 返回分配给用户的社区名称
+func userCommunityNames(userID uint) []string {
+	names := make([]string, 0)
+	db.Model(&models.Community{}).
+		Where("id IN (?)", db.Model(&models.UserCommunity{}).Select("community_id").Where("user_id = ?", userID)).
+		Pluck("name", &names)
+	return names
+}
+
+// communityScope 返回当前请求可访问的社区，restricted 为 false 表示不限（管理员）
+func communityScope(c *gin.Context) (names []string, restricted bool) {
+	v, ok := c.Get("scope")
+	if !ok {
+		return nil, false
+	}
+	return v.([]string), true
+}
+
+// inScope 判断社区是否在当前请求的访问范围内
+func inScope(c *gin.Context, community string) bool {
+	names, restricted := communityScope(c)
+	if !restricted {
+		return true
+	}
+	for _, n := range names {
+		if n == community {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeNodes 返回限定在可访问社区内的节点查询
+func scopeNodes(c *gin.Context) *gorm.DB {
+	if names, restricted := communityScope(c); restricted {
+		return db.Where("community IN ?", names)
+	}
+	return db
+}
+
+// scopeCommunities 返回限定在可访问社区内的社区查询
+func scopeCommunities(c *gin.Context) *gorm.DB {
+	if names, restricted := communityScope(c); restricted {
+		return db.Where("name IN ?", names)
+	}
+	return db
+}
+
+// createUser 管理员创建用户，非管理员用户需再分配社区
+func createUser(c *gin.Context) {
+	var p struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+		IsAdmin  bool   `json:"is_admin"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	p.Username = strings.TrimSpace(p.Username)
+	if p.Username == "" {
+		respondError(c, 400, ErrInvalidRequest, "Username is required")
+		return
+	}
+	if len(p.Password) < 6 {
+		respondError(c, 400, ErrPasswordTooShort, "New password must be at least 6 characters")
+		return
+	}
+	var count int64
+	db.Model(&models.User{}).Where("username = ?", p.Username).Count(&count)
+	if count > 0 {
+		respondError(c, 400, ErrUserExists, "User already exists")
+		return
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(p.Password), bcrypt.DefaultCost)
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	user := models.User{Username: p.Username, Password: string(hash), IsAdmin: p.IsAdmin}
+	if err := db.Create(&user).Error; err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	// is_admin 的列默认值为 true，创建时 false 会被忽略，需要单独写入
+	if !p.IsAdmin {
+		db.Model(&user).Update("is_admin", false)
+	}
+	c.JSON(200, user)
+}
+
+// updateUser 管理员修改用户角色或重置密码，修改后该用户之前签发的令牌全部失效
+func updateUser(c *gin.Context) {
+	var p struct {
+		IsAdmin  *bool  `json:"is_admin"`
+		Password string `json:"password"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	var user models.User
+	if err := db.First(&user, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrUserNotFound, "User not found")
+		return
+	}
+	updates := map[string]interface{}{}
+	if p.IsAdmin != nil && *p.IsAdmin != user.IsAdmin {
+		if me, _ := currentUser(c); me.ID == user.ID {
+			respondError(c, 400, ErrInvalidRequest, "Cannot change your own role")
+			return
+		}
+		updates["is_admin"] = *p.IsAdmin
+	}
+	if p.Password != "" {
+		if len(p.Password) < 6 {
+			respondError(c, 400, ErrPasswordTooShort, "New password must be at least 6 characters")
+			return
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(p.Password), bcrypt.DefaultCost)
+		if err != nil {
+			respondError(c, 500, ErrInternal, "Internal server error")
+			return
+		}
+		updates["password"] = string(hash)
+	}
+	if len(updates) == 0 {
+		c.JSON(200, user)
+		return
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+		return invalidateTokens(tx, &user)
+	})
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	if _, ok := updates["is_admin"]; ok {
+		recordAudit(c, "user.role", user.Username, userRole(user))
+	}
+	if _, ok := updates["password"]; ok {
+		recordAudit(c, "user.password_reset", user.Username, "")
+	}
+	c.JSON(200, user)
+}
+
+func deleteUser(c *gin.Context) {
+	var user models.User
+	if err := db.First(&user, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrUserNotFound, "User not found")
+		return
+	}
+	if me, _ := currentUser(c); me.ID == user.ID {
+		respondError(c, 400, ErrInvalidRequest, "Cannot delete yourself")
+		return
+	}
+	db.Where("user_id = ?", user.ID).Delete(&models.UserCommunity{})
+	db.Where("user_id = ?", user.ID).Delete(&models.UserPreference{})
+	db.Where("user_id = ?", user.ID).Delete(&models.UserSession{})
+	db.Delete(&user)
+	c.JSON(200, gin.H{"message": "deleted"})
+}
+
+func getUserCommunities(c *gin.Context) {
+	var user models.User
+	if err := db.First(&user, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrUserNotFound, "User not found")
+		return
+	}
+	var comms []models.Community
+	db.Where("name IN ?", userCommunityNames(user.ID)).Find(&comms)
+	c.JSON(200, comms)
+}
+
+// setUserCommunities 用给定的社区 ID 列表替换用户的社区分配
+func setUserCommunities(c *gin.Context) {
+	var user models.User
+	if err := db.First(&user, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrUserNotFound, "User not found")
+		return
+	}
+	var p struct {
+		CommunityIDs []uint `json:"community_ids" binding:"required"`
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+	var count int64
+	db.Model(&models.Community{}).Where("id IN ?", p.CommunityIDs).Count(&count)
+	if int(count) != len(p.CommunityIDs) {
+		respondError(c, 400, ErrCommunityNotFound, "Community not found")
+		return
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.UserCommunity{}).Error; err != nil {
+			return err
+		}
+		for _, id := range p.CommunityIDs {
+			if err := tx.Create(&models.UserCommunity{UserID: user.ID, CommunityID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, 500, ErrInternal, "Internal server error")
+		return
+	}
+	c.JSON(200, gin.H{"user_id": user.ID, "communities": userCommunityNames(user.ID)})
+}
+
+// scopedCommunity 查找社区并确认其在当前请求的访问范围内
+func scopedCommunity(c *gin.Context, name string) (models.Community, error) {
+	if !inScope(c, name) {
+		return models.Community{}, newAPIError(ErrCommunityNotFound, "Community not found")
+	}
+	return lookupCommunity(name)
+}
+
+// scopeEdges 过滤掉不在访问范围内的在线 edge
+func scopeEdges(c *gin.Context, edges map[string]utils.EdgeInfo) map[string]utils.EdgeInfo {
+	if _, restricted := communityScope(c); !restricted {
+		return edges
+	}
+	res := make(map[string]utils.EdgeInfo)
+	for mac, info := range edges {
+		if inScope(c, info.Community) {
+			res[mac] = info
+		}
+	}
+	return res
+}
+
+// scopeRelays 只保留至少一端属于给定节点的中转记录
+func scopeRelays(c *gin.Context, relays []RelayEvent, nodes []models.Node) []RelayEvent {
+	if _, restricted := communityScope(c); !restricted {
+		return relays
+	}
+	macs := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		macs[n.MacAddress] = true
+	}
+	res := make([]RelayEvent, 0)
+	for _, r := range relays {
+		if macs[r.SrcMac] || macs[r.DstMac] {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// pruneUserCommunities 删除已被彻底删除的社区的分配记录
+func pruneUserCommunities() {
+	db.Where("community_id NOT IN (?)", db.Unscoped().Model(&models.Community{}).Select("id")).Delete(&models.UserCommunity{})
+}
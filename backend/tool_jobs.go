@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	toolJobRetention = 10 * time.Minute // 已结束的任务保留多久以便查询结果
+	toolJobMaxLines  = 5000             // 单个任务最多保留的输出行数
+	toolJobPing      = 15 * time.Second // SSE 心跳间隔
+)
+
+// 任务状态
+const (
+	toolJobRunning  = "running"
+	toolJobDone     = "done"
+	toolJobFailed   = "failed"
+	toolJobTimeout  = "timeout"
+	toolJobCanceled = "canceled"
+)
+
+// toolJob 后台执行的诊断任务，输出按行追加，订阅者通过 changed 通道得知有新内容
+type toolJob struct {
+	ID         string      `json:"id"`
+	Command    string      `json:"command"`
+	Target     string      `json:"target"`
+	Port       int         `json:"port,omitempty"`
+	Count      int         `json:"count,omitempty"`
+	User       string      `json:"user"`
+	Status     string      `json:"status"`
+	Result     interface{} `json:"result"`
+	Error      string      `json:"error,omitempty"`
+	Truncated  bool        `json:"truncated,omitempty"` // 输出超过 toolJobMaxLines，之后的行被丢弃
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt *time.Time  `json:"finished_at"`
+
+	output  []string
+	changed chan struct{} // 每次更新时关闭并替换
+	cancel  context.CancelFunc
+}
+
+var (
+	toolJobs      = make(map[string]*toolJob)
+	toolJobsMutex sync.Mutex
+)
+
+func newToolJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// notify 唤醒所有等待中的订阅者，调用方需持有 toolJobsMutex
+func (j *toolJob) notify() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// view 返回任务快照，调用方需持有 toolJobsMutex
+func (j *toolJob) view(withOutput bool) gin.H {
+	data, _ := json.Marshal(j)
+	var res gin.H
+	json.Unmarshal(data, &res)
+	res["lines"] = len(j.output)
+	if withOutput {
+		res["output"] = append([]string(nil), j.output...)
+	}
+	return res
+}
+
+// pruneToolJobs 删除结束超过 toolJobRetention 的任务，调用方需持有 toolJobsMutex
+func pruneToolJobs() {
+	for id, j := range toolJobs {
+		if j.FinishedAt != nil && time.Since(*j.FinishedAt) > toolJobRetention {
+			delete(toolJobs, id)
+		}
+	}
+}
+
+// toolJobFunc 任务的执行函数，每行输出调用一次 onLine，返回结构化结果
+type toolJobFunc func(ctx context.Context, onLine func(string)) (interface{}, error)
+
+// run 执行任务直到完成、超时或被取消
+func (j *toolJob) run(ctx context.Context, fn toolJobFunc) {
+	result, err := fn(ctx, func(l string) {
+		toolJobsMutex.Lock()
+		defer toolJobsMutex.Unlock()
+		if len(j.output) >= toolJobMaxLines {
+			j.Truncated = true
+			return
+		}
+		j.output = append(j.output, l)
+		j.notify()
+	})
+
+	toolJobsMutex.Lock()
+	defer toolJobsMutex.Unlock()
+	now := time.Now()
+	j.Result, j.FinishedAt = result, &now
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		j.Status, j.Error = toolJobTimeout, "timed out"
+	case ctx.Err() == context.Canceled:
+		j.Status, j.Error = toolJobCanceled, "canceled"
+	case err != nil:
+		j.Status, j.Error = toolJobFailed, err.Error()
+	default:
+		j.Status = toolJobDone
+	}
+	j.cancel()
+	j.notify()
+}
+
+// startToolJob 在后台启动诊断任务并立即返回任务 ID，
+// 之后通过 /tools/jobs/:id 查询结果或 /tools/jobs/:id/stream 获取实时输出
+// 超时时间和同时运行的任务数由设置 tool_job_timeout_seconds、tool_max_jobs 控制
+func startToolJob(c *gin.Context) {
+	p, ok := bindToolRequest(c)
+	if !ok {
+		return
+	}
+	timeout := time.Duration(settingIntValue("tool_job_timeout_seconds")) * time.Second
+
+	toolJobsMutex.Lock()
+	pruneToolJobs()
+	running := 0
+	for _, j := range toolJobs {
+		if j.Status == toolJobRunning {
+			running++
+		}
+	}
+	if limit := settingIntValue("tool_max_jobs"); running >= limit {
+		toolJobsMutex.Unlock()
+		respondError(c, 429, ErrTooManyToolJobs, trf(c, "Too many running tool jobs (limit %d)", limit))
+		return
+	}
+	j := &toolJob{Command: p.Command, Target: p.Target, Port: p.Port, Count: p.Count, User: c.GetString("username")}
+	view := launchToolJobLocked(j, timeout, func(ctx context.Context, onLine func(string)) (interface{}, error) {
+		return runTool(ctx, p, onLine)
+	})
+	toolJobsMutex.Unlock()
+	c.JSON(202, view)
+}
+
+// launchToolJobLocked 登记任务并在后台执行 fn，返回任务快照，调用方需持有 toolJobsMutex。
+// 其他需要流式输出的后台操作（如安装 supernode）也通过它复用 /tools/jobs 的查询、取消与 SSE 接口
+func launchToolJobLocked(j *toolJob, timeout time.Duration, fn toolJobFunc) gin.H {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	j.ID, j.Status, j.StartedAt = newToolJobID(), toolJobRunning, time.Now()
+	j.output, j.changed, j.cancel = make([]string, 0), make(chan struct{}), cancel
+	toolJobs[j.ID] = j
+	go j.run(ctx, fn)
+	return j.view(false)
+}
+
+// getToolJobs 列出保留中的任务（不含输出），按开始时间倒序
+func getToolJobs(c *gin.Context) {
+	toolJobsMutex.Lock()
+	pruneToolJobs()
+	jobs := make([]*toolJob, 0, len(toolJobs))
+	for _, j := range toolJobs {
+		jobs = append(jobs, j)
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].StartedAt.After(jobs[b].StartedAt) })
+	res := make([]gin.H, 0, len(jobs))
+	for _, j := range jobs {
+		res = append(res, j.view(false))
+	}
+	toolJobsMutex.Unlock()
+	c.JSON(200, res)
+}
+
+func lookupToolJob(c *gin.Context) (*toolJob, bool) {
+	toolJobsMutex.Lock()
+	j, ok := toolJobs[c.Param("id")]
+	toolJobsMutex.Unlock()
+	if !ok {
+		respondError(c, 404, ErrToolJobNotFound, "Tool job not found")
+	}
+	return j, ok
+}
+
+// getToolJob 返回任务状态、全部输出以及结束后的结构化结果
+func getToolJob(c *gin.Context) {
+	j, ok := lookupToolJob(c)
+	if !ok {
+		return
+	}
+	toolJobsMutex.Lock()
+	view := j.view(true)
+	toolJobsMutex.Unlock()
+	c.JSON(200, view)
+}
+
+// cancelToolJob 终止运行中的任务，已结束的任务不受影响
+func cancelToolJob(c *gin.Context) {
+	j, ok := lookupToolJob(c)
+	if !ok {
+		return
+	}
+	j.cancel()
+	c.JSON(200, gin.H{"message": tr(c, "Cancel requested")})
+}
+
+// streamToolJob 以 SSE 推送任务输出：
+// output - 一行输出（从第 ?from= 行开始回放，默认从头）；done - 任务结束，数据为任务状态和结果
+func streamToolJob(c *gin.Context) {
+	j, ok := lookupToolJob(c)
+	if !ok {
+		return
+	}
+	next, _ := strconv.Atoi(c.Query("from"))
+	next = max(next, 0)
+	send := func(event string, v interface{}) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, data)
+		c.Writer.Flush()
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	ticker := time.NewTicker(toolJobPing)
+	defer ticker.Stop()
+	for {
+		toolJobsMutex.Lock()
+		lines := append([]string(nil), j.output[min(next, len(j.output)):]...)
+		next += len(lines)
+		finished := j.Status != toolJobRunning
+		var view gin.H
+		if finished {
+			view = j.view(false)
+		}
+		changed := j.changed
+		toolJobsMutex.Unlock()
+
+		for _, l := range lines {
+			send("output", l)
+		}
+		if finished {
+			send("done", view)
+			return
+		}
+		c.Writer.Flush()
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-changed:
+		case <-ticker.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+		}
+	}
+}
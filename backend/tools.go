@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const toolTimeout = 5 * time.Second // tcp / dns 的超时
+
+// isValidTarget 验证目标是否为有效的 IP 地址或域名
+func isValidTarget(target string) bool {
+	if target == "" {
+		return false
+	}
+	// 检查是否为有效 IP
+	if ip := net.ParseIP(target); ip != nil {
+		return true
+	}
+	// 检查是否为有效域名（只允许字母、数字、点和连字符）
+	domainRegex := regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+	return domainRegex.MatchString(target) && len(target) <= 253
+}
+
+// toolRequest 诊断命令参数，同步执行（execTool）与后台任务（startToolJob）共用
+type toolRequest struct {
+	Command string `json:"command" binding:"required,oneof=ping traceroute tcp dns"`
+	Target  string `json:"target" binding:"required"`
+	Port    int    `json:"port" binding:"required_if=Command tcp,omitempty,min=1,max=65535"`
+	Count   int    `json:"count" binding:"omitempty,min=1,max=100"` // ping 次数，默认 4
+}
+
+// bindToolRequest 检查网络工具是否启用并绑定参数，失败时已写入响应
+func bindToolRequest(c *gin.Context) (toolRequest, bool) {
+	var p toolRequest
+	// 检查是否禁用网络工具
+	if appConfig.DisableNetTools {
+		respondError(c, 403, ErrToolsDisabled, "Network tools are disabled by the administrator")
+		return p, false
+	}
+	if !bindJSON(c, &p) {
+		return p, false
+	}
+	// 验证目标地址
+	if !isValidTarget(p.Target) {
+		respondError(c, 400, ErrInvalidTarget, "Invalid target address")
+		return p, false
+	}
+	if err := checkToolTarget(p.Target); err != nil {
+		respondErr(c, 403, err, ErrTargetNotAllowed)
+		return p, false
+	}
+	return p, true
+}
+
+// toolTargetRanges 返回 vpn 策略下允许的网段：所有社区的地址范围加上 tool_target_allowlist
+func toolTargetRanges() []*net.IPNet {
+	var ranges []*net.IPNet
+	var comms []models.Community
+	db.Select("range").Find(&comms)
+	for _, comm := range comms {
+		if _, ipnet, err := net.ParseCIDR(comm.Range); err == nil {
+			ranges = append(ranges, ipnet)
+		}
+	}
+	extra, err := parseCIDRList(getSettingValue("tool_target_allowlist", ""))
+	if err != nil {
+		log.Printf("Ignoring invalid tool_target_allowlist: %v", err)
+	}
+	return append(ranges, extra...)
+}
+
+// checkToolTarget 按设置 tool_target_policy 检查诊断目标：any 不限制；
+// vpn 只允许 toolTargetRanges 内的地址，域名解析出的每个地址都必须在范围内，
+// 防止诊断工具被用来扫描公网或服务器所在局域网
+func checkToolTarget(target string) error {
+	if getSettingValue("tool_target_policy", "any") != "vpn" {
+		return nil
+	}
+	addrs := []string{target}
+	if net.ParseIP(target) == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), toolTimeout)
+		defer cancel()
+		var err error
+		if addrs, err = net.DefaultResolver.LookupHost(ctx, target); err != nil || len(addrs) == 0 {
+			return newAPIErrorf(ErrTargetNotAllowed, "Cannot resolve %s to check it against the allowed ranges", target)
+		}
+	}
+	ranges := toolTargetRanges()
+	for _, a := range addrs {
+		ip := net.ParseIP(a)
+		allowed := false
+		for _, r := range ranges {
+			if ip != nil && r.Contains(ip) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return newAPIErrorf(ErrTargetNotAllowed, "Target %s is outside the VPN ranges allowed for diagnostics", a)
+		}
+	}
+	return nil
+}
+
+// runTool 执行诊断命令，输出逐行回调 onLine，返回结构化结果；ctx 结束时终止命令
+//   - ping: 每个回复的 RTT 及统计（utils.PingResult）
+//   - traceroute: 每一跳的地址和 RTT（[]utils.TraceHop）
+//   - tcp: TCP 端口连通性（需指定 port）
+//   - dns: 域名解析，目标为 IP 时做反向解析
+func runTool(ctx context.Context, p toolRequest, onLine func(string)) (interface{}, error) {
+	switch p.Command {
+	case "ping":
+		count := p.Count
+		if count == 0 {
+			count = 4
+		}
+		var out strings.Builder
+		name, args := utils.PingCommand(count, p.Target)
+		err := utils.StreamCommand(ctx, func(l string) {
+			out.WriteString(l + "\n")
+			onLine(l)
+		}, name, args...)
+		return utils.ParsePing(out.String()), err
+	case "traceroute":
+		var out strings.Builder
+		name, args := utils.TracerouteCommand(10, p.Target)
+		err := utils.StreamCommand(ctx, func(l string) {
+			out.WriteString(l + "\n")
+			onLine(l)
+		}, name, args...)
+		return utils.ParseTraceroute(out.String()), err
+	case "tcp":
+		r := utils.CheckTCPPort(p.Target, p.Port, toolTimeout)
+		if r.Open {
+			onLine(fmt.Sprintf("%s open (%.1f ms)", r.Target, r.RTTMs))
+		} else {
+			onLine(fmt.Sprintf("%s closed: %s", r.Target, r.Error))
+		}
+		return r, nil
+	case "dns":
+		r := utils.LookupDNS(p.Target, toolTimeout)
+		if r.CNAME != "" {
+			onLine(fmt.Sprintf("%s CNAME %s", r.Name, r.CNAME))
+		}
+		for _, a := range r.Addrs {
+			onLine(fmt.Sprintf("%s -> %s", r.Name, a))
+		}
+		for _, name := range r.PTR {
+			onLine(fmt.Sprintf("%s PTR %s", r.Name, name))
+		}
+		if r.Error != "" {
+			onLine("error: " + r.Error)
+			return r, errors.New(r.Error)
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("unknown command %q", p.Command)
+}
+
+// execTool 同步执行网络诊断命令，output 为原始输出，result 为结构化结果（见 runTool）
+// 耗时较长的 ping/traceroute 建议改用 /tools/jobs 后台执行并流式获取输出
+func execTool(c *gin.Context) {
+	p, ok := bindToolRequest(c)
+	if !ok {
+		return
+	}
+	var out strings.Builder
+	result, err := runTool(c.Request.Context(), p, func(l string) {
+		out.WriteString(l + "\n")
+	})
+	res := gin.H{"command": p.Command, "target": p.Target, "output": out.String(), "result": result}
+	if err != nil {
+		res["error"], res["code"] = err.Error(), ErrToolFailed
+	}
+	c.JSON(200, res)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"log"
+	"n2n_ui/backend/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const trashPurgeInterval = 1 * time.Hour
+
+// trashRetention 回收站保留时长，设置 trash_retention_days，0 表示永久保留
+func trashRetention() time.Duration {
+	days, err := strconv.Atoi(getSettingValue("trash_retention_days", "30"))
+	if err != nil || days < 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// purgeExpiredTrash 永久删除超过保留期的软删除记录
+func purgeExpiredTrash() {
+	retention := trashRetention()
+	if retention == 0 {
+		return
+	}
+	cutoff := time.Now().Add(-retention)
+	nodes := db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&models.Node{})
+	comms := db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&models.Community{})
+	pruneUserCommunities()
+	if nodes.RowsAffected > 0 || comms.RowsAffected > 0 {
+		log.Printf("Purged %d nodes and %d communities from trash", nodes.RowsAffected, comms.RowsAffected)
+	}
+}
+
+func startTrashPurger() {
+	purgeExpiredTrash()
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		purgeExpiredTrash()
+	}
+}
+
+type trashEntry struct {
+	Item      interface{} `json:"item"`
+	DeletedAt time.Time   `json:"deleted_at"`
+	PurgeAt   *time.Time  `json:"purge_at"` // 按保留期自动清除的时间，永久保留时为 null
+}
+
+func newTrashEntry(item interface{}, deletedAt time.Time, retention time.Duration) trashEntry {
+	e := trashEntry{Item: item, DeletedAt: deletedAt}
+	if retention > 0 {
+		t := deletedAt.Add(retention)
+		e.PurgeAt = &t
+	}
+	return e
+}
+
+func getTrash(c *gin.Context) {
+	retention := trashRetention()
+	var nodes []models.Node
+	db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&nodes)
+	var comms []models.Community
+	db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&comms)
+
+	nodeEntries := make([]trashEntry, 0, len(nodes))
+	for _, n := range nodes {
+		nodeEntries = append(nodeEntries, newTrashEntry(n, n.DeletedAt.Time, retention))
+	}
+	commEntries := make([]trashEntry, 0, len(comms))
+	for _, cm := range comms {
+		commEntries = append(commEntries, newTrashEntry(cm, cm.DeletedAt.Time, retention))
+	}
+	c.JSON(200, gin.H{"nodes": nodeEntries, "communities": commEntries, "retention_days": int(retention.Hours() / 24)})
+}
+
+func restoreNode(c *gin.Context) {
+	var n models.Node
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNotFound, "Node not found in trash")
+		return
+	}
+	comm, err := lookupCommunity(n.Community)
+	if err != nil {
+		respondError(c, 409, ErrCommunityNotFound, "Community of this node no longer exists, restore it first")
+		return
+	}
+	// 删除期间端口、MAC 与 IP 可能已分配给新节点，社区端口范围也可能已修改，恢复前重新检查
+	port, err := allocateLocalPort(comm, n.LocalPort, n.ID)
+	if err != nil {
+		respondErr(c, 409, err, ErrPortInUse)
+		return
+	}
+	for _, cf := range findNodeConflicts(n.MacAddress, n.IPAddress) {
+		if cf.Node.ID != n.ID && !cf.Deleted {
+			respondError(c, 409, ErrNodeConflict, "MAC or IP address already used by another node", gin.H{"conflicts": []nodeConflict{cf}})
+			return
+		}
+	}
+	db.Unscoped().Model(&n).Updates(map[string]interface{}{"deleted_at": nil, "local_port": port})
+	if err := syncCommunityList(); err != nil {
+		c.JSON(200, gin.H{"message": "restored", "warning": tr(c, "Failed to write community.list") + ": " + err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "restored"})
+}
+
+func purgeNode(c *gin.Context) {
+	res := db.Unscoped().Where("deleted_at IS NOT NULL").Delete(&models.Node{}, c.Param("id"))
+	if res.RowsAffected == 0 {
+		respondError(c, 404, ErrNotFound, "Node not found in trash")
+		return
+	}
+	c.JSON(200, gin.H{"message": "purged"})
+}
+
+func restoreCommunity(c *gin.Context) {
+	var cm models.Community
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL").First(&cm, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNotFound, "Community not found in trash")
+		return
+	}
+	if cm.SupernodeID != 0 && runtimeFor(cm.SupernodeID) == nil {
+		respondError(c, 409, ErrInstanceNotFound, "Supernode instance of this community no longer exists")
+		return
+	}
+	db.Unscoped().Model(&cm).Update("deleted_at", nil)
+	if err := syncCommunityList(); err != nil {
+		c.JSON(200, gin.H{"message": "restored", "warning": tr(c, "Failed to write community.list") + ": " + err.Error()})
+		return
+	}
+	c.JSON(200, gin.H{"message": "restored"})
+}
+
+func purgeCommunity(c *gin.Context) {
+	res := db.Unscoped().Where("deleted_at IS NOT NULL").Delete(&models.Community{}, c.Param("id"))
+	if res.RowsAffected == 0 {
+		respondError(c, 404, ErrNotFound, "Community not found in trash")
+		return
+	}
+	pruneUserCommunities()
+	c.JSON(200, gin.H{"message": "purged"})
+}
+
+// emptyTrash 清空回收站
+func emptyTrash(c *gin.Context) {
+	nodes := db.Unscoped().Where("deleted_at IS NOT NULL").Delete(&models.Node{})
+	comms := db.Unscoped().Where("deleted_at IS NOT NULL").Delete(&models.Community{})
+	pruneUserCommunities()
+	c.JSON(200, gin.H{"nodes": nodes.RowsAffected, "communities": comms.RowsAffected})
+}
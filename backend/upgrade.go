@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 自升级：从 update_check_url 返回的最新发布中下载当前平台的程序 n2n_admin_<os>_<arch>（Windows 加 .exe），
+// 按同一发布中的 checksums.txt（sha256sum 格式）校验，并要求 checksums.txt.sig（checksums.txt 的 Ed25519 签名，base64）
+// 能被内置公钥 UpdateSigningKey 或 N2N_UPDATE_PUBLIC_KEY 验证；没有可用公钥时拒绝升级，只有显式设置
+// N2N_UPDATE_INSECURE=true 才跳过签名校验（仍校验 SHA-256，但不能防止发布源被篡改）。校验通过后原子替换当前程序，
+// 旧程序保留为 <程序>.old，然后重启：设置了 N2N_SERVICE_UNIT 时重启该服务，否则直接重新执行新程序。
+// 命令行 -upgrade 只替换程序并重启服务；管理员也可通过 POST /api/admin/upgrade 触发。
+
+const (
+	upgradeDownloadTimeout = 10 * time.Minute
+	upgradeMaxBinarySize   = 256 << 20
+	upgradeChecksumsAsset  = "checksums.txt"
+)
+
+// UpdateSigningKey 内置的发布签名公钥（base64 Ed25519），发布构建时通过 -ldflags "-X main.UpdateSigningKey=..." 写入
+var UpdateSigningKey = ""
+
+var upgradeRunning atomic.Bool
+
+// upgradeResult 一次升级的结果
+type upgradeResult struct {
+	From              string `json:"from"`
+	To                string `json:"to"`
+	Asset             string `json:"asset"`
+	SHA256            string `json:"sha256"`
+	SignatureVerified bool   `json:"signature_verified"`
+	Backup            string `json:"backup"` // 旧程序的位置，可手动回滚
+}
+
+// upgradeAssetName 当前平台的发布文件名
+func upgradeAssetName() string {
+	name := fmt.Sprintf("n2n_admin_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// downloadAsset 下载发布文件，max 为允许的最大字节数
+func downloadAsset(ctx context.Context, url string, w io.Writer, max int64) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "n2n-admin/"+Version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("download %s: unexpected status %s", url, resp.Status)
+	}
+	n, err := io.Copy(w, io.LimitReader(resp.Body, max+1))
+	if err != nil {
+		return err
+	}
+	if n > max {
+		return fmt.Errorf("download %s: file exceeds %d bytes", url, max)
+	}
+	return nil
+}
+
+// expectedChecksum 从 sha256sum 格式的内容中查找文件的校验和
+func expectedChecksum(checksums []byte, name string) (string, error) {
+	sc := bufio.NewScanner(bytes.NewReader(checksums))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s has no entry for %s", upgradeChecksumsAsset, name)
+}
+
+// updateSigningKeys 信任的发布签名公钥：内置公钥与 N2N_UPDATE_PUBLIC_KEY
+func updateSigningKeys() ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for name, v := range map[string]string{"built-in signing key": UpdateSigningKey, "N2N_UPDATE_PUBLIC_KEY": appConfig.UpdatePublicKey} {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%s is not a base64 Ed25519 public key", name)
+		}
+		keys = append(keys, ed25519.PublicKey(key))
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no update signing key: this build has no built-in key and N2N_UPDATE_PUBLIC_KEY is not set " +
+			"(set N2N_UPDATE_INSECURE=true to upgrade with SHA-256 checks only)")
+	}
+	return keys, nil
+}
+
+// verifyChecksumsSignature 校验 checksums.txt 的签名，任一信任的公钥验证通过即可
+func verifyChecksumsSignature(ctx context.Context, release releaseInfo, checksums []byte) error {
+	keys, err := updateSigningKeys()
+	if err != nil {
+		return err
+	}
+	url := release.assetURL(upgradeChecksumsAsset + ".sig")
+	if url == "" {
+		return fmt.Errorf("release %s has no %s.sig", release.TagName, upgradeChecksumsAsset)
+	}
+	var buf bytes.Buffer
+	if err := downloadAsset(ctx, url, &buf, 4096); err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(buf.String()))
+	if err != nil {
+		return fmt.Errorf("%s.sig is not base64", upgradeChecksumsAsset)
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, checksums, sig) {
+			return nil
+		}
+	}
+	return errors.New("signature of " + upgradeChecksumsAsset + " does not match any trusted signing key")
+}
+
+// performUpgrade 下载、校验并替换当前程序；force 为 false 时只升级到更高的版本
+func performUpgrade(ctx context.Context, force bool) (upgradeResult, error) {
+	res := upgradeResult{From: Version, Asset: upgradeAssetName()}
+	if !upgradeRunning.CompareAndSwap(false, true) {
+		return res, newAPIError(ErrUpgradeInProgress, "An upgrade is already in progress")
+	}
+	defer upgradeRunning.Store(false)
+
+	ctx, cancel := context.WithTimeout(ctx, upgradeDownloadTimeout)
+	defer cancel()
+	release, err := fetchLatestRelease(ctx, getSettingValue("update_check_url", defaultUpdateCheckURL))
+	if err != nil {
+		return res, fmt.Errorf("query latest release: %w", err)
+	}
+	res.To = release.TagName
+	if !force && compareVersions(release.TagName, Version) <= 0 {
+		return res, newAPIError(ErrAlreadyUpToDate, "Already running the latest version")
+	}
+	binURL, sumURL := release.assetURL(res.Asset), release.assetURL(upgradeChecksumsAsset)
+	if binURL == "" || sumURL == "" {
+		return res, fmt.Errorf("release %s has no %s or %s", release.TagName, res.Asset, upgradeChecksumsAsset)
+	}
+
+	var checksums bytes.Buffer
+	if err := downloadAsset(ctx, sumURL, &checksums, 1<<20); err != nil {
+		return res, err
+	}
+	if appConfig.UpdateInsecure {
+		log.Printf("Upgrade: N2N_UPDATE_INSECURE is set, skipping the signature check of %s", release.TagName)
+	} else {
+		if err := verifyChecksumsSignature(ctx, release, checksums.Bytes()); err != nil {
+			return res, err
+		}
+		res.SignatureVerified = true
+	}
+	want, err := expectedChecksum(checksums.Bytes(), res.Asset)
+	if err != nil {
+		return res, err
+	}
+
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		return res, fmt.Errorf("locate current executable: %w", err)
+	}
+	info, err := os.Stat(exe)
+	if err != nil {
+		return res, err
+	}
+	// 临时文件与当前程序在同一目录，保证替换是同一文件系统内的 rename
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".n2n_admin-upgrade-*")
+	if err != nil {
+		return res, fmt.Errorf("create temporary file next to %s: %w", exe, err)
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	err = downloadAsset(ctx, binURL, io.MultiWriter(tmp, h), upgradeMaxBinarySize)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return res, err
+	}
+	res.SHA256 = hex.EncodeToString(h.Sum(nil))
+	if res.SHA256 != want {
+		return res, fmt.Errorf("checksum mismatch for %s: got %s, want %s", res.Asset, res.SHA256, want)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0100); err != nil {
+		return res, err
+	}
+	res.Backup = exe + ".old"
+	if err := replaceExecutable(exe, tmp.Name(), res.Backup); err != nil {
+		return res, fmt.Errorf("replace %s: %w", exe, err)
+	}
+	log.Printf("Upgrade: replaced %s with %s (%s), previous binary kept at %s", exe, res.To, res.SHA256, res.Backup)
+	return res, nil
+}
+
+// restartPanel 升级后重启面板：设置了 N2N_SERVICE_UNIT 时通过服务管理器重启，否则直接执行新程序
+func restartPanel() error {
+	if appConfig.ServiceUnit != "" {
+		return host.RestartService(appConfig.ServiceUnit)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	return execSelf(exe)
+}
+
+// upgradePanel 管理员触发升级，成功后返回结果并在稍后重启
+func upgradePanel(c *gin.Context) {
+	var p struct {
+		Force bool `json:"force"` // 为 true 时即使不是更新的版本也重新安装
+	}
+	if !bindOptionalJSON(c, &p) {
+		return
+	}
+	res, err := performUpgrade(c.Request.Context(), p.Force)
+	if err != nil {
+		// 已是最新版本、已有升级在进行时返回 409，其余为下载或校验失败
+		var ae *apiError
+		if errors.As(err, &ae) {
+			respondErr(c, 409, err, ErrUpgradeFailed)
+			return
+		}
+		log.Printf("Upgrade failed: %v", err)
+		respondError(c, 502, ErrUpgradeFailed, "Upgrade failed", gin.H{"detail": err.Error()})
+		return
+	}
+	recordAudit(c, "system.upgrade", res.To, fmt.Sprintf("from %s, sha256 %s", res.From, res.SHA256))
+	c.JSON(200, gin.H{"upgrade": res, "restarting": true})
+	go func() {
+		time.Sleep(time.Second) // 先把响应发出去
+		if err := restartPanel(); err != nil {
+			log.Printf("Upgrade: restart failed, please restart n2n-admin manually: %v", err)
+		}
+	}()
+}
+
+// runUpgradeCommand 处理 -upgrade 参数：替换程序后重启 N2N_SERVICE_UNIT 指定的服务
+func runUpgradeCommand(force bool) {
+	res, err := performUpgrade(context.Background(), force)
+	if err != nil {
+		var ae *apiError
+		if errors.As(err, &ae) && ae.Code == ErrAlreadyUpToDate {
+			fmt.Printf("当前已是最新版本 %s\n", Version)
+			return
+		}
+		log.Fatalf("错误: 升级失败，程序未修改: %v", err)
+	}
+	fmt.Printf("已从 %s 升级到 %s（sha256 %s", res.From, res.To, res.SHA256)
+	if res.SignatureVerified {
+		fmt.Print("，签名校验通过")
+	}
+	fmt.Printf("），旧程序保留为 %s\n", res.Backup)
+	if appConfig.ServiceUnit == "" {
+		fmt.Println("未设置 N2N_SERVICE_UNIT，请手动重启 n2n-admin 服务")
+		return
+	}
+	if err := host.RestartService(appConfig.ServiceUnit); err != nil {
+		log.Fatalf("错误: 重启服务 %s 失败: %v", appConfig.ServiceUnit, err)
+	}
+	fmt.Printf("已重启服务 %s\n", appConfig.ServiceUnit)
+}
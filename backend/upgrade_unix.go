@@ -0,0 +1,23 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// replaceExecutable 用 next 原子替换 exe：先将当前程序硬链接为 backup，再 rename 覆盖，
+// 任何时刻 exe 路径上都是一个完整的程序
+func replaceExecutable(exe, next, backup string) error {
+	os.Remove(backup)
+	if err := os.Link(exe, backup); err != nil {
+		return err
+	}
+	return os.Rename(next, exe)
+}
+
+// execSelf 以相同的参数和环境变量执行新程序，进程号不变
+func execSelf(exe string) error {
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
@@ -0,0 +1,26 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// replaceExecutable 运行中的程序不能被覆盖但可以改名：先将当前程序改名为 backup，再把 next 放到原位置
+func replaceExecutable(exe, next, backup string) error {
+	os.Remove(backup)
+	if err := os.Rename(exe, backup); err != nil {
+		return err
+	}
+	if err := os.Rename(next, exe); err != nil {
+		os.Rename(backup, exe)
+		return err
+	}
+	return nil
+}
+
+// execSelf Windows 不支持替换当前进程，需通过服务管理器重启
+func execSelf(exe string) error {
+	return errors.New("set N2N_SERVICE_UNIT to the service name to restart automatically on Windows")
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 供 Terraform / Ansible 等外部工具声明式管理的接口：按 MAC 或社区名定位对象，PUT 时不存在则创建、存在则整体替换，
+// ID 保持不变。请求体与部署定义中的 node / community 条目相同，校验和应用逻辑也与导入部署定义共用。
+// 响应带 ETag，PUT 时可用 If-Match 防止覆盖他人的修改，用 If-None-Match: * 只允许创建。
+
+// specETag 由对象的声明式字段计算 ETag
+func specETag(spec interface{}) string {
+	data, _ := json.Marshal(spec)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// checkPreconditions 按 If-Match / If-None-Match 检查对象当前状态，不满足时返回 412
+func checkPreconditions(c *gin.Context, exists bool, etag string) bool {
+	ok := true
+	if m := c.GetHeader("If-Match"); m != "" {
+		ok = exists && (strings.TrimSpace(m) == "*" || etagListContains(m, etag))
+	}
+	if m := c.GetHeader("If-None-Match"); m != "" {
+		ok = ok && !(exists && (strings.TrimSpace(m) == "*" || etagListContains(m, etag)))
+	}
+	if !ok {
+		respondError(c, 412, ErrPreconditionFailed, "Resource has been modified", gin.H{"etag": etag})
+	}
+	return ok
+}
+
+func etagListContains(list, etag string) bool {
+	for _, e := range strings.Split(list, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(e), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// applySingleSpec 用部署定义的校验和应用逻辑处理单个对象，失败时已写入响应
+func applySingleSpec(c *gin.Context, spec deploySpec) bool {
+	plan := planDeploySpec(spec, false)
+	if len(plan.Problems) > 0 {
+		respondErr(c, 400, plan.Problems[0].err, ErrInvalidRequest)
+		return false
+	}
+	if len(plan.Changes) == 0 {
+		return true
+	}
+	if err := plan.apply(); err != nil {
+		respondError(c, 409, ErrInvalidSpec, "Failed to apply spec", gin.H{"detail": err.Error()})
+		return false
+	}
+	return true
+}
+
+func findNodeByMac(mac string) (models.Node, bool) {
+	var n models.Node
+	mac = utils.NormalizeMac(mac)
+	err := db.Where("mac_address = ?", mac).First(&n).Error
+	return n, err == nil
+}
+
+// getNodeByMac 按 MAC 返回节点，响应头带 ETag
+func getNodeByMac(c *gin.Context) {
+	n, ok := findNodeByMac(c.Param("mac"))
+	if !ok {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	c.Header("ETag", specETag(nodeSpecOf(n)))
+	c.JSON(200, n)
+}
+
+// upsertNodeByMac 按 MAC 创建或整体替换节点，创建时返回 201
+func upsertNodeByMac(c *gin.Context) {
+	var s nodeSpec
+	if !bindJSON(c, &s) {
+		return
+	}
+	s.MAC = c.Param("mac")
+	existing, exists := findNodeByMac(s.MAC)
+	etag := ""
+	if exists {
+		etag = specETag(nodeSpecOf(existing))
+	}
+	if !checkPreconditions(c, exists, etag) {
+		return
+	}
+	if !applySingleSpec(c, deploySpec{Version: deploySpecVersion, Nodes: []nodeSpec{s}}) {
+		return
+	}
+	n, _ := findNodeByMac(s.MAC)
+	c.Header("ETag", specETag(nodeSpecOf(n)))
+	status := 200
+	if !exists {
+		status = 201
+	}
+	c.JSON(status, n)
+}
+
+// communityETag 社区的 ETag，所属实例按名称参与计算
+func communityETag(comm models.Community) string {
+	var inst models.SupernodeInstance
+	db.First(&inst, comm.SupernodeID)
+	return specETag(communitySpecOf(comm, map[uint]string{inst.ID: inst.Name}))
+}
+
+// getCommunityByName 按名称返回社区，响应头带 ETag
+func getCommunityByName(c *gin.Context) {
+	var comm models.Community
+	if err := db.Where("name = ?", c.Param("name")).First(&comm).Error; err != nil {
+		respondError(c, 404, ErrCommunityNotFound, "Community not found")
+		return
+	}
+	c.Header("ETag", communityETag(comm))
+	c.JSON(200, comm)
+}
+
+// upsertCommunityByName 按名称创建或整体替换社区（password 为空时保留原密码），创建时返回 201
+func upsertCommunityByName(c *gin.Context) {
+	var s communitySpec
+	if !bindJSON(c, &s) {
+		return
+	}
+	s.Name = c.Param("name")
+	var existing models.Community
+	exists := db.Where("name = ?", s.Name).First(&existing).Error == nil
+	etag := ""
+	if exists {
+		etag = communityETag(existing)
+	}
+	if !checkPreconditions(c, exists, etag) {
+		return
+	}
+	if !applySingleSpec(c, deploySpec{Version: deploySpecVersion, Communities: []communitySpec{s}}) {
+		return
+	}
+	var comm models.Community
+	db.Where("name = ?", s.Name).First(&comm)
+	c.Header("ETag", communityETag(comm))
+	status := 200
+	if !exists {
+		status = 201
+	}
+	if err := syncCommunityList(); err != nil {
+		c.JSON(status, struct {
+			models.Community
+			Warning string `json:"warning"`
+		}{comm, tr(c, "Failed to write community.list") + ": " + err.Error()})
+		return
+	}
+	c.JSON(status, comm)
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"strings"
+)
+
+// MAC is a hardware address in the canonical storage format: 12 upper-case hex
+// digits without separators, e.g. AABBCCDDEEFF. Node and blocklist records are
+// saved in this format, and edges reported by the management port are keyed by it.
+type MAC string
+
+// ErrInvalidMac is returned by ParseMAC for input that is not a 48-bit MAC address
+var ErrInvalidMac = errors.New("invalid MAC address format")
+
+var macSeparators = strings.NewReplacer(":", "", "-", "", ".", "")
+
+// NormalizeMac converts AA:bb:cc-DD... to AABBCCDD... without validating the result.
+// Use it for lookups; use ParseMAC for input that will be stored.
+func NormalizeMac(mac string) string {
+	return strings.ToUpper(macSeparators.Replace(strings.TrimSpace(mac)))
+}
+
+// ParseMAC accepts aa:bb:cc:dd:ee:ff, AA-BB-CC-DD-EE-FF, aabb.ccdd.eeff or
+// AABBCCDDEEFF and returns the canonical form
+func ParseMAC(s string) (MAC, error) {
+	m := NormalizeMac(s)
+	if len(m) != 12 {
+		return "", ErrInvalidMac
+	}
+	for _, c := range m {
+		if !((c >= '0' && c <= '9') || (c >= 'A' && c <= 'F')) {
+			return "", ErrInvalidMac
+		}
+	}
+	return MAC(m), nil
+}
+
+// RandomMAC generates a random locally administered MAC address in canonical form
+func RandomMAC() (MAC, error) {
+	mac, err := GenerateRandomMac()
+	if err != nil {
+		return "", err
+	}
+	return MAC(NormalizeMac(mac)), nil
+}
+
+// Format returns the address as aa:bb:cc:dd:ee:ff, the format edge and the
+// supernode management port use. Non-canonical values are returned unchanged.
+func (m MAC) Format() string {
+	s := string(m)
+	if len(s) != 12 {
+		return s
+	}
+	parts := make([]string, 0, 6)
+	for i := 0; i < 12; i += 2 {
+		parts = append(parts, s[i:i+2])
+	}
+	return strings.ToLower(strings.Join(parts, ":"))
+}
+
+func (m MAC) String() string {
+	return string(m)
+}
+
+// NormalizeIP returns the canonical text form of an IP address (e.g. without
+// leading zeros or surrounding spaces). Invalid input is returned trimmed.
+func NormalizeIP(ip string) string {
+	ip = strings.TrimSpace(ip)
+	if parsed := net.ParseIP(ip); parsed != nil {
+		return parsed.String()
+	}
+	return ip
+}
@@ -16,6 +16,15 @@ type ConfigParams struct {
 	Compression bool
 	Routing     string
 	LocalPort   int
+	MTU         int      // 0 leaves the edge default
+	ExtraArgs   []string // additional validated flags, one per line, written before -f
+	TapDevice   string   // defaults to n2n0
+	TTL         int      // registration packet TTL (-L), 0 leaves the edge default
+	Backups     []string // backup supernodes, written as additional -l lines after Supernode
+	// n2n 3.x user/password authentication, used when AuthPassword is set
+	AuthUser     string // sent with -I instead of Name
+	AuthPassword string
+	SupernodeKey string // supernode (federation) public key for -P
 }
 
 func formatMac(mac string) string {
@@ -47,24 +56,48 @@ func GenerateConfFile(p ConfigParams) string {
 	sb.WriteString("# n2n Edge Configuration Generated by n2n_ui\n")
 	sb.WriteString(fmt.Sprintf("# Node Name: %s\n\n", p.Name))
 
-	sb.WriteString("-d=n2n0\n")
+	dev := p.TapDevice
+	if dev == "" {
+		dev = "n2n0"
+	}
+	sb.WriteString(fmt.Sprintf("-d=%s\n", dev))
 	sb.WriteString(fmt.Sprintf("-a=%s\n", p.IP))
 	sb.WriteString(fmt.Sprintf("-c=%s\n", p.Community))
 	sb.WriteString(fmt.Sprintf("-k=%s\n", p.Password))
 	sb.WriteString(fmt.Sprintf("-l=%s\n", p.Supernode))
+	for _, sn := range p.Backups {
+		sb.WriteString(fmt.Sprintf("-l=%s\n", sn))
+	}
 	sb.WriteString(fmt.Sprintf("-m=%s\n", formatMac(p.Mac)))
-	sb.WriteString(fmt.Sprintf("-I=%s\n", p.Name))
+	if p.AuthPassword != "" {
+		sb.WriteString(fmt.Sprintf("-I=%s\n", p.AuthUser))
+		sb.WriteString(fmt.Sprintf("-J=%s\n", p.AuthPassword))
+		if p.SupernodeKey != "" {
+			sb.WriteString(fmt.Sprintf("-P=%s\n", p.SupernodeKey))
+		}
+	} else {
+		sb.WriteString(fmt.Sprintf("-I=%s\n", p.Name))
+	}
 	sb.WriteString(fmt.Sprintf("%s\n", getEncryptionFlag(p.Encryption)))
 
 	if p.LocalPort > 0 {
 		sb.WriteString(fmt.Sprintf("-p=%d\n", p.LocalPort))
 	}
+	if p.MTU > 0 {
+		sb.WriteString(fmt.Sprintf("-M=%d\n", p.MTU))
+	}
+	if p.TTL > 0 {
+		sb.WriteString(fmt.Sprintf("-L=%d\n", p.TTL))
+	}
 	if p.Compression {
 		sb.WriteString("-z1\n")
 	}
 	if p.Routing != "" {
 		sb.WriteString(fmt.Sprintf("-n=%s\n", p.Routing))
 	}
+	for _, arg := range p.ExtraArgs {
+		sb.WriteString(arg + "\n")
+	}
 
 	// Always foreground for easy service management, -f is safe for systemd too
 	sb.WriteString("-f\n")
@@ -0,0 +1,58 @@
+package utils
+
+import "strings"
+
+// LineDiff returns a line-based diff of old and new, prefixing removed lines
+// with "-", added lines with "+" and unchanged lines with " ".
+// An empty string means the two texts are identical.
+func LineDiff(old, new string) string {
+	if old == new {
+		return ""
+	}
+	a := strings.Split(strings.TrimRight(old, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(new, "\n"), "\n")
+	if old == "" {
+		a = nil
+	}
+
+	// Longest common subsequence table; config files are small
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			sb.WriteString(" " + a[i] + "\n")
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			sb.WriteString("-" + a[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+" + b[j] + "\n")
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		sb.WriteString("-" + a[i] + "\n")
+	}
+	for ; j < len(b); j++ {
+		sb.WriteString("+" + b[j] + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type FirewallPeer struct {
+	Name string
+	IP   string
+}
+
+type FirewallParams struct {
+	NodeName string
+	NodeIP   string
+	Device   string // TAP device name, defaults to n2n0
+	Allowed  []FirewallPeer
+}
+
+// commentText strips control characters so that a name cannot break out of a script comment
+func commentText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func firewallDevice(p FirewallParams) string {
+	if p.Device == "" {
+		return "n2n0"
+	}
+	return p.Device
+}
+
+// GenerateIptablesScript renders a shell script that only accepts overlay traffic from permitted peers
+func GenerateIptablesScript(p FirewallParams) string {
+	dev := firewallDevice(p)
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n")
+	sb.WriteString("# n2n Micro-segmentation Rules Generated by n2n_ui\n")
+	sb.WriteString(fmt.Sprintf("# Node Name: %s (%s)\n\n", commentText(p.NodeName), p.NodeIP))
+
+	sb.WriteString("iptables -N N2N_SEG 2>/dev/null || iptables -F N2N_SEG\n")
+	sb.WriteString("iptables -A N2N_SEG -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT\n")
+	for _, peer := range p.Allowed {
+		sb.WriteString(fmt.Sprintf("iptables -A N2N_SEG -s %s -j ACCEPT # %s\n", peer.IP, commentText(peer.Name)))
+	}
+	sb.WriteString("iptables -A N2N_SEG -j DROP\n")
+	for _, chain := range []string{"INPUT", "FORWARD"} {
+		sb.WriteString(fmt.Sprintf("iptables -C %s -i %s -j N2N_SEG 2>/dev/null || iptables -I %s -i %s -j N2N_SEG\n", chain, dev, chain, dev))
+	}
+	return sb.String()
+}
+
+// GenerateNftScript renders the same policy as an nftables ruleset using a named set of overlay IPs
+func GenerateNftScript(p FirewallParams) string {
+	dev := firewallDevice(p)
+	ips := make([]string, 0, len(p.Allowed))
+	for _, peer := range p.Allowed {
+		ips = append(ips, peer.IP)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("#!/usr/sbin/nft -f\n")
+	sb.WriteString("# n2n Micro-segmentation Rules Generated by n2n_ui\n")
+	sb.WriteString(fmt.Sprintf("# Node Name: %s (%s)\n\n", commentText(p.NodeName), p.NodeIP))
+
+	sb.WriteString("table inet n2n_seg\n")
+	sb.WriteString("delete table inet n2n_seg\n")
+	sb.WriteString("table inet n2n_seg {\n")
+	sb.WriteString("\tset allowed_peers {\n")
+	sb.WriteString("\t\ttype ipv4_addr\n")
+	if len(ips) > 0 {
+		sb.WriteString(fmt.Sprintf("\t\telements = { %s }\n", strings.Join(ips, ", ")))
+	}
+	sb.WriteString("\t}\n")
+	for _, hook := range []string{"input", "forward"} {
+		sb.WriteString(fmt.Sprintf("\tchain %s {\n", hook))
+		sb.WriteString(fmt.Sprintf("\t\ttype filter hook %s priority 0; policy accept;\n", hook))
+		sb.WriteString(fmt.Sprintf("\t\tiifname \"%s\" ct state established,related accept\n", dev))
+		sb.WriteString(fmt.Sprintf("\t\tiifname \"%s\" ip saddr @allowed_peers accept\n", dev))
+		sb.WriteString(fmt.Sprintf("\t\tiifname \"%s\" drop\n", dev))
+		sb.WriteString("\t}\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
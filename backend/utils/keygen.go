@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// n2n 3.x user/password authentication derives keys with n2n's own hash and curve25519.
+// The derivation is done by the n2n-keygen tool shipped with n2n so the keys always match
+// what the supernode and edge compute.
+
+// KeygenBinary is the n2n-keygen executable, looked up in PATH by default
+var KeygenBinary = "n2n-keygen"
+
+// Keygen runs the key derivation; mock mode replaces it so no n2n installation is needed
+var Keygen = runKeygen
+
+func runKeygen(arg ...string) (string, error) {
+	out, err := exec.Command(KeygenBinary, arg...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s: %v: %s", KeygenBinary, err, strings.TrimSpace(string(out)))
+	}
+	// the key is the last field of the last non-empty line
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) == 0 {
+		return "", fmt.Errorf("%s: empty output", KeygenBinary)
+	}
+	return fields[len(fields)-1], nil
+}
+
+// UserPublicKey returns the public key of an edge user for the community.list user line:
+//
+//	n2n-keygen <user> <password>  ->  * <user> <public key>
+func UserPublicKey(user, password string) (string, error) {
+	return Keygen(user, password)
+}
+
+// FederationPublicKey returns the supernode public key edges pass with -P:
+//
+//	n2n-keygen -F <federation>  ->  -P <public key>
+func FederationPublicKey(federation string) (string, error) {
+	return Keygen("-F", federation)
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// Supernode log event types
+const (
+	EventRelay          = "relay"           // packet forwarded through the supernode between two edges
+	EventRegister       = "register"        // edge registered (or re-registered) with the supernode
+	EventPurge          = "purge"           // edge registration expired and was removed
+	EventAuthFailed     = "auth_failed"     // edge rejected because of bad credentials
+	EventCommunityError = "community_error" // registration for a community the supernode does not allow
+)
+
+// LogEvent is a structured event extracted from a single supernode log line
+type LogEvent struct {
+	Type      string `json:"type"`
+	Mac       string `json:"mac,omitempty"`     // edge MAC, normalized to upper case without separators
+	DstMac    string `json:"dst_mac,omitempty"` // relay destination
+	Community string `json:"community,omitempty"`
+	Addr      string `json:"addr,omitempty"` // edge socket address as logged by the supernode
+	Line      string `json:"line"`
+}
+
+// LogEventExtractor recognizes one kind of event in a log line
+type LogEventExtractor interface {
+	Extract(line string) (LogEvent, bool)
+}
+
+// regexExtractor maps named capture groups (mac, dst, community, addr) onto LogEvent fields
+type regexExtractor struct {
+	typ string
+	re  *regexp.Regexp
+}
+
+func (r regexExtractor) Extract(line string) (LogEvent, bool) {
+	m := r.re.FindStringSubmatch(line)
+	if m == nil {
+		return LogEvent{}, false
+	}
+	ev := LogEvent{Type: r.typ, Line: line}
+	for i, name := range r.re.SubexpNames() {
+		switch name {
+		case "mac":
+			ev.Mac = NormalizeMac(m[i])
+		case "dst":
+			ev.DstMac = NormalizeMac(m[i])
+		case "community":
+			ev.Community = m[i]
+		case "addr":
+			ev.Addr = m[i]
+		}
+	}
+	return ev, true
+}
+
+// NewRegexExtractor builds an extractor from a regular expression whose named groups
+// (?P<mac>), (?P<dst>), (?P<community>) and (?P<addr>) fill the event fields
+func NewRegexExtractor(eventType, expr string) (LogEventExtractor, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s pattern: %w", eventType, err)
+	}
+	return regexExtractor{typ: eventType, re: re}, nil
+}
+
+const macExpr = `[0-9A-Fa-f]{2}(?:[:-][0-9A-Fa-f]{2}){5}`
+
+func mustExtractor(eventType, expr string) LogEventExtractor {
+	ex, err := NewRegexExtractor(eventType, expr)
+	if err != nil {
+		panic(err)
+	}
+	return ex
+}
+
+// Built-in extractors for n2n 2.x and 3.x supernode logs, checked in order. Sample lines:
+//
+//	2.x: forwarding packet to 1.2.3.4:5678: from 02:00:00:00:01:01 to 02:00:00:00:01:02
+//	3.x: 15/Oct/2026 10:00:00 [sn_utils.c:1234] forwarding packet to [1.2.3.4:5678] from 02:00:00:00:01:01 to 02:00:00:00:01:02
+//	2.x: Rx REGISTER_SUPER for 02:00:00:00:01:01 [1.2.3.4:5678]
+//	3.x: Rx REGISTER_SUPER for community 'office' from 02:00:00:00:01:01 [1.2.3.4:5678]
+//	2.x: Purging old registration 02:00:00:00:01:01
+//	3.x: purge_expired_nodes removed 02:00:00:00:01:01
+//	3.x: Rx REGISTER_SUPER with wrong or old PIN/credentials from 02:00:00:00:01:01 [1.2.3.4:5678]
+//	3.x: authentication error, MAC or IP address already in use, sending REGISTER_SUPER_NAK to 02:00:00:00:01:01
+//	2.x: Discarded registration with unallowed community 'guest'
+//	3.x: discarded registration with unallowed community 'guest'
+var defaultExtractors = []LogEventExtractor{
+	mustExtractor(EventRelay, `forwarding packet.*from (?P<mac>`+macExpr+`) to (?P<dst>`+macExpr+`)`),
+	mustExtractor(EventAuthFailed, `(?i)(?:authentication (?:error|fail)|wrong or old (?:pin|credentials)|wrong credentials|REGISTER_SUPER_NAK)(?:.*?(?P<mac>`+macExpr+`))?(?:.*?\[(?P<addr>[^\]]+)\])?`),
+	mustExtractor(EventCommunityError, `(?i)(?:unallowed|unknown|invalid|not allowed) community\s*'?(?P<community>[^'\s]*)'?`),
+	mustExtractor(EventRegister, `(?i)Rx REGISTER_SUPER\b(?:.*?community '(?P<community>[^']*)')?.*? (?P<mac>`+macExpr+`)(?:.*?\[(?P<addr>[^\]]+)\])?`),
+	mustExtractor(EventPurge, `(?i)(?:purging|purge_\w+|removed expired)\b.*?(?P<mac>`+macExpr+`)`),
+}
+
+var (
+	extractors     = append([]LogEventExtractor(nil), defaultExtractors...)
+	extractorMutex sync.RWMutex
+)
+
+// RegisterLogEventExtractor adds a custom extractor, checked before the built-in ones
+func RegisterLogEventExtractor(ex LogEventExtractor) {
+	extractorMutex.Lock()
+	defer extractorMutex.Unlock()
+	extractors = append([]LogEventExtractor{ex}, extractors...)
+}
+
+// ParseLogEvent returns the first event recognized in line
+func ParseLogEvent(line string) (LogEvent, bool) {
+	extractorMutex.RLock()
+	defer extractorMutex.RUnlock()
+	for _, ex := range extractors {
+		if ev, ok := ex.Extract(line); ok {
+			return ev, true
+		}
+	}
+	return LogEvent{}, false
+}
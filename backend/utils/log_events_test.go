@@ -0,0 +1,113 @@
+package utils
+
+import "testing"
+
+// Lines as written by n2n 2.x (sn.c) and 3.x (sn_utils.c) supernodes, both with the
+// supernode's own trace prefix and as forwarded by journald
+func TestParseLogEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want LogEvent
+	}{
+		{
+			name: "2.x relay",
+			line: "15/Oct/2026 10:00:00 [sn.c: 642] forwarding packet to 198.51.100.23:51820: from 02:00:00:00:01:01 to 02:00:00:00:01:02",
+			want: LogEvent{Type: EventRelay, Mac: "020000000101", DstMac: "020000000102"},
+		},
+		{
+			name: "3.x relay via journald",
+			line: "Oct 15 10:00:00 sn1 supernode[4242]: 15/Oct/2026 10:00:00 [sn_utils.c:1234] forwarding packet to [198.51.100.23:51820] from 02:00:00:00:01:01 to 02:00:00:00:01:02",
+			want: LogEvent{Type: EventRelay, Mac: "020000000101", DstMac: "020000000102"},
+		},
+		{
+			name: "2.x register",
+			line: "15/Oct/2026 10:00:00 [sn.c: 563] Rx REGISTER_SUPER for 02:00:00:00:01:01 [198.51.100.23:51820]",
+			want: LogEvent{Type: EventRegister, Mac: "020000000101", Addr: "198.51.100.23:51820"},
+		},
+		{
+			name: "3.x register with community",
+			line: "15/Oct/2026 10:00:00 [sn_utils.c:1620] Rx REGISTER_SUPER for community 'office' from 02:00:00:00:01:01 [198.51.100.23:51820]",
+			want: LogEvent{Type: EventRegister, Mac: "020000000101", Community: "office", Addr: "198.51.100.23:51820"},
+		},
+		{
+			name: "3.x register with dash separated MAC",
+			line: "Rx REGISTER_SUPER for community 'lab' from 02-00-00-00-02-0a [203.0.113.7:40000]",
+			want: LogEvent{Type: EventRegister, Mac: "02000000020A", Community: "lab", Addr: "203.0.113.7:40000"},
+		},
+		{
+			name: "2.x purge",
+			line: "15/Oct/2026 10:00:00 [sn.c: 310] Purging old registration 02:00:00:00:01:01",
+			want: LogEvent{Type: EventPurge, Mac: "020000000101"},
+		},
+		{
+			name: "3.x purge",
+			line: "15/Oct/2026 10:00:00 [sn_utils.c: 402] purge_expired_nodes removed 02:00:00:00:01:01",
+			want: LogEvent{Type: EventPurge, Mac: "020000000101"},
+		},
+		{
+			name: "3.x wrong credentials",
+			line: "15/Oct/2026 10:00:00 [sn_utils.c:1702] WARNING: Rx REGISTER_SUPER with wrong or old PIN/credentials from 02:00:00:00:01:01 [198.51.100.23:51820]",
+			want: LogEvent{Type: EventAuthFailed, Mac: "020000000101", Addr: "198.51.100.23:51820"},
+		},
+		{
+			name: "3.x address in use",
+			line: "15/Oct/2026 10:00:00 [sn_utils.c:1750] authentication error, MAC or IP address already in use, sending REGISTER_SUPER_NAK to 02:00:00:00:01:01",
+			want: LogEvent{Type: EventAuthFailed, Mac: "020000000101"},
+		},
+		{
+			name: "2.x unallowed community",
+			line: "15/Oct/2026 10:00:00 [sn.c: 590] Discarded registration with unallowed community 'guest'",
+			want: LogEvent{Type: EventCommunityError, Community: "guest"},
+		},
+		{
+			name: "3.x unallowed community via journald",
+			line: "Oct 15 10:00:00 sn1 supernode[4242]: 15/Oct/2026 10:00:00 [sn_utils.c:1580] discarded registration with unallowed community 'guest'",
+			want: LogEvent{Type: EventCommunityError, Community: "guest"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseLogEvent(tt.line)
+			if !ok {
+				t.Fatalf("ParseLogEvent(%q) recognized nothing", tt.line)
+			}
+			tt.want.Line = tt.line
+			if got != tt.want {
+				t.Errorf("ParseLogEvent(%q)\n got %+v\nwant %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLogEventIgnoresOtherLines(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"15/Oct/2026 10:00:00 [sn.c: 120] Supernode ready: listening on port 7654 [UDP]",
+		"15/Oct/2026 10:00:00 [sn_utils.c:2010] management port 5645 opened",
+		"15/Oct/2026 10:00:00 [supernode.c: 80] Loaded community list with 2 communities",
+		"Oct 15 10:00:00 sn1 systemd[1]: Started n2n supernode process.",
+	} {
+		if ev, ok := ParseLogEvent(line); ok {
+			t.Errorf("ParseLogEvent(%q) = %+v, want no event", line, ev)
+		}
+	}
+}
+
+func TestRegisterLogEventExtractor(t *testing.T) {
+	saved := extractors
+	t.Cleanup(func() { extractors = saved })
+
+	ex, err := NewRegexExtractor(EventRegister, `custom join (?P<mac>`+macExpr+`) in (?P<community>\w+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterLogEventExtractor(ex)
+	got, ok := ParseLogEvent("custom join 02:00:00:00:01:01 in office")
+	if !ok || got.Type != EventRegister || got.Mac != "020000000101" || got.Community != "office" {
+		t.Errorf("custom extractor: got %+v, %v", got, ok)
+	}
+	if _, err := NewRegexExtractor(EventRegister, `(`); err == nil {
+		t.Error("NewRegexExtractor accepted an invalid pattern")
+	}
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPConfig describes how to reach an outgoing mail server
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	TLSMode  string // none, starttls, tls
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// SendMail delivers a plain-text UTF-8 message through the configured SMTP server
+func SendMail(cfg SMTPConfig, subject, body string) error {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return fmt.Errorf("smtp host, from and to are required")
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(cfg.Host, fmt.Sprintf("%d", port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if cfg.TLSMode == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if cfg.TLSMode == "starttls" {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, rcpt := range cfg.To {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(buildMailMessage(cfg.From, cfg.To, subject, body))); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func buildMailMessage(from string, to []string, subject, body string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("From: %s\r\n", from))
+	sb.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(to, ", ")))
+	sb.WriteString(fmt.Sprintf("Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", subject)))
+	sb.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	sb.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	sb.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return sb.String()
+}
+
+// SplitList splits a comma/semicolon separated list and drops empty items
+func SplitList(s string) []string {
+	res := make([]string, 0)
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
@@ -0,0 +1,329 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types
+const (
+	mqttConnect    = 1
+	mqttConnack    = 2
+	mqttPublish    = 3
+	mqttPuback     = 4
+	mqttDisconnect = 14
+)
+
+const (
+	defaultMQTTTimeout  = 10 * time.Second
+	mqttMaxRemainingLen = 268435455
+)
+
+var mqttConnackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// MQTTMessage is an application message. QoS 0 and 1 are supported.
+type MQTTMessage struct {
+	Topic   string
+	Payload string
+	QoS     byte
+	Retain  bool
+}
+
+// MQTTConfig describes a broker connection. Will is registered with the broker and
+// published by it when the connection drops unexpectedly, Birth is published after
+// every successful connect; either is ignored when its Topic is empty.
+type MQTTConfig struct {
+	Broker    string // host:port
+	TLS       bool
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration
+	Timeout   time.Duration
+	Will      MQTTMessage
+	Birth     MQTTMessage
+}
+
+// MQTTClient is a minimal publish-only MQTT 3.1.1 client. It connects on demand,
+// waits for PUBACK on QoS 1 messages and is safe for concurrent use.
+type MQTTClient struct {
+	cfg      MQTTConfig
+	mu       sync.Mutex
+	conn     net.Conn
+	r        *bufio.Reader
+	nextID   uint16
+	connects int
+}
+
+// NewMQTTClient creates a client, the connection is opened by Connect or the first Publish
+func NewMQTTClient(cfg MQTTConfig) *MQTTClient {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultMQTTTimeout
+	}
+	return &MQTTClient{cfg: cfg}
+}
+
+// ValidateMQTTTopic checks that a topic can be published to
+func ValidateMQTTTopic(topic string) error {
+	if topic == "" {
+		return errors.New("topic is empty")
+	}
+	if len(topic) > 65535 {
+		return errors.New("topic is too long")
+	}
+	if strings.ContainsAny(topic, "+#\x00") {
+		return errors.New("topic must not contain wildcards")
+	}
+	return nil
+}
+
+func mqttString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// mqttPacket prepends the fixed header (type, flags and remaining length)
+func mqttPacket(typ, flags byte, body []byte) []byte {
+	b := []byte{typ<<4 | flags}
+	n := len(body)
+	for {
+		d := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			d |= 0x80
+		}
+		b = append(b, d)
+		if n == 0 {
+			break
+		}
+	}
+	return append(b, body...)
+}
+
+// readPacket reads one control packet and returns its type, flags and body
+func (c *MQTTClient) readPacket() (byte, byte, []byte, error) {
+	h, err := c.r.ReadByte()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	n, mul := 0, 1
+	for i := 0; ; i++ {
+		d, err := c.r.ReadByte()
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		n += int(d&0x7f) * mul
+		if d&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, 0, nil, errors.New("malformed remaining length")
+		}
+		mul *= 128
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, 0, nil, err
+	}
+	return h >> 4, h & 0x0f, body, nil
+}
+
+func (c *MQTTClient) dial() (net.Conn, error) {
+	d := &net.Dialer{Timeout: c.cfg.Timeout}
+	if c.cfg.TLS {
+		host, _, err := net.SplitHostPort(c.cfg.Broker)
+		if err != nil {
+			return nil, err
+		}
+		return tls.DialWithDialer(d, "tcp", c.cfg.Broker, &tls.Config{ServerName: host})
+	}
+	return d.Dial("tcp", c.cfg.Broker)
+}
+
+func (c *MQTTClient) connectPacket() []byte {
+	flags := byte(0x02) // clean session
+	body := mqttString(nil, "MQTT")
+	body = append(body, 4) // protocol level 3.1.1
+	flagPos := len(body)
+	body = append(body, 0)
+	keepAlive := c.cfg.KeepAlive / time.Second
+	if keepAlive > 65535 {
+		keepAlive = 65535
+	}
+	body = binary.BigEndian.AppendUint16(body, uint16(keepAlive))
+	body = mqttString(body, c.cfg.ClientID)
+	if w := c.cfg.Will; w.Topic != "" {
+		flags |= 0x04 | (w.QoS&0x03)<<3
+		if w.Retain {
+			flags |= 0x20
+		}
+		body = mqttString(body, w.Topic)
+		body = mqttString(body, w.Payload)
+	}
+	if c.cfg.Username != "" {
+		flags |= 0x80
+		body = mqttString(body, c.cfg.Username)
+		if c.cfg.Password != "" {
+			flags |= 0x40
+			body = mqttString(body, c.cfg.Password)
+		}
+	}
+	body[flagPos] = flags
+	return mqttPacket(mqttConnect, 0, body)
+}
+
+// connect opens the connection and waits for CONNACK, the caller holds c.mu
+func (c *MQTTClient) connect() error {
+	conn, err := c.dial()
+	if err != nil {
+		return err
+	}
+	c.conn, c.r = conn, bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(c.cfg.Timeout))
+	if _, err := conn.Write(c.connectPacket()); err != nil {
+		c.closeConn()
+		return err
+	}
+	typ, _, body, err := c.readPacket()
+	if err == nil && (typ != mqttConnack || len(body) != 2) {
+		err = fmt.Errorf("unexpected packet type %d", typ)
+	}
+	if err == nil && body[1] != 0 {
+		reason, ok := mqttConnackErrors[body[1]]
+		if !ok {
+			reason = fmt.Sprintf("code %d", body[1])
+		}
+		err = errors.New("connection refused: " + reason)
+	}
+	if err != nil {
+		c.closeConn()
+		return err
+	}
+	c.connects++
+	if c.cfg.Birth.Topic != "" {
+		if err := c.publish(c.cfg.Birth); err != nil {
+			c.closeConn()
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *MQTTClient) closeConn() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn, c.r = nil, nil
+	}
+}
+
+// publish sends a message on the open connection, the caller holds c.mu
+func (c *MQTTClient) publish(m MQTTMessage) error {
+	if m.QoS > 1 {
+		return errors.New("QoS 2 is not supported")
+	}
+	flags := m.QoS << 1
+	if m.Retain {
+		flags |= 0x01
+	}
+	body := mqttString(nil, m.Topic)
+	var id uint16
+	if m.QoS > 0 {
+		c.nextID++
+		if c.nextID == 0 {
+			c.nextID = 1
+		}
+		id = c.nextID
+		body = binary.BigEndian.AppendUint16(body, id)
+	}
+	body = append(body, m.Payload...)
+	if len(body) > mqttMaxRemainingLen {
+		return errors.New("message too large")
+	}
+	c.conn.SetDeadline(time.Now().Add(c.cfg.Timeout))
+	if _, err := c.conn.Write(mqttPacket(mqttPublish, flags, body)); err != nil {
+		return err
+	}
+	if m.QoS == 0 {
+		return nil
+	}
+	return c.waitFor(mqttPuback, id)
+}
+
+// waitFor reads packets until the acknowledgement with the given packet ID arrives
+func (c *MQTTClient) waitFor(typ byte, id uint16) error {
+	for {
+		t, _, body, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+		if t == typ && len(body) >= 2 && binary.BigEndian.Uint16(body) == id {
+			return nil
+		}
+	}
+}
+
+// Connect opens the connection if it is not open yet
+func (c *MQTTClient) Connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		return nil
+	}
+	return c.connect()
+}
+
+// Connects returns how many times the client has connected, a change means
+// retained state may need to be published again
+func (c *MQTTClient) Connects() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connects
+}
+
+// Publish sends a message, reconnecting once when the connection has been lost
+func (c *MQTTClient) Publish(m MQTTMessage) error {
+	if err := ValidateMQTTTopic(m.Topic); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.conn == nil {
+			if err = c.connect(); err != nil {
+				return err
+			}
+		}
+		if err = c.publish(m); err == nil {
+			return nil
+		}
+		c.closeConn()
+	}
+	return err
+}
+
+// Close sends DISCONNECT and closes the connection. The broker discards the will
+// message on a clean disconnect.
+func (c *MQTTClient) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		c.conn.SetDeadline(time.Now().Add(c.cfg.Timeout))
+		c.conn.Write(mqttPacket(mqttDisconnect, 0, nil))
+		c.closeConn()
+	}
+}
@@ -1,52 +1,376 @@
 package utils
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// MgmtClient talks to a supernode management port over a single long-lived UDP
+// socket. Requests are serialized on that socket, so one client is safe for
+// concurrent use; zero values of the tuning fields select the defaults below.
 type MgmtClient struct {
 	Addr     string
 	Password string
+	CacheTTL time.Duration // 0 disables caching of edge info
+
+	Timeout      time.Duration // wait for the first reply datagram and for a JSON reply to complete
+	IdleTimeout  time.Duration // a legacy text reply ends after this long without a datagram
+	Retries      int           // extra attempts after a timeout or socket error, negative disables
+	RetryBackoff time.Duration // delay before the first retry, doubled for each further one
+
+	connMu     sync.Mutex
+	conn       *net.UDPConn
+	connClosed bool
+	legacy     atomic.Bool // the supernode does not speak the JSON API, use the text edge table
+
+	statusMu sync.Mutex
+	status   MgmtStatus
+
+	cacheMu    sync.Mutex
+	cacheEdges map[string]EdgeInfo
+	cacheErr   error
+	cacheAt    time.Time
+	stop       chan struct{}
+	closed     bool
+}
+
+// MgmtStatus summarizes recent mgmt port reachability: Failures counts consecutive
+// requests that got no usable reply (after retries) and resets on the next success
+type MgmtStatus struct {
+	Failures    int       `json:"failures"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastSuccess time.Time `json:"last_success"`
+	LastFailure time.Time `json:"last_failure"`
+}
+
+// Status returns the current reachability summary
+func (m *MgmtClient) Status() MgmtStatus {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	return m.status
+}
+
+func (m *MgmtClient) recordResult(err error) {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	if err == nil {
+		m.status.Failures, m.status.LastError, m.status.LastSuccess = 0, "", time.Now()
+		return
+	}
+	m.status.Failures++
+	m.status.LastError, m.status.LastFailure = err.Error(), time.Now()
+}
+
+// MgmtRow is one "_type":"row" object of an n2n 3.x JSON mgmt reply
+type MgmtRow map[string]interface{}
+
+var mgmtTagCounter uint32
+
+const (
+	defaultMgmtTimeout      = 1 * time.Second
+	defaultMgmtIdleTimeout  = 150 * time.Millisecond
+	defaultMgmtRetries      = 2
+	defaultMgmtRetryBackoff = 100 * time.Millisecond
+)
+
+var (
+	// ErrNoResponse is returned when the mgmt port did not answer within the timeout
+	ErrNoResponse = errors.New("no response from mgmt port")
+	// ErrNotJSON is returned when a JSON request is answered with something else (n2n 2.x)
+	ErrNotJSON = errors.New("mgmt port does not speak the JSON API (n2n 3.x required)")
+
+	errIncompleteReply = errors.New("incomplete reply from mgmt port")
+	errClientClosed    = errors.New("mgmt client closed")
+)
+
+// QueryObserver, when set, is called after every mgmt port round trip with the
+// command name (the JSON API method or the legacy command) and its duration
+var QueryObserver func(op string, d time.Duration, err error)
+
+// mgmtOpName extracts the method from a raw request without the auth field
+func mgmtOpName(command string) string {
+	fields := strings.Fields(command)
+	switch {
+	case len(fields) >= 3 && (fields[0] == "r" || fields[0] == "w"):
+		return fields[2]
+	case len(fields) > 0:
+		return fields[0]
+	}
+	return ""
+}
+
+// Read issues a n2n 3.x JSON read request ("r <tag> <method>")
+func (m *MgmtClient) Read(method string, args ...string) ([]MgmtRow, error) {
+	return m.jsonRequest("r", method, args...)
+}
+
+// Write issues a n2n 3.x JSON write request, authenticating with Password.
+// Supernodes started with --management-password reject writes without it ("badauth").
+func (m *MgmtClient) Write(method string, args ...string) ([]MgmtRow, error) {
+	return m.jsonRequest("w", method, args...)
+}
+
+// SetVerbosity changes the supernode log verbosity at runtime
+func (m *MgmtClient) SetVerbosity(level int) error {
+	_, err := m.Write("verbose", fmt.Sprintf("%d", level))
+	return err
+}
+
+// GetVerbosity reads the current supernode trace level
+func (m *MgmtClient) GetVerbosity() (int, error) {
+	rows, err := m.Read("verbose")
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		if v, ok := row["traceLevel"].(float64); ok {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("verbosity not reported by supernode")
+}
+
+// ReloadCommunities asks the supernode to re-read its community.list
+func (m *MgmtClient) ReloadCommunities() error {
+	_, err := m.Write("reload_communities")
+	return err
+}
+
+// PacketStats returns the raw rows of the "packetstats" read command
+func (m *MgmtClient) PacketStats() ([]MgmtRow, error) {
+	return m.Read("packetstats")
+}
+
+// Supernodes returns the raw rows of the "supernodes" read command: the federation
+// peers this supernode knows about, with their address and last-seen time
+func (m *MgmtClient) Supernodes() ([]MgmtRow, error) {
+	return m.Read("supernodes")
+}
+
+// ErrUnsupported is returned when the supernode does not know the requested method
+var ErrUnsupported = fmt.Errorf("command not supported by this supernode")
+
+// DropEdge asks the supernode to purge an edge registration. Mainline n2n 3.x does not
+// implement this method yet, in which case ErrUnsupported is returned.
+func (m *MgmtClient) DropEdge(mac string) error {
+	_, err := m.Write("drop_edge", mac)
+	return err
+}
+
+func (m *MgmtClient) jsonRequest(kind, method string, args ...string) ([]MgmtRow, error) {
+	tag := fmt.Sprintf("%d", atomic.AddUint32(&mgmtTagCounter, 1)%1000)
+	tagField := tag
+	if m.Password != "" {
+		// <tag>:<flags>:<auth>, flag 1 = auth field present
+		tagField = fmt.Sprintf("%s:1:%s", tag, m.Password)
+	}
+	req := fmt.Sprintf("%s %s %s", kind, tagField, method)
+	if len(args) > 0 {
+		req += " " + strings.Join(args, " ")
+	}
+	resp, err := m.request(req+"\n", jsonReplyDone(tag))
+	if err != nil {
+		return nil, err
+	}
+	return parseJSONReply(resp, tag)
+}
+
+// jsonReplyDone reports a JSON reply complete once the "end" or "error" object for tag
+// arrives. Anything that is not JSON also ends the wait so n2n 2.x is detected quickly.
+func jsonReplyDone(tag string) func(chunk []byte) bool {
+	return func(chunk []byte) bool {
+		for _, line := range strings.Split(string(chunk), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			var obj MgmtRow
+			if json.Unmarshal([]byte(line), &obj) != nil {
+				return true
+			}
+			if t, _ := obj["_tag"].(string); t == tag && (obj["_type"] == "end" || obj["_type"] == "error") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// parseJSONReply collects row objects for the given tag and surfaces protocol errors
+func parseJSONReply(resp, tag string) ([]MgmtRow, error) {
+	rows := make([]MgmtRow, 0)
+	for _, line := range strings.Split(resp, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var obj MgmtRow
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			return nil, ErrNotJSON
+		}
+		if t, _ := obj["_tag"].(string); t != tag {
+			continue
+		}
+		switch obj["_type"] {
+		case "error":
+			if obj["error"] == "unknowncmd" {
+				return nil, ErrUnsupported
+			}
+			return nil, fmt.Errorf("mgmt error: %v", obj["error"])
+		case "row":
+			rows = append(rows, obj)
+		}
+	}
+	return rows, nil
 }
 
 type EdgeInfo struct {
-	Mac      string `json:"mac"`
-	Internal string `json:"internal"`
-	External string `json:"external"`
-	LastSeen int    `json:"last_seen"`
+	Mac       string `json:"mac"`
+	Internal  string `json:"internal"`
+	External  string `json:"external"`
+	LastSeen  int    `json:"last_seen"`
+	Community string `json:"community"`
 }
 
+// Query sends a legacy text command and returns the raw reply, which ends once the
+// port stays quiet for IdleTimeout
 func (m *MgmtClient) Query(command string) (string, error) {
-	conn, err := net.DialTimeout("udp", m.Addr, 1*time.Second)
-	if err != nil {
-		return "", err
+	return m.request(command, nil)
+}
+
+func (m *MgmtClient) timeout() time.Duration {
+	if m.Timeout > 0 {
+		return m.Timeout
+	}
+	return defaultMgmtTimeout
+}
+
+func (m *MgmtClient) idleTimeout() time.Duration {
+	if m.IdleTimeout > 0 {
+		return m.IdleTimeout
+	}
+	return defaultMgmtIdleTimeout
+}
+
+// request runs roundTrip with retries and exponential backoff and reports the
+// total duration to QueryObserver
+func (m *MgmtClient) request(command string, done func(chunk []byte) bool) (resp string, err error) {
+	start := time.Now()
+	retries, backoff := m.Retries, m.RetryBackoff
+	if retries == 0 {
+		retries = defaultMgmtRetries
+	}
+	if backoff <= 0 {
+		backoff = defaultMgmtRetryBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err = m.roundTrip(command, done)
+		if err == nil || err == errClientClosed || attempt >= retries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
 	}
-	defer conn.Close()
+	if err != errClientClosed {
+		m.recordResult(err)
+	}
+	if QueryObserver != nil {
+		QueryObserver(mgmtOpName(command), time.Since(start), err)
+	}
+	return resp, err
+}
 
-	_, err = conn.Write([]byte(command))
+// roundTrip sends one request on the shared socket and collects reply datagrams.
+// With done set the reply is complete when done returns true; otherwise (legacy text)
+// it ends after IdleTimeout without data. The socket is held for the whole exchange
+// because legacy replies carry no tag to tell concurrent requests apart.
+func (m *MgmtClient) roundTrip(command string, done func(chunk []byte) bool) (string, error) {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+	conn, err := m.dialLocked()
 	if err != nil {
 		return "", err
 	}
+	drainStale(conn)
+	if _, err := conn.Write([]byte(command)); err != nil {
+		m.resetLocked()
+		return "", err
+	}
 
-	var fullResp strings.Builder
-	buffer := make([]byte, 8192)
-	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
-	
+	var resp strings.Builder
+	buffer := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(m.timeout()))
 	for {
 		n, err := conn.Read(buffer)
 		if n > 0 {
-			fullResp.Write(buffer[:n])
-			conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+			resp.Write(buffer[:n])
+			if done != nil && done(buffer[:n]) {
+				return resp.String(), nil
+			}
+			if done == nil {
+				conn.SetReadDeadline(time.Now().Add(m.idleTimeout()))
+			}
 		}
-		if err != nil {
-			break
+		if err == nil {
+			continue
 		}
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			switch {
+			case resp.Len() == 0:
+				return "", ErrNoResponse
+			case done != nil:
+				return "", errIncompleteReply
+			}
+			return resp.String(), nil
+		}
+		// e.g. ICMP port unreachable while the supernode restarts
+		m.resetLocked()
+		return "", err
+	}
+}
+
+// drainStale discards late datagrams of an earlier request that timed out
+func drainStale(conn *net.UDPConn) {
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			return
+		}
+	}
+}
+
+func (m *MgmtClient) dialLocked() (*net.UDPConn, error) {
+	if m.connClosed {
+		return nil, errClientClosed
+	}
+	if m.conn != nil {
+		return m.conn, nil
+	}
+	raddr, err := net.ResolveUDPAddr("udp", m.Addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return nil, err
+	}
+	m.conn = conn
+	return conn, nil
+}
+
+func (m *MgmtClient) resetLocked() {
+	if m.conn != nil {
+		m.conn.Close()
+		m.conn = nil
 	}
-	return fullResp.String(), nil
 }
 
 func (m *MgmtClient) GetOnlineMacs() (map[string]int, error) {
@@ -61,7 +385,98 @@ func (m *MgmtClient) GetOnlineMacs() (map[string]int, error) {
 	return res, nil
 }
 
+// GetEdgeInfo returns the edge table, served from the shared cache while it is fresh.
+// Concurrent callers wait for a single in-flight query instead of each hitting the mgmt port.
 func (m *MgmtClient) GetEdgeInfo() (map[string]EdgeInfo, error) {
+	if m.CacheTTL <= 0 {
+		return m.FetchEdgeInfo()
+	}
+	m.cacheMu.Lock()
+	defer m.cacheMu.Unlock()
+	if m.cacheAt.IsZero() || time.Since(m.cacheAt) > m.CacheTTL {
+		m.cacheEdges, m.cacheErr = m.FetchEdgeInfo()
+		m.cacheAt = time.Now()
+	}
+	return copyEdges(m.cacheEdges), m.cacheErr
+}
+
+// InvalidateCache forces the next GetEdgeInfo call to query the supernode
+func (m *MgmtClient) InvalidateCache() {
+	m.cacheMu.Lock()
+	m.cacheAt = time.Time{}
+	m.cacheMu.Unlock()
+}
+
+// StartCacheRefresher keeps the cache warm so API requests rarely wait on the mgmt port
+func (m *MgmtClient) StartCacheRefresher() {
+	if m.CacheTTL <= 0 {
+		return
+	}
+	stop := m.stopChan()
+	ticker := time.NewTicker(m.CacheTTL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		edges, err := m.FetchEdgeInfo()
+		m.cacheMu.Lock()
+		m.cacheEdges, m.cacheErr, m.cacheAt = edges, err, time.Now()
+		m.cacheMu.Unlock()
+	}
+}
+
+func (m *MgmtClient) stopChan() chan struct{} {
+	m.cacheMu.Lock()
+	defer m.cacheMu.Unlock()
+	if m.stop == nil {
+		m.stop = make(chan struct{})
+	}
+	return m.stop
+}
+
+// Close stops the background cache refresher and releases the socket
+func (m *MgmtClient) Close() {
+	m.connMu.Lock()
+	m.resetLocked()
+	m.connClosed = true
+	m.connMu.Unlock()
+	stop := m.stopChan()
+	m.cacheMu.Lock()
+	defer m.cacheMu.Unlock()
+	if !m.closed {
+		m.closed = true
+		close(stop)
+	}
+}
+
+func copyEdges(src map[string]EdgeInfo) map[string]EdgeInfo {
+	if src == nil {
+		return nil
+	}
+	dst := make(map[string]EdgeInfo, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+// FetchEdgeInfo queries the supernode for the current edge table, bypassing the cache.
+// The JSON "edges" reply is preferred because its end marker tells when the table is
+// complete; supernodes without the JSON API fall back to parsing the text table.
+func (m *MgmtClient) FetchEdgeInfo() (map[string]EdgeInfo, error) {
+	if !m.legacy.Load() {
+		rows, err := m.Read("edges")
+		if err == nil {
+			return edgesFromRows(rows), nil
+		}
+		if err != ErrNotJSON && err != ErrUnsupported {
+			return nil, err
+		}
+		m.legacy.Store(true)
+	}
 	resp, err := m.Query("edges")
 	if err != nil {
 		return nil, err
@@ -72,8 +487,15 @@ func (m *MgmtClient) GetEdgeInfo() (map[string]EdgeInfo, error) {
 	onlineEdges := make(map[string]EdgeInfo)
 
 	isSupernodeSection := false
+	currentCommunity := ""
 
 	for _, line := range lines {
+		// Edge tables are grouped under "community: <name>" headings
+		if idx := strings.Index(strings.ToLower(line), "community:"); idx >= 0 && reMac.FindString(line) == "" {
+			currentCommunity = strings.Trim(strings.TrimSpace(line[idx+len("community:"):]), "|")
+			currentCommunity = strings.TrimSpace(currentCommunity)
+			continue
+		}
 		if strings.Contains(line, "SUPERNODES") {
 			isSupernodeSection = true
 			continue
@@ -88,7 +510,7 @@ func (m *MgmtClient) GetEdgeInfo() (map[string]EdgeInfo, error) {
 
 		mac := reMac.FindString(line)
 		if mac != "" {
-			cleanMac := strings.ToUpper(strings.ReplaceAll(mac, ":", ""))
+			cleanMac := NormalizeMac(mac)
 			fields := strings.Split(line, "|")
 			if len(fields) >= 5 {
 				internal := strings.TrimSpace(fields[1])
@@ -97,13 +519,38 @@ func (m *MgmtClient) GetEdgeInfo() (map[string]EdgeInfo, error) {
 				fmt.Sscanf(strings.TrimSpace(fields[len(fields)-1]), "%d", &lastSeen)
 				
 				onlineEdges[cleanMac] = EdgeInfo{
-					Mac:      cleanMac,
-					Internal: internal,
-					External: external,
-					LastSeen: lastSeen,
+					Mac:       cleanMac,
+					Internal:  internal,
+					External:  external,
+					LastSeen:  lastSeen,
+					Community: currentCommunity,
 				}
 			}
 		}
 	}
 	return onlineEdges, nil
-}
\ No newline at end of file
+}
+// edgesFromRows converts JSON "edges" rows, skipping federation peers (communities
+// starting with '*') like the text parser skips the SUPERNODES section
+func edgesFromRows(rows []MgmtRow) map[string]EdgeInfo {
+	edges := make(map[string]EdgeInfo)
+	for _, row := range rows {
+		community, _ := row["community"].(string)
+		mac, _ := row["macaddr"].(string)
+		if mac == "" || strings.HasPrefix(community, "*") {
+			continue
+		}
+		cleanMac := NormalizeMac(mac)
+		internal, _ := row["ip4addr"].(string)
+		external, _ := row["sockaddr"].(string)
+		lastSeen, _ := row["last_seen"].(float64)
+		edges[cleanMac] = EdgeInfo{
+			Mac:       cleanMac,
+			Internal:  internal,
+			External:  external,
+			LastSeen:  int(lastSeen),
+			Community: community,
+		}
+	}
+	return edges
+}
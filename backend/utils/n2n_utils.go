@@ -6,6 +6,7 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 )
 
@@ -50,7 +51,8 @@ func CompareIP(a, b net.IP) int {
 	return 0
 }
 
-// WriteCommunityList writes a list of communities to a file
+// WriteCommunityList writes a list of communities to a file, one entry per line.
+// n2n 3.x user lines (" * <user> <public key>") may follow the community they belong to.
 func WriteCommunityList(filePath string, communities []string) error {
 	content := strings.Join(communities, "\n")
 	if content != "" {
@@ -59,6 +61,24 @@ func WriteCommunityList(filePath string, communities []string) error {
 	return os.WriteFile(filePath, []byte(content), 0644)
 }
 
+// ReadCommunityList reads community names from a supernode community.list file, skipping comments and user lines
+func ReadCommunityList(filePath string) ([]string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	communities := make([]string, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "*") {
+			continue // "*" starts a user line of the preceding community
+		}
+		// n2n 3.x allows an optional network after the name, e.g. "mycomm 10.0.0.0/24"
+		communities = append(communities, strings.Fields(line)[0])
+	}
+	return communities, nil
+}
+
 // ReadSupernodeConfig reads n2n supernode config file into a map
 func ReadSupernodeConfig(filePath string) (map[string]string, error) {
 	data, err := os.ReadFile(filePath)
@@ -86,19 +106,27 @@ func ReadSupernodeConfig(filePath string) (map[string]string, error) {
 	return config, nil
 }
 
-// WriteSupernodeConfig writes map back to n2n supernode config file
-func WriteSupernodeConfig(filePath string, config map[string]string) error {
+// FormatSupernodeConfig renders a supernode config file, options sorted so that the output is stable
+func FormatSupernodeConfig(config map[string]string) string {
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 	var lines []string
-	for k, v := range config {
-		if v == "" {
+	for _, k := range keys {
+		if v := config[k]; v == "" {
 			lines = append(lines, fmt.Sprintf("-%s", k))
 		} else {
 			lines = append(lines, fmt.Sprintf("-%s=%s", k, v))
 		}
 	}
-	
-	content := strings.Join(lines, "\n") + "\n"
-	return os.WriteFile(filePath, []byte(content), 0644)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// WriteSupernodeConfig writes map back to n2n supernode config file
+func WriteSupernodeConfig(filePath string, config map[string]string) error {
+	return os.WriteFile(filePath, []byte(FormatSupernodeConfig(config)), 0644)
 }
 
 // RunCommand executes a system command
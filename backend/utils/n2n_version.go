@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// SupernodeBinary is the supernode executable, looked up in PATH by default
+var SupernodeBinary = "supernode"
+
+// SupernodeVersion reports the installed supernode version; mock mode replaces it
+var SupernodeVersion = runSupernodeVersion
+
+// n2n prints "Welcome to n2n v.3.0.0.r1234.abcdef for Linux" or "n2n v3.1.1" in its help text
+var n2nVersionPattern = regexp.MustCompile(`n2n v\.?(\d+\.\d+(?:\.\d+)?)`)
+
+// ParseN2NVersion extracts the x.y[.z] version from supernode or edge help output
+func ParseN2NVersion(out string) string {
+	if m := n2nVersionPattern.FindStringSubmatch(out); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// runSupernodeVersion runs "supernode -h". The help screen exits with a non-zero
+// status on some versions, so the output is parsed regardless of the exit code.
+func runSupernodeVersion() (string, error) {
+	out, err := exec.Command(SupernodeBinary, "-h").CombinedOutput()
+	if v := ParseN2NVersion(string(out)); v != "" {
+		return v, nil
+	}
+	var execErr *exec.Error
+	if errors.As(err, &execErr) {
+		return "", fmt.Errorf("%s: %v", SupernodeBinary, err)
+	}
+	return "", fmt.Errorf("%s -h: no version in output: %.80s", SupernodeBinary, strings.TrimSpace(string(out)))
+}
+
+// MgmtCapabilities describes what the management port of a running supernode supports
+type MgmtCapabilities struct {
+	JSON       bool `json:"json"`       // n2n 3.x JSON API ("r <tag> <method>")
+	Timestamps bool `json:"timestamps"` // the "timestamps" read command
+}
+
+// ProbeCapabilities asks the management port for "verbose" and "timestamps". A text
+// reply means n2n 2.x, which only has the legacy edge table. ErrNoResponse and other
+// transport errors are returned as is since nothing can be concluded from them.
+func (m *MgmtClient) ProbeCapabilities() (MgmtCapabilities, error) {
+	var caps MgmtCapabilities
+	if _, err := m.Read("verbose"); err != nil {
+		if errors.Is(err, ErrNotJSON) {
+			return caps, nil
+		}
+		if !errors.Is(err, ErrUnsupported) {
+			return caps, err
+		}
+	}
+	caps.JSON = true
+	_, err := m.Read("timestamps")
+	switch {
+	case err == nil:
+		caps.Timestamps = true
+	case !errors.Is(err, ErrUnsupported):
+		return caps, err
+	}
+	return caps, nil
+}
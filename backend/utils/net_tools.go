@@ -0,0 +1,329 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lineWriter splits written bytes into lines for StreamCommand
+type lineWriter struct {
+	buf    []byte
+	onLine func(string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.onLine(strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// StreamCommand runs a command and calls onLine for every line of combined stdout/stderr
+// as it is produced. The process is killed when ctx is done; output pipes held open by
+// leftover children are closed a second later so the call never outlives ctx for long.
+func StreamCommand(ctx context.Context, onLine func(string), name string, arg ...string) error {
+	w := &lineWriter{onLine: onLine}
+	cmd := exec.CommandContext(ctx, name, arg...)
+	cmd.Stdout, cmd.Stderr = w, w
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	if len(w.buf) > 0 {
+		onLine(string(w.buf))
+	}
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// PingReply is one echo reply parsed from ping output
+type PingReply struct {
+	Seq   int     `json:"seq"`
+	TTL   int     `json:"ttl"`
+	RTTMs float64 `json:"rtt_ms"`
+}
+
+// PingResult is the structured form of iputils / busybox ping output
+type PingResult struct {
+	Replies     []PingReply `json:"replies"`
+	Transmitted int         `json:"transmitted"`
+	Received    int         `json:"received"`
+	LossPct     float64     `json:"loss_pct"`
+	MinMs       float64     `json:"min_ms"`
+	AvgMs       float64     `json:"avg_ms"`
+	MaxMs       float64     `json:"max_ms"`
+	MdevMs      float64     `json:"mdev_ms"`
+}
+
+var (
+	pingReplyRe   = regexp.MustCompile(`icmp_seq=(\d+)\s+ttl=(\d+)\s+time=([\d.]+)\s*ms`)
+	pingSummaryRe = regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received`)
+	pingLossRe    = regexp.MustCompile(`([\d.]+)% packet loss`)
+	pingRttRe     = regexp.MustCompile(`= ([\d.]+)/([\d.]+)/([\d.]+)(?:/([\d.]+))? ms`)
+
+	// Windows ping, English or Chinese locale
+	winPingReplyRe   = regexp.MustCompile(`(?:time|时间)[=<]([\d.]+)ms\s+TTL=(\d+)`)
+	winPingSummaryRe = regexp.MustCompile(`= (\d+)[，,]\s*\S+ = (\d+)[，,]\s*\S+ = \d+\s*[(（](\d+)%`)
+	winPingRttRe     = regexp.MustCompile(`= (\d+)ms[，,]\s*\S+ = (\d+)ms[，,]\s*\S+ = (\d+)ms`)
+)
+
+// PingCommand returns the ping invocation for the host OS: iputils style
+// "-c count -W 2" on Linux/macOS, "-n count -w 2000" on Windows
+func PingCommand(count int, target string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "ping", []string{"-n", strconv.Itoa(count), "-w", "2000", target}
+	}
+	return "ping", []string{"-c", strconv.Itoa(count), "-W", "2", target}
+}
+
+// TracerouteCommand returns a numeric traceroute invocation limited to maxHops
+// (tracert -d on Windows)
+func TracerouteCommand(maxHops int, target string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "tracert", []string{"-d", "-h", strconv.Itoa(maxHops), "-w", "2000", target}
+	}
+	return "traceroute", []string{"-m", strconv.Itoa(maxHops), "-n", target}
+}
+
+// ParsePing extracts per-packet replies and summary statistics from ping output:
+//
+//	64 bytes from 10.0.0.1: icmp_seq=1 ttl=64 time=0.045 ms
+//	4 packets transmitted, 4 received, 0% packet loss, time 3060ms
+//	rtt min/avg/max/mdev = 0.040/0.045/0.051/0.004 ms
+//
+// Windows output has no sequence numbers, replies are numbered in order:
+//
+//	Reply from 10.0.0.1: bytes=32 time<1ms TTL=64
+//	    Packets: Sent = 4, Received = 4, Lost = 0 (0% loss),
+//	    Minimum = 0ms, Maximum = 1ms, Average = 0ms
+func ParsePing(out string) PingResult {
+	res := PingResult{Replies: make([]PingReply, 0)}
+	for _, line := range strings.Split(out, "\n") {
+		if m := pingReplyRe.FindStringSubmatch(line); m != nil {
+			seq, _ := strconv.Atoi(m[1])
+			ttl, _ := strconv.Atoi(m[2])
+			rtt, _ := strconv.ParseFloat(m[3], 64)
+			res.Replies = append(res.Replies, PingReply{Seq: seq, TTL: ttl, RTTMs: rtt})
+			continue
+		}
+		if m := winPingReplyRe.FindStringSubmatch(line); m != nil {
+			rtt, _ := strconv.ParseFloat(m[1], 64)
+			ttl, _ := strconv.Atoi(m[2])
+			res.Replies = append(res.Replies, PingReply{Seq: len(res.Replies) + 1, TTL: ttl, RTTMs: rtt})
+			continue
+		}
+		if m := winPingSummaryRe.FindStringSubmatch(line); m != nil {
+			res.Transmitted, _ = strconv.Atoi(m[1])
+			res.Received, _ = strconv.Atoi(m[2])
+			res.LossPct, _ = strconv.ParseFloat(m[3], 64)
+			continue
+		}
+		if m := winPingRttRe.FindStringSubmatch(line); m != nil {
+			res.MinMs, _ = strconv.ParseFloat(m[1], 64)
+			res.MaxMs, _ = strconv.ParseFloat(m[2], 64)
+			res.AvgMs, _ = strconv.ParseFloat(m[3], 64)
+			continue
+		}
+		if m := pingSummaryRe.FindStringSubmatch(line); m != nil {
+			res.Transmitted, _ = strconv.Atoi(m[1])
+			res.Received, _ = strconv.Atoi(m[2])
+		}
+		if m := pingLossRe.FindStringSubmatch(line); m != nil {
+			res.LossPct, _ = strconv.ParseFloat(m[1], 64)
+		}
+		if m := pingRttRe.FindStringSubmatch(line); m != nil {
+			res.MinMs, _ = strconv.ParseFloat(m[1], 64)
+			res.AvgMs, _ = strconv.ParseFloat(m[2], 64)
+			res.MaxMs, _ = strconv.ParseFloat(m[3], 64)
+			if m[4] != "" {
+				res.MdevMs, _ = strconv.ParseFloat(m[4], 64)
+			}
+		}
+	}
+	return res
+}
+
+// TraceHop is one hop of a traceroute; Addr is empty and RTTs is empty when every probe timed out
+type TraceHop struct {
+	Hop   int       `json:"hop"`
+	Addr  string    `json:"addr"`
+	RTTMs []float64 `json:"rtt_ms"`
+	Lost  int       `json:"lost"` // probes answered with "*"
+}
+
+var (
+	traceHopRe  = regexp.MustCompile(`^\s*(\d+)\s+(.*)$`)
+	traceAddrRe = regexp.MustCompile(`^\d{1,3}(?:\.\d{1,3}){3}$|^[0-9a-fA-F:]+:[0-9a-fA-F:]*$`)
+)
+
+// ParseTraceroute extracts hops from numeric (-n) traceroute output, or tracert -d
+// output on Windows where RTTs come first and "<1 ms" means under a millisecond:
+//
+//	1  10.0.0.1  0.312 ms  0.290 ms  0.281 ms
+//	2  * * *
+//	3    <1 ms     1 ms    <1 ms  10.0.0.1
+func ParseTraceroute(out string) []TraceHop {
+	hops := make([]TraceHop, 0)
+	for _, line := range strings.Split(out, "\n") {
+		m := traceHopRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(m[1])
+		hop := TraceHop{Hop: n, RTTMs: make([]float64, 0)}
+		fields := strings.Fields(m[2])
+		for i := 0; i < len(fields); i++ {
+			f := fields[i]
+			switch {
+			case f == "*":
+				hop.Lost++
+			case traceAddrRe.MatchString(f):
+				if hop.Addr == "" {
+					hop.Addr = f
+				}
+			case i+1 < len(fields) && fields[i+1] == "ms":
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(f, "<"), 64); err == nil {
+					hop.RTTMs = append(hop.RTTMs, v)
+				}
+				i++
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// PortCheckResult is the outcome of a TCP connect test
+type PortCheckResult struct {
+	Target string  `json:"target"`
+	Open   bool    `json:"open"`
+	RTTMs  float64 `json:"rtt_ms,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// CheckTCPPort tries to open a TCP connection to host:port
+func CheckTCPPort(host string, port int, timeout time.Duration) PortCheckResult {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	res := PortCheckResult{Target: addr}
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	conn.Close()
+	res.Open = true
+	res.RTTMs = float64(time.Since(start).Microseconds()) / 1000
+	return res
+}
+
+// DNSResult holds the records found for a name
+type DNSResult struct {
+	Name  string   `json:"name"`
+	CNAME string   `json:"cname,omitempty"`
+	Addrs []string `json:"addrs"`
+	PTR   []string `json:"ptr,omitempty"` // reverse lookup when the target is an IP
+	Error string   `json:"error,omitempty"`
+}
+
+// LookupDNS resolves a host name, or does a reverse lookup for an IP address
+func LookupDNS(target string, timeout time.Duration) DNSResult {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	res := DNSResult{Name: target, Addrs: make([]string, 0)}
+	r := net.DefaultResolver
+	if net.ParseIP(target) != nil {
+		names, err := r.LookupAddr(ctx, target)
+		if err != nil {
+			res.Error = err.Error()
+		}
+		res.PTR = names
+		return res
+	}
+	if cname, err := r.LookupCNAME(ctx, target); err == nil && strings.TrimSuffix(cname, ".") != target {
+		res.CNAME = cname
+	}
+	addrs, err := r.LookupHost(ctx, target)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Addrs = addrs
+	return res
+}
+
+// Iperf3Result is the summary of one iperf3 run (iperf3 -J)
+type Iperf3Result struct {
+	Reverse     bool    `json:"reverse"` // -R: server sends, client receives
+	Seconds     float64 `json:"seconds"`
+	SentBps     float64 `json:"sent_bps"`
+	ReceivedBps float64 `json:"received_bps"`
+	Retransmits int     `json:"retransmits"`
+	JitterMs    float64 `json:"jitter_ms,omitempty"`    // UDP only
+	LostPct     float64 `json:"lost_percent,omitempty"` // UDP only
+}
+
+// ParseIperf3 extracts the end-of-test summary from iperf3 JSON output. An error reported
+// by iperf3 itself (e.g. "unable to connect to server") is returned as the error.
+func ParseIperf3(data []byte) (Iperf3Result, error) {
+	type sum struct {
+		Seconds       float64 `json:"seconds"`
+		BitsPerSecond float64 `json:"bits_per_second"`
+		Retransmits   int     `json:"retransmits"`
+		JitterMs      float64 `json:"jitter_ms"`
+		LostPercent   float64 `json:"lost_percent"`
+	}
+	var doc struct {
+		Start struct {
+			TestStart struct {
+				Reverse int `json:"reverse"`
+			} `json:"test_start"`
+		} `json:"start"`
+		End struct {
+			SumSent     *sum `json:"sum_sent"`
+			SumReceived *sum `json:"sum_received"`
+			Sum         *sum `json:"sum"` // UDP
+		} `json:"end"`
+		Error string `json:"error"`
+	}
+	var res Iperf3Result
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return res, fmt.Errorf("invalid iperf3 output: %v", err)
+	}
+	if doc.Error != "" {
+		return res, errors.New(doc.Error)
+	}
+	res.Reverse = doc.Start.TestStart.Reverse != 0
+	switch {
+	case doc.End.SumSent != nil && doc.End.SumReceived != nil:
+		res.Seconds = doc.End.SumReceived.Seconds
+		res.SentBps = doc.End.SumSent.BitsPerSecond
+		res.ReceivedBps = doc.End.SumReceived.BitsPerSecond
+		res.Retransmits = doc.End.SumSent.Retransmits
+	case doc.End.Sum != nil:
+		res.Seconds = doc.End.Sum.Seconds
+		res.SentBps = doc.End.Sum.BitsPerSecond
+		res.ReceivedBps = doc.End.Sum.BitsPerSecond * (100 - doc.End.Sum.LostPercent) / 100
+		res.JitterMs = doc.End.Sum.JitterMs
+		res.LostPct = doc.End.Sum.LostPercent
+	default:
+		return res, errors.New("iperf3 output has no summary")
+	}
+	return res, nil
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"errors"
+	"time"
+)
+
+// PacketCounter is one row of the "packetstats" command. A n2n 3.x supernode reports
+// forward / broadcast (tx_pkt), reg_super (rx_pkt and nak) and errors (tx_pkt);
+// fields a row does not carry are zero.
+type PacketCounter struct {
+	Type  string `json:"type"`
+	TxPkt uint64 `json:"tx_pkt"`
+	RxPkt uint64 `json:"rx_pkt"`
+	Nak   uint64 `json:"nak"`
+}
+
+// Timestamps is the reply of the "timestamps" command, zero times mean "never"
+type Timestamps struct {
+	StartTime    time.Time `json:"start_time"`
+	LastForward  time.Time `json:"last_fwd"`
+	LastRegSuper time.Time `json:"last_reg_super"`
+}
+
+// SupernodeStats combines packetstats and timestamps. Timestamps is nil when the
+// supernode does not implement the command.
+type SupernodeStats struct {
+	Counters   []PacketCounter `json:"counters"`
+	Timestamps *Timestamps     `json:"timestamps,omitempty"`
+}
+
+// Counter returns the row of the given type
+func (s SupernodeStats) Counter(typ string) (PacketCounter, bool) {
+	for _, c := range s.Counters {
+		if c.Type == typ {
+			return c, true
+		}
+	}
+	return PacketCounter{}, false
+}
+
+func rowUint(row MgmtRow, key string) uint64 {
+	if v, ok := row[key].(float64); ok && v > 0 {
+		return uint64(v)
+	}
+	return 0
+}
+
+func rowTime(row MgmtRow, key string) time.Time {
+	if v, ok := row[key].(float64); ok && v > 0 {
+		return time.Unix(int64(v), 0)
+	}
+	return time.Time{}
+}
+
+// ParsePacketStats converts raw packetstats rows, skipping rows without a type
+func ParsePacketStats(rows []MgmtRow) []PacketCounter {
+	res := make([]PacketCounter, 0, len(rows))
+	for _, row := range rows {
+		typ, ok := row["type"].(string)
+		if !ok || typ == "" {
+			continue
+		}
+		res = append(res, PacketCounter{Type: typ, TxPkt: rowUint(row, "tx_pkt"), RxPkt: rowUint(row, "rx_pkt"), Nak: rowUint(row, "nak")})
+	}
+	return res
+}
+
+// ParseTimestamps converts the timestamps reply
+func ParseTimestamps(rows []MgmtRow) Timestamps {
+	var ts Timestamps
+	for _, row := range rows {
+		if t := rowTime(row, "start_time"); !t.IsZero() {
+			ts.StartTime = t
+		}
+		if t := rowTime(row, "last_fwd"); !t.IsZero() {
+			ts.LastForward = t
+		}
+		if t := rowTime(row, "last_reg_super"); !t.IsZero() {
+			ts.LastRegSuper = t
+		}
+	}
+	return ts
+}
+
+// Timestamps reads the supernode start time and the times of the last forwarded
+// packet and last registration
+func (m *MgmtClient) Timestamps() (Timestamps, error) {
+	rows, err := m.Read("timestamps")
+	if err != nil {
+		return Timestamps{}, err
+	}
+	return ParseTimestamps(rows), nil
+}
+
+// Stats reads packetstats and, when supported, timestamps
+func (m *MgmtClient) Stats() (SupernodeStats, error) {
+	rows, err := m.PacketStats()
+	if err != nil {
+		return SupernodeStats{}, err
+	}
+	stats := SupernodeStats{Counters: ParsePacketStats(rows)}
+	ts, err := m.Timestamps()
+	switch {
+	case err == nil:
+		stats.Timestamps = &ts
+	case !errors.Is(err, ErrUnsupported):
+		return stats, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	stunMagicCookie       = 0x2112A442
+	stunBindingRequest    = 0x0001
+	stunBindingSuccess    = 0x0101
+	stunAttrMappedAddress = 0x0001
+	stunAttrXorMapped     = 0x0020
+)
+
+// StunPublicIP asks a STUN server (RFC 5389 binding request) for the address our
+// UDP packets arrive from, i.e. the public IP of this host or of the NAT in front of it.
+func StunPublicIP(server string, timeout time.Duration) (net.IP, error) {
+	conn, err := net.DialTimeout("udp", server, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	req := make([]byte, 20)
+	binary.BigEndian.PutUint16(req[0:], stunBindingRequest)
+	binary.BigEndian.PutUint32(req[4:], stunMagicCookie)
+	txID := req[8:20]
+	if _, err := rand.Read(txID); err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 1024)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		if n < 20 || binary.BigEndian.Uint16(buf[0:]) != stunBindingSuccess || string(buf[8:20]) != string(txID) {
+			continue // not a reply to our request
+		}
+		return parseStunAddress(buf[20:n], buf[4:8])
+	}
+}
+
+// parseStunAddress extracts the (XOR-)MAPPED-ADDRESS attribute from a binding response
+func parseStunAddress(attrs, cookie []byte) (net.IP, error) {
+	var mapped net.IP
+	for len(attrs) >= 4 {
+		typ := binary.BigEndian.Uint16(attrs[0:])
+		length := int(binary.BigEndian.Uint16(attrs[2:]))
+		if len(attrs) < 4+length {
+			break
+		}
+		val := attrs[4 : 4+length]
+		// only IPv4 (family 0x01) is useful as an edge -l address here
+		if (typ == stunAttrXorMapped || typ == stunAttrMappedAddress) && length >= 8 && val[1] == 0x01 {
+			ip := make(net.IP, 4)
+			copy(ip, val[4:8])
+			if typ == stunAttrXorMapped {
+				for i := range ip {
+					ip[i] ^= cookie[i]
+				}
+				return ip, nil
+			}
+			mapped = ip
+		}
+		attrs = attrs[4+(length+3)&^3:] // attributes are padded to 4 bytes
+	}
+	if mapped != nil {
+		return mapped, nil
+	}
+	return nil, errors.New("no IPv4 mapped address in STUN response")
+}
+
+// HTTPPublicIP fetches the public IP from a checker service that answers with the
+// caller's address as plain text (e.g. https://api.ipify.org)
+func HTTPPublicIP(url string, timeout time.Duration) (net.IP, error) {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return nil, fmt.Errorf("%s returned no IP address", url)
+	}
+	return ip, nil
+}
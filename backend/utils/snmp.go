@@ -0,0 +1,527 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A minimal read-only SNMP agent: v1 and v2c GetRequest, GetNextRequest and (v2c)
+// GetBulkRequest over UDP, answered from a snapshot of variables supplied by the caller.
+// SetRequest is always rejected; requests with a wrong community are silently dropped.
+
+// SNMP value types
+const (
+	SNMPInteger     byte = 0x02
+	SNMPOctetString byte = 0x04
+	SNMPCounter32   byte = 0x41
+	SNMPGauge32     byte = 0x42
+	SNMPTimeTicks   byte = 0x43
+	SNMPCounter64   byte = 0x46
+
+	snmpNull           byte = 0x05
+	snmpObjectID       byte = 0x06
+	snmpSequence       byte = 0x30
+	snmpNoSuchObject   byte = 0x80
+	snmpNoSuchInstance byte = 0x81
+	snmpEndOfMibView   byte = 0x82
+
+	snmpGetRequest     byte = 0xa0
+	snmpGetNextRequest byte = 0xa1
+	snmpGetResponse    byte = 0xa2
+	snmpSetRequest     byte = 0xa3
+	snmpGetBulkRequest byte = 0xa5
+
+	snmpVersion1  = 0
+	snmpVersion2c = 1
+
+	snmpErrTooBig      = 1
+	snmpErrNoSuchName  = 2
+	snmpErrNotWritable = 17
+
+	snmpMaxResponse        = 1472 // keep responses within one Ethernet frame
+	snmpMaxRepetitions     = 100
+	snmpMaxRequestVarBinds = 128
+)
+
+// OID is an SNMP object identifier
+type OID []uint32
+
+// ParseOID parses a dotted OID such as "1.3.6.1.4.1", a leading dot is allowed
+func ParseOID(s string) (OID, error) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("OID needs at least two components")
+	}
+	oid := make(OID, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID component %q", p)
+		}
+		oid = append(oid, uint32(n))
+	}
+	if oid[0] > 2 || (oid[0] < 2 && oid[1] >= 40) {
+		return nil, fmt.Errorf("invalid OID prefix")
+	}
+	return oid, nil
+}
+
+func (o OID) String() string {
+	parts := make([]string, len(o))
+	for i, n := range o {
+		parts[i] = strconv.FormatUint(uint64(n), 10)
+	}
+	return strings.Join(parts, ".")
+}
+
+// Append returns a new OID with the sub-identifiers appended
+func (o OID) Append(sub ...uint32) OID {
+	res := make(OID, 0, len(o)+len(sub))
+	return append(append(res, o...), sub...)
+}
+
+// Compare orders OIDs lexicographically, returning -1, 0 or 1
+func (o OID) Compare(p OID) int {
+	for i := 0; i < len(o) && i < len(p); i++ {
+		if o[i] != p[i] {
+			if o[i] < p[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(o) < len(p):
+		return -1
+	case len(o) > len(p):
+		return 1
+	}
+	return 0
+}
+
+// SNMPVar is one variable binding. Value is an int64 for SNMPInteger, a string for
+// SNMPOctetString and a uint64 for counters, gauges and time ticks.
+type SNMPVar struct {
+	OID   OID
+	Type  byte
+	Value interface{}
+}
+
+// SNMPAgent answers read-only SNMP requests. Community is consulted for every request
+// so it can change at runtime; Vars returns the current variables in any order.
+type SNMPAgent struct {
+	Community func() string
+	Vars      func() []SNMPVar
+}
+
+var errSNMPMalformed = errors.New("malformed SNMP message")
+
+// Serve answers requests on conn until it is closed
+func (a *SNMPAgent) Serve(conn net.PacketConn) error {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		if resp := a.Handle(buf[:n]); resp != nil {
+			conn.WriteTo(resp, addr)
+		}
+	}
+}
+
+// snmpRequest is a decoded request message
+type snmpRequest struct {
+	version   int64
+	community string
+	pduType   byte
+	requestID int64
+	// error-status / error-index, or non-repeaters / max-repetitions for GetBulk
+	field1, field2 int64
+	oids           []OID
+}
+
+// Handle decodes one request and returns the encoded response, or nil when the
+// request is malformed, uses an unsupported version or a wrong community
+func (a *SNMPAgent) Handle(packet []byte) []byte {
+	req, err := decodeSNMPRequest(packet)
+	if err != nil || (req.version != snmpVersion1 && req.version != snmpVersion2c) {
+		return nil
+	}
+	community := a.Community()
+	if community == "" || subtle.ConstantTimeCompare([]byte(req.community), []byte(community)) != 1 {
+		return nil
+	}
+	switch req.pduType {
+	case snmpGetRequest, snmpGetNextRequest, snmpSetRequest:
+	case snmpGetBulkRequest:
+		if req.version == snmpVersion1 {
+			return nil
+		}
+	default:
+		return nil
+	}
+	vars := a.Vars()
+	sort.Slice(vars, func(i, j int) bool { return vars[i].OID.Compare(vars[j].OID) < 0 })
+	v := snmpView{vars: vars, v1: req.version == snmpVersion1}
+
+	var (
+		results          []SNMPVar
+		errStatus, errIx int
+	)
+	switch req.pduType {
+	case snmpSetRequest:
+		results = echoVarBinds(req.oids)
+		errStatus, errIx = snmpErrNotWritable, 1
+		if v.v1 {
+			errStatus = snmpErrNoSuchName
+		}
+	case snmpGetRequest, snmpGetNextRequest:
+		for i, oid := range req.oids {
+			r := v.get(oid)
+			if req.pduType == snmpGetNextRequest {
+				r = v.next(oid)
+			}
+			if v.v1 && isSNMPException(r.Type) {
+				results, errStatus, errIx = echoVarBinds(req.oids), snmpErrNoSuchName, i+1
+				break
+			}
+			results = append(results, r)
+		}
+	case snmpGetBulkRequest:
+		results = v.bulk(req.oids, int(req.field1), int(req.field2))
+	}
+	resp := encodeSNMPResponse(req, errStatus, errIx, results)
+	if len(resp) > snmpMaxResponse && req.pduType != snmpGetBulkRequest {
+		resp = encodeSNMPResponse(req, snmpErrTooBig, 0, echoVarBinds(req.oids))
+	}
+	return resp
+}
+
+// snmpView looks up variables in a sorted snapshot. SNMPv1 cannot carry Counter64,
+// so those variables are invisible to v1 requests.
+type snmpView struct {
+	vars []SNMPVar
+	v1   bool
+}
+
+func (v snmpView) visible(s SNMPVar) bool {
+	return !v.v1 || s.Type != SNMPCounter64
+}
+
+func (v snmpView) get(oid OID) SNMPVar {
+	i := sort.Search(len(v.vars), func(i int) bool { return v.vars[i].OID.Compare(oid) >= 0 })
+	if i < len(v.vars) && v.vars[i].OID.Compare(oid) == 0 && v.visible(v.vars[i]) {
+		return v.vars[i]
+	}
+	return SNMPVar{OID: oid, Type: snmpNoSuchObject}
+}
+
+func (v snmpView) next(oid OID) SNMPVar {
+	i := sort.Search(len(v.vars), func(i int) bool { return v.vars[i].OID.Compare(oid) > 0 })
+	for ; i < len(v.vars); i++ {
+		if v.visible(v.vars[i]) {
+			return v.vars[i]
+		}
+	}
+	return SNMPVar{OID: oid, Type: snmpEndOfMibView}
+}
+
+// bulk implements GetBulk: the first nonRepeaters OIDs get one successor each, the
+// rest up to maxRepetitions successors, stopping before the response grows too large
+func (v snmpView) bulk(oids []OID, nonRepeaters, maxRepetitions int) []SNMPVar {
+	if nonRepeaters < 0 {
+		nonRepeaters = 0
+	}
+	if nonRepeaters > len(oids) {
+		nonRepeaters = len(oids)
+	}
+	if maxRepetitions < 0 {
+		maxRepetitions = 0
+	}
+	if maxRepetitions > snmpMaxRepetitions {
+		maxRepetitions = snmpMaxRepetitions
+	}
+	res := make([]SNMPVar, 0)
+	size := 0
+	add := func(s SNMPVar) bool {
+		n := len(encodeVarBind(s))
+		if size+n > snmpMaxResponse-100 && len(res) > 0 {
+			return false
+		}
+		size += n
+		res = append(res, s)
+		return true
+	}
+	for _, oid := range oids[:nonRepeaters] {
+		if !add(v.next(oid)) {
+			return res
+		}
+	}
+	cursor := append([]OID(nil), oids[nonRepeaters:]...)
+	for r := 0; r < maxRepetitions && len(cursor) > 0; r++ {
+		allEnd := true
+		for i, oid := range cursor {
+			s := v.next(oid)
+			if s.Type != snmpEndOfMibView {
+				allEnd = false
+			}
+			if !add(s) {
+				return res
+			}
+			cursor[i] = s.OID
+		}
+		if allEnd {
+			break
+		}
+	}
+	return res
+}
+
+func isSNMPException(t byte) bool {
+	return t == snmpNoSuchObject || t == snmpNoSuchInstance || t == snmpEndOfMibView
+}
+
+// echoVarBinds returns the requested OIDs with NULL values, used in error responses
+func echoVarBinds(oids []OID) []SNMPVar {
+	res := make([]SNMPVar, len(oids))
+	for i, oid := range oids {
+		res[i] = SNMPVar{OID: oid, Type: snmpNull}
+	}
+	return res
+}
+
+// --- BER decoding ---
+
+// berRead splits the first TLV off b
+func berRead(b []byte) (tag byte, val, rest []byte, err error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errSNMPMalformed
+	}
+	tag, l, off := b[0], int(b[1]), 2
+	if l&0x80 != 0 {
+		n := l & 0x7f
+		if n == 0 || n > 3 || len(b) < 2+n {
+			return 0, nil, nil, errSNMPMalformed
+		}
+		l = 0
+		for _, c := range b[2 : 2+n] {
+			l = l<<8 | int(c)
+		}
+		off += n
+	}
+	if len(b)-off < l {
+		return 0, nil, nil, errSNMPMalformed
+	}
+	return tag, b[off : off+l], b[off+l:], nil
+}
+
+// berExpect reads a TLV and checks its tag
+func berExpect(b []byte, want byte) (val, rest []byte, err error) {
+	tag, val, rest, err := berRead(b)
+	if err == nil && tag != want {
+		err = errSNMPMalformed
+	}
+	return val, rest, err
+}
+
+func berDecodeInt(b []byte) (int64, error) {
+	if len(b) == 0 || len(b) > 8 {
+		return 0, errSNMPMalformed
+	}
+	v := int64(int8(b[0]))
+	for _, c := range b[1:] {
+		v = v<<8 | int64(c)
+	}
+	return v, nil
+}
+
+func berReadInt(b []byte) (int64, []byte, error) {
+	val, rest, err := berExpect(b, SNMPInteger)
+	if err != nil {
+		return 0, nil, err
+	}
+	v, err := berDecodeInt(val)
+	return v, rest, err
+}
+
+func berDecodeOID(b []byte) (OID, error) {
+	if len(b) == 0 {
+		return nil, errSNMPMalformed
+	}
+	oid := OID{}
+	var sub uint64
+	for i, c := range b {
+		sub = sub<<7 | uint64(c&0x7f)
+		if sub > 0xffffffff {
+			return nil, errSNMPMalformed
+		}
+		if c&0x80 != 0 {
+			if i == len(b)-1 {
+				return nil, errSNMPMalformed
+			}
+			continue
+		}
+		if len(oid) == 0 {
+			first := sub / 40
+			if first > 2 {
+				first = 2
+			}
+			oid = append(oid, uint32(first), uint32(sub-first*40))
+		} else {
+			oid = append(oid, uint32(sub))
+		}
+		sub = 0
+	}
+	return oid, nil
+}
+
+func decodeSNMPRequest(packet []byte) (snmpRequest, error) {
+	var req snmpRequest
+	msg, _, err := berExpect(packet, snmpSequence)
+	if err != nil {
+		return req, err
+	}
+	if req.version, msg, err = berReadInt(msg); err != nil {
+		return req, err
+	}
+	community, msg, err := berExpect(msg, SNMPOctetString)
+	if err != nil {
+		return req, err
+	}
+	req.community = string(community)
+	tag, pdu, _, err := berRead(msg)
+	if err != nil {
+		return req, err
+	}
+	req.pduType = tag
+	if req.requestID, pdu, err = berReadInt(pdu); err != nil {
+		return req, err
+	}
+	if req.field1, pdu, err = berReadInt(pdu); err != nil {
+		return req, err
+	}
+	if req.field2, pdu, err = berReadInt(pdu); err != nil {
+		return req, err
+	}
+	list, _, err := berExpect(pdu, snmpSequence)
+	if err != nil {
+		return req, err
+	}
+	for len(list) > 0 {
+		var vb []byte
+		if vb, list, err = berExpect(list, snmpSequence); err != nil {
+			return req, err
+		}
+		raw, _, err := berExpect(vb, snmpObjectID)
+		if err != nil {
+			return req, err
+		}
+		oid, err := berDecodeOID(raw)
+		if err != nil {
+			return req, err
+		}
+		req.oids = append(req.oids, oid)
+		if len(req.oids) > snmpMaxRequestVarBinds {
+			return req, errSNMPMalformed
+		}
+	}
+	return req, nil
+}
+
+// --- BER encoding ---
+
+func berTLV(tag byte, val []byte) []byte {
+	l := len(val)
+	var hdr []byte
+	switch {
+	case l < 0x80:
+		hdr = []byte{tag, byte(l)}
+	case l < 0x100:
+		hdr = []byte{tag, 0x81, byte(l)}
+	default:
+		hdr = []byte{tag, 0x82, byte(l >> 8), byte(l)}
+	}
+	return append(hdr, val...)
+}
+
+func berInt(v int64) []byte {
+	b := []byte{byte(v)}
+	for v > 127 || v < -128 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return b
+}
+
+func berUint(v uint64) []byte {
+	b := []byte{byte(v)}
+	for v > 0xff {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
+
+func berOID(o OID) []byte {
+	if len(o) < 2 {
+		return []byte{0}
+	}
+	res := make([]byte, 0, len(o)+4)
+	subs := append([]uint32{o[0]*40 + o[1]}, o[2:]...)
+	for _, n := range subs {
+		chunk := []byte{byte(n & 0x7f)}
+		for n >>= 7; n > 0; n >>= 7 {
+			chunk = append([]byte{byte(n&0x7f) | 0x80}, chunk...)
+		}
+		res = append(res, chunk...)
+	}
+	return res
+}
+
+func encodeSNMPValue(s SNMPVar) []byte {
+	switch s.Type {
+	case SNMPInteger:
+		v, _ := s.Value.(int64)
+		return berTLV(s.Type, berInt(v))
+	case SNMPOctetString:
+		v, _ := s.Value.(string)
+		return berTLV(s.Type, []byte(v))
+	case SNMPCounter32, SNMPGauge32, SNMPTimeTicks:
+		v, _ := s.Value.(uint64)
+		return berTLV(s.Type, berUint(v&0xffffffff))
+	case SNMPCounter64:
+		v, _ := s.Value.(uint64)
+		return berTLV(s.Type, berUint(v))
+	case snmpNoSuchObject, snmpNoSuchInstance, snmpEndOfMibView:
+		return berTLV(s.Type, nil)
+	}
+	return berTLV(snmpNull, nil)
+}
+
+func encodeVarBind(s SNMPVar) []byte {
+	return berTLV(snmpSequence, append(berTLV(snmpObjectID, berOID(s.OID)), encodeSNMPValue(s)...))
+}
+
+func encodeSNMPResponse(req snmpRequest, errStatus, errIndex int, vars []SNMPVar) []byte {
+	var list []byte
+	for _, s := range vars {
+		list = append(list, encodeVarBind(s)...)
+	}
+	pdu := berTLV(SNMPInteger, berInt(req.requestID))
+	pdu = append(pdu, berTLV(SNMPInteger, berInt(int64(errStatus)))...)
+	pdu = append(pdu, berTLV(SNMPInteger, berInt(int64(errIndex)))...)
+	pdu = append(pdu, berTLV(snmpSequence, list)...)
+	msg := berTLV(SNMPInteger, berInt(req.version))
+	msg = append(msg, berTLV(SNMPOctetString, []byte(req.community))...)
+	msg = append(msg, berTLV(snmpGetResponse, pdu)...)
+	return berTLV(snmpSequence, msg)
+}
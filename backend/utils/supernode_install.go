@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// N2NSourceRepo is cloned when n2n is built from source
+const N2NSourceRepo = "https://github.com/ntop/n2n.git"
+
+// Distro identifies the Linux distribution from /etc/os-release
+type Distro struct {
+	ID      string   `json:"id"`
+	Like    []string `json:"like,omitempty"`
+	Version string   `json:"version,omitempty"`
+	Name    string   `json:"name"`
+}
+
+// ParseOSRelease reads the ID, ID_LIKE, VERSION_ID and PRETTY_NAME fields of an os-release file
+func ParseOSRelease(data string) Distro {
+	var d Distro
+	for _, line := range strings.Split(data, "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		v = strings.Trim(v, `"'`)
+		switch k {
+		case "ID":
+			d.ID = strings.ToLower(v)
+		case "ID_LIKE":
+			d.Like = strings.Fields(strings.ToLower(v))
+		case "VERSION_ID":
+			d.Version = v
+		case "PRETTY_NAME":
+			d.Name = v
+		}
+	}
+	if d.Name == "" {
+		d.Name = strings.TrimSpace(d.ID + " " + d.Version)
+	}
+	return d
+}
+
+// DetectDistro reads /etc/os-release, falling back to /usr/lib/os-release
+func DetectDistro() (Distro, error) {
+	for _, p := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		if data, err := os.ReadFile(p); err == nil {
+			d := ParseOSRelease(string(data))
+			if d.ID == "" {
+				return d, fmt.Errorf("%s has no ID", p)
+			}
+			return d, nil
+		}
+	}
+	return Distro{}, fmt.Errorf("cannot identify the distribution: no os-release file")
+}
+
+// Is reports whether the distribution is, or is derived from, one of ids
+func (d Distro) Is(ids ...string) bool {
+	for _, id := range ids {
+		if d.ID == id {
+			return true
+		}
+		for _, like := range d.Like {
+			if like == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PackageManager returns apt, dnf, yum, apk or pacman, or "" for unknown distributions
+func (d Distro) PackageManager() string {
+	switch {
+	case d.Is("debian", "ubuntu"):
+		return "apt"
+	case d.Is("fedora"):
+		return "dnf"
+	case d.Is("rhel", "centos"):
+		if major, _, _ := strings.Cut(d.Version, "."); major == "7" {
+			return "yum"
+		}
+		return "dnf"
+	case d.Is("alpine"):
+		return "apk"
+	case d.Is("arch"):
+		return "pacman"
+	}
+	return ""
+}
+
+// InstallCommand returns the non-interactive command that installs pkgs with pm
+func InstallCommand(pm string, pkgs ...string) (string, []string) {
+	switch pm {
+	case "apt":
+		return "env", append([]string{"DEBIAN_FRONTEND=noninteractive", "apt-get", "install", "-y"}, pkgs...)
+	case "apk":
+		return "apk", append([]string{"add", "--no-cache"}, pkgs...)
+	case "pacman":
+		return "pacman", append([]string{"-S", "--noconfirm", "--needed"}, pkgs...)
+	}
+	return pm, append([]string{"install", "-y"}, pkgs...)
+}
+
+// BuildDependencies lists the packages needed to build n2n from source with pm
+func BuildDependencies(pm string) []string {
+	switch pm {
+	case "apt":
+		return []string{"build-essential", "autoconf", "automake", "libtool", "pkg-config", "git"}
+	case "apk":
+		return []string{"build-base", "autoconf", "automake", "libtool", "pkgconf", "linux-headers", "git"}
+	case "pacman":
+		return []string{"base-devel", "git"}
+	}
+	return []string{"gcc", "make", "autoconf", "automake", "libtool", "pkgconfig", "git"}
+}
+
+// SupernodeUnitFile renders a systemd service that runs the supernode in the
+// foreground with the given config file
+func SupernodeUnitFile(binary, configPath string) string {
+	return fmt.Sprintf(`[Unit]
+Description=n2n supernode
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+ExecStart=%s %s
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`, binary, configPath)
+}
@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Syslog severities (RFC 5424 section 6.2.1)
+const (
+	SyslogEmergency = iota
+	SyslogAlert
+	SyslogCritical
+	SyslogError
+	SyslogWarning
+	SyslogNotice
+	SyslogInfo
+	SyslogDebug
+)
+
+const (
+	syslogMaxUDPMessage  = 2048 // RFC 5426: receivers SHOULD accept 2048 octets
+	defaultSyslogTimeout = 5 * time.Second
+)
+
+// SyslogFacilities maps facility names to their codes
+var SyslogFacilities = map[string]int{
+	"user": 1, "daemon": 3, "auth": 4, "authpriv": 10,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// SyslogConfig describes a remote syslog server. Network is udp, tcp or tls; TCP and
+// TLS use octet-counting framing (RFC 6587 / RFC 5425).
+type SyslogConfig struct {
+	Network  string
+	Address  string
+	Facility int
+	Hostname string
+	AppName  string
+	Timeout  time.Duration
+}
+
+// SyslogMessage is one RFC 5424 message. Data becomes a single structured data
+// element named SDID.
+type SyslogMessage struct {
+	Severity int
+	Time     time.Time
+	MsgID    string
+	SDID     string
+	Data     map[string]string
+	Message  string
+}
+
+// syslogHeaderField replaces characters not allowed in header fields and applies the
+// length limit, an empty field becomes the nil value "-"
+func syslogHeaderField(s string, max int) string {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s) && len(b) < max; i++ {
+		if s[i] >= 33 && s[i] <= 126 {
+			b = append(b, s[i])
+		} else {
+			b = append(b, '_')
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
+
+var sdValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// FormatRFC5424 renders a message as
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ID key="value" ...] MSG
+func FormatRFC5424(cfg SyslogConfig, procID int, m SyslogMessage) []byte {
+	t := m.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d %s ", cfg.Facility*8+m.Severity,
+		t.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(cfg.Hostname, 255), syslogHeaderField(cfg.AppName, 48), procID, syslogHeaderField(m.MsgID, 32))
+	if m.SDID == "" || len(m.Data) == 0 {
+		b.WriteString("-")
+	} else {
+		keys := make([]string, 0, len(m.Data))
+		for k := range m.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString("[" + syslogHeaderField(m.SDID, 32))
+		for _, k := range keys {
+			name := strings.Map(func(r rune) rune {
+				if r == '=' || r == ']' || r == '"' {
+					return '_'
+				}
+				return r
+			}, syslogHeaderField(k, 32))
+			fmt.Fprintf(&b, ` %s="%s"`, name, sdValueEscaper.Replace(m.Data[k]))
+		}
+		b.WriteString("]")
+	}
+	if m.Message != "" {
+		b.WriteString(" " + m.Message)
+	}
+	return []byte(b.String())
+}
+
+// SyslogClient sends messages over a single connection, reconnecting after errors.
+// It is safe for concurrent use.
+type SyslogClient struct {
+	cfg  SyslogConfig
+	pid  int
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogClient creates a client, the connection is opened on the first Send
+func NewSyslogClient(cfg SyslogConfig, procID int) *SyslogClient {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultSyslogTimeout
+	}
+	return &SyslogClient{cfg: cfg, pid: procID}
+}
+
+func (c *SyslogClient) dial() (net.Conn, error) {
+	switch c.cfg.Network {
+	case "udp", "tcp":
+		return net.DialTimeout(c.cfg.Network, c.cfg.Address, c.cfg.Timeout)
+	case "tls":
+		host, _, err := net.SplitHostPort(c.cfg.Address)
+		if err != nil {
+			return nil, err
+		}
+		return tls.DialWithDialer(&net.Dialer{Timeout: c.cfg.Timeout}, "tcp", c.cfg.Address, &tls.Config{ServerName: host})
+	}
+	return nil, fmt.Errorf("unsupported syslog network %q", c.cfg.Network)
+}
+
+// frame applies the transport framing: UDP carries one message per datagram, TCP and
+// TLS prefix the message with its length
+func (c *SyslogClient) frame(msg []byte) []byte {
+	if c.cfg.Network == "udp" {
+		if len(msg) > syslogMaxUDPMessage {
+			msg = msg[:syslogMaxUDPMessage]
+			for len(msg) > 0 && !utf8.Valid(msg) {
+				msg = msg[:len(msg)-1]
+			}
+		}
+		return msg
+	}
+	return append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+}
+
+// Send delivers a message, retrying once on a fresh connection when the existing one
+// has been closed by the server
+func (c *SyslogClient) Send(m SyslogMessage) error {
+	data := c.frame(FormatRFC5424(c.cfg, c.pid, m))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.conn == nil {
+			if c.conn, err = c.dial(); err != nil {
+				c.conn = nil
+				return err
+			}
+		}
+		c.conn.SetWriteDeadline(time.Now().Add(c.cfg.Timeout))
+		if _, err = c.conn.Write(data); err == nil {
+			return nil
+		}
+		c.conn.Close()
+		c.conn = nil
+	}
+	return err
+}
+
+// Close closes the connection
+func (c *SyslogClient) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
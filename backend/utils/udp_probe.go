@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ProbeMagic prefixes every echo probe packet; responders must ignore anything else
+const ProbeMagic = "N2N-PROBE"
+
+type ProbeResult struct {
+	Target   string    `json:"target"`
+	Sent     int       `json:"sent"`
+	Received int       `json:"received"`
+	LossPct  float64   `json:"loss_pct"`
+	MinMs    float64   `json:"min_ms"`
+	AvgMs    float64   `json:"avg_ms"`
+	MaxMs    float64   `json:"max_ms"`
+	Error    string    `json:"error,omitempty"`
+	ProbedAt time.Time `json:"probed_at"`
+}
+
+// ProbeUDPEcho sends count echo requests to addr and measures round-trip times
+func ProbeUDPEcho(addr string, count int, timeout time.Duration) ProbeResult {
+	res := ProbeResult{Target: addr, ProbedAt: time.Now()}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer conn.Close()
+
+	var total float64
+	buf := make([]byte, 128)
+	for seq := 0; seq < count; seq++ {
+		payload := []byte(fmt.Sprintf("%s %d %d", ProbeMagic, seq, time.Now().UnixNano()))
+		start := time.Now()
+		if _, err := conn.Write(payload); err != nil {
+			res.Error = err.Error()
+			break
+		}
+		res.Sent++
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				break
+			}
+			// Ignore late replies to earlier sequence numbers
+			if !bytes.Equal(buf[:n], payload) {
+				continue
+			}
+			rtt := float64(time.Since(start).Microseconds()) / 1000
+			if res.Received == 0 || rtt < res.MinMs {
+				res.MinMs = rtt
+			}
+			if rtt > res.MaxMs {
+				res.MaxMs = rtt
+			}
+			total += rtt
+			res.Received++
+			break
+		}
+	}
+	if res.Received > 0 {
+		res.AvgMs = total / float64(res.Received)
+	}
+	if res.Sent > 0 {
+		res.LossPct = float64(res.Sent-res.Received) * 100 / float64(res.Sent)
+	}
+	if res.Received == 0 && res.Error == "" {
+		res.Error = "no echo reply (responder not installed or blocked)"
+	}
+	return res
+}
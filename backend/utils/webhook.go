@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// PostJSON posts a JSON payload and returns the response body, failing on non-2xx status
+func PostJSON(target string, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := webhookClient.Post(target, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return body, fmt.Errorf("http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// SendTelegram sends a text message through the Telegram Bot API
+func SendTelegram(botToken, chatID, text string) error {
+	if botToken == "" || chatID == "" {
+		return fmt.Errorf("telegram bot token and chat id are required")
+	}
+	target := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", botToken)
+	body, err := PostJSON(target, map[string]interface{}{"chat_id": chatID, "text": text})
+	if err != nil {
+		return err
+	}
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(body, &result); err == nil && !result.OK {
+		return fmt.Errorf("telegram: %s", result.Description)
+	}
+	return nil
+}
+
+// SendDingTalk sends a text message to a DingTalk robot webhook, signing it when a secret is set
+func SendDingTalk(webhook, secret, text string) error {
+	if webhook == "" {
+		return fmt.Errorf("dingtalk webhook is required")
+	}
+	target := webhook
+	if secret != "" {
+		ts := fmt.Sprintf("%d", time.Now().UnixMilli())
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(ts + "\n" + secret))
+		sign := url.QueryEscape(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target = fmt.Sprintf("%s%stimestamp=%s&sign=%s", target, sep, ts, sign)
+	}
+	payload := map[string]interface{}{"msgtype": "text", "text": map[string]string{"content": text}}
+	body, err := PostJSON(target, payload)
+	if err != nil {
+		return err
+	}
+	return robotError("dingtalk", body)
+}
+
+// SendWeCom sends a text message to a WeCom (企业微信) group robot webhook
+func SendWeCom(webhook, text string) error {
+	if webhook == "" {
+		return fmt.Errorf("wecom webhook is required")
+	}
+	payload := map[string]interface{}{"msgtype": "text", "text": map[string]string{"content": text}}
+	body, err := PostJSON(webhook, payload)
+	if err != nil {
+		return err
+	}
+	return robotError("wecom", body)
+}
+
+// robotError decodes the errcode/errmsg envelope shared by DingTalk and WeCom robots
+func robotError(provider string, body []byte) error {
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(body, &result); err == nil && result.ErrCode != 0 {
+		return fmt.Errorf("%s: %d %s", provider, result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
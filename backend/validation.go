@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 以下校验函数同时供 createNode 与 /api/validate 使用，保证规则一致
+
+func validateNodeName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return newAPIError(ErrNodeNameRequired, "Node name is required")
+	}
+	// 名称会写入配置文件与防火墙脚本的注释，不允许换行等控制字符
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return newAPIError(ErrInvalidRequest, "Node name must not contain control characters")
+	}
+	return nil
+}
+
+func lookupCommunity(name string) (models.Community, error) {
+	var comm models.Community
+	if err := db.Where("name = ?", name).First(&comm).Error; err != nil {
+		return comm, newAPIError(ErrCommunityNotFound, "Community not found")
+	}
+	return comm, nil
+}
+
+func validateMacAddress(mac string) error {
+	if _, err := utils.ParseMAC(mac); err != nil {
+		return newAPIError(ErrInvalidMac, "Invalid MAC address format")
+	}
+	return nil
+}
+
+// validateNodeIP 校验 IP 格式以及是否位于社区网段内
+func validateNodeIP(ip string, comm models.Community) error {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return newAPIError(ErrInvalidIP, "Invalid IP address format")
+	}
+	if comm.Range != "" {
+		if _, ipnet, err := net.ParseCIDR(comm.Range); err == nil && !ipnet.Contains(parsed) {
+			return newAPIError(ErrNodeIPOutOfRange, "IP address not in community range")
+		}
+	}
+	return nil
+}
+
+// validateEncryption 校验 edge 加密算法，空值按 AES 处理
+func validateEncryption(enc string) error {
+	switch enc {
+	case "", "AES", "Twofish", "ChaCha20", "Speck":
+		return nil
+	}
+	return newAPIError(ErrInvalidRequest, "Unsupported encryption")
+}
+
+// tapDeviceName 合法的 TAP 设备名：Linux 接口名最长 15 个字符
+var tapDeviceName = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,15}$`)
+
+// validateEdgeOptions 校验节点级的 MTU、TAP 设备名和注册包 TTL，0 / 空值表示使用默认
+func validateEdgeOptions(mtu int, tap string, ttl int) error {
+	if mtu != 0 && (mtu < 500 || mtu > 9000) {
+		return newAPIError(ErrInvalidRequest, "MTU must be between 500 and 9000")
+	}
+	if tap != "" && !tapDeviceName.MatchString(tap) {
+		return newAPIError(ErrInvalidRequest, "Invalid TAP device name")
+	}
+	if ttl < 0 || ttl > 255 {
+		return newAPIError(ErrInvalidRequest, "TTL must be between 1 and 255")
+	}
+	return nil
+}
+
+func validateCIDR(cidr string) error {
+	if _, _, err := net.ParseCIDR(cidr); err != nil {
+		return newAPIError(ErrInvalidCIDR, "Invalid CIDR format")
+	}
+	return nil
+}
+
+// validateRoute 校验路由网段与网关：网段需写成网络地址（192.168.1.0/24 而不是 192.168.1.5/24），
+// 指定社区时网关必须是社区网段内的 VPN 地址，且路由网段不能与社区网段重叠
+func validateRoute(routeNet, routeGw string, comm models.Community) error {
+	ip, ipnet, err := net.ParseCIDR(routeNet)
+	if err != nil {
+		return newAPIError(ErrInvalidRoute, "Invalid route network format")
+	}
+	if !ip.Equal(ipnet.IP) {
+		return newAPIErrorf(ErrInvalidRoute, "Route network must be a network address, e.g. %s", ipnet)
+	}
+	gw := net.ParseIP(routeGw)
+	if gw == nil {
+		return newAPIError(ErrInvalidRoute, "Invalid route gateway format")
+	}
+	if _, commNet, err := net.ParseCIDR(comm.Range); err == nil {
+		if !commNet.Contains(gw) {
+			return newAPIError(ErrInvalidRoute, "Route gateway must be a VPN address in the community")
+		}
+		if cidrsOverlap(ipnet, commNet) {
+			return newAPIError(ErrInvalidRoute, "Route network overlaps the community range")
+		}
+	}
+	return nil
+}
+
+// validateField 供前端边输入边校验
+func validateField(c *gin.Context) {
+	var p struct {
+		Field     string `json:"field"`
+		Value     string `json:"value"`
+		Community string `json:"community"` // field=ip 时必填
+		Gateway   string `json:"gateway"`   // field=route 时必填，可同时指定 community
+	}
+	if !bindJSON(c, &p) {
+		return
+	}
+
+	var err error
+	switch p.Field {
+	case "node_name":
+		err = validateNodeName(p.Value)
+	case "mac":
+		err = validateMacAddress(p.Value)
+	case "ip":
+		var comm models.Community
+		if comm, err = scopedCommunity(c, p.Community); err == nil {
+			err = validateNodeIP(p.Value, comm)
+		}
+	case "cidr":
+		err = validateCIDR(p.Value)
+	case "route":
+		// 指定社区时同时校验网关是否在社区网段内
+		var comm models.Community
+		if p.Community != "" {
+			comm, err = scopedCommunity(c, p.Community)
+		}
+		if err == nil {
+			err = validateRoute(p.Value, p.Gateway, comm)
+		}
+	case "community":
+		_, err = scopedCommunity(c, p.Value)
+	case "extra_args":
+		_, err = normalizeEdgeFlags(p.Value)
+	default:
+		respondError(c, 400, ErrInvalidRequest, "Unknown field")
+		return
+	}
+
+	if err != nil {
+		var ae *apiError
+		code := ErrInvalidRequest
+		if errors.As(err, &ae) {
+			code = ae.Code
+		}
+		c.JSON(200, gin.H{"field": p.Field, "valid": false, "error": localizeErr(c, err), "code": code})
+		return
+	}
+	res := gin.H{"field": p.Field, "valid": true}
+	// 网段重叠在这里只作提示，保存时才会拒绝
+	if _, ipnet, err := net.ParseCIDR(p.Value); err == nil && (p.Field == "cidr" || p.Field == "route") {
+		var overlaps []subnetOverlap
+		if p.Field == "cidr" {
+			overlaps = findSubnetOverlaps(ipnet, "", "", 0, 0)
+		} else {
+			overlaps = findSubnetOverlaps(ipnet, p.Community, net.ParseIP(p.Gateway).String(), 0, 0)
+		}
+		if len(overlaps) > 0 {
+			res["overlaps"] = overlaps
+			res["warning"] = tr(c, "Subnet overlaps existing community ranges or node routes")
+		}
+	}
+	c.JSON(200, res)
+}
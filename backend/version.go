@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 版本信息与更新检查：/api/version 返回构建信息（版本、提交、构建时间、兼容的 n2n 版本）和前端资源版本，
+// 前端定期请求并与自身加载时的 frontend 对比，面板升级后提示刷新页面。
+// 开启 update_check_enabled 时每天查询一次 update_check_url（默认 GitHub Releases）的最新版本，
+// 有新版本时通知管理员一次并在 /api/version 中返回；离线环境关闭该设置即可，不会发出任何请求。
+
+// 构建时通过 -ldflags "-X main.GitCommit=... -X main.BuildDate=..." 写入，见 build.sh
+var (
+	GitCommit = ""
+	BuildDate = ""
+)
+
+// 支持的 n2n 版本范围：2.8 起的文本管理端口，3.x 的 JSON 管理端口与用户认证
+const (
+	n2nMinVersion = "2.8"
+	n2nMaxVersion = "3.x"
+)
+
+const (
+	defaultUpdateCheckURL = "https://api.github.com/repos/27260102/n2n-admin/releases/latest"
+	updateCheckInterval   = 24 * time.Hour
+	updateCheckTimeout    = 15 * time.Second
+	updateAlertType       = "update_available"
+)
+
+// updateStatus 最近一次更新检查的结果
+type updateStatus struct {
+	Enabled   bool       `json:"enabled"`
+	Latest    string     `json:"latest,omitempty"`
+	Available bool       `json:"available"`
+	URL       string     `json:"url,omitempty"` // 发布页面
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+var (
+	lastUpdateCheck updateStatus
+	updateMutex     sync.Mutex
+
+	frontendVersionOnce sync.Once
+	frontendVersion     string
+)
+
+// assetVersion 内嵌前端 index.html 的哈希。index.html 引用带内容哈希的资源文件，前端每次构建后都会变化
+func assetVersion() string {
+	frontendVersionOnce.Do(func() {
+		if data, err := fs.ReadFile(content, "dist/index.html"); err == nil {
+			sum := sha256.Sum256(data)
+			frontendVersion = hex.EncodeToString(sum[:6])
+		}
+	})
+	return frontendVersion
+}
+
+// compareVersions 比较 v1.2.3 形式的版本号，忽略前缀 v 和 -rc1 等后缀，缺少的部分视为 0
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.SplitN(strings.TrimPrefix(strings.TrimSpace(a), "v"), "-", 2)[0], ".")
+	pb := strings.Split(strings.SplitN(strings.TrimPrefix(strings.TrimSpace(b), "v"), "-", 2)[0], ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			y, _ = strconv.Atoi(pb[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// releaseInfo GitHub Releases 接口返回的发布信息
+type releaseInfo struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// assetURL 按文件名查找发布文件的下载地址
+func (r releaseInfo) assetURL(name string) string {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL
+		}
+	}
+	return ""
+}
+
+// fetchLatestRelease 查询最新发布版本
+func fetchLatestRelease(ctx context.Context, url string) (releaseInfo, error) {
+	var release releaseInfo
+	ctx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return release, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "n2n-admin/"+Version)
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	recordDependency("update_check", time.Since(start), err)
+	if err != nil {
+		return release, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return release, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&release); err != nil {
+		return release, err
+	}
+	if release.TagName == "" {
+		return release, fmt.Errorf("response has no tag_name")
+	}
+	return release, nil
+}
+
+// checkForUpdate 查询最新版本并更新缓存的结果，发现新版本时通知管理员（每个版本一次）
+func checkForUpdate(ctx context.Context) updateStatus {
+	now := time.Now()
+	st := updateStatus{Enabled: true, CheckedAt: &now}
+	release, err := fetchLatestRelease(ctx, getSettingValue("update_check_url", defaultUpdateCheckURL))
+	if err != nil {
+		st.Error = err.Error()
+		log.Printf("Update check failed: %v", err)
+	} else {
+		st.Latest, st.URL, st.Available = release.TagName, release.HTMLURL, compareVersions(release.TagName, Version) > 0
+	}
+
+	updateMutex.Lock()
+	prev := lastUpdateCheck
+	if err != nil && prev.Latest != "" {
+		// 查询失败时保留上次得到的版本信息
+		st.Latest, st.URL, st.Available = prev.Latest, prev.URL, prev.Available
+	}
+	lastUpdateCheck = st
+	updateMutex.Unlock()
+
+	if st.Available && st.Latest != prev.Latest {
+		dispatchAlert(Alert{Type: updateAlertType, Level: "info", AdminOnly: true, Title: "n2n-admin 有新版本: " + st.Latest,
+			Message: fmt.Sprintf("当前版本 %s，最新版本 %s，发布说明见 %s", Version, st.Latest, st.URL)})
+	}
+	return st
+}
+
+// currentUpdateStatus 缓存的更新检查结果，关闭检查时只返回 enabled=false
+func currentUpdateStatus() updateStatus {
+	if getSettingValue("update_check_enabled", "true") != "true" {
+		return updateStatus{}
+	}
+	updateMutex.Lock()
+	defer updateMutex.Unlock()
+	st := lastUpdateCheck
+	st.Enabled = true
+	return st
+}
+
+func getVersion(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"version":    Version,
+		"commit":     GitCommit,
+		"build_date": BuildDate,
+		"go_version": runtime.Version(),
+		"platform":   runtime.GOOS + "/" + runtime.GOARCH,
+		"frontend":   assetVersion(),
+		"n2n":        gin.H{"min": n2nMinVersion, "max": n2nMaxVersion},
+		"update":     currentUpdateStatus(),
+	})
+}
+
+// checkUpdateNow 管理员手动触发一次更新检查
+func checkUpdateNow(c *gin.Context) {
+	if getSettingValue("update_check_enabled", "true") != "true" {
+		respondError(c, 409, ErrUpdateCheckDisabled, "Update check is disabled")
+		return
+	}
+	c.JSON(200, checkForUpdate(c.Request.Context()))
+}
+
+// startUpdateChecker 启动后稍等片刻检查一次，之后每天检查
+func startUpdateChecker() {
+	time.Sleep(time.Minute)
+	for {
+		if getSettingValue("update_check_enabled", "true") == "true" {
+			checkForUpdate(context.Background())
+		}
+		time.Sleep(updateCheckInterval)
+	}
+}
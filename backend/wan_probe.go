@@ -0,0 +1,90 @@
+package main
+
+import (
+	"n2n_ui/backend/models"
+	"n2n_ui/backend/utils"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	wanProbeInterval = 60 * time.Second
+	wanProbeCount    = 4
+	wanProbeTimeout  = 1 * time.Second
+)
+
+var (
+	wanProbeResults = make(map[string]utils.ProbeResult) // MAC -> 最近一次探测结果
+	wanProbeMutex   sync.Mutex
+)
+
+// wanProbeTarget 根据 edge 的公网地址和回显端口设置得到探测目标
+func wanProbeTarget(info utils.EdgeInfo) string {
+	host := strings.Split(info.External, ":")[0]
+	if host == "" {
+		return ""
+	}
+	return net.JoinHostPort(host, getSettingValue("wan_probe_port", "56460"))
+}
+
+func probeEdge(mac string, info utils.EdgeInfo) utils.ProbeResult {
+	target := wanProbeTarget(info)
+	if target == "" {
+		return utils.ProbeResult{Error: "edge has no external address", ProbedAt: time.Now()}
+	}
+	res := utils.ProbeUDPEcho(target, wanProbeCount, wanProbeTimeout)
+	wanProbeMutex.Lock()
+	wanProbeResults[mac] = res
+	wanProbeMutex.Unlock()
+	return res
+}
+
+// startWanProbe 定期从面板主机探测所有在线 edge 的公网往返延迟，需设置 wan_probe_enabled=true
+func startWanProbe() {
+	ticker := time.NewTicker(wanProbeInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if getSettingValue("wan_probe_enabled", "false") != "true" {
+			continue
+		}
+		edges, _ := allEdgeInfo()
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, 8)
+		for mac, info := range edges {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(mac string, info utils.EdgeInfo) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				probeEdge(mac, info)
+			}(mac, info)
+		}
+		wg.Wait()
+	}
+}
+
+func getWanProbes(c *gin.Context) {
+	wanProbeMutex.Lock()
+	defer wanProbeMutex.Unlock()
+	c.JSON(200, wanProbeResults)
+}
+
+func probeNode(c *gin.Context) {
+	var n models.Node
+	if err := scopeNodes(c).First(&n, c.Param("id")).Error; err != nil {
+		respondError(c, 404, ErrNodeNotFound, "Node not found")
+		return
+	}
+	mac := n.MacAddress
+	edges, _ := allEdgeInfo()
+	info, online := edges[mac]
+	if !online {
+		respondError(c, 409, ErrNodeOffline, "Node is offline")
+		return
+	}
+	c.JSON(200, probeEdge(mac, info))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"n2n_ui/backend/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	watchdogInterval      = 10 * time.Second
+	watchdogFailThreshold = 2 // 连续 N 次检查失败才判定故障，避免重启过程中误判
+)
+
+// watchdogState 一个实例的故障跟踪状态
+type watchdogState struct {
+	failures    int
+	incident    *models.SupernodeIncident
+	nextAttempt time.Time
+}
+
+// watchdogLimits 读取自动重启次数与首次重试间隔，设置 watchdog_max_restarts（0 表示只记录不重启）/ watchdog_backoff_seconds
+func watchdogLimits() (int, time.Duration) {
+	max, err := strconv.Atoi(getSettingValue("watchdog_max_restarts", "3"))
+	if err != nil || max < 0 {
+		max = 3
+	}
+	secs, err := strconv.Atoi(getSettingValue("watchdog_backoff_seconds", "10"))
+	if err != nil || secs < 1 {
+		secs = 10
+	}
+	return max, time.Duration(secs) * time.Second
+}
+
+// checkInstanceHealth 检查 systemd 单元状态与管理端口（绕过缓存），返回故障原因，正常时返回空串
+func checkInstanceHealth(rt *instanceRuntime) string {
+	if !host.ServiceActive(rt.instance.Unit) {
+		return "systemd 服务未处于 active 状态"
+	}
+	if _, err := rt.client.FetchEdgeInfo(); err != nil {
+		return "管理端口无响应: " + err.Error()
+	}
+	return ""
+}
+
+// startWatchdog 定期检查所有实例，故障时按指数退避自动重启，重启次数用尽仍未恢复则告警
+func startWatchdog() {
+	states := make(map[uint]*watchdogState)
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		seen := make(map[uint]bool)
+		for _, rt := range listRuntimes() {
+			seen[rt.instance.ID] = true
+			st, ok := states[rt.instance.ID]
+			if !ok {
+				st = &watchdogState{}
+				states[rt.instance.ID] = st
+			}
+			watchdogCheck(rt, st)
+		}
+		for id := range states {
+			if !seen[id] {
+				delete(states, id)
+			}
+		}
+	}
+}
+
+func watchdogCheck(rt *instanceRuntime, st *watchdogState) {
+	reason := checkInstanceHealth(rt)
+	if reason == "" {
+		if st.incident != nil {
+			now := time.Now()
+			st.incident.Status, st.incident.ResolvedAt = "recovered", &now
+			db.Save(st.incident)
+			log.Printf("Watchdog: %s recovered after %d restart(s)", rt.instance.Name, st.incident.Restarts)
+		}
+		*st = watchdogState{}
+		return
+	}
+	st.failures++
+	if st.failures < watchdogFailThreshold {
+		return
+	}
+	maxRestarts, backoff := watchdogLimits()
+	if st.incident == nil {
+		st.incident = &models.SupernodeIncident{
+			InstanceID: rt.instance.ID, InstanceName: rt.instance.Name, Reason: reason, Status: "recovering", StartedAt: time.Now(),
+		}
+		db.Create(st.incident)
+		log.Printf("Watchdog: %s unhealthy: %s", rt.instance.Name, reason)
+	}
+	if st.incident.Status == "failed" || time.Now().Before(st.nextAttempt) {
+		return
+	}
+	if st.incident.Restarts >= maxRestarts {
+		st.incident.Status = "failed"
+		db.Save(st.incident)
+		dispatchAlert(Alert{Type: "supernode_recovery_failed", Level: "critical", Title: "Supernode 自动恢复失败: " + rt.instance.Name,
+			Message: fmt.Sprintf("%s，已自动重启 %d 次仍未恢复，请人工处理", reason, st.incident.Restarts)})
+		return
+	}
+	st.incident.Restarts++
+	log.Printf("Watchdog: restarting %s (attempt %d/%d)", rt.instance.Name, st.incident.Restarts, maxRestarts)
+	if err := host.RestartService(rt.instance.Unit); err != nil {
+		st.incident.LastError = err.Error()
+	}
+	db.Save(st.incident)
+	rt.client.InvalidateCache()
+	st.nextAttempt = time.Now().Add(backoff << (st.incident.Restarts - 1))
+}
+
+// getIncidents 返回最近的故障记录，可用 ?instance= 过滤
+func getIncidents(c *gin.Context) {
+	q := db.Order("id DESC").Limit(100)
+	if v := c.Query("instance"); v != "" {
+		q = q.Where("instance_id = ?", v)
+	}
+	var list []models.SupernodeIncident
+	q.Find(&list)
+	c.JSON(200, list)
+}
@@ -22,7 +22,9 @@ npm run build
 echo "[2/2] Building Backend (Go)..."
 cd "$BASE_DIR/backend"
 go mod tidy
-go build -ldflags="-s -w" -o n2n_admin .
+GIT_COMMIT=$(git -C "$BASE_DIR" rev-parse --short HEAD 2>/dev/null || echo "")
+BUILD_DATE=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+go build -ldflags="-s -w -X main.GitCommit=$GIT_COMMIT -X main.BuildDate=$BUILD_DATE" -o n2n_admin .
 
 echo ""
 echo "======================================="
@@ -6,6 +6,7 @@ import CommunityList from './pages/CommunityList';
 import Dashboard from './pages/Dashboard';
 import Settings from './pages/Settings';
 import Login from './pages/Login';
+import { BASE_PATH } from './api/basePath';
 import './App.css';
 
 const ProtectedRoute = ({ children }: { children: React.ReactNode }) => {
@@ -19,7 +20,7 @@ const ProtectedRoute = ({ children }: { children: React.ReactNode }) => {
 function App() {
   return (
     <ErrorBoundary>
-    <BrowserRouter>
+    <BrowserRouter basename={BASE_PATH || undefined}>
       <Routes>
         <Route path="/login" element={<Login />} />
         
@@ -0,0 +1,5 @@
+/**
+ * 面板部署的子路径（如 /n2n），由后端在 index.html 中注入，未部署在子路径时为空字符串
+ */
+export const BASE_PATH: string =
+  (window as unknown as { __N2N_BASE_PATH__?: string }).__N2N_BASE_PATH__ || '';
@@ -8,14 +8,57 @@ import type {
   RelayEvent,
   Settings,
   SnConfig,
+  SnConfigProblem,
+  SnConfigValidation,
   NodeFormValues,
   CommunityFormValues,
   LogsResponse,
+  SpeedTest,
+  SpecChange,
+  RequestStats,
+  PublicAddressReport,
+  SelfTestResult,
+  SupernodeStatus,
+  SupernodeInstallRequest,
+  SupernodeInstallPlan,
+  ToolJobInfo,
+  SetupStatus,
+  SetupRequest,
+  UserPreferences,
+  Profile,
+  UserSession,
+  LoginCaptcha,
+  RefreshResponse,
+  SupernodeStats,
+  AlertRule,
+  AlertEvent,
+  Invitation,
+  InvitationSummary,
+  InvitationRequest,
+  NodeRegistration,
+  IntegrityCheck,
+  IntegrityReport,
+  ProvisionedNode,
+  VersionInfo,
+  UpdateStatus,
+  UpgradeResult,
+  OutdatedConfigs,
+  PendingConfig,
+  PasswordRotation,
+  CommunityDriftReport,
+  EventPage,
+  EventQuery,
+  StatsHistory,
+  StatsRetentionRun,
+  StatsRetentionStatus,
+  ClusterStatus,
+  MaintenanceStatus,
   ApiError
 } from '../types';
+import { BASE_PATH } from './basePath';
 
 const api = axios.create({
-  baseURL: '/api',
+  baseURL: `${BASE_PATH}/api`,
   timeout: 30000,
 });
 
@@ -31,10 +74,14 @@ api.interceptors.response.use((r) => r, (error) => {
   if (error.response?.status === 401) {
     localStorage.removeItem('n2n_token');
     localStorage.removeItem('n2n_user');
-    if (!window.location.pathname.startsWith('/login')) {
-      window.location.href = '/login';
+    if (!window.location.pathname.startsWith(`${BASE_PATH}/login`)) {
+      window.location.href = `${BASE_PATH}/login`;
     }
   }
+  // 只读维护模式：修改请求被拒绝时显示维护说明
+  if (error.response?.status === 503 && error.response.data?.code === 'MAINTENANCE_MODE') {
+    message.warning({ content: error.response.data.maintenance_message || error.response.data.error, key: 'maintenance' });
+  }
   return Promise.reject(error);
 });
 
@@ -70,28 +117,140 @@ export const showApiError = (error: unknown, defaultMessage = '操作失败'): v
 
 export const nodeApi = {
   list: () => api.get<Node[]>('/nodes'),
-  create: (data: NodeFormValues) => api.post<Node>('/nodes', data),
+  create: (data: NodeFormValues) => api.post<ProvisionedNode>('/nodes', data),
   delete: (id: number) => api.delete(`/nodes/${id}`),
-  getConfig: (id: number) => api.get<{ conf: string }>(`/nodes/${id}/config`),
+  getConfig: (id: number) => api.get<{ conf: string; pending?: PendingConfig | null }>(`/nodes/${id}/config`),
+  getSpeedTests: (id: number) =>
+    api.get<{ tests: SpeedTest[]; latest: SpeedTest | null; has_agent: boolean }>(`/nodes/${id}/speedtest`),
+  startSpeedTest: (id: number) => api.post<SpeedTest>(`/nodes/${id}/speedtest`),
+  upsertByMac: (mac: string, data: Record<string, unknown>, etag?: string) =>
+    api.put<Node>(`/nodes/by-mac/${encodeURIComponent(mac)}`, data, { headers: etag ? { 'If-Match': etag } : {} }),
+  generateAuthKey: (id: number) =>
+    api.post<{ auth_user: string; auth_public_key: string; secret: string; warning?: string }>(`/nodes/${id}/auth-key`),
+  deleteAuthKey: (id: number) => api.delete(`/nodes/${id}/auth-key`),
+  outdatedConfigs: () => api.get<OutdatedConfigs>('/nodes/outdated-configs'),
+  listPending: () => api.get<{ nodes: Node[]; registrations: NodeRegistration[] }>('/nodes/pending'),
+  approve: (id: number) => api.post<Node>(`/nodes/${id}/approve`),
+  reject: (id: number, data?: { reason?: string; block?: boolean }) => api.post(`/nodes/${id}/reject`, data ?? {}),
 };
 
 export const communityApi = {
   list: () => api.get<Community[]>('/communities'),
   create: (data: CommunityFormValues) => api.post<Community>('/communities', data),
   delete: (id: number) => api.delete(`/communities/${id}`),
+  updateDefaults: (id: number, data: Partial<Community>) => api.put<Community>(`/communities/${id}/defaults`, data),
+  upsertByName: (name: string, data: Record<string, unknown>, etag?: string) =>
+    api.put<Community>(`/communities/by-name/${encodeURIComponent(name)}`, data, {
+      headers: etag ? { 'If-Match': etag } : {},
+    }),
+  setUserAuth: (id: number, enabled: boolean) =>
+    api.put<{ id: number; user_auth: boolean; nodes_without_key?: string[]; warning?: string }>(`/communities/${id}/user-auth`, { enabled }),
+  rotatePassword: (id: number, graceMinutes?: number) =>
+    api.post<PasswordRotation>(`/communities/${id}/rotate-password`, graceMinutes === undefined ? {} : { grace_minutes: graceMinutes }),
+  cancelPasswordRotation: (id: number) => api.delete(`/communities/${id}/rotate-password`),
+  checkDrift: (instance?: number) => api.get<CommunityDriftReport[]>('/communities/reconcile', { params: { instance } }),
+  reconcile: (instance?: number) =>
+    api.post<CommunityDriftReport[]>('/communities/reconcile', null, { params: { instance } }),
 };
 
 export const systemApi = {
   getStats: () => api.get<Stats>('/stats'),
+  getVersion: () => api.get<VersionInfo>('/version'),
+  checkUpdate: () => api.post<UpdateStatus>('/version/check'),
+  upgrade: (force = false) => api.post<{ upgrade: UpgradeResult; restarting: boolean }>('/admin/upgrade', { force }),
   getTopology: () => api.get<TopologyData>('/topology'),
+  getRoutes: () => api.get<{ routes: any[]; conflicts: number }>('/routes'),
   getSettings: () => api.get<Settings>('/settings'),
   saveSettings: (data: Settings) => api.post('/settings', data),
   getSnConfig: () => api.get<SnConfig>('/supernode/config'),
-  saveSnConfig: (data: SnConfig) => api.post('/supernode/config', data),
+  saveSnConfig: (data: SnConfig, force = false) =>
+    api.post<{ message: string; problems: SnConfigProblem[] }>('/supernode/config', data, { params: force ? { force: true } : {} }),
+  validateSnConfig: (data: SnConfig) => api.post<SnConfigValidation>('/supernode/config/validate', data),
   restartSn: () => api.post('/supernode/restart'),
-  execTool: (command: string, target: string) => api.post<{ output: string; error?: string }>('/tools/exec', { command, target }),
+  execTool: (command: string, target: string, port?: number) =>
+    api.post<{ output: string; result?: any; error?: string; code?: string }>('/tools/exec', { command, target, port }),
+  startToolJob: (command: string, target: string, port?: number, count?: number) =>
+    api.post<{ id: string; status: string }>('/tools/jobs', { command, target, port, count }),
+  cancelToolJob: (id: string) => api.delete(`/tools/jobs/${id}`),
   getRelays: () => api.get<RelayEvent[]>('/relays'),
   getRecentLogs: () => api.get<LogsResponse>('/supernode/logs/recent'),
+  exportSpec: (secrets = false) =>
+    api.get<string>('/admin/export-spec', { params: { secrets }, responseType: 'text' }),
+  importSpec: (yaml: string, opts: { dry_run?: boolean; prune?: boolean } = {}) =>
+    api.post<{ changes: SpecChange[]; applied: boolean; warning?: string }>('/admin/import-spec', yaml, {
+      params: opts,
+      headers: { 'Content-Type': 'application/yaml' },
+    }),
+  getEvents: (query: EventQuery = {}) => api.get<EventPage>('/events', { params: query }),
+  exportEvents: (query: Omit<EventQuery, 'limit' | 'cursor'> = {}) =>
+    api.get<Blob>('/events/export', { params: query, responseType: 'blob' }),
+  eventTypes: () => api.get<Record<string, string[]>>('/events/types'),
+  getStatsHistory: (params: { metric?: string; series?: string; hours?: number; resolution?: number } = {}) =>
+    api.get<StatsHistory>('/stats/history', { params }),
+  getStatsRetention: () => api.get<StatsRetentionStatus>('/admin/stats-retention'),
+  runStatsRetention: (vacuum = false) =>
+    api.post<StatsRetentionRun>('/admin/stats-retention/run', null, { params: vacuum ? { vacuum: true } : {} }),
+  getClusterStatus: () => api.get<ClusterStatus>('/admin/cluster'),
+  getMaintenance: () => api.get<MaintenanceStatus>('/maintenance'),
+  setMaintenance: (enabled: boolean, message?: string) =>
+    api.put<MaintenanceStatus>('/admin/maintenance', message === undefined ? { enabled } : { enabled, message }),
+  getRequestStats: () => api.get<RequestStats>('/admin/stats'),
+  resetRequestStats: () => api.post('/admin/stats/reset'),
+  getPublicAddress: (refresh = false) =>
+    api.get<PublicAddressReport>('/admin/public-address', { params: refresh ? { refresh: true } : {} }),
+  applyPublicAddress: () => api.post<PublicAddressReport>('/admin/public-address/apply'),
+  supernodeStatus: (instance?: number) =>
+    api.get<SupernodeStatus>('/supernode/status', { params: instance ? { instance } : {} }),
+  installSupernode: (data: SupernodeInstallRequest, instance?: number) =>
+    api.post<SupernodeInstallPlan | ToolJobInfo>('/supernode/install', data, { params: instance ? { instance } : {} }),
+  supernodeSelfTest: (instance?: number) =>
+    api.get<SelfTestResult>('/supernode/selftest', { params: instance ? { instance } : {} }),
+  supernodeStats: (instance?: number) =>
+    api.get<SupernodeStats>('/supernode/stats', { params: instance ? { instance } : {} }),
+  getPreferences: () => api.get<UserPreferences>('/me/preferences'),
+  updatePreferences: (data: Partial<Omit<UserPreferences, 'updated_at'>>) =>
+    api.put<UserPreferences>('/me/preferences', data),
+  getProfile: () => api.get<Profile>('/me'),
+  changeUsername: (username: string, password: string) =>
+    api.put<{ username: string }>('/me/username', { username, password }),
+  listSessions: () => api.get<UserSession[]>('/me/sessions'),
+  revokeSession: (id: number) => api.delete(`/me/sessions/${id}`),
+  listTokens: () => api.get<UserSession[]>('/me/tokens'),
+  createToken: (name: string, expires_days = 0) =>
+    api.post<{ id: number; name: string; expires_at: string | null; token: string }>('/me/tokens', { name, expires_days }),
+  revokeToken: (id: number) => api.delete(`/me/tokens/${id}`),
+  updateUser: (id: number, data: { is_admin?: boolean; password?: string }) => api.put(`/users/${id}`, data),
+  refreshToken: () => api.post<RefreshResponse>('/refresh'),
+  loginCaptcha: (username?: string) => api.get<LoginCaptcha>('/login/captcha', { params: username ? { username } : {} }),
+  listAlertRules: () => api.get<AlertRule[]>('/alerts/rules'),
+  createAlertRule: (data: Partial<Omit<AlertRule, 'id' | 'created_at' | 'updated_at'>>) =>
+    api.post<AlertRule>('/alerts/rules', data),
+  updateAlertRule: (id: number, data: Partial<Omit<AlertRule, 'id' | 'created_at' | 'updated_at'>>) =>
+    api.put<AlertRule>(`/alerts/rules/${id}`, data),
+  deleteAlertRule: (id: number) => api.delete(`/alerts/rules/${id}`),
+  alertHistory: (params?: { rule?: number; status?: 'firing' | 'resolved'; unacknowledged?: boolean }) =>
+    api.get<AlertEvent[]>('/alerts/history', { params }),
+  acknowledgeAlert: (id: number) => api.post<AlertEvent>(`/alerts/history/${id}/ack`),
+  listInvitations: () => api.get<InvitationSummary[]>('/invitations'),
+  createInvitation: (data: InvitationRequest) =>
+    api.post<{ invitation: Invitation; token: string; link: string }>('/invitations', data),
+  revokeInvitation: (id: number) => api.delete<Invitation>(`/invitations/${id}`),
+  listRegistrations: (params?: { status?: NodeRegistration['status']; invitation?: number }) =>
+    api.get<NodeRegistration[]>('/invitations/registrations', { params }),
+  approveRegistration: (id: number) =>
+    api.post<{ registration: NodeRegistration; node: Node }>(`/invitations/registrations/${id}/approve`),
+  rejectRegistration: (id: number, reason?: string) =>
+    api.post<NodeRegistration>(`/invitations/registrations/${id}/reject`, { reason }),
+  integrityReport: () => api.get<IntegrityReport>('/admin/integrity'),
+  fixIntegrity: (checks?: IntegrityCheck[]) => api.post<IntegrityReport>('/admin/integrity/fix', { checks }),
+};
+
+export const setupApi = {
+  status: () => api.get<SetupStatus>('/setup'),
+  run: (data: SetupRequest, token?: string) =>
+    api.post<{ token: string; user: unknown; community: Community | null; warning?: string }>('/setup', data, {
+      headers: token ? { 'X-Setup-Token': token } : {},
+    }),
 };
 
 export default api;
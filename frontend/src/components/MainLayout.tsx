@@ -11,6 +11,7 @@ import {
 } from '@ant-design/icons';
 import { useNavigate, useLocation } from 'react-router-dom';
 import axios from 'axios';
+import { BASE_PATH } from '../api/basePath';
 
 const { Header, Content, Sider } = Layout;
 const { Text } = Typography;
@@ -49,7 +50,7 @@ const MainLayout: React.FC<MainLayoutProps> = ({ children }) => {
   const handleChangePassword = async (values: any) => {
     setPwdLoading(true);
     try {
-      await axios.post('/api/change-password', values, {
+      await axios.post(`${BASE_PATH}/api/change-password`, values, {
         headers: { Authorization: `Bearer ${localStorage.getItem('n2n_token')}` }
       });
       message.success('密码修改成功，请重新登录');
@@ -3,6 +3,7 @@ import { Card, Form, Input, Button, message, Typography, Layout } from 'antd';
 import { UserOutlined, LockOutlined } from '@ant-design/icons';
 import axios from 'axios';
 import { useNavigate } from 'react-router-dom';
+import { BASE_PATH } from '../api/basePath';
 
 const { Title } = Typography;
 const { Content } = Layout;
@@ -14,7 +15,7 @@ const Login: React.FC = () => {
   const onFinish = async (values: any) => {
     setLoading(true);
     try {
-      const { data } = await axios.post('/api/login', values);
+      const { data } = await axios.post(`${BASE_PATH}/api/login`, values);
       localStorage.setItem('n2n_token', data.token);
       localStorage.setItem('n2n_user', JSON.stringify(data.user));
       message.success('登录成功');
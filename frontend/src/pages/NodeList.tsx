@@ -157,6 +157,11 @@ const NodeList: React.FC = () => {
           <Tag color={record.is_online ? 'green' : 'default'}>
             {record.is_online ? '在线' : '离线'}
           </Tag>
+          {record.is_expired && (
+            <Tooltip title={`到期时间: ${new Date(record.expires_at).toLocaleString()}`}>
+              <Tag color="red">已到期</Tag>
+            </Tooltip>
+          )}
         </Space>
       ),
     },
@@ -255,6 +260,22 @@ const NodeList: React.FC = () => {
             </Col>
           </Row>
 
+          <Divider plain>资产信息</Divider>
+
+          <Row gutter={16}>
+            <Col span={8}>
+              <Form.Item name="owner" label="负责人"><Input /></Form.Item>
+            </Col>
+            <Col span={8}>
+              <Form.Item name="contact" label="联系方式"><Input placeholder="电话 / IM" /></Form.Item>
+            </Col>
+            <Col span={8}>
+              <Form.Item name="device_type" label="设备类型"><Input placeholder="router / nas / laptop" /></Form.Item>
+            </Col>
+          </Row>
+          <Form.Item name="site" label="物理位置"><Input placeholder="例如: 上海办公室 3F 机柜 A" /></Form.Item>
+          <Form.Item name="description" label="备注"><Input.TextArea rows={2} /></Form.Item>
+
           <Divider plain>高级路由</Divider>
           
           <Row gutter={16}>
@@ -3,6 +3,7 @@ import { Card, Form, Input, Button, message, Typography, Row, Col, Alert, Space
 import { SaveOutlined, ReloadOutlined, ProfileOutlined } from '@ant-design/icons';
 import { systemApi } from '../api';
 import axios from 'axios';
+import { BASE_PATH } from '../api/basePath';
 
 const { Title, Text } = Typography;
 
@@ -20,7 +21,7 @@ const Settings: React.FC = () => {
       const [settingsRes, snRes, healthRes] = await Promise.all([
         systemApi.getSettings(),
         systemApi.getSnConfig(),
-        axios.get('/api/health')
+        axios.get(`${BASE_PATH}/api/health`)
       ]);
       globalForm.setFieldsValue(settingsRes.data);
       snForm.setFieldsValue(snRes.data);
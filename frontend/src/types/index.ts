@@ -4,21 +4,43 @@ export interface Node {
   ip_address: string;
   mac_address: string;
   community: string;
-  description: string;
+  description: string; // 备注
+  owner?: string;
+  owner_email?: string;
+  contact?: string;
+  site?: string;
+  device_type?: string;
   encryption?: string;
   compression?: boolean;
   routing?: string;
   local_port?: number;
+  extra_args?: string; // 额外的 edge 参数，每行一个，如 -E、-S1
+  mtu?: number; // 0 表示使用社区的 MTU
+  tap_device?: string; // 空表示 n2n0
+  ttl?: number; // 注册包 TTL，0 表示不设置
   is_enabled: boolean;
   last_seen?: string;
+  expires_at?: string | null;
+  pending_approval?: boolean; // 待审批，批准前保持禁用
+  requested_by?: string; // 提交节点的用户名，自动发现时为空
   created_at: string;
   updated_at: string;
   // 运行时字段（后端返回）
   is_online?: boolean;
+  online_unknown?: boolean;
   is_mapped?: boolean;
   external_ip?: string;
   location?: string;
   conn_type?: 'P2P' | 'Relay';
+  is_expired?: boolean;
+  last_external_ip?: string;
+  last_location?: string;
+  last_conn_type?: 'P2P' | 'Relay' | '';
+  reliability?: number; // 最近 24 小时在 supernode 上可见的轮询比例（%）
+  config_outdated?: boolean; // 已下发的配置与当前配置不同，需要重新下发
+  has_agent?: boolean; // 有 edge 代理，过期的配置会由代理自动更新
+  auth_user?: string; // n2n 用户认证用户名
+  auth_public_key?: string;
 }
 
 export interface Community {
@@ -26,6 +48,14 @@ export interface Community {
   name: string;
   range: string;
   password?: string;
+  port_start?: number;
+  port_end?: number;
+  default_encryption?: string;
+  default_compression?: boolean;
+  mtu?: number;
+  supernode_override?: string;
+  user_auth?: boolean;
+  password_rotate_at?: string | null; // 待生效的密码轮换时间
   created_at: string;
 }
 
@@ -42,6 +72,22 @@ export interface ConfigParams {
   local_port?: number;
 }
 
+export interface SpeedTest {
+  id: number;
+  node_id: number;
+  status: 'pending' | 'running' | 'done' | 'failed';
+  server: string;
+  duration: number;
+  upload_bps: number; // edge -> supernode
+  download_bps: number; // supernode -> edge
+  retransmits: number;
+  error?: string;
+  requested_by: string;
+  created_at: string;
+  started_at: string | null;
+  finished_at: string | null;
+}
+
 export interface RelayEvent {
   src_mac: string;
   dst_mac: string;
@@ -49,10 +95,20 @@ export interface RelayEvent {
   pkt_count: number;
 }
 
+export interface MgmtOutage {
+  instance_id: number;
+  name: string;
+  error: string;
+  failures: number;
+  since: string | null;
+}
+
 export interface Stats {
   node_count: number;
   community_count: number;
   online_count: number;
+  supernode_unreachable: boolean;
+  unreachable_instances: MgmtOutage[];
 }
 
 export interface TopologyNode {
@@ -113,3 +169,430 @@ export interface HealthResponse {
 export interface LogsResponse {
   logs: string[];
 }
+
+export interface SpecChange {
+  kind: 'setting' | 'supernode' | 'community' | 'node';
+  name: string;
+  action: 'create' | 'update' | 'delete';
+  fields?: string[];
+}
+
+export interface StatsSummary {
+  name: string;
+  count: number;
+  errors_4xx?: number;
+  errors: number;
+  error_rate: number;
+  avg_ms: number;
+  p95_ms: number;
+  max_ms: number;
+  total_ms: number;
+}
+
+export interface RequestStats {
+  since: string;
+  routes: StatsSummary[];
+  dependencies: StatsSummary[];
+}
+
+export interface PublicAddressReport {
+  detected_ip: string;
+  source: string; // STUN 服务器或 HTTP 查询地址
+  suggested: string; // 建议的 supernode_host
+  configured: string;
+  configured_ips?: string[];
+  matches: boolean;
+  warning?: string;
+  error?: string;
+  checked_at: string;
+}
+
+export interface SelfTestStep {
+  name: 'listening' | 'address' | 'external' | 'federation_peer';
+  status: 'ok' | 'warn' | 'fail' | 'skipped';
+  message?: string;
+  detail?: Record<string, unknown>;
+}
+
+export interface SelfTestResult {
+  instance: string;
+  supernode: string; // 写入 edge 配置的地址
+  status: 'ok' | 'warn' | 'fail';
+  steps: SelfTestStep[];
+}
+
+export interface SupernodeVersion {
+  installed?: string; // supernode -h 报告的版本
+  error?: string; // 无法检测已安装版本的原因
+  mgmt?: { json: boolean; timestamps: boolean }; // 管理端口无响应时省略
+  supported: string;
+  warnings: string[];
+  checked_at: string;
+}
+
+export interface SupernodeStatus {
+  instance: string;
+  active: boolean;
+  mgmt: { failures: number; last_error?: string; last_success: string; last_failure: string };
+  version: SupernodeVersion;
+}
+
+export interface SupernodeInstallRequest {
+  method?: 'source' | 'package'; // source（默认）从 GitHub 编译，package 使用发行版软件包
+  ref?: string; // method=source 时编译的分支或标签，默认 3.0
+  port?: number; // 默认 7654
+  reinstall?: boolean;
+  overwrite?: boolean; // 覆盖已存在的 supernode.conf 与 systemd 单元
+  dry_run?: boolean;
+}
+
+export interface SupernodeInstallPlan {
+  distro: { id: string; like?: string[]; version?: string; name: string };
+  steps: { title: string; command?: string }[];
+}
+
+// 后台任务（诊断工具、supernode 安装），输出通过 /tools/jobs/:id/stream 获取
+export interface ToolJobInfo {
+  id: string;
+  command: string;
+  target: string;
+  user: string;
+  status: 'running' | 'done' | 'failed' | 'timeout' | 'canceled';
+  result: unknown;
+  error?: string;
+  lines: number;
+  started_at: string;
+  finished_at: string | null;
+}
+
+export interface SetupStatus {
+  initialized: boolean;
+  token_required?: boolean; // 需在 X-Setup-Token 头中提供 N2N_SETUP_TOKEN 或启动日志中的初始化令牌
+  suggested_supernode_host?: string;
+}
+
+export interface SetupRequest {
+  username: string;
+  password: string;
+  supernode_host?: string;
+  community?: { name: string; range?: string; password: string };
+}
+
+export interface UserPreferences {
+  timezone: string; // IANA 时区名，为空时使用浏览器时区
+  language: string; // 为空时按浏览器语言
+  dashboard_layout: unknown;
+  updated_at: string;
+}
+
+export interface Profile {
+  id: number;
+  username: string;
+  is_admin: boolean;
+  last_login_at: string | null;
+  last_login_ip: string;
+  communities?: string[]; // 非管理员可管理的社区
+}
+
+export interface UserSession {
+  id: number;
+  kind: 'session' | 'api_token';
+  name: string;
+  ip: string;
+  user_agent: string;
+  created_at: string;
+  last_seen_at: string;
+  expires_at: string | null; // API 令牌可不过期
+  current: boolean; // 当前请求使用的会话
+}
+
+export interface LoginCaptcha {
+  captcha_required: boolean;
+  captcha_provider: 'off' | 'builtin' | 'turnstile' | 'hcaptcha';
+  captcha_id?: string; // builtin：提交时带上 captcha_id 与 captcha_answer
+  captcha_question?: string;
+  captcha_site_key?: string; // turnstile / hcaptcha：提交组件得到的 captcha_token
+}
+
+export interface RefreshResponse {
+  token: string;
+  expires_at: string; // 新访问令牌的过期时间
+  session_expires_at: string | null; // 会话到期后必须重新登录
+  idle_timeout?: number; // 秒，超过该时间没有请求时会话失效
+}
+
+export interface PacketCounter {
+  type: string; // forward / broadcast / reg_super / errors
+  tx_pkt: number;
+  rx_pkt: number;
+  nak: number; // reg_super 被拒绝的注册
+}
+
+export interface SupernodeStats {
+  instance: string;
+  counters: PacketCounter[];
+  timestamps: { start_time: string; last_fwd: string; last_reg_super: string } | null; // 旧版 supernode 不支持时为 null
+}
+
+export type AlertMetric = 'online_count' | 'relay_ratio' | 'supernode_restarts';
+
+export interface AlertRule {
+  id: number;
+  name: string;
+  metric: AlertMetric;
+  target: string; // online_count：社区名；relay_ratio：节点 ID；supernode_restarts：实例 ID；空表示全部
+  comparator: '<' | '<=' | '>' | '>=' | '==' | '!=';
+  threshold: number;
+  duration: number; // 秒
+  severity: 'info' | 'warning' | 'critical';
+  channels: string; // 逗号分隔，空表示按通知路由
+  enabled: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface AlertEvent {
+  id: number;
+  rule_id: number;
+  rule_name: string;
+  severity: string;
+  value: number;
+  message: string;
+  status: 'firing' | 'resolved';
+  fired_at: string;
+  resolved_at: string | null;
+  acknowledged_at: string | null;
+  acknowledged_by?: string;
+}
+
+export interface Invitation {
+  id: number;
+  community: string;
+  note: string;
+  max_uses: number; // 0 表示不限
+  uses: number;
+  require_approval: boolean;
+  node_tags: string;
+  node_expiry_days: number; // 0 表示注册的节点永久有效
+  expires_at: string | null;
+  revoked_at: string | null;
+  created_by: string;
+  created_at: string;
+}
+
+export interface InvitationSummary {
+  invitation: Invitation;
+  usable: boolean; // 未撤销且未过期
+  pending: number; // 待审批的申请数
+}
+
+export interface InvitationRequest {
+  community: string;
+  note?: string;
+  max_uses?: number;
+  expires_days?: number; // 0 表示不过期，最多 365 天
+  require_approval?: boolean;
+  node_tags?: string;
+  node_expiry_days?: number;
+}
+
+export interface NodeRegistration {
+  id: number;
+  invitation_id: number;
+  community: string;
+  name: string;
+  email: string;
+  status: 'pending' | 'approved' | 'rejected';
+  node_id: number;
+  reason?: string;
+  ip: string;
+  created_at: string;
+  decided_at: string | null;
+  decided_by: string;
+}
+
+export type IntegrityCheck = 'orphan_nodes' | 'duplicate_macs' | 'unknown_settings' | 'expired_trash';
+
+export interface IntegrityIssue {
+  check: IntegrityCheck;
+  count: number;
+  fix: string; // 修复时的处理方式
+  items: unknown[];
+  fixed?: number;
+  error?: string;
+}
+
+export interface IntegrityReport {
+  checked_at: string;
+  total: number;
+  fixed: number;
+  issues: IntegrityIssue[];
+}
+
+// 创建节点的响应：节点字段之外附带 edge 配置（待审批、禁用或已到期时没有）和已更新的 supernode 文件
+export interface ProvisionedNode extends Node {
+  conf?: string;
+  supernode_changes: string[];
+}
+
+export interface UpdateStatus {
+  enabled: boolean;
+  latest?: string;
+  available: boolean;
+  url?: string;
+  checked_at?: string;
+  error?: string;
+}
+
+export interface VersionInfo {
+  version: string;
+  commit: string;
+  build_date: string;
+  go_version: string;
+  platform: string;
+  frontend: string; // 内嵌前端资源的版本，与页面加载时不同说明面板已升级
+  n2n: { min: string; max: string };
+  update: UpdateStatus;
+}
+
+export interface UpgradeResult {
+  from: string;
+  to: string;
+  asset: string;
+  sha256: string;
+  signature_verified: boolean;
+  backup: string; // 旧程序的位置，可手动回滚
+}
+
+export interface OutdatedConfigNode {
+  id: number;
+  name: string;
+  community: string;
+  ip_address: string;
+  owner: string;
+  owner_email: string;
+  last_seen: string | null;
+  is_enabled: boolean;
+}
+
+export interface OutdatedConfigs {
+  agent: OutdatedConfigNode[]; // 由 edge 代理自动更新
+  manual: OutdatedConfigNode[]; // 需要手动重新下发
+}
+
+export interface PendingConfig {
+  conf: string;
+  hash: string;
+  apply_at: string; // 新密码生效时间
+}
+
+export interface PasswordRotation {
+  community: string;
+  password: string; // 只返回一次
+  apply_at: string;
+  applied: boolean; // grace_minutes 为 0 时立即生效
+  nodes: { agent: number; manual: number };
+}
+
+export type EventSource = 'audit' | 'login' | 'alert' | 'incident';
+
+export interface EventRecord {
+  source: EventSource;
+  id: number;
+  time: string;
+  actor: string; // 告警与 supernode 故障为 system
+  type: string;
+  target?: string;
+  detail?: string;
+  ip?: string;
+}
+
+export interface EventQuery {
+  source?: string; // 逗号分隔，默认全部来源
+  from?: string; // RFC3339 或 2006-01-02，含
+  to?: string; // 不含
+  actor?: string;
+  type?: string;
+  q?: string;
+  limit?: number;
+  cursor?: string;
+}
+
+export interface EventPage {
+  events: EventRecord[];
+  next_cursor: string; // 为空表示没有更多
+}
+
+export interface StatsPoint {
+  time: string; // 桶的起始时间
+  avg: number;
+  min: number;
+  max: number;
+}
+
+export interface StatsHistory {
+  metric: 'edges' | 'supernode_up';
+  series: string;
+  resolution: number; // 秒
+  points: StatsPoint[];
+}
+
+export interface StatsRetentionRun {
+  at: string;
+  rolled_up: number;
+  expired: number;
+  vacuumed: boolean;
+  free_bytes_before?: number;
+  duration_ms: number;
+  vacuum_error?: string;
+}
+
+export interface StatsRetentionStatus {
+  tiers: { resolution: number; retention_hours: number; setting: string; buckets: number; oldest?: string }[];
+  free_bytes: number;
+  free_percent: string;
+  vacuum_free_percent: number;
+  last_run: StatsRetentionRun | null;
+}
+
+export interface ClusterStatus {
+  role: 'standalone' | 'auto' | 'follower';
+  member: string;
+  leader: boolean; // 本实例是否运行后台任务
+  members?: { name: string; since: string; leader: boolean }[];
+  leader_lease?: { name: string; holder: string; acquired_at: string; expires_at: string } | null;
+}
+
+export interface MaintenanceStatus {
+  enabled: boolean; // 只读维护模式，修改请求返回 503 (MAINTENANCE_MODE)
+  message: string;
+}
+
+export interface CommunityDriftReport {
+  instance_id: number;
+  instance: string;
+  checked_at: string;
+  trigger?: 'schedule' | 'file_change' | 'write_failed' | 'manual';
+  in_sync: boolean;
+  missing_in_file: string[]; // 数据库中有但 community.list 中没有
+  extra_in_file: string[]; // community.list 中有但数据库中没有
+  auth_drift: string[]; // 用户认证公钥行不一致的社区
+  unknown_live: string[]; // supernode 上活跃但未登记的社区
+  read_error?: string;
+  healed: boolean;
+  heal_error?: string;
+}
+
+export interface SnConfigProblem {
+  level: 'error' | 'warning';
+  option?: string;
+  message: string;
+}
+
+export interface SnConfigValidation {
+  valid: boolean; // 没有 error 级别的问题
+  problems: SnConfigProblem[];
+  config: SnConfig; // 合并后的配置
+  content: string; // 将写入的 supernode.conf
+  diff: string;
+}